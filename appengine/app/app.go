@@ -32,11 +32,15 @@ func createInfra() (domain.Repositories, *chat.Queryers, chat.Pubsub, DoneFunc)
 	doneFuncs = append(doneFuncs, cancel)
 	go repos.UpdatingService(ctx)
 
+	index := inmemory.NewMessageIndex(ps)
+	go index.UpdatingService(ctx)
+
 	qs := &chat.Queryers{
-		UserQueryer:    repos.UserRepository,
-		RoomQueryer:    repos.RoomRepository,
-		MessageQueryer: repos.MessageRepository,
-		EventQueryer:   repos.EventRepository,
+		UserQueryer:     repos.UserRepository,
+		RoomQueryer:     repos.RoomRepository,
+		MessageQueryer:  repos.MessageRepository,
+		MessageSearcher: index,
+		EventQueryer:    repos.EventRepository,
 	}
 
 	done := func() {
@@ -86,7 +90,9 @@ var (
 func init() {
 	var serverDoneFunc func()
 	repos, qs, ps, infraDoneFunc := createInfra()
-	gochatServer, serverDoneFunc = goserver.CreateServerFromInfra(repos, qs, ps, loadConfig())
+	// the local file system is not writable on the appengine.
+	blobs := inmemory.NewBlobStore()
+	gochatServer, serverDoneFunc = goserver.CreateServerFromInfra(repos, qs, ps, blobs, loadConfig())
 	doneFunc = func() {
 		serverDoneFunc()
 		infraDoneFunc()
@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// AckResult is the result of the action message.
+// The fields are set depending on the action.
+type AckResult struct {
+	MessageID uint64 `json:"message_id,omitempty"`
+	RoomID    uint64 `json:"room_id,omitempty"`
+	UserID    uint64 `json:"user_id,omitempty"`
+}
+
+// Ack is the acknowledgement for the action message
+// which has the request ID.
+// It has the result of the action, or the error if the action fails.
+//
+// It implements Event interface.
+type Ack struct {
+	RequestID string             `json:"request_id"`
+	Action    string             `json:"action"`
+	OK        bool               `json:"ok"`
+	Result    *AckResult         `json:"result,omitempty"`
+	Error     *event.ErrorRaised `json:"error,omitempty"`
+	AckedAt   time.Time          `json:"acked_at"`
+}
+
+func (Ack) Type() event.Type         { return event.TypeNone }
+func (a Ack) Timestamp() time.Time   { return a.AckedAt }
+func (Ack) StreamID() event.StreamID { return event.NoneStream }
+
+// NewAck creates the Ack for the action message m which is succeeded.
+func NewAck(m action.ActionMessage, result AckResult) Ack {
+	return Ack{
+		RequestID: action.RequestIDOf(m),
+		Action:    string(m.Action()),
+		OK:        true,
+		Result:    &result,
+		AckedAt:   time.Now(),
+	}
+}
+
+// NewErrorAck creates the Ack for the action message m which is failed by err.
+func NewErrorAck(m action.ActionMessage, err error) Ack {
+	errRaised := NewErrorRaised(err, m)
+	return Ack{
+		RequestID: action.RequestIDOf(m),
+		Action:    string(m.Action()),
+		OK:        false,
+		Error:     &errRaised,
+		AckedAt:   time.Now(),
+	}
+}
+
+// NewAckJSON returns the EventJSON containing the Ack.
+func NewAckJSON(ack Ack) EventJSON {
+	return EventJSON{
+		EventName: EventNameAck,
+		Data:      ack,
+	}
+}
+
+const (
+	// DefaultAckCacheSize is the default number of the acknowledgements
+	// which are remembered to de-duplicate the retried requests.
+	DefaultAckCacheSize = 1024
+
+	// DefaultAckCacheTTL is the default duration for the acknowledgement
+	// to be remembered.
+	DefaultAckCacheTTL = 10 * time.Minute
+)
+
+type userAndRequestID struct {
+	UserID    uint64
+	RequestID string
+}
+
+type ackCacheEntry struct {
+	key      userAndRequestID
+	ack      Ack
+	expireAt time.Time
+}
+
+// ackCache remembers the acknowledgements for the succeeded requests
+// so that the retried request with same request ID is not executed twice.
+// The request IDs are scoped by the user rather than the connection,
+// since the client retries the request after reconnecting, which
+// creates new connection.
+//
+// The oldest entry is evicted when the cache exceeds its size.
+// It is safe for the concurrent use.
+type ackCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[userAndRequestID]*list.Element
+	order   *list.List // front is the oldest.
+}
+
+func newAckCache(size int, ttl time.Duration) *ackCache {
+	return &ackCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[userAndRequestID]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+// Find returns the remembered Ack for the user and request ID.
+// It returns false if not found or expired.
+func (c *ackCache) Find(userID uint64, requestID string) (Ack, bool) {
+	key := userAndRequestID{userID, requestID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return Ack{}, false
+	}
+	entry := elem.Value.(*ackCacheEntry)
+	if time.Now().After(entry.expireAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return Ack{}, false
+	}
+	return entry.ack, true
+}
+
+// Store remembers the Ack for the user and request ID.
+func (c *ackCache) Store(userID uint64, requestID string, ack Ack) {
+	key := userAndRequestID{userID, requestID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	for c.order.Len() >= c.size && c.order.Len() > 0 {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ackCacheEntry).key)
+	}
+	c.entries[key] = c.order.PushBack(&ackCacheEntry{
+		key:      key,
+		ack:      ack,
+		expireAt: time.Now().Add(c.ttl),
+	})
+}
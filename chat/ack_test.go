@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat/action"
+)
+
+func TestNewAck(t *testing.T) {
+	const RequestID = "request-1"
+
+	cm := action.ChatMessage{}
+	cm.ActionName = action.ActionChatMessage
+	cm.RequestID = RequestID
+
+	{ // case1: succeeded action
+		ack := NewAck(cm, AckResult{MessageID: 1, RoomID: 2})
+		if !ack.OK {
+			t.Error("succeeded ack should be OK")
+		}
+		if ack.RequestID != RequestID {
+			t.Errorf("different request ID, expect: %v, got: %v", RequestID, ack.RequestID)
+		}
+		if ack.Action != string(action.ActionChatMessage) {
+			t.Errorf("different action, expect: %v, got: %v", action.ActionChatMessage, ack.Action)
+		}
+		if ack.Result == nil || ack.Result.MessageID != 1 || ack.Result.RoomID != 2 {
+			t.Errorf("different result, got: %#v", ack.Result)
+		}
+		if ack.Error != nil {
+			t.Errorf("succeeded ack should have no error, got: %#v", ack.Error)
+		}
+	}
+
+	{ // case2: failed action
+		ack := NewErrorAck(cm, NewNotFoundError("room not found"))
+		if ack.OK {
+			t.Error("failed ack should not be OK")
+		}
+		if ack.RequestID != RequestID {
+			t.Errorf("different request ID, expect: %v, got: %v", RequestID, ack.RequestID)
+		}
+		if ack.Result != nil {
+			t.Errorf("failed ack should have no result, got: %#v", ack.Result)
+		}
+		if ack.Error == nil || ack.Error.Code != ErrorCodeNotFound {
+			t.Errorf("different error, got: %#v", ack.Error)
+		}
+	}
+
+	if evJSON := NewAckJSON(NewErrorAck(cm, errors.New("error"))); evJSON.EventName != EventNameAck {
+		t.Errorf("different event name, expect: %v, got: %v", EventNameAck, evJSON.EventName)
+	}
+}
+
+func TestAckCache(t *testing.T) {
+	t.Parallel()
+
+	const (
+		UserID    = uint64(1)
+		RequestID = "request-1"
+	)
+
+	cache := newAckCache(2, time.Hour)
+	if _, ok := cache.Find(UserID, RequestID); ok {
+		t.Fatal("empty cache returns some Ack")
+	}
+
+	ack := Ack{RequestID: RequestID, OK: true}
+	cache.Store(UserID, RequestID, ack)
+	if got, ok := cache.Find(UserID, RequestID); !ok || got.RequestID != RequestID {
+		t.Errorf("can not find stored Ack, got: %#v", got)
+	}
+
+	// request ID is scoped by the user.
+	if _, ok := cache.Find(UserID+1, RequestID); ok {
+		t.Error("Ack for other user is found")
+	}
+
+	// the oldest one is evicted.
+	cache.Store(UserID, "request-2", Ack{})
+	cache.Store(UserID, "request-3", Ack{})
+	if _, ok := cache.Find(UserID, RequestID); ok {
+		t.Error("the oldest Ack is not evicted")
+	}
+	for _, id := range []string{"request-2", "request-3"} {
+		if _, ok := cache.Find(UserID, id); !ok {
+			t.Errorf("Ack for %v is evicted", id)
+		}
+	}
+}
+
+func TestAckCacheExpire(t *testing.T) {
+	t.Parallel()
+
+	const TTL = 1 * time.Millisecond
+
+	cache := newAckCache(DefaultAckCacheSize, TTL)
+	cache.Store(1, "request-1", Ack{})
+	time.Sleep(2 * TTL)
+
+	if _, ok := cache.Find(1, "request-1"); ok {
+		t.Error("expired Ack is found")
+	}
+}
@@ -24,6 +24,18 @@ func (a AnyMessage) Action() Action {
 	return ActionEmpty
 }
 
+// get correlation ID from any message.
+// return empty string if not exist.
+func (a AnyMessage) GetCorrelationID() string {
+	return a.String(KeyCorrelationID)
+}
+
+// get request ID from any message.
+// return empty string if not exist.
+func (a AnyMessage) GetRequestID() string {
+	return a.String(KeyRequestID)
+}
+
 func (a AnyMessage) String(key string) string {
 	n, _ := a[key].(string)
 	return n
@@ -74,6 +86,30 @@ func (a AnyMessage) Object(key string) map[string]interface{} {
 	return n
 }
 
+// CorrelationIDOf returns the client-supplied correlation ID
+// contained in the ActionMessage.
+// It returns empty string if the message has no correlation ID.
+func CorrelationIDOf(m ActionMessage) string {
+	if m, ok := m.(interface {
+		GetCorrelationID() string
+	}); ok {
+		return m.GetCorrelationID()
+	}
+	return ""
+}
+
+// RequestIDOf returns the client-supplied request ID
+// contained in the ActionMessage.
+// It returns empty string if the message has no request ID.
+func RequestIDOf(m ActionMessage) string {
+	if m, ok := m.(interface {
+		GetRequestID() string
+	}); ok {
+		return m.GetRequestID()
+	}
+	return ""
+}
+
 // Convert AnyMessage to ActionMessage specified by
 // AnyMessage.Action().
 // it returns error if AnyMessage has invalid data structure.
@@ -83,12 +119,52 @@ func ConvertAnyMessage(m AnyMessage) (ActionMessage, error) {
 	// TODO support other Actions?
 	case ActionChatMessage:
 		return ParseChatMessage(m, a)
+	case ActionEditChatMessage:
+		return ParseEditChatMessage(m, a)
+	case ActionDeleteChatMessage:
+		return ParseDeleteChatMessage(m, a)
+	case ActionAddReaction:
+		return ParseAddReaction(m, a)
+	case ActionRemoveReaction:
+		return ParseRemoveReaction(m, a)
 	case ActionReadMessage:
 		return ParseReadMessage(m, a)
 	case ActionTypeStart:
 		return ParseTypeStart(m, a)
 	case ActionTypeEnd:
 		return ParseTypeEnd(m, a)
+	case ActionCreateRoom:
+		return ParseCreateRoom(m, a)
+	case ActionDeleteRoom:
+		return ParseDeleteRoom(m, a)
+	case ActionAddRoomMember:
+		return ParseAddRoomMember(m, a)
+	case ActionRemoveRoomMember:
+		return ParseRemoveRoomMember(m, a)
+	case ActionRenameRoom:
+		return ParseRenameRoom(m, a)
+	case ActionChangeRoomMemberRole:
+		return ParseChangeRoomMemberRole(m, a)
+	case ActionLeaveRoom:
+		return ParseLeaveRoom(m, a)
+	case ActionTransferRoomOwnership:
+		return ParseTransferRoomOwnership(m, a)
+	case ActionFindOrCreateTalkRoom:
+		return ParseFindOrCreateTalkRoom(m, a)
+	case ActionPinMessage:
+		return ParsePinMessage(m, a)
+	case ActionUnpinMessage:
+		return ParseUnpinMessage(m, a)
+	case ActionUpdateUserProfile:
+		return ParseUpdateUserProfile(m, a)
+	case ActionSendFriendRequest,
+		ActionAcceptFriendRequest,
+		ActionDeclineFriendRequest,
+		ActionCancelFriendRequest,
+		ActionRemoveFriend,
+		ActionBlockUser,
+		ActionUnblockUser:
+		return ParseUserRelationAction(m, a)
 	case ActionEmpty:
 		return m, errors.New("JSON object must have any action field")
 	}
@@ -109,14 +185,35 @@ const (
 	ActionUserConnect    Action = "USER_CONNECT"
 	ActionUserDisconnect Action = "USER_DISCONNECT"
 
-	ActionCreateRoom       Action = "CREATE_ROOM"
-	ActionDeleteRoom       Action = "DELETE_ROOM"
-	ActionAddRoomMember    Action = "ADD_ROOM_MEMBER"
-	ActionRemoveRoomMember Action = "REMOVE_ROOM_MEMBER"
+	ActionCreateRoom            Action = "CREATE_ROOM"
+	ActionDeleteRoom            Action = "DELETE_ROOM"
+	ActionAddRoomMember         Action = "ADD_ROOM_MEMBER"
+	ActionRemoveRoomMember      Action = "REMOVE_ROOM_MEMBER"
+	ActionRenameRoom            Action = "RENAME_ROOM"
+	ActionChangeRoomMemberRole  Action = "CHANGE_ROOM_MEMBER_ROLE"
+	ActionLeaveRoom             Action = "LEAVE_ROOM"
+	ActionTransferRoomOwnership Action = "TRANSFER_ROOM_OWNERSHIP"
+	ActionFindOrCreateTalkRoom  Action = "FIND_OR_CREATE_TALK_ROOM"
+	ActionPinMessage            Action = "PIN_MESSAGE"
+	ActionUnpinMessage          Action = "UNPIN_MESSAGE"
+
+	ActionUpdateUserProfile Action = "UPDATE_USER_PROFILE"
+
+	ActionSendFriendRequest    Action = "SEND_FRIEND_REQUEST"
+	ActionAcceptFriendRequest  Action = "ACCEPT_FRIEND_REQUEST"
+	ActionDeclineFriendRequest Action = "DECLINE_FRIEND_REQUEST"
+	ActionCancelFriendRequest  Action = "CANCEL_FRIEND_REQUEST"
+	ActionRemoveFriend         Action = "REMOVE_FRIEND"
+	ActionBlockUser            Action = "BLOCK_USER"
+	ActionUnblockUser          Action = "UNBLOCK_USER"
 
 	// server from/to front-end client
-	ActionReadMessage Action = "READ_MESSAGE"
-	ActionChatMessage Action = "CHAT_MESSAGE"
+	ActionReadMessage       Action = "READ_MESSAGE"
+	ActionChatMessage       Action = "CHAT_MESSAGE"
+	ActionEditChatMessage   Action = "EDIT_CHAT_MESSAGE"
+	ActionDeleteChatMessage Action = "DELETE_CHAT_MESSAGE"
+	ActionAddReaction       Action = "ADD_REACTION"
+	ActionRemoveReaction    Action = "REMOVE_REACTION"
 
 	ActionTypeStart Action = "TYPE_START"
 	ActionTypeEnd   Action = "TYPE_END"
@@ -124,23 +221,40 @@ const (
 
 const (
 	// key for the action field in AnyMessage.
-	KeyAction   = "action"
-	KeySenderID = "sender_id"
-	KeyRoomID   = "room_id"
+	KeyAction    = "action"
+	KeySenderID  = "sender_id"
+	KeyRoomID    = "room_id"
+	KeyMessageID = "message_id"
+
+	// key for the client-supplied ID which is echoed back
+	// with the result of the action.
+	KeyCorrelationID = "correlation_id"
+
+	// key for the client-supplied ID which requests the
+	// acknowledgement of the action.
+	KeyRequestID = "request_id"
 )
 
 // common fields for the websocket action message structs.
 // it implements ActionMessage interface.
 type EmbdFields struct {
-	ActionName Action `json:"action,omitempty"`
+	ActionName    Action `json:"action,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	RequestID     string `json:"request_id,omitempty"`
 }
 
 func (ef EmbdFields) Action() Action { return ef.ActionName }
 
+func (ef EmbdFields) GetCorrelationID() string { return ef.CorrelationID }
+
+func (ef EmbdFields) GetRequestID() string { return ef.RequestID }
+
 // helper function for parsing fields from AnyMessage.
-// it will load Action from AnyMessage.
+// it will load Action, CorrelationID and RequestID from AnyMessage.
 func (ef *EmbdFields) ParseFields(m AnyMessage) {
 	ef.ActionName = m.Action()
+	ef.CorrelationID = m.String(KeyCorrelationID)
+	ef.RequestID = m.String(KeyRequestID)
 }
 
 // common fields for the websocket message to be
@@ -190,6 +304,8 @@ func ParseErrorMessage(m AnyMessage, action Action) (ErrorMessage, error) {
 	}
 	msg := ErrorMessage{}
 	msg.ActionName = action
+	msg.CorrelationID = m.String(KeyCorrelationID)
+	msg.RequestID = m.String(KeyRequestID)
 	msg.ErrorMsg = m.String("error")
 	msg.Cause = AnyMessage(m.Object("cause"))
 	return msg, nil
@@ -229,6 +345,14 @@ type ChatMessage struct {
 	RoomID   uint64 `json:"room_id,omitempty"`
 	SenderID uint64 `json:"sender_id,omitempty"` // it is overwritten by the server
 	Content  string `json:"content,omitempty"`
+
+	// ParentID is the ID of the thread root message to reply.
+	// Zero value means the message is not a reply.
+	ParentID uint64 `json:"parent_id,omitempty"`
+
+	// AttachmentIDs are the IDs of the uploaded attachments
+	// to be attached to the message.
+	AttachmentIDs []uint64 `json:"attachment_ids,omitempty"`
 }
 
 func ParseChatMessage(m AnyMessage, action Action) (ChatMessage, error) {
@@ -237,12 +361,120 @@ func ParseChatMessage(m AnyMessage, action Action) (ChatMessage, error) {
 	}
 	cm := ChatMessage{}
 	cm.ActionName = action
+	cm.CorrelationID = m.String(KeyCorrelationID)
+	cm.RequestID = m.String(KeyRequestID)
 	cm.RoomID = m.UInt64(KeyRoomID)
 	cm.SenderID = m.UInt64(KeySenderID)
 	cm.Content = m.String("content")
+	cm.ParentID = m.UInt64("parent_id")
+	if ids := m.UInt64s("attachment_ids"); len(ids) > 0 {
+		cm.AttachmentIDs = ids
+	}
 	return cm, nil
 }
 
+// EditChatMessage indicates action for editing the content of
+// the chat message which is already posted.
+// it implements ActionMessage interface.
+type EditChatMessage struct {
+	EmbdFields
+	MessageID uint64 `json:"message_id,omitempty"`
+	RoomID    uint64 `json:"room_id,omitempty"`
+	SenderID  uint64 `json:"sender_id,omitempty"` // it is overwritten by the server
+	Content   string `json:"content,omitempty"`
+}
+
+func ParseEditChatMessage(m AnyMessage, action Action) (EditChatMessage, error) {
+	if action != ActionEditChatMessage {
+		return EditChatMessage{}, errors.New("ParseEditChatMessage: invalid action")
+	}
+	em := EditChatMessage{}
+	em.ActionName = action
+	em.CorrelationID = m.String(KeyCorrelationID)
+	em.RequestID = m.String(KeyRequestID)
+	em.MessageID = m.UInt64(KeyMessageID)
+	em.RoomID = m.UInt64(KeyRoomID)
+	em.SenderID = m.UInt64(KeySenderID)
+	em.Content = m.String("content")
+	return em, nil
+}
+
+// DeleteChatMessage indicates action for deleting the chat message
+// which is already posted.
+// it implements ActionMessage interface.
+type DeleteChatMessage struct {
+	EmbdFields
+	MessageID uint64 `json:"message_id,omitempty"`
+	RoomID    uint64 `json:"room_id,omitempty"`
+	SenderID  uint64 `json:"sender_id,omitempty"` // it is overwritten by the server
+}
+
+func ParseDeleteChatMessage(m AnyMessage, action Action) (DeleteChatMessage, error) {
+	if action != ActionDeleteChatMessage {
+		return DeleteChatMessage{}, errors.New("ParseDeleteChatMessage: invalid action")
+	}
+	dm := DeleteChatMessage{}
+	dm.ActionName = action
+	dm.CorrelationID = m.String(KeyCorrelationID)
+	dm.RequestID = m.String(KeyRequestID)
+	dm.MessageID = m.UInt64(KeyMessageID)
+	dm.RoomID = m.UInt64(KeyRoomID)
+	dm.SenderID = m.UInt64(KeySenderID)
+	return dm, nil
+}
+
+// AddReaction indicates action for adding the emoji reaction
+// to the chat message.
+// it implements ActionMessage interface.
+type AddReaction struct {
+	EmbdFields
+	MessageID uint64 `json:"message_id,omitempty"`
+	RoomID    uint64 `json:"room_id,omitempty"`
+	SenderID  uint64 `json:"sender_id,omitempty"` // it is overwritten by the server
+	Emoji     string `json:"emoji,omitempty"`
+}
+
+func ParseAddReaction(m AnyMessage, action Action) (AddReaction, error) {
+	if action != ActionAddReaction {
+		return AddReaction{}, errors.New("ParseAddReaction: invalid action")
+	}
+	am := AddReaction{}
+	am.ActionName = action
+	am.CorrelationID = m.String(KeyCorrelationID)
+	am.RequestID = m.String(KeyRequestID)
+	am.MessageID = m.UInt64(KeyMessageID)
+	am.RoomID = m.UInt64(KeyRoomID)
+	am.SenderID = m.UInt64(KeySenderID)
+	am.Emoji = m.String("emoji")
+	return am, nil
+}
+
+// RemoveReaction indicates action for removing the emoji reaction
+// from the chat message.
+// it implements ActionMessage interface.
+type RemoveReaction struct {
+	EmbdFields
+	MessageID uint64 `json:"message_id,omitempty"`
+	RoomID    uint64 `json:"room_id,omitempty"`
+	SenderID  uint64 `json:"sender_id,omitempty"` // it is overwritten by the server
+	Emoji     string `json:"emoji,omitempty"`
+}
+
+func ParseRemoveReaction(m AnyMessage, action Action) (RemoveReaction, error) {
+	if action != ActionRemoveReaction {
+		return RemoveReaction{}, errors.New("ParseRemoveReaction: invalid action")
+	}
+	rm := RemoveReaction{}
+	rm.ActionName = action
+	rm.CorrelationID = m.String(KeyCorrelationID)
+	rm.RequestID = m.String(KeyRequestID)
+	rm.MessageID = m.UInt64(KeyMessageID)
+	rm.RoomID = m.UInt64(KeyRoomID)
+	rm.SenderID = m.UInt64(KeySenderID)
+	rm.Emoji = m.String("emoji")
+	return rm, nil
+}
+
 // ReadMessages indicates notification which some chat messages are read by
 // any user.
 // it implements ChatActionMessage interface.
@@ -259,6 +491,8 @@ func ParseReadMessage(m AnyMessage, action Action) (ReadMessages, error) {
 	}
 	rm := ReadMessages{}
 	rm.ActionName = action
+	rm.CorrelationID = m.String(KeyCorrelationID)
+	rm.RequestID = m.String(KeyRequestID)
 	rm.RoomID = m.UInt64(KeyRoomID)
 	rm.SenderID = m.UInt64(KeySenderID)
 	rm.ReadAt = m.Time("read_at")
@@ -281,6 +515,8 @@ func ParseTypeStart(m AnyMessage, action Action) (TypeStart, error) {
 	}
 	ts := TypeStart{}
 	ts.ActionName = action
+	ts.CorrelationID = m.String(KeyCorrelationID)
+	ts.RequestID = m.String(KeyRequestID)
 	ts.ChatActionFields.ParseFields(m)
 	return ts, nil
 }
@@ -301,6 +537,8 @@ func ParseTypeEnd(m AnyMessage, action Action) (TypeEnd, error) {
 	}
 	te := TypeEnd{}
 	te.ActionName = action
+	te.CorrelationID = m.String(KeyCorrelationID)
+	te.RequestID = m.String(KeyRequestID)
 	te.ChatActionFields.ParseFields(m)
 	return te, nil
 }
@@ -321,6 +559,8 @@ func ParseCreateRoom(m AnyMessage, action Action) (CreateRoom, error) {
 	}
 	cr := CreateRoom{}
 	cr.ActionName = action
+	cr.CorrelationID = m.String(KeyCorrelationID)
+	cr.RequestID = m.String(KeyRequestID)
 	cr.SenderID = uint64(m.Number("sender_id"))
 	cr.RoomName = m.String("room_name")
 	cr.RoomMemberIDs = m.UInt64s("room_member_ids")
@@ -342,6 +582,8 @@ func ParseDeleteRoom(m AnyMessage, action Action) (DeleteRoom, error) {
 	}
 	dr := DeleteRoom{}
 	dr.ActionName = action
+	dr.CorrelationID = m.String(KeyCorrelationID)
+	dr.RequestID = m.String(KeyRequestID)
 	dr.SenderID = uint64(m.Number("sender_id"))
 	dr.RoomID = uint64(m.Number("room_id"))
 	return dr, nil
@@ -363,6 +605,8 @@ func ParseAddRoomMember(m AnyMessage, action Action) (AddRoomMember, error) {
 	}
 	arm := AddRoomMember{}
 	arm.ActionName = action
+	arm.CorrelationID = m.String(KeyCorrelationID)
+	arm.RequestID = m.String(KeyRequestID)
 	arm.SenderID = uint64(m.Number("sender_id"))
 	arm.RoomID = uint64(m.Number("room_id"))
 	arm.AddUserID = uint64(m.Number("add_user_id"))
@@ -385,8 +629,327 @@ func ParseRemoveRoomMember(m AnyMessage, action Action) (RemoveRoomMember, error
 	}
 	rrm := RemoveRoomMember{}
 	rrm.ActionName = action
+	rrm.CorrelationID = m.String(KeyCorrelationID)
+	rrm.RequestID = m.String(KeyRequestID)
 	rrm.SenderID = uint64(m.Number("sender_id"))
 	rrm.RoomID = uint64(m.Number("room_id"))
 	rrm.RemoveUserID = uint64(m.Number("remove_user_id"))
 	return rrm, nil
 }
+
+// RenameRoom indicates action for renaming the room.
+// it implements ActionMessage interface.
+type RenameRoom struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	RoomID   uint64 `json:"room_id"`
+	RoomName string `json:"room_name"`
+}
+
+func ParseRenameRoom(m AnyMessage, action Action) (RenameRoom, error) {
+	if action != ActionRenameRoom {
+		return RenameRoom{}, errors.New("RenameRoom: invalid action")
+	}
+	rr := RenameRoom{}
+	rr.ActionName = action
+	rr.CorrelationID = m.String(KeyCorrelationID)
+	rr.RequestID = m.String(KeyRequestID)
+	rr.SenderID = uint64(m.Number("sender_id"))
+	rr.RoomID = uint64(m.Number("room_id"))
+	rr.RoomName = m.String("room_name")
+	return rr, nil
+}
+
+// ChangeRoomMemberRole indicates action for changing the role
+// of the room member.
+// it implements ActionMessage interface.
+type ChangeRoomMemberRole struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	RoomID   uint64 `json:"room_id"`
+	UserID   uint64 `json:"user_id"`
+	Role     string `json:"role"`
+}
+
+func ParseChangeRoomMemberRole(m AnyMessage, action Action) (ChangeRoomMemberRole, error) {
+	if action != ActionChangeRoomMemberRole {
+		return ChangeRoomMemberRole{}, errors.New("ChangeRoomMemberRole: invalid action")
+	}
+	crr := ChangeRoomMemberRole{}
+	crr.ActionName = action
+	crr.CorrelationID = m.String(KeyCorrelationID)
+	crr.RequestID = m.String(KeyRequestID)
+	crr.SenderID = uint64(m.Number("sender_id"))
+	crr.RoomID = uint64(m.Number("room_id"))
+	crr.UserID = uint64(m.Number("user_id"))
+	crr.Role = m.String("role")
+	return crr, nil
+}
+
+// LeaveRoom indicates action for leaving the room by the sender.
+// it implements ActionMessage interface.
+type LeaveRoom struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	RoomID   uint64 `json:"room_id"`
+}
+
+func ParseLeaveRoom(m AnyMessage, action Action) (LeaveRoom, error) {
+	if action != ActionLeaveRoom {
+		return LeaveRoom{}, errors.New("LeaveRoom: invalid action")
+	}
+	lr := LeaveRoom{}
+	lr.ActionName = action
+	lr.CorrelationID = m.String(KeyCorrelationID)
+	lr.RequestID = m.String(KeyRequestID)
+	lr.SenderID = uint64(m.Number("sender_id"))
+	lr.RoomID = uint64(m.Number("room_id"))
+	return lr, nil
+}
+
+// TransferRoomOwnership indicates action for transferring
+// the ownership of the room to other member.
+// it implements ActionMessage interface.
+type TransferRoomOwnership struct {
+	EmbdFields
+
+	SenderID   uint64 `json:"sender_id"`
+	RoomID     uint64 `json:"room_id"`
+	NewOwnerID uint64 `json:"new_owner_id"`
+}
+
+func ParseTransferRoomOwnership(m AnyMessage, action Action) (TransferRoomOwnership, error) {
+	if action != ActionTransferRoomOwnership {
+		return TransferRoomOwnership{}, errors.New("TransferRoomOwnership: invalid action")
+	}
+	tro := TransferRoomOwnership{}
+	tro.ActionName = action
+	tro.CorrelationID = m.String(KeyCorrelationID)
+	tro.RequestID = m.String(KeyRequestID)
+	tro.SenderID = uint64(m.Number("sender_id"))
+	tro.RoomID = uint64(m.Number("room_id"))
+	tro.NewOwnerID = uint64(m.Number("new_owner_id"))
+	return tro, nil
+}
+
+// FindOrCreateTalkRoom indicates action for finding the talk room
+// between the sender and its friend, or creating it if not found.
+// it implements ActionMessage interface.
+type FindOrCreateTalkRoom struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	FriendID uint64 `json:"friend_id"`
+}
+
+func ParseFindOrCreateTalkRoom(m AnyMessage, action Action) (FindOrCreateTalkRoom, error) {
+	if action != ActionFindOrCreateTalkRoom {
+		return FindOrCreateTalkRoom{}, errors.New("FindOrCreateTalkRoom: invalid action")
+	}
+	ftr := FindOrCreateTalkRoom{}
+	ftr.ActionName = action
+	ftr.CorrelationID = m.String(KeyCorrelationID)
+	ftr.RequestID = m.String(KeyRequestID)
+	ftr.SenderID = uint64(m.Number("sender_id"))
+	ftr.FriendID = uint64(m.Number("friend_id"))
+	return ftr, nil
+}
+
+// PinMessage indicates action for pinning the message to the room.
+// it implements ActionMessage interface.
+type PinMessage struct {
+	EmbdFields
+
+	SenderID  uint64 `json:"sender_id"`
+	RoomID    uint64 `json:"room_id"`
+	MessageID uint64 `json:"message_id"`
+}
+
+func ParsePinMessage(m AnyMessage, action Action) (PinMessage, error) {
+	if action != ActionPinMessage {
+		return PinMessage{}, errors.New("PinMessage: invalid action")
+	}
+	pm := PinMessage{}
+	pm.ActionName = action
+	pm.CorrelationID = m.String(KeyCorrelationID)
+	pm.RequestID = m.String(KeyRequestID)
+	pm.SenderID = uint64(m.Number("sender_id"))
+	pm.RoomID = uint64(m.Number("room_id"))
+	pm.MessageID = uint64(m.Number("message_id"))
+	return pm, nil
+}
+
+// UnpinMessage indicates action for unpinning the message from the room.
+// it implements ActionMessage interface.
+type UnpinMessage struct {
+	EmbdFields
+
+	SenderID  uint64 `json:"sender_id"`
+	RoomID    uint64 `json:"room_id"`
+	MessageID uint64 `json:"message_id"`
+}
+
+func ParseUnpinMessage(m AnyMessage, action Action) (UnpinMessage, error) {
+	if action != ActionUnpinMessage {
+		return UnpinMessage{}, errors.New("UnpinMessage: invalid action")
+	}
+	um := UnpinMessage{}
+	um.ActionName = action
+	um.CorrelationID = m.String(KeyCorrelationID)
+	um.RequestID = m.String(KeyRequestID)
+	um.SenderID = uint64(m.Number("sender_id"))
+	um.RoomID = uint64(m.Number("room_id"))
+	um.MessageID = uint64(m.Number("message_id"))
+	return um, nil
+}
+
+// CreateUser indicates action for signing up the new user.
+// It is not sent through the websocket since the user
+// is not logged in yet.
+// it implements ActionMessage interface.
+type CreateUser struct {
+	EmbdFields
+
+	Name      string `json:"user_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password"`
+}
+
+// UpdateUserProfile indicates action for updating
+// the profile of the sender.
+// it implements ActionMessage interface.
+type UpdateUserProfile struct {
+	EmbdFields
+
+	SenderID  uint64 `json:"sender_id"`
+	Name      string `json:"user_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func ParseUpdateUserProfile(m AnyMessage, action Action) (UpdateUserProfile, error) {
+	if action != ActionUpdateUserProfile {
+		return UpdateUserProfile{}, errors.New("UpdateUserProfile: invalid action")
+	}
+	uup := UpdateUserProfile{}
+	uup.ActionName = action
+	uup.CorrelationID = m.String(KeyCorrelationID)
+	uup.RequestID = m.String(KeyRequestID)
+	uup.SenderID = uint64(m.Number("sender_id"))
+	uup.Name = m.String("user_name")
+	uup.FirstName = m.String("first_name")
+	uup.LastName = m.String("last_name")
+	return uup, nil
+}
+
+// DeleteUser indicates action for deleting the account
+// of the sender.
+// It is not sent through the websocket since the connection
+// is closed by the deletion.
+// it implements ActionMessage interface.
+type DeleteUser struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+}
+
+// SendFriendRequest indicates action for sending the friend request
+// from the sender to the user.
+// it implements ActionMessage interface.
+type SendFriendRequest struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// AcceptFriendRequest indicates action for accepting the friend request
+// which the sender received from the user.
+// it implements ActionMessage interface.
+type AcceptFriendRequest struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// DeclineFriendRequest indicates action for declining the friend request
+// which the sender received from the user.
+// it implements ActionMessage interface.
+type DeclineFriendRequest struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// CancelFriendRequest indicates action for canceling the friend request
+// which the sender sent to the user.
+// it implements ActionMessage interface.
+type CancelFriendRequest struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// RemoveFriend indicates action for removing the friendship
+// between the sender and the user.
+// it implements ActionMessage interface.
+type RemoveFriend struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// BlockUser indicates action for blocking the user by the sender.
+// it implements ActionMessage interface.
+type BlockUser struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// UnblockUser indicates action for unblocking the user by the sender.
+// it implements ActionMessage interface.
+type UnblockUser struct {
+	EmbdFields
+
+	SenderID uint64 `json:"sender_id"`
+	UserID   uint64 `json:"user_id"`
+}
+
+// ParseUserRelationAction parses the actions which relates the sender
+// with other user, such as SendFriendRequest and BlockUser.
+// They have same fields, sender_id and user_id.
+func ParseUserRelationAction(m AnyMessage, action Action) (ActionMessage, error) {
+	ef := EmbdFields{}
+	ef.ParseFields(m)
+	ef.ActionName = action
+	senderID := m.UInt64(KeySenderID)
+	userID := m.UInt64("user_id")
+
+	switch action {
+	case ActionSendFriendRequest:
+		return SendFriendRequest{ef, senderID, userID}, nil
+	case ActionAcceptFriendRequest:
+		return AcceptFriendRequest{ef, senderID, userID}, nil
+	case ActionDeclineFriendRequest:
+		return DeclineFriendRequest{ef, senderID, userID}, nil
+	case ActionCancelFriendRequest:
+		return CancelFriendRequest{ef, senderID, userID}, nil
+	case ActionRemoveFriend:
+		return RemoveFriend{ef, senderID, userID}, nil
+	case ActionBlockUser:
+		return BlockUser{ef, senderID, userID}, nil
+	case ActionUnblockUser:
+		return UnblockUser{ef, senderID, userID}, nil
+	}
+	return nil, errors.New("ParseUserRelationAction: invalid action")
+}
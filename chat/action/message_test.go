@@ -137,3 +137,294 @@ func TestParseRemoveRoomMember(t *testing.T) {
 		t.Errorf("different sender id")
 	}
 }
+
+func TestParseChangeRoomMemberRole(t *testing.T) {
+	const (
+		SenderID = uint64(1)
+		RoomID   = uint64(2)
+		UserID   = uint64(3)
+		Role     = "admin"
+	)
+	origin := ChangeRoomMemberRole{
+		SenderID: SenderID,
+		RoomID:   RoomID,
+		UserID:   UserID,
+		Role:     Role,
+	}
+	bs, err := json.Marshal(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var any AnyMessage
+	if err := json.Unmarshal(bs, &any); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseChangeRoomMemberRole(any, ActionChangeRoomMemberRole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RoomID != RoomID {
+		t.Errorf("different room id")
+	}
+	if got.UserID != UserID {
+		t.Errorf("different user id")
+	}
+	if got.SenderID != SenderID {
+		t.Errorf("different sender id")
+	}
+	if got.Role != Role {
+		t.Errorf("different role")
+	}
+}
+
+func TestParseEditChatMessage(t *testing.T) {
+	const (
+		SenderID  = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+		Content   = "edited"
+	)
+	origin := EditChatMessage{
+		MessageID: MessageID,
+		RoomID:    RoomID,
+		SenderID:  SenderID,
+		Content:   Content,
+	}
+	bs, err := json.Marshal(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var any AnyMessage
+	if err := json.Unmarshal(bs, &any); err != nil {
+		t.Fatal(err)
+	}
+	any.SetString(KeyAction, string(ActionEditChatMessage))
+
+	msg, err := ConvertAnyMessage(any)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := msg.(EditChatMessage)
+	if !ok {
+		t.Fatalf("invalid converted type, got: %T", msg)
+	}
+	if got.MessageID != MessageID {
+		t.Errorf("different message id")
+	}
+	if got.RoomID != RoomID {
+		t.Errorf("different room id")
+	}
+	if got.SenderID != SenderID {
+		t.Errorf("different sender id")
+	}
+	if got.Content != Content {
+		t.Errorf("different content")
+	}
+}
+
+func TestParseDeleteChatMessage(t *testing.T) {
+	const (
+		SenderID  = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+	origin := DeleteChatMessage{
+		MessageID: MessageID,
+		RoomID:    RoomID,
+		SenderID:  SenderID,
+	}
+	bs, err := json.Marshal(origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var any AnyMessage
+	if err := json.Unmarshal(bs, &any); err != nil {
+		t.Fatal(err)
+	}
+	any.SetString(KeyAction, string(ActionDeleteChatMessage))
+
+	msg, err := ConvertAnyMessage(any)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := msg.(DeleteChatMessage)
+	if !ok {
+		t.Fatalf("invalid converted type, got: %T", msg)
+	}
+	if got.MessageID != MessageID {
+		t.Errorf("different message id")
+	}
+	if got.RoomID != RoomID {
+		t.Errorf("different room id")
+	}
+	if got.SenderID != SenderID {
+		t.Errorf("different sender id")
+	}
+}
+
+func TestCorrelationIDOf(t *testing.T) {
+	const CorrelationID = "correlation-1"
+
+	for _, any := range []AnyMessage{
+		{KeyAction: string(ActionChatMessage), KeyCorrelationID: CorrelationID},
+		{KeyAction: string(ActionTypeStart), KeyCorrelationID: CorrelationID},
+		{KeyAction: string(ActionDeleteChatMessage), KeyCorrelationID: CorrelationID},
+	} {
+		msg, err := ConvertAnyMessage(any)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := CorrelationIDOf(msg); got != CorrelationID {
+			t.Errorf("different correlation ID for %T, expect: %v, got: %v", msg, CorrelationID, got)
+		}
+	}
+
+	// AnyMessage itself has the correlation ID.
+	if got := CorrelationIDOf(AnyMessage{KeyCorrelationID: CorrelationID}); got != CorrelationID {
+		t.Errorf("different correlation ID for AnyMessage, expect: %v, got: %v", CorrelationID, got)
+	}
+	if got := CorrelationIDOf(AnyMessage{}); got != "" {
+		t.Errorf("correlation ID should be empty, got: %v", got)
+	}
+}
+
+func TestRequestIDOf(t *testing.T) {
+	const RequestID = "request-1"
+
+	msg, err := ConvertAnyMessage(AnyMessage{
+		KeyAction:    string(ActionChatMessage),
+		KeyRequestID: RequestID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := RequestIDOf(msg); got != RequestID {
+		t.Errorf("different request ID, expect: %v, got: %v", RequestID, got)
+	}
+	if got := RequestIDOf(AnyMessage{}); got != "" {
+		t.Errorf("request ID should be empty, got: %v", got)
+	}
+}
+
+func TestConvertAnyMessageRoomActions(t *testing.T) {
+	for _, tcase := range []struct {
+		Action Action
+		Expect ActionMessage
+	}{
+		{ActionCreateRoom, CreateRoom{}},
+		{ActionDeleteRoom, DeleteRoom{}},
+		{ActionAddRoomMember, AddRoomMember{}},
+		{ActionRemoveRoomMember, RemoveRoomMember{}},
+		{ActionRenameRoom, RenameRoom{}},
+		{ActionChangeRoomMemberRole, ChangeRoomMemberRole{}},
+		{ActionLeaveRoom, LeaveRoom{}},
+		{ActionTransferRoomOwnership, TransferRoomOwnership{}},
+		{ActionFindOrCreateTalkRoom, FindOrCreateTalkRoom{}},
+		{ActionPinMessage, PinMessage{}},
+		{ActionUnpinMessage, UnpinMessage{}},
+	} {
+		msg, err := ConvertAnyMessage(AnyMessage{KeyAction: string(tcase.Action)})
+		if err != nil {
+			t.Fatalf("can not convert %v: %v", tcase.Action, err)
+		}
+		if reflect.TypeOf(msg) != reflect.TypeOf(tcase.Expect) {
+			t.Errorf("invalid converted type for %v, expect: %T, got: %T", tcase.Action, tcase.Expect, msg)
+		}
+		if msg.Action() != tcase.Action {
+			t.Errorf("different action, expect: %v, got: %v", tcase.Action, msg.Action())
+		}
+	}
+}
+
+func TestConvertAnyMessageUpdateUserProfile(t *testing.T) {
+	msg, err := ConvertAnyMessage(AnyMessage{
+		KeyAction:    string(ActionUpdateUserProfile),
+		"sender_id":  float64(1),
+		"user_name":  "name",
+		"first_name": "first",
+		"last_name":  "last",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uup, ok := msg.(UpdateUserProfile)
+	if !ok {
+		t.Fatalf("invalid converted type, expect: UpdateUserProfile, got: %T", msg)
+	}
+	if uup.SenderID != 1 || uup.Name != "name" || uup.FirstName != "first" || uup.LastName != "last" {
+		t.Errorf("different converted fields: %#v", uup)
+	}
+}
+
+func TestConvertAnyMessageUserRelationActions(t *testing.T) {
+	for _, testcase := range []struct {
+		Action Action
+		Expect ActionMessage
+	}{
+		{ActionSendFriendRequest, SendFriendRequest{}},
+		{ActionAcceptFriendRequest, AcceptFriendRequest{}},
+		{ActionDeclineFriendRequest, DeclineFriendRequest{}},
+		{ActionCancelFriendRequest, CancelFriendRequest{}},
+		{ActionRemoveFriend, RemoveFriend{}},
+		{ActionBlockUser, BlockUser{}},
+		{ActionUnblockUser, UnblockUser{}},
+	} {
+		msg, err := ConvertAnyMessage(AnyMessage{
+			KeyAction:        string(testcase.Action),
+			KeyCorrelationID: "correlation",
+			"sender_id":      float64(1),
+			"user_id":        float64(2),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(msg) != reflect.TypeOf(testcase.Expect) {
+			t.Fatalf("invalid converted type, expect: %T, got: %T", testcase.Expect, msg)
+		}
+		if msg.Action() != testcase.Action || CorrelationIDOf(msg) != "correlation" {
+			t.Errorf("different converted fields: %#v", msg)
+		}
+		fields := reflect.ValueOf(msg)
+		if fields.FieldByName("SenderID").Uint() != 1 || fields.FieldByName("UserID").Uint() != 2 {
+			t.Errorf("different converted IDs: %#v", msg)
+		}
+	}
+}
+
+func TestConvertAnyMessageReactionActions(t *testing.T) {
+	for _, testcase := range []struct {
+		Action Action
+		Expect ActionMessage
+	}{
+		{ActionAddReaction, AddReaction{}},
+		{ActionRemoveReaction, RemoveReaction{}},
+	} {
+		msg, err := ConvertAnyMessage(AnyMessage{
+			KeyAction:    string(testcase.Action),
+			"sender_id":  float64(1),
+			"room_id":    float64(2),
+			"message_id": float64(3),
+			"emoji":      ":+1:",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(msg) != reflect.TypeOf(testcase.Expect) {
+			t.Fatalf("invalid converted type, expect: %T, got: %T", testcase.Expect, msg)
+		}
+		if msg.Action() != testcase.Action {
+			t.Errorf("different action, expect: %v, got: %v", testcase.Action, msg.Action())
+		}
+		fields := reflect.ValueOf(msg)
+		if fields.FieldByName("SenderID").Uint() != 1 ||
+			fields.FieldByName("RoomID").Uint() != 2 ||
+			fields.FieldByName("MessageID").Uint() != 3 ||
+			fields.FieldByName("Emoji").String() != ":+1:" {
+			t.Errorf("different converted fields: %#v", msg)
+		}
+	}
+}
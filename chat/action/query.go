@@ -8,9 +8,53 @@ type QueryRoomMessages struct {
 	Limit  int       `json:"limit" query:"limit"`
 }
 
+// QueryThreadMessages is a query for
+// reply messages in the thread specified by the root message.
+type QueryThreadMessages struct {
+	RoomID    uint64
+	MessageID uint64
+	Before    Timestamp `json:"before" query:"before"`
+	Limit     int       `json:"limit" query:"limit"`
+}
+
 // QueryUnreadRoomMessages is a query for
 // unread messages by user in specified room.
 type QueryUnreadRoomMessages struct {
 	RoomID uint64
 	Limit  int `json:"limit" query:"limit"`
 }
+
+// QuerySearchMessages is a query for
+// messages matched with the query text in the rooms which
+// user belongs to.
+type QuerySearchMessages struct {
+	Query string `json:"q" query:"q"`
+
+	// filters for the messages. zero value means no filter.
+	RoomID uint64    `json:"room_id" query:"room_id"`
+	UserID uint64    `json:"user_id" query:"user_id"`
+	After  Timestamp `json:"after" query:"after"`
+	Before Timestamp `json:"before" query:"before"`
+
+	Limit int `json:"limit" query:"limit"`
+}
+
+// QueryUserMentions is a query for
+// messages mentioning the user.
+type QueryUserMentions struct {
+	Before Timestamp `json:"before" query:"before"`
+	Limit  int       `json:"limit" query:"limit"`
+}
+
+// QueryUserEvents is a query for
+// the events which the user can receive.
+type QueryUserEvents struct {
+	// the sequence number of the last received event.
+	After uint64 `json:"after" query:"after"`
+
+	// filter for the stream of the events, "user", "room" or "message".
+	// empty value means no filter.
+	Stream string `json:"stream" query:"stream"`
+
+	Limit int `json:"limit" query:"limit"`
+}
@@ -0,0 +1,214 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+//go:generate mockgen -destination=../internal/mocks/mock_attachment_service.go -package=mocks github.com/shirasudon/go-chat/chat AttachmentService
+
+// AttachmentService is the interface for uploading and
+// downloading the files attached to the messages.
+type AttachmentService interface {
+	// Upload stores the file, whose content is read from data, into the room
+	// by the user. The thumbnail is also stored if the file is an image.
+	// The uploaded attachment can be attached to the message by its ID
+	// when the user posts the message.
+	// It returns the information of the uploaded attachment and error if any.
+	Upload(ctx context.Context, userID, roomID uint64, fileName, contentType string, data io.Reader) (*queried.Attachment, error)
+
+	// Find returns the information of the attachment permitted to
+	// the user.
+	Find(ctx context.Context, userID, attachmentID uint64) (*queried.Attachment, error)
+
+	// Open returns the information and the content of the attachment
+	// permitted to the user. If thumbnail is true, the content is
+	// the thumbnail whose type is ThumbnailContentType.
+	// The caller must close the content after use.
+	Open(ctx context.Context, userID, attachmentID uint64, thumbnail bool) (*queried.Attachment, io.ReadCloser, error)
+}
+
+type AttachmentServiceImpl struct {
+	users       domain.UserRepository
+	rooms       domain.RoomRepository
+	msgs        domain.MessageRepository
+	attachments domain.AttachmentRepository
+	blobs       BlobStore
+
+	thumbnailSize int
+}
+
+// NewAttachmentServiceImpl creates AttachmentServiceImpl which stores
+// the files into the blobs, and makes the thumbnails which fit in
+// thumbnailSize x thumbnailSize pixels.
+func NewAttachmentServiceImpl(repos domain.Repositories, blobs BlobStore, thumbnailSize int) *AttachmentServiceImpl {
+	if repos == nil || blobs == nil {
+		panic("passing nil arguments")
+	}
+	if thumbnailSize <= 0 {
+		panic("thumbnail size must be positive")
+	}
+	return &AttachmentServiceImpl{
+		users:         repos.Users(),
+		rooms:         repos.Rooms(),
+		msgs:          repos.Messages(),
+		attachments:   repos.Attachments(),
+		blobs:         blobs,
+		thumbnailSize: thumbnailSize,
+	}
+}
+
+// newBlobKey returns the random key for the BlobStore.
+func newBlobKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func newQueriedAttachment(a domain.Attachment) *queried.Attachment {
+	return &queried.Attachment{
+		AttachmentID: a.ID,
+		UserID:       a.UserID,
+		RoomID:       a.RoomID,
+		MessageID:    a.MessageID,
+		FileName:     a.FileName,
+		ContentType:  a.ContentType,
+		Size:         a.Size,
+		HasThumbnail: a.HasThumbnail(),
+		CreatedAt:    a.CreatedAt,
+	}
+}
+
+func (s *AttachmentServiceImpl) Upload(ctx context.Context, userID, roomID uint64, fileName, contentType string, data io.Reader) (*queried.Attachment, error) {
+	user, err := s.users.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	room, err := s.rooms.Find(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+
+	blobKey, err := newBlobKey()
+	if err != nil {
+		return nil, NewInfraError("can not generate blob key: %v", err)
+	}
+	if err := s.blobs.Put(ctx, blobKey, bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	storedKeys := []string{blobKey}
+
+	var thumbnailKey string
+	if strings.HasPrefix(contentType, "image/") {
+		// the image which can not be decoded is stored without the thumbnail.
+		if thumbnail, err := makeThumbnail(content, s.thumbnailSize); err != nil {
+			log.Printf("AttachmentService: can not make thumbnail for %q: %v", fileName, err)
+		} else {
+			thumbnailKey = blobKey + "-thumbnail"
+			if err := s.blobs.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+				s.deleteBlobs(ctx, storedKeys)
+				return nil, err
+			}
+			storedKeys = append(storedKeys, thumbnailKey)
+		}
+	}
+
+	a, err := domain.NewAttachment(ctx, s.attachments, user, room,
+		fileName, contentType, int64(len(content)), blobKey, thumbnailKey)
+	if err != nil {
+		s.deleteBlobs(ctx, storedKeys)
+		return nil, err
+	}
+	return newQueriedAttachment(a), nil
+}
+
+// deleteBlobs deletes the blobs which are stored but not used.
+func (s *AttachmentServiceImpl) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("AttachmentService: can not delete unused blob(key=%v): %v", key, err)
+		}
+	}
+}
+
+// findPermitted finds the attachment which the user can access.
+// The attachment is permitted to the members of the room, but
+// the one not attached yet is permitted to only its uploader.
+// The attachment on the deleted message is not found.
+func (s *AttachmentServiceImpl) findPermitted(ctx context.Context, userID, attachmentID uint64) (domain.Attachment, error) {
+	a, err := s.attachments.Find(ctx, attachmentID)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+
+	room, err := s.rooms.Find(ctx, a.RoomID)
+	if IsNotFoundError(err) {
+		return domain.Attachment{}, NewNotFoundError("attachment (id=%v) is not found", attachmentID)
+	}
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	if !room.MemberIDSet.Has(userID) {
+		return domain.Attachment{}, domain.NewPermissionError("user(id=%d) is not a member of the room(id=%d), can not access the attachment(id=%d)", userID, room.ID, a.ID)
+	}
+
+	if !a.IsAttached() {
+		if a.UserID != userID {
+			return domain.Attachment{}, domain.NewPermissionError("the attachment(id=%d) is not attached yet, can be accessed by only its uploader", a.ID)
+		}
+		return a, nil
+	}
+
+	m, err := s.msgs.Find(ctx, a.MessageID)
+	if IsNotFoundError(err) || (err == nil && m.Deleted) {
+		return domain.Attachment{}, NewNotFoundError("attachment (id=%v) is not found", attachmentID)
+	}
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (s *AttachmentServiceImpl) Find(ctx context.Context, userID, attachmentID uint64) (*queried.Attachment, error) {
+	a, err := s.findPermitted(ctx, userID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	return newQueriedAttachment(a), nil
+}
+
+func (s *AttachmentServiceImpl) Open(ctx context.Context, userID, attachmentID uint64, thumbnail bool) (*queried.Attachment, io.ReadCloser, error) {
+	a, err := s.findPermitted(ctx, userID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := a.BlobKey
+	if thumbnail {
+		if !a.HasThumbnail() {
+			return nil, nil, NewNotFoundError("attachment (id=%v) has no thumbnail", attachmentID)
+		}
+		key = a.ThumbnailKey
+	}
+	content, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newQueriedAttachment(a), content, nil
+}
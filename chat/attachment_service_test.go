@@ -0,0 +1,221 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/internal/mocks"
+)
+
+func TestAttachmentServiceImplement(t *testing.T) {
+	t.Parallel()
+	// make sure the interface is implemented.
+	var _ AttachmentService = &AttachmentServiceImpl{}
+}
+
+func TestAttachmentServiceUpload(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		User = domain.User{ID: 1}
+		Room = domain.Room{ID: 1, MemberIDSet: domain.NewUserIDSet(User.ID)}
+	)
+	const NewAttachmentID = uint64(1)
+
+	users := mocks.NewMockUserRepository(ctrl)
+	users.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil).AnyTimes()
+	rooms := mocks.NewMockRoomRepository(ctrl)
+	rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil).AnyTimes()
+
+	for _, testcase := range []struct {
+		FileName     string
+		ContentType  string
+		Data         []byte
+		HasThumbnail bool
+	}{
+		{"photo.png", "image/png", encodeTestImage(t, 300, 150, false), true},
+		{"broken.png", "image/png", []byte("broken image"), false},
+		{"note.txt", "text/plain", []byte("hello"), false},
+	} {
+		stored := make(map[string][]byte)
+		blobs := mocks.NewMockBlobStore(ctrl)
+		blobs.EXPECT().
+			Put(gomock.Any(), gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, key string, r io.Reader) {
+				if !IsValidBlobKey(key) {
+					t.Errorf("invalid blob key %q", key)
+				}
+				stored[key], _ = ioutil.ReadAll(r)
+			}).
+			Return(nil).
+			AnyTimes()
+
+		attachments := mocks.NewMockAttachmentRepository(ctrl)
+		attachments.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, a domain.Attachment) {
+				if !bytes.Equal(stored[a.BlobKey], testcase.Data) {
+					t.Errorf("the content is not stored by the blob key")
+				}
+				if a.HasThumbnail() != testcase.HasThumbnail {
+					t.Errorf("%v: expect to have thumbnail %v, got %v", testcase.FileName, testcase.HasThumbnail, a.HasThumbnail())
+				}
+				if a.HasThumbnail() && len(stored[a.ThumbnailKey]) == 0 {
+					t.Errorf("the thumbnail is not stored by the thumbnail key")
+				}
+			}).
+			Return(NewAttachmentID, nil).
+			Times(1)
+
+		service := NewAttachmentServiceImpl(domain.SimpleRepositories{
+			UserRepository:       users,
+			RoomRepository:       rooms,
+			AttachmentRepository: attachments,
+		}, blobs, 64)
+
+		a, err := service.Upload(context.Background(), User.ID, Room.ID,
+			testcase.FileName, testcase.ContentType, bytes.NewReader(testcase.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.AttachmentID != NewAttachmentID || a.Size != int64(len(testcase.Data)) || a.HasThumbnail != testcase.HasThumbnail {
+			t.Errorf("different uploaded attachment, got %#v", a)
+		}
+	}
+}
+
+func TestAttachmentServiceUploadFail(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		User = domain.User{ID: 1}
+		Room = domain.Room{ID: 1, MemberIDSet: domain.NewUserIDSet()}
+	)
+
+	users := mocks.NewMockUserRepository(ctrl)
+	users.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil).Times(1)
+	rooms := mocks.NewMockRoomRepository(ctrl)
+	rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil).Times(1)
+
+	// the stored blob is deleted when the user can not upload.
+	var storedKey string
+	blobs := mocks.NewMockBlobStore(ctrl)
+	blobs.EXPECT().
+		Put(gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, key string, r io.Reader) { storedKey = key }).
+		Return(nil).
+		Times(1)
+	blobs.EXPECT().
+		Delete(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, key string) {
+			if key != storedKey {
+				t.Errorf("different blob is deleted, expect %v, got %v", storedKey, key)
+			}
+		}).
+		Return(nil).
+		Times(1)
+
+	service := NewAttachmentServiceImpl(domain.SimpleRepositories{
+		UserRepository:       users,
+		RoomRepository:       rooms,
+		AttachmentRepository: mocks.NewMockAttachmentRepository(ctrl),
+	}, blobs, 64)
+
+	_, err := service.Upload(context.Background(), User.ID, Room.ID, "note.txt", "text/plain", strings.NewReader("hello"))
+	if err == nil {
+		t.Fatal("the user not in the room uploads the attachment")
+	}
+}
+
+func TestAttachmentServiceOpen(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const (
+		Uploader  = uint64(1)
+		Member    = uint64(2)
+		NotMember = uint64(3)
+	)
+	var (
+		Room       = domain.Room{ID: 1, MemberIDSet: domain.NewUserIDSet(Uploader, Member)}
+		Message    = domain.Message{ID: 1, RoomID: Room.ID}
+		DeletedMsg = domain.Message{ID: 2, RoomID: Room.ID, Deleted: true}
+
+		Attached    = domain.Attachment{ID: 1, UserID: Uploader, RoomID: Room.ID, MessageID: Message.ID, BlobKey: "attached", ThumbnailKey: "attached-thumbnail"}
+		NotAttached = domain.Attachment{ID: 2, UserID: Uploader, RoomID: Room.ID, BlobKey: "not-attached"}
+		OnDeleted   = domain.Attachment{ID: 3, UserID: Uploader, RoomID: Room.ID, MessageID: DeletedMsg.ID, BlobKey: "on-deleted"}
+	)
+
+	attachments := mocks.NewMockAttachmentRepository(ctrl)
+	for _, a := range []domain.Attachment{Attached, NotAttached, OnDeleted} {
+		attachments.EXPECT().Find(gomock.Any(), a.ID).Return(a, nil).AnyTimes()
+	}
+	rooms := mocks.NewMockRoomRepository(ctrl)
+	rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil).AnyTimes()
+	msgs := mocks.NewMockMessageRepository(ctrl)
+	msgs.EXPECT().Find(gomock.Any(), Message.ID).Return(Message, nil).AnyTimes()
+	msgs.EXPECT().Find(gomock.Any(), DeletedMsg.ID).Return(DeletedMsg, nil).AnyTimes()
+
+	blobs := mocks.NewMockBlobStore(ctrl)
+	blobs.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, key string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(key)), nil
+		}).
+		AnyTimes()
+
+	service := NewAttachmentServiceImpl(domain.SimpleRepositories{
+		RoomRepository:       rooms,
+		MessageRepository:    msgs,
+		AttachmentRepository: attachments,
+	}, blobs, 64)
+
+	for _, testcase := range []struct {
+		UserID       uint64
+		AttachmentID uint64
+		Thumbnail    bool
+		Expect       string
+		IsNotFound   bool
+		IsPermission bool
+	}{
+		{Member, Attached.ID, false, Attached.BlobKey, false, false},
+		{Member, Attached.ID, true, Attached.ThumbnailKey, false, false},
+		{Uploader, NotAttached.ID, false, NotAttached.BlobKey, false, false},
+		{Uploader, NotAttached.ID, true, "", true, false},
+		{Member, NotAttached.ID, false, "", false, true},
+		{NotMember, Attached.ID, false, "", false, true},
+		{Member, OnDeleted.ID, false, "", true, false},
+	} {
+		_, content, err := service.Open(context.Background(), testcase.UserID, testcase.AttachmentID, testcase.Thumbnail)
+		if testcase.IsNotFound || testcase.IsPermission {
+			if got := IsNotFoundError(err); got != testcase.IsNotFound {
+				t.Errorf("%#v: expect not found error %v, got %v", testcase, testcase.IsNotFound, err)
+			}
+			if got := domain.IsPermissionError(err); got != testcase.IsPermission {
+				t.Errorf("%#v: expect permission error %v, got %v", testcase, testcase.IsPermission, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%#v: %v", testcase, err)
+			continue
+		}
+		got, _ := ioutil.ReadAll(content)
+		content.Close()
+		if string(got) != testcase.Expect {
+			t.Errorf("%#v: different content, expect %v, got %v", testcase, testcase.Expect, string(got))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"context"
+	"io"
+)
+
+//go:generate mockgen -destination=../internal/mocks/mock_blob_store.go -package=mocks github.com/shirasudon/go-chat/chat BlobStore
+
+// BlobStore is the interface for the storage of the binary data,
+// such as the attachment files, which is identified by the key.
+// The key consists of only the alphanumerics, '-' and '_'.
+type BlobStore interface {
+	// Put stores the data read from r by the key.
+	// If the data for the key already exists, it is overwritten.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get returns the reader for the data stored by the key.
+	// The caller must close the reader after use.
+	// It returns NotFoundError if the data is not found.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete deletes the data stored by the key.
+	// It returns nil if the data is not found.
+	Delete(ctx context.Context, key string) error
+}
+
+// IsValidBlobKey returns whether the key can be used for the BlobStore.
+func IsValidBlobKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"github.com/shirasudon/go-chat/chat/action"
@@ -31,6 +32,38 @@ type CommandService interface {
 	// It returns affected room result and InfraError if any.
 	RemoveRoomMember(ctx context.Context, m action.RemoveRoomMember) (*result.RemoveRoomMember, error)
 
+	// RenameRoom changes the name of the specified room.
+	// It returns renamed Room's ID and error if any.
+	RenameRoom(ctx context.Context, m action.RenameRoom) (roomID uint64, err error)
+
+	// ChangeRoomMemberRole changes the role of the member in the specified room.
+	// It returns affected Room's ID and error if any.
+	ChangeRoomMemberRole(ctx context.Context, m action.ChangeRoomMemberRole) (roomID uint64, err error)
+
+	// LeaveRoom removes the sender from the specified room.
+	// When the owner leaves, the ownership is transferred to other member
+	// or the room is archived if no member remains.
+	// It returns left Room's ID and error if any.
+	LeaveRoom(ctx context.Context, m action.LeaveRoom) (roomID uint64, err error)
+
+	// TransferRoomOwnership transfers the ownership of the specified room
+	// to other member.
+	// It returns affected Room's ID and error if any.
+	TransferRoomOwnership(ctx context.Context, m action.TransferRoomOwnership) (roomID uint64, err error)
+
+	// FindOrCreateTalkRoom finds the talk room, which is unique 1:1 room,
+	// between the sender and its friend, or creates it if not found.
+	// It returns the talk room result and error if any.
+	FindOrCreateTalkRoom(ctx context.Context, m action.FindOrCreateTalkRoom) (*result.FindOrCreateTalkRoom, error)
+
+	// PinMessage pins the message to the specified room.
+	// It returns affected Room's ID and error if any.
+	PinMessage(ctx context.Context, m action.PinMessage) (roomID uint64, err error)
+
+	// UnpinMessage unpins the message from the specified room.
+	// It returns affected Room's ID and error if any.
+	UnpinMessage(ctx context.Context, m action.UnpinMessage) (roomID uint64, err error)
+
 	// Mark that the room messages are read by the specified user.
 	// It returns updated room ID and nil, or
 	// returns InfraError when the message can not be marked to read.
@@ -40,6 +73,82 @@ type CommandService interface {
 	// It returns posted message id and nil or InfraError
 	// which indicates the message can not be posted.
 	PostRoomMessage(ctx context.Context, m action.ChatMessage) (msgID uint64, err error)
+
+	// Edit the content of the message in the specified room.
+	// It returns edited message id and nil or InfraError
+	// which indicates the message can not be edited.
+	EditRoomMessage(ctx context.Context, m action.EditChatMessage) (msgID uint64, err error)
+
+	// Delete the message in the specified room.
+	// It returns deleted message id and nil or InfraError
+	// which indicates the message can not be deleted.
+	DeleteRoomMessage(ctx context.Context, m action.DeleteChatMessage) (msgID uint64, err error)
+
+	// Add the emoji reaction to the message in the specified room.
+	// It returns reacted message id and nil or error
+	// which indicates the reaction can not be added.
+	AddReaction(ctx context.Context, m action.AddReaction) (msgID uint64, err error)
+
+	// Remove the emoji reaction from the message in the specified room.
+	// It returns reacted message id and nil or error
+	// which indicates the reaction can not be removed.
+	RemoveReaction(ctx context.Context, m action.RemoveReaction) (msgID uint64, err error)
+
+	// Notify that the user starts typing in the specified room.
+	// The typing event is only published and not stored.
+	// It returns the room ID and error if any.
+	StartTyping(ctx context.Context, m action.TypeStart) (roomID uint64, err error)
+
+	// Notify that the user ends typing in the specified room.
+	// The typing event is only published and not stored.
+	// It returns the room ID and error if any.
+	EndTyping(ctx context.Context, m action.TypeEnd) (roomID uint64, err error)
+
+	// CreateUser signs up the new user.
+	// It returns created User's ID and error if any.
+	CreateUser(ctx context.Context, m action.CreateUser) (userID uint64, err error)
+
+	// UpdateUserProfile changes the profile of the sender.
+	// It returns updated User's ID and error if any.
+	UpdateUserProfile(ctx context.Context, m action.UpdateUserProfile) (userID uint64, err error)
+
+	// DeleteUser deletes the account of the sender.
+	// The rooms and friends of the deleted user are updated
+	// asynchronously by RunUpdateService().
+	// It returns deleted User's ID and error if any.
+	DeleteUser(ctx context.Context, m action.DeleteUser) (userID uint64, err error)
+
+	// SendFriendRequest sends the friend request from the sender to the user.
+	// It returns the requested User's ID and error if any.
+	SendFriendRequest(ctx context.Context, m action.SendFriendRequest) (userID uint64, err error)
+
+	// AcceptFriendRequest accepts the friend request which the sender
+	// received from the user, then they become the friends each other.
+	// It returns the requesting User's ID and error if any.
+	AcceptFriendRequest(ctx context.Context, m action.AcceptFriendRequest) (userID uint64, err error)
+
+	// DeclineFriendRequest declines the friend request which the sender
+	// received from the user.
+	// It returns the requesting User's ID and error if any.
+	DeclineFriendRequest(ctx context.Context, m action.DeclineFriendRequest) (userID uint64, err error)
+
+	// CancelFriendRequest cancels the friend request which the sender
+	// sent to the user.
+	// It returns the requested User's ID and error if any.
+	CancelFriendRequest(ctx context.Context, m action.CancelFriendRequest) (userID uint64, err error)
+
+	// RemoveFriend removes the friendship between the sender and the user.
+	// It returns the removed friend's ID and error if any.
+	RemoveFriend(ctx context.Context, m action.RemoveFriend) (userID uint64, err error)
+
+	// BlockUser blocks the user by the sender. The friendship and
+	// the friend requests between them are also removed.
+	// It returns the blocked User's ID and error if any.
+	BlockUser(ctx context.Context, m action.BlockUser) (userID uint64, err error)
+
+	// UnblockUser unblocks the user blocked by the sender.
+	// It returns the unblocked User's ID and error if any.
+	UnblockUser(ctx context.Context, m action.UnblockUser) (userID uint64, err error)
 }
 
 // CommandServiceImpl provides the usecases for
@@ -49,18 +158,33 @@ type CommandServiceImpl struct {
 	msgs         domain.MessageRepository
 	users        domain.UserRepository
 	rooms        domain.RoomRepository
+	attachments  domain.AttachmentRepository
 	events       event.EventRepository
 	pubsub       Pubsub
+	hasher       *domain.PasswordHasher
 	updateCancel chan struct{}
 }
 
+// NewCommandServiceImpl creates CommandServiceImpl which hashes
+// the user password by domain.DefaultPasswordHasher.
 func NewCommandServiceImpl(repos domain.Repositories, pubsub Pubsub) *CommandServiceImpl {
+	return NewCommandServiceImplWithHasher(repos, pubsub, domain.DefaultPasswordHasher)
+}
+
+// NewCommandServiceImplWithHasher creates CommandServiceImpl which hashes
+// the user password by given hasher.
+func NewCommandServiceImplWithHasher(repos domain.Repositories, pubsub Pubsub, hasher *domain.PasswordHasher) *CommandServiceImpl {
+	if hasher == nil {
+		panic("nil PasswordHasher")
+	}
 	return &CommandServiceImpl{
 		msgs:         repos.Messages(),
 		users:        repos.Users(),
 		rooms:        repos.Rooms(),
+		attachments:  repos.Attachments(),
 		events:       repos.Events(),
 		pubsub:       pubsub,
+		hasher:       hasher,
 		updateCancel: make(chan struct{}),
 	}
 }
@@ -68,15 +192,20 @@ func NewCommandServiceImpl(repos domain.Repositories, pubsub Pubsub) *CommandSer
 // Run updating service for the domain events.
 // It blocks until calling CancelUpdate() or context is done.
 func (s *CommandServiceImpl) RunUpdateService(ctx context.Context) {
-	roomDeleted := s.pubsub.Sub(event.TypeRoomDeleted)
+	deletions := s.pubsub.Sub(event.TypeRoomDeleted, event.TypeUserDeleted)
 	for {
 		select {
-		case ev, chAlived := <-roomDeleted:
+		case ev, chAlived := <-deletions:
 			if !chAlived {
 				return
 			}
-			deleted := ev.(event.RoomDeleted)
-			err := s.msgs.RemoveAllByRoomID(ctx, deleted.RoomID)
+			var err error
+			switch deleted := ev.(type) {
+			case event.RoomDeleted:
+				err = s.msgs.RemoveAllByRoomID(ctx, deleted.RoomID)
+			case event.UserDeleted:
+				err = s.removeDeletedUser(ctx, deleted)
+			}
 			// TODO error handling, create ErrorEvent? or just log?
 			_ = err
 		case <-ctx.Done():
@@ -93,8 +222,71 @@ func (s *CommandServiceImpl) CancelUpdateService() {
 	close(s.updateCancel)
 }
 
+// removeDeletedUser removes the deleted user from its rooms and
+// the friend lists of its friends. Each of the changes is committed
+// with its domain events, and the first error is returned if any.
+func (s *CommandServiceImpl) removeDeletedUser(ctx context.Context, deleted event.UserDeleted) error {
+	// the deleted user is no longer in the repository,
+	// so it is restored from the event.
+	user := domain.User{
+		ID:        deleted.UserID,
+		Name:      deleted.Name,
+		FriendIDs: domain.NewUserIDSet(deleted.FriendIDs...),
+	}
+
+	rooms, err := s.rooms.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, room := range rooms {
+		room := room
+		err := s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+			if err := room.RemoveDeletedUser(ctx, s.rooms, &user); err != nil {
+				return nil, err
+			}
+			return room.Events(), nil
+		})
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, friendID := range deleted.FriendIDs {
+		friendID := friendID
+		err := s.withEventTransaction(ctx, s.users, func(ctx context.Context) ([]event.Event, error) {
+			friend, err := s.users.Find(ctx, friendID)
+			if err != nil {
+				return nil, err
+			}
+			if !friend.HasFriend(user) {
+				return nil, nil
+			}
+			if _, err := friend.RemoveFriend(user); err != nil {
+				return nil, err
+			}
+			if _, err := s.users.Store(ctx, friend); err != nil {
+				return nil, err
+			}
+			return friend.Events(), nil
+		})
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 // Do function on the context of the transaction.
 // It also commits the some domain events returned from txFunc.
+// The events are stored in the transaction if the EventRepository can
+// join it, so that the events are rollbacked with the state changes.
+// Otherwise, such as the file log, the events are stored after the
+// transaction is committed, and the error is returned if those can not
+// be stored, rather than dropping them.
+// The events are published with the sequence numbers assigned by the
+// EventRepository, after the outermost transaction is committed.
 func (s *CommandServiceImpl) withEventTransaction(
 	ctx context.Context,
 	txBeginner domain.TxBeginner,
@@ -102,36 +294,120 @@ func (s *CommandServiceImpl) withEventTransaction(
 ) error {
 	return withTransaction(ctx, txBeginner, func(ctx context.Context) error {
 		events, err := txFunc(ctx)
-		if err != nil {
+		if err != nil || events == nil {
 			return err
 		}
 
-		if events != nil {
-			_, err := s.events.Store(ctx, events...)
+		if _, ok := s.events.(domain.TxBeginner); ok {
+			seqs, err := s.events.Store(ctx, events...)
 			if err != nil {
 				return err
 			}
-			s.pubsub.Pub(events...)
+			return afterCommit(ctx, func() error {
+				s.publish(events, seqs)
+				return nil
+			})
 		}
-		return nil
+
+		return afterCommit(ctx, func() error {
+			seqs, err := s.storeEvents(ctx, events)
+			if err != nil {
+				return err
+			}
+			s.publish(events, seqs)
+			return nil
+		})
 	})
 }
 
+// StoreEventsRetries is the number of retries to store the events
+// after the transaction is committed.
+const StoreEventsRetries = 3
+
+// storeEvents stores the events with retrying, since the state changes
+// are already committed and the events can not be rollbacked.
+func (s *CommandServiceImpl) storeEvents(ctx context.Context, events []event.Event) ([]uint64, error) {
+	var (
+		seqs []uint64
+		err  error
+	)
+	for i := 0; i <= StoreEventsRetries; i++ {
+		if seqs, err = s.events.Store(ctx, events...); err == nil {
+			return seqs, nil
+		}
+		log.Printf("CommandService: can not store events, retry(%d): %v", i, err)
+	}
+	return nil, err
+}
+
+// publish publishes the events with its sequence numbers.
+func (s *CommandServiceImpl) publish(events []event.Event, seqs []uint64) {
+	if len(seqs) == len(events) {
+		stored := make([]event.Event, 0, len(events))
+		for i, ev := range events {
+			stored = append(stored, event.WithSequence(ev, seqs[i]))
+		}
+		events = stored
+	}
+	s.pubsub.Pub(events...)
+}
+
 // Do function on the context of the transaction.
 // The transaction begins before run the txFunc, then run the txFunc, then commit if txFunc returns nil.
 // The transaction is rollbacked if txFunc returns some error.
+// If the context already has the transaction, the nested transaction
+// joins it and the outer transaction is kept in the context.
+// The functions registered by afterCommit are run after the outermost
+// transaction is committed, and the first error of those is returned.
 func withTransaction(ctx context.Context, txBeginner domain.TxBeginner, txFunc func(ctx context.Context) error) error {
 	tx, err := txBeginner.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	err = txFunc(domain.SetTx(ctx, tx))
+	var committed *afterCommitFuncs
+	if _, ok := domain.GetTx(ctx); !ok {
+		committed = &afterCommitFuncs{}
+		ctx = context.WithValue(domain.SetTx(ctx, tx), afterCommitKey{}, committed)
+	}
+	err = txFunc(ctx)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if committed == nil {
+		return nil
+	}
+
+	var firstErr error
+	for _, f := range *committed {
+		if err := f(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// afterCommitKey is the context key for the afterCommitFuncs.
+type afterCommitKey struct{}
+
+// afterCommitFuncs is the functions to be run after the
+// outermost transaction is committed.
+type afterCommitFuncs []func() error
+
+// afterCommit registers the function to be run after the outermost
+// transaction in the context is committed.
+// The function is run immediately if the transaction in the context
+// is not begun by withTransaction.
+func afterCommit(ctx context.Context, f func() error) error {
+	if committed, ok := ctx.Value(afterCommitKey{}).(*afterCommitFuncs); ok {
+		*committed = append(*committed, f)
+		return nil
+	}
+	return f()
 }
 
 // It creates room specified by given actiom message.
@@ -142,11 +418,21 @@ func (s *CommandServiceImpl) CreateRoom(ctx context.Context, m action.CreateRoom
 		return 0, err
 	}
 
+	// the members are used to verify that they can be added by the user.
+	members := make([]domain.User, 0, len(m.RoomMemberIDs))
+	for _, id := range m.RoomMemberIDs {
+		if id == user.ID {
+			continue
+		}
+		member, err := s.users.Find(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		members = append(members, member)
+	}
+
 	err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
-		room, err := domain.NewRoom(
-			ctx, s.rooms, m.RoomName,
-			&user, domain.NewUserIDSet(m.RoomMemberIDs...),
-		)
+		room, err := domain.NewRoomWithMembers(ctx, s.rooms, m.RoomName, &user, members)
 		if err != nil {
 			return nil, err
 		}
@@ -183,6 +469,42 @@ func (s *CommandServiceImpl) DeleteRoom(ctx context.Context, m action.DeleteRoom
 	return roomID, err
 }
 
+// implements FindOrCreateTalkRoom for CommandService interface.
+func (s *CommandServiceImpl) FindOrCreateTalkRoom(ctx context.Context, m action.FindOrCreateTalkRoom) (*result.FindOrCreateTalkRoom, error) {
+	res := &result.FindOrCreateTalkRoom{}
+	err := s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		user, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		friend, err := s.users.Find(ctx, m.FriendID)
+		if err != nil {
+			return nil, err
+		}
+
+		// the talk room is unique for the pair of the users.
+		found, ok, err := domain.FindTalkRoom(ctx, s.rooms, &user, friend)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res.RoomID = found.ID
+			return nil, nil
+		}
+
+		room, err := domain.NewTalkRoom(ctx, s.rooms, &user, friend)
+		if err != nil {
+			return nil, err
+		}
+		res.RoomID, res.Created = room.ID, true
+		return room.Events(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // implements AddRoomMember for CommandService interface.
 func (s *CommandServiceImpl) AddRoomMember(ctx context.Context, m action.AddRoomMember) (*result.AddRoomMember, error) {
 	var err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
@@ -192,18 +514,17 @@ func (s *CommandServiceImpl) AddRoomMember(ctx context.Context, m action.AddRoom
 		}
 
 		// TODO use FindAll?
-		// use commander to verify the AddRoomMember is performed by owner of the room
+		// use commander to verify the AddRoomMember is performed by owner or admin of the room
 		commander, err := s.users.Find(ctx, m.SenderID)
 		if err != nil {
 			return nil, err
 		}
-		_ = commander
 		addUser, err := s.users.Find(ctx, m.AddUserID)
 		if err != nil {
 			return nil, err
 		}
 
-		if _, err := room.AddMember(addUser); err != nil {
+		if _, err := room.AddMember(&commander, addUser); err != nil {
 			return nil, err
 		}
 		if _, err := s.rooms.Store(ctx, room); err != nil {
@@ -227,18 +548,17 @@ func (s *CommandServiceImpl) RemoveRoomMember(ctx context.Context, m action.Remo
 		}
 
 		// TODO use FindAll?
-		// use commander to verify the RemoveRoomMember is performed by owner of the room
+		// use commander to verify the RemoveRoomMember is performed by owner or admin of the room
 		commander, err := s.users.Find(ctx, m.SenderID)
 		if err != nil {
 			return nil, err
 		}
-		_ = commander
 		removeUser, err := s.users.Find(ctx, m.RemoveUserID)
 		if err != nil {
 			return nil, err
 		}
 
-		if _, err := room.RemoveMember(removeUser); err != nil {
+		if _, err := room.RemoveMember(&commander, removeUser); err != nil {
 			return nil, err
 		}
 		if _, err := s.rooms.Store(ctx, room); err != nil {
@@ -253,6 +573,175 @@ func (s *CommandServiceImpl) RemoveRoomMember(ctx context.Context, m action.Remo
 	return &result.RemoveRoomMember{RoomID: m.RoomID, UserID: m.RemoveUserID}, nil
 }
 
+// implements RenameRoom for CommandService interface.
+func (s *CommandServiceImpl) RenameRoom(ctx context.Context, m action.RenameRoom) (roomID uint64, err error) {
+	err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		room, err := s.rooms.Find(ctx, m.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		commander, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := room.Rename(&commander, m.RoomName); err != nil {
+			return nil, err
+		}
+		if _, err := s.rooms.Store(ctx, room); err != nil {
+			return nil, err
+		}
+		return room.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.RoomID, nil
+}
+
+// implements ChangeRoomMemberRole for CommandService interface.
+func (s *CommandServiceImpl) ChangeRoomMemberRole(ctx context.Context, m action.ChangeRoomMemberRole) (roomID uint64, err error) {
+	role, err := domain.ParseRoomRole(m.Role)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		room, err := s.rooms.Find(ctx, m.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		commander, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.users.Find(ctx, m.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := room.ChangeMemberRole(&commander, user, role); err != nil {
+			return nil, err
+		}
+		if _, err := s.rooms.Store(ctx, room); err != nil {
+			return nil, err
+		}
+		return room.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.RoomID, nil
+}
+
+// implements LeaveRoom for CommandService interface.
+func (s *CommandServiceImpl) LeaveRoom(ctx context.Context, m action.LeaveRoom) (roomID uint64, err error) {
+	err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		room, err := s.rooms.Find(ctx, m.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := room.Leave(&user); err != nil {
+			return nil, err
+		}
+		if _, err := s.rooms.Store(ctx, room); err != nil {
+			return nil, err
+		}
+		return room.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.RoomID, nil
+}
+
+// implements TransferRoomOwnership for CommandService interface.
+func (s *CommandServiceImpl) TransferRoomOwnership(ctx context.Context, m action.TransferRoomOwnership) (roomID uint64, err error) {
+	err = s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		room, err := s.rooms.Find(ctx, m.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		commander, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		newOwner, err := s.users.Find(ctx, m.NewOwnerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := room.TransferOwnership(&commander, newOwner); err != nil {
+			return nil, err
+		}
+		if _, err := s.rooms.Store(ctx, room); err != nil {
+			return nil, err
+		}
+		return room.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.RoomID, nil
+}
+
+// implements PinMessage for CommandService interface.
+func (s *CommandServiceImpl) PinMessage(ctx context.Context, m action.PinMessage) (roomID uint64, err error) {
+	return s.updateRoomPins(ctx, m.SenderID, m.RoomID, m.MessageID, func(room *domain.Room, commander *domain.User, msg domain.Message) error {
+		_, err := room.PinMessage(commander, msg)
+		return err
+	})
+}
+
+// implements UnpinMessage for CommandService interface.
+func (s *CommandServiceImpl) UnpinMessage(ctx context.Context, m action.UnpinMessage) (roomID uint64, err error) {
+	return s.updateRoomPins(ctx, m.SenderID, m.RoomID, m.MessageID, func(room *domain.Room, commander *domain.User, msg domain.Message) error {
+		_, err := room.UnpinMessage(commander, msg)
+		return err
+	})
+}
+
+// updateRoomPins changes the pins of the room by pinFunc with
+// the commander and the message, then stores the room and its events.
+// It returns the room ID and error if any.
+func (s *CommandServiceImpl) updateRoomPins(
+	ctx context.Context,
+	commanderID, roomID, msgID uint64,
+	pinFunc func(room *domain.Room, commander *domain.User, msg domain.Message) error,
+) (uint64, error) {
+	err := s.withEventTransaction(ctx, s.rooms, func(ctx context.Context) ([]event.Event, error) {
+		room, err := s.rooms.Find(ctx, roomID)
+		if err != nil {
+			return nil, err
+		}
+		commander, err := s.users.Find(ctx, commanderID)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := s.findRoomMessage(ctx, room.ID, msgID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pinFunc(&room, &commander, msg); err != nil {
+			return nil, err
+		}
+		if _, err := s.rooms.Store(ctx, room); err != nil {
+			return nil, err
+		}
+		return room.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return roomID, nil
+}
+
 // Post the message to the specified room.
 // It returns posted message id and nil or error
 // which indicates the message can not be posted.
@@ -268,17 +757,167 @@ func (s *CommandServiceImpl) PostRoomMessage(ctx context.Context, m action.ChatM
 	}
 
 	err = s.withEventTransaction(ctx, s.msgs, func(ctx context.Context) ([]event.Event, error) {
-		msg, err := domain.NewRoomMessage(ctx, s.msgs, user, room, m.Content)
+		attachments, err := s.findAttachments(ctx, m.AttachmentIDs)
 		if err != nil {
 			return nil, err
 		}
+
+		var msg domain.Message
+		if m.ParentID != 0 {
+			// the message is a reply in the thread.
+			parent, err := s.findRoomMessage(ctx, room.ID, m.ParentID)
+			if err != nil {
+				return nil, err
+			}
+			msg, err = domain.NewThreadMessage(ctx, s.msgs, s.users, user, room, &parent, m.Content, attachments...)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			msg, err = domain.NewRoomMessage(ctx, s.msgs, s.users, user, room, m.Content, attachments...)
+			if err != nil {
+				return nil, err
+			}
+		}
 		msgID = msg.ID
 
+		for _, a := range attachments {
+			if _, err := s.attachments.Store(ctx, *a); err != nil {
+				return nil, err
+			}
+		}
+
 		return msg.Events(), nil
 	})
 	return msgID, err
 }
 
+// findAttachments finds the attachments specified by the IDs.
+// It returns NotFoundError if any of them is not found.
+func (s *CommandServiceImpl) findAttachments(ctx context.Context, ids []uint64) ([]*domain.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > domain.MaxMessageAttachments {
+		return nil, domain.NewValidationError("the message can not have more than %d attachments", domain.MaxMessageAttachments)
+	}
+	attachments := make([]*domain.Attachment, 0, len(ids))
+	for _, id := range ids {
+		a, err := s.attachments.Find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	return attachments, nil
+}
+
+// findRoomMessage finds the message which belongs to the specified room.
+// It returns NotFoundError if the message is not in the room.
+func (s *CommandServiceImpl) findRoomMessage(ctx context.Context, roomID, msgID uint64) (domain.Message, error) {
+	msg, err := s.msgs.Find(ctx, msgID)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	if msg.RoomID != roomID {
+		return domain.Message{}, NewNotFoundError("message (id=%v) is not found in the room (id=%v)", msgID, roomID)
+	}
+	return msg, nil
+}
+
+// Edit the content of the message in the specified room.
+// It returns edited message id and nil or error
+// which indicates the message can not be edited.
+func (s *CommandServiceImpl) EditRoomMessage(ctx context.Context, m action.EditChatMessage) (msgID uint64, err error) {
+	user, room, err := s.findUserAndRoom(ctx, m.SenderID, m.RoomID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withEventTransaction(ctx, s.msgs, func(ctx context.Context) ([]event.Event, error) {
+		msg, err := s.findRoomMessage(ctx, room.ID, m.MessageID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := msg.Edit(ctx, s.msgs, &user, room, m.Content); err != nil {
+			return nil, err
+		}
+		msgID = msg.ID
+
+		return msg.Events(), nil
+	})
+	return msgID, err
+}
+
+// Delete the message in the specified room.
+// It returns deleted message id and nil or error
+// which indicates the message can not be deleted.
+func (s *CommandServiceImpl) DeleteRoomMessage(ctx context.Context, m action.DeleteChatMessage) (msgID uint64, err error) {
+	user, room, err := s.findUserAndRoom(ctx, m.SenderID, m.RoomID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withEventTransaction(ctx, s.msgs, func(ctx context.Context) ([]event.Event, error) {
+		msg, err := s.findRoomMessage(ctx, room.ID, m.MessageID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := msg.Delete(ctx, s.msgs, &user, room); err != nil {
+			return nil, err
+		}
+		msgID = msg.ID
+
+		return msg.Events(), nil
+	})
+	return msgID, err
+}
+
+// implements AddReaction for CommandService interface.
+func (s *CommandServiceImpl) AddReaction(ctx context.Context, m action.AddReaction) (msgID uint64, err error) {
+	return s.updateReaction(ctx, m.SenderID, m.RoomID, m.MessageID, func(ctx context.Context, msg *domain.Message, user *domain.User, room domain.Room) error {
+		return msg.AddReaction(ctx, s.msgs, user, room, m.Emoji)
+	})
+}
+
+// implements RemoveReaction for CommandService interface.
+func (s *CommandServiceImpl) RemoveReaction(ctx context.Context, m action.RemoveReaction) (msgID uint64, err error) {
+	return s.updateReaction(ctx, m.SenderID, m.RoomID, m.MessageID, func(ctx context.Context, msg *domain.Message, user *domain.User, room domain.Room) error {
+		return msg.RemoveReaction(ctx, s.msgs, user, room, m.Emoji)
+	})
+}
+
+// updateReaction changes the reactions of the message in the room
+// by reactFunc with the user, then stores its events.
+// It returns the message ID and error if any.
+func (s *CommandServiceImpl) updateReaction(
+	ctx context.Context,
+	userID, roomID, msgID uint64,
+	reactFunc func(ctx context.Context, msg *domain.Message, user *domain.User, room domain.Room) error,
+) (uint64, error) {
+	user, room, err := s.findUserAndRoom(ctx, userID, roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withEventTransaction(ctx, s.msgs, func(ctx context.Context) ([]event.Event, error) {
+		msg, err := s.findRoomMessage(ctx, room.ID, msgID)
+		if err != nil {
+			return nil, err
+		}
+		if err := reactFunc(ctx, &msg, &user, room); err != nil {
+			return nil, err
+		}
+		return msg.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return msgID, nil
+}
+
 // Mark the message is read by the specified user.
 // It returns updated room ID or error when the message can not be marked to read.
 func (s *CommandServiceImpl) ReadRoomMessages(ctx context.Context, m action.ReadMessages) (uint64, error) {
@@ -308,3 +947,196 @@ func (s *CommandServiceImpl) ReadRoomMessages(ctx context.Context, m action.Read
 	})
 	return m.RoomID, txErr
 }
+
+// It notifies that the user starts typing in the room.
+// The UserTypingStarted event is ephemeral, so it is just published
+// without storing into the EventRepository.
+// It returns the room ID and error if any.
+func (s *CommandServiceImpl) StartTyping(ctx context.Context, m action.TypeStart) (uint64, error) {
+	user, room, err := s.findUserAndRoom(ctx, m.SenderID, m.RoomID)
+	if err != nil {
+		return 0, err
+	}
+
+	ev, err := room.StartTypingBy(&user)
+	if err != nil {
+		return 0, err
+	}
+	s.pubsub.Pub(ev)
+	return room.ID, nil
+}
+
+// It notifies that the user ends typing in the room.
+// The UserTypingEnded event is ephemeral, so it is just published
+// without storing into the EventRepository.
+// It returns the room ID and error if any.
+func (s *CommandServiceImpl) EndTyping(ctx context.Context, m action.TypeEnd) (uint64, error) {
+	user, room, err := s.findUserAndRoom(ctx, m.SenderID, m.RoomID)
+	if err != nil {
+		return 0, err
+	}
+
+	ev, err := room.EndTypingBy(&user)
+	if err != nil {
+		return 0, err
+	}
+	s.pubsub.Pub(ev)
+	return room.ID, nil
+}
+
+// implements CreateUser for CommandService interface.
+func (s *CommandServiceImpl) CreateUser(ctx context.Context, m action.CreateUser) (userID uint64, err error) {
+	// hashing is done before the transaction since it takes a time.
+	password, err := s.hasher.Hash(m.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withEventTransaction(ctx, s.users, func(ctx context.Context) ([]event.Event, error) {
+		user, err := domain.NewUser(
+			ctx, s.users, m.Name, m.FirstName, m.LastName,
+			password, domain.NewUserIDSet(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		userID = user.ID
+		return user.Events(), nil
+	})
+	return userID, err
+}
+
+// implements UpdateUserProfile for CommandService interface.
+func (s *CommandServiceImpl) UpdateUserProfile(ctx context.Context, m action.UpdateUserProfile) (userID uint64, err error) {
+	err = s.withEventTransaction(ctx, s.users, func(ctx context.Context) ([]event.Event, error) {
+		user, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := user.UpdateProfile(ctx, s.users, m.Name, m.FirstName, m.LastName); err != nil {
+			return nil, err
+		}
+		if _, err := s.users.Store(ctx, user); err != nil {
+			return nil, err
+		}
+		return user.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.SenderID, nil
+}
+
+// implements DeleteUser for CommandService interface.
+func (s *CommandServiceImpl) DeleteUser(ctx context.Context, m action.DeleteUser) (userID uint64, err error) {
+	err = s.withEventTransaction(ctx, s.users, func(ctx context.Context) ([]event.Event, error) {
+		user, err := s.users.Find(ctx, m.SenderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := user.Delete(ctx, s.users); err != nil {
+			return nil, err
+		}
+		return user.Events(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return m.SenderID, nil
+}
+
+// updateUserRelation changes the relation between the sender and
+// the other user by relateFunc, then stores both of them and
+// their events. It returns the other user's ID and error if any.
+func (s *CommandServiceImpl) updateUserRelation(
+	ctx context.Context,
+	senderID, userID uint64,
+	relateFunc func(sender, user *domain.User) error,
+) (uint64, error) {
+	err := s.withEventTransaction(ctx, s.users, func(ctx context.Context) ([]event.Event, error) {
+		sender, err := s.users.Find(ctx, senderID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.users.Find(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := relateFunc(&sender, &user); err != nil {
+			return nil, err
+		}
+		for _, u := range []domain.User{sender, user} {
+			if _, err := s.users.Store(ctx, u); err != nil {
+				return nil, err
+			}
+		}
+		return append(sender.Events(), user.Events()...), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// implements SendFriendRequest for CommandService interface.
+func (s *CommandServiceImpl) SendFriendRequest(ctx context.Context, m action.SendFriendRequest) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.RequestFriend(user)
+		return err
+	})
+}
+
+// implements AcceptFriendRequest for CommandService interface.
+func (s *CommandServiceImpl) AcceptFriendRequest(ctx context.Context, m action.AcceptFriendRequest) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.AcceptFriendRequest(user)
+		return err
+	})
+}
+
+// implements DeclineFriendRequest for CommandService interface.
+func (s *CommandServiceImpl) DeclineFriendRequest(ctx context.Context, m action.DeclineFriendRequest) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.DeclineFriendRequest(user)
+		return err
+	})
+}
+
+// implements CancelFriendRequest for CommandService interface.
+func (s *CommandServiceImpl) CancelFriendRequest(ctx context.Context, m action.CancelFriendRequest) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.CancelFriendRequest(user)
+		return err
+	})
+}
+
+// implements RemoveFriend for CommandService interface.
+func (s *CommandServiceImpl) RemoveFriend(ctx context.Context, m action.RemoveFriend) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.Unfriend(user)
+		return err
+	})
+}
+
+// implements BlockUser for CommandService interface.
+func (s *CommandServiceImpl) BlockUser(ctx context.Context, m action.BlockUser) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.Block(user)
+		return err
+	})
+}
+
+// implements UnblockUser for CommandService interface.
+func (s *CommandServiceImpl) UnblockUser(ctx context.Context, m action.UnblockUser) (userID uint64, err error) {
+	return s.updateUserRelation(ctx, m.SenderID, m.UserID, func(sender, user *domain.User) error {
+		_, err := sender.Unblock(user)
+		return err
+	})
+}
+
+func (s *CommandServiceImpl) findUserAndRoom(ctx context.Context, userID, roomID uint64) (domain.User, domain.Room, error) {
+	return findUserAndRoom(ctx, s.users, s.rooms, userID, roomID)
+}
@@ -2,6 +2,8 @@ package chat
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -47,7 +49,7 @@ func TestChatUpdateServiceAtRoomDeleted(t *testing.T) {
 
 	roomDeleted := make(chan interface{}, 1)
 	pubsub.EXPECT().
-		Sub(event.TypeRoomDeleted).
+		Sub(event.TypeRoomDeleted, event.TypeUserDeleted).
 		Return(roomDeleted).
 		Times(1)
 
@@ -93,6 +95,111 @@ func TestChatUpdateServiceAtRoomDeleted(t *testing.T) {
 	}
 }
 
+func TestChatUpdateServiceAtUserDeleted(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		DeletedUserID = uint64(1)
+		FriendID      = uint64(2)
+		OtherUserID   = uint64(3)
+		TalkRoomID    = uint64(10)
+		RoomID        = uint64(11)
+	)
+
+	deletions := make(chan interface{}, 1)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Sub(event.TypeRoomDeleted, event.TypeUserDeleted).
+		Return(deletions).
+		Times(1)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	// set timeout 10ms for testing.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	doneCh := make(chan struct{}, 1)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(2)
+	rooms.EXPECT().
+		FindAllByUserID(gomock.Any(), DeletedUserID).
+		Return([]domain.Room{
+			{ID: TalkRoomID, IsTalkRoom: true, MemberIDSet: domain.NewUserIDSet(DeletedUserID, FriendID)},
+			{ID: RoomID, OwnerID: DeletedUserID, MemberIDSet: domain.NewUserIDSet(DeletedUserID, OtherUserID)},
+		}, nil).
+		Times(1)
+	rooms.EXPECT().
+		Remove(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if r.ID != TalkRoomID {
+				t.Errorf("different removed room, expect: %v, got: %v", TalkRoomID, r.ID)
+			}
+		}).
+		Return(nil).
+		Times(1)
+	rooms.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if r.MemberIDSet.Has(DeletedUserID) {
+				t.Error("deleted user still remains in the room")
+			}
+			if r.OwnerID != OtherUserID {
+				t.Errorf("the ownership is not transferred, expect: %v, got: %v", OtherUserID, r.OwnerID)
+			}
+		}).
+		Return(RoomID, nil).
+		Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), FriendID).
+		Return(domain.User{ID: FriendID, FriendIDs: domain.NewUserIDSet(DeletedUserID)}, nil).
+		Times(1)
+	users.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.FriendIDs.Has(DeletedUserID) {
+				t.Error("deleted user still remains in the friends")
+			}
+			doneCh <- struct{}{}
+		}).
+		Return(FriendID, nil).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		AnyTimes()
+
+	commandService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub)
+
+	go commandService.RunUpdateService(ctx)
+	defer commandService.CancelUpdateService()
+
+	// pass the UserDeleted to updateService
+	deletions <- event.UserDeleted{UserID: DeletedUserID, FriendIDs: []uint64{FriendID}}
+
+	select {
+	case <-doneCh:
+		// PASS
+	case <-ctx.Done():
+		t.Errorf("timeout to fail")
+	}
+}
+
 func TestCommandServiceCreateRoom(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -223,6 +330,79 @@ func TestCommandServiceDeleteRoom(t *testing.T) {
 	}
 }
 
+func TestCommandServiceFindOrCreateTalkRoom(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		TalkRoom = action.FindOrCreateTalkRoom{
+			SenderID: 1,
+			FriendID: 2,
+		}
+		CreatedRoomID = uint64(3)
+
+		User   = domain.User{ID: TalkRoom.SenderID, FriendIDs: domain.NewUserIDSet(TalkRoom.FriendID)}
+		Friend = domain.User{ID: TalkRoom.FriendID, FriendIDs: domain.NewUserIDSet(TalkRoom.SenderID)}
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), TalkRoom.SenderID).Return(User, nil).Times(2)
+	users.EXPECT().Find(gomock.Any(), TalkRoom.FriendID).Return(Friend, nil).Times(2)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil).Times(2)
+	gomock.InOrder(
+		// case1: not found, then create.
+		rooms.EXPECT().FindAllByUserID(gomock.Any(), TalkRoom.SenderID).
+			Return([]domain.Room{}, nil).Times(1),
+		rooms.EXPECT().Store(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, r domain.Room) {
+				if !r.IsTalkRoom {
+					t.Error("created room is not a talk room")
+				}
+			}).
+			Return(CreatedRoomID, nil).Times(1),
+
+		// case2: found.
+		rooms.EXPECT().FindAllByUserID(gomock.Any(), TalkRoom.SenderID).
+			Return([]domain.Room{
+				{ID: CreatedRoomID, IsTalkRoom: true, MemberIDSet: domain.NewUserIDSet(User.ID, Friend.ID)},
+			}, nil).Times(1),
+	)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().Store(gomock.Any(), IsEvType(event.RoomCreated{})).Return([]uint64{1}, nil).Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(IsEvType(event.RoomCreated{})).Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub)
+
+	// case1: created
+	res, err := cmdService.FindOrCreateTalkRoom(context.Background(), TalkRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RoomID != CreatedRoomID || !res.Created {
+		t.Errorf("different result for creating talk room: %#v", res)
+	}
+
+	// case2: found
+	res, err = cmdService.FindOrCreateTalkRoom(context.Background(), TalkRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RoomID != CreatedRoomID || res.Created {
+		t.Errorf("different result for finding talk room: %#v", res)
+	}
+}
+
 func TestCommandServiceAddRoomMember(t *testing.T) {
 	t.Parallel()
 
@@ -395,59 +575,136 @@ func TestCommandServiceRemoveRoomMember(t *testing.T) {
 	}
 }
 
-func TestCommandServicePostRoomMessage(t *testing.T) {
+func TestCommandServiceRenameRoom(t *testing.T) {
+	t.Parallel()
+
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	var (
-		ChatMessage = action.ChatMessage{
+		RenameRoom = action.RenameRoom{
 			SenderID: 1,
 			RoomID:   1,
-			Content:  "hello",
+			RoomName: "renamed",
 		}
 
-		User = domain.User{ID: ChatMessage.SenderID}
-		Room = domain.Room{ID: ChatMessage.RoomID, OwnerID: User.ID,
-			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Sender = domain.User{ID: RenameRoom.SenderID}
+		Room   = domain.Room{
+			ID:          RenameRoom.RoomID,
+			Name:        "room",
+			OwnerID:     RenameRoom.SenderID,
+			MemberIDSet: domain.NewUserIDSet(RenameRoom.SenderID),
+		}
 	)
 
-	const (
-		NewMsgID = uint64(1)
-	)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.RoomRenamed{})).
+		Times(1)
 
 	rooms := mocks.NewMockRoomRepository(mockCtrl)
 	rooms.EXPECT().
-		Find(gomock.Any(), ChatMessage.RoomID).
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	rooms.EXPECT().
+		Find(gomock.Any(), RenameRoom.RoomID).
 		Return(Room, nil).
 		Times(1)
+	rooms.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if r.Name != RenameRoom.RoomName {
+				t.Errorf("room is not renamed, expect: %v, got: %v", RenameRoom.RoomName, r.Name)
+			}
+		}).
+		Return(Room.ID, nil).
+		Times(1)
 
 	users := mocks.NewMockUserRepository(mockCtrl)
 	users.EXPECT().
-		Find(gomock.Any(), ChatMessage.SenderID).
-		Return(User, nil).
+		Find(gomock.Any(), RenameRoom.SenderID).
+		Return(Sender, nil).
 		Times(1)
 
-	msgs := mocks.NewMockMessageRepository(mockCtrl)
-	beginTx := msgs.EXPECT().
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub)
+
+	// do test function.
+	roomID, err := cmdService.RenameRoom(context.Background(), RenameRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomID != Room.ID {
+		t.Errorf("different room id for RenameRoom, expect: %v, got: %v", Room.ID, roomID)
+	}
+}
+
+func TestCommandServiceChangeRoomMemberRole(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		ChangeRole = action.ChangeRoomMemberRole{
+			SenderID: 1,
+			RoomID:   1,
+			UserID:   2,
+			Role:     "admin",
+		}
+
+		Sender = domain.User{ID: ChangeRole.SenderID}
+		User   = domain.User{ID: ChangeRole.UserID}
+		Room   = domain.Room{
+			ID:          ChangeRole.RoomID,
+			OwnerID:     ChangeRole.SenderID,
+			MemberIDSet: domain.NewUserIDSet(ChangeRole.SenderID, ChangeRole.UserID),
+		}
+	)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.RoomMemberRoleChanged{})).
+		Times(1)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
 		BeginTx(gomock.Any(), gomock.Nil()).
 		Return(domain.EmptyTxBeginner{}, nil).
 		Times(1)
-
-	msgStore := msgs.EXPECT().
+	rooms.EXPECT().
+		Find(gomock.Any(), ChangeRole.RoomID).
+		Return(Room, nil).
+		Times(1)
+	rooms.EXPECT().
 		Store(gomock.Any(), gomock.Any()).
-		Return(NewMsgID, nil).
+		Do(func(_ context.Context, r domain.Room) {
+			if got := r.RoleOf(ChangeRole.UserID); got != domain.RoomRoleAdmin {
+				t.Errorf("role is not changed, expect: %v, got: %v", domain.RoomRoleAdmin, got)
+			}
+		}).
+		Return(Room.ID, nil).
 		Times(1)
 
-	pubsub := mocks.NewMockPubsub(mockCtrl)
-	publishEv := pubsub.EXPECT().
-		Pub(IsEvType(event.MessageCreated{})).
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), ChangeRole.SenderID).
+		Return(Sender, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), ChangeRole.UserID).
+		Return(User, nil).
 		Times(1)
-
-	gomock.InOrder(
-		beginTx,
-		msgStore,
-		publishEv,
-	)
 
 	events := mocks.NewMockEventRepository(mockCtrl)
 	events.EXPECT().
@@ -456,143 +713,1684 @@ func TestCommandServicePostRoomMessage(t *testing.T) {
 		Times(1)
 
 	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
-		UserRepository:    users,
-		RoomRepository:    rooms,
-		MessageRepository: msgs,
-		EventRepository:   events,
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
 	}, pubsub)
 
 	// do test function.
-	msgID, err := cmdService.PostRoomMessage(context.Background(), ChatMessage)
+	roomID, err := cmdService.ChangeRoomMemberRole(context.Background(), ChangeRole)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if msgID != NewMsgID {
-		t.Errorf("different new message id for post room message, expect: %v, got: %v", NewMsgID, msgID)
+	if roomID != Room.ID {
+		t.Errorf("different room id for ChangeRoomMemberRole, expect: %v, got: %v", Room.ID, roomID)
+	}
+
+	// invalid role is rejected before any repository access.
+	ChangeRole.Role = "invalid"
+	if _, err := cmdService.ChangeRoomMemberRole(context.Background(), ChangeRole); err == nil {
+		t.Error("change to invalid role, but no error")
 	}
 }
 
-func TestCommandServiceReadRoomMessages(t *testing.T) {
+func TestCommandServiceLeaveRoom(t *testing.T) {
+	t.Parallel()
+
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	{ // case1 success
-		var (
-			RoomCreated = time.Now()
-
-			ReadMessages = action.ReadMessages{
-				SenderID: 1,
-				RoomID:   1,
-				ReadAt:   RoomCreated.Add(24 * time.Hour),
-			}
-
-			User = domain.User{ID: ReadMessages.SenderID}
-			Room = domain.Room{ID: ReadMessages.RoomID, OwnerID: User.ID, CreatedAt: RoomCreated,
-				MemberIDSet: domain.NewUserIDSet(User.ID), MemberReadTimes: domain.NewTimeSet(User.ID)}
-		)
-
-		users := mocks.NewMockUserRepository(mockCtrl)
-		users.EXPECT().
-			Find(gomock.Any(), ReadMessages.SenderID).
-			Return(User, nil).
-			Times(1)
+	var (
+		LeaveRoom = action.LeaveRoom{
+			SenderID: 1,
+			RoomID:   1,
+		}
+		OtherUserID = uint64(2)
 
-		rooms := mocks.NewMockRoomRepository(mockCtrl)
-		beginTx := rooms.EXPECT().
-			BeginTx(gomock.Any(), gomock.Nil()).
-			Return(domain.EmptyTxBeginner{}, nil).
-			Times(1)
+		Sender = domain.User{ID: LeaveRoom.SenderID}
+		Room   = domain.Room{
+			ID:          LeaveRoom.RoomID,
+			OwnerID:     LeaveRoom.SenderID,
+			MemberIDSet: domain.NewUserIDSet(LeaveRoom.SenderID, OtherUserID),
+		}
+	)
 
-		roomsFind := rooms.EXPECT().
-			Find(gomock.Any(), ReadMessages.RoomID).
-			Return(Room, nil).
-			Times(1)
+	// owner leaves the room, so the ownership is transferred.
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.RoomOwnershipTransferred{}), IsEvType(event.RoomMemberLeft{})).
+		Times(1)
 
-		roomsStore := rooms.EXPECT().
-			Store(gomock.Any(), gomock.Any()).
-			Return(ReadMessages.RoomID, nil).
-			Times(1)
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	rooms.EXPECT().
+		Find(gomock.Any(), LeaveRoom.RoomID).
+		Return(Room, nil).
+		Times(1)
+	rooms.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if r.MemberIDSet.Has(LeaveRoom.SenderID) {
+				t.Error("left user still remains in the room")
+			}
+			if r.OwnerID != OtherUserID {
+				t.Errorf("the ownership is not transferred, expect: %v, got: %v", OtherUserID, r.OwnerID)
+			}
+		}).
+		Return(Room.ID, nil).
+		Times(1)
 
-		pubsub := mocks.NewMockPubsub(mockCtrl)
-		publishEv := pubsub.EXPECT().
-			Pub(IsEvType(event.RoomMessagesReadByUser{})).
-			Times(1)
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), LeaveRoom.SenderID).
+		Return(Sender, nil).
+		Times(1)
 
-		gomock.InOrder(
-			beginTx,
-			roomsFind,
-			roomsStore,
-			publishEv,
-		)
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1, 2}, nil).
+		Times(1)
 
-		events := mocks.NewMockEventRepository(mockCtrl)
-		events.EXPECT().
-			Store(gomock.Any(), gomock.Any()).
-			Return([]uint64{1}, nil).
-			Times(1)
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub)
 
-		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
-			UserRepository:  users,
-			RoomRepository:  rooms,
-			EventRepository: events,
-		}, pubsub)
+	// do test function.
+	roomID, err := cmdService.LeaveRoom(context.Background(), LeaveRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomID != Room.ID {
+		t.Errorf("different room id for LeaveRoom, expect: %v, got: %v", Room.ID, roomID)
+	}
+}
 
-		// do test function.
-		roomID, err := cmdService.ReadRoomMessages(context.Background(), ReadMessages)
-		if err != nil {
-			t.Fatal(err)
+func TestCommandServiceTransferRoomOwnership(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		Transfer = action.TransferRoomOwnership{
+			SenderID:   1,
+			RoomID:     1,
+			NewOwnerID: 2,
 		}
-		if roomID != ReadMessages.RoomID {
-			t.Errorf("different updated room id for read room message, expect: %v, got: %v", ReadMessages.RoomID, roomID)
+
+		Sender   = domain.User{ID: Transfer.SenderID}
+		NewOwner = domain.User{ID: Transfer.NewOwnerID}
+		Room     = domain.Room{
+			ID:          Transfer.RoomID,
+			OwnerID:     Transfer.SenderID,
+			MemberIDSet: domain.NewUserIDSet(Transfer.SenderID, Transfer.NewOwnerID),
 		}
+	)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.RoomOwnershipTransferred{})).
+		Times(1)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	rooms.EXPECT().
+		Find(gomock.Any(), Transfer.RoomID).
+		Return(Room, nil).
+		Times(1)
+	rooms.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if r.OwnerID != Transfer.NewOwnerID {
+				t.Errorf("the ownership is not transferred, expect: %v, got: %v", Transfer.NewOwnerID, r.OwnerID)
+			}
+		}).
+		Return(Room.ID, nil).
+		Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), Transfer.SenderID).
+		Return(Sender, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), Transfer.NewOwnerID).
+		Return(NewOwner, nil).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub)
+
+	// do test function.
+	roomID, err := cmdService.TransferRoomOwnership(context.Background(), Transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomID != Room.ID {
+		t.Errorf("different room id for TransferRoomOwnership, expect: %v, got: %v", Room.ID, roomID)
 	}
+}
 
-	{ // case2: empty ReadAt
-		var (
-			RoomCreated = time.Now()
+func TestCommandServicePinMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
 
-			ReadMessages = action.ReadMessages{
-				SenderID: 1,
-				RoomID:   1,
-				ReadAt:   time.Time{},
+	var (
+		PinMessage = action.PinMessage{
+			SenderID:  1,
+			RoomID:    1,
+			MessageID: 1,
+		}
+
+		Sender  = domain.User{ID: PinMessage.SenderID}
+		Room    = domain.Room{ID: PinMessage.RoomID, OwnerID: Sender.ID, MemberIDSet: domain.NewUserIDSet(Sender.ID)}
+		Message = domain.Message{ID: PinMessage.MessageID, RoomID: Room.ID, UserID: Sender.ID}
+	)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(IsEvType(event.MessagePinned{}))
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+	rooms.EXPECT().Find(gomock.Any(), PinMessage.RoomID).Return(Room, nil)
+	rooms.EXPECT().Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, r domain.Room) {
+			if !r.Pins.Has(PinMessage.MessageID) {
+				t.Errorf("the message is not pinned to the stored room")
 			}
+		}).
+		Return(Room.ID, nil)
 
-			User = domain.User{ID: ReadMessages.SenderID}
-			Room = domain.Room{ID: ReadMessages.RoomID, OwnerID: User.ID, CreatedAt: RoomCreated,
-				MemberIDSet: domain.NewUserIDSet(User.ID), MemberReadTimes: domain.NewTimeSet(User.ID)}
-		)
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), PinMessage.SenderID).Return(Sender, nil)
 
-		users := mocks.NewMockUserRepository(mockCtrl)
-		users.EXPECT().Find(gomock.Any(), ReadMessages.SenderID).
-			Return(User, nil)
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().Find(gomock.Any(), PinMessage.MessageID).Return(Message, nil)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub)
+
+	// do test function.
+	roomID, err := cmdService.PinMessage(context.Background(), PinMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomID != Room.ID {
+		t.Errorf("different room id for PinMessage, expect: %v, got: %v", Room.ID, roomID)
+	}
+}
+
+func TestCommandServiceUnpinMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		UnpinMessage = action.UnpinMessage{
+			SenderID:  1,
+			RoomID:    1,
+			MessageID: 1,
+		}
+
+		Sender  = domain.User{ID: UnpinMessage.SenderID}
+		Member  = domain.User{ID: 2}
+		Room    = domain.Room{ID: UnpinMessage.RoomID, OwnerID: Sender.ID, MemberIDSet: domain.NewUserIDSet(Sender.ID, Member.ID)}
+		Message = domain.Message{ID: UnpinMessage.MessageID, RoomID: Room.ID, UserID: Sender.ID}
+	)
+	Room.Pins.Add(domain.Pin{MessageID: Message.ID, PinnedBy: Sender.ID})
+
+	{ // case1: success
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(IsEvType(event.MessageUnpinned{}))
 
 		rooms := mocks.NewMockRoomRepository(mockCtrl)
 		rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
-		rooms.EXPECT().Find(gomock.Any(), ReadMessages.RoomID).Return(Room, nil)
-		rooms.EXPECT().Store(gomock.Any(), gomock.Any()).Return(ReadMessages.RoomID, nil)
+		rooms.EXPECT().Find(gomock.Any(), UnpinMessage.RoomID).Return(Room, nil)
+		rooms.EXPECT().Store(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, r domain.Room) {
+				if r.Pins.Has(UnpinMessage.MessageID) {
+					t.Errorf("the message is still pinned to the stored room")
+				}
+			}).
+			Return(Room.ID, nil)
 
-		pubsub := mocks.NewMockPubsub(mockCtrl)
-		pubsub.EXPECT().Pub(IsEvType(event.RoomMessagesReadByUser{}))
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), UnpinMessage.SenderID).Return(Sender, nil)
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().Find(gomock.Any(), UnpinMessage.MessageID).Return(Message, nil)
 
 		events := mocks.NewMockEventRepository(mockCtrl)
 		events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
 
 		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
-			UserRepository:  users,
-			RoomRepository:  rooms,
-			EventRepository: events,
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+			EventRepository:   events,
 		}, pubsub)
 
 		// do test function.
-		roomID, err := cmdService.ReadRoomMessages(context.Background(), ReadMessages)
+		roomID, err := cmdService.UnpinMessage(context.Background(), UnpinMessage)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if roomID != ReadMessages.RoomID {
-			t.Errorf("different updated room id for read room message, expect: %v, got: %v", ReadMessages.RoomID, roomID)
+		if roomID != Room.ID {
+			t.Errorf("different room id for UnpinMessage, expect: %v, got: %v", Room.ID, roomID)
 		}
-		if got, ok := Room.MemberReadTimes.Get(User.ID); !ok || got == (time.Time{}) {
-			t.Errorf("after read by user, the read time in the room is not changed")
+	}
+
+	{ // case2: unpinned by not an admin
+		Room.Pins = domain.NewPinSet(domain.Pin{MessageID: Message.ID, PinnedBy: Sender.ID})
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		rooms.EXPECT().Find(gomock.Any(), UnpinMessage.RoomID).Return(Room, nil)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), Member.ID).Return(Member, nil)
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().Find(gomock.Any(), UnpinMessage.MessageID).Return(Message, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+		}, mocks.NewMockPubsub(mockCtrl))
+
+		unpinByMember := UnpinMessage
+		unpinByMember.SenderID = Member.ID
+
+		// do test function.
+		_, err := cmdService.UnpinMessage(context.Background(), unpinByMember)
+		if !domain.IsPermissionError(err) {
+			t.Errorf("unpinned by not an admin, expect PermissionError, got: %#v", err)
+		}
+	}
+}
+
+func TestCommandServicePostRoomMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		ChatMessage = action.ChatMessage{
+			SenderID: 1,
+			RoomID:   1,
+			Content:  "hello",
+		}
+
+		User = domain.User{ID: ChatMessage.SenderID}
+		Room = domain.Room{ID: ChatMessage.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+	)
+
+	const (
+		NewMsgID = uint64(1)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		Find(gomock.Any(), ChatMessage.RoomID).
+		Return(Room, nil).
+		Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), ChatMessage.SenderID).
+		Return(User, nil).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	beginTx := msgs.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+
+	msgStore := msgs.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return(NewMsgID, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	publishEv := pubsub.EXPECT().
+		Pub(IsEvType(event.MessageCreated{})).
+		Times(1)
+
+	gomock.InOrder(
+		beginTx,
+		msgStore,
+		publishEv,
+	)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub)
+
+	// do test function.
+	msgID, err := cmdService.PostRoomMessage(context.Background(), ChatMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID != NewMsgID {
+		t.Errorf("different new message id for post room message, expect: %v, got: %v", NewMsgID, msgID)
+	}
+}
+
+func TestCommandServicePostRoomMessageWithAttachments(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		ChatMessage = action.ChatMessage{
+			SenderID:      1,
+			RoomID:        1,
+			Content:       "see the file",
+			AttachmentIDs: []uint64{2},
+		}
+
+		User = domain.User{ID: ChatMessage.SenderID}
+		Room = domain.Room{ID: ChatMessage.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Attachment = domain.Attachment{ID: 2, UserID: User.ID, RoomID: Room.ID}
+	)
+
+	const (
+		NewMsgID = uint64(1)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		Find(gomock.Any(), ChatMessage.RoomID).
+		Return(Room, nil).
+		Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), ChatMessage.SenderID).
+		Return(User, nil).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	msgs.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, m domain.Message) {
+			if !reflect.DeepEqual(m.AttachmentIDs, ChatMessage.AttachmentIDs) {
+				t.Errorf("different attachment IDs of the message, expect: %v, got: %v", ChatMessage.AttachmentIDs, m.AttachmentIDs)
+			}
+		}).
+		Return(NewMsgID, nil).
+		Times(1)
+
+	attachments := mocks.NewMockAttachmentRepository(mockCtrl)
+	attachments.EXPECT().
+		Find(gomock.Any(), Attachment.ID).
+		Return(Attachment, nil).
+		Times(1)
+	attachments.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, a domain.Attachment) {
+			if a.ID != Attachment.ID || a.MessageID != NewMsgID {
+				t.Errorf("the attachment is not attached to the message, got: %#v", a)
+			}
+		}).
+		Return(Attachment.ID, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.MessageCreated{})).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:       users,
+		RoomRepository:       rooms,
+		MessageRepository:    msgs,
+		AttachmentRepository: attachments,
+		EventRepository:      events,
+	}, pubsub)
+
+	msgID, err := cmdService.PostRoomMessage(context.Background(), ChatMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID != NewMsgID {
+		t.Errorf("different new message id for post room message, expect: %v, got: %v", NewMsgID, msgID)
+	}
+}
+
+func TestCommandServicePostRoomMessageReply(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		ChatMessage = action.ChatMessage{
+			SenderID: 1,
+			RoomID:   1,
+			Content:  "reply",
+			ParentID: 2,
+		}
+
+		User = domain.User{ID: ChatMessage.SenderID}
+		Room = domain.Room{ID: ChatMessage.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Parent = domain.Message{ID: ChatMessage.ParentID, RoomID: Room.ID, UserID: User.ID}
+	)
+
+	const (
+		NewMsgID = uint64(3)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().
+		Find(gomock.Any(), ChatMessage.RoomID).
+		Return(Room, nil).
+		Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), ChatMessage.SenderID).
+		Return(User, nil).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	msgs.EXPECT().
+		Find(gomock.Any(), Parent.ID).
+		Return(Parent, nil).
+		Times(1)
+
+	// stores the reply and recounts the replies of the parent.
+	replyStore := msgs.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return(NewMsgID, nil).
+		Times(1)
+	recount := msgs.EXPECT().
+		RecountReplies(gomock.Any(), Parent.ID).
+		Return(domain.Message{ID: Parent.ID, RoomID: Room.ID, ReplyCount: 1}, nil).
+		Times(1)
+	gomock.InOrder(replyStore, recount)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.MessageCreated{})).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub)
+
+	msgID, err := cmdService.PostRoomMessage(context.Background(), ChatMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID != NewMsgID {
+		t.Errorf("different new message id for reply, expect: %v, got: %v", NewMsgID, msgID)
+	}
+}
+
+func TestCommandServiceReadRoomMessages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	{ // case1 success
+		var (
+			RoomCreated = time.Now()
+
+			ReadMessages = action.ReadMessages{
+				SenderID: 1,
+				RoomID:   1,
+				ReadAt:   RoomCreated.Add(24 * time.Hour),
+			}
+
+			User = domain.User{ID: ReadMessages.SenderID}
+			Room = domain.Room{ID: ReadMessages.RoomID, OwnerID: User.ID, CreatedAt: RoomCreated,
+				MemberIDSet: domain.NewUserIDSet(User.ID), MemberReadTimes: domain.NewTimeSet(User.ID)}
+		)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().
+			Find(gomock.Any(), ReadMessages.SenderID).
+			Return(User, nil).
+			Times(1)
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		beginTx := rooms.EXPECT().
+			BeginTx(gomock.Any(), gomock.Nil()).
+			Return(domain.EmptyTxBeginner{}, nil).
+			Times(1)
+
+		roomsFind := rooms.EXPECT().
+			Find(gomock.Any(), ReadMessages.RoomID).
+			Return(Room, nil).
+			Times(1)
+
+		roomsStore := rooms.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Return(ReadMessages.RoomID, nil).
+			Times(1)
+
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		publishEv := pubsub.EXPECT().
+			Pub(IsEvType(event.RoomMessagesReadByUser{})).
+			Times(1)
+
+		gomock.InOrder(
+			beginTx,
+			roomsFind,
+			roomsStore,
+			publishEv,
+		)
+
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Return([]uint64{1}, nil).
+			Times(1)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:  users,
+			RoomRepository:  rooms,
+			EventRepository: events,
+		}, pubsub)
+
+		// do test function.
+		roomID, err := cmdService.ReadRoomMessages(context.Background(), ReadMessages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if roomID != ReadMessages.RoomID {
+			t.Errorf("different updated room id for read room message, expect: %v, got: %v", ReadMessages.RoomID, roomID)
+		}
+	}
+
+	{ // case2: empty ReadAt
+		var (
+			RoomCreated = time.Now()
+
+			ReadMessages = action.ReadMessages{
+				SenderID: 1,
+				RoomID:   1,
+				ReadAt:   time.Time{},
+			}
+
+			User = domain.User{ID: ReadMessages.SenderID}
+			Room = domain.Room{ID: ReadMessages.RoomID, OwnerID: User.ID, CreatedAt: RoomCreated,
+				MemberIDSet: domain.NewUserIDSet(User.ID), MemberReadTimes: domain.NewTimeSet(User.ID)}
+		)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), ReadMessages.SenderID).
+			Return(User, nil)
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		rooms.EXPECT().Find(gomock.Any(), ReadMessages.RoomID).Return(Room, nil)
+		rooms.EXPECT().Store(gomock.Any(), gomock.Any()).Return(ReadMessages.RoomID, nil)
+
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(IsEvType(event.RoomMessagesReadByUser{}))
+
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:  users,
+			RoomRepository:  rooms,
+			EventRepository: events,
+		}, pubsub)
+
+		// do test function.
+		roomID, err := cmdService.ReadRoomMessages(context.Background(), ReadMessages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if roomID != ReadMessages.RoomID {
+			t.Errorf("different updated room id for read room message, expect: %v, got: %v", ReadMessages.RoomID, roomID)
+		}
+		if got, ok := Room.MemberReadTimes.Get(User.ID); !ok || got == (time.Time{}) {
+			t.Errorf("after read by user, the read time in the room is not changed")
+		}
+	}
+}
+
+func TestCommandServiceEditRoomMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		EditChatMessage = action.EditChatMessage{
+			MessageID: 1,
+			RoomID:    1,
+			SenderID:  1,
+			Content:   "edited",
+		}
+
+		User = domain.User{ID: EditChatMessage.SenderID}
+		Room = domain.Room{ID: EditChatMessage.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Message = domain.Message{ID: EditChatMessage.MessageID, RoomID: Room.ID,
+			UserID: User.ID, Content: "hello"}
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), EditChatMessage.RoomID).Return(Room, nil).Times(1)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		Find(gomock.Any(), EditChatMessage.SenderID).
+		Return(User, nil).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	beginTx := msgs.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+
+	msgFind := msgs.EXPECT().
+		Find(gomock.Any(), EditChatMessage.MessageID).
+		Return(Message, nil).
+		Times(1)
+
+	msgStore := msgs.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, m domain.Message) {
+			if m.Content != EditChatMessage.Content {
+				t.Errorf("stored message has different content, expect: %v, got: %v", EditChatMessage.Content, m.Content)
+			}
+		}).
+		Return(Message.ID, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	publishEv := pubsub.EXPECT().
+		Pub(IsEvType(event.MessageEdited{})).
+		Times(1)
+
+	gomock.InOrder(
+		beginTx,
+		msgFind,
+		msgStore,
+		publishEv,
+	)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub)
+
+	// do test function.
+	msgID, err := cmdService.EditRoomMessage(context.Background(), EditChatMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID != Message.ID {
+		t.Errorf("different message id for edit room message, expect: %v, got: %v", Message.ID, msgID)
+	}
+
+	// the author who left the room can not edit the message.
+	left := Room
+	left.MemberIDSet = domain.NewUserIDSet()
+	rooms.EXPECT().Find(gomock.Any(), EditChatMessage.RoomID).Return(left, nil).Times(1)
+	users.EXPECT().Find(gomock.Any(), EditChatMessage.SenderID).Return(User, nil).Times(1)
+	msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil).Times(1)
+	msgs.EXPECT().Find(gomock.Any(), EditChatMessage.MessageID).Return(Message, nil).Times(1)
+	if _, err := cmdService.EditRoomMessage(context.Background(), EditChatMessage); !domain.IsPermissionError(err) {
+		t.Errorf("the message is edited by not a member, expect PermissionError, got: %v", err)
+	}
+}
+
+func TestCommandServiceDeleteRoomMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		DeleteChatMessage = action.DeleteChatMessage{
+			MessageID: 1,
+			RoomID:    1,
+			SenderID:  1,
+		}
+
+		User = domain.User{ID: DeleteChatMessage.SenderID}
+		Room = domain.Room{ID: DeleteChatMessage.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Message = domain.Message{ID: DeleteChatMessage.MessageID, RoomID: Room.ID,
+			UserID: User.ID, Content: "hello"}
+	)
+
+	{ // case1: success
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), DeleteChatMessage.RoomID).Return(Room, nil)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), DeleteChatMessage.SenderID).Return(User, nil)
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		msgs.EXPECT().Find(gomock.Any(), DeleteChatMessage.MessageID).Return(Message, nil)
+		msgs.EXPECT().Store(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, m domain.Message) {
+				if !m.Deleted {
+					t.Errorf("stored message is not marked as deleted")
+				}
+			}).
+			Return(Message.ID, nil)
+
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(IsEvType(event.MessageDeleted{}))
+
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+			EventRepository:   events,
+		}, pubsub)
+
+		// do test function.
+		msgID, err := cmdService.DeleteRoomMessage(context.Background(), DeleteChatMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgID != Message.ID {
+			t.Errorf("different message id for delete room message, expect: %v, got: %v", Message.ID, msgID)
+		}
+	}
+
+	{ // case2: message is not in the room
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), DeleteChatMessage.RoomID).Return(Room, nil)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), DeleteChatMessage.SenderID).Return(User, nil)
+
+		otherRoomMessage := Message
+		otherRoomMessage.RoomID = DeleteChatMessage.RoomID + 1
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		msgs.EXPECT().Find(gomock.Any(), DeleteChatMessage.MessageID).Return(otherRoomMessage, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+		}, mocks.NewMockPubsub(mockCtrl))
+
+		// do test function.
+		_, err := cmdService.DeleteRoomMessage(context.Background(), DeleteChatMessage)
+		if err == nil {
+			t.Fatal("deleting the message in the other room, but no error")
+		}
+		if !IsNotFoundError(err) {
+			t.Errorf("invalid error type, expect: NotFoundError, got: %#v", err)
+		}
+	}
+}
+
+func TestCommandServiceAddReaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		AddReaction = action.AddReaction{
+			MessageID: 1,
+			RoomID:    1,
+			SenderID:  1,
+			Emoji:     ":+1:",
+		}
+
+		User = domain.User{ID: AddReaction.SenderID}
+		Room = domain.Room{ID: AddReaction.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+		Message = domain.Message{ID: AddReaction.MessageID, RoomID: Room.ID, UserID: User.ID}
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), AddReaction.RoomID).Return(Room, nil)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), AddReaction.SenderID).Return(User, nil)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+	msgs.EXPECT().Find(gomock.Any(), AddReaction.MessageID).Return(Message, nil)
+	msgs.EXPECT().Store(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, m domain.Message) {
+			if !m.Reactions.Has(AddReaction.Emoji, User.ID) {
+				t.Errorf("stored message has no added reaction")
+			}
+		}).
+		Return(Message.ID, nil)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(IsEvType(event.ReactionAdded{}))
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub)
+
+	// do test function.
+	msgID, err := cmdService.AddReaction(context.Background(), AddReaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID != Message.ID {
+		t.Errorf("different message id for add reaction, expect: %v, got: %v", Message.ID, msgID)
+	}
+}
+
+func TestCommandServiceRemoveReaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		RemoveReaction = action.RemoveReaction{
+			MessageID: 1,
+			RoomID:    1,
+			SenderID:  1,
+			Emoji:     ":+1:",
+		}
+
+		User = domain.User{ID: RemoveReaction.SenderID}
+		Room = domain.Room{ID: RemoveReaction.RoomID, OwnerID: User.ID,
+			MemberIDSet: domain.NewUserIDSet(User.ID)}
+	)
+
+	{ // case1: success
+		Message := domain.Message{ID: RemoveReaction.MessageID, RoomID: Room.ID, UserID: User.ID}
+		Message.Reactions.Add(RemoveReaction.Emoji, User.ID)
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), RemoveReaction.RoomID).Return(Room, nil)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), RemoveReaction.SenderID).Return(User, nil)
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		msgs.EXPECT().Find(gomock.Any(), RemoveReaction.MessageID).Return(Message, nil)
+		msgs.EXPECT().Store(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, m domain.Message) {
+				if m.Reactions.Has(RemoveReaction.Emoji, User.ID) {
+					t.Errorf("stored message still has removed reaction")
+				}
+			}).
+			Return(Message.ID, nil)
+
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(IsEvType(event.ReactionRemoved{}))
+
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+			EventRepository:   events,
+		}, pubsub)
+
+		// do test function.
+		msgID, err := cmdService.RemoveReaction(context.Background(), RemoveReaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgID != Message.ID {
+			t.Errorf("different message id for remove reaction, expect: %v, got: %v", Message.ID, msgID)
+		}
+	}
+
+	{ // case2: not reacted yet
+		Message := domain.Message{ID: RemoveReaction.MessageID, RoomID: Room.ID, UserID: User.ID}
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), RemoveReaction.RoomID).Return(Room, nil)
+
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), RemoveReaction.SenderID).Return(User, nil)
+
+		msgs := mocks.NewMockMessageRepository(mockCtrl)
+		msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil)
+		msgs.EXPECT().Find(gomock.Any(), RemoveReaction.MessageID).Return(Message, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:    users,
+			RoomRepository:    rooms,
+			MessageRepository: msgs,
+		}, mocks.NewMockPubsub(mockCtrl))
+
+		// do test function.
+		_, err := cmdService.RemoveReaction(context.Background(), RemoveReaction)
+		if !domain.IsValidationError(err) {
+			t.Errorf("removing not reacted emoji, expect ValidationError, got: %#v", err)
+		}
+	}
+}
+
+func TestCommandServiceStartTyping(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		TypeStart = action.TypeStart{}
+		User      = domain.User{ID: 1}
+		Room      = domain.Room{ID: 2, MemberIDSet: domain.NewUserIDSet(User.ID)}
+	)
+	TypeStart.SenderID = User.ID
+	TypeStart.RoomID = Room.ID
+
+	{ // case1: success
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil)
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil)
+
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(IsEvType(event.UserTypingStarted{})).Times(1)
+
+		// typing event is not stored, so EventRepository is not called.
+		events := mocks.NewMockEventRepository(mockCtrl)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository:  users,
+			RoomRepository:  rooms,
+			EventRepository: events,
+		}, pubsub)
+
+		// do test function.
+		roomID, err := cmdService.StartTyping(context.Background(), TypeStart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if roomID != Room.ID {
+			t.Errorf("different room id for start typing, expect: %v, got: %v", Room.ID, roomID)
+		}
+	}
+
+	{ // case2: user is not a member of the room
+		users := mocks.NewMockUserRepository(mockCtrl)
+		users.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil)
+
+		otherRoom := Room
+		otherRoom.MemberIDSet = domain.NewUserIDSet(User.ID + 1)
+
+		rooms := mocks.NewMockRoomRepository(mockCtrl)
+		rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(otherRoom, nil)
+
+		cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+			UserRepository: users,
+			RoomRepository: rooms,
+		}, mocks.NewMockPubsub(mockCtrl))
+
+		// do test function.
+		if _, err := cmdService.StartTyping(context.Background(), TypeStart); err == nil {
+			t.Fatal("typing by the user not in the room, but no error")
+		}
+	}
+}
+
+func TestCommandServiceEndTyping(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		TypeEnd = action.TypeEnd{}
+		User    = domain.User{ID: 1}
+		Room    = domain.Room{ID: 2, MemberIDSet: domain.NewUserIDSet(User.ID)}
+	)
+	TypeEnd.SenderID = User.ID
+	TypeEnd.RoomID = Room.ID
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(IsEvType(event.UserTypingEnded{})).Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: mocks.NewMockEventRepository(mockCtrl),
+	}, pubsub)
+
+	// do test function.
+	roomID, err := cmdService.EndTyping(context.Background(), TypeEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roomID != Room.ID {
+		t.Errorf("different room id for end typing, expect: %v, got: %v", Room.ID, roomID)
+	}
+}
+
+func TestCommandServiceCreateUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		CreateUser = action.CreateUser{
+			Name:     "user",
+			Password: "password",
+		}
+		CreatedUserID = uint64(1)
+		ExistName     = "exist"
+	)
+
+	hasher, err := domain.NewPasswordHasher(domain.DefaultPasswordPolicy, domain.MinPasswordHashCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(2)
+	users.EXPECT().
+		ExistsByName(gomock.Any(), CreateUser.Name).
+		Return(false, nil).
+		Times(1)
+	users.EXPECT().
+		ExistsByName(gomock.Any(), ExistName).
+		Return(true, nil).
+		Times(1)
+	users.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.Name != CreateUser.Name || !u.VerifyPassword(hasher, CreateUser.Password) {
+				t.Errorf("different stored user: %#v", u)
+			}
+			if u.Password.Hash == CreateUser.Password {
+				t.Errorf("stored password is not hashed")
+			}
+		}).
+		Return(CreatedUserID, nil).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), IsEvType(event.UserCreated{})).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.UserCreated{})).
+		Times(1)
+
+	cmdService := NewCommandServiceImplWithHasher(domain.SimpleRepositories{
+		UserRepository:  users,
+		EventRepository: events,
+	}, pubsub, hasher)
+
+	// case1: success
+	userID, err := cmdService.CreateUser(context.Background(), CreateUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != CreatedUserID {
+		t.Errorf("different created user id, expect: %v, got: %v", CreatedUserID, userID)
+	}
+
+	// case2: duplicated name
+	duplicated := CreateUser
+	duplicated.Name = ExistName
+	if _, err := cmdService.CreateUser(context.Background(), duplicated); !domain.IsValidationError(err) {
+		t.Errorf("create user with duplicated name, expect ValidationError, got: %v", err)
+	}
+
+	// case3: password violating the policy
+	weak := CreateUser
+	weak.Password = "short"
+	if _, err := cmdService.CreateUser(context.Background(), weak); !domain.IsValidationError(err) {
+		t.Errorf("create user with weak password, expect ValidationError, got: %v", err)
+	}
+}
+
+func TestCommandServiceUpdateUserProfile(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		UpdateProfile = action.UpdateUserProfile{
+			SenderID:  1,
+			Name:      "new-name",
+			FirstName: "first",
+			LastName:  "last",
+		}
+		Sender = domain.User{ID: UpdateProfile.SenderID, Name: "user"}
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), UpdateProfile.SenderID).
+		Return(Sender, nil).
+		Times(1)
+	users.EXPECT().
+		ExistsByName(gomock.Any(), UpdateProfile.Name).
+		Return(false, nil).
+		Times(1)
+	users.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.Name != UpdateProfile.Name || u.FirstName != UpdateProfile.FirstName || u.LastName != UpdateProfile.LastName {
+				t.Errorf("profile is not updated: %#v", u)
+			}
+		}).
+		Return(Sender.ID, nil).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), IsEvType(event.UserProfileUpdated{})).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.UserProfileUpdated{})).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		EventRepository: events,
+	}, pubsub)
+
+	userID, err := cmdService.UpdateUserProfile(context.Background(), UpdateProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != Sender.ID {
+		t.Errorf("different updated user id, expect: %v, got: %v", Sender.ID, userID)
+	}
+}
+
+func TestCommandServiceDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		DeleteUser = action.DeleteUser{SenderID: 1}
+		Sender     = domain.User{ID: DeleteUser.SenderID, FriendIDs: domain.NewUserIDSet(2)}
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), DeleteUser.SenderID).
+		Return(Sender, nil).
+		Times(1)
+	users.EXPECT().
+		Remove(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.ID != Sender.ID {
+				t.Errorf("different removed user, expect: %v, got: %v", Sender.ID, u.ID)
+			}
+		}).
+		Return(nil).
+		Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), IsEvType(event.UserDeleted{})).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.UserDeleted{})).
+		Do(func(ev event.Event) {
+			deleted := ev.(event.UserDeleted)
+			if deleted.UserID != Sender.ID || len(deleted.FriendIDs) != 1 {
+				t.Errorf("invalid UserDeleted event: %#v", deleted)
+			}
+		}).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		EventRepository: events,
+	}, pubsub)
+
+	userID, err := cmdService.DeleteUser(context.Background(), DeleteUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != Sender.ID {
+		t.Errorf("different deleted user id, expect: %v, got: %v", Sender.ID, userID)
+	}
+}
+
+func TestCommandServiceSendFriendRequest(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		SendRequest = action.SendFriendRequest{SenderID: 1, UserID: 2}
+		Sender      = domain.User{ID: SendRequest.SenderID, EventHolder: domain.NewEventHolder()}
+		Receiver    = domain.User{ID: SendRequest.UserID, EventHolder: domain.NewEventHolder()}
+		BlockingID  = uint64(3)
+		Blocking    = domain.User{ID: BlockingID, BlockedUserIDs: domain.NewUserIDSet(Sender.ID)}
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(2)
+	users.EXPECT().
+		Find(gomock.Any(), Sender.ID).
+		Return(Sender, nil).
+		Times(2)
+	users.EXPECT().
+		Find(gomock.Any(), Receiver.ID).
+		Return(Receiver, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), BlockingID).
+		Return(Blocking, nil).
+		Times(1)
+	users.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.ID == Sender.ID && !u.HasRequestedFriend(Receiver) {
+				t.Errorf("friend request is not stored: %#v", u)
+			}
+		}).
+		Return(uint64(0), nil).
+		Times(2)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), IsEvType(event.FriendRequestSent{})).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.FriendRequestSent{})).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		EventRepository: events,
+	}, pubsub)
+
+	// case1: success
+	userID, err := cmdService.SendFriendRequest(context.Background(), SendRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != Receiver.ID {
+		t.Errorf("different requested user id, expect: %v, got: %v", Receiver.ID, userID)
+	}
+
+	// case2: blocked by the receiver
+	toBlocking := SendRequest
+	toBlocking.UserID = BlockingID
+	if _, err := cmdService.SendFriendRequest(context.Background(), toBlocking); !domain.IsPermissionError(err) {
+		t.Errorf("send request to blocking user, expect PermissionError, got: %v", err)
+	}
+}
+
+func TestCommandServiceAcceptFriendRequest(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		Accept   = action.AcceptFriendRequest{SenderID: 1, UserID: 2}
+		Receiver = domain.User{ID: Accept.SenderID, EventHolder: domain.NewEventHolder()}
+		Sender   = domain.User{ID: Accept.UserID, RequestedFriendIDs: domain.NewUserIDSet(Receiver.ID)}
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().
+		BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), Receiver.ID).
+		Return(Receiver, nil).
+		Times(1)
+	users.EXPECT().
+		Find(gomock.Any(), Sender.ID).
+		Return(Sender, nil).
+		Times(1)
+
+	stored := make(map[uint64]domain.User)
+	users.EXPECT().
+		Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) { stored[u.ID] = u }).
+		Return(uint64(0), nil).
+		Times(2)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().
+		Store(gomock.Any(), IsEvType(event.FriendRequestAccepted{})).
+		Return([]uint64{1}, nil).
+		Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().
+		Pub(IsEvType(event.FriendRequestAccepted{})).
+		Times(1)
+
+	cmdService := NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		EventRepository: events,
+	}, pubsub)
+
+	userID, err := cmdService.AcceptFriendRequest(context.Background(), Accept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != Sender.ID {
+		t.Errorf("different requesting user id, expect: %v, got: %v", Sender.ID, userID)
+	}
+
+	storedReceiver, storedSender := stored[Receiver.ID], stored[Sender.ID]
+	if !storedReceiver.HasFriend(Sender) || !storedSender.HasFriend(Receiver) {
+		t.Errorf("friendship is not mutual, receiver: %#v, sender: %#v", storedReceiver, storedSender)
+	}
+	if storedSender.HasRequestedFriend(Receiver) {
+		t.Errorf("accepted request still remains")
+	}
+}
+
+// outerTx is a transaction object to be distinguished from the
+// no-operating transaction of the nested one.
+type outerTx struct{ domain.EmptyTxBeginner }
+
+func (tx *outerTx) BeginTx(context.Context, *sql.TxOptions) (domain.Tx, error) { return tx, nil }
+
+func TestWithTransactionNested(t *testing.T) {
+	outer := &outerTx{}
+	ctx := domain.SetTx(context.Background(), outer)
+
+	// nested transaction returns no-operating transaction which
+	// must not replace the outer one in the context.
+	err := withTransaction(ctx, domain.EmptyTxBeginner{}, func(ctx context.Context) error {
+		tx, ok := domain.GetTx(ctx)
+		if !ok {
+			t.Fatal("transaction is not found in the context")
+		}
+		if tx != outer {
+			t.Errorf("outer transaction is replaced by nested one, got: %#v", tx)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the top-level transaction is set to the context.
+	err = withTransaction(context.Background(), outer, func(ctx context.Context) error {
+		if tx, ok := domain.GetTx(ctx); !ok || tx != outer {
+			t.Errorf("transaction is not set to the context, got: %#v", tx)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recordingTx is a transaction which records whether it is
+// committed or rollbacked. The Commit returns CommitErr.
+type recordingTx struct {
+	Committed  bool
+	Rollbacked bool
+	CommitErr  error
+}
+
+func (tx *recordingTx) BeginTx(context.Context, *sql.TxOptions) (domain.Tx, error) { return tx, nil }
+func (tx *recordingTx) Commit() error                                              { tx.Committed = true; return tx.CommitErr }
+func (tx *recordingTx) Rollback() error                                            { tx.Rollbacked = true; return nil }
+
+// txEventRepository is a EventRepository which can join the transaction.
+type txEventRepository struct {
+	*mocks.MockEventRepository
+	domain.EmptyTxBeginner
+}
+
+func TestCommandServiceWithEventTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	Events := []event.Event{event.RoomCreated{RoomID: 1}}
+	returnEvents := func(ctx context.Context) ([]event.Event, error) { return Events, nil }
+	expectPublished := func(pubsub *mocks.MockPubsub, seq uint64) {
+		pubsub.EXPECT().
+			Pub(gomock.Any()).
+			Do(func(evs ...event.Event) {
+				if got := event.SequenceOf(evs[0]); got != seq {
+					t.Errorf("published event has different sequence, expect: %v, got: %v", seq, got)
+				}
+			}).
+			Times(1)
+	}
+
+	// case1: events are not stored nor published when commit fails.
+	{
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().Store(gomock.Any(), gomock.Any()).Times(0)
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(gomock.Any()).Times(0)
+
+		cs := NewCommandServiceImpl(domain.SimpleRepositories{EventRepository: events}, pubsub)
+		tx := &recordingTx{CommitErr: errors.New("commit failure")}
+		if err := cs.withEventTransaction(context.Background(), tx, returnEvents); err == nil {
+			t.Fatal("commit failure should return error")
+		}
+	}
+
+	// case2: events are stored after commit if the EventRepository
+	// can not join the transaction.
+	{
+		tx := &recordingTx{}
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Do(func(context.Context, ...event.Event) {
+				if !tx.Committed {
+					t.Error("events are stored before commit")
+				}
+			}).
+			Return([]uint64{10}, nil).
+			Times(1)
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		expectPublished(pubsub, 10)
+
+		cs := NewCommandServiceImpl(domain.SimpleRepositories{EventRepository: events}, pubsub)
+		if err := cs.withEventTransaction(context.Background(), tx, returnEvents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// case3: storing after commit is retried, and the command fails
+	// rather than dropping the events.
+	{
+		events := mocks.NewMockEventRepository(mockCtrl)
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Return(nil, NewInfraError("store failure")).
+			Times(StoreEventsRetries + 1)
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().Pub(gomock.Any()).Times(0)
+
+		cs := NewCommandServiceImpl(domain.SimpleRepositories{EventRepository: events}, pubsub)
+		if err := cs.withEventTransaction(context.Background(), &recordingTx{}, returnEvents); err == nil {
+			t.Fatal("store failure should return error")
+		}
+	}
+
+	// case4: events are stored in the transaction if the EventRepository
+	// can join it, and the failure rollbacks the transaction.
+	{
+		tx := &recordingTx{}
+		events := txEventRepository{MockEventRepository: mocks.NewMockEventRepository(mockCtrl)}
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Do(func(ctx context.Context, _ ...event.Event) {
+				if got, ok := domain.GetTx(ctx); !ok || got != tx || tx.Committed {
+					t.Error("events are not stored in the transaction")
+				}
+			}).
+			Return([]uint64{10}, nil).
+			Times(1)
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Return(nil, NewInfraError("store failure")).
+			Times(1)
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		expectPublished(pubsub, 10)
+
+		cs := NewCommandServiceImpl(domain.SimpleRepositories{EventRepository: events}, pubsub)
+		if err := cs.withEventTransaction(context.Background(), tx, returnEvents); err != nil {
+			t.Fatal(err)
+		}
+
+		tx = &recordingTx{}
+		if err := cs.withEventTransaction(context.Background(), tx, returnEvents); err == nil {
+			t.Fatal("store failure should return error")
+		}
+		if !tx.Rollbacked || tx.Committed {
+			t.Error("store failure does not rollback the transaction")
+		}
+	}
+
+	// case5: the nested transaction publishes the events after the
+	// outer transaction is committed.
+	{
+		outer := &recordingTx{}
+		events := txEventRepository{MockEventRepository: mocks.NewMockEventRepository(mockCtrl)}
+		events.EXPECT().
+			Store(gomock.Any(), gomock.Any()).
+			Return([]uint64{10}, nil).
+			Times(2)
+		pubsub := mocks.NewMockPubsub(mockCtrl)
+		pubsub.EXPECT().
+			Pub(gomock.Any()).
+			Do(func(...event.Event) {
+				if !outer.Committed {
+					t.Error("events are published before the outer transaction is committed")
+				}
+			}).
+			Times(1)
+
+		cs := NewCommandServiceImpl(domain.SimpleRepositories{EventRepository: events}, pubsub)
+		err := withTransaction(context.Background(), outer, func(ctx context.Context) error {
+			return cs.withEventTransaction(ctx, domain.EmptyTxBeginner{}, returnEvents)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// not published when the outer transaction is rollbacked.
+		outer = &recordingTx{}
+		err = withTransaction(context.Background(), outer, func(ctx context.Context) error {
+			if err := cs.withEventTransaction(ctx, domain.EmptyTxBeginner{}, returnEvents); err != nil {
+				return err
+			}
+			return errors.New("outer failure")
+		})
+		if err == nil || !outer.Rollbacked {
+			t.Errorf("outer transaction is not rollbacked, error: %v", err)
 		}
 	}
 }
@@ -3,6 +3,10 @@ package chat
 import (
 	"errors"
 	"fmt"
+
+	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
 )
 
 // TODO distinguish errors from infrastructures and domain.
@@ -58,3 +62,76 @@ func IsNotFoundError(err error) bool {
 		return false
 	}
 }
+
+// ErrNotConnected is the error for the action message sent
+// by the connection which is not connected to the Hub.
+var ErrNotConnected = errors.New("not connected to the server")
+
+// ErrReplayNotSupported is the error for the Hub which can not
+// replay the stored events.
+var ErrReplayNotSupported = errors.New("replaying events is not supported")
+
+// ErrHubShutdown is the error for the Hub which is already shut down.
+var ErrHubShutdown = errors.New("hub is already shut down")
+
+// Error codes for the client to distinguish the kind of
+// the error caused by the action message.
+const (
+	// the action message has invalid data structure.
+	ErrorCodeInvalidAction = "invalid_action"
+
+	// the connection is not connected to the Hub.
+	ErrorCodeNotConnected = "not_connected"
+
+	// the requested data is not found.
+	ErrorCodeNotFound = "not_found"
+
+	// the action is rejected by the domain rules, such as
+	// the user is not a member of the room.
+	ErrorCodeRejected = "rejected"
+
+	// the user is not permitted to perform the action, such as
+	// the room member without the admin role removes other member.
+	ErrorCodeForbidden = "forbidden"
+
+	// the action fails by the internal error.
+	ErrorCodeInternal = "internal_error"
+)
+
+// ErrorCodeOf returns the error code corresponding to the err.
+func ErrorCodeOf(err error) string {
+	switch err.(type) {
+	case NotFoundError, *NotFoundError:
+		return ErrorCodeNotFound
+	case InfraError, *InfraError:
+		return ErrorCodeInternal
+	case domain.PermissionError, *domain.PermissionError:
+		return ErrorCodeForbidden
+	}
+	if err == ErrNotConnected {
+		return ErrorCodeNotConnected
+	}
+	return ErrorCodeRejected
+}
+
+// NewErrorRaised creates the ErrorRaised event from the err caused
+// by handling the action message m.
+// The event contains the error code, action name and correlation ID
+// of m so that the client can find which action is failed.
+// The message of InfraError is hidden by ErrInternalError.
+func NewErrorRaised(err error, m action.ActionMessage) event.ErrorRaised {
+	code := ErrorCodeOf(err)
+	if code == ErrorCodeInternal {
+		err = ErrInternalError
+	}
+	ev := event.ErrorRaised{
+		Message: err.Error(),
+		Code:    code,
+	}
+	if m != nil {
+		ev.Action = string(m.Action())
+		ev.CorrelationID = action.CorrelationIDOf(m)
+	}
+	ev.Occurs()
+	return ev
+}
@@ -3,6 +3,9 @@ package chat
 import (
 	"errors"
 	"testing"
+
+	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/domain"
 )
 
 func TestInfraError(t *testing.T) {
@@ -57,3 +60,59 @@ func TestIsNotFoundError(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorCodeOf(t *testing.T) {
+	for _, tcase := range []struct {
+		Err  error
+		Code string
+	}{
+		{NewNotFoundError(""), ErrorCodeNotFound},
+		{NotFoundError{}, ErrorCodeNotFound},
+		{NewInfraError(""), ErrorCodeInternal},
+		{ErrNotConnected, ErrorCodeNotConnected},
+		{domain.NewPermissionError(""), ErrorCodeForbidden},
+		{errors.New(""), ErrorCodeRejected},
+	} {
+		if got := ErrorCodeOf(tcase.Err); got != tcase.Code {
+			t.Errorf("different error code for %#v, expect: %v, got: %v", tcase.Err, tcase.Code, got)
+		}
+	}
+}
+
+func TestNewErrorRaised(t *testing.T) {
+	const CorrelationID = "correlation-1"
+
+	cm := action.ChatMessage{}
+	cm.ActionName = action.ActionChatMessage
+	cm.CorrelationID = CorrelationID
+
+	{ // case1: error caused by the action message.
+		ev := NewErrorRaised(NewNotFoundError("room not found"), cm)
+		if ev.Code != ErrorCodeNotFound {
+			t.Errorf("different error code, expect: %v, got: %v", ErrorCodeNotFound, ev.Code)
+		}
+		if ev.Action != string(action.ActionChatMessage) {
+			t.Errorf("different action, expect: %v, got: %v", action.ActionChatMessage, ev.Action)
+		}
+		if ev.CorrelationID != CorrelationID {
+			t.Errorf("different correlation ID, expect: %v, got: %v", CorrelationID, ev.CorrelationID)
+		}
+		if ev.Timestamp().IsZero() {
+			t.Error("error event has no timestamp")
+		}
+	}
+
+	{ // case2: infra error is hidden.
+		ev := NewErrorRaised(NewInfraError("database is broken"), cm)
+		if ev.Message != ErrInternalError.Error() {
+			t.Errorf("infra error is not hidden, got: %v", ev.Message)
+		}
+	}
+
+	{ // case3: no action message.
+		ev := NewErrorRaised(errors.New("error"), nil)
+		if ev.Action != "" || ev.CorrelationID != "" {
+			t.Errorf("action fields should be empty, got: %#v", ev)
+		}
+	}
+}
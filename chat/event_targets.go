@@ -0,0 +1,291 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// userFinder finds the user, which is satisfied by both of
+// domain.UserRepository and UserQueryer.
+type userFinder interface {
+	Find(ctx context.Context, userID uint64) (domain.User, error)
+}
+
+// roomFinder finds the room, which is satisfied by both of
+// domain.RoomRepository and RoomQueryer.
+type roomFinder interface {
+	Find(ctx context.Context, roomID uint64) (domain.Room, error)
+}
+
+// eventTargetIDs returns the IDs of the users who can receive the event.
+// It is the permission model for the events, and is used for both of
+// sending the live events and querying the stored events.
+// The room events are sent to only the members who had joined the room
+// when the event occurred, so that the stored events before joining
+// are not exposed to the new member.
+// It returns empty IDs for the event which is not sent to any users.
+func eventTargetIDs(ctx context.Context, users userFinder, rooms roomFinder, ev event.Event) ([]uint64, error) {
+	targetIDs := []uint64{}
+
+	// TODO: integrates these into interfaces, RoomMemberSender and UserFriendSender?
+	switch ev := ev.(type) {
+	case event.MessageCreated:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.MessageEdited:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.MessageDeleted:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.ReactionAdded:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.ReactionRemoved:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.MessagePinned:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.MessageUnpinned:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomCreated:
+		targetIDs = ev.MemberIDs
+
+	case event.RoomDeleted:
+		targetIDs = ev.MemberIDs
+
+	case event.RoomAddedMember:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomRemovedMember:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomRenamed:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomMemberRoleChanged:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomMemberLeft:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		// contains the left user to notify its other connections.
+		targetIDs = append(room.MemberIDsJoinedBy(ev.Timestamp()), ev.UserID)
+
+	case event.RoomOwnershipTransferred:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	case event.RoomArchived:
+		targetIDs = []uint64{ev.ArchivedBy}
+
+	case event.RoomMessagesReadByUser:
+		room, err := rooms.Find(ctx, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = room.MemberIDsJoinedBy(ev.Timestamp())
+
+	// the presence of the user is hidden from the users blocked by the user.
+	case event.UserTypingStarted:
+		user, room, err := findUserAndRoom(ctx, users, rooms, ev.SenderID, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = user.VisibleUserIDs(otherMemberIDs(room, ev.SenderID))
+
+	case event.UserTypingEnded:
+		user, room, err := findUserAndRoom(ctx, users, rooms, ev.SenderID, ev.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = user.VisibleUserIDs(otherMemberIDs(room, ev.SenderID))
+
+	case event.ActiveClientActivated:
+		user, err := users.Find(ctx, ev.UserID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = append(user.VisibleUserIDs(user.FriendIDs.List()), user.ID) // contains user-self.
+
+	case event.ActiveClientInactivated:
+		user, err := users.Find(ctx, ev.UserID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = user.VisibleUserIDs(user.FriendIDs.List())
+
+	case event.UserProfileUpdated:
+		user, err := users.Find(ctx, ev.UserID)
+		if err != nil {
+			return nil, err
+		}
+		targetIDs = append(user.VisibleUserIDs(user.FriendIDs.List()), user.ID) // contains user-self.
+
+	case event.UserRemovedFriend:
+		targetIDs = []uint64{ev.UserID, ev.RemovedFriendID}
+
+	case event.FriendRequestSent:
+		targetIDs = []uint64{ev.SenderID, ev.ReceiverID}
+
+	case event.FriendRequestAccepted:
+		targetIDs = []uint64{ev.SenderID, ev.ReceiverID}
+
+	case event.FriendRequestDeclined:
+		targetIDs = []uint64{ev.SenderID, ev.ReceiverID}
+
+	case event.FriendRequestCanceled:
+		targetIDs = []uint64{ev.SenderID, ev.ReceiverID}
+
+	case event.UserBlocked:
+		// the blocked user is not notified. The removed friendship
+		// and friend requests are notified by their own events.
+		targetIDs = []uint64{ev.UserID}
+
+	case event.UserUnblocked:
+		targetIDs = []uint64{ev.UserID}
+
+	case event.UserMentioned:
+		// the mention is pushed to the mentioned user directly,
+		// regardless of the notifications for the room.
+		targetIDs = []uint64{ev.UserID}
+
+	case event.UserDeleted:
+		targetIDs = ev.FriendIDs
+	}
+
+	return targetIDs, nil
+}
+
+// messageFinder finds the message, which is satisfied by both of
+// domain.MessageRepository and MessageQueryer.
+type messageFinder interface {
+	Find(ctx context.Context, msgID uint64) (domain.Message, error)
+}
+
+// redactDeletedMessage returns the stored event without the content of
+// the message which is deleted after the event occurred, since the content
+// should be hidden after deletion.
+func redactDeletedMessage(ctx context.Context, msgs messageFinder, ev event.Event) (event.Event, error) {
+	switch ev := ev.(type) {
+	case event.MessageCreated:
+		deleted, err := isDeletedMessage(ctx, msgs, ev.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			ev.Content = ""
+			ev.AttachmentIDs = nil
+		}
+		return ev, nil
+
+	case event.MessageEdited:
+		deleted, err := isDeletedMessage(ctx, msgs, ev.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			ev.Content = ""
+		}
+		return ev, nil
+	}
+	return ev, nil
+}
+
+// It returns true when the message is deleted or
+// is removed from the datastore.
+func isDeletedMessage(ctx context.Context, msgs messageFinder, msgID uint64) (bool, error) {
+	m, err := msgs.Find(ctx, msgID)
+	if IsNotFoundError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return m.Deleted, nil
+}
+
+func findUserAndRoom(ctx context.Context, users userFinder, rooms roomFinder, userID, roomID uint64) (domain.User, domain.Room, error) {
+	user, err := users.Find(ctx, userID)
+	if err != nil {
+		return domain.User{}, domain.Room{}, err
+	}
+	room, err := rooms.Find(ctx, roomID)
+	if err != nil {
+		return domain.User{}, domain.Room{}, err
+	}
+	return user, room, nil
+}
+
+// It returns the room member IDs except the specified user.
+func otherMemberIDs(room domain.Room, userID uint64) []uint64 {
+	ids := make([]uint64, 0, len(room.MemberIDs()))
+	for _, id := range room.MemberIDs() {
+		if id != userID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func containsID(ids []uint64, id uint64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/chat/result"
 	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/domain/event"
 )
@@ -13,6 +14,19 @@ import (
 // Conn is exported at the chat package so that the higher layer need not to import domain package.
 type Conn domain.Conn
 
+// WaitingConn is a Conn which can wait for sending the event until
+// the connection can buffer it.
+// The replayed events are sent by SendWait if the Conn implements it,
+// so that the connection need not buffer all of the replayed events at once.
+type WaitingConn interface {
+	Conn
+
+	// SendWait sends the event as same as Send, but waits for the
+	// connection to be able to buffer the event.
+	// It returns error when the connection is closed or context is done.
+	SendWait(ctx context.Context, ev event.Event) error
+}
+
 // Hub is a interface for a hub which conmmunicates the event/action messages
 // between the active client connections.
 type Hub interface {
@@ -20,6 +34,13 @@ type Hub interface {
 	// If conection is invalid then return error.
 	Connect(ctx context.Context, c Conn) error
 
+	// ConnectAfter accepts new connection to the hub, as same as Connect,
+	// but it replays the stored events after the sequence number
+	// to the connection before sending the live events.
+	// It is used by the reconnecting client to receive the events
+	// sent while disconnected, without duplicates or gaps.
+	ConnectAfter(ctx context.Context, c Conn, after uint64) error
+
 	// Send sends ActionMessage with the connection which sent the message, to the hub.
 	// The Conn is used to verify that the message is exactlly
 	// sent by the connected user.
@@ -35,12 +56,21 @@ type Hub interface {
 // propagates domain events for those connections.
 // It implements Hub interface.
 type HubImpl struct {
-	messages chan actionMessageRequest
-	events   chan event.Event
-	shutdown chan struct{}
+	messages  chan actionMessageRequest
+	events    chan event.Event
+	handovers chan handoverRequest
+	shutdown  chan struct{}
+
+	// lastSeq is the maximum sequence number of the live events
+	// handled by the hub. It is only accessed by the goroutine
+	// sending the live events.
+	lastSeq uint64
 
 	chatCommand   *CommandServiceImpl
+	eventQueryer  EventQueryer
 	activeClients *domain.ActiveClientRepository
+	typings       *typingStates
+	acks          *ackCache
 	pubsub        Pubsub
 }
 
@@ -52,20 +82,62 @@ type actionMessageRequest struct {
 	Conn domain.Conn
 }
 
+// handoverRequest is a request to add the connection, which
+// is replayed the stored events until the sequence number After,
+// to the hub.
+// It is used to handle ConnectAfter by ChatHub.
+type handoverRequest struct {
+	User  domain.User
+	Conn  domain.Conn
+	After uint64
+	Done  chan handoverResult
+}
+
+// handoverResult is a result for the handoverRequest.
+// Behind is true when the hub already sent the live events after
+// the replayed ones, and the connection is not added to the hub.
+// Activated is the event for the user is activated newly, or nil.
+type handoverResult struct {
+	Behind    bool
+	Activated event.Event
+	Err       error
+}
+
+// replayPageSize is the number of the events queried at once
+// for replaying.
+const replayPageSize = 100
+
+// NewHubImpl creates HubImpl which can not replay the stored events.
+// Use NewHubImplWithEvents to support ConnectAfter.
 func NewHubImpl(cmd *CommandServiceImpl) *HubImpl {
 	if cmd == nil {
 		panic("passed nil arguments")
 	}
 
-	return &HubImpl{
-		messages: make(chan actionMessageRequest, 1),
-		events:   make(chan event.Event, 1),
-		shutdown: make(chan struct{}),
+	hub := &HubImpl{
+		messages:  make(chan actionMessageRequest, 1),
+		events:    make(chan event.Event, 1),
+		handovers: make(chan handoverRequest),
+		shutdown:  make(chan struct{}),
 
 		chatCommand:   cmd,
 		activeClients: domain.NewActiveClientRepository(64),
 		pubsub:        cmd.pubsub,
 	}
+	hub.typings = newTypingStates(DefaultTypingTimeout, hub.expireTyping)
+	hub.acks = newAckCache(DefaultAckCacheSize, DefaultAckCacheTTL)
+	return hub
+}
+
+// NewHubImplWithEvents creates HubImpl which replays the stored
+// events queried by the EventQueryer.
+func NewHubImplWithEvents(cmd *CommandServiceImpl, events EventQueryer) *HubImpl {
+	if events == nil {
+		panic("nil EventQueryer")
+	}
+	hub := NewHubImpl(cmd)
+	hub.eventQueryer = events
+	return hub
 }
 
 // Stop handling messages from the connections and
@@ -73,6 +145,7 @@ func NewHubImpl(cmd *CommandServiceImpl) *HubImpl {
 // Multiple calling will cause panic.
 func (hub *HubImpl) Shutdown() {
 	close(hub.shutdown)
+	hub.typings.Clear()
 }
 
 // Start handling messages from the connections and
@@ -96,12 +169,7 @@ func (hub *HubImpl) actionReceivingService(ctx context.Context) {
 	for {
 		select {
 		case req := <-hub.messages:
-			err := hub.handleMessage(ctx, req)
-			if err != nil {
-				log.Println(err)
-				// TODO send error event to requested connection.
-				// req.Conn.Send()
-			}
+			hub.handleRequest(ctx, req)
 		case ev, chAlived := <-logouts:
 			if !chAlived {
 				return
@@ -121,25 +189,164 @@ func (hub *HubImpl) actionReceivingService(ctx context.Context) {
 	}
 }
 
-func (hub *HubImpl) handleMessage(ctx context.Context, req actionMessageRequest) error {
-	var err error = nil
+// handleRequest handles the action message and returns its result
+// to the requested connection.
+// If the action message has the request ID, the Ack is returned to
+// the connection, otherwise only the error event is returned when
+// the action fails.
+// The request which is already succeeded is not handled again and
+// the same Ack is returned.
+func (hub *HubImpl) handleRequest(ctx context.Context, req actionMessageRequest) {
+	requestID := action.RequestIDOf(req.ActionMessage)
+	if requestID == "" {
+		if _, err := hub.handleMessage(ctx, req); err != nil {
+			log.Println(err)
+			hub.sendError(req, err)
+		}
+		return
+	}
+
+	userID := req.Conn.UserID()
+	if ack, ok := hub.acks.Find(userID, requestID); ok {
+		// the request is retried, returns the previous result.
+		req.Conn.Send(NewAckJSON(ack))
+		return
+	}
+
+	result, err := hub.handleMessage(ctx, req)
+	if err != nil {
+		log.Println(err)
+		// the failed request is not remembered so that
+		// the client can retry it.
+		req.Conn.Send(NewAckJSON(NewErrorAck(req.ActionMessage, err)))
+		return
+	}
+
+	ack := NewAck(req.ActionMessage, result)
+	hub.acks.Store(userID, requestID, ack)
+	req.Conn.Send(NewAckJSON(ack))
+}
+
+func (hub *HubImpl) handleMessage(ctx context.Context, req actionMessageRequest) (AckResult, error) {
+	var (
+		ret AckResult
+		err error
+	)
 
 	if !hub.activeClients.ExistByConn(req.Conn) {
-		return fmt.Errorf("not connected to the server")
+		return ret, ErrNotConnected
 	}
 
 	switch m := req.ActionMessage.(type) {
 	case action.ChatMessage:
-		_, err = hub.chatCommand.PostRoomMessage(ctx, m)
-	// TODO case action.EditChatMessage:
-	// TODO case action.DeleteChatMessage:
+		ret.RoomID = m.RoomID
+		ret.MessageID, err = hub.chatCommand.PostRoomMessage(ctx, m)
+	case action.EditChatMessage:
+		ret.RoomID = m.RoomID
+		ret.MessageID, err = hub.chatCommand.EditRoomMessage(ctx, m)
+	case action.DeleteChatMessage:
+		ret.RoomID = m.RoomID
+		ret.MessageID, err = hub.chatCommand.DeleteRoomMessage(ctx, m)
+	case action.AddReaction:
+		ret.RoomID = m.RoomID
+		ret.MessageID, err = hub.chatCommand.AddReaction(ctx, m)
+	case action.RemoveReaction:
+		ret.RoomID = m.RoomID
+		ret.MessageID, err = hub.chatCommand.RemoveReaction(ctx, m)
 	case action.ReadMessages:
-		_, err = hub.chatCommand.ReadRoomMessages(ctx, m)
-	case action.TypeStart, action.TypeEnd:
-		// TODO convert acitionMessage to event then publish in chatCommand
+		ret.RoomID, err = hub.chatCommand.ReadRoomMessages(ctx, m)
+	case action.TypeStart:
+		ret.RoomID, err = hub.chatCommand.StartTyping(ctx, m)
+		if err == nil {
+			hub.typings.Start(m.SenderID, m.RoomID)
+		}
+	case action.TypeEnd:
+		hub.typings.End(m.SenderID, m.RoomID)
+		ret.RoomID, err = hub.chatCommand.EndTyping(ctx, m)
+	case action.CreateRoom:
+		ret.RoomID, err = hub.chatCommand.CreateRoom(ctx, m)
+	case action.DeleteRoom:
+		ret.RoomID, err = hub.chatCommand.DeleteRoom(ctx, m)
+	case action.AddRoomMember:
+		var added *result.AddRoomMember
+		if added, err = hub.chatCommand.AddRoomMember(ctx, m); err == nil {
+			ret.RoomID, ret.UserID = added.RoomID, added.UserID
+		}
+	case action.RemoveRoomMember:
+		var removed *result.RemoveRoomMember
+		if removed, err = hub.chatCommand.RemoveRoomMember(ctx, m); err == nil {
+			ret.RoomID, ret.UserID = removed.RoomID, removed.UserID
+		}
+	case action.RenameRoom:
+		ret.RoomID, err = hub.chatCommand.RenameRoom(ctx, m)
+	case action.ChangeRoomMemberRole:
+		if ret.RoomID, err = hub.chatCommand.ChangeRoomMemberRole(ctx, m); err == nil {
+			ret.UserID = m.UserID
+		}
+	case action.LeaveRoom:
+		ret.RoomID, err = hub.chatCommand.LeaveRoom(ctx, m)
+	case action.TransferRoomOwnership:
+		if ret.RoomID, err = hub.chatCommand.TransferRoomOwnership(ctx, m); err == nil {
+			ret.UserID = m.NewOwnerID
+		}
+	case action.FindOrCreateTalkRoom:
+		var talkRoom *result.FindOrCreateTalkRoom
+		if talkRoom, err = hub.chatCommand.FindOrCreateTalkRoom(ctx, m); err == nil {
+			ret.RoomID, ret.UserID = talkRoom.RoomID, m.FriendID
+		}
+	case action.PinMessage:
+		ret.MessageID = m.MessageID
+		ret.RoomID, err = hub.chatCommand.PinMessage(ctx, m)
+	case action.UnpinMessage:
+		ret.MessageID = m.MessageID
+		ret.RoomID, err = hub.chatCommand.UnpinMessage(ctx, m)
+	case action.UpdateUserProfile:
+		ret.UserID, err = hub.chatCommand.UpdateUserProfile(ctx, m)
+	case action.SendFriendRequest:
+		ret.UserID, err = hub.chatCommand.SendFriendRequest(ctx, m)
+	case action.AcceptFriendRequest:
+		ret.UserID, err = hub.chatCommand.AcceptFriendRequest(ctx, m)
+	case action.DeclineFriendRequest:
+		ret.UserID, err = hub.chatCommand.DeclineFriendRequest(ctx, m)
+	case action.CancelFriendRequest:
+		ret.UserID, err = hub.chatCommand.CancelFriendRequest(ctx, m)
+	case action.RemoveFriend:
+		ret.UserID, err = hub.chatCommand.RemoveFriend(ctx, m)
+	case action.BlockUser:
+		ret.UserID, err = hub.chatCommand.BlockUser(ctx, m)
+	case action.UnblockUser:
+		ret.UserID, err = hub.chatCommand.UnblockUser(ctx, m)
+	default:
+		err = fmt.Errorf("unsupported action: %v", m.Action())
 	}
 
-	return err
+	return ret, err
+}
+
+// sendError sends the error event to the connection which
+// requested the failed action message.
+func (hub *HubImpl) sendError(req actionMessageRequest, err error) {
+	req.Conn.Send(NewEventJSON(NewErrorRaised(err, req.ActionMessage)))
+}
+
+// expireTyping ends the typing state of the user in the room
+// since the client does not notify end of typing.
+func (hub *HubImpl) expireTyping(userID, roomID uint64) {
+	te := action.TypeEnd{}
+	te.SenderID = userID
+	te.RoomID = roomID
+	if _, err := hub.chatCommand.EndTyping(context.Background(), te); err != nil {
+		// TODO error handling
+		log.Println(err)
+	}
+}
+
+// endAllTyping ends all of the typing states of the user.
+// It is used when the user is no longer connected.
+func (hub *HubImpl) endAllTyping(userID uint64) {
+	for _, roomID := range hub.typings.EndAll(userID) {
+		hub.expireTyping(userID, roomID)
+	}
 }
 
 func (hub *HubImpl) handleLogoutEvent(logout eventUserLoggedOut) error {
@@ -152,6 +359,7 @@ func (hub *HubImpl) handleLogoutEvent(logout eventUserLoggedOut) error {
 	if err != nil {
 		return err
 	}
+	hub.endAllTyping(logout.UserID)
 	hub.pubsub.Pub(ev)
 	return nil
 }
@@ -177,6 +385,8 @@ func (hub *HubImpl) broadcastEvent(ev event.Event, targetIDs ...uint64) error {
 // by the Hub interface.
 var HubHandlingEventTypes = []event.Type{
 	event.TypeMessageCreated,
+	event.TypeMessageEdited,
+	event.TypeMessageDeleted,
 	event.TypeActiveClientActivated,
 	event.TypeActiveClientInactivated,
 	event.TypeRoomCreated,
@@ -184,6 +394,27 @@ var HubHandlingEventTypes = []event.Type{
 	event.TypeRoomAddedMember,
 	event.TypeRoomRemovedMember,
 	event.TypeRoomMessagesReadByUser,
+	event.TypeUserTypingStarted,
+	event.TypeUserTypingEnded,
+	event.TypeRoomRenamed,
+	event.TypeRoomMemberRoleChanged,
+	event.TypeRoomMemberLeft,
+	event.TypeRoomOwnershipTransferred,
+	event.TypeRoomArchived,
+	event.TypeUserProfileUpdated,
+	event.TypeUserRemovedFriend,
+	event.TypeUserDeleted,
+	event.TypeFriendRequestSent,
+	event.TypeFriendRequestAccepted,
+	event.TypeFriendRequestDeclined,
+	event.TypeFriendRequestCanceled,
+	event.TypeUserBlocked,
+	event.TypeUserUnblocked,
+	event.TypeReactionAdded,
+	event.TypeReactionRemoved,
+	event.TypeMessagePinned,
+	event.TypeMessageUnpinned,
+	event.TypeUserMentioned,
 }
 
 func (hub *HubImpl) eventSendingService(ctx context.Context) {
@@ -200,114 +431,245 @@ func (hub *HubImpl) eventSendingService(ctx context.Context) {
 				return
 			}
 			if ev, ok := ev.(event.Event); ok {
+				if seq := event.SequenceOf(ev); seq > hub.lastSeq {
+					hub.lastSeq = seq
+				}
 				err := hub.sendEvent(ctx, ev)
 				if err != nil {
 					// TODO error handling
 					log.Println(err)
 				}
 			}
+		case req := <-hub.handovers:
+			// the handover is done at the same goroutine with sending
+			// the live events so that no live events are missed
+			// between the replayed and the live events.
+			req.Done <- hub.handover(req)
 		}
 	} // ... for
 }
 
 func (hub *HubImpl) sendEvent(ctx context.Context, ev event.Event) error {
-	var (
-		chatCommand = hub.chatCommand
-		targetIDs   = []uint64{}
-	)
+	targetIDs, err := hub.targetIDsOf(ctx, ev)
+	if err != nil {
+		return err
+	}
 
-	// TODO: integrates these into interfaces, RoomMemberSender and UserFriendSender?
-	switch ev := ev.(type) {
-	case event.MessageCreated:
-		room, err := chatCommand.rooms.Find(ctx, ev.RoomID)
-		if err != nil {
-			return err
-		}
-		targetIDs = room.MemberIDSet.List()
+	if ev, ok := ev.(event.UserDeleted); ok {
+		// the connections of the deleted user are closed
+		// as same as logged out.
+		logout := eventUserLoggedOut{UserID: ev.UserID}
+		logout.Occurs()
+		hub.pubsub.Pub(logout)
+	}
 
-	case event.RoomCreated:
-		targetIDs = ev.MemberIDs
+	return hub.broadcastEvent(ev, targetIDs...)
+}
 
-	case event.RoomDeleted:
-		targetIDs = ev.MemberIDs
+// targetIDsOf returns the IDs of the users who can receive the event.
+func (hub *HubImpl) targetIDsOf(ctx context.Context, ev event.Event) ([]uint64, error) {
+	return eventTargetIDs(ctx, hub.chatCommand.users, hub.chatCommand.rooms, ev)
+}
 
-	case event.RoomAddedMember:
-		room, err := chatCommand.rooms.Find(ctx, ev.RoomID)
-		if err != nil {
-			return err
-		}
-		targetIDs = room.MemberIDSet.List()
+// Send ActionMessage with the connection which sent the message.
+// the connection is used to verify that the message is exactlly
+// sent by the connected user.
+// The error is sent to given conn when the message is invalid.
+func (hub *HubImpl) Send(conn Conn, message action.ActionMessage) {
+	select {
+	case <-hub.shutdown:
+		return
+	case hub.messages <- actionMessageRequest{message, conn}:
+	}
+}
 
-	case event.RoomRemovedMember:
-		room, err := chatCommand.rooms.Find(ctx, ev.RoomID)
-		if err != nil {
+// Connect new websocket connection to the hub.
+func (hub *HubImpl) Connect(ctx context.Context, c Conn) error {
+	user, err := hub.findConnectedUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	activated, err := hub.connect(user, c, 0)
+	if err != nil {
+		return err
+	}
+	if activated != nil {
+		// publish activated event.
+		hub.pubsub.Pub(activated)
+	}
+	return nil
+}
+
+// ConnectAfter connects new websocket connection to the hub
+// after replaying the stored events after the sequence number.
+// The events are replayed in the order of the sequence number,
+// and only the events which the user can receive are replayed.
+// The live events already replayed are not sent to the connection again.
+// The replay is done at the caller goroutine, so that the slow
+// connection does not block sending the live events to the others.
+// It returns ErrReplayNotSupported if the hub has no EventQueryer.
+func (hub *HubImpl) ConnectAfter(ctx context.Context, c Conn, after uint64) error {
+	if hub.eventQueryer == nil {
+		return ErrReplayNotSupported
+	}
+
+	user, err := hub.findConnectedUser(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	// the live events until the cursor are not sent after replaying,
+	// so the cursor must be the stored event.
+	if after > 0 {
+		if _, err := hub.eventQueryer.FindAllBySequence(ctx, after-1, 1); err != nil {
+			if IsNotFoundError(err) {
+				return NewNotFoundError("event cursor(%d) is not found", after)
+			}
 			return err
 		}
-		targetIDs = room.MemberIDSet.List()
+	}
 
-	case event.RoomMessagesReadByUser:
-		room, err := chatCommand.rooms.Find(ctx, ev.RoomID)
+	for {
+		after, err = hub.replay(ctx, c, user, after)
 		if err != nil {
 			return err
 		}
-		targetIDs = room.MemberIDSet.List()
 
-	case event.ActiveClientActivated:
-		user, err := chatCommand.users.Find(ctx, ev.UserID)
-		if err != nil {
-			return err
+		req := handoverRequest{
+			User:  user,
+			Conn:  c,
+			After: after,
+			Done:  make(chan handoverResult, 1),
+		}
+		select {
+		case hub.handovers <- req:
+		case <-hub.shutdown:
+			return ErrHubShutdown
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		targetIDs = append(user.FriendIDs.List(), user.ID) // contains user-self.
 
-	case event.ActiveClientInactivated:
-		user, err := chatCommand.users.Find(ctx, ev.UserID)
-		if err != nil {
-			return err
+		res := <-req.Done
+		if res.Err != nil {
+			return res.Err
 		}
-		targetIDs = user.FriendIDs.List()
+		if res.Behind {
+			// the live events are sent during replaying,
+			// replays those again.
+			continue
+		}
+		if res.Activated != nil {
+			// publish activated event.
+			hub.pubsub.Pub(res.Activated)
+		}
+		return nil
 	}
+}
 
-	return hub.broadcastEvent(ev, targetIDs...)
+// replay sends the stored events after the sequence number to the
+// connection, and returns the sequence number of the last replayed event.
+// It may block until the connection can buffer the events if the
+// connection is WaitingConn.
+func (hub *HubImpl) replay(ctx context.Context, c Conn, user domain.User, after uint64) (uint64, error) {
+	for {
+		records, err := hub.eventQueryer.FindAllBySequence(ctx, after, replayPageSize)
+		if err != nil && !IsNotFoundError(err) {
+			return after, err
+		}
+
+		for _, r := range records {
+			if !isHubHandlingEvent(r.Event) {
+				after = r.Seq
+				continue
+			}
+			targetIDs, err := hub.targetIDsOf(ctx, r.Event)
+			if err != nil && !IsNotFoundError(err) {
+				// the event can not be skipped, otherwise the replay has a gap.
+				return after, err
+			}
+			after = r.Seq
+			// the event for the deleted room and so on can not be sent.
+			if err == nil && containsID(targetIDs, user.ID) {
+				ev, err := redactDeletedMessage(ctx, hub.chatCommand.msgs, r.Event)
+				if err != nil {
+					return after, err
+				}
+				toSend := NewEventJSON(event.WithSequence(ev, r.Seq))
+				if wc, ok := c.(WaitingConn); ok {
+					if err := wc.SendWait(ctx, toSend); err != nil {
+						return after, err
+					}
+				} else {
+					c.Send(toSend)
+				}
+			}
+		}
+		if len(records) < replayPageSize {
+			return after, nil
+		}
+	}
 }
 
-// Send ActionMessage with the connection which sent the message.
-// the connection is used to verify that the message is exactlly
-// sent by the connected user.
-// The error is sent to given conn when the message is invalid.
-func (hub *HubImpl) Send(conn Conn, message action.ActionMessage) {
-	select {
-	case <-hub.shutdown:
-		return
-	case hub.messages <- actionMessageRequest{message, conn}:
+// handover adds the connection replayed the stored events to the hub.
+// It must be called in the goroutine sending the live events,
+// so that the live events after the replayed ones are sent
+// to the connection.
+func (hub *HubImpl) handover(req handoverRequest) handoverResult {
+	if hub.lastSeq > req.After {
+		return handoverResult{Behind: true}
 	}
+	// the live events until the last replayed one are already
+	// sent by replaying.
+	activated, err := hub.connect(req.User, req.Conn, req.After)
+	return handoverResult{Activated: activated, Err: err}
 }
 
-// Connect new websocket connection to the hub.
-func (hub *HubImpl) Connect(ctx context.Context, c Conn) error {
+func (hub *HubImpl) findConnectedUser(ctx context.Context, c Conn) (domain.User, error) {
 	userID := c.UserID()
 	user, err := hub.chatCommand.users.Find(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("connected user(%d) is not found", userID)
+		return user, fmt.Errorf("connected user(%d) is not found", userID)
 	}
+	return user, nil
+}
 
-	if ac, err := hub.activeClients.Find(userID); err == nil {
+// connect adds the connection to the ActiveClient of the user.
+// The live events until the sequence number sentSeq are not sent
+// to the connection.
+// It returns the event for the user is activated newly, or nil.
+func (hub *HubImpl) connect(user domain.User, c Conn, sentSeq uint64) (event.Event, error) {
+	if ac, err := hub.activeClients.Find(user.ID); err == nil {
 		// the connected user is already activated, so just add connection.
-		_, err := ac.AddConn(c)
-		if err != nil {
-			return err
+		if _, err := ac.AddConn(c); err != nil {
+			return nil, err
+		}
+		if err := ac.SkipSequence(c, sentSeq); err != nil {
+			return nil, err
 		}
-		return hub.activeClients.Store(ac)
+		return nil, hub.activeClients.Store(ac)
 	}
 
 	// the conncected user is inactivate, activate it.
-	_, activated, err := domain.NewActiveClient(hub.activeClients, c, user)
+	ac, activated, err := domain.NewActiveClient(hub.activeClients, c, user)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := ac.SkipSequence(c, sentSeq); err != nil {
+		return nil, err
 	}
+	return activated, nil
+}
 
-	// publish activated event.
-	hub.pubsub.Pub(activated)
-	return nil
+// isHubHandlingEvent returns whether the event is one of the
+// HubHandlingEventTypes.
+func isHubHandlingEvent(ev event.Event) bool {
+	for _, typ := range HubHandlingEventTypes {
+		if ev.Type() == typ {
+			return true
+		}
+	}
+	return false
 }
 
 // Disconnect the given websocket connection from the hub.
@@ -337,6 +699,7 @@ func (hub *HubImpl) Disconnect(conn Conn) {
 		// TODO error log
 		return
 	}
+	hub.endAllTyping(conn.UserID())
 	// publish inactivated event.
 	hub.pubsub.Pub(inactivated)
 }
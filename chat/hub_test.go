@@ -2,11 +2,14 @@ package chat
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/domain/event"
 	"github.com/shirasudon/go-chat/internal/mocks"
@@ -42,6 +45,7 @@ func TestHubSendEvent(t *testing.T) {
 		UserID        = uint64(1)
 		RoomMemberIDs = []uint64{1, 2, 3}
 		UserFriendIDs = []uint64{4, 5, 6}
+		LeftUserID    = uint64(7)
 	)
 
 	// Firstly build Hub
@@ -78,6 +82,30 @@ func TestHubSendEvent(t *testing.T) {
 			Event:       event.MessageCreated{RoomID: RoomID},
 			SendUserIDs: RoomMemberIDs,
 		},
+		{
+			Event:       event.MessageEdited{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessageDeleted{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.ReactionAdded{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.ReactionRemoved{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessagePinned{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessageUnpinned{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
 		{
 			Event:       event.RoomCreated{RoomID: RoomID, MemberIDs: RoomMemberIDs},
 			SendUserIDs: RoomMemberIDs,
@@ -90,6 +118,26 @@ func TestHubSendEvent(t *testing.T) {
 			Event:       event.RoomMessagesReadByUser{RoomID: RoomID},
 			SendUserIDs: RoomMemberIDs,
 		},
+		{
+			Event:       event.RoomRenamed{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.RoomMemberRoleChanged{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.RoomMemberLeft{RoomID: RoomID, UserID: LeftUserID},
+			SendUserIDs: append([]uint64{LeftUserID}, RoomMemberIDs...), // contains left user
+		},
+		{
+			Event:       event.RoomOwnershipTransferred{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.RoomArchived{RoomID: RoomID, ArchivedBy: LeftUserID},
+			SendUserIDs: []uint64{LeftUserID},
+		},
 		{
 			Event:       event.ActiveClientActivated{UserID: UserID},
 			SendUserIDs: append([]uint64{1}, UserFriendIDs...), // contains UserID itself
@@ -98,6 +146,46 @@ func TestHubSendEvent(t *testing.T) {
 			Event:       event.ActiveClientInactivated{UserID: UserID},
 			SendUserIDs: UserFriendIDs,
 		},
+		{
+			Event:       event.UserProfileUpdated{UserID: UserID},
+			SendUserIDs: append([]uint64{1}, UserFriendIDs...), // contains UserID itself
+		},
+		{
+			Event:       event.UserRemovedFriend{UserID: UserID, RemovedFriendID: LeftUserID},
+			SendUserIDs: []uint64{UserID, LeftUserID},
+		},
+		{
+			Event:       event.UserDeleted{UserID: LeftUserID, FriendIDs: UserFriendIDs},
+			SendUserIDs: UserFriendIDs,
+		},
+		{
+			Event:       event.UserMentioned{RoomID: RoomID, UserID: LeftUserID},
+			SendUserIDs: []uint64{LeftUserID},
+		},
+		{
+			Event:       event.FriendRequestSent{SenderID: UserID, ReceiverID: LeftUserID},
+			SendUserIDs: []uint64{UserID, LeftUserID},
+		},
+		{
+			Event:       event.FriendRequestAccepted{SenderID: UserID, ReceiverID: LeftUserID},
+			SendUserIDs: []uint64{UserID, LeftUserID},
+		},
+		{
+			Event:       event.FriendRequestDeclined{SenderID: UserID, ReceiverID: LeftUserID},
+			SendUserIDs: []uint64{UserID, LeftUserID},
+		},
+		{
+			Event:       event.FriendRequestCanceled{SenderID: UserID, ReceiverID: LeftUserID},
+			SendUserIDs: []uint64{UserID, LeftUserID},
+		},
+		{
+			Event:       event.UserBlocked{UserID: UserID, BlockedUserID: LeftUserID},
+			SendUserIDs: []uint64{UserID},
+		},
+		{
+			Event:       event.UserUnblocked{UserID: UserID, UnblockedUserID: LeftUserID},
+			SendUserIDs: []uint64{UserID},
+		},
 	} {
 		// register user connections to Hub.
 		conns := make([]*SendRecorder, 0, len(testcase.SendUserIDs))
@@ -169,6 +257,30 @@ func TestHubSendEventFail(t *testing.T) {
 			Event:       event.MessageCreated{RoomID: RoomID},
 			SendUserIDs: RoomMemberIDs,
 		},
+		{
+			Event:       event.MessageEdited{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessageDeleted{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.ReactionAdded{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.ReactionRemoved{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessagePinned{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
+		{
+			Event:       event.MessageUnpinned{RoomID: RoomID},
+			SendUserIDs: RoomMemberIDs,
+		},
 		{
 			Event:       event.RoomMessagesReadByUser{RoomID: RoomID},
 			SendUserIDs: RoomMemberIDs,
@@ -181,6 +293,10 @@ func TestHubSendEventFail(t *testing.T) {
 			Event:       event.ActiveClientInactivated{UserID: UserID},
 			SendUserIDs: UserFriendIDs,
 		},
+		{
+			Event:       event.UserProfileUpdated{UserID: UserID},
+			SendUserIDs: append([]uint64{1}, UserFriendIDs...), // contains UserID itself
+		},
 	} {
 		// register user connections to Hub.
 		conns := make([]*SendRecorder, 0, len(testcase.SendUserIDs))
@@ -337,6 +453,270 @@ func TestHubEventSendingServiceAtMessageCreated(t *testing.T) {
 	}
 }
 
+func TestHubSendTypingEvent(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		RoomID        = uint64(1)
+		SenderID      = uint64(1)
+		RoomMemberIDs = []uint64{1, 2, 3}
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(RoomMemberIDs...)}, nil).
+		AnyTimes()
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			return domain.User{ID: userID}, nil
+		}).AnyTimes()
+
+	repos := domain.SimpleRepositories{
+		RoomRepository: rooms,
+		UserRepository: users,
+	}
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(repos, pubsub))
+
+	for _, ev := range []event.Event{
+		event.UserTypingStarted{RoomID: RoomID, SenderID: SenderID},
+		event.UserTypingEnded{RoomID: RoomID, SenderID: SenderID},
+	} {
+		conns := make([]*SendRecorder, 0, len(RoomMemberIDs))
+		for _, id := range RoomMemberIDs {
+			conn := &SendRecorder{userID: id}
+			if err := hub.Connect(context.Background(), conn); err != nil {
+				t.Fatalf("can not connect user id=%d, err=%v", id, err)
+			}
+			conns = append(conns, conn)
+		}
+
+		if err := hub.sendEvent(context.Background(), ev); err != nil {
+			t.Fatalf("sending event %#v, got error: %v", ev, err)
+		}
+
+		// the typing user itself should not receive the event.
+		for _, c := range conns {
+			if c.UserID() == SenderID && c.IsSent {
+				t.Errorf("send %T, but the sender (id=%d) also received", ev, c.UserID())
+			}
+			if c.UserID() != SenderID && !c.IsSent {
+				t.Errorf("send %T, but user (id=%d) does not received", ev, c.UserID())
+			}
+			hub.Disconnect(c)
+		}
+	}
+}
+
+func TestHubSendTypingEventHiddenFromBlockedUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		RoomID        = uint64(1)
+		SenderID      = uint64(1)
+		BlockedID     = uint64(3)
+		RoomMemberIDs = []uint64{1, 2, 3}
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(RoomMemberIDs...)}, nil).
+		AnyTimes()
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			u := domain.User{ID: userID}
+			if userID == SenderID {
+				u.BlockedUserIDs = domain.NewUserIDSet(BlockedID)
+			}
+			return u, nil
+		}).AnyTimes()
+
+	repos := domain.SimpleRepositories{
+		RoomRepository: rooms,
+		UserRepository: users,
+	}
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(repos, pubsub))
+
+	conns := make([]*SendRecorder, 0, len(RoomMemberIDs))
+	for _, id := range RoomMemberIDs {
+		conn := &SendRecorder{userID: id}
+		if err := hub.Connect(context.Background(), conn); err != nil {
+			t.Fatalf("can not connect user id=%d, err=%v", id, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	ev := event.UserTypingStarted{RoomID: RoomID, SenderID: SenderID}
+	if err := hub.sendEvent(context.Background(), ev); err != nil {
+		t.Fatalf("sending event %#v, got error: %v", ev, err)
+	}
+
+	for _, c := range conns {
+		switch c.UserID() {
+		case SenderID, BlockedID:
+			if c.IsSent {
+				t.Errorf("typing event is sent to user (id=%d)", c.UserID())
+			}
+		default:
+			if !c.IsSent {
+				t.Errorf("typing event is not sent to user (id=%d)", c.UserID())
+			}
+		}
+		hub.Disconnect(c)
+	}
+}
+
+func TestHubTypingExpiration(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID          = uint64(1)
+		RoomID          = uint64(2)
+		TypingTimeout   = 1 * time.Millisecond
+		TimeoutDuration = 100 * time.Millisecond
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).
+		AnyTimes()
+
+	pubsubCh := make(chan event.Event, 2)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).Do(func(evs ...event.Event) {
+		for _, ev := range evs {
+			pubsubCh <- ev
+		}
+	}).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository: users,
+		RoomRepository: rooms,
+	}, pubsub))
+	hub.typings = newTypingStates(TypingTimeout, hub.expireTyping)
+
+	conn := &SendRecorder{userID: UserID}
+	if err := hub.Connect(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Disconnect(conn)
+
+	// consume the activated event.
+	if ev := <-pubsubCh; ev.Type() != event.TypeActiveClientActivated {
+		t.Fatalf("different event type, expect: %v, got: %v", event.TypeActiveClientActivated, ev.Type())
+	}
+
+	typeStart := action.TypeStart{}
+	typeStart.SenderID = UserID
+	typeStart.RoomID = RoomID
+	if _, err := hub.handleMessage(context.Background(), actionMessageRequest{typeStart, conn}); err != nil {
+		t.Fatal(err)
+	}
+
+	// typing state is expired without the end of typing by the client.
+	timeout := time.After(TimeoutDuration)
+	for _, expect := range []event.Type{event.TypeUserTypingStarted, event.TypeUserTypingEnded} {
+		select {
+		case ev := <-pubsubCh:
+			if ev.Type() != expect {
+				t.Fatalf("different event type, expect: %v, got: %v", expect, ev.Type())
+			}
+		case <-timeout:
+			t.Fatalf("timeout: %v is not published", expect)
+		}
+	}
+}
+
+func TestHubTypingEndedByDisconnect(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID = uint64(1)
+		RoomID = uint64(2)
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).
+		AnyTimes()
+
+	pubsubCh := make(chan event.Event, 8)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).Do(func(evs ...event.Event) {
+		for _, ev := range evs {
+			pubsubCh <- ev
+		}
+	}).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository: users,
+		RoomRepository: rooms,
+	}, pubsub))
+
+	conn := &SendRecorder{userID: UserID}
+	if err := hub.Connect(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+
+	typeStart := action.TypeStart{}
+	typeStart.SenderID = UserID
+	typeStart.RoomID = RoomID
+	if _, err := hub.handleMessage(context.Background(), actionMessageRequest{typeStart, conn}); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Disconnect(conn)
+
+	expects := []event.Type{
+		event.TypeActiveClientActivated,
+		event.TypeUserTypingStarted,
+		event.TypeUserTypingEnded,
+		event.TypeActiveClientInactivated,
+	}
+	if len(pubsubCh) != len(expects) {
+		t.Fatalf("different published events size, expect: %v, got: %v", len(expects), len(pubsubCh))
+	}
+	for _, expect := range expects {
+		if ev := <-pubsubCh; ev.Type() != expect {
+			t.Errorf("different event type, expect: %v, got: %v", expect, ev.Type())
+		}
+	}
+	if hub.typings.End(UserID, RoomID) {
+		t.Error("typing state is remained after disconnect")
+	}
+}
+
 func TestHubActionReceivingService(t *testing.T) {
 	t.Parallel()
 
@@ -410,34 +790,358 @@ func TestHubActionReceivingService(t *testing.T) {
 	}
 }
 
-func TestHubListenReturnByShutdown(t *testing.T) {
+func TestHubActionReceivingServiceSendError(t *testing.T) {
 	t.Parallel()
 
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	const (
-		TimeoutDuration = 10 * time.Millisecond
-		SleepDuration   = 5 * time.Millisecond
+		UserID          = uint64(2)
+		RoomID          = uint64(3)
+		CorrelationID   = "correlation-1"
+		TimeoutDuration = 100 * time.Millisecond
 	)
 
-	pubsubCh := make(chan interface{}, 1)
 	ps := mocks.NewMockPubsub(mockCtrl)
-	ps.EXPECT().Sub(event.TypeExternal).Return(pubsubCh).Times(1)
-	ps.EXPECT().Sub(HubHandlingEventTypes).Return(pubsubCh).Times(1)
+	ps.EXPECT().Pub(gomock.Any()).AnyTimes()
+	ps.EXPECT().Sub(event.TypeExternal).Return(make(chan interface{})).Times(1)
 
-	// build mock repositories.
-	repos := domain.SimpleRepositories{}
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{}, NewNotFoundError("room not found")).
+		AnyTimes()
+
+	repos := domain.SimpleRepositories{
+		UserRepository: users,
+		RoomRepository: rooms,
+	}
+
+	// build mock conn which receives the error event.
+	errCh := make(chan event.Event, 1)
+	conn := mocks.NewMockConn(mockCtrl)
+	conn.EXPECT().UserID().Return(UserID).AnyTimes()
+	conn.EXPECT().Send(gomock.Any()).Do(func(ev event.Event) {
+		errCh <- ev
+	}).Times(1)
 
-	// build hub
 	ctx, cancel := context.WithTimeout(context.Background(), TimeoutDuration)
 	defer cancel()
 
-	doneCh := make(chan bool, 1)
-
 	hub := NewHubImpl(NewCommandServiceImpl(repos, ps))
-	go func() {
-		hub.Listen(ctx)
+	go hub.actionReceivingService(ctx)
+
+	if err := hub.Connect(ctx, conn); err != nil {
+		t.Fatal(err)
+	}
+
+	typeStart := action.TypeStart{}
+	typeStart.ActionName = action.ActionTypeStart
+	typeStart.CorrelationID = CorrelationID
+	typeStart.SenderID = UserID
+	typeStart.RoomID = RoomID
+	hub.Send(conn, typeStart)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("timeout: the failed action is not notified to the conn")
+	case ev := <-errCh:
+		evJSON, ok := ev.(EventJSON)
+		if !ok {
+			t.Fatalf("invalid event type, expect: EventJSON, got: %T", ev)
+		}
+		if evJSON.EventName != EventNameErrorRaised {
+			t.Errorf("different event name, expect: %v, got: %v", EventNameErrorRaised, evJSON.EventName)
+		}
+		errRaised, ok := evJSON.Data.(event.ErrorRaised)
+		if !ok {
+			t.Fatalf("invalid event data, expect: ErrorRaised, got: %T", evJSON.Data)
+		}
+		if errRaised.Code != ErrorCodeNotFound {
+			t.Errorf("different error code, expect: %v, got: %v", ErrorCodeNotFound, errRaised.Code)
+		}
+		if errRaised.Action != string(action.ActionTypeStart) {
+			t.Errorf("different action, expect: %v, got: %v", action.ActionTypeStart, errRaised.Action)
+		}
+		if errRaised.CorrelationID != CorrelationID {
+			t.Errorf("different correlation ID, expect: %v, got: %v", CorrelationID, errRaised.CorrelationID)
+		}
+	}
+}
+
+// EventRecorder records all of the events sent by
+// using Send() method.
+// It implements Conn interface.
+type EventRecorder struct {
+	SendRecorder
+	Events []event.Event
+}
+
+func (r *EventRecorder) Send(ev event.Event) { r.IsSent = true; r.Events = append(r.Events, ev) }
+
+func TestHubHandleRequestWithAck(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+		RequestID = "request-1"
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).
+		AnyTimes()
+
+	// the message is posted only once even if the request is retried.
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil).Times(1)
+	msgs.EXPECT().Store(gomock.Any(), gomock.Any()).Return(MessageID, nil).Times(1)
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil).Times(1)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:    users,
+		RoomRepository:    rooms,
+		MessageRepository: msgs,
+		EventRepository:   events,
+	}, pubsub))
+
+	conn := &EventRecorder{SendRecorder: SendRecorder{userID: UserID}}
+	if err := hub.Connect(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Disconnect(conn)
+
+	cm := action.ChatMessage{RoomID: RoomID, SenderID: UserID, Content: "hello"}
+	cm.ActionName = action.ActionChatMessage
+	cm.RequestID = RequestID
+
+	// first request and its retry.
+	hub.handleRequest(context.Background(), actionMessageRequest{cm, conn})
+	hub.handleRequest(context.Background(), actionMessageRequest{cm, conn})
+
+	if len(conn.Events) != 2 {
+		t.Fatalf("different number of acks, expect: %v, got: %v", 2, len(conn.Events))
+	}
+	for _, ev := range conn.Events {
+		evJSON, ok := ev.(EventJSON)
+		if !ok || evJSON.EventName != EventNameAck {
+			t.Fatalf("ack is not sent, got: %#v", ev)
+		}
+		ack := evJSON.Data.(Ack)
+		if !ack.OK {
+			t.Errorf("ack is not OK, got: %#v", ack)
+		}
+		if ack.RequestID != RequestID {
+			t.Errorf("different request ID, expect: %v, got: %v", RequestID, ack.RequestID)
+		}
+		if ack.Result == nil || ack.Result.MessageID != MessageID || ack.Result.RoomID != RoomID {
+			t.Errorf("different ack result, got: %#v", ack.Result)
+		}
+	}
+}
+
+func TestHubHandleRequestWithErrorAck(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		RequestID = "request-1"
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	// the failed request is executed again by retrying.
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{}, NewNotFoundError("room not found")).
+		Times(2)
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository: users,
+		RoomRepository: rooms,
+	}, pubsub))
+
+	conn := &EventRecorder{SendRecorder: SendRecorder{userID: UserID}}
+	if err := hub.Connect(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Disconnect(conn)
+
+	cm := action.ChatMessage{RoomID: RoomID, SenderID: UserID, Content: "hello"}
+	cm.ActionName = action.ActionChatMessage
+	cm.RequestID = RequestID
+
+	hub.handleRequest(context.Background(), actionMessageRequest{cm, conn})
+	hub.handleRequest(context.Background(), actionMessageRequest{cm, conn})
+
+	if len(conn.Events) != 2 {
+		t.Fatalf("different number of acks, expect: %v, got: %v", 2, len(conn.Events))
+	}
+	for _, ev := range conn.Events {
+		evJSON, ok := ev.(EventJSON)
+		if !ok || evJSON.EventName != EventNameAck {
+			t.Fatalf("ack is not sent, got: %#v", ev)
+		}
+		ack := evJSON.Data.(Ack)
+		if ack.OK {
+			t.Errorf("ack for failed request should not be OK")
+		}
+		if ack.Error == nil || ack.Error.Code != ErrorCodeNotFound {
+			t.Errorf("different ack error, got: %#v", ack.Error)
+		}
+	}
+}
+
+func TestHubHandleRoomActions(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID      = uint64(1)
+		OtherUserID = uint64(2)
+		RoomID      = uint64(3)
+		ThirdUserID = uint64(4)
+	)
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			return domain.User{ID: userID}, nil
+		}).AnyTimes()
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).Return(domain.EmptyTxBeginner{}, nil).AnyTimes()
+	rooms.EXPECT().Store(gomock.Any(), gomock.Any()).Return(RoomID, nil).Times(1)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, OwnerID: OtherUserID, MemberIDSet: domain.NewUserIDSet(UserID, OtherUserID, ThirdUserID)}, nil).
+		AnyTimes()
+
+	events := mocks.NewMockEventRepository(mockCtrl)
+	events.EXPECT().Store(gomock.Any(), gomock.Any()).Return([]uint64{1}, nil).AnyTimes()
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Pub(gomock.Any()).AnyTimes()
+
+	hub := NewHubImpl(NewCommandServiceImpl(domain.SimpleRepositories{
+		UserRepository:  users,
+		RoomRepository:  rooms,
+		EventRepository: events,
+	}, pubsub))
+
+	conn := &EventRecorder{SendRecorder: SendRecorder{userID: UserID}}
+	if err := hub.Connect(context.Background(), conn); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Disconnect(conn)
+
+	{ // case1: create room
+		cr := action.CreateRoom{SenderID: UserID, RoomName: "room", RoomMemberIDs: []uint64{UserID}}
+		cr.ActionName = action.ActionCreateRoom
+		res, err := hub.handleMessage(context.Background(), actionMessageRequest{cr, conn})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.RoomID != RoomID {
+			t.Errorf("different created room id, expect: %v, got: %v", RoomID, res.RoomID)
+		}
+	}
+
+	{ // case2: delete room which is not owned by the user.
+		dr := action.DeleteRoom{SenderID: UserID, RoomID: RoomID}
+		dr.ActionName = action.ActionDeleteRoom
+		if _, err := hub.handleMessage(context.Background(), actionMessageRequest{dr, conn}); err == nil {
+			t.Error("deleting the room by the user which is not the owner, but no error")
+		}
+	}
+
+	{ // case3: remove the member by the user which is not the owner nor admin.
+		rrm := action.RemoveRoomMember{SenderID: UserID, RoomID: RoomID, RemoveUserID: ThirdUserID}
+		rrm.ActionName = action.ActionRemoveRoomMember
+		_, err := hub.handleMessage(context.Background(), actionMessageRequest{rrm, conn})
+		if !domain.IsPermissionError(err) {
+			t.Errorf("removing the member by the user which is not the owner, expect permission error, got: %v", err)
+		}
+	}
+
+	{ // case4: rename the room by the user which is not the owner nor admin.
+		rr := action.RenameRoom{SenderID: UserID, RoomID: RoomID, RoomName: "renamed"}
+		rr.ActionName = action.ActionRenameRoom
+		_, err := hub.handleMessage(context.Background(), actionMessageRequest{rr, conn})
+		if got := ErrorCodeOf(err); got != ErrorCodeForbidden {
+			t.Errorf("different error code for renaming the room, expect: %v, got: %v", ErrorCodeForbidden, got)
+		}
+	}
+
+	{ // case5: change the role by the user which is not the owner nor admin.
+		crr := action.ChangeRoomMemberRole{SenderID: UserID, RoomID: RoomID, UserID: ThirdUserID, Role: "read_only"}
+		crr.ActionName = action.ActionChangeRoomMemberRole
+		_, err := hub.handleMessage(context.Background(), actionMessageRequest{crr, conn})
+		if got := ErrorCodeOf(err); got != ErrorCodeForbidden {
+			t.Errorf("different error code for changing the role, expect: %v, got: %v", ErrorCodeForbidden, got)
+		}
+	}
+}
+
+func TestHubListenReturnByShutdown(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		TimeoutDuration = 10 * time.Millisecond
+		SleepDuration   = 5 * time.Millisecond
+	)
+
+	pubsubCh := make(chan interface{}, 1)
+	ps := mocks.NewMockPubsub(mockCtrl)
+	ps.EXPECT().Sub(event.TypeExternal).Return(pubsubCh).Times(1)
+	ps.EXPECT().Sub(HubHandlingEventTypes).Return(pubsubCh).Times(1)
+
+	// build mock repositories.
+	repos := domain.SimpleRepositories{}
+
+	// build hub
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutDuration)
+	defer cancel()
+
+	doneCh := make(chan bool, 1)
+
+	hub := NewHubImpl(NewCommandServiceImpl(repos, ps))
+	go func() {
+		hub.Listen(ctx)
 		doneCh <- true
 	}()
 	time.Sleep(SleepDuration) // waiting for the standing the hub.Listen
@@ -451,3 +1155,348 @@ func TestHubListenReturnByShutdown(t *testing.T) {
 		return // PASS
 	}
 }
+
+// SyncEventRecorder records the sent EventJSONs, which may be sent from
+// the other goroutine.
+type SyncEventRecorder struct {
+	userID uint64
+
+	mu     sync.Mutex
+	events []EventJSON
+}
+
+func (r *SyncEventRecorder) UserID() uint64 { return r.userID }
+func (r *SyncEventRecorder) Close() error   { return nil }
+func (r *SyncEventRecorder) Send(ev event.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ev, ok := ev.(EventJSON); ok {
+		r.events = append(r.events, ev)
+	}
+}
+
+func (r *SyncEventRecorder) Sequences() []uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seqs := make([]uint64, 0, len(r.events))
+	for _, ev := range r.events {
+		seqs = append(seqs, ev.Seq)
+	}
+	return seqs
+}
+
+func TestHubConnectAfter(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		TimeoutDuration = 100 * time.Millisecond
+
+		UserID      = uint64(1)
+		OtherUserID = uint64(2)
+		MemberRoom  = uint64(1)
+		OtherRoom   = uint64(2)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), MemberRoom).
+		Return(domain.Room{ID: MemberRoom, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).
+		AnyTimes()
+	rooms.EXPECT().Find(gomock.Any(), OtherRoom).
+		Return(domain.Room{ID: OtherRoom, MemberIDSet: domain.NewUserIDSet(OtherUserID)}, nil).
+		AnyTimes()
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			return domain.User{ID: userID, FriendIDs: domain.NewUserIDSet()}, nil
+		}).AnyTimes()
+
+	liveEvents := make(chan interface{}, 8)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Sub(HubHandlingEventTypes).Return(liveEvents).Times(1)
+	pubsub.EXPECT().Pub(gomock.Any()).
+		Do(func(ev event.Event) { liveEvents <- ev }).
+		AnyTimes()
+
+	events := mocks.NewMockEventQueryer(mockCtrl)
+	// the cursor is verified before replaying.
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(0), 1).
+		Return([]event.Record{{Seq: 1, Event: event.MessageCreated{RoomID: MemberRoom}}}, nil).
+		Times(1)
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(1), replayPageSize).
+		Return([]event.Record{
+			{Seq: 2, Event: event.MessageCreated{RoomID: OtherRoom}},
+			{Seq: 3, Event: event.MessageCreated{RoomID: MemberRoom}},
+			{Seq: 4, Event: event.UserCreated{UserID: UserID}},
+			{Seq: 5, Event: event.FriendRequestSent{SenderID: OtherUserID, ReceiverID: UserID}},
+		}, nil).
+		Times(1)
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(98), 1).
+		Return(nil, NewNotFoundError("not found")).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Return(domain.Message{}, nil).
+		AnyTimes()
+
+	commandService := NewCommandServiceImpl(domain.SimpleRepositories{
+		RoomRepository:    rooms,
+		UserRepository:    users,
+		MessageRepository: msgs,
+	}, pubsub)
+
+	// the hub without EventQueryer can not replay.
+	if err := NewHubImpl(commandService).ConnectAfter(context.Background(), &SyncEventRecorder{userID: UserID}, 1); err != ErrReplayNotSupported {
+		t.Errorf("expect ErrReplayNotSupported, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHubImplWithEvents(commandService, events)
+	go hub.eventSendingService(ctx)
+	defer hub.Shutdown()
+
+	// unknown cursor
+	invalid := &SyncEventRecorder{userID: UserID}
+	if err := hub.ConnectAfter(ctx, invalid, 99); !IsNotFoundError(err) {
+		t.Errorf("expect NotFoundError for the unknown cursor, got %v", err)
+	}
+	if hub.activeClients.ExistByConn(invalid) {
+		t.Error("the connection with the unknown cursor is connected")
+	}
+
+	conn := &SyncEventRecorder{userID: UserID}
+	if err := hub.ConnectAfter(ctx, conn, 1); err != nil {
+		t.Fatal(err)
+	}
+	if expect, got := []uint64{3, 5}, conn.Sequences(); !reflect.DeepEqual(expect, got) {
+		t.Fatalf("different replayed events, expect sequences %v, got %v", expect, got)
+	}
+
+	// the live event already replayed is not sent again.
+	commandService.pubsub.Pub(event.WithSequence(event.MessageCreated{RoomID: MemberRoom}, 3))
+	commandService.pubsub.Pub(event.WithSequence(event.MessageCreated{RoomID: MemberRoom}, 6))
+
+	// replayed events, activated event and the live event.
+	expect := []uint64{3, 5, 0, 6}
+	timeout := time.After(TimeoutDuration)
+	for len(conn.Sequences()) < len(expect) {
+		select {
+		case <-timeout:
+			t.Fatalf("timeout: expect sequences %v, got %v", expect, conn.Sequences())
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// waits for the rest of events if any.
+	time.Sleep(10 * time.Millisecond)
+	if got := conn.Sequences(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("different sent events, expect sequences %v, got %v", expect, got)
+	}
+}
+
+// blockingEventRecorder is a SyncEventRecorder which blocks
+// at the first sending until released.
+type blockingEventRecorder struct {
+	SyncEventRecorder
+	blocked chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (r *blockingEventRecorder) Send(ev event.Event) {
+	r.once.Do(func() {
+		close(r.blocked)
+		<-r.release
+	})
+	r.SyncEventRecorder.Send(ev)
+}
+
+func TestHubConnectAfterNotBlockingLiveEvents(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		TimeoutDuration = 100 * time.Millisecond
+
+		UserID      = uint64(1)
+		OtherUserID = uint64(2)
+		RoomID      = uint64(1)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID, OtherUserID)}, nil).
+		AnyTimes()
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			return domain.User{ID: userID, FriendIDs: domain.NewUserIDSet()}, nil
+		}).AnyTimes()
+
+	liveEvents := make(chan interface{}, 8)
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	pubsub.EXPECT().Sub(HubHandlingEventTypes).Return(liveEvents).Times(1)
+	pubsub.EXPECT().Pub(gomock.Any()).
+		Do(func(ev event.Event) { liveEvents <- ev }).
+		AnyTimes()
+
+	events := mocks.NewMockEventQueryer(mockCtrl)
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(0), 1).
+		Return([]event.Record{{Seq: 1, Event: event.MessageCreated{RoomID: RoomID}}}, nil).
+		Times(1)
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(1), replayPageSize).
+		Return([]event.Record{{Seq: 2, Event: event.MessageCreated{RoomID: RoomID}}}, nil).
+		Times(1)
+	// the event stored during replaying is replayed again.
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(2), replayPageSize).
+		Return([]event.Record{{Seq: 3, Event: event.MessageCreated{RoomID: RoomID}}}, nil).
+		Times(1)
+
+	msgs := mocks.NewMockMessageRepository(mockCtrl)
+	msgs.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Return(domain.Message{}, nil).
+		AnyTimes()
+
+	commandService := NewCommandServiceImpl(domain.SimpleRepositories{
+		RoomRepository:    rooms,
+		UserRepository:    users,
+		MessageRepository: msgs,
+	}, pubsub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHubImplWithEvents(commandService, events)
+	go hub.eventSendingService(ctx)
+	defer hub.Shutdown()
+
+	other := &SyncEventRecorder{userID: OtherUserID}
+	if err := hub.Connect(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &blockingEventRecorder{
+		SyncEventRecorder: SyncEventRecorder{userID: UserID},
+		blocked:           make(chan struct{}),
+		release:           make(chan struct{}),
+	}
+	done := make(chan error, 1)
+	go func() { done <- hub.ConnectAfter(ctx, conn, 1) }()
+
+	select {
+	case <-conn.blocked:
+	case <-time.After(TimeoutDuration):
+		t.Fatal("timeout: replaying is not started")
+	}
+
+	// the live event is sent to the other while replaying is blocked.
+	commandService.pubsub.Pub(event.WithSequence(event.MessageCreated{RoomID: RoomID}, 3))
+	timeout := time.After(TimeoutDuration)
+	for !containsID(other.Sequences(), 3) {
+		select {
+		case <-timeout:
+			t.Fatalf("timeout: live event is not sent while replaying, got %v", other.Sequences())
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	close(conn.release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(TimeoutDuration):
+		t.Fatal("timeout: ConnectAfter does not return")
+	}
+
+	commandService.pubsub.Pub(event.WithSequence(event.MessageCreated{RoomID: RoomID}, 4))
+
+	// replayed events including the one sent while replaying,
+	// activated event and the live event.
+	expect := []uint64{2, 3, 0, 4}
+	timeout = time.After(TimeoutDuration)
+	for len(conn.Sequences()) < len(expect) {
+		select {
+		case <-timeout:
+			t.Fatalf("timeout: expect sequences %v, got %v", expect, conn.Sequences())
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// waits for the rest of events if any.
+	time.Sleep(10 * time.Millisecond)
+	if got := conn.Sequences(); !reflect.DeepEqual(expect, got) {
+		t.Errorf("different sent events, expect sequences %v, got %v", expect, got)
+	}
+}
+
+func TestHubConnectAfterFailsOnRepositoryError(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID     = uint64(1)
+		RoomID     = uint64(1)
+		BrokenRoom = uint64(2)
+	)
+
+	rooms := mocks.NewMockRoomRepository(mockCtrl)
+	rooms.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{}, NewNotFoundError("room not found")).
+		AnyTimes()
+	rooms.EXPECT().Find(gomock.Any(), BrokenRoom).
+		Return(domain.Room{}, NewInfraError("temporary failure")).
+		AnyTimes()
+
+	users := mocks.NewMockUserRepository(mockCtrl)
+	users.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).
+		AnyTimes()
+
+	pubsub := mocks.NewMockPubsub(mockCtrl)
+	// the replay fails before the handover to the running hub.
+	pubsub.EXPECT().Sub(HubHandlingEventTypes).Return(make(chan interface{})).MaxTimes(1)
+	pubsub.EXPECT().Pub(gomock.Any()).Times(0)
+
+	events := mocks.NewMockEventQueryer(mockCtrl)
+	events.EXPECT().FindAllBySequence(gomock.Any(), uint64(0), replayPageSize).
+		Return([]event.Record{
+			// the event for the deleted room is skipped.
+			{Seq: 1, Event: event.MessageCreated{RoomID: RoomID}},
+			{Seq: 2, Event: event.MessageCreated{RoomID: BrokenRoom}},
+		}, nil).
+		Times(1)
+
+	commandService := NewCommandServiceImpl(domain.SimpleRepositories{
+		RoomRepository: rooms,
+		UserRepository: users,
+	}, pubsub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHubImplWithEvents(commandService, events)
+	go hub.eventSendingService(ctx)
+	defer hub.Shutdown()
+
+	conn := &SyncEventRecorder{userID: UserID}
+	if err := hub.ConnectAfter(ctx, conn, 0); err == nil {
+		t.Fatal("the temporary failure is skipped, but ConnectAfter should fail")
+	}
+	if hub.activeClients.ExistByConn(conn) {
+		t.Error("the connection is connected with the gap of the replayed events")
+	}
+}
@@ -7,26 +7,75 @@ import (
 )
 
 const (
-	EventNameMessageCreated          = "message_created"
-	EventNameActiveClientActivated   = "client_activated"
-	EventNameActiveClientInactivated = "client_inactivated"
-	EventNameRoomCreated             = "room_created"
-	EventNameRoomDeleted             = "room_deleted"
-	EventNameRoomAddedMember         = "room_added_member"
-	EventNameRoomRemovedMember       = "room_removed_member"
-	EventNameRoomMessagesReadByUser  = "room_messages_read_by_user"
-	EventNameUnknown                 = "unknown"
+	EventNameMessageCreated           = "message_created"
+	EventNameMessageEdited            = "message_edited"
+	EventNameMessageDeleted           = "message_deleted"
+	EventNameActiveClientActivated    = "client_activated"
+	EventNameActiveClientInactivated  = "client_inactivated"
+	EventNameRoomCreated              = "room_created"
+	EventNameRoomDeleted              = "room_deleted"
+	EventNameRoomAddedMember          = "room_added_member"
+	EventNameRoomRemovedMember        = "room_removed_member"
+	EventNameRoomMessagesReadByUser   = "room_messages_read_by_user"
+	EventNameUserTypingStarted        = "user_typing_started"
+	EventNameUserTypingEnded          = "user_typing_ended"
+	EventNameRoomRenamed              = "room_renamed"
+	EventNameRoomMemberRoleChanged    = "room_member_role_changed"
+	EventNameRoomMemberLeft           = "room_member_left"
+	EventNameRoomOwnershipTransferred = "room_ownership_transferred"
+	EventNameRoomArchived             = "room_archived"
+	EventNameUserProfileUpdated       = "user_profile_updated"
+	EventNameUserRemovedFriend        = "user_removed_friend"
+	EventNameUserDeleted              = "user_deleted"
+	EventNameFriendRequestSent        = "friend_request_sent"
+	EventNameFriendRequestAccepted    = "friend_request_accepted"
+	EventNameFriendRequestDeclined    = "friend_request_declined"
+	EventNameFriendRequestCanceled    = "friend_request_canceled"
+	EventNameUserBlocked              = "user_blocked"
+	EventNameUserUnblocked            = "user_unblocked"
+	EventNameReactionAdded            = "reaction_added"
+	EventNameReactionRemoved          = "reaction_removed"
+	EventNameMessagePinned            = "message_pinned"
+	EventNameMessageUnpinned          = "message_unpinned"
+	EventNameUserMentioned            = "user_mentioned"
+	EventNameErrorRaised              = "error_raised"
+	EventNameAck                      = "ack"
+	EventNameUnknown                  = "unknown"
 )
 
 var eventEncodeNames = map[event.Type]string{
-	event.TypeMessageCreated:          EventNameMessageCreated,
-	event.TypeActiveClientActivated:   EventNameActiveClientActivated,
-	event.TypeActiveClientInactivated: EventNameActiveClientInactivated,
-	event.TypeRoomCreated:             EventNameRoomCreated,
-	event.TypeRoomDeleted:             EventNameRoomDeleted,
-	event.TypeRoomAddedMember:         EventNameRoomAddedMember,
-	event.TypeRoomRemovedMember:       EventNameRoomRemovedMember,
-	event.TypeRoomMessagesReadByUser:  EventNameRoomMessagesReadByUser,
+	event.TypeMessageCreated:           EventNameMessageCreated,
+	event.TypeMessageEdited:            EventNameMessageEdited,
+	event.TypeMessageDeleted:           EventNameMessageDeleted,
+	event.TypeActiveClientActivated:    EventNameActiveClientActivated,
+	event.TypeActiveClientInactivated:  EventNameActiveClientInactivated,
+	event.TypeRoomCreated:              EventNameRoomCreated,
+	event.TypeRoomDeleted:              EventNameRoomDeleted,
+	event.TypeRoomAddedMember:          EventNameRoomAddedMember,
+	event.TypeRoomRemovedMember:        EventNameRoomRemovedMember,
+	event.TypeRoomMessagesReadByUser:   EventNameRoomMessagesReadByUser,
+	event.TypeUserTypingStarted:        EventNameUserTypingStarted,
+	event.TypeUserTypingEnded:          EventNameUserTypingEnded,
+	event.TypeRoomRenamed:              EventNameRoomRenamed,
+	event.TypeRoomMemberRoleChanged:    EventNameRoomMemberRoleChanged,
+	event.TypeRoomMemberLeft:           EventNameRoomMemberLeft,
+	event.TypeRoomOwnershipTransferred: EventNameRoomOwnershipTransferred,
+	event.TypeRoomArchived:             EventNameRoomArchived,
+	event.TypeUserProfileUpdated:       EventNameUserProfileUpdated,
+	event.TypeUserRemovedFriend:        EventNameUserRemovedFriend,
+	event.TypeUserDeleted:              EventNameUserDeleted,
+	event.TypeFriendRequestSent:        EventNameFriendRequestSent,
+	event.TypeFriendRequestAccepted:    EventNameFriendRequestAccepted,
+	event.TypeFriendRequestDeclined:    EventNameFriendRequestDeclined,
+	event.TypeFriendRequestCanceled:    EventNameFriendRequestCanceled,
+	event.TypeUserBlocked:              EventNameUserBlocked,
+	event.TypeUserUnblocked:            EventNameUserUnblocked,
+	event.TypeReactionAdded:            EventNameReactionAdded,
+	event.TypeReactionRemoved:          EventNameReactionRemoved,
+	event.TypeMessagePinned:            EventNameMessagePinned,
+	event.TypeMessageUnpinned:          EventNameMessageUnpinned,
+	event.TypeUserMentioned:            EventNameUserMentioned,
+	event.TypeErrorRaised:              EventNameErrorRaised,
 }
 
 // EventJSON is a data-transfer-object
@@ -35,11 +84,17 @@ var eventEncodeNames = map[event.Type]string{
 type EventJSON struct {
 	EventName string      `json:"event"`
 	Data      event.Event `json:"data"`
+
+	// Seq is the sequence number of the stored event, which is used
+	// as the cursor to find the events after it.
+	// It is omitted for the event which is not stored.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 func (EventJSON) Type() event.Type           { return event.TypeNone }
 func (e EventJSON) Timestamp() time.Time     { return e.Data.Timestamp() }
 func (e EventJSON) StreamID() event.StreamID { return e.Data.StreamID() }
+func (e EventJSON) Sequence() uint64         { return e.Seq }
 
 func NewEventJSON(ev event.Event) EventJSON {
 	if ev == nil {
@@ -53,5 +108,6 @@ func NewEventJSON(ev event.Event) EventJSON {
 	return EventJSON{
 		EventName: eventName,
 		Data:      ev,
+		Seq:       event.SequenceOf(ev),
 	}
 }
@@ -1,6 +1,8 @@
 package chat
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -18,12 +20,36 @@ func TestEventEncodeNames(t *testing.T) {
 func TestNewEventJSON(t *testing.T) {
 	for _, ev := range []event.Event{
 		event.MessageCreated{},
+		event.MessageEdited{},
+		event.MessageDeleted{},
+		event.UserTypingStarted{},
+		event.UserTypingEnded{},
+		event.ErrorRaised{},
 		event.ActiveClientActivated{},
 		event.ActiveClientInactivated{},
 		event.RoomCreated{},
 		event.RoomDeleted{},
 		event.RoomAddedMember{},
+		event.RoomRenamed{},
+		event.RoomMemberRoleChanged{},
+		event.RoomMemberLeft{},
+		event.RoomOwnershipTransferred{},
+		event.RoomArchived{},
 		event.RoomMessagesReadByUser{},
+		event.UserProfileUpdated{},
+		event.UserRemovedFriend{},
+		event.FriendRequestSent{},
+		event.FriendRequestAccepted{},
+		event.FriendRequestDeclined{},
+		event.FriendRequestCanceled{},
+		event.UserBlocked{},
+		event.UserUnblocked{},
+		event.ReactionAdded{},
+		event.ReactionRemoved{},
+		event.MessagePinned{},
+		event.MessageUnpinned{},
+		event.UserMentioned{},
+		event.UserDeleted{},
 	} {
 		evJSON := NewEventJSON(ev)
 		if evJSON.EventName == EventNameUnknown {
@@ -35,3 +61,34 @@ func TestNewEventJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestNewEventJSONWithSequence(t *testing.T) {
+	evJSON := NewEventJSON(event.WithSequence(event.MessageCreated{}, 3))
+	if evJSON.Seq != 3 {
+		t.Errorf("different sequence number, expect: 3, got: %v", evJSON.Seq)
+	}
+	if got := event.SequenceOf(evJSON); got != 3 {
+		t.Errorf("different sequence number of EventJSON, expect: 3, got: %v", got)
+	}
+
+	data, err := json.Marshal(evJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := decoded["seq"].(float64); !ok || got != 3 {
+		t.Errorf("the sequence number is not encoded, got: %s", data)
+	}
+
+	// not stored event has no sequence number.
+	data, err = json.Marshal(NewEventJSON(event.UserTypingStarted{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(`"seq"`)) {
+		t.Errorf("the sequence number is encoded for not stored event, got: %s", data)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
 )
 
 //go:generate mockgen -destination=../internal/mocks/mock_login_service.go -package=mocks github.com/shirasudon/go-chat/chat LoginService
@@ -21,29 +22,53 @@ type LoginService interface {
 }
 
 type LoginServiceImpl struct {
-	users  UserQueryer
+	users  domain.UserRepository
+	hasher *domain.PasswordHasher
 	pubsub Pubsub
 }
 
-func NewLoginServiceImpl(users UserQueryer, pubsub Pubsub) *LoginServiceImpl {
-	if users == nil || pubsub == nil {
+func NewLoginServiceImpl(users domain.UserRepository, hasher *domain.PasswordHasher, pubsub Pubsub) *LoginServiceImpl {
+	if users == nil || hasher == nil || pubsub == nil {
 		panic("passing nil arguments")
 	}
 	return &LoginServiceImpl{
 		users:  users,
+		hasher: hasher,
 		pubsub: pubsub,
 	}
 }
 
 func (ls *LoginServiceImpl) Login(ctx context.Context, username, password string) (*queried.AuthUser, error) {
-	auth, err := ls.users.FindByNameAndPassword(ctx, username, password)
-	if err != nil {
+	user, err := ls.users.FindByName(ctx, username)
+	if err != nil && !IsNotFoundError(err) {
 		return nil, err
 	}
-	ev := eventUserLoggedIn{UserID: auth.ID}
+	// not found user and wrong password are not distinguished
+	// to hide whether the user exists. The password is verified even
+	// for the not found user so that the response time does not tell it.
+	if err != nil {
+		ls.hasher.VerifyDummy(password)
+		return nil, NewNotFoundError("user name (%v) and password are not matched", username)
+	}
+	if !user.VerifyPassword(ls.hasher, password) {
+		return nil, NewNotFoundError("user name (%v) and password are not matched", username)
+	}
+
+	// the password hashed by old algorithm or cost is rehashed transparently.
+	// failing it does not affect the login, it is retried at next login.
+	_ = withTransaction(ctx, ls.users, func(ctx context.Context) error {
+		rehashed, err := user.RehashPassword(ls.hasher, password)
+		if err != nil || !rehashed {
+			return err
+		}
+		_, err = ls.users.Store(ctx, user)
+		return err
+	})
+
+	ev := eventUserLoggedIn{UserID: user.ID}
 	ev.Occurs()
 	ls.pubsub.Pub(ev)
-	return auth, nil
+	return &queried.AuthUser{ID: user.ID, Name: user.Name}, nil
 }
 
 func (ls *LoginServiceImpl) Logout(ctx context.Context, userID uint64) {
@@ -7,6 +7,7 @@ import (
 	"github.com/golang/mock/gomock"
 
 	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/internal/mocks"
 )
 
@@ -32,11 +33,21 @@ func TestNewLoginServicePanic(t *testing.T) {
 	}
 
 	ps := mocks.NewMockPubsub(ctrl)
-	users := mocks.NewMockUserQueryer(ctrl)
-	testPanic(func() { _ = NewLoginServiceImpl(users, nil) })
-	testPanic(func() { _ = NewLoginServiceImpl(nil, ps) })
+	users := mocks.NewMockUserRepository(ctrl)
+	testPanic(func() { _ = NewLoginServiceImpl(users, testHasher, nil) })
+	testPanic(func() { _ = NewLoginServiceImpl(users, nil, ps) })
+	testPanic(func() { _ = NewLoginServiceImpl(nil, testHasher, ps) })
 }
 
+// testHasher is PasswordHasher with minimum cost to run test faster.
+var testHasher = func() *domain.PasswordHasher {
+	h, err := domain.NewPasswordHasher(domain.DefaultPasswordPolicy, domain.MinPasswordHashCost)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}()
+
 func TestLoginServiceLogin(t *testing.T) {
 	t.Parallel()
 	ctrl := gomock.NewController(t)
@@ -49,19 +60,65 @@ func TestLoginServiceLogin(t *testing.T) {
 		UserName = "name"
 		Password = "password"
 	)
-	auth := queried.AuthUser{ID: 1, Name: UserName, Password: Password}
+	hashed, err := testHasher.Hash(Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := domain.User{ID: 1, Name: UserName, Password: hashed}
+
+	users := mocks.NewMockUserRepository(ctrl)
+	users.EXPECT().FindByName(gomock.Any(), UserName).
+		Return(user, nil).Times(1)
+	users.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).Times(1)
 
-	users := mocks.NewMockUserQueryer(ctrl)
-	users.EXPECT().FindByNameAndPassword(gomock.Any(), UserName, Password).
-		Return(&auth, nil).Times(1)
+	// password hashed by current cost is not stored again.
+	users.EXPECT().Store(gomock.Any(), gomock.Any()).Times(0)
 
-	impl := NewLoginServiceImpl(users, ps)
+	impl := NewLoginServiceImpl(users, testHasher, ps)
 	got, err := impl.Login(context.Background(), UserName, Password)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if (*got) != auth {
-		t.Errorf("different AuthUser, expect: %v, got: %v", got, auth)
+	if expect := (queried.AuthUser{ID: user.ID, Name: user.Name}); *got != expect {
+		t.Errorf("different AuthUser, expect: %v, got: %v", expect, *got)
+	}
+}
+
+func TestLoginServiceLoginRehash(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ps := mocks.NewMockPubsub(ctrl)
+	ps.EXPECT().Pub(gomock.Any()).Times(1)
+
+	const (
+		UserName = "name"
+		Password = "password"
+	)
+	// the user created before hashing the password.
+	user := domain.User{ID: 1, Name: UserName, Password: domain.HashedPassword{Hash: Password}}
+
+	users := mocks.NewMockUserRepository(ctrl)
+	users.EXPECT().FindByName(gomock.Any(), UserName).
+		Return(user, nil).Times(1)
+	users.EXPECT().BeginTx(gomock.Any(), gomock.Nil()).
+		Return(domain.EmptyTxBeginner{}, nil).Times(1)
+	users.EXPECT().Store(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, u domain.User) {
+			if u.Password.Algorithm != domain.PasswordAlgorithmBcrypt {
+				t.Errorf("stored password is not rehashed, got: %v", u.Password)
+			}
+			if !u.VerifyPassword(testHasher, Password) {
+				t.Errorf("rehashed password is not verified")
+			}
+		}).
+		Return(user.ID, nil).Times(1)
+
+	impl := NewLoginServiceImpl(users, testHasher, ps)
+	if _, err := impl.Login(context.Background(), UserName, Password); err != nil {
+		t.Fatal(err)
 	}
 }
 
@@ -74,16 +131,30 @@ func TestLoginServiceLoginFail(t *testing.T) {
 		UserName = "name"
 		Password = "password"
 	)
+	hashed, err := testHasher.Hash(Password)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	ps := mocks.NewMockPubsub(ctrl)
-	users := mocks.NewMockUserQueryer(ctrl)
-	users.EXPECT().FindByNameAndPassword(gomock.Any(), UserName, Password).
-		Return(nil, NewNotFoundError("error!")).Times(1)
-
-	impl := NewLoginServiceImpl(users, ps)
-	_, err := impl.Login(context.Background(), UserName, Password)
-	if err == nil {
-		t.Errorf("user not found but no error")
+	users := mocks.NewMockUserRepository(ctrl)
+	users.EXPECT().FindByName(gomock.Any(), UserName).
+		Return(domain.User{ID: 1, Name: UserName, Password: hashed}, nil).Times(1)
+	users.EXPECT().FindByName(gomock.Any(), "not-found").
+		Return(domain.User{}, NewNotFoundError("error!")).Times(1)
+
+	impl := NewLoginServiceImpl(users, testHasher, ps)
+	for _, testcase := range []struct {
+		Name     string
+		Password string
+	}{
+		{UserName, "wrong-password"},
+		{"not-found", Password},
+	} {
+		_, err := impl.Login(context.Background(), testcase.Name, testcase.Password)
+		if !IsNotFoundError(err) {
+			t.Errorf("login with name: %v password: %v, expect NotFoundError, got: %v", testcase.Name, testcase.Password, err)
+		}
 	}
 }
 
@@ -95,9 +166,9 @@ func TestLoginServiceLogout(t *testing.T) {
 	ps := mocks.NewMockPubsub(ctrl)
 	ps.EXPECT().Pub(gomock.Any()).Times(1)
 
-	users := mocks.NewMockUserQueryer(ctrl)
+	users := mocks.NewMockUserRepository(ctrl)
 
-	impl := NewLoginServiceImpl(users, ps)
+	impl := NewLoginServiceImpl(users, testHasher, ps)
 	const (
 		UserID uint64 = 1
 	)
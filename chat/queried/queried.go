@@ -3,7 +3,11 @@
 
 package queried
 
-import "time"
+import (
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
 
 // EmptyRoomInfo is RoomInfo having empty fields rather than nil.
 var EmptyRoomInfo = RoomInfo{
@@ -18,6 +22,7 @@ type RoomInfo struct {
 	CreatorID   uint64              `json:"room_creator_id"`
 	Members     []RoomMemberProfile `json:"room_members"`
 	MembersSize int                 `json:"room_members_size"`
+	PinsSize    int                 `json:"room_pins_size"`
 }
 
 // RoomMemberProfile is a user profile with room specific information.
@@ -25,12 +30,16 @@ type RoomMemberProfile struct {
 	UserProfile
 
 	MessageReadAt time.Time `json:"message_read_at"`
+	Role          string    `json:"role"`
 }
 
 // EmptyUserRelation is UserRelation having empty fields rather than nil.
 var EmptyUserRelation = UserRelation{
-	Friends: []UserProfile{},
-	Rooms:   []UserRoom{},
+	Friends:                []UserProfile{},
+	Rooms:                  []UserRoom{},
+	TalkRooms:              []UserTalkRoom{},
+	IncomingFriendRequests: []UserProfile{},
+	OutgoingFriendRequests: []UserProfile{},
 }
 
 // UserRelation is the abstarct information associated with specified User.
@@ -38,14 +47,29 @@ type UserRelation struct {
 	UserProfile
 
 	Friends []UserProfile `json:"friends"`
-	Rooms   []UserRoom    `json:"rooms"`
+
+	// Rooms are the group rooms, and TalkRooms are
+	// the 1:1 rooms with the friends.
+	Rooms     []UserRoom     `json:"rooms"`
+	TalkRooms []UserTalkRoom `json:"talk_rooms"`
+
+	// the pending friend requests which the user received
+	// from others and sent to others.
+	IncomingFriendRequests []UserProfile `json:"incoming_friend_requests"`
+	OutgoingFriendRequests []UserProfile `json:"outgoing_friend_requests"`
+}
+
+// BlockedUsers is the list of the users blocked by the user.
+type BlockedUsers struct {
+	UserID uint64        `json:"user_id"`
+	Users  []UserProfile `json:"blocked_users"`
 }
 
 // AuthUser is a authenticated user information.
+// It never holds the password.
 type AuthUser struct {
-	ID       uint64 `json:"user_id"`
-	Name     string `json:"user_name"`
-	Password string `json:"password"`
+	ID   uint64 `json:"user_id"`
+	Name string `json:"user_name"`
 }
 
 // UserProfile holds information for user profile.
@@ -62,6 +86,12 @@ type UserRoom struct {
 	RoomName string `json:"room_name"`
 }
 
+// UserTalkRoom holds abstract information for the talk room.
+type UserTalkRoom struct {
+	RoomID   uint64 `json:"room_id"`
+	FriendID uint64 `json:"friend_id"`
+}
+
 // EmptyRoomMessages is RoomMessages having empty fields rather than nil.
 var EmptyRoomMessages = RoomMessages{
 	Msgs: []Message{},
@@ -79,11 +109,170 @@ type RoomMessages struct {
 	} `json:"cursor"`
 }
 
+// Message is a message information.
+// The deleted message is shown as a tombstone, which has
+// Deleted flag and empty content.
 type Message struct {
 	MessageID uint64    `json:"message_id"`
 	UserID    uint64    `json:"user_id"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
+	EditedAt  time.Time `json:"edited_at"`
+	Deleted   bool      `json:"deleted"`
+
+	// ParentID is the thread root message which the message replies to.
+	ParentID uint64 `json:"parent_id,omitempty"`
+
+	// the number of the replies and the time of the last reply
+	// in the thread, which the message is the root of.
+	ReplyCount  int       `json:"reply_count"`
+	LastReplyAt time.Time `json:"last_reply_at"`
+
+	Reactions []Reaction `json:"reactions"`
+
+	// Mentions are the IDs of the users mentioned in the message.
+	Mentions []uint64 `json:"mentions,omitempty"`
+
+	// Attachments are the IDs of the attachments on the message.
+	Attachments []uint64 `json:"attachments,omitempty"`
+}
+
+// Reaction is the aggregated reactions with the emoji to the message.
+// ReactedByMe is whether the requesting user reacted with the emoji.
+type Reaction struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// EmptyRoomPins is RoomPins having empty fields rather than nil.
+var EmptyRoomPins = RoomPins{
+	Pins: []PinnedMessage{},
+}
+
+// RoomPins is a list of the messages pinned to the room,
+// ordered by latest pinned at.
+type RoomPins struct {
+	RoomID   uint64          `json:"room_id"`
+	Pins     []PinnedMessage `json:"pins"`
+	PinsSize int             `json:"pins_size"`
+}
+
+// PinnedMessage is a message with the information for the pin.
+type PinnedMessage struct {
+	Message
+
+	PinnedBy uint64    `json:"pinned_by"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// EmptySearchedMessages is SearchedMessages having empty fields rather than nil.
+var EmptySearchedMessages = SearchedMessages{
+	Msgs: []SearchedMessage{},
+}
+
+// SearchedMessages is a list of the messages matched with
+// the Query, ordered by the relevance.
+type SearchedMessages struct {
+	Query    string            `json:"query"`
+	Msgs     []SearchedMessage `json:"messages"`
+	MsgsSize int               `json:"messages_size"`
+}
+
+// SearchedMessage is a message with the information for the search.
+// Snippet is the HTML-escaped part of the content, in which
+// the matched terms are enclosed by <em> tag.
+type SearchedMessage struct {
+	Message
+
+	RoomID  uint64  `json:"room_id"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// MessageSearchResult is a message matched with the search query.
+// Score is the relevance for the query, the larger is the more relevant.
+type MessageSearchResult struct {
+	MessageID uint64
+	Score     float64
+	Snippet   string
+}
+
+// EmptyUserMentions is UserMentions having empty fields rather than nil.
+var EmptyUserMentions = UserMentions{
+	Mentions: []Mention{},
+}
+
+// UserMentions is a list of the messages mentioning the user,
+// ordered by latest created at.
+// UnreadCount is the number of all of the unread mentions,
+// not only the ones in the list.
+type UserMentions struct {
+	UserID      uint64    `json:"user_id"`
+	Mentions    []Mention `json:"mentions"`
+	UnreadCount int       `json:"unread_count"`
+
+	Cursor struct {
+		Current time.Time `json:"current"`
+		Next    time.Time `json:"next"`
+	} `json:"cursor"`
+}
+
+// Mention is a message mentioning the user.
+// It is unread until the user reads the messages in the room.
+type Mention struct {
+	Message
+
+	RoomID uint64 `json:"room_id"`
+	Unread bool   `json:"unread"`
+}
+
+// UserEvents is the stored events which the user can receive,
+// ordered by ascending sequence number.
+// Each of the events is encoded with its event name and sequence number.
+// The cursor is the sequence number, and Next is used as the cursor
+// for the next query.
+type UserEvents struct {
+	UserID uint64        `json:"user_id"`
+	Events []event.Event `json:"events"`
+
+	Cursor struct {
+		Current uint64 `json:"current"`
+		Next    uint64 `json:"next"`
+	} `json:"cursor"`
+}
+
+// Attachment is the information of the file attached to the message.
+// MessageID is zero until the attachment is attached to the message.
+type Attachment struct {
+	AttachmentID uint64    `json:"attachment_id"`
+	UserID       uint64    `json:"user_id"`
+	RoomID       uint64    `json:"room_id"`
+	MessageID    uint64    `json:"message_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// EmptyThreadMessages is ThreadMessages having empty fields rather than nil.
+var EmptyThreadMessages = ThreadMessages{
+	Msgs: []Message{},
+}
+
+// ThreadMessages is a list of the reply messages in the thread
+// specified by the Parent message.
+type ThreadMessages struct {
+	RoomID uint64  `json:"room_id"`
+	Parent Message `json:"parent"`
+
+	Msgs []Message `json:"messages"`
+
+	Cursor struct {
+		Current time.Time `json:"current"`
+		Next    time.Time `json:"next"`
+	} `json:"cursor"`
 }
 
 // EmptyUnreadRoomMessages is UnreadRoomMessages having empty fields rather than nil.
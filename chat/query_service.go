@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/domain/event"
 )
 
@@ -15,15 +18,14 @@ import (
 
 // QueryService is the interface for the querying the information from backend datastore.
 type QueryService interface {
-	// It finds authenticated user profile matched with given user name and password.
-	// It returns queried user profile and nil when the user is found in the data-store, or
-	// returns nil and NotFoundError when the user is not found.
-	FindUserByNameAndPassword(ctx context.Context, name, password string) (*queried.AuthUser, error)
-
 	// Find the relational information of user specified by userID.
 	// It returns queried result and nil, or nil and NotFoundError if the information is not found.
 	FindUserRelation(ctx context.Context, userID uint64) (*queried.UserRelation, error)
 
+	// Find the users blocked by the user specified by userID.
+	// It returns queried result and nil, or nil and NotFoundError if the user is not found.
+	FindBlockedUsers(ctx context.Context, userID uint64) (*queried.BlockedUsers, error)
+
 	// Find the room information specified by roomID with userID.
 	// It returns queried result and nil, or nil and NotFoundError if the information is not found.
 	FindRoomInfo(ctx context.Context, userID, roomID uint64) (*queried.RoomInfo, error)
@@ -32,9 +34,29 @@ type QueryService interface {
 	// It returns queried messages and nil, or nil and InfraError if infrastructure raise some errors.
 	FindRoomMessages(ctx context.Context, userID uint64, q action.QueryRoomMessages) (*queried.RoomMessages, error)
 
+	// Find the reply messages in the thread specified by QueryThreadMessages with userID.
+	// It returns queried messages and nil, or nil and NotFoundError if the thread is not found.
+	FindThreadMessages(ctx context.Context, userID uint64, q action.QueryThreadMessages) (*queried.ThreadMessages, error)
+
+	// Find the messages pinned to the room specified by roomID with userID.
+	// It returns queried pins and nil, or nil and NotFoundError if the user is not a room member.
+	FindRoomPins(ctx context.Context, userID, roomID uint64) (*queried.RoomPins, error)
+
+	// Find the messages mentioning the user specified by userID with QueryUserMentions.
+	// It returns queried mentions and nil, or nil and NotFoundError if the user is not found.
+	FindUserMentions(ctx context.Context, userID uint64, q action.QueryUserMentions) (*queried.UserMentions, error)
+
+	// Search the messages matched with QuerySearchMessages in the rooms which user belongs to.
+	// It returns searched messages and nil, or nil and ValidationError if the query is invalid.
+	SearchMessages(ctx context.Context, userID uint64, q action.QuerySearchMessages) (*queried.SearchedMessages, error)
+
 	// Find the unread messages belonging to the room specified by QueryUnreadRoomMessages with userID.
 	// It returns queried messages and nil, or nil and InfraError if infrastructure raise some errors.
 	FindUnreadRoomMessages(ctx context.Context, userID uint64, q action.QueryUnreadRoomMessages) (*queried.UnreadRoomMessages, error)
+
+	// Find the stored events which the user specified by userID can receive, filtered by QueryUserEvents.
+	// It returns queried events and nil, or nil and ValidationError if the query is invalid.
+	FindUserEvents(ctx context.Context, userID uint64, q action.QueryUserEvents) (*queried.UserEvents, error)
 }
 
 // TODO cache feature.
@@ -45,6 +67,8 @@ type QueryServiceImpl struct {
 	rooms RoomQueryer
 	msgs  MessageQueryer
 
+	searcher MessageSearcher
+
 	events EventQueryer
 }
 
@@ -53,29 +77,145 @@ func NewQueryServiceImpl(qs *Queryers) *QueryServiceImpl {
 		panic("nil Queryers")
 	}
 	return &QueryServiceImpl{
-		users:  qs.UserQueryer,
-		rooms:  qs.RoomQueryer,
-		msgs:   qs.MessageQueryer,
-		events: qs.EventQueryer,
+		users:    qs.UserQueryer,
+		rooms:    qs.RoomQueryer,
+		msgs:     qs.MessageQueryer,
+		searcher: qs.MessageSearcher,
+		events:   qs.EventQueryer,
 	}
 }
 
-// TODO event permittion in for user id,
-func (s *QueryServiceImpl) FindEventsByTimeCursor(ctx context.Context, after time.Time, limit int) ([]event.Event, error) {
-	evs, err := s.events.FindAllByTimeCursor(ctx, after, limit)
-	if err != nil && IsNotFoundError(err) {
-		return []event.Event{}, nil
+// Find the events after the sequence number, which the user can receive.
+// The events are filtered by the same permission as sending the live
+// events, that is, the events for the rooms which the user belongs to,
+// the presence of the friends and the user stream of the user-self.
+// It returns the events, the sequence number of the last
+// scanned event and error if infrastructure raise some errors.
+func (s *QueryServiceImpl) FindEventsBySequence(ctx context.Context, userID uint64, after uint64, limit int) ([]event.Record, uint64, error) {
+	return s.findPermittedEvents(ctx, userID, after, limit, s.events.FindAllBySequence)
+}
+
+// Find the events associated with the stream after the sequence number,
+// which the user can receive.
+// It returns the events, the sequence number of the last
+// scanned event and error if infrastructure raise some errors.
+func (s *QueryServiceImpl) FindEventsByStreamIDSequence(ctx context.Context, userID uint64, streamID event.StreamID, after uint64, limit int) ([]event.Record, uint64, error) {
+	return s.findPermittedEvents(ctx, userID, after, limit, func(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+		return s.events.FindAllByStreamIDSequence(ctx, streamID, after, limit)
+	})
+}
+
+// MaxScannedUserEvents is the maximum number of the stored events scanned
+// for a query, so that the query for the user who can receive few events
+// does not scan whole of the events at once.
+const MaxScannedUserEvents = 1000
+
+// findPermittedEvents finds the events by findEvents and filters those
+// by the permission of the user, until the number of events reaches the limit
+// or the number of scanned events reaches MaxScannedUserEvents.
+// The events are paged by the sequence number so that the events having
+// the same timestamp are not dropped at the page boundaries.
+// It also returns the sequence number of the last scanned event, which is
+// used as the cursor for the next query.
+func (s *QueryServiceImpl) findPermittedEvents(
+	ctx context.Context,
+	userID uint64,
+	after uint64,
+	limit int,
+	findEvents func(ctx context.Context, after uint64, limit int) ([]event.Record, error),
+) ([]event.Record, uint64, error) {
+	permitted := make([]event.Record, 0, limit)
+	for scanned := 0; len(permitted) < limit && scanned < MaxScannedUserEvents; {
+		n := limit
+		if rest := MaxScannedUserEvents - scanned; n > rest {
+			n = rest
+		}
+		records, err := findEvents(ctx, after, n)
+		if err != nil && !IsNotFoundError(err) {
+			return nil, 0, err
+		}
+
+		for _, r := range records {
+			targetIDs, err := eventTargetIDs(ctx, s.users, s.rooms, r.Event)
+			if err != nil && !IsNotFoundError(err) {
+				return nil, 0, err
+			}
+			// the event for the deleted room and so on can not be sent.
+			if err == nil && containsID(targetIDs, userID) {
+				if r.Event, err = redactDeletedMessage(ctx, s.msgs, r.Event); err != nil {
+					return nil, 0, err
+				}
+				permitted = append(permitted, r)
+			}
+			after = r.Seq
+			scanned++
+			if len(permitted) == limit {
+				break
+			}
+		}
+
+		// no more events.
+		if len(records) < n {
+			break
+		}
 	}
-	return evs, err
+	return permitted, after, nil
 }
 
-// Find user profile matched with user name and password.
-// It returns queried user profile and nil when found in the data-store.
-// It returns nil and error when the user is not found.
-func (s *QueryServiceImpl) FindUserByNameAndPassword(ctx context.Context, name, password string) (*queried.AuthUser, error) {
-	user, err := s.users.FindByNameAndPassword(ctx, name, password)
-	// TODO cache?
-	return user, err
+// MaxUserEventsLimit is the maximum number of the events
+// for FindUserEvents.
+const MaxUserEventsLimit = 100
+
+// eventStreams maps the stream name in the query to the StreamID.
+var eventStreams = map[string]event.StreamID{
+	"user":    event.UserStream,
+	"room":    event.RoomStream,
+	"message": event.MessageStream,
+}
+
+// Find the stored events which the user can receive, after the sequence number.
+// The events are encoded by EventJSON with its sequence number, and ordered
+// by ascending sequence number.
+// It returns error if the query is invalid, the user is not found or
+// infrastructure raise some errors.
+func (s *QueryServiceImpl) FindUserEvents(ctx context.Context, userID uint64, q action.QueryUserEvents) (*queried.UserEvents, error) {
+	// check query paramnter
+	if q.Limit > MaxUserEventsLimit || q.Limit <= 0 {
+		q.Limit = MaxUserEventsLimit
+	}
+
+	if _, err := s.users.Find(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var (
+		records []event.Record
+		next    uint64
+		err     error
+	)
+	if q.Stream == "" {
+		records, next, err = s.FindEventsBySequence(ctx, userID, q.After, q.Limit)
+	} else {
+		streamID, ok := eventStreams[q.Stream]
+		if !ok {
+			return nil, domain.NewValidationError("unknown event stream %q", q.Stream)
+		}
+		records, next, err = s.FindEventsByStreamIDSequence(ctx, userID, streamID, q.After, q.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userEvents := &queried.UserEvents{
+		UserID: userID,
+		Events: make([]event.Event, 0, len(records)),
+	}
+	for _, r := range records {
+		userEvents.Events = append(userEvents.Events, NewEventJSON(event.WithSequence(r.Event, r.Seq)))
+	}
+	userEvents.Cursor.Current = q.After
+	userEvents.Cursor.Next = next
+	return userEvents, nil
 }
 
 // Find abstract information associated with the User.
@@ -86,6 +226,37 @@ func (s *QueryServiceImpl) FindUserRelation(ctx context.Context, userID uint64)
 	return relation, err
 }
 
+// Find the users blocked by the user.
+// The blocked users which are already deleted are not contained.
+// It returns queried result and error if the user is not found.
+func (s *QueryServiceImpl) FindBlockedUsers(ctx context.Context, userID uint64) (*queried.BlockedUsers, error) {
+	user, err := s.users.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := &queried.BlockedUsers{
+		UserID: userID,
+		Users:  make([]queried.UserProfile, 0, len(user.BlockedUserIDs.List())),
+	}
+	for _, id := range user.BlockedUserIDs.List() {
+		u, err := s.users.Find(ctx, id)
+		if err != nil {
+			if IsNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+		blocked.Users = append(blocked.Users, queried.UserProfile{
+			UserID:    u.ID,
+			UserName:  u.Name,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+		})
+	}
+	return blocked, nil
+}
+
 // Find detailed room information specified by room ID.
 // It also requires userID to query the information which
 // can be permmited to the user.
@@ -152,19 +323,240 @@ func (s *QueryServiceImpl) FindRoomMessages(ctx context.Context, userID uint64,
 
 	qMsgs := make([]queried.Message, 0, len(msgs))
 	for _, m := range msgs {
-		qm := queried.Message{
-			MessageID: m.ID,
-			UserID:    m.UserID,
-			Content:   m.Content,
-			CreatedAt: m.CreatedAt,
-		}
-		qMsgs = append(qMsgs, qm)
+		qMsgs = append(qMsgs, newQueriedMessage(m, userID))
 	}
 	roomMsgs.Msgs = qMsgs
 
 	return roomMsgs, nil
 }
 
+// NewQueriedReactions aggregates the reactions to the message
+// for the user requesting the message.
+func NewQueriedReactions(reactions domain.ReactionSet, userID uint64) []queried.Reaction {
+	emojis := reactions.Emojis()
+	qReactions := make([]queried.Reaction, 0, len(emojis))
+	for _, emoji := range emojis {
+		qReactions = append(qReactions, queried.Reaction{
+			Emoji:       emoji,
+			Count:       len(reactions.UserIDs(emoji)),
+			ReactedByMe: reactions.Has(emoji, userID),
+		})
+	}
+	return qReactions
+}
+
+// newQueriedMessage converts the domain message to the queried one
+// for the user requesting the message.
+func newQueriedMessage(m domain.Message, userID uint64) queried.Message {
+	qm := queried.Message{
+		MessageID:   m.ID,
+		UserID:      m.UserID,
+		Content:     m.Content,
+		CreatedAt:   m.CreatedAt,
+		EditedAt:    m.EditedAt,
+		Deleted:     m.Deleted,
+		ParentID:    m.ParentID,
+		ReplyCount:  m.ReplyCount,
+		LastReplyAt: m.LastReplyAt,
+		Reactions:   NewQueriedReactions(m.Reactions, userID),
+		Mentions:    m.MentionedIDs(),
+		Attachments: m.AttachmentIDs,
+	}
+	// deleted message is shown as tombstone.
+	if m.Deleted {
+		qm.Content = ""
+		qm.Attachments = nil
+	}
+	return qm
+}
+
+// Find the reply messages in the thread, which is specified by
+// the root message in the room.
+// It returns error if the thread is not found or infrastructure raise some errors.
+func (s *QueryServiceImpl) FindThreadMessages(ctx context.Context, userID uint64, q action.QueryThreadMessages) (*queried.ThreadMessages, error) {
+	// check query paramnter
+	if q.Limit > MaxRoomMessagesLimit || q.Limit <= 0 {
+		q.Limit = MaxRoomMessagesLimit
+	}
+
+	if q.Before.Time().Equal(time.Time{}) {
+		q.Before = action.TimestampNow()
+	}
+
+	r, err := s.rooms.Find(ctx, q.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !r.HasMember(u) {
+		return nil, fmt.Errorf("can not get the messages from room(id=%d) by not a room member user(id=%d)", q.RoomID, userID)
+	}
+
+	parent, err := s.msgs.Find(ctx, q.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.RoomID != q.RoomID || parent.IsReply() {
+		return nil, NewNotFoundError("thread (id=%v) is not found in the room (id=%v)", q.MessageID, q.RoomID)
+	}
+
+	msgs, err := s.msgs.FindThreadMessagesOrderByLatest(ctx, parent.ID, q.Before.Time(), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	threadMsgs := &queried.ThreadMessages{
+		RoomID: q.RoomID,
+		Parent: newQueriedMessage(parent, userID),
+		Msgs:   make([]queried.Message, 0, len(msgs)),
+	}
+	threadMsgs.Cursor.Current = q.Before.Time()
+	if last := len(msgs) - 1; last >= 0 {
+		threadMsgs.Cursor.Next = msgs[last].CreatedAt
+	} else {
+		threadMsgs.Cursor.Next = q.Before.Time()
+	}
+	for _, m := range msgs {
+		threadMsgs.Msgs = append(threadMsgs.Msgs, newQueriedMessage(m, userID))
+	}
+	return threadMsgs, nil
+}
+
+// Find the messages pinned to the room.
+// It returns error if the user is not a room member or infrastructure raise some errors.
+func (s *QueryServiceImpl) FindRoomPins(ctx context.Context, userID, roomID uint64) (*queried.RoomPins, error) {
+	r, err := s.rooms.Find(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !r.MemberIDSet.Has(userID) {
+		return nil, NewNotFoundError("user (id=%v) is not a member of the room (id=%v)", userID, roomID)
+	}
+
+	pins := r.Pins.List()
+	roomPins := &queried.RoomPins{
+		RoomID: roomID,
+		Pins:   make([]queried.PinnedMessage, 0, len(pins)),
+	}
+	for _, pin := range pins {
+		m, err := s.msgs.Find(ctx, pin.MessageID)
+		if IsNotFoundError(err) {
+			continue // skip the message no longer in the datastore.
+		}
+		if err != nil {
+			return nil, err
+		}
+		roomPins.Pins = append(roomPins.Pins, queried.PinnedMessage{
+			Message:  newQueriedMessage(m, userID),
+			PinnedBy: pin.PinnedBy,
+			PinnedAt: pin.PinnedAt,
+		})
+	}
+	roomPins.PinsSize = len(roomPins.Pins)
+	return roomPins, nil
+}
+
+// Find the messages mentioning the user, ordered by latest created at.
+// The mentions are read when the user reads the messages in the room.
+// It returns error if the user is not found or infrastructure raise some errors.
+func (s *QueryServiceImpl) FindUserMentions(ctx context.Context, userID uint64, q action.QueryUserMentions) (*queried.UserMentions, error) {
+	// check query paramnter
+	if q.Limit > MaxRoomMessagesLimit || q.Limit <= 0 {
+		q.Limit = MaxRoomMessagesLimit
+	}
+
+	if q.Before.Time().Equal(time.Time{}) {
+		q.Before = action.TimestampNow()
+	}
+
+	if _, err := s.users.Find(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	mentions, err := s.msgs.FindUserMentions(ctx, userID, q.Before.Time(), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions.Cursor.Current = q.Before.Time()
+	if last := len(mentions.Mentions) - 1; last >= 0 {
+		mentions.Cursor.Next = mentions.Mentions[last].CreatedAt
+	} else {
+		mentions.Cursor.Next = q.Before.Time()
+	}
+	return mentions, nil
+}
+
+// maximum length of the query text to search the messages in characters.
+const maxSearchQueryLength = 256
+
+// Search the messages matched with the query text in the rooms which user belongs to.
+// The messages are ordered by the relevance.
+// It returns error if the query is invalid or infrastructure raise some errors.
+func (s *QueryServiceImpl) SearchMessages(ctx context.Context, userID uint64, q action.QuerySearchMessages) (*queried.SearchedMessages, error) {
+	// check query paramnter
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Query == "" {
+		return nil, domain.NewValidationError("query text to search the messages is empty")
+	}
+	if utf8.RuneCountInString(q.Query) > maxSearchQueryLength {
+		return nil, domain.NewValidationError("query text to search the messages must be at most %d characters", maxSearchQueryLength)
+	}
+	if q.Limit > MaxRoomMessagesLimit || q.Limit <= 0 {
+		q.Limit = MaxRoomMessagesLimit
+	}
+	if s.searcher == nil {
+		return nil, NewInfraError("searching the messages is not supported")
+	}
+
+	rooms, err := s.rooms.FindAllByUserID(ctx, userID)
+	if err != nil && !IsNotFoundError(err) {
+		return nil, err
+	}
+	roomIDs := make([]uint64, 0, len(rooms))
+	for _, r := range rooms {
+		if q.RoomID == 0 || q.RoomID == r.ID {
+			roomIDs = append(roomIDs, r.ID)
+		}
+	}
+	if q.RoomID != 0 && len(roomIDs) == 0 {
+		return nil, NewNotFoundError("user (id=%v) is not a member of the room (id=%v)", userID, q.RoomID)
+	}
+
+	results, err := s.searcher.SearchMessages(ctx, roomIDs, q)
+	if err != nil {
+		return nil, err
+	}
+
+	searched := &queried.SearchedMessages{
+		Query: q.Query,
+		Msgs:  make([]queried.SearchedMessage, 0, len(results)),
+	}
+	for _, res := range results {
+		m, err := s.msgs.Find(ctx, res.MessageID)
+		if IsNotFoundError(err) {
+			continue // skip the message no longer in the datastore.
+		}
+		if err != nil {
+			return nil, err
+		}
+		if m.Deleted {
+			continue
+		}
+		searched.Msgs = append(searched.Msgs, queried.SearchedMessage{
+			Message: newQueriedMessage(m, userID),
+			RoomID:  m.RoomID,
+			Score:   res.Score,
+			Snippet: res.Snippet,
+		})
+	}
+	searched.MsgsSize = len(searched.Msgs)
+	return searched, nil
+}
+
 // Find unread messages from specified room.
 // It returns error if infrastructure raise some errors.
 func (s *QueryServiceImpl) FindUnreadRoomMessages(ctx context.Context, userID uint64, q action.QueryUnreadRoomMessages) (*queried.UnreadRoomMessages, error) {
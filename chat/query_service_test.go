@@ -2,6 +2,7 @@ package chat
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/chat/queried"
 	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
 	"github.com/shirasudon/go-chat/internal/mocks"
 )
 
@@ -19,37 +21,6 @@ func TestQueryServiceImplement(t *testing.T) {
 	_ = qs
 }
 
-func TestQueryServiceFindUserByNameAndPassword(t *testing.T) {
-	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
-
-	var (
-		user = &queried.AuthUser{
-			ID:       1,
-			Name:     "user",
-			Password: "password",
-		}
-	)
-
-	userQr := mocks.NewMockUserQueryer(mockCtrl)
-	userQr.EXPECT().
-		FindByNameAndPassword(gomock.Any(), user.Name, user.Password).
-		Return(user, nil).
-		Times(1)
-
-	qservice := NewQueryServiceImpl(&Queryers{
-		UserQueryer: userQr,
-	})
-
-	res, err := qservice.FindUserByNameAndPassword(context.Background(), user.Name, user.Password)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if user != res {
-		t.Errorf("different queried result, expect: %v, got: %v", user, res)
-	}
-}
-
 func TestQueryServiceFindRoomInfo(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -162,6 +133,75 @@ func TestQueryServiceFindRoomMessagesSuccess(t *testing.T) {
 	}
 }
 
+func TestQueryServiceFindThreadMessages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		user = domain.User{ID: 1}
+		room = domain.Room{
+			ID:          1,
+			MemberIDSet: domain.NewUserIDSet(user.ID),
+		}
+		parent = domain.Message{ID: 2, RoomID: room.ID, Content: "root", ReplyCount: 1}
+	)
+
+	query := action.QueryThreadMessages{
+		RoomID:    room.ID,
+		MessageID: parent.ID,
+		Before:    action.TimestampNow(),
+		Limit:     10,
+	}
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), room.ID).Return(room, nil).AnyTimes()
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), user.ID).Return(user, nil).AnyTimes()
+
+	replies := []domain.Message{
+		{
+			ID:        3,
+			Content:   "reply",
+			ParentID:  parent.ID,
+			CreatedAt: query.Before.Time().Add(-100 * time.Millisecond),
+		},
+	}
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), parent.ID).Return(parent, nil).Times(1)
+	msgQr.EXPECT().Find(gomock.Any(), replies[0].ID).Return(replies[0], nil).Times(1)
+	msgQr.EXPECT().
+		FindThreadMessagesOrderByLatest(gomock.Any(), parent.ID, query.Before.Time(), query.Limit).
+		Return(replies, nil).
+		Times(1)
+
+	qservice := NewQueryServiceImpl(&Queryers{
+		MessageQueryer: msgQr,
+		RoomQueryer:    roomQr,
+		UserQueryer:    userQr,
+	})
+
+	msgs, err := qservice.FindThreadMessages(context.Background(), user.ID, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs.Parent.MessageID != parent.ID || msgs.Parent.ReplyCount != parent.ReplyCount {
+		t.Errorf("different parent message: %#v", msgs.Parent)
+	}
+	if got, expect := msgs.Cursor.Next, replies[0].CreatedAt; !expect.Equal(got) {
+		t.Errorf("different next cursor, expect: %v, got: %v", expect, got)
+	}
+	if len(msgs.Msgs) != 1 || msgs.Msgs[0].ParentID != parent.ID {
+		t.Errorf("different thread messages: %#v", msgs.Msgs)
+	}
+
+	// the reply is not a thread root.
+	query.MessageID = replies[0].ID
+	if _, err := qservice.FindThreadMessages(context.Background(), user.ID, query); !IsNotFoundError(err) {
+		t.Errorf("query the thread of the reply, expect NotFoundError, got: %v", err)
+	}
+}
+
 func TestQueryServiceFindRoomMessagesInvalidParameter(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -330,3 +370,556 @@ func TestQueryServiceFindUnreadRoomMessages(t *testing.T) {
 		t.Errorf("different message size, expect: %v, got: %v", 2, got.MsgsSize)
 	}
 }
+
+func TestQueryServiceFindBlockedUsers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		user    = domain.User{ID: 1, BlockedUserIDs: domain.NewUserIDSet(2, 3)}
+		blocked = domain.User{ID: 2, Name: "blocked"}
+	)
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), user.ID).Return(user, nil).Times(1)
+	userQr.EXPECT().Find(gomock.Any(), blocked.ID).Return(blocked, nil).Times(1)
+	// the deleted user is not contained.
+	userQr.EXPECT().Find(gomock.Any(), uint64(3)).Return(domain.User{}, NewNotFoundError("not found")).Times(1)
+
+	qservice := NewQueryServiceImpl(&Queryers{UserQueryer: userQr})
+
+	got, err := qservice.FindBlockedUsers(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != user.ID {
+		t.Errorf("different user id, expect: %v, got: %v", user.ID, got.UserID)
+	}
+	if len(got.Users) != 1 || got.Users[0].UserID != blocked.ID || got.Users[0].UserName != blocked.Name {
+		t.Errorf("different blocked users: %#v", got.Users)
+	}
+}
+
+func TestNewQueriedReactions(t *testing.T) {
+	const UserID = uint64(1)
+
+	reactions := domain.NewReactionSet()
+	reactions.Add(":smile:", UserID+1)
+	reactions.Add(":+1:", UserID)
+	reactions.Add(":+1:", UserID+1)
+
+	got := NewQueriedReactions(reactions, UserID)
+	expect := []queried.Reaction{
+		{Emoji: ":+1:", Count: 2, ReactedByMe: true},
+		{Emoji: ":smile:", Count: 1, ReactedByMe: false},
+	}
+	if !reflect.DeepEqual(expect, got) {
+		t.Errorf("different queried reactions, expect: %#v, got: %#v", expect, got)
+	}
+
+	// no reactions should be empty list rather than nil.
+	if got := NewQueriedReactions(domain.ReactionSet{}, UserID); got == nil || len(got) != 0 {
+		t.Errorf("no reactions should be empty list, got: %#v", got)
+	}
+}
+
+func TestQueryServiceFindRoomPins(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		UserID  = uint64(1)
+		Now     = time.Now()
+		Message = domain.Message{ID: 1, RoomID: 1, UserID: UserID, Content: "pinned"}
+		Room    = domain.Room{
+			ID:          1,
+			MemberIDSet: domain.NewUserIDSet(UserID),
+			Pins: domain.NewPinSet(
+				domain.Pin{MessageID: Message.ID, PinnedBy: UserID, PinnedAt: Now},
+				domain.Pin{MessageID: 2, PinnedBy: UserID, PinnedAt: Now.Add(-time.Second)},
+			),
+		}
+	)
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), Room.ID).Return(Room, nil).Times(2)
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), Message.ID).Return(Message, nil).Times(1)
+	// the message not found is not contained.
+	msgQr.EXPECT().Find(gomock.Any(), uint64(2)).Return(domain.Message{}, NewNotFoundError("not found")).Times(1)
+
+	qservice := NewQueryServiceImpl(&Queryers{RoomQueryer: roomQr, MessageQueryer: msgQr})
+
+	// case1: success
+	got, err := qservice.FindRoomPins(context.Background(), UserID, Room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RoomID != Room.ID || got.PinsSize != 1 || len(got.Pins) != 1 {
+		t.Fatalf("different room pins: %#v", got)
+	}
+	if pin := got.Pins[0]; pin.MessageID != Message.ID || pin.Content != Message.Content ||
+		pin.PinnedBy != UserID || !pin.PinnedAt.Equal(Now) {
+		t.Errorf("different pinned message: %#v", pin)
+	}
+
+	// case2: not a member
+	if _, err := qservice.FindRoomPins(context.Background(), UserID+1, Room.ID); !IsNotFoundError(err) {
+		t.Errorf("query by not a room member, expect NotFoundError, got: %v", err)
+	}
+}
+
+func TestQueryServiceSearchMessages(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		UserID   = uint64(1)
+		Rooms    = []domain.Room{{ID: 1}, {ID: 2}}
+		Message  = domain.Message{ID: 1, RoomID: 2, UserID: UserID, Content: "hello world"}
+		Deleted  = domain.Message{ID: 2, RoomID: 1, UserID: UserID, Deleted: true}
+		Query    = action.QuerySearchMessages{Query: " hello ", Limit: 10}
+		Expected = action.QuerySearchMessages{Query: "hello", Limit: 10}
+	)
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().FindAllByUserID(gomock.Any(), UserID).Return(Rooms, nil).Times(3)
+
+	searcher := mocks.NewMockMessageSearcher(mockCtrl)
+	searcher.EXPECT().SearchMessages(gomock.Any(), []uint64{1, 2}, Expected).
+		Return([]queried.MessageSearchResult{
+			{MessageID: Message.ID, Score: 2, Snippet: "<em>hello</em> world"},
+			{MessageID: Deleted.ID, Score: 1},
+			{MessageID: 3, Score: 0.5},
+		}, nil).Times(1)
+	roomFiltered := Expected
+	roomFiltered.RoomID = 2
+	searcher.EXPECT().SearchMessages(gomock.Any(), []uint64{2}, roomFiltered).
+		Return([]queried.MessageSearchResult{}, nil).Times(1)
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), Message.ID).Return(Message, nil).Times(1)
+	// the deleted message and the message not found are not contained.
+	msgQr.EXPECT().Find(gomock.Any(), Deleted.ID).Return(Deleted, nil).Times(1)
+	msgQr.EXPECT().Find(gomock.Any(), uint64(3)).Return(domain.Message{}, NewNotFoundError("not found")).Times(1)
+
+	qservice := NewQueryServiceImpl(&Queryers{RoomQueryer: roomQr, MessageQueryer: msgQr, MessageSearcher: searcher})
+
+	// case1: success
+	got, err := qservice.SearchMessages(context.Background(), UserID, Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Query != Expected.Query || got.MsgsSize != 1 || len(got.Msgs) != 1 {
+		t.Fatalf("different searched messages: %#v", got)
+	}
+	if m := got.Msgs[0]; m.MessageID != Message.ID || m.RoomID != Message.RoomID ||
+		m.Content != Message.Content || m.Score != 2 || m.Snippet != "<em>hello</em> world" {
+		t.Errorf("different searched message: %#v", m)
+	}
+
+	// case2: filtered by the room
+	q := Query
+	q.RoomID = 2
+	if got, err := qservice.SearchMessages(context.Background(), UserID, q); err != nil || got.MsgsSize != 0 {
+		t.Errorf("search in the room, expect empty result, got: %#v, %v", got, err)
+	}
+
+	// case3: not a member of the room
+	q.RoomID = 3
+	if _, err := qservice.SearchMessages(context.Background(), UserID, q); !IsNotFoundError(err) {
+		t.Errorf("search in the room by not a room member, expect NotFoundError, got: %v", err)
+	}
+
+	// case4: empty query
+	q = Query
+	q.Query = "  "
+	if _, err := qservice.SearchMessages(context.Background(), UserID, q); !domain.IsValidationError(err) {
+		t.Errorf("search with empty query, expect ValidationError, got: %v", err)
+	}
+}
+
+func TestQueryServiceFindUserMentions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var (
+		User   = domain.User{ID: 1}
+		Before = time.Now()
+		Query  = action.QueryUserMentions{Before: action.Timestamp(Before), Limit: 10}
+	)
+
+	res := queried.EmptyUserMentions
+	res.UserID = User.ID
+	res.Mentions = []queried.Mention{
+		{Message: queried.Message{MessageID: 2, CreatedAt: Before.Add(-time.Second)}, Unread: true},
+		{Message: queried.Message{MessageID: 1, CreatedAt: Before.Add(-2 * time.Second)}},
+	}
+	res.UnreadCount = 1
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), User.ID).Return(User, nil).Times(1)
+	userQr.EXPECT().Find(gomock.Any(), User.ID+1).Return(domain.User{}, NewNotFoundError("not found")).Times(1)
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().FindUserMentions(gomock.Any(), User.ID, Before, Query.Limit).Return(&res, nil).Times(1)
+
+	qservice := NewQueryServiceImpl(&Queryers{UserQueryer: userQr, MessageQueryer: msgQr})
+
+	// case1: success
+	got, err := qservice.FindUserMentions(context.Background(), User.ID, Query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != User.ID || got.UnreadCount != 1 || len(got.Mentions) != 2 {
+		t.Fatalf("different user mentions: %#v", got)
+	}
+	if !got.Cursor.Current.Equal(Before) || !got.Cursor.Next.Equal(res.Mentions[1].CreatedAt) {
+		t.Errorf("different cursor: %#v", got.Cursor)
+	}
+
+	// case2: user not found
+	if _, err := qservice.FindUserMentions(context.Background(), User.ID+1, Query); !IsNotFoundError(err) {
+		t.Errorf("query by not found user, expect NotFoundError, got: %v", err)
+	}
+}
+
+func TestQueryServiceFindUserEvents(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID   = uint64(1)
+		FriendID = uint64(2)
+		OtherID  = uint64(3)
+		Limit    = 2
+	)
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID, FriendIDs: domain.NewUserIDSet(FriendID)}, nil).AnyTimes()
+	userQr.EXPECT().Find(gomock.Any(), FriendID).
+		Return(domain.User{ID: FriendID, FriendIDs: domain.NewUserIDSet(UserID)}, nil).AnyTimes()
+	userQr.EXPECT().Find(gomock.Any(), OtherID).
+		Return(domain.User{}, NewNotFoundError("not found")).AnyTimes()
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), uint64(1)).
+		Return(domain.Room{ID: 1, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).AnyTimes()
+	roomQr.EXPECT().Find(gomock.Any(), uint64(2)).
+		Return(domain.Room{ID: 2, MemberIDSet: domain.NewUserIDSet(OtherID)}, nil).AnyTimes()
+
+	eventQr := mocks.NewMockEventQueryer(mockCtrl)
+	// the events are queried until the limit is filled.
+	eventQr.EXPECT().FindAllBySequence(gomock.Any(), uint64(10), Limit).
+		Return([]event.Record{
+			{Seq: 11, Event: event.MessageCreated{RoomID: 2}},
+			{Seq: 12, Event: event.MessageCreated{MessageID: 1, RoomID: 1}},
+		}, nil).Times(1)
+	eventQr.EXPECT().FindAllBySequence(gomock.Any(), uint64(12), Limit).
+		Return([]event.Record{
+			{Seq: 13, Event: event.UserProfileUpdated{UserID: FriendID}},
+			{Seq: 14, Event: event.FriendRequestSent{SenderID: OtherID, ReceiverID: OtherID + 1}},
+		}, nil).Times(1)
+	eventQr.EXPECT().FindAllByStreamIDSequence(gomock.Any(), event.RoomStream, uint64(10), Limit).
+		Return(nil, NewNotFoundError("not found")).Times(1)
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), uint64(1)).
+		Return(domain.Message{ID: 1, RoomID: 1}, nil).AnyTimes()
+
+	qservice := NewQueryServiceImpl(&Queryers{
+		UserQueryer:    userQr,
+		RoomQueryer:    roomQr,
+		MessageQueryer: msgQr,
+		EventQueryer:   eventQr,
+	})
+
+	q := action.QueryUserEvents{After: 10, Limit: Limit}
+	got, err := qservice.FindUserEvents(context.Background(), UserID, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 2 {
+		t.Fatalf("different number of events, expect: %v, got: %v", 2, len(got.Events))
+	}
+	for i, expect := range []struct {
+		Name string
+		Seq  uint64
+	}{
+		{EventNameMessageCreated, 12},
+		{EventNameUserProfileUpdated, 13},
+	} {
+		ev, ok := got.Events[i].(EventJSON)
+		if !ok {
+			t.Fatalf("the event is not encoded by EventJSON, got: %#v", got.Events[i])
+		}
+		if ev.EventName != expect.Name || ev.Seq != expect.Seq {
+			t.Errorf("different event, expect: %v(%v), got: %v(%v)", expect.Name, expect.Seq, ev.EventName, ev.Seq)
+		}
+	}
+	if got.Cursor.Current != 10 || got.Cursor.Next != 13 {
+		t.Errorf("different cursor, got: %#v", got.Cursor)
+	}
+
+	// filtered by the stream
+	q.Stream = "room"
+	got, err = qservice.FindUserEvents(context.Background(), UserID, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 0 || got.Cursor.Next != 10 {
+		t.Errorf("expect no events, got: %#v", got)
+	}
+
+	// fail cases
+	q.Stream = "unknown"
+	if _, err := qservice.FindUserEvents(context.Background(), UserID, q); !domain.IsValidationError(err) {
+		t.Errorf("unknown stream should be validation error, got: %v", err)
+	}
+	q.Stream = ""
+	if _, err := qservice.FindUserEvents(context.Background(), OtherID, q); !IsNotFoundError(err) {
+		t.Errorf("not found user should be not found error, got: %v", err)
+	}
+}
+
+// recordsQueryer is a EventQueryer which queries the records
+// by the sequence number.
+type recordsQueryer struct {
+	EventQueryer
+	records []event.Record
+}
+
+func (qr recordsQueryer) FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+	found := make([]event.Record, 0, limit)
+	for _, r := range qr.records {
+		if r.Seq > after && len(found) < limit {
+			found = append(found, r)
+		}
+	}
+	if len(found) == 0 {
+		return nil, NewNotFoundError("not found")
+	}
+	return found, nil
+}
+
+func TestQueryServiceFindUserEventsSameTimestamp(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID = uint64(1)
+		RoomID = uint64(1)
+		Limit  = 2
+	)
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).AnyTimes()
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), RoomID).
+		Return(domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID)}, nil).AnyTimes()
+
+	// the events more than the limit have the same timestamp.
+	now := time.Now()
+	eventQr := recordsQueryer{}
+	for seq := uint64(1); seq <= 2*Limit+1; seq++ {
+		ev := event.MessageCreated{RoomID: RoomID}
+		ev.CreatedAt = now
+		eventQr.records = append(eventQr.records, event.Record{Seq: seq, Event: ev})
+	}
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), gomock.Any()).
+		Return(domain.Message{RoomID: RoomID}, nil).AnyTimes()
+
+	qservice := NewQueryServiceImpl(&Queryers{
+		UserQueryer:    userQr,
+		RoomQueryer:    roomQr,
+		MessageQueryer: msgQr,
+		EventQueryer:   eventQr,
+	})
+
+	// all of the events are found by following the cursor.
+	var (
+		q    = action.QueryUserEvents{Limit: Limit}
+		seqs []uint64
+	)
+	for i := 0; i < len(eventQr.records); i++ {
+		got, err := qservice.FindUserEvents(context.Background(), UserID, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Events) == 0 {
+			break
+		}
+		for _, ev := range got.Events {
+			seqs = append(seqs, ev.(EventJSON).Seq)
+		}
+		q.After = got.Cursor.Next
+	}
+	if expect := []uint64{1, 2, 3, 4, 5}; !reflect.DeepEqual(expect, seqs) {
+		t.Errorf("different events, expect sequences: %v, got: %v", expect, seqs)
+	}
+}
+
+func TestQueryServiceFindUserEventsScanLimit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID      = uint64(1)
+		OtherRoomID = uint64(2)
+		BrokenRoom  = uint64(3)
+	)
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), UserID).
+		Return(domain.User{ID: UserID}, nil).AnyTimes()
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), OtherRoomID).
+		Return(domain.Room{ID: OtherRoomID, MemberIDSet: domain.NewUserIDSet(UserID + 1)}, nil).AnyTimes()
+	roomQr.EXPECT().Find(gomock.Any(), BrokenRoom).
+		Return(domain.Room{}, NewInfraError("temporary failure")).AnyTimes()
+
+	// the events which the user can not receive are more than the scan limit.
+	eventQr := recordsQueryer{}
+	for seq := uint64(1); seq <= MaxScannedUserEvents+10; seq++ {
+		eventQr.records = append(eventQr.records, event.Record{
+			Seq:   seq,
+			Event: event.MessageCreated{RoomID: OtherRoomID},
+		})
+	}
+
+	qservice := NewQueryServiceImpl(&Queryers{
+		UserQueryer:  userQr,
+		RoomQueryer:  roomQr,
+		EventQueryer: eventQr,
+	})
+
+	// case1: the scan stops at the limit and returns the cursor reached.
+	got, err := qservice.FindUserEvents(context.Background(), UserID, action.QueryUserEvents{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 0 || got.Cursor.Next != MaxScannedUserEvents {
+		t.Errorf("different result for scan limit, got %v events with cursor %#v", len(got.Events), got.Cursor)
+	}
+
+	// case2: the error other than not found is returned
+	// rather than skipping the event.
+	eventQr.records = []event.Record{{Seq: 1, Event: event.MessageCreated{RoomID: BrokenRoom}}}
+	qservice.events = eventQr
+	if _, err := qservice.FindUserEvents(context.Background(), UserID, action.QueryUserEvents{}); err == nil {
+		t.Error("the temporary failure is skipped, but it should be returned")
+	} else if _, ok := err.(*InfraError); !ok {
+		t.Errorf("expect InfraError for the temporary failure, got: %v", err)
+	}
+}
+
+func TestQueryServiceFindUserEventsJoinedAndDeleted(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID        = uint64(1)
+		NewMemberID   = uint64(2)
+		RoomID        = uint64(1)
+		MessageID     = uint64(1)
+		DeletedID     = uint64(2)
+		RemovedID     = uint64(3)
+		BrokenMessage = uint64(4)
+	)
+
+	joinedAt := time.Now()
+	room := domain.Room{ID: RoomID, MemberIDSet: domain.NewUserIDSet(UserID, NewMemberID)}
+	room.MemberJoinedAt.Set(NewMemberID, joinedAt)
+
+	userQr := mocks.NewMockUserQueryer(mockCtrl)
+	userQr.EXPECT().Find(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, userID uint64) (domain.User, error) {
+			return domain.User{ID: userID}, nil
+		}).AnyTimes()
+
+	roomQr := mocks.NewMockRoomQueryer(mockCtrl)
+	roomQr.EXPECT().Find(gomock.Any(), RoomID).Return(room, nil).AnyTimes()
+
+	msgQr := mocks.NewMockMessageQueryer(mockCtrl)
+	msgQr.EXPECT().Find(gomock.Any(), MessageID).
+		Return(domain.Message{ID: MessageID, RoomID: RoomID}, nil).AnyTimes()
+	msgQr.EXPECT().Find(gomock.Any(), DeletedID).
+		Return(domain.Message{ID: DeletedID, RoomID: RoomID, Deleted: true}, nil).AnyTimes()
+	msgQr.EXPECT().Find(gomock.Any(), RemovedID).
+		Return(domain.Message{}, NewNotFoundError("not found")).AnyTimes()
+	msgQr.EXPECT().Find(gomock.Any(), BrokenMessage).
+		Return(domain.Message{}, NewInfraError("temporary failure")).AnyTimes()
+
+	newMessage := func(msgID uint64, at time.Time) event.MessageCreated {
+		ev := event.MessageCreated{MessageID: msgID, RoomID: RoomID, Content: "content"}
+		ev.CreatedAt = at
+		return ev
+	}
+	edited := event.MessageEdited{MessageID: DeletedID, RoomID: RoomID, Content: "edited"}
+	edited.CreatedAt = joinedAt.Add(time.Second)
+
+	eventQr := recordsQueryer{records: []event.Record{
+		{Seq: 1, Event: newMessage(MessageID, joinedAt.Add(-time.Second))},
+		{Seq: 2, Event: newMessage(DeletedID, joinedAt)},
+		{Seq: 3, Event: edited},
+		{Seq: 4, Event: newMessage(RemovedID, joinedAt.Add(time.Second))},
+	}}
+
+	qservice := NewQueryServiceImpl(&Queryers{
+		UserQueryer:    userQr,
+		RoomQueryer:    roomQr,
+		MessageQueryer: msgQr,
+		EventQueryer:   eventQr,
+	})
+
+	for _, testcase := range []struct {
+		UserID   uint64
+		Seqs     []uint64
+		Contents []string
+	}{
+		{UserID, []uint64{1, 2, 3, 4}, []string{"content", "", "", ""}},
+		// the events before joining are not exposed to the new member.
+		{NewMemberID, []uint64{2, 3, 4}, []string{"", "", ""}},
+	} {
+		got, err := qservice.FindUserEvents(context.Background(), testcase.UserID, action.QueryUserEvents{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			seqs     []uint64
+			contents []string
+		)
+		for _, ev := range got.Events {
+			evJSON := ev.(EventJSON)
+			seqs = append(seqs, evJSON.Seq)
+			switch data := evJSON.Data.(type) {
+			case event.MessageCreated:
+				contents = append(contents, data.Content)
+			case event.MessageEdited:
+				contents = append(contents, data.Content)
+			}
+		}
+		if !reflect.DeepEqual(testcase.Seqs, seqs) {
+			t.Errorf("user(id=%d): different events, expect sequences: %v, got: %v", testcase.UserID, testcase.Seqs, seqs)
+		}
+		// the content of the deleted message is hidden.
+		if !reflect.DeepEqual(testcase.Contents, contents) {
+			t.Errorf("user(id=%d): different contents, expect: %q, got: %q", testcase.UserID, testcase.Contents, contents)
+		}
+	}
+
+	// the error other than not found is returned
+	// rather than exposing the content.
+	eventQr.records = []event.Record{{Seq: 1, Event: newMessage(BrokenMessage, joinedAt)}}
+	qservice.events = eventQr
+	if _, err := qservice.FindUserEvents(context.Background(), UserID, action.QueryUserEvents{}); err == nil {
+		t.Error("the temporary failure is skipped, but it should be returned")
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"time"
 
+	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/chat/queried"
 	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/domain/event"
 )
 
-//go:generate mockgen -destination=../internal/mocks/mock_queryer.go -package=mocks github.com/shirasudon/go-chat/chat UserQueryer,RoomQueryer,MessageQueryer,EventQueryer
+//go:generate mockgen -destination=../internal/mocks/mock_queryer.go -package=mocks github.com/shirasudon/go-chat/chat UserQueryer,RoomQueryer,MessageQueryer,MessageSearcher,EventQueryer
 
 // Queryers is just data struct which have
 // some XXXQueryers.
@@ -17,6 +18,7 @@ type Queryers struct {
 	UserQueryer
 	RoomQueryer
 	MessageQueryer
+	MessageSearcher
 
 	EventQueryer
 }
@@ -27,10 +29,6 @@ type UserQueryer interface {
 	// It returns NotFoundError if not found.
 	Find(ctx context.Context, userID uint64) (domain.User, error)
 
-	// Find a user profile specified by user name and password.
-	// It returns error if not found.
-	FindByNameAndPassword(ctx context.Context, name, password string) (*queried.AuthUser, error)
-
 	// Find a user related information with userID.
 	// It returns queried result or NotFoundError if the information is not found.
 	FindUserRelation(ctx context.Context, userID uint64) (*queried.UserRelation, error)
@@ -53,6 +51,9 @@ type RoomQueryer interface {
 
 // MessageQueryer queries messages stored in the data-store.
 type MessageQueryer interface {
+	// Find a message specified by msgID and return it.
+	// It returns NotFoundError if not found.
+	Find(ctx context.Context, msgID uint64) (domain.Message, error)
 
 	// Find all messages from the room specified by room_id.
 	// The returned messages are, ordered by latest created at,
@@ -61,12 +62,41 @@ type MessageQueryer interface {
 	// specified limit.
 	// It returns InfraError if infrastructure raise some errors.
 	// It returns NotFoundError if not found.
+	// The replies in the threads are not contained.
 	FindRoomMessagesOrderByLatest(ctx context.Context, roomID uint64, before time.Time, limit int) ([]domain.Message, error)
 
+	// Find all reply messages in the thread specified by parentID.
+	// The returned messages are, ordered by latest created at,
+	// all of before specified before time,
+	// and the number of messages is limted to less than
+	// specified limit.
+	// It returns InfraError if infrastructure raise some errors.
+	FindThreadMessagesOrderByLatest(ctx context.Context, parentID uint64, before time.Time, limit int) ([]domain.Message, error)
+
 	// Find all unread messages from the room specified by room_id.
 	// The returned messages are, ordered by latest created at,
 	// It returns NotFoundError if not found.
 	FindUnreadRoomMessages(ctx context.Context, userID, roomID uint64, limit int) (*queried.UnreadRoomMessages, error)
+
+	// Find all messages mentioning the user specified by userID.
+	// The returned mentions are, ordered by latest created at,
+	// all of before specified before time, all in the rooms which
+	// the user belongs to, and the number of mentions is limited to
+	// less than specified limit. The deleted messages are not contained.
+	// The mention is unread if it is created after the user read the room.
+	// It returns InfraError if infrastructure raise some errors.
+	FindUserMentions(ctx context.Context, userID uint64, before time.Time, limit int) (*queried.UserMentions, error)
+}
+
+// MessageSearcher searches messages by the full-text query.
+type MessageSearcher interface {
+	// Search the messages matched with all of the terms in the query text.
+	// The returned results are, ordered by the relevance,
+	// all in the rooms specified by roomIDs, filtered by the query
+	// except for its RoomID, and the number of results is limited
+	// to less than the limit of the query.
+	// It returns InfraError if infrastructure raise some errors.
+	SearchMessages(ctx context.Context, roomIDs []uint64, q action.QuerySearchMessages) ([]queried.MessageSearchResult, error)
 }
 
 // EventQueryer queries events stored in the data-store.
@@ -82,4 +112,19 @@ type EventQueryer interface {
 	// and all of after specified after time.
 	// It returns NotFoundError if not found.
 	FindAllByStreamID(ctx context.Context, streamID event.StreamID, after time.Time, limit int) ([]event.Event, error)
+
+	// Find events from the data-store with its sequence number.
+	// The returned events are, ordered by ascending sequence number
+	// and all of after specified sequence number.
+	// Unlike time cursor, the sequence number can distinguish the events
+	// having the same timestamp.
+	// It returns NotFoundError if not found.
+	FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error)
+
+	// Find events, associated with specified stream ID, from the data-store
+	// with its sequence number.
+	// The returned events are, ordered by ascending sequence number
+	// and all of after specified sequence number.
+	// It returns NotFoundError if not found.
+	FindAllByStreamIDSequence(ctx context.Context, streamID event.StreamID, after uint64, limit int) ([]event.Record, error)
 }
@@ -10,3 +10,11 @@ type AddRoomMember struct {
 
 // RemoveRoomMember is result for the chat.CommandService.RemoveRoomMember().
 type RemoveRoomMember AddRoomMember
+
+// FindOrCreateTalkRoom is result for the chat.CommandService.FindOrCreateTalkRoom().
+type FindOrCreateTalkRoom struct {
+	RoomID uint64
+
+	// it is true when the talk room is newly created.
+	Created bool
+}
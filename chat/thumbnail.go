@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	// register the image formats to be decoded.
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// ThumbnailContentType is the content type of the thumbnails.
+const ThumbnailContentType = "image/png"
+
+const (
+	// maxThumbnailSourcePixels limits the pixels of the image
+	// to make the thumbnail, so that the huge image does not
+	// consume the memory too much.
+	maxThumbnailSourcePixels = 50 * 1000 * 1000
+
+	// maxThumbnailSamples is the approximate number of the source
+	// pixels averaged for each of the thumbnail pixels per axis.
+	maxThumbnailSamples = 4
+)
+
+// makeThumbnail decodes the image data and returns the PNG encoded
+// thumbnail which fits in size x size pixels with keeping the aspect
+// ratio. The image smaller than the size is not enlarged.
+func makeThumbnail(data []byte, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("thumbnail size must be positive")
+	}
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if conf.Width <= 0 || conf.Height <= 0 {
+		return nil, errors.New("image has no pixels")
+	}
+	if conf.Width*conf.Height > maxThumbnailSourcePixels {
+		return nil, fmt.Errorf("image is too large, %dx%d", conf.Width, conf.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	dst := resizeImage(src, thumbnailBounds(src.Bounds().Dx(), src.Bounds().Dy(), size))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbnailBounds returns the bounds of the thumbnail for the
+// w x h image, which fits in size x size.
+func thumbnailBounds(w, h, size int) image.Rectangle {
+	if w <= size && h <= size {
+		return image.Rect(0, 0, w, h)
+	}
+	if w >= h {
+		h = h * size / w
+		w = size
+	} else {
+		w = w * size / h
+		h = size
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return image.Rect(0, 0, w, h)
+}
+
+// resizeImage resizes the src image into the bounds by averaging
+// the source pixels in the box corresponding to each destination pixel.
+func resizeImage(src image.Image, bounds image.Rectangle) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dw, dh := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(bounds)
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := sb.Min.Y+y*sh/dh, sb.Min.Y+(y+1)*sh/dh
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		ystep := boxStep(sy1 - sy0)
+
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := sb.Min.X+x*sw/dw, sb.Min.X+(x+1)*sw/dw
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+			xstep := boxStep(sx1 - sx0)
+
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy += ystep {
+				for sx := sx0; sx < sx1; sx += xstep {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+cr, g+cg, b+cb, a+ca, n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// boxStep returns the step to sample about maxThumbnailSamples
+// pixels in the box.
+func boxStep(boxSize int) int {
+	step := boxSize / maxThumbnailSamples
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
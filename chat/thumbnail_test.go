@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodeTestImage(t *testing.T, w, h int, jpg bool) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if jpg {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnailBounds(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		W, H, Size int
+		Expect     image.Rectangle
+	}{
+		{100, 50, 200, image.Rect(0, 0, 100, 50)},
+		{400, 200, 100, image.Rect(0, 0, 100, 50)},
+		{200, 400, 100, image.Rect(0, 0, 50, 100)},
+		{300, 300, 100, image.Rect(0, 0, 100, 100)},
+		{1000, 1, 100, image.Rect(0, 0, 100, 1)},
+	} {
+		if got := thumbnailBounds(testcase.W, testcase.H, testcase.Size); got != testcase.Expect {
+			t.Errorf("thumbnailBounds(%d, %d, %d): expect %v, got %v",
+				testcase.W, testcase.H, testcase.Size, testcase.Expect, got)
+		}
+	}
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		Data   []byte
+		Expect image.Point
+	}{
+		{encodeTestImage(t, 200, 100, false), image.Pt(64, 32)},
+		{encodeTestImage(t, 100, 200, true), image.Pt(32, 64)},
+		{encodeTestImage(t, 20, 10, false), image.Pt(20, 10)},
+	} {
+		thumbnail, err := makeThumbnail(testcase.Data, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(thumbnail))
+		if err != nil {
+			t.Fatalf("thumbnail is not a PNG image: %v", err)
+		}
+		if got := img.Bounds().Size(); got != testcase.Expect {
+			t.Errorf("different thumbnail size, expect %v, got %v", testcase.Expect, got)
+		}
+	}
+
+	// the averaged color is kept for the uniform image.
+	src := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = 10, 20, 30, 255
+	}
+	dst := resizeImage(src, image.Rect(0, 0, 7, 7))
+	if got := dst.RGBAAt(3, 3); got != (color.RGBA{10, 20, 30, 255}) {
+		t.Errorf("different color of the resized image, got %v", got)
+	}
+
+	if _, err := makeThumbnail([]byte("not an image"), 64); err == nil {
+		t.Error("makes the thumbnail for the invalid image")
+	}
+}
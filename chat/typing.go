@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTypingTimeout is the default duration for the typing state
+// to be expired when the client does not notify end of typing.
+const DefaultTypingTimeout = 10 * time.Second
+
+type userAndRoomID struct {
+	UserID uint64
+	RoomID uint64
+}
+
+// typingStates holds users typing in the rooms.
+// Each typing state is expired after the timeout, and then
+// onExpire is called with the expired user and room.
+// It is safe for the concurrent use.
+type typingStates struct {
+	timeout  time.Duration
+	onExpire func(userID, roomID uint64)
+
+	mu     sync.Mutex
+	timers map[userAndRoomID]*time.Timer
+}
+
+func newTypingStates(timeout time.Duration, onExpire func(userID, roomID uint64)) *typingStates {
+	return &typingStates{
+		timeout:  timeout,
+		onExpire: onExpire,
+		timers:   make(map[userAndRoomID]*time.Timer),
+	}
+}
+
+// Start sets the user to typing state in the room.
+// If the user is already typing, the expiration is extended.
+func (ts *typingStates) Start(userID, roomID uint64) {
+	key := userAndRoomID{userID, roomID}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if timer, ok := ts.timers[key]; ok {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ts.timeout, func() {
+		ts.mu.Lock()
+		// the timer may be replaced or removed before firing.
+		if current, ok := ts.timers[key]; !ok || current != timer {
+			ts.mu.Unlock()
+			return
+		}
+		delete(ts.timers, key)
+		ts.mu.Unlock()
+
+		ts.onExpire(userID, roomID)
+	})
+	ts.timers[key] = timer
+}
+
+// End removes the typing state of the user in the room.
+// It returns true if the user was typing.
+func (ts *typingStates) End(userID, roomID uint64) bool {
+	key := userAndRoomID{userID, roomID}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	timer, ok := ts.timers[key]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(ts.timers, key)
+	return true
+}
+
+// EndAll removes all of the typing states of the user.
+// It returns the room IDs which the user was typing in.
+func (ts *typingStates) EndAll(userID uint64) []uint64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	roomIDs := make([]uint64, 0, 2)
+	for key, timer := range ts.timers {
+		if key.UserID != userID {
+			continue
+		}
+		timer.Stop()
+		delete(ts.timers, key)
+		roomIDs = append(roomIDs, key.RoomID)
+	}
+	return roomIDs
+}
+
+// Clear removes all of the typing states without expiration.
+func (ts *typingStates) Clear() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for key, timer := range ts.timers {
+		timer.Stop()
+		delete(ts.timers, key)
+	}
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypingStatesStartAndEnd(t *testing.T) {
+	t.Parallel()
+
+	expired := make(chan userAndRoomID, 1)
+	ts := newTypingStates(time.Hour, func(userID, roomID uint64) {
+		expired <- userAndRoomID{userID, roomID}
+	})
+	defer ts.Clear()
+
+	if ts.End(1, 2) {
+		t.Error("End returns true for the user not typing")
+	}
+
+	ts.Start(1, 2)
+	ts.Start(1, 2) // extends expiration.
+	if !ts.End(1, 2) {
+		t.Error("End returns false for the typing user")
+	}
+	if ts.End(1, 2) {
+		t.Error("End returns true for the user already ended typing")
+	}
+
+	select {
+	case <-expired:
+		t.Error("ended typing state should not be expired")
+	default:
+	}
+}
+
+func TestTypingStatesEndAll(t *testing.T) {
+	t.Parallel()
+
+	ts := newTypingStates(time.Hour, func(userID, roomID uint64) {
+		t.Errorf("unexpected expiration for user(%d) in room(%d)", userID, roomID)
+	})
+	defer ts.Clear()
+
+	ts.Start(1, 2)
+	ts.Start(1, 3)
+	ts.Start(4, 2)
+
+	roomIDs := ts.EndAll(1)
+	if len(roomIDs) != 2 {
+		t.Fatalf("different ended rooms size, expect: %v, got: %v", 2, len(roomIDs))
+	}
+	for _, id := range roomIDs {
+		if id != 2 && id != 3 {
+			t.Errorf("unexpected ended room id: %v", id)
+		}
+	}
+
+	// other users are not affected.
+	if !ts.End(4, 2) {
+		t.Error("typing state of other user is also ended")
+	}
+	if roomIDs := ts.EndAll(1); len(roomIDs) != 0 {
+		t.Errorf("typing states still remain after EndAll: %v", roomIDs)
+	}
+}
+
+func TestTypingStatesExpire(t *testing.T) {
+	t.Parallel()
+
+	const (
+		TypingTimeout   = 1 * time.Millisecond
+		TimeoutDuration = 100 * time.Millisecond
+	)
+
+	expired := make(chan userAndRoomID, 1)
+	ts := newTypingStates(TypingTimeout, func(userID, roomID uint64) {
+		expired <- userAndRoomID{userID, roomID}
+	})
+	defer ts.Clear()
+
+	ts.Start(1, 2)
+
+	select {
+	case key := <-expired:
+		if key.UserID != 1 || key.RoomID != 2 {
+			t.Errorf("different expired key, expect: %v, got: %v", userAndRoomID{1, 2}, key)
+		}
+	case <-time.After(TimeoutDuration):
+		t.Fatal("timeout: typing state is not expired")
+	}
+
+	if ts.End(1, 2) {
+		t.Error("expired typing state still remains")
+	}
+}
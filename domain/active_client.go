@@ -114,7 +114,9 @@ func (cm *ActiveClientRepository) Remove(ac *ActiveClient) error {
 
 // connection specific infomation.
 type connectionInfo struct {
-	// TODO
+	// the events until this sequence number are already sent to
+	// the connection, such as by replaying the stored events.
+	sentSeq uint64
 }
 
 // ActiveClient is a one active domain User which has several
@@ -219,14 +221,37 @@ func (ac *ActiveClient) HasConn(c Conn) bool {
 }
 
 // Send domain event to all of the client connections.
+// The stored event is not sent to the connection which
+// already received it, see also SkipSequence.
 func (ac *ActiveClient) Send(ev event.Event) {
+	seq := event.SequenceOf(ev)
+
 	ac.mu.RLock()
-	for c, _ := range ac.conns {
+	for c, info := range ac.conns {
+		if seq > 0 && seq <= info.sentSeq {
+			continue
+		}
 		c.Send(ev)
 	}
 	ac.mu.RUnlock()
 }
 
+// SkipSequence marks the events until the sequence number
+// as already sent to the connection, so that those are not sent twice.
+// It returns error if the connection is not found.
+func (ac *ActiveClient) SkipSequence(c Conn, seq uint64) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	info, ok := ac.conns[c]
+	if !ok {
+		return errors.New("connection is not found")
+	}
+	info.sentSeq = seq
+	ac.conns[c] = info
+	return nil
+}
+
 // MaxConns is the maximum number of the connections
 // per ActiveClient.
 const MaxConns = 16
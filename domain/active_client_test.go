@@ -160,6 +160,40 @@ func TestActiveClientSend(t *testing.T) {
 	}
 }
 
+func TestActiveClientSkipSequence(t *testing.T) {
+	repo := NewActiveClientRepository(10)
+	user := User{ID: 1}
+	conn := &ConnImpl{userID: user.ID}
+
+	ac, _, _ := NewActiveClient(repo, conn, user)
+
+	conn2 := &ConnImpl{userID: user.ID}
+	ac.AddConn(conn2)
+	if err := ac.SkipSequence(conn2, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for seq := uint64(1); seq <= 3; seq++ {
+		ac.Send(event.WithSequence(event.MessageCreated{}, seq))
+	}
+	// the event which is not stored is always sent.
+	ac.Send(event.UserTypingStarted{})
+
+	if got := len(conn.receviedEv); got != 4 {
+		t.Errorf("different number of the received events, expect: %d, got: %d", 4, got)
+	}
+	if got := len(conn2.receviedEv); got != 2 {
+		t.Fatalf("different number of the received events for skipping conn, expect: %d, got: %d", 2, got)
+	}
+	if got := event.SequenceOf(conn2.receviedEv[0]); got != 3 {
+		t.Errorf("the skipped event is sent, got sequence: %d", got)
+	}
+
+	if err := ac.SkipSequence(&ConnImpl{userID: user.ID}, 1); err == nil {
+		t.Error("skipping sequence for the unknown connection should return error")
+	}
+}
+
 func TestActiveClientHasConn(t *testing.T) {
 	repo := NewActiveClientRepository(10)
 	user := User{ID: 1}
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:generate mockgen -destination=../internal/mocks/mock_attachments.go -package=mocks github.com/shirasudon/go-chat/domain AttachmentRepository
+
+type AttachmentRepository interface {
+	TxBeginner
+
+	Find(ctx context.Context, id uint64) (Attachment, error)
+
+	// Store stores given attachment to the repository.
+	// user need not to set ID for attachment since it is auto set
+	// when attachment is newly.
+	// If the attachment already exists in the repository, it is updated.
+	// It returns stored Attachment ID and error.
+	Store(ctx context.Context, a Attachment) (uint64, error)
+}
+
+const (
+	// MaxMessageAttachments is the maximum number of the attachments
+	// on the message.
+	MaxMessageAttachments = 10
+
+	// MaxAttachmentFileNameLength is the maximum length of the file
+	// name of the attachment in bytes.
+	MaxAttachmentFileNameLength = 255
+)
+
+// Attachment is the file uploaded to the room, which is
+// attached to the message. The file content and its thumbnail
+// are stored in the blob store by the keys.
+type Attachment struct {
+	// ID and CreatedAt are auto set.
+	ID        uint64    `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+
+	UserID uint64 `db:"user_id"`
+	RoomID uint64 `db:"room_id"`
+
+	// MessageID is the ID of the message which the attachment
+	// is attached to. Zero value means the attachment is not
+	// attached to any message yet.
+	MessageID uint64 `db:"message_id"`
+
+	FileName    string `db:"file_name"`
+	ContentType string `db:"content_type"`
+	Size        int64  `db:"size"`
+
+	// BlobKey is the key of the file content in the blob store.
+	BlobKey string `db:"blob_key"`
+
+	// ThumbnailKey is the key of the thumbnail in the blob store.
+	// Empty value means the attachment has no thumbnail.
+	ThumbnailKey string `db:"thumbnail_key"`
+}
+
+// validateAttachmentFileName returns error when the file name
+// can not be used for the attachment.
+func validateAttachmentFileName(name string) error {
+	if name == "" {
+		return NewValidationError("the file name of the attachment is empty")
+	}
+	if len(name) > MaxAttachmentFileNameLength {
+		return NewValidationError("the file name of the attachment is too long, must be at most %d bytes", MaxAttachmentFileNameLength)
+	}
+	if !utf8.ValidString(name) {
+		return NewValidationError("the file name of the attachment must be a valid UTF-8 string")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return NewValidationError("the file name of the attachment must not be a path, got %q", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return NewValidationError("the file name of the attachment must not contain control characters")
+		}
+	}
+	return nil
+}
+
+// NewAttachment creates new attachment uploaded by the user u
+// into the room r. The file content, and the thumbnail if any,
+// must be already stored in the blob store by the keys.
+// The created attachment is immediately stored into the repository.
+// It returns new attachment and error if any.
+func NewAttachment(
+	ctx context.Context,
+	attachments AttachmentRepository,
+	u User,
+	r Room,
+	fileName, contentType string,
+	size int64,
+	blobKey, thumbnailKey string,
+) (Attachment, error) {
+	if err := validateRoomPoster(u, r); err != nil {
+		return Attachment{}, err
+	}
+	if err := validateAttachmentFileName(fileName); err != nil {
+		return Attachment{}, err
+	}
+	if contentType == "" {
+		return Attachment{}, NewValidationError("the content type of the attachment is empty")
+	}
+	if size < 0 {
+		return Attachment{}, NewValidationError("the size of the attachment must not be negative")
+	}
+	if blobKey == "" {
+		return Attachment{}, errors.New("the attachment must have the blob key")
+	}
+
+	a := Attachment{
+		ID:           0,
+		CreatedAt:    time.Now(),
+		UserID:       u.ID,
+		RoomID:       r.ID,
+		FileName:     fileName,
+		ContentType:  contentType,
+		Size:         size,
+		BlobKey:      blobKey,
+		ThumbnailKey: thumbnailKey,
+	}
+	id, err := attachments.Store(ctx, a)
+	if err != nil {
+		return Attachment{}, err
+	}
+	a.ID = id
+	return a, nil
+}
+
+func (a *Attachment) NotExist() bool {
+	return a == nil || a.ID == 0
+}
+
+// IsAttached returns whether the attachment is attached to the message.
+func (a *Attachment) IsAttached() bool {
+	return a.MessageID != 0
+}
+
+// HasThumbnail returns whether the attachment has the thumbnail.
+func (a *Attachment) HasThumbnail() bool {
+	return a.ThumbnailKey != ""
+}
+
+// validateAttachments returns error when the attachments can not
+// be attached to the message by the user u in the room r.
+func validateAttachments(u User, r Room, attachments []*Attachment) error {
+	if len(attachments) > MaxMessageAttachments {
+		return NewValidationError("the message can not have more than %d attachments", MaxMessageAttachments)
+	}
+	seen := make(map[uint64]bool, len(attachments))
+	for _, a := range attachments {
+		if a.NotExist() {
+			return errors.New("the attachment not in the datastore, can not be attached")
+		}
+		if a.UserID != u.ID {
+			return NewPermissionError("user(id=%d) is not the uploader of the attachment(id=%d), can not attach it", u.ID, a.ID)
+		}
+		if a.RoomID != r.ID {
+			return NewValidationError("the attachment(id=%d) is not in the room(id=%d)", a.ID, r.ID)
+		}
+		if a.IsAttached() || seen[a.ID] {
+			return NewValidationError("the attachment(id=%d) is already attached", a.ID)
+		}
+		seen[a.ID] = true
+	}
+	return nil
+}
+
+// attachmentIDs returns the IDs of the attachments ordered by the ID.
+func attachmentIDs(attachments []*Attachment) []uint64 {
+	if len(attachments) == 0 {
+		return nil
+	}
+	ids := make([]uint64, 0, len(attachments))
+	for _, a := range attachments {
+		ids = append(ids, a.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// attachTo marks the attachments as attached to the message.
+// The caller must store the attachments into the repository.
+func attachTo(m *Message, attachments []*Attachment) {
+	for _, a := range attachments {
+		a.MessageID = m.ID
+	}
+}
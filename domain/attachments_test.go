@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+type AttachmentRepositoryStub struct{}
+
+func (a *AttachmentRepositoryStub) BeginTx(context.Context, *sql.TxOptions) (Tx, error) {
+	panic("not implemented")
+}
+
+func (a *AttachmentRepositoryStub) Find(ctx context.Context, id uint64) (Attachment, error) {
+	panic("not implemented")
+}
+
+func (a *AttachmentRepositoryStub) Store(ctx context.Context, att Attachment) (uint64, error) {
+	return att.ID + 1, nil
+}
+
+var attachmentRepo AttachmentRepository = &AttachmentRepositoryStub{}
+
+func TestNewAttachment(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx  = context.Background()
+		user = User{ID: 1}
+		room = Room{ID: 1}
+	)
+	room.MemberIDSet.Add(user.ID)
+
+	a, err := NewAttachment(ctx, attachmentRepo, user, room, "photo.png", "image/png", 10, "blob", "blob-thumbnail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NotExist() {
+		t.Fatalf("attachment is created but has invalid ID(%d)", a.ID)
+	}
+	if a.UserID != user.ID || a.RoomID != room.ID || a.IsAttached() || !a.HasThumbnail() {
+		t.Errorf("created attachment has different fields, got %#v", a)
+	}
+
+	// fail cases
+	for _, testcase := range []struct {
+		FileName    string
+		ContentType string
+		Size        int64
+		BlobKey     string
+	}{
+		{"", "image/png", 10, "blob"},
+		{strings.Repeat("a", MaxAttachmentFileNameLength+1), "image/png", 10, "blob"},
+		{"../photo.png", "image/png", 10, "blob"},
+		{`dir\photo.png`, "image/png", 10, "blob"},
+		{"..", "image/png", 10, "blob"},
+		{"photo\n.png", "image/png", 10, "blob"},
+		{"photo.png", "", 10, "blob"},
+		{"photo.png", "image/png", -1, "blob"},
+		{"photo.png", "image/png", 10, ""},
+	} {
+		_, err := NewAttachment(ctx, attachmentRepo, user, room, testcase.FileName, testcase.ContentType, testcase.Size, testcase.BlobKey, "")
+		if err == nil {
+			t.Errorf("invalid attachment is created, %#v", testcase)
+		}
+	}
+
+	// read-only member can not upload.
+	room.MemberRoles.Set(user.ID, RoomRoleReadOnly)
+	_, err = NewAttachment(ctx, attachmentRepo, user, room, "photo.png", "image/png", 10, "blob", "")
+	if !IsPermissionError(err) {
+		t.Errorf("read-only member uploads the attachment, got err: %v", err)
+	}
+}
+
+func TestNewRoomMessageAttachments(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx  = context.Background()
+		user = User{ID: 1}
+		room = Room{ID: 1}
+	)
+	room.MemberIDSet.Add(user.ID)
+
+	newAttachments := func() []*Attachment {
+		return []*Attachment{
+			{ID: 3, UserID: user.ID, RoomID: room.ID},
+			{ID: 2, UserID: user.ID, RoomID: room.ID},
+		}
+	}
+
+	attachments := newAttachments()
+	m, err := NewRoomMessage(ctx, msgRepo, userRepo, user, room, "content", attachments...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []uint64{2, 3}; !reflect.DeepEqual(m.AttachmentIDs, expect) {
+		t.Errorf("different attachment IDs, expect %v, got %v", expect, m.AttachmentIDs)
+	}
+	for _, a := range attachments {
+		if a.MessageID != m.ID {
+			t.Errorf("attachment(id=%d) is not attached to the message", a.ID)
+		}
+	}
+	ev := m.Events()[0].(event.MessageCreated)
+	if !reflect.DeepEqual(ev.AttachmentIDs, m.AttachmentIDs) {
+		t.Errorf("MessageCreated has different attachment IDs, expect %v, got %v", m.AttachmentIDs, ev.AttachmentIDs)
+	}
+
+	// fail cases
+	tooMany := make([]*Attachment, 0, MaxMessageAttachments+1)
+	for i := 1; i <= MaxMessageAttachments+1; i++ {
+		tooMany = append(tooMany, &Attachment{ID: uint64(i), UserID: user.ID, RoomID: room.ID})
+	}
+	for _, testcase := range []struct {
+		Attachments     []*Attachment
+		IsPermissionErr bool
+	}{
+		{[]*Attachment{{ID: 0, UserID: user.ID, RoomID: room.ID}}, false},
+		{[]*Attachment{{ID: 2, UserID: user.ID + 1, RoomID: room.ID}}, true},
+		{[]*Attachment{{ID: 2, UserID: user.ID, RoomID: room.ID + 1}}, false},
+		{[]*Attachment{{ID: 2, UserID: user.ID, RoomID: room.ID, MessageID: 1}}, false},
+		{[]*Attachment{{ID: 2, UserID: user.ID, RoomID: room.ID}, {ID: 2, UserID: user.ID, RoomID: room.ID}}, false},
+		{tooMany, false},
+	} {
+		_, err := NewRoomMessage(ctx, msgRepo, userRepo, user, room, "content", testcase.Attachments...)
+		if err == nil {
+			t.Errorf("invalid attachments are attached, %v", testcase.Attachments)
+			continue
+		}
+		if got := IsPermissionError(err); got != testcase.IsPermissionErr {
+			t.Errorf("different error type, expect permission error %v, got %v", testcase.IsPermissionErr, err)
+		}
+	}
+
+	// reply can also have the attachments.
+	parent := Message{ID: 10, RoomID: room.ID}
+	attachments = newAttachments()
+	reply, err := NewThreadMessage(ctx, msgRepo, userRepo, user, room, &parent, "reply", attachments...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []uint64{2, 3}; !reflect.DeepEqual(reply.AttachmentIDs, expect) {
+		t.Errorf("different attachment IDs of the reply, expect %v, got %v", expect, reply.AttachmentIDs)
+	}
+	if attachments[0].MessageID != reply.ID {
+		t.Errorf("attachment is not attached to the reply")
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "fmt"
+
+// PermissionError represents that the user is not permitted
+// to perform the operation, such as the room member without
+// the admin role tries to remove other member.
+// It can be shown directly for the client side.
+//
+// It implements error interface.
+type PermissionError struct {
+	Cause error
+}
+
+// NewPermissionError create new PermissionError with same syntax as fmt.Errorf().
+func NewPermissionError(msgFormat string, args ...interface{}) *PermissionError {
+	return &PermissionError{Cause: fmt.Errorf(msgFormat, args...)}
+}
+
+func (err PermissionError) Error() string {
+	return fmt.Sprintf("permission error: %v", err.Cause.Error())
+}
+
+// It returns true when the type of given err is *PermissionError or PermissionError,
+// otherwise false.
+func IsPermissionError(err error) bool {
+	switch err.(type) {
+	case PermissionError, *PermissionError:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidationError represents that the given value is invalid
+// for the domain rules, such as the empty user name.
+// It can be shown directly for the client side.
+//
+// It implements error interface.
+type ValidationError struct {
+	Cause error
+}
+
+// NewValidationError create new ValidationError with same syntax as fmt.Errorf().
+func NewValidationError(msgFormat string, args ...interface{}) *ValidationError {
+	return &ValidationError{Cause: fmt.Errorf(msgFormat, args...)}
+}
+
+func (err ValidationError) Error() string {
+	return fmt.Sprintf("validation error: %v", err.Cause.Error())
+}
+
+// It returns true when the type of given err is *ValidationError or ValidationError,
+// otherwise false.
+func IsValidationError(err error) bool {
+	switch err.(type) {
+	case ValidationError, *ValidationError:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// prototypes holds zero values for each event type
+// which can be restored from the serialized form.
+var prototypes = map[Type]Event{
+	TypeErrorRaised:              ErrorRaised{},
+	TypeUserCreated:              UserCreated{},
+	TypeUserAddedFriend:          UserAddedFriend{},
+	TypeUserProfileUpdated:       UserProfileUpdated{},
+	TypeUserRemovedFriend:        UserRemovedFriend{},
+	TypeFriendRequestSent:        FriendRequestSent{},
+	TypeFriendRequestAccepted:    FriendRequestAccepted{},
+	TypeFriendRequestDeclined:    FriendRequestDeclined{},
+	TypeFriendRequestCanceled:    FriendRequestCanceled{},
+	TypeUserBlocked:              UserBlocked{},
+	TypeUserUnblocked:            UserUnblocked{},
+	TypeReactionAdded:            ReactionAdded{},
+	TypeReactionRemoved:          ReactionRemoved{},
+	TypeMessagePinned:            MessagePinned{},
+	TypeMessageUnpinned:          MessageUnpinned{},
+	TypeUserMentioned:            UserMentioned{},
+	TypeUserDeleted:              UserDeleted{},
+	TypeRoomCreated:              RoomCreated{},
+	TypeRoomDeleted:              RoomDeleted{},
+	TypeRoomAddedMember:          RoomAddedMember{},
+	TypeRoomRemovedMember:        RoomRemovedMember{},
+	TypeRoomMessagesReadByUser:   RoomMessagesReadByUser{},
+	TypeRoomRenamed:              RoomRenamed{},
+	TypeRoomMemberRoleChanged:    RoomMemberRoleChanged{},
+	TypeRoomMemberLeft:           RoomMemberLeft{},
+	TypeRoomOwnershipTransferred: RoomOwnershipTransferred{},
+	TypeRoomArchived:             RoomArchived{},
+	TypeMessageCreated:           MessageCreated{},
+	TypeMessageEdited:            MessageEdited{},
+	TypeMessageDeleted:           MessageDeleted{},
+	TypeActiveClientActivated:    ActiveClientActivated{},
+	TypeActiveClientInactivated:  ActiveClientInactivated{},
+}
+
+// Decode restores the Event with Type t from JSON data,
+// which is typically encoded by json.Marshal(Event).
+// It returns error if the type t can not be restored,
+// such as TypeNone and TypeExternal.
+func Decode(t Type, data []byte) (Event, error) {
+	proto, ok := prototypes[t]
+	if !ok {
+		return nil, fmt.Errorf("event type(%v) can not be decoded", t)
+	}
+
+	ptr := reflect.New(reflect.TypeOf(proto))
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface().(Event), nil
+}
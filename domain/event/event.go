@@ -2,6 +2,7 @@ package event
 
 import (
 	"context"
+	"reflect"
 	"time"
 )
 
@@ -30,6 +31,15 @@ type Event interface {
 	Timestamp() time.Time
 }
 
+// Record is the Event stored in the data-store with its sequence number.
+// The sequence number is assigned by the data-store in stored order,
+// and is unique and monotonically increasing over all of the events.
+// It can be used as the cursor for the paging the events.
+type Record struct {
+	Seq   uint64
+	Event Event
+}
+
 // Type represents event type.
 type Type uint
 
@@ -45,8 +55,30 @@ const (
 	TypeRoomRemovedMember
 	TypeRoomMessagesReadByUser
 	TypeMessageCreated
+	TypeMessageEdited
+	TypeMessageDeleted
 	TypeActiveClientActivated
 	TypeActiveClientInactivated
+	TypeUserTypingStarted
+	TypeUserTypingEnded
+	TypeRoomRenamed
+	TypeRoomMemberRoleChanged
+	TypeRoomMemberLeft
+	TypeRoomOwnershipTransferred
+	TypeRoomArchived
+	TypeUserProfileUpdated
+	TypeUserRemovedFriend
+	TypeFriendRequestSent
+	TypeFriendRequestAccepted
+	TypeFriendRequestDeclined
+	TypeFriendRequestCanceled
+	TypeUserBlocked
+	TypeUserUnblocked
+	TypeReactionAdded
+	TypeReactionRemoved
+	TypeMessagePinned
+	TypeMessageUnpinned
+	TypeUserMentioned
 	TypeExternal
 )
 
@@ -83,6 +115,10 @@ const (
 // It implements Event interface.
 type EventEmbd struct {
 	CreatedAt time.Time `json:"created_at"`
+
+	// Seq is the sequence number assigned by the data-store.
+	// It is zero for the event which is not stored yet, or never stored.
+	Seq uint64 `json:"-"`
 }
 
 // Occurs confirms the event has occured at a point.
@@ -91,11 +127,49 @@ func (e *EventEmbd) Occurs() { e.CreatedAt = time.Now() }
 func (EventEmbd) Type() Type             { return TypeNone }
 func (EventEmbd) StreamID() StreamID     { return NoneStream }
 func (e EventEmbd) Timestamp() time.Time { return e.CreatedAt }
+func (e EventEmbd) Sequence() uint64     { return e.Seq }
+
+func (e *EventEmbd) setSequence(seq uint64) { e.Seq = seq }
+
+// Sequencer can return the sequence number assigned by the data-store.
+type Sequencer interface {
+	Sequence() uint64
+}
+
+// SequenceOf returns the sequence number of given Event.
+// It returns zero if the Event does not implement Sequencer.
+func SequenceOf(ev Event) uint64 {
+	if ev, ok := ev.(Sequencer); ok {
+		return ev.Sequence()
+	}
+	return 0
+}
+
+// WithSequence returns the copy of given Event which has the sequence number.
+// The Event which does not embed EventEmbd is returned as is.
+func WithSequence(ev Event, seq uint64) Event {
+	ptr := reflect.New(reflect.TypeOf(ev))
+	ptr.Elem().Set(reflect.ValueOf(ev))
+
+	setter, ok := ptr.Interface().(interface {
+		setSequence(uint64)
+	})
+	if !ok {
+		return ev
+	}
+	setter.setSequence(seq)
+	return ptr.Elem().Interface().(Event)
+}
 
 // domain event for the error is raised.
+// Code, Action and CorrelationID are set when the error is
+// caused by the action requested from the client.
 type ErrorRaised struct {
 	EventEmbd
-	Message string `json:"message"`
+	Message       string `json:"message"`
+	Code          string `json:"code,omitempty"`
+	Action        string `json:"action,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
 func (ErrorRaised) Type() Type { return TypeErrorRaised }
@@ -1,6 +1,9 @@
 package event
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestEventEmbd(t *testing.T) {
 	for _, ev := range []struct {
@@ -13,13 +16,36 @@ func TestEventEmbd(t *testing.T) {
 		{"UserEventEmbd", UserEventEmbd{}, TypeNone, UserStream},
 		{"UserCreated", UserCreated{}, TypeUserCreated, UserStream},
 		{"UserAddedFriend", UserAddedFriend{}, TypeUserAddedFriend, UserStream},
+		{"UserProfileUpdated", UserProfileUpdated{}, TypeUserProfileUpdated, UserStream},
+		{"UserRemovedFriend", UserRemovedFriend{}, TypeUserRemovedFriend, UserStream},
+		{"FriendRequestSent", FriendRequestSent{}, TypeFriendRequestSent, UserStream},
+		{"FriendRequestAccepted", FriendRequestAccepted{}, TypeFriendRequestAccepted, UserStream},
+		{"FriendRequestDeclined", FriendRequestDeclined{}, TypeFriendRequestDeclined, UserStream},
+		{"FriendRequestCanceled", FriendRequestCanceled{}, TypeFriendRequestCanceled, UserStream},
+		{"UserBlocked", UserBlocked{}, TypeUserBlocked, UserStream},
+		{"UserUnblocked", UserUnblocked{}, TypeUserUnblocked, UserStream},
+		{"UserDeleted", UserDeleted{}, TypeUserDeleted, UserStream},
 		{"RoomEventEmbd", RoomEventEmbd{}, TypeNone, RoomStream},
 		{"RoomCreated", RoomCreated{}, TypeRoomCreated, RoomStream},
 		{"RoomDeleted", RoomDeleted{}, TypeRoomDeleted, RoomStream},
 		{"RoomAddedMember", RoomAddedMember{}, TypeRoomAddedMember, RoomStream},
 		{"RoomMessagesReadByUser", RoomMessagesReadByUser{}, TypeRoomMessagesReadByUser, RoomStream},
+		{"RoomRenamed", RoomRenamed{}, TypeRoomRenamed, RoomStream},
+		{"RoomMemberRoleChanged", RoomMemberRoleChanged{}, TypeRoomMemberRoleChanged, RoomStream},
+		{"RoomMemberLeft", RoomMemberLeft{}, TypeRoomMemberLeft, RoomStream},
+		{"RoomOwnershipTransferred", RoomOwnershipTransferred{}, TypeRoomOwnershipTransferred, RoomStream},
+		{"RoomArchived", RoomArchived{}, TypeRoomArchived, RoomStream},
 		{"MessageEventEmbd", MessageEventEmbd{}, TypeNone, MessageStream},
 		{"MessageCreated", MessageCreated{}, TypeMessageCreated, MessageStream},
+		{"MessageEdited", MessageEdited{}, TypeMessageEdited, MessageStream},
+		{"MessageDeleted", MessageDeleted{}, TypeMessageDeleted, MessageStream},
+		{"ReactionAdded", ReactionAdded{}, TypeReactionAdded, MessageStream},
+		{"ReactionRemoved", ReactionRemoved{}, TypeReactionRemoved, MessageStream},
+		{"MessagePinned", MessagePinned{}, TypeMessagePinned, RoomStream},
+		{"MessageUnpinned", MessageUnpinned{}, TypeMessageUnpinned, RoomStream},
+		{"UserMentioned", UserMentioned{}, TypeUserMentioned, MessageStream},
+		{"UserTypingStarted", UserTypingStarted{}, TypeUserTypingStarted, RoomStream},
+		{"UserTypingEnded", UserTypingEnded{}, TypeUserTypingEnded, RoomStream},
 		{"ActiveClientActivated", ActiveClientActivated{}, TypeActiveClientActivated, NoneStream},
 		{"ActiveClientInactivated", ActiveClientInactivated{}, TypeActiveClientInactivated, NoneStream},
 		{"ExternalEventEmbd", ExternalEventEmbd{}, TypeExternal, NoneStream},
@@ -51,3 +77,72 @@ func TestTypeString(t *testing.T) {
 		t.Errorf("different type string, expect: %v, got: %v", expect, got)
 	}
 }
+
+func TestDecode(t *testing.T) {
+	// case1: all of decodable types
+	for typ, proto := range prototypes {
+		if typ != proto.Type() {
+			t.Errorf("prototype has different type, expect: %v, got: %v", typ, proto.Type())
+		}
+	}
+
+	ev := RoomCreated{RoomID: 1, Name: "room", MemberIDs: []uint64{1, 2}}
+	ev.Occurs()
+	data, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(ev.Type(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := got.(RoomCreated)
+	if !ok {
+		t.Fatalf("different decoded event type, expect: %T, got: %T", ev, got)
+	}
+	if decoded.RoomID != ev.RoomID || decoded.Name != ev.Name || len(decoded.MemberIDs) != len(ev.MemberIDs) {
+		t.Errorf("different decoded event, expect: %#v, got: %#v", ev, decoded)
+	}
+	if !decoded.Timestamp().Equal(ev.Timestamp()) {
+		t.Errorf("different decoded timestamp, expect: %v, got: %v", ev.Timestamp(), decoded.Timestamp())
+	}
+
+	// case2: not decodable types
+	for _, typ := range []Type{TypeNone, TypeExternal} {
+		if _, err := Decode(typ, []byte("{}")); err == nil {
+			t.Errorf("decoding type(%v) should return error", typ)
+		}
+	}
+
+	// case3: invalid data
+	if _, err := Decode(TypeRoomCreated, []byte("{")); err == nil {
+		t.Error("decoding invalid data should return error")
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	ev := MessageCreated{MessageID: 1, Content: "hello"}
+	got := WithSequence(ev, 10)
+	if SequenceOf(got) != 10 {
+		t.Errorf("different sequence number, expect: 10, got: %v", SequenceOf(got))
+	}
+	if SequenceOf(ev) != 0 {
+		t.Errorf("the original event is modified, got sequence: %v", SequenceOf(ev))
+	}
+	if created, ok := got.(MessageCreated); !ok || created.Content != ev.Content {
+		t.Errorf("different event, expect: %#v, got: %#v", ev, got)
+	}
+
+	// the sequence number is not serialized with the event.
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(got.Type(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SequenceOf(decoded) != 0 {
+		t.Errorf("the sequence number is serialized, got: %v", SequenceOf(decoded))
+	}
+}
@@ -1,5 +1,7 @@
 package event
 
+import "time"
+
 // -----------------------
 // Message events
 // -----------------------
@@ -18,6 +20,81 @@ type MessageCreated struct {
 	RoomID    uint64 `json:"room_id"`
 	CreatedBy uint64 `json:"created_by"`
 	Content   string `json:"content"`
+
+	// AttachmentIDs are the IDs of the attachments on the message.
+	AttachmentIDs []uint64 `json:"attachment_ids,omitempty"`
+
+	// Thread is set only when the message is a reply in the thread,
+	// so that the clients can update the thread summary.
+	Thread *ThreadSummary `json:"thread,omitempty"`
+}
+
+// ThreadSummary is the summary of the thread just after
+// the reply is created or deleted.
+type ThreadSummary struct {
+	ParentID    uint64    `json:"parent_id"`
+	ReplyCount  int       `json:"reply_count"`
+	LastReplyAt time.Time `json:"last_reply_at"`
 }
 
 func (MessageCreated) Type() Type { return TypeMessageCreated }
+
+// Event for the message is edited.
+type MessageEdited struct {
+	MessageEventEmbd
+	MessageID uint64 `json:"message_id"`
+	RoomID    uint64 `json:"room_id"`
+	EditedBy  uint64 `json:"edited_by"`
+	Content   string `json:"content"`
+}
+
+func (MessageEdited) Type() Type { return TypeMessageEdited }
+
+// Event for the message is deleted.
+// It does not contain the message content since
+// the content should be hidden after deletion.
+type MessageDeleted struct {
+	MessageEventEmbd
+	MessageID uint64 `json:"message_id"`
+	RoomID    uint64 `json:"room_id"`
+	DeletedBy uint64 `json:"deleted_by"`
+
+	// Thread is set only when the message is a reply in the thread,
+	// so that the clients can update the thread summary.
+	Thread *ThreadSummary `json:"thread,omitempty"`
+}
+
+func (MessageDeleted) Type() Type { return TypeMessageDeleted }
+
+// Event for the reaction is added to the message.
+type ReactionAdded struct {
+	MessageEventEmbd
+	MessageID uint64 `json:"message_id"`
+	RoomID    uint64 `json:"room_id"`
+	UserID    uint64 `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
+
+func (ReactionAdded) Type() Type { return TypeReactionAdded }
+
+// Event for the reaction is removed from the message.
+type ReactionRemoved struct {
+	MessageEventEmbd
+	MessageID uint64 `json:"message_id"`
+	RoomID    uint64 `json:"room_id"`
+	UserID    uint64 `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
+
+func (ReactionRemoved) Type() Type { return TypeReactionRemoved }
+
+// Event for the user is mentioned in the message.
+type UserMentioned struct {
+	MessageEventEmbd
+	MessageID   uint64 `json:"message_id"`
+	RoomID      uint64 `json:"room_id"`
+	MentionedBy uint64 `json:"mentioned_by"`
+	UserID      uint64 `json:"user_id"`
+}
+
+func (UserMentioned) Type() Type { return TypeUserMentioned }
@@ -55,6 +55,57 @@ type RoomRemovedMember struct {
 
 func (RoomRemovedMember) Type() Type { return TypeRoomRemovedMember }
 
+// Event for Room is renamed.
+type RoomRenamed struct {
+	RoomEventEmbd
+	RoomID    uint64 `json:"room_id"`
+	RenamedBy uint64 `json:"renamed_by"`
+	OldName   string `json:"old_name"`
+	Name      string `json:"name"`
+}
+
+func (RoomRenamed) Type() Type { return TypeRoomRenamed }
+
+// Event for the role of the room member is changed.
+type RoomMemberRoleChanged struct {
+	RoomEventEmbd
+	RoomID    uint64 `json:"room_id"`
+	UserID    uint64 `json:"user_id"`
+	ChangedBy uint64 `json:"changed_by"`
+	OldRole   string `json:"old_role"`
+	Role      string `json:"role"`
+}
+
+func (RoomMemberRoleChanged) Type() Type { return TypeRoomMemberRoleChanged }
+
+// Event for the member left the Room by itself.
+type RoomMemberLeft struct {
+	RoomEventEmbd
+	RoomID uint64 `json:"room_id"`
+	UserID uint64 `json:"user_id"`
+}
+
+func (RoomMemberLeft) Type() Type { return TypeRoomMemberLeft }
+
+// Event for the ownership of the Room is transferred to other member.
+type RoomOwnershipTransferred struct {
+	RoomEventEmbd
+	RoomID     uint64 `json:"room_id"`
+	OldOwnerID uint64 `json:"old_owner_id"`
+	NewOwnerID uint64 `json:"new_owner_id"`
+}
+
+func (RoomOwnershipTransferred) Type() Type { return TypeRoomOwnershipTransferred }
+
+// Event for the Room is archived since no member remains.
+type RoomArchived struct {
+	RoomEventEmbd
+	RoomID     uint64 `json:"room_id"`
+	ArchivedBy uint64 `json:"archived_by"`
+}
+
+func (RoomArchived) Type() Type { return TypeRoomArchived }
+
 // Event for the room messages are read by the user.
 type RoomMessagesReadByUser struct {
 	RoomEventEmbd
@@ -64,3 +115,47 @@ type RoomMessagesReadByUser struct {
 }
 
 func (RoomMessagesReadByUser) Type() Type { return TypeRoomMessagesReadByUser }
+
+// Event for the message is pinned to the room.
+type MessagePinned struct {
+	RoomEventEmbd
+	RoomID    uint64 `json:"room_id"`
+	MessageID uint64 `json:"message_id"`
+	PinnedBy  uint64 `json:"pinned_by"`
+}
+
+func (MessagePinned) Type() Type { return TypeMessagePinned }
+
+// Event for the message is unpinned from the room.
+type MessageUnpinned struct {
+	RoomEventEmbd
+	RoomID     uint64 `json:"room_id"`
+	MessageID  uint64 `json:"message_id"`
+	UnpinnedBy uint64 `json:"unpinned_by"`
+}
+
+func (MessageUnpinned) Type() Type { return TypeMessageUnpinned }
+
+// Event for the user starts typing in the room.
+// It is ephemeral event which is not stored in the EventRepository.
+type UserTypingStarted struct {
+	RoomEventEmbd
+	RoomID     uint64    `json:"room_id"`
+	SenderID   uint64    `json:"sender_id"`
+	SenderName string    `json:"sender_name"`
+	StartAt    time.Time `json:"start_at"`
+}
+
+func (UserTypingStarted) Type() Type { return TypeUserTypingStarted }
+
+// Event for the user ends typing in the room.
+// It is ephemeral event which is not stored in the EventRepository.
+type UserTypingEnded struct {
+	RoomEventEmbd
+	RoomID     uint64    `json:"room_id"`
+	SenderID   uint64    `json:"sender_id"`
+	SenderName string    `json:"sender_name"`
+	EndAt      time.Time `json:"end_at"`
+}
+
+func (UserTypingEnded) Type() Type { return TypeUserTypingEnded }
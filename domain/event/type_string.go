@@ -4,9 +4,9 @@ package event
 
 import "strconv"
 
-const _Type_name = "TypeNoneTypeErrorRaisedTypeUserCreatedTypeUserDeletedTypeUserAddedFriendTypeRoomCreatedTypeRoomDeletedTypeRoomAddedMemberTypeRoomRemovedMemberTypeRoomMessagesReadByUserTypeMessageCreatedTypeActiveClientActivatedTypeActiveClientInactivatedTypeExternal"
+const _Type_name = "TypeNoneTypeErrorRaisedTypeUserCreatedTypeUserDeletedTypeUserAddedFriendTypeRoomCreatedTypeRoomDeletedTypeRoomAddedMemberTypeRoomRemovedMemberTypeRoomMessagesReadByUserTypeMessageCreatedTypeMessageEditedTypeMessageDeletedTypeActiveClientActivatedTypeActiveClientInactivatedTypeUserTypingStartedTypeUserTypingEndedTypeRoomRenamedTypeRoomMemberRoleChangedTypeRoomMemberLeftTypeRoomOwnershipTransferredTypeRoomArchivedTypeUserProfileUpdatedTypeUserRemovedFriendTypeFriendRequestSentTypeFriendRequestAcceptedTypeFriendRequestDeclinedTypeFriendRequestCanceledTypeUserBlockedTypeUserUnblockedTypeReactionAddedTypeReactionRemovedTypeMessagePinnedTypeMessageUnpinnedTypeUserMentionedTypeExternal"
 
-var _Type_index = [...]uint8{0, 8, 23, 38, 53, 72, 87, 102, 121, 142, 168, 186, 211, 238, 250}
+var _Type_index = [...]uint16{0, 8, 23, 38, 53, 72, 87, 102, 121, 142, 168, 186, 203, 221, 246, 273, 294, 313, 328, 353, 371, 399, 415, 437, 458, 479, 504, 529, 554, 569, 586, 603, 622, 639, 658, 675, 687}
 
 func (i Type) String() string {
 	if i >= Type(len(_Type_index)-1) {
@@ -14,6 +14,7 @@ func (UserEventEmbd) StreamID() StreamID { return UserStream }
 // Event for User is created.
 type UserCreated struct {
 	UserEventEmbd
+	UserID    uint64   `json:"user_id"`
 	Name      string   `json:"user_name"`
 	FirstName string   `json:"first_name"`
 	LastName  string   `json:"last_name"`
@@ -30,3 +31,92 @@ type UserAddedFriend struct {
 }
 
 func (UserAddedFriend) Type() Type { return TypeUserAddedFriend }
+
+// Event for User's profile is updated.
+type UserProfileUpdated struct {
+	UserEventEmbd
+	UserID    uint64 `json:"user_id"`
+	Name      string `json:"user_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func (UserProfileUpdated) Type() Type { return TypeUserProfileUpdated }
+
+// Event for User is removed from the friends.
+type UserRemovedFriend struct {
+	UserEventEmbd
+	UserID          uint64 `json:"user_id"`
+	RemovedFriendID uint64 `json:"removed_friend_id"`
+}
+
+func (UserRemovedFriend) Type() Type { return TypeUserRemovedFriend }
+
+// Event for User sent the friend request to other user.
+// The SenderID and ReceiverID of the following friend request
+// events are same as the original request.
+type FriendRequestSent struct {
+	UserEventEmbd
+	SenderID   uint64 `json:"sender_id"`
+	ReceiverID uint64 `json:"receiver_id"`
+}
+
+func (FriendRequestSent) Type() Type { return TypeFriendRequestSent }
+
+// Event for the receiver accepted the friend request.
+// Both of the users become the friends each other.
+type FriendRequestAccepted struct {
+	UserEventEmbd
+	SenderID   uint64 `json:"sender_id"`
+	ReceiverID uint64 `json:"receiver_id"`
+}
+
+func (FriendRequestAccepted) Type() Type { return TypeFriendRequestAccepted }
+
+// Event for the receiver declined the friend request.
+type FriendRequestDeclined struct {
+	UserEventEmbd
+	SenderID   uint64 `json:"sender_id"`
+	ReceiverID uint64 `json:"receiver_id"`
+}
+
+func (FriendRequestDeclined) Type() Type { return TypeFriendRequestDeclined }
+
+// Event for the sender canceled the friend request.
+type FriendRequestCanceled struct {
+	UserEventEmbd
+	SenderID   uint64 `json:"sender_id"`
+	ReceiverID uint64 `json:"receiver_id"`
+}
+
+func (FriendRequestCanceled) Type() Type { return TypeFriendRequestCanceled }
+
+// Event for User blocked other user.
+type UserBlocked struct {
+	UserEventEmbd
+	UserID        uint64 `json:"user_id"`
+	BlockedUserID uint64 `json:"blocked_user_id"`
+}
+
+func (UserBlocked) Type() Type { return TypeUserBlocked }
+
+// Event for User unblocked other user.
+type UserUnblocked struct {
+	UserEventEmbd
+	UserID          uint64 `json:"user_id"`
+	UnblockedUserID uint64 `json:"unblocked_user_id"`
+}
+
+func (UserUnblocked) Type() Type { return TypeUserUnblocked }
+
+// Event for User is deleted.
+// It contains the friends at the deletion, since the
+// deleted user can not be found after that.
+type UserDeleted struct {
+	UserEventEmbd
+	UserID    uint64   `json:"user_id"`
+	Name      string   `json:"user_name"`
+	FriendIDs []uint64 `json:"friend_ids"`
+}
+
+func (UserDeleted) Type() Type { return TypeUserDeleted }
@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// friends.go defines the friend request workflow for the User.
+// Since the friendship is mutual, the methods change both of
+// the users, and the caller should store both of them.
+// The event is added into the user who calls the method.
+
+// validateOtherUser returns error when the users can not
+// be related with each other.
+func validateOtherUser(u, other *User) error {
+	if u.NotExist() || other.NotExist() {
+		return fmt.Errorf("newly user can not be related with other user")
+	}
+	if u.ID == other.ID {
+		return NewValidationError("can not relate the user(id=%d) with itself", u.ID)
+	}
+	return nil
+}
+
+// HasRequestedFriend returns whether the user sent
+// the friend request to the receiver, which is not answered yet.
+func (u *User) HasRequestedFriend(receiver User) bool {
+	return u.RequestedFriendIDs.Has(receiver.ID)
+}
+
+// HasBlocked returns whether the user blocks the other user.
+func (u *User) HasBlocked(other User) bool {
+	return u.BlockedUserIDs.Has(other.ID)
+}
+
+// RequestFriend sends the friend request from the user to the receiver.
+// It returns ValidationError when they are already friends or
+// the request is already sent by either of them, and PermissionError
+// when the receiver blocks the user.
+func (u *User) RequestFriend(receiver *User) (event.FriendRequestSent, error) {
+	if err := validateOtherUser(u, receiver); err != nil {
+		return event.FriendRequestSent{}, err
+	}
+	if u.HasFriend(*receiver) {
+		return event.FriendRequestSent{}, NewValidationError("user(id=%d) is already a friend", receiver.ID)
+	}
+	if u.HasRequestedFriend(*receiver) {
+		return event.FriendRequestSent{}, NewValidationError("friend request to user(id=%d) is already sent", receiver.ID)
+	}
+	if receiver.HasRequestedFriend(*u) {
+		return event.FriendRequestSent{}, NewValidationError("friend request from user(id=%d) is already received, accept it instead", receiver.ID)
+	}
+	if u.HasBlocked(*receiver) {
+		return event.FriendRequestSent{}, NewValidationError("can not send friend request to blocked user(id=%d)", receiver.ID)
+	}
+	if receiver.HasBlocked(*u) {
+		return event.FriendRequestSent{}, NewPermissionError("can not send friend request to user(id=%d)", receiver.ID)
+	}
+
+	u.RequestedFriendIDs.Add(receiver.ID)
+
+	ev := event.FriendRequestSent{
+		SenderID:   u.ID,
+		ReceiverID: receiver.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// validateReceivedRequest returns ValidationError when the user
+// does not receive the friend request from the sender.
+func validateReceivedRequest(u, sender *User) error {
+	if err := validateOtherUser(u, sender); err != nil {
+		return err
+	}
+	if !sender.HasRequestedFriend(*u) {
+		return NewValidationError("friend request from user(id=%d) is not found", sender.ID)
+	}
+	return nil
+}
+
+// AcceptFriendRequest accepts the friend request from the sender,
+// then both of the users become the friends each other.
+// It returns ValidationError when the request is not found.
+func (u *User) AcceptFriendRequest(sender *User) (event.FriendRequestAccepted, error) {
+	if err := validateReceivedRequest(u, sender); err != nil {
+		return event.FriendRequestAccepted{}, err
+	}
+
+	sender.RequestedFriendIDs.Remove(u.ID)
+	sender.FriendIDs.Add(u.ID)
+	u.FriendIDs.Add(sender.ID)
+
+	ev := event.FriendRequestAccepted{
+		SenderID:   sender.ID,
+		ReceiverID: u.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// DeclineFriendRequest declines the friend request from the sender.
+// It returns ValidationError when the request is not found.
+func (u *User) DeclineFriendRequest(sender *User) (event.FriendRequestDeclined, error) {
+	if err := validateReceivedRequest(u, sender); err != nil {
+		return event.FriendRequestDeclined{}, err
+	}
+
+	sender.RequestedFriendIDs.Remove(u.ID)
+
+	ev := event.FriendRequestDeclined{
+		SenderID:   sender.ID,
+		ReceiverID: u.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// CancelFriendRequest cancels the friend request sent to the receiver.
+// It returns ValidationError when the request is not found.
+func (u *User) CancelFriendRequest(receiver *User) (event.FriendRequestCanceled, error) {
+	if err := validateOtherUser(u, receiver); err != nil {
+		return event.FriendRequestCanceled{}, err
+	}
+	if !u.HasRequestedFriend(*receiver) {
+		return event.FriendRequestCanceled{}, NewValidationError("friend request to user(id=%d) is not found", receiver.ID)
+	}
+
+	u.RequestedFriendIDs.Remove(receiver.ID)
+
+	ev := event.FriendRequestCanceled{
+		SenderID:   u.ID,
+		ReceiverID: receiver.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// Unfriend removes the friendship between the user and the friend.
+// It returns ValidationError when they are not friends.
+func (u *User) Unfriend(friend *User) (event.UserRemovedFriend, error) {
+	if err := validateOtherUser(u, friend); err != nil {
+		return event.UserRemovedFriend{}, err
+	}
+	if !u.HasFriend(*friend) {
+		return event.UserRemovedFriend{}, NewValidationError("user(id=%d) is not a friend", friend.ID)
+	}
+
+	friend.FriendIDs.Remove(u.ID)
+	return u.RemoveFriend(*friend)
+}
+
+// Block blocks the other user. The friendship and the friend
+// requests between them are removed with their events,
+// then the blocked user can not send the friend request to the user.
+// It returns ValidationError when the other user is already blocked.
+func (u *User) Block(other *User) (event.UserBlocked, error) {
+	if err := validateOtherUser(u, other); err != nil {
+		return event.UserBlocked{}, err
+	}
+	if u.HasBlocked(*other) {
+		return event.UserBlocked{}, NewValidationError("user(id=%d) is already blocked", other.ID)
+	}
+
+	if u.HasFriend(*other) {
+		if _, err := u.Unfriend(other); err != nil {
+			return event.UserBlocked{}, err
+		}
+	}
+	if u.HasRequestedFriend(*other) {
+		if _, err := u.CancelFriendRequest(other); err != nil {
+			return event.UserBlocked{}, err
+		}
+	}
+	if other.HasRequestedFriend(*u) {
+		if _, err := u.DeclineFriendRequest(other); err != nil {
+			return event.UserBlocked{}, err
+		}
+	}
+
+	u.BlockedUserIDs.Add(other.ID)
+
+	ev := event.UserBlocked{
+		UserID:        u.ID,
+		BlockedUserID: other.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// Unblock removes the block for the other user.
+// The friendship removed by blocking is not restored.
+// It returns ValidationError when the other user is not blocked.
+func (u *User) Unblock(other *User) (event.UserUnblocked, error) {
+	if err := validateOtherUser(u, other); err != nil {
+		return event.UserUnblocked{}, err
+	}
+	if !u.HasBlocked(*other) {
+		return event.UserUnblocked{}, NewValidationError("user(id=%d) is not blocked", other.ID)
+	}
+
+	u.BlockedUserIDs.Remove(other.ID)
+
+	ev := event.UserUnblocked{
+		UserID:          u.ID,
+		UnblockedUserID: other.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// validateNotBlockedBy returns PermissionError when the other user
+// blocks the user, then the user can not relate with the other user
+// for the operation.
+func validateNotBlockedBy(u *User, other User, operation string) error {
+	if other.HasBlocked(*u) {
+		return NewPermissionError("user(id=%d) can not %s user(id=%d)", u.ID, operation, other.ID)
+	}
+	return nil
+}
+
+// VisibleUserIDs returns the user IDs except the users blocked by the user.
+// It is used to hide the presence of the user from the blocked users.
+func (u *User) VisibleUserIDs(ids []uint64) []uint64 {
+	visibles := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if !u.BlockedUserIDs.Has(id) {
+			visibles = append(visibles, id)
+		}
+	}
+	return visibles
+}
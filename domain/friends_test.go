@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestUserFriendRequestAccept(t *testing.T) {
+	sender := User{ID: 1, EventHolder: NewEventHolder()}
+	receiver := User{ID: 2, EventHolder: NewEventHolder()}
+
+	ev, err := sender.RequestFriend(&receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.SenderID != sender.ID || ev.ReceiverID != receiver.ID {
+		t.Errorf("different FriendRequestSent: %#v", ev)
+	}
+	if !sender.HasRequestedFriend(receiver) {
+		t.Errorf("friend request is not held by the sender")
+	}
+
+	// duplicated requests by either of them.
+	if _, err := sender.RequestFriend(&receiver); !IsValidationError(err) {
+		t.Errorf("duplicated request should be ValidationError, got: %v", err)
+	}
+	if _, err := receiver.RequestFriend(&sender); !IsValidationError(err) {
+		t.Errorf("request to the sender should be ValidationError, got: %v", err)
+	}
+
+	accepted, err := receiver.AcceptFriendRequest(&sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted.SenderID != sender.ID || accepted.ReceiverID != receiver.ID {
+		t.Errorf("different FriendRequestAccepted: %#v", accepted)
+	}
+	if !sender.HasFriend(receiver) || !receiver.HasFriend(sender) {
+		t.Errorf("friendship should be mutual after accepted")
+	}
+	if sender.HasRequestedFriend(receiver) {
+		t.Errorf("accepted request still remains")
+	}
+	if got := len(receiver.Events()); got != 1 {
+		t.Errorf("receiver should have one event, got: %d", got)
+	}
+
+	// already friends.
+	if _, err := sender.RequestFriend(&receiver); !IsValidationError(err) {
+		t.Errorf("request to the friend should be ValidationError, got: %v", err)
+	}
+	if _, err := receiver.AcceptFriendRequest(&sender); !IsValidationError(err) {
+		t.Errorf("accept no request should be ValidationError, got: %v", err)
+	}
+}
+
+func TestUserFriendRequestDeclineAndCancel(t *testing.T) {
+	sender := User{ID: 1, EventHolder: NewEventHolder()}
+	receiver := User{ID: 2, EventHolder: NewEventHolder()}
+
+	// decline
+	if _, err := sender.RequestFriend(&receiver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receiver.DeclineFriendRequest(&sender); err != nil {
+		t.Fatal(err)
+	}
+	if sender.HasRequestedFriend(receiver) || sender.HasFriend(receiver) || receiver.HasFriend(sender) {
+		t.Errorf("declined request should not remain or make friends")
+	}
+	if _, err := receiver.DeclineFriendRequest(&sender); !IsValidationError(err) {
+		t.Errorf("decline no request should be ValidationError, got: %v", err)
+	}
+
+	// cancel
+	if _, err := sender.RequestFriend(&receiver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sender.CancelFriendRequest(&receiver); err != nil {
+		t.Fatal(err)
+	}
+	if sender.HasRequestedFriend(receiver) {
+		t.Errorf("canceled request still remains")
+	}
+	if _, err := sender.CancelFriendRequest(&receiver); !IsValidationError(err) {
+		t.Errorf("cancel no request should be ValidationError, got: %v", err)
+	}
+
+	// request to itself
+	if _, err := sender.RequestFriend(&sender); !IsValidationError(err) {
+		t.Errorf("request to itself should be ValidationError, got: %v", err)
+	}
+}
+
+func TestUserUnfriend(t *testing.T) {
+	u := User{ID: 1, FriendIDs: NewUserIDSet(2), EventHolder: NewEventHolder()}
+	friend := User{ID: 2, FriendIDs: NewUserIDSet(1), EventHolder: NewEventHolder()}
+
+	ev, err := u.Unfriend(&friend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.UserID != u.ID || ev.RemovedFriendID != friend.ID {
+		t.Errorf("different UserRemovedFriend: %#v", ev)
+	}
+	if u.HasFriend(friend) || friend.HasFriend(u) {
+		t.Errorf("friendship still remains after unfriend")
+	}
+	if _, err := u.Unfriend(&friend); !IsValidationError(err) {
+		t.Errorf("unfriend no friend should be ValidationError, got: %v", err)
+	}
+}
+
+func TestUserBlock(t *testing.T) {
+	u := User{ID: 1, FriendIDs: NewUserIDSet(2), RequestedFriendIDs: NewUserIDSet(3), EventHolder: NewEventHolder()}
+	friend := User{ID: 2, FriendIDs: NewUserIDSet(1), EventHolder: NewEventHolder()}
+	requested := User{ID: 3, EventHolder: NewEventHolder()}
+	requester := User{ID: 4, RequestedFriendIDs: NewUserIDSet(1), EventHolder: NewEventHolder()}
+
+	for _, other := range []*User{&friend, &requested, &requester} {
+		ev, err := u.Block(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.UserID != u.ID || ev.BlockedUserID != other.ID {
+			t.Errorf("different UserBlocked: %#v", ev)
+		}
+		if !u.HasBlocked(*other) {
+			t.Errorf("user(id=%d) is not blocked", other.ID)
+		}
+	}
+	if u.HasFriend(friend) || friend.HasFriend(u) {
+		t.Errorf("friendship still remains after block")
+	}
+	if u.HasRequestedFriend(requested) || requester.HasRequestedFriend(u) {
+		t.Errorf("friend request still remains after block")
+	}
+
+	// removed friend, canceled and declined requests, and blocks.
+	expectTypes := []event.Type{
+		event.TypeUserRemovedFriend, event.TypeUserBlocked,
+		event.TypeFriendRequestCanceled, event.TypeUserBlocked,
+		event.TypeFriendRequestDeclined, event.TypeUserBlocked,
+	}
+	events := u.Events()
+	if len(events) != len(expectTypes) {
+		t.Fatalf("different number of events, expect: %d, got: %d", len(expectTypes), len(events))
+	}
+	for i, ev := range events {
+		if ev.Type() != expectTypes[i] {
+			t.Errorf("different event type at %d, expect: %v, got: %v", i, expectTypes[i], ev.Type())
+		}
+	}
+
+	// blocked user can not send request.
+	if _, err := friend.RequestFriend(&u); !IsPermissionError(err) {
+		t.Errorf("request from blocked user should be PermissionError, got: %v", err)
+	}
+	if _, err := u.RequestFriend(&friend); !IsValidationError(err) {
+		t.Errorf("request to blocked user should be ValidationError, got: %v", err)
+	}
+	if _, err := u.Block(&friend); !IsValidationError(err) {
+		t.Errorf("block again should be ValidationError, got: %v", err)
+	}
+}
+
+func TestUserUnblock(t *testing.T) {
+	u := User{ID: 1, BlockedUserIDs: NewUserIDSet(2), EventHolder: NewEventHolder()}
+	blocked := User{ID: 2, EventHolder: NewEventHolder()}
+
+	ev, err := u.Unblock(&blocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.UserID != u.ID || ev.UnblockedUserID != blocked.ID {
+		t.Errorf("different UserUnblocked: %#v", ev)
+	}
+	if u.HasBlocked(blocked) {
+		t.Errorf("user(id=%d) is still blocked", blocked.ID)
+	}
+	if got := len(u.Events()); got != 1 {
+		t.Errorf("user should have one event, got: %d", got)
+	}
+
+	// unblocked user can send request again.
+	if _, err := blocked.RequestFriend(&u); err != nil {
+		t.Errorf("request from unblocked user, got error: %v", err)
+	}
+	if _, err := u.Unblock(&blocked); !IsValidationError(err) {
+		t.Errorf("unblock not blocked user should be ValidationError, got: %v", err)
+	}
+}
+
+func TestUserVisibleUserIDs(t *testing.T) {
+	u := User{ID: 1, BlockedUserIDs: NewUserIDSet(3)}
+
+	visibles := u.VisibleUserIDs([]uint64{2, 3, 4})
+	if len(visibles) != 2 || visibles[0] != 2 || visibles[1] != 4 {
+		t.Errorf("different visible user IDs, expect: %v, got: %v", []uint64{2, 4}, visibles)
+	}
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// MentionRoom is the special name to mention all of the room members
+// by "@room".
+const MentionRoom = "room"
+
+// isMentionNameRune returns whether the rune can be
+// used in the name following "@".
+func isMentionNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// ParseMentions returns the names mentioned by "@name" in the content,
+// in order of appearance without duplication. The "@" preceded by
+// an ASCII name character, such as in the email address, is not a mention.
+// The trailing dots of the name are regarded as the punctuations.
+func ParseMentions(content string) []string {
+	names := make([]string, 0, 2)
+	seen := make(map[string]bool, 2)
+
+	prev := ' '
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r != '@' || (prev < utf8.RuneSelf && isMentionNameRune(prev)) {
+			prev = r
+			i += size
+			continue
+		}
+
+		start := i + size
+		end := start
+		for end < len(content) {
+			r, size := utf8.DecodeRuneInString(content[end:])
+			if !isMentionNameRune(r) {
+				break
+			}
+			end += size
+		}
+		name := content[start:end]
+		for len(name) > 0 && name[len(name)-1] == '.' {
+			name = name[:len(name)-1]
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+
+		prev = '@'
+		i = end
+	}
+	return names
+}
+
+// resolveMentions returns the IDs of the room members mentioned
+// in the content by the author u. "@room" mentions all of the
+// room members. The author and the names which are not room
+// members are not contained.
+func resolveMentions(ctx context.Context, users UserRepository, u User, r Room, content string) (UserIDSet, error) {
+	mentioned := NewUserIDSet()
+	for _, name := range ParseMentions(content) {
+		if name == MentionRoom {
+			for _, id := range r.MemberIDs() {
+				mentioned.Add(id)
+			}
+			continue
+		}
+
+		exist, err := users.ExistsByName(ctx, name)
+		if err != nil {
+			return UserIDSet{}, err
+		}
+		if !exist {
+			continue
+		}
+		user, err := users.FindByName(ctx, name)
+		if err != nil {
+			return UserIDSet{}, err
+		}
+		if r.MemberIDSet.Has(user.ID) {
+			mentioned.Add(user.ID)
+		}
+	}
+	mentioned.Remove(u.ID)
+	return mentioned, nil
+}
+
+// MentionedIDs returns the IDs of the users mentioned in
+// the message, ordered by the user ID.
+func (m *Message) MentionedIDs() []uint64 {
+	ids := m.MentionedUserIDs.List()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// addMentionedEvents adds UserMentioned event for each of
+// the users mentioned in the message.
+func (m *Message) addMentionedEvents() {
+	for _, userID := range m.MentionedIDs() {
+		ev := event.UserMentioned{
+			MessageID:   m.ID,
+			RoomID:      m.RoomID,
+			MentionedBy: m.UserID,
+			UserID:      userID,
+		}
+		ev.Occurs()
+		m.AddEvent(ev)
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestParseMentions(t *testing.T) {
+	for _, testcase := range []struct {
+		Content string
+		Names   []string
+	}{
+		{"hello @user1 and @user_2.", []string{"user1", "user_2"}},
+		{"@room @room look at this", []string{"room"}},
+		{"@first.last, @a-b!", []string{"first.last", "a-b"}},
+		{"mail to user@example.com", []string{}},
+		{"@ alone and @@double", []string{"double"}},
+		{"こんにちは@ユーザー さん", []string{"ユーザー"}},
+		{"no mentions", []string{}},
+	} {
+		if got := ParseMentions(testcase.Content); !reflect.DeepEqual(got, testcase.Names) {
+			t.Errorf("ParseMentions(%q): expect %v, got %v", testcase.Content, testcase.Names, got)
+		}
+	}
+}
+
+func TestNewRoomMessageMentions(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		user = User{ID: 1}
+		room = Room{ID: 1, MemberIDSet: NewUserIDSet(user.ID, 2, 3, ExistUserID)}
+	)
+
+	for _, testcase := range []struct {
+		Content   string
+		Mentioned []uint64
+	}{
+		{"hi @" + ExistUserName, []uint64{ExistUserID}},
+		// the author and the unknown user are not mentioned.
+		{"@" + ExistUserName + " @" + ExistUserName + ", @" + MentionRoom, []uint64{2, 3, ExistUserID}},
+		{"hi @" + ExistUserName + ".", []uint64{ExistUserID}},
+		{"hi all", []uint64{}},
+	} {
+		m, err := NewRoomMessage(ctx, msgRepo, userRepo, user, room, testcase.Content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mentioned := make([]uint64, 0, len(testcase.Mentioned))
+		for _, ev := range m.Events()[1:] {
+			ev, ok := ev.(event.UserMentioned)
+			if !ok {
+				t.Fatalf("message holds unexpected event: %#v", ev)
+			}
+			if ev.MessageID != m.ID || ev.RoomID != room.ID || ev.MentionedBy != user.ID {
+				t.Errorf("UserMentioned has different fields: %#v", ev)
+			}
+			if !m.MentionedUserIDs.Has(ev.UserID) {
+				t.Errorf("user(id=%d) is mentioned but not recorded on the message", ev.UserID)
+			}
+			mentioned = append(mentioned, ev.UserID)
+		}
+		if !reflect.DeepEqual(mentioned, testcase.Mentioned) {
+			t.Errorf("content %q: expect mentioned users %v, got %v", testcase.Content, testcase.Mentioned, mentioned)
+		}
+		if got := len(m.MentionedUserIDs.List()); got != len(testcase.Mentioned) {
+			t.Errorf("content %q: expect %d mentioned users on the message, got %d", testcase.Content, len(testcase.Mentioned), got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/shirasudon/go-chat/domain/event"
@@ -19,9 +20,16 @@ type MessageRepository interface {
 	// Store stores given message to the repository.
 	// user need not to set ID for message since it is auto set
 	// when message is newly.
+	// If the message already exists in the repository, it is updated.
 	// It returns stored Message ID and error.
 	Store(ctx context.Context, m Message) (uint64, error)
 
+	// RecountReplies updates ReplyCount and LastReplyAt of the thread root
+	// message specified by parentID, by counting its replies not deleted.
+	// They are not updated by Store, so that the concurrent replies are not lost.
+	// It returns the updated thread root message and error.
+	RecountReplies(ctx context.Context, parentID uint64) (Message, error)
+
 	// RemoveAllByRoomID removes all messages related with roomID.
 	RemoveAllByRoomID(ctx context.Context, roomID uint64) error
 }
@@ -37,51 +45,196 @@ type Message struct {
 	UserID  uint64 `db:"user_id"`
 	RoomID  uint64 `db:"room_id"`
 	Deleted bool   `db:"deleted"`
+
+	// EditedAt is the time of the last edit.
+	// Zero value means the message has never been edited.
+	EditedAt time.Time `db:"edited_at"`
+
+	// ParentID is the ID of the thread root message which
+	// the message replies to. Zero value means the message
+	// is not a reply.
+	ParentID uint64 `db:"parent_id"`
+
+	// ReplyCount and LastReplyAt summarize the replies
+	// to the thread root message.
+	ReplyCount  int       `db:"reply_count"`
+	LastReplyAt time.Time `db:"last_reply_at"`
+
+	// Reactions are the emoji reactions by the users.
+	Reactions ReactionSet `db:"-"`
+
+	// MentionedUserIDs are the IDs of the room members
+	// mentioned in the content when the message is created.
+	MentionedUserIDs UserIDSet `db:"-"`
+
+	// AttachmentIDs are the IDs of the attachments on the message,
+	// ordered by the ID.
+	AttachmentIDs []uint64 `db:"-"`
+}
+
+// validateRoomPoster returns error when the user can not
+// post the message into the room.
+func validateRoomPoster(u User, r Room) error {
+	if u.NotExist() {
+		return errors.New("the user not in the datastore, can not create new message")
+	}
+	if r.NotExist() {
+		return errors.New("the room not in the datastore, can not create new message")
+	}
+	if !r.HasMember(u) {
+		return fmt.Errorf("user(id=%d) not a member of the room(id=%d), can not create message", u.ID, r.ID)
+	}
+	if !r.RoleOf(u.ID).CanPost() {
+		return NewPermissionError("user(id=%d) is read-only member of the room(id=%d), can not create message", u.ID, r.ID)
+	}
+	return nil
 }
 
 // NewRoomMessage creates new message for the specified room.
 // The created message is immediately stored into the repository.
+// The room members mentioned by "@name" or "@room" in the content are
+// recorded on the message, and the message also holds UserMentioned
+// event for each of them.
+// The attachments, which must be uploaded into the room by u and
+// not attached yet, are marked as attached to the created message.
+// The caller must store the attachments into the repository.
 // It returns new message holding event message created and error if any.
 func NewRoomMessage(
 	ctx context.Context,
 	msgs MessageRepository,
+	users UserRepository,
 	u User,
 	r Room,
 	content string,
+	attachments ...*Attachment,
 ) (Message, error) {
-	if u.NotExist() {
-		return Message{}, errors.New("the user not in the datastore, can not create new message")
+	if err := validateRoomPoster(u, r); err != nil {
+		return Message{}, err
 	}
-	if r.NotExist() {
-		return Message{}, errors.New("the room not in the datastore, can not create new message")
+	if err := validateAttachments(u, r, attachments); err != nil {
+		return Message{}, err
 	}
-	if !r.HasMember(u) {
-		return Message{}, fmt.Errorf("user(id=%d) not a member of the room(id=%d), can not create message", u.ID, r.ID)
+
+	mentioned, err := resolveMentions(ctx, users, u, r, content)
+	if err != nil {
+		return Message{}, err
 	}
 
 	m := Message{
-		EventHolder: NewEventHolder(),
-		ID:          0,
-		CreatedAt:   time.Now(),
-		Content:     content,
-		UserID:      u.ID,
-		RoomID:      r.ID,
-		Deleted:     false,
+		EventHolder:      NewEventHolder(),
+		ID:               0,
+		CreatedAt:        time.Now(),
+		Content:          content,
+		UserID:           u.ID,
+		RoomID:           r.ID,
+		Deleted:          false,
+		MentionedUserIDs: mentioned,
+		AttachmentIDs:    attachmentIDs(attachments),
 	}
 	id, err := msgs.Store(ctx, m)
 	if err != nil {
 		return Message{}, err
 	}
 	m.ID = id
+	attachTo(&m, attachments)
 
 	ev := event.MessageCreated{
-		MessageID: m.ID,
-		RoomID:    m.RoomID,
-		CreatedBy: u.ID,
-		Content:   content,
+		MessageID:     m.ID,
+		RoomID:        m.RoomID,
+		CreatedBy:     u.ID,
+		Content:       content,
+		AttachmentIDs: m.AttachmentIDs,
 	}
 	ev.Occurs()
 	m.AddEvent(ev)
+	m.addMentionedEvents()
+
+	return m, nil
+}
+
+// NewThreadMessage creates new message replying to the parent message
+// in the specified room. The parent must be a thread root, which is not
+// a reply, and not deleted. The created message and the parent, whose
+// reply count and last reply time are updated, are immediately stored
+// into the repository. The mentions in the content and the attachments
+// are handled as same as NewRoomMessage.
+// It returns new message holding event message created, which has
+// the thread summary, and error if any.
+func NewThreadMessage(
+	ctx context.Context,
+	msgs MessageRepository,
+	users UserRepository,
+	u User,
+	r Room,
+	parent *Message,
+	content string,
+	attachments ...*Attachment,
+) (Message, error) {
+	if err := validateRoomPoster(u, r); err != nil {
+		return Message{}, err
+	}
+	if err := validateAttachments(u, r, attachments); err != nil {
+		return Message{}, err
+	}
+	if parent.NotExist() {
+		return Message{}, errors.New("the parent message not in the datastore, can not reply to it")
+	}
+	if parent.RoomID != r.ID {
+		return Message{}, NewValidationError("the parent message(id=%d) is not in the room(id=%d)", parent.ID, r.ID)
+	}
+	if parent.IsReply() {
+		return Message{}, NewValidationError("the message(id=%d) is a reply, can not reply to it", parent.ID)
+	}
+	if parent.Deleted {
+		return Message{}, NewValidationError("the message(id=%d) is already deleted, can not reply to it", parent.ID)
+	}
+
+	mentioned, err := resolveMentions(ctx, users, u, r, content)
+	if err != nil {
+		return Message{}, err
+	}
+
+	m := Message{
+		EventHolder:      NewEventHolder(),
+		ID:               0,
+		CreatedAt:        time.Now(),
+		Content:          content,
+		UserID:           u.ID,
+		RoomID:           r.ID,
+		Deleted:          false,
+		MentionedUserIDs: mentioned,
+		AttachmentIDs:    attachmentIDs(attachments),
+		ParentID:         parent.ID,
+	}
+	id, err := msgs.Store(ctx, m)
+	if err != nil {
+		return Message{}, err
+	}
+	m.ID = id
+	attachTo(&m, attachments)
+
+	updated, err := msgs.RecountReplies(ctx, parent.ID)
+	if err != nil {
+		return Message{}, err
+	}
+	parent.ReplyCount = updated.ReplyCount
+	parent.LastReplyAt = updated.LastReplyAt
+
+	ev := event.MessageCreated{
+		MessageID:     m.ID,
+		RoomID:        m.RoomID,
+		CreatedBy:     u.ID,
+		Content:       content,
+		AttachmentIDs: m.AttachmentIDs,
+		Thread: &event.ThreadSummary{
+			ParentID:    parent.ID,
+			ReplyCount:  parent.ReplyCount,
+			LastReplyAt: parent.LastReplyAt,
+		},
+	}
+	ev.Occurs()
+	m.AddEvent(ev)
+	m.addMentionedEvents()
 
 	return m, nil
 }
@@ -89,3 +242,114 @@ func NewRoomMessage(
 func (m *Message) NotExist() bool {
 	return m == nil || m.ID == 0
 }
+
+// IsReply returns whether the message is a reply in the thread.
+func (m *Message) IsReply() bool {
+	return m.ParentID != 0
+}
+
+// validateAuthor returns error when the user is not the author
+// of the message in the room, or is no longer a member of the room.
+func (m *Message) validateAuthor(u *User, r Room, operation string) error {
+	if m.NotExist() {
+		return fmt.Errorf("the message not in the datastore, can not %s it", operation)
+	}
+	if u.NotExist() {
+		return fmt.Errorf("the user not in the datastore, can not %s the message", operation)
+	}
+	if m.RoomID != r.ID {
+		return fmt.Errorf("the message(id=%d) is not in the room(id=%d)", m.ID, r.ID)
+	}
+	if m.UserID != u.ID {
+		return NewPermissionError("user(id=%d) is not the author of the message(id=%d), can not %s it", u.ID, m.ID, operation)
+	}
+	if !r.HasMember(*u) {
+		return NewPermissionError("user(id=%d) not a member of the room(id=%d), can not %s the message", u.ID, r.ID, operation)
+	}
+	return nil
+}
+
+// Edit replaces the message content by the specified user.
+// Only the author of the message, who must be a member of the room
+// and can post to it, can edit it. Deleted message can not be edited,
+// and the content can not be empty.
+// The edited message is immediately stored into the repository.
+// After successing that, the message holds MessageEdited event.
+func (m *Message) Edit(ctx context.Context, msgs MessageRepository, u *User, r Room, content string) error {
+	if err := m.validateAuthor(u, r, "edit"); err != nil {
+		return err
+	}
+	if !r.RoleOf(u.ID).CanPost() {
+		return NewPermissionError("user(id=%d) is read-only member of the room(id=%d), can not edit the message", u.ID, r.ID)
+	}
+	if m.Deleted {
+		return NewValidationError("the message(id=%d) is already deleted, can not be edited", m.ID)
+	}
+	if strings.TrimSpace(content) == "" {
+		return NewValidationError("the message(id=%d) can not be edited to empty content", m.ID)
+	}
+
+	m.Content = content
+	m.EditedAt = time.Now()
+	if _, err := msgs.Store(ctx, *m); err != nil {
+		return err
+	}
+
+	ev := event.MessageEdited{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		EditedBy:  u.ID,
+		Content:   content,
+	}
+	ev.Occurs()
+	m.AddEvent(ev)
+
+	return nil
+}
+
+// Delete marks the message as deleted by the specified user.
+// Only the author of the message, who must be a member of the room,
+// can delete it.
+// The deleted message remains in the repository as a tombstone,
+// but its content is cleared. The deleted reply is not counted
+// in the thread summary of its parent.
+// After successing that, the message holds MessageDeleted event.
+func (m *Message) Delete(ctx context.Context, msgs MessageRepository, u *User, r Room) error {
+	if err := m.validateAuthor(u, r, "delete"); err != nil {
+		return err
+	}
+	if m.Deleted {
+		return NewValidationError("the message(id=%d) is already deleted", m.ID)
+	}
+
+	m.Deleted = true
+	m.Content = ""
+	m.Reactions = NewReactionSet()
+	if _, err := msgs.Store(ctx, *m); err != nil {
+		return err
+	}
+
+	var thread *event.ThreadSummary
+	if m.IsReply() {
+		parent, err := msgs.RecountReplies(ctx, m.ParentID)
+		if err != nil {
+			return err
+		}
+		thread = &event.ThreadSummary{
+			ParentID:    parent.ID,
+			ReplyCount:  parent.ReplyCount,
+			LastReplyAt: parent.LastReplyAt,
+		}
+	}
+
+	ev := event.MessageDeleted{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		DeletedBy: u.ID,
+		Thread:    thread,
+	}
+	ev.Occurs()
+	m.AddEvent(ev)
+
+	return nil
+}
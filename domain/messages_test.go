@@ -35,6 +35,43 @@ func (m *MessageRepositoryStub) RemoveAllByRoomID(ctx context.Context, roomID ui
 	panic("not implemented")
 }
 
+func (m *MessageRepositoryStub) RecountReplies(ctx context.Context, parentID uint64) (Message, error) {
+	return Message{ID: parentID}, nil
+}
+
+// threadRepositoryStub is a MessageRepositoryStub which
+// recounts the replies stored into it.
+type threadRepositoryStub struct {
+	MessageRepositoryStub
+	replies []Message
+}
+
+func (repo *threadRepositoryStub) Store(ctx context.Context, msg Message) (uint64, error) {
+	if !msg.IsReply() {
+		return msg.ID, nil
+	}
+	if msg.NotExist() {
+		msg.ID = uint64(len(repo.replies) + 1)
+		repo.replies = append(repo.replies, msg)
+	} else {
+		repo.replies[msg.ID-1] = msg
+	}
+	return msg.ID, nil
+}
+
+func (repo *threadRepositoryStub) RecountReplies(ctx context.Context, parentID uint64) (Message, error) {
+	parent := Message{ID: parentID}
+	for _, m := range repo.replies {
+		if m.ParentID == parentID && !m.Deleted {
+			parent.ReplyCount += 1
+			if m.CreatedAt.After(parent.LastReplyAt) {
+				parent.LastReplyAt = m.CreatedAt
+			}
+		}
+	}
+	return parent, nil
+}
+
 var msgRepo MessageRepository = &MessageRepositoryStub{}
 
 func TestMessageCreatedSuccess(t *testing.T) {
@@ -43,8 +80,8 @@ func TestMessageCreatedSuccess(t *testing.T) {
 		user = User{ID: 1}
 		room = Room{ID: 1}
 	)
-	room.AddMember(user)
-	m, err := NewRoomMessage(ctx, msgRepo, user, room, "content")
+	room.MemberIDSet.Add(user.ID)
+	m, err := NewRoomMessage(ctx, msgRepo, userRepo, user, room, "content")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -85,10 +122,229 @@ func TestMessageCreatedFail(t *testing.T) {
 		{User{ID: 0}, Room{ID: 0}},
 	} {
 		user, room := testcase.User, testcase.Room
-		room.AddMember(user)
-		_, err := NewRoomMessage(ctx, msgRepo, user, room, "content")
+		room.MemberIDSet.Add(user.ID)
+		_, err := NewRoomMessage(ctx, msgRepo, userRepo, user, room, "content")
 		if err == nil {
 			t.Errorf("invalid combination of user and room, but no error: user(%d), room(%d)", user.ID, room.ID)
 		}
 	}
 }
+
+func TestThreadMessageCreated(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		user   = User{ID: 1}
+		room   = Room{ID: 1, MemberIDSet: NewUserIDSet(1)}
+		parent = Message{ID: 10, RoomID: room.ID, UserID: user.ID}
+	)
+
+	threadRepo := &threadRepositoryStub{}
+	m, err := NewThreadMessage(ctx, threadRepo, userRepo, user, room, &parent, "reply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsReply() || m.ParentID != parent.ID {
+		t.Errorf("created message is not a reply to the parent(id=%d), got: %v", parent.ID, m.ParentID)
+	}
+	if parent.ReplyCount != 1 || parent.LastReplyAt != m.CreatedAt {
+		t.Errorf("thread summary of the parent is not updated: %v, %v", parent.ReplyCount, parent.LastReplyAt)
+	}
+
+	ev, ok := m.Events()[0].(event.MessageCreated)
+	if !ok {
+		t.Fatalf("Message is created but event is not a MessageCreated, got: %v", m.Events()[0])
+	}
+	if ev.Thread == nil || ev.Thread.ParentID != parent.ID || ev.Thread.ReplyCount != 1 {
+		t.Errorf("MessageCreated has different thread summary: %#v", ev.Thread)
+	}
+
+	// invalid parents.
+	for _, p := range []Message{
+		{ID: 11, RoomID: room.ID + 1},
+		{ID: 12, RoomID: room.ID, ParentID: parent.ID},
+		{ID: 13, RoomID: room.ID, Deleted: true},
+	} {
+		if _, err := NewThreadMessage(ctx, msgRepo, userRepo, user, room, &p, "reply"); !IsValidationError(err) {
+			t.Errorf("reply to invalid parent %#v, expect validation error, got: %v", p, err)
+		}
+	}
+	if _, err := NewThreadMessage(ctx, msgRepo, userRepo, user, room, &Message{}, "reply"); err == nil {
+		t.Errorf("reply to not existing parent, but no error")
+	}
+}
+
+func TestMessageEdit(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		author = User{ID: 1}
+		other  = User{ID: 2}
+		room   = Room{ID: 1, MemberIDSet: NewUserIDSet(author.ID, other.ID)}
+	)
+
+	// case1: success
+	m := Message{ID: 1, RoomID: 1, UserID: author.ID, Content: "before"}
+	if err := m.Edit(ctx, msgRepo, &author, room, "after"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "after" {
+		t.Errorf("message is edited but content is not changed, got: %v", m.Content)
+	}
+	if m.EditedAt == (time.Time{}) {
+		t.Errorf("message is edited but edited time is not set")
+	}
+	events := m.Events()
+	if len(events) != 1 {
+		t.Fatalf("message is edited but message has no event for that")
+	}
+	ev, ok := events[0].(event.MessageEdited)
+	if !ok {
+		t.Fatalf("message is edited but event is not a MessageEdited, got: %v", events[0])
+	}
+	if ev.MessageID != m.ID || ev.EditedBy != author.ID || ev.Content != "after" {
+		t.Errorf("MessageEdited has invalid fields, got: %#v", ev)
+	}
+
+	// case2: edited by not an author
+	m = Message{ID: 1, RoomID: 1, UserID: author.ID, Content: "before"}
+	if err := m.Edit(ctx, msgRepo, &other, room, "after"); !IsPermissionError(err) {
+		t.Errorf("message is edited by not an author, but no permission error: %v", err)
+	}
+
+	// case3: edit deleted message
+	m = Message{ID: 1, RoomID: 1, UserID: author.ID, Deleted: true}
+	if err := m.Edit(ctx, msgRepo, &author, room, "after"); !IsValidationError(err) {
+		t.Errorf("deleted message is edited, but no validation error: %v", err)
+	}
+
+	// case4: edit not exist message
+	m = Message{ID: 0, RoomID: 1, UserID: author.ID}
+	if err := m.Edit(ctx, msgRepo, &author, room, "after"); err == nil {
+		t.Errorf("not exist message is edited, but no error")
+	}
+
+	// case5: edit to empty content
+	m = Message{ID: 1, RoomID: 1, UserID: author.ID, Content: "before"}
+	for _, content := range []string{"", " \n"} {
+		if err := m.Edit(ctx, msgRepo, &author, room, content); !IsValidationError(err) {
+			t.Errorf("message is edited to empty content %q, but no validation error: %v", content, err)
+		}
+	}
+
+	// case6: edited by the author who left the room
+	left := Room{ID: 1, MemberIDSet: NewUserIDSet(other.ID)}
+	if err := m.Edit(ctx, msgRepo, &author, left, "after"); !IsPermissionError(err) {
+		t.Errorf("message is edited by not a member, but no permission error: %v", err)
+	}
+
+	// case7: edited by the read-only member
+	readOnly := Room{ID: 1, MemberIDSet: NewUserIDSet(author.ID)}
+	readOnly.MemberRoles.Set(author.ID, RoomRoleReadOnly)
+	if err := m.Edit(ctx, msgRepo, &author, readOnly, "after"); !IsPermissionError(err) {
+		t.Errorf("message is edited by read-only member, but no permission error: %v", err)
+	}
+
+	// case8: edited with the other room
+	if err := m.Edit(ctx, msgRepo, &author, Room{ID: 2, MemberIDSet: NewUserIDSet(author.ID)}, "after"); err == nil {
+		t.Errorf("message is edited with the other room, but no error")
+	}
+	if m.Content != "before" {
+		t.Errorf("message is not edited but content is changed, got: %v", m.Content)
+	}
+}
+
+func TestMessageDelete(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		author = User{ID: 1}
+		other  = User{ID: 2}
+		room   = Room{ID: 1, MemberIDSet: NewUserIDSet(author.ID, other.ID)}
+	)
+
+	// case1: success
+	m := Message{ID: 1, RoomID: 1, UserID: author.ID, Content: "content"}
+	if err := m.Delete(ctx, msgRepo, &author, room); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Deleted {
+		t.Errorf("message is deleted but deleted flag is not set")
+	}
+	if m.Content != "" {
+		t.Errorf("message is deleted but content is remained, got: %v", m.Content)
+	}
+	events := m.Events()
+	if len(events) != 1 {
+		t.Fatalf("message is deleted but message has no event for that")
+	}
+	ev, ok := events[0].(event.MessageDeleted)
+	if !ok {
+		t.Fatalf("message is deleted but event is not a MessageDeleted, got: %v", events[0])
+	}
+	if ev.MessageID != m.ID || ev.DeletedBy != author.ID {
+		t.Errorf("MessageDeleted has invalid fields, got: %#v", ev)
+	}
+
+	// case2: deleted twice
+	if err := m.Delete(ctx, msgRepo, &author, room); !IsValidationError(err) {
+		t.Errorf("message is deleted twice, but no validation error: %v", err)
+	}
+
+	// case3: deleted by not an author
+	m = Message{ID: 1, RoomID: 1, UserID: author.ID, Content: "content"}
+	if err := m.Delete(ctx, msgRepo, &other, room); !IsPermissionError(err) {
+		t.Errorf("message is deleted by not an author, but no permission error: %v", err)
+	}
+
+	// case4: deleted by the author who left the room
+	left := Room{ID: 1, MemberIDSet: NewUserIDSet(other.ID)}
+	if err := m.Delete(ctx, msgRepo, &author, left); !IsPermissionError(err) {
+		t.Errorf("message is deleted by not a member, but no permission error: %v", err)
+	}
+	if m.Deleted {
+		t.Errorf("message is not deleted but deleted flag is set")
+	}
+}
+
+func TestThreadMessageDelete(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		user       = User{ID: 1}
+		room       = Room{ID: 1, MemberIDSet: NewUserIDSet(1)}
+		parent     = Message{ID: 10, RoomID: room.ID, UserID: user.ID}
+		threadRepo = &threadRepositoryStub{}
+	)
+
+	first, err := NewThreadMessage(ctx, threadRepo, userRepo, user, room, &parent, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewThreadMessage(ctx, threadRepo, userRepo, user, room, &parent, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 2 {
+		t.Fatalf("different reply count of the parent, expect: 2, got: %v", parent.ReplyCount)
+	}
+
+	// the deleted reply is not counted in the thread.
+	if err := second.Delete(ctx, threadRepo, &user, room); err != nil {
+		t.Fatal(err)
+	}
+	events := second.Events()
+	ev, ok := events[len(events)-1].(event.MessageDeleted)
+	if !ok {
+		t.Fatalf("message is deleted but event is not a MessageDeleted, got: %v", events[len(events)-1])
+	}
+	if ev.Thread == nil || ev.Thread.ParentID != parent.ID ||
+		ev.Thread.ReplyCount != 1 || !ev.Thread.LastReplyAt.Equal(first.CreatedAt) {
+		t.Errorf("MessageDeleted has different thread summary: %#v", ev.Thread)
+	}
+
+	// the deleted message which is not a reply has no thread summary.
+	root := Message{ID: 11, RoomID: room.ID, UserID: user.ID}
+	if err := root.Delete(ctx, threadRepo, &user, room); err != nil {
+		t.Fatal(err)
+	}
+	if ev := root.Events()[0].(event.MessageDeleted); ev.Thread != nil {
+		t.Errorf("MessageDeleted for the root has thread summary: %#v", ev.Thread)
+	}
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The algorithms for hashing the user password.
+const (
+	// PasswordAlgorithmPlain indicates the password is stored without hashing.
+	// It is only used by the users created before hashing the password,
+	// and such passwords are rehashed at the next login.
+	PasswordAlgorithmPlain = ""
+
+	// PasswordAlgorithmBcrypt indicates the password is hashed by bcrypt.
+	PasswordAlgorithmBcrypt = "bcrypt"
+)
+
+// The range and default of the cost for hashing the user password.
+const (
+	MinPasswordHashCost     = bcrypt.MinCost
+	MaxPasswordHashCost     = bcrypt.MaxCost
+	DefaultPasswordHashCost = bcrypt.DefaultCost
+)
+
+// maximum length of the password in bytes.
+// bcrypt only uses first 72 bytes of the password, so longer
+// password is rejected to prevent the truncated match.
+const maxPasswordBytes = 72
+
+// HashedPassword is the user password hashed by the algorithm with the cost.
+// The algorithm and cost are recorded for each user so that the password
+// can be rehashed when the cost is changed.
+type HashedPassword struct {
+	Algorithm string
+	Cost      int
+	Hash      string
+}
+
+// IsZero returns whether the password is not set.
+func (p HashedPassword) IsZero() bool {
+	return p == HashedPassword{}
+}
+
+// PasswordPolicy is the rules for the password set by the user.
+type PasswordPolicy struct {
+	// minimum length of the password in characters.
+	MinLength int
+
+	// the password must contain at least one letter.
+	RequireLetter bool
+
+	// the password must contain at least one digit.
+	RequireDigit bool
+}
+
+// DefaultPasswordPolicy is the default rules for the password.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     8,
+	RequireLetter: false,
+	RequireDigit:  false,
+}
+
+// Validate returns ValidationError when the password
+// does not satisfy the policy.
+func (policy PasswordPolicy) Validate(password string) error {
+	if password == "" {
+		return NewValidationError("password is empty")
+	}
+	if len(password) > maxPasswordBytes {
+		return NewValidationError("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	var length int
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if length < policy.MinLength {
+		return NewValidationError("password must be at least %d characters", policy.MinLength)
+	}
+	if policy.RequireLetter && !hasLetter {
+		return NewValidationError("password must contain a letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		return NewValidationError("password must contain a digit")
+	}
+	return nil
+}
+
+// PasswordHasher hashes the user password with the policy,
+// and verifies the password with the hashed one.
+type PasswordHasher struct {
+	policy PasswordPolicy
+	cost   int
+
+	// dummy is the hashed password verified instead of
+	// the one of the user not found. It is created lazily.
+	dummyOnce sync.Once
+	dummy     HashedPassword
+}
+
+// DefaultPasswordHasher uses DefaultPasswordPolicy and DefaultPasswordHashCost.
+var DefaultPasswordHasher = &PasswordHasher{
+	policy: DefaultPasswordPolicy,
+	cost:   DefaultPasswordHashCost,
+}
+
+// NewPasswordHasher creates PasswordHasher with the policy and the cost.
+// It returns error when the cost is out of range.
+func NewPasswordHasher(policy PasswordPolicy, cost int) (*PasswordHasher, error) {
+	if cost < MinPasswordHashCost || cost > MaxPasswordHashCost {
+		return nil, fmt.Errorf("password hash cost should be in [%d, %d], but %d",
+			MinPasswordHashCost, MaxPasswordHashCost, cost)
+	}
+	return &PasswordHasher{policy: policy, cost: cost}, nil
+}
+
+// Hash returns the hashed password. It returns ValidationError
+// when the password does not satisfy the policy.
+func (h *PasswordHasher) Hash(password string) (HashedPassword, error) {
+	if err := h.policy.Validate(password); err != nil {
+		return HashedPassword{}, err
+	}
+	return h.hash(password)
+}
+
+// hash returns the hashed password without validating the policy.
+// It is used to rehash the password which is already accepted.
+func (h *PasswordHasher) hash(password string) (HashedPassword, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return HashedPassword{}, err
+	}
+	return HashedPassword{
+		Algorithm: PasswordAlgorithmBcrypt,
+		Cost:      h.cost,
+		Hash:      string(hash),
+	}, nil
+}
+
+// Verify returns whether the password matches the hashed one.
+func (h *PasswordHasher) Verify(hashed HashedPassword, password string) bool {
+	switch hashed.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hashed.Hash), []byte(password)) == nil
+	case PasswordAlgorithmPlain:
+		return hashed.Hash != "" &&
+			subtle.ConstantTimeCompare([]byte(hashed.Hash), []byte(password)) == 1
+	default:
+		return false
+	}
+}
+
+// VerifyDummy verifies the password with the dummy hashed one and
+// always returns false. It is used when the user is not found, so that
+// the time to verify does not tell whether the user exists.
+func (h *PasswordHasher) VerifyDummy(password string) bool {
+	h.dummyOnce.Do(func() {
+		// failing it does not happen since the cost is valid.
+		h.dummy, _ = h.hash("dummy password")
+	})
+	h.Verify(h.dummy, password)
+	return false
+}
+
+// NeedsRehash returns whether the hashed password should be
+// rehashed by the current algorithm and cost.
+func (h *PasswordHasher) NeedsRehash(hashed HashedPassword) bool {
+	return hashed.Algorithm != PasswordAlgorithmBcrypt || hashed.Cost != h.cost
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 4, RequireLetter: true, RequireDigit: true}
+	for _, testcase := range []struct {
+		Password string
+		Valid    bool
+	}{
+		{"pa55", true},
+		{"パス12", true},
+		{"", false},
+		{"p5", false},
+		{"pass", false},
+		{"1234", false},
+		{strings.Repeat("p5", 37), false}, // over 72 bytes
+	} {
+		err := policy.Validate(testcase.Password)
+		if testcase.Valid && err != nil {
+			t.Errorf("valid password(%q) is rejected: %v", testcase.Password, err)
+		}
+		if !testcase.Valid && !IsValidationError(err) {
+			t.Errorf("invalid password(%q) should be ValidationError, got: %v", testcase.Password, err)
+		}
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	for _, cost := range []int{MinPasswordHashCost - 1, MaxPasswordHashCost + 1} {
+		if _, err := NewPasswordHasher(DefaultPasswordPolicy, cost); err == nil {
+			t.Errorf("out of range cost(%d) is accepted", cost)
+		}
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordPolicy{MinLength: 8}, MinPasswordHashCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := hasher.Hash("short"); !IsValidationError(err) {
+		t.Errorf("password violating policy should be ValidationError, got: %v", err)
+	}
+
+	const Password = "password"
+	hashed, err := hasher.Hash(Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed.Algorithm != PasswordAlgorithmBcrypt || hashed.Cost != MinPasswordHashCost {
+		t.Errorf("different algorithm or cost, got: %v", hashed)
+	}
+	if hashed.Hash == Password {
+		t.Errorf("password is not hashed")
+	}
+	if !hasher.Verify(hashed, Password) {
+		t.Errorf("hashed password is not verified")
+	}
+	if hasher.Verify(hashed, "wrong-password") {
+		t.Errorf("wrong password is verified")
+	}
+	if hasher.NeedsRehash(hashed) {
+		t.Errorf("password hashed by current cost needs rehash")
+	}
+
+	// changing cost
+	costUp, err := NewPasswordHasher(PasswordPolicy{MinLength: 8}, MinPasswordHashCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !costUp.NeedsRehash(hashed) {
+		t.Errorf("password hashed by old cost does not need rehash")
+	}
+	if !costUp.Verify(hashed, Password) {
+		t.Errorf("password hashed by old cost is not verified")
+	}
+
+	// legacy plain password
+	plain := HashedPassword{Algorithm: PasswordAlgorithmPlain, Hash: Password}
+	if !hasher.Verify(plain, Password) || !hasher.NeedsRehash(plain) {
+		t.Errorf("plain password should be verified and need rehash")
+	}
+	if hasher.Verify(HashedPassword{}, "") {
+		t.Errorf("empty password is verified")
+	}
+	if hasher.Verify(HashedPassword{Algorithm: "unknown", Hash: Password}, Password) {
+		t.Errorf("unknown algorithm is verified")
+	}
+}
+
+func TestPasswordHasherVerifyDummy(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordPolicy{MinLength: 8}, MinPasswordHashCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"", "password", "dummy password"} {
+		if hasher.VerifyDummy(password) {
+			t.Errorf("dummy password is verified with %q", password)
+		}
+	}
+	// the dummy is hashed by same cost as the users' ones,
+	// so that the time to verify is same as them.
+	if hasher.dummy.Algorithm != PasswordAlgorithmBcrypt || hasher.dummy.Cost != MinPasswordHashCost {
+		t.Errorf("different algorithm or cost of the dummy, got: %v", hasher.dummy)
+	}
+}
@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// MaxRoomPins is the maximum number of the pinned messages in the room.
+const MaxRoomPins = 50
+
+// Pin is the message pinned to the room.
+type Pin struct {
+	MessageID uint64
+	PinnedBy  uint64
+	PinnedAt  time.Time
+}
+
+// PinSet is a set for the pinned messages in the room.
+// empty value is valid for use.
+type PinSet struct {
+	set map[uint64]Pin
+}
+
+func NewPinSet(pins ...Pin) PinSet {
+	set := make(map[uint64]Pin, len(pins))
+	for _, p := range pins {
+		set[p.MessageID] = p
+	}
+	return PinSet{
+		set: set,
+	}
+}
+
+func (set *PinSet) getMap() map[uint64]Pin {
+	if set.set == nil {
+		set.set = make(map[uint64]Pin, 4)
+	}
+	return set.set
+}
+
+// Has returns whether the message is pinned.
+func (set *PinSet) Has(msgID uint64) bool {
+	_, ok := set.getMap()[msgID]
+	return ok
+}
+
+// Add adds the pin into the set.
+func (set *PinSet) Add(p Pin) {
+	set.getMap()[p.MessageID] = p
+}
+
+// Remove removes the pin for the message from the set.
+func (set *PinSet) Remove(msgID uint64) {
+	delete(set.getMap(), msgID)
+}
+
+// Len returns the number of the pins.
+func (set *PinSet) Len() int {
+	return len(set.getMap())
+}
+
+// List returns the pins ordered by latest pinned at.
+func (set *PinSet) List() []Pin {
+	m := set.getMap()
+	pins := make([]Pin, 0, len(m))
+	for _, p := range m {
+		pins = append(pins, p)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		if pins[i].PinnedAt.Equal(pins[j].PinnedAt) {
+			return pins[i].MessageID > pins[j].MessageID
+		}
+		return pins[i].PinnedAt.After(pins[j].PinnedAt)
+	})
+	return pins
+}
+
+// validatePinner returns error when the commander
+// can not pin or unpin the message in the room.
+func (r *Room) validatePinner(commander *User, m Message, operation string) error {
+	if r.NotExist() {
+		return fmt.Errorf("newly room can not %s the message", operation)
+	}
+	if commander.NotExist() {
+		return fmt.Errorf("the user not in the datastore, can not %s the message", operation)
+	}
+	if m.NotExist() {
+		return fmt.Errorf("the message not in the datastore, can not %s it", operation)
+	}
+	if m.RoomID != r.ID {
+		return fmt.Errorf("the message(id=%d) is not in the room(id=%d)", m.ID, r.ID)
+	}
+	if !r.RoleOf(commander.ID).CanPin() {
+		return NewPermissionError("user(id=%d) is not permitted to pin the messages in the room(id=%d)", commander.ID, r.ID)
+	}
+	return nil
+}
+
+// PinMessage pins the message to the room by the commander,
+// who must be the owner or an admin of the room.
+// The room can have at most MaxRoomPins messages.
+//
+// It returns MessagePinned event and error if any.
+func (r *Room) PinMessage(commander *User, m Message) (event.MessagePinned, error) {
+	if err := r.validatePinner(commander, m, "pin"); err != nil {
+		return event.MessagePinned{}, err
+	}
+	if m.Deleted {
+		return event.MessagePinned{}, NewValidationError("the message(id=%d) is already deleted, can not be pinned", m.ID)
+	}
+	if r.Pins.Has(m.ID) {
+		return event.MessagePinned{}, NewValidationError("the message(id=%d) is already pinned", m.ID)
+	}
+	if r.Pins.Len() >= MaxRoomPins {
+		return event.MessagePinned{}, NewValidationError("the room(id=%d) can not have more than %d pins", r.ID, MaxRoomPins)
+	}
+
+	ev := event.MessagePinned{
+		RoomID:    r.ID,
+		MessageID: m.ID,
+		PinnedBy:  commander.ID,
+	}
+	ev.Occurs()
+
+	r.Pins.Add(Pin{
+		MessageID: m.ID,
+		PinnedBy:  commander.ID,
+		PinnedAt:  ev.Timestamp(),
+	})
+	r.AddEvent(ev)
+	return ev, nil
+}
+
+// UnpinMessage unpins the message from the room by the commander,
+// who must be the owner or an admin of the room.
+//
+// It returns MessageUnpinned event and error if any.
+func (r *Room) UnpinMessage(commander *User, m Message) (event.MessageUnpinned, error) {
+	if err := r.validatePinner(commander, m, "unpin"); err != nil {
+		return event.MessageUnpinned{}, err
+	}
+	if !r.Pins.Has(m.ID) {
+		return event.MessageUnpinned{}, NewValidationError("the message(id=%d) is not pinned", m.ID)
+	}
+
+	r.Pins.Remove(m.ID)
+
+	ev := event.MessageUnpinned{
+		RoomID:     r.ID,
+		MessageID:  m.ID,
+		UnpinnedBy: commander.ID,
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+	return ev, nil
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestPinSetList(t *testing.T) {
+	now := time.Now()
+	set := NewPinSet(
+		Pin{MessageID: 1, PinnedAt: now.Add(-time.Second)},
+		Pin{MessageID: 2, PinnedAt: now},
+		Pin{MessageID: 3, PinnedAt: now},
+	)
+
+	pins := set.List()
+	if len(pins) != 3 {
+		t.Fatalf("different pins size, expect: 3, got: %v", len(pins))
+	}
+	for i, expectID := range []uint64{3, 2, 1} {
+		if pins[i].MessageID != expectID {
+			t.Errorf("pins are not ordered by latest, expect: %v, got: %v", expectID, pins[i].MessageID)
+		}
+	}
+
+	set.Remove(2)
+	if set.Has(2) || set.Len() != 2 {
+		t.Errorf("removed pin is remained")
+	}
+}
+
+func TestRoomPinMessage(t *testing.T) {
+	var (
+		owner  = User{ID: 1}
+		admin  = User{ID: 2}
+		member = User{ID: 3}
+	)
+	newRoom := func() Room {
+		r := Room{ID: 1, OwnerID: owner.ID, MemberIDSet: NewUserIDSet(owner.ID, admin.ID, member.ID)}
+		r.MemberRoles.Set(admin.ID, RoomRoleAdmin)
+		return r
+	}
+	m := Message{ID: 1, RoomID: 1, UserID: member.ID}
+
+	// case1: success by the admin
+	r := newRoom()
+	ev, err := r.PinMessage(&admin, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.RoomID != r.ID || ev.MessageID != m.ID || ev.PinnedBy != admin.ID {
+		t.Errorf("MessagePinned has invalid fields, got: %#v", ev)
+	}
+	if !r.Pins.Has(m.ID) {
+		t.Errorf("message is pinned but not found in the room")
+	}
+	if events := r.Events(); len(events) != 1 {
+		t.Errorf("message is pinned but room has no event for that")
+	} else if _, ok := events[0].(event.MessagePinned); !ok {
+		t.Errorf("message is pinned but event is not a MessagePinned, got: %v", events[0])
+	}
+
+	// case2: pinned twice
+	if _, err := r.PinMessage(&owner, m); !IsValidationError(err) {
+		t.Errorf("message is pinned twice, expect ValidationError, got: %v", err)
+	}
+
+	// case3: pinned by not an admin
+	r = newRoom()
+	if _, err := r.PinMessage(&member, m); !IsPermissionError(err) {
+		t.Errorf("message is pinned by not an admin, expect PermissionError, got: %v", err)
+	}
+
+	// case4: deleted message
+	if _, err := r.PinMessage(&owner, Message{ID: 1, RoomID: 1, Deleted: true}); !IsValidationError(err) {
+		t.Errorf("deleted message is pinned, expect ValidationError, got: %v", err)
+	}
+
+	// case5: message in other room
+	if _, err := r.PinMessage(&owner, Message{ID: 1, RoomID: 2}); err == nil {
+		t.Errorf("message in other room is pinned, but no error")
+	}
+
+	// case6: too many pins
+	for i := 0; i < MaxRoomPins; i++ {
+		r.Pins.Add(Pin{MessageID: uint64(i + 100)})
+	}
+	if _, err := r.PinMessage(&owner, m); !IsValidationError(err) {
+		t.Errorf("pins are exceeded the limit, expect ValidationError, got: %v", err)
+	}
+}
+
+func TestRoomUnpinMessage(t *testing.T) {
+	var (
+		owner  = User{ID: 1}
+		member = User{ID: 2}
+	)
+	r := Room{ID: 1, OwnerID: owner.ID, MemberIDSet: NewUserIDSet(owner.ID, member.ID)}
+	m := Message{ID: 1, RoomID: 1, UserID: member.ID}
+	r.Pins.Add(Pin{MessageID: m.ID, PinnedBy: owner.ID})
+
+	// case1: unpinned by not an admin
+	if _, err := r.UnpinMessage(&member, m); !IsPermissionError(err) {
+		t.Errorf("message is unpinned by not an admin, expect PermissionError, got: %v", err)
+	}
+
+	// case2: success
+	ev, err := r.UnpinMessage(&owner, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.RoomID != r.ID || ev.MessageID != m.ID || ev.UnpinnedBy != owner.ID {
+		t.Errorf("MessageUnpinned has invalid fields, got: %#v", ev)
+	}
+	if r.Pins.Has(m.ID) {
+		t.Errorf("message is unpinned but still found in the room")
+	}
+
+	// case3: not pinned
+	if _, err := r.UnpinMessage(&owner, m); !IsValidationError(err) {
+		t.Errorf("not pinned message is unpinned, expect ValidationError, got: %v", err)
+	}
+}
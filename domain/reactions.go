@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// maximum length of the reaction emoji in characters.
+// It allows the short code, such as ":thumbsup:", as well as
+// the emoji sequence.
+const maxReactionEmojiLength = 32
+
+// ReactionSet is a set for the reactions to the message.
+// Each user can react to the message once per emoji.
+// empty value is valid for use.
+type ReactionSet struct {
+	set map[string]UserIDSet
+}
+
+func NewReactionSet() ReactionSet {
+	return ReactionSet{
+		set: make(map[string]UserIDSet, 4),
+	}
+}
+
+func (set *ReactionSet) getMap() map[string]UserIDSet {
+	if set.set == nil {
+		set.set = make(map[string]UserIDSet, 4)
+	}
+	return set.set
+}
+
+// Has returns whether the user reacted with the emoji.
+func (set *ReactionSet) Has(emoji string, userID uint64) bool {
+	users, ok := set.getMap()[emoji]
+	return ok && users.Has(userID)
+}
+
+// Add adds the reaction with the emoji by the user.
+func (set *ReactionSet) Add(emoji string, userID uint64) {
+	users, ok := set.getMap()[emoji]
+	if !ok {
+		users = NewUserIDSet()
+		set.getMap()[emoji] = users
+	}
+	users.Add(userID)
+}
+
+// Remove removes the reaction with the emoji by the user.
+// The emoji having no reactions is also removed.
+func (set *ReactionSet) Remove(emoji string, userID uint64) {
+	users, ok := set.getMap()[emoji]
+	if !ok {
+		return
+	}
+	users.Remove(userID)
+	if len(users.List()) == 0 {
+		delete(set.getMap(), emoji)
+	}
+}
+
+// Emojis returns the emojis which have any reactions,
+// ordered by the emoji.
+func (set *ReactionSet) Emojis() []string {
+	m := set.getMap()
+	emojis := make([]string, 0, len(m))
+	for emoji := range m {
+		emojis = append(emojis, emoji)
+	}
+	sort.Strings(emojis)
+	return emojis
+}
+
+// UserIDs returns the IDs of the users who reacted with the emoji.
+func (set *ReactionSet) UserIDs(emoji string) []uint64 {
+	users := set.getMap()[emoji]
+	return users.List()
+}
+
+// Copy returns a deep copy of the set.
+func (set *ReactionSet) Copy() ReactionSet {
+	copied := NewReactionSet()
+	for emoji, users := range set.getMap() {
+		copied.set[emoji] = NewUserIDSet(users.List()...)
+	}
+	return copied
+}
+
+// validateReactionEmoji returns ValidationError when the emoji
+// can not be used as the reaction.
+func validateReactionEmoji(emoji string) error {
+	if emoji == "" {
+		return NewValidationError("reaction emoji is empty")
+	}
+	if utf8.RuneCountInString(emoji) > maxReactionEmojiLength {
+		return NewValidationError("reaction emoji must be at most %d characters", maxReactionEmojiLength)
+	}
+	if strings.IndexFunc(emoji, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' }) >= 0 {
+		return NewValidationError("reaction emoji must not contain spaces")
+	}
+	return nil
+}
+
+// validateReactor returns error when the user can not
+// react to the message in the room.
+func (m *Message) validateReactor(u *User, r Room, emoji string) error {
+	if m.NotExist() {
+		return errors.New("the message not in the datastore, can not be reacted")
+	}
+	if u.NotExist() {
+		return errors.New("the user not in the datastore, can not react to the message")
+	}
+	if m.RoomID != r.ID {
+		return fmt.Errorf("the message(id=%d) is not in the room(id=%d)", m.ID, r.ID)
+	}
+	if !r.HasMember(*u) {
+		return NewPermissionError("user(id=%d) not a member of the room(id=%d), can not react to the message", u.ID, r.ID)
+	}
+	if m.Deleted {
+		return NewValidationError("the message(id=%d) is already deleted, can not be reacted", m.ID)
+	}
+	return validateReactionEmoji(emoji)
+}
+
+// AddReaction adds the reaction with the emoji by the user, who must be
+// a member of the room. The user can react to the message once per emoji.
+// The reacted message is immediately stored into the repository.
+// After successing that, the message holds ReactionAdded event.
+func (m *Message) AddReaction(ctx context.Context, msgs MessageRepository, u *User, r Room, emoji string) error {
+	if err := m.validateReactor(u, r, emoji); err != nil {
+		return err
+	}
+	if m.Reactions.Has(emoji, u.ID) {
+		return NewValidationError("user(id=%d) already reacted to the message(id=%d) with %s", u.ID, m.ID, emoji)
+	}
+
+	m.Reactions.Add(emoji, u.ID)
+	if _, err := msgs.Store(ctx, *m); err != nil {
+		return err
+	}
+
+	ev := event.ReactionAdded{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		UserID:    u.ID,
+		Emoji:     emoji,
+	}
+	ev.Occurs()
+	m.AddEvent(ev)
+
+	return nil
+}
+
+// RemoveReaction removes the reaction with the emoji by the user.
+// The reacted message is immediately stored into the repository.
+// After successing that, the message holds ReactionRemoved event.
+func (m *Message) RemoveReaction(ctx context.Context, msgs MessageRepository, u *User, r Room, emoji string) error {
+	if err := m.validateReactor(u, r, emoji); err != nil {
+		return err
+	}
+	if !m.Reactions.Has(emoji, u.ID) {
+		return NewValidationError("user(id=%d) does not react to the message(id=%d) with %s", u.ID, m.ID, emoji)
+	}
+
+	m.Reactions.Remove(emoji, u.ID)
+	if _, err := msgs.Store(ctx, *m); err != nil {
+		return err
+	}
+
+	ev := event.ReactionRemoved{
+		MessageID: m.ID,
+		RoomID:    m.RoomID,
+		UserID:    u.ID,
+		Emoji:     emoji,
+	}
+	ev.Occurs()
+	m.AddEvent(ev)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestReactionSet(t *testing.T) {
+	var set ReactionSet // zero value is valid.
+	set.Add(":+1:", 1)
+	set.Add(":+1:", 2)
+	set.Add(":smile:", 1)
+
+	if !set.Has(":+1:", 1) || !set.Has(":+1:", 2) || !set.Has(":smile:", 1) {
+		t.Errorf("added reactions are not found")
+	}
+	if set.Has(":smile:", 2) {
+		t.Errorf("not added reaction is found")
+	}
+	if got := set.Emojis(); len(got) != 2 || got[0] != ":+1:" || got[1] != ":smile:" {
+		t.Errorf("different emojis, got: %v", got)
+	}
+
+	copied := set.Copy()
+	copied.Remove(":smile:", 1)
+	if !set.Has(":smile:", 1) {
+		t.Errorf("removing from the copy affects the original")
+	}
+	if got := copied.Emojis(); len(got) != 1 || got[0] != ":+1:" {
+		t.Errorf("emoji having no reactions should be removed, got: %v", got)
+	}
+}
+
+func TestMessageAddReaction(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		member = User{ID: 1}
+		other  = User{ID: 2}
+		room   = Room{ID: 1}
+	)
+	room.MemberIDSet.Add(member.ID)
+
+	// case1: success
+	m := Message{ID: 1, RoomID: room.ID, UserID: member.ID}
+	if err := m.AddReaction(ctx, msgRepo, &member, room, ":+1:"); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Reactions.Has(":+1:", member.ID) {
+		t.Errorf("reaction is added but not found in the message")
+	}
+	events := m.Events()
+	if len(events) != 1 {
+		t.Fatalf("reaction is added but message has no event for that")
+	}
+	ev, ok := events[0].(event.ReactionAdded)
+	if !ok {
+		t.Fatalf("reaction is added but event is not a ReactionAdded, got: %v", events[0])
+	}
+	if ev.MessageID != m.ID || ev.RoomID != room.ID || ev.UserID != member.ID || ev.Emoji != ":+1:" {
+		t.Errorf("ReactionAdded has invalid fields, got: %#v", ev)
+	}
+
+	// case2: react twice with same emoji
+	if err := m.AddReaction(ctx, msgRepo, &member, room, ":+1:"); !IsValidationError(err) {
+		t.Errorf("reacted twice with same emoji, expect ValidationError, got: %v", err)
+	}
+
+	// case3: react by not a member
+	if err := m.AddReaction(ctx, msgRepo, &other, room, ":+1:"); !IsPermissionError(err) {
+		t.Errorf("reacted by not a member, expect PermissionError, got: %v", err)
+	}
+
+	// case4: react to deleted message
+	m = Message{ID: 1, RoomID: room.ID, UserID: member.ID, Deleted: true}
+	if err := m.AddReaction(ctx, msgRepo, &member, room, ":+1:"); !IsValidationError(err) {
+		t.Errorf("reacted to deleted message, expect ValidationError, got: %v", err)
+	}
+
+	// case5: invalid emojis
+	m = Message{ID: 1, RoomID: room.ID, UserID: member.ID}
+	for _, emoji := range []string{"", "a b", strings.Repeat("a", maxReactionEmojiLength+1)} {
+		if err := m.AddReaction(ctx, msgRepo, &member, room, emoji); !IsValidationError(err) {
+			t.Errorf("reacted with invalid emoji %q, expect ValidationError, got: %v", emoji, err)
+		}
+	}
+
+	// case6: message in other room
+	m = Message{ID: 1, RoomID: room.ID + 1, UserID: member.ID}
+	if err := m.AddReaction(ctx, msgRepo, &member, room, ":+1:"); err == nil {
+		t.Errorf("reacted to the message in other room, but no error")
+	}
+}
+
+func TestMessageRemoveReaction(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		member = User{ID: 1}
+		room   = Room{ID: 1}
+	)
+	room.MemberIDSet.Add(member.ID)
+
+	// case1: success
+	m := Message{ID: 1, RoomID: room.ID, UserID: member.ID}
+	m.Reactions.Add(":+1:", member.ID)
+	if err := m.RemoveReaction(ctx, msgRepo, &member, room, ":+1:"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Reactions.Has(":+1:", member.ID) {
+		t.Errorf("reaction is removed but still found in the message")
+	}
+	events := m.Events()
+	if len(events) != 1 {
+		t.Fatalf("reaction is removed but message has no event for that")
+	}
+	ev, ok := events[0].(event.ReactionRemoved)
+	if !ok {
+		t.Fatalf("reaction is removed but event is not a ReactionRemoved, got: %v", events[0])
+	}
+	if ev.MessageID != m.ID || ev.RoomID != room.ID || ev.UserID != member.ID || ev.Emoji != ":+1:" {
+		t.Errorf("ReactionRemoved has invalid fields, got: %#v", ev)
+	}
+
+	// case2: remove not reacted emoji
+	if err := m.RemoveReaction(ctx, msgRepo, &member, room, ":+1:"); !IsValidationError(err) {
+		t.Errorf("removed not reacted emoji, expect ValidationError, got: %v", err)
+	}
+}
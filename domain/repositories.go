@@ -10,18 +10,21 @@ type Repositories interface {
 	Users() UserRepository
 	Messages() MessageRepository
 	Rooms() RoomRepository
+	Attachments() AttachmentRepository
 
 	Events() event.EventRepository
 }
 
 // SimpleRepositories implementes Repositories interface.
 // It acts just returning its fields when interface
-// methods, Users(), Messages() and Rooms(), are called.
+// methods, Users(), Messages(), Rooms() and Attachments(), are called.
 type SimpleRepositories struct {
 	UserRepository    UserRepository
 	MessageRepository MessageRepository
 	RoomRepository    RoomRepository
 
+	AttachmentRepository AttachmentRepository
+
 	EventRepository event.EventRepository
 }
 
@@ -37,6 +40,10 @@ func (s SimpleRepositories) Rooms() RoomRepository {
 	return s.RoomRepository
 }
 
+func (s SimpleRepositories) Attachments() AttachmentRepository {
+	return s.AttachmentRepository
+}
+
 func (s SimpleRepositories) Events() event.EventRepository {
 	return s.EventRepository
 }
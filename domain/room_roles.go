@@ -0,0 +1,114 @@
+package domain
+
+import "fmt"
+
+// RoomRole is a role of the room member, which
+// determines what the member can do in the room.
+type RoomRole string
+
+const (
+	// The owner can do everything for the room.
+	// Only one member, Room.OwnerID, has this role.
+	RoomRoleOwner RoomRole = "owner"
+
+	// The admin can manage the room members except the owner and
+	// other admins, and can rename the room and pin the messages.
+	RoomRoleAdmin RoomRole = "admin"
+
+	// The member can post messages to the room.
+	// It is default role for the new member.
+	RoomRoleMember RoomRole = "member"
+
+	// The read-only member can only read messages in the room.
+	RoomRoleReadOnly RoomRole = "read_only"
+)
+
+// ParseRoomRole returns the RoomRole corresponding to the string s.
+// It returns error if s is not a valid role.
+func ParseRoomRole(s string) (RoomRole, error) {
+	switch r := RoomRole(s); r {
+	case RoomRoleOwner, RoomRoleAdmin, RoomRoleMember, RoomRoleReadOnly:
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid room role: %v", s)
+}
+
+// rank returns a rank of the role to compare with other roles.
+// the higher rank has the more permissions.
+func (role RoomRole) rank() int {
+	switch role {
+	case RoomRoleOwner:
+		return 3
+	case RoomRoleAdmin:
+		return 2
+	case RoomRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// CanManageMembers returns true when the role can add, remove and
+// change the role of the room members.
+func (role RoomRole) CanManageMembers() bool {
+	return role.rank() >= RoomRoleAdmin.rank()
+}
+
+// CanRename returns true when the role can rename the room.
+func (role RoomRole) CanRename() bool {
+	return role.rank() >= RoomRoleAdmin.rank()
+}
+
+// CanPin returns true when the role can pin and unpin the messages.
+func (role RoomRole) CanPin() bool {
+	return role.rank() >= RoomRoleAdmin.rank()
+}
+
+// CanPost returns true when the role can post messages to the room.
+func (role RoomRole) CanPost() bool {
+	return role.rank() >= RoomRoleMember.rank()
+}
+
+// IsHigherThan returns true when the role has more permissions than other.
+func (role RoomRole) IsHigherThan(other RoomRole) bool {
+	return role.rank() > other.rank()
+}
+
+// RoleSet is a set for the RoomRole of the room members.
+// The member not in the set has RoomRoleMember.
+// empty value is valid for use.
+type RoleSet struct {
+	set map[uint64]RoomRole
+}
+
+func NewRoleSet() RoleSet {
+	return RoleSet{
+		set: make(map[uint64]RoomRole, 4),
+	}
+}
+
+func (set *RoleSet) getMap() map[uint64]RoomRole {
+	if set.set == nil {
+		set.set = make(map[uint64]RoomRole, 4)
+	}
+	return set.set
+}
+
+// Get returns RoomRole corresponding to the id.
+// It returns RoomRoleMember if the id is not set.
+func (set *RoleSet) Get(id uint64) RoomRole {
+	if role, ok := set.getMap()[id]; ok {
+		return role
+	}
+	return RoomRoleMember
+}
+
+// Set sets RoomRole with corresponding id to the internal map.
+func (set *RoleSet) Set(id uint64, role RoomRole) {
+	set.getMap()[id] = role
+}
+
+// Delete deletes id from the internal map.
+func (set *RoleSet) Delete(id uint64) {
+	delete(set.getMap(), id)
+}
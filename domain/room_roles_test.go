@@ -0,0 +1,66 @@
+package domain
+
+import "testing"
+
+func TestParseRoomRole(t *testing.T) {
+	for _, s := range []string{"owner", "admin", "member", "read_only"} {
+		role, err := ParseRoomRole(s)
+		if err != nil {
+			t.Errorf("can not parse valid role %v: %v", s, err)
+		}
+		if string(role) != s {
+			t.Errorf("different parsed role, expect: %v, got: %v", s, role)
+		}
+	}
+	if _, err := ParseRoomRole("invalid"); err == nil {
+		t.Error("parse invalid role, but no error")
+	}
+}
+
+func TestRoomRolePermissions(t *testing.T) {
+	for _, c := range []struct {
+		Role          RoomRole
+		ManageMembers bool
+		Rename        bool
+		Pin           bool
+		Post          bool
+	}{
+		{RoomRoleOwner, true, true, true, true},
+		{RoomRoleAdmin, true, true, true, true},
+		{RoomRoleMember, false, false, false, true},
+		{RoomRoleReadOnly, false, false, false, false},
+		{RoomRole(""), false, false, false, false},
+	} {
+		if got := c.Role.CanManageMembers(); got != c.ManageMembers {
+			t.Errorf("%v: different CanManageMembers, expect: %v, got: %v", c.Role, c.ManageMembers, got)
+		}
+		if got := c.Role.CanRename(); got != c.Rename {
+			t.Errorf("%v: different CanRename, expect: %v, got: %v", c.Role, c.Rename, got)
+		}
+		if got := c.Role.CanPin(); got != c.Pin {
+			t.Errorf("%v: different CanPin, expect: %v, got: %v", c.Role, c.Pin, got)
+		}
+		if got := c.Role.CanPost(); got != c.Post {
+			t.Errorf("%v: different CanPost, expect: %v, got: %v", c.Role, c.Post, got)
+		}
+	}
+
+	if !RoomRoleOwner.IsHigherThan(RoomRoleAdmin) || RoomRoleAdmin.IsHigherThan(RoomRoleAdmin) {
+		t.Error("invalid order of the roles")
+	}
+}
+
+func TestRoleSet(t *testing.T) {
+	var set RoleSet // empty value is valid.
+	if got := set.Get(1); got != RoomRoleMember {
+		t.Errorf("default role is not member, got: %v", got)
+	}
+	set.Set(1, RoomRoleAdmin)
+	if got := set.Get(1); got != RoomRoleAdmin {
+		t.Errorf("different role, expect: %v, got: %v", RoomRoleAdmin, got)
+	}
+	set.Delete(1)
+	if got := set.Get(1); got != RoomRoleMember {
+		t.Errorf("deleted role still remains, got: %v", got)
+	}
+}
@@ -40,6 +40,10 @@ type Room struct {
 	Name       string
 	IsTalkRoom bool
 
+	// archived room has no member since all of the members left,
+	// and can not be modified any more.
+	IsArchived bool
+
 	CreatedAt time.Time
 
 	OwnerID     uint64
@@ -47,6 +51,18 @@ type Room struct {
 
 	// key: userID, value: ReadTime
 	MemberReadTimes TimeSet
+
+	// key: userID, value: the time the user joined the room.
+	// zero time means the user joined before the time is recorded.
+	MemberJoinedAt TimeSet
+
+	// key: userID, value: RoomRole.
+	// The owner's role is determined by OwnerID
+	// rather than this set.
+	MemberRoles RoleSet
+
+	// key: messageID, value: Pin.
+	Pins PinSet
 }
 
 // TimeSet is a set for the time.Time.
@@ -103,8 +119,10 @@ func NewRoom(ctx context.Context, roomRepo RoomRepository, name string, user *Us
 
 	now := time.Now()
 	timeSet := NewTimeSet()
+	joinedAt := NewTimeSet()
 	for id, _ := range memberIDs.idMap {
 		timeSet.Set(id, now)
+		joinedAt.Set(id, now)
 	}
 
 	r := &Room{
@@ -116,6 +134,8 @@ func NewRoom(ctx context.Context, roomRepo RoomRepository, name string, user *Us
 		OwnerID:         user.ID,
 		MemberIDSet:     memberIDs,
 		MemberReadTimes: timeSet,
+		MemberJoinedAt:  joinedAt,
+		MemberRoles:     NewRoleSet(),
 	}
 	id, err := roomRepo.Store(ctx, *r)
 	if err != nil {
@@ -137,6 +157,113 @@ func NewRoom(ctx context.Context, roomRepo RoomRepository, name string, user *Us
 	return r, nil
 }
 
+// create new Room entity with the members into the repository.
+// It returns PermissionError when any of the members blocks the user,
+// otherwise it is same as NewRoom.
+func NewRoomWithMembers(ctx context.Context, roomRepo RoomRepository, name string, user *User, members []User) (*Room, error) {
+	memberIDs := NewUserIDSet()
+	for _, m := range members {
+		if m.NotExist() {
+			return nil, fmt.Errorf("the user not in the datastore, can not be a room member")
+		}
+		if m.ID == user.ID {
+			continue
+		}
+		if err := validateNotBlockedBy(user, m, "add room member"); err != nil {
+			return nil, err
+		}
+		memberIDs.Add(m.ID)
+	}
+	return NewRoom(ctx, roomRepo, name, user, memberIDs)
+}
+
+// FindTalkRoom finds the talk room between the user and the friend
+// from the repository. It returns the found room and true, or
+// returns false if the talk room does not exist.
+func FindTalkRoom(ctx context.Context, roomRepo RoomRepository, user *User, friend User) (Room, bool, error) {
+	if user.NotExist() || friend.NotExist() {
+		return Room{}, false, fmt.Errorf("the user not in the datastore, can not find talk room")
+	}
+	if err := validateNotBlockedBy(user, friend, "talk with"); err != nil {
+		return Room{}, false, err
+	}
+	rooms, err := roomRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return Room{}, false, err
+	}
+	for _, r := range rooms {
+		if r.IsTalkRoom && r.HasMember(friend) {
+			return r, true, nil
+		}
+	}
+	return Room{}, false, nil
+}
+
+// create new talk room, which is 1:1 room between the user and its friend,
+// into the repository. The members of the talk room are fixed,
+// and the room can not be deleted by one side.
+// Caller should check the talk room does not exist yet by FindTalkRoom.
+// It retruns room holding RoomCreated event and error if any.
+func NewTalkRoom(ctx context.Context, roomRepo RoomRepository, user *User, friend User) (*Room, error) {
+	if user.NotExist() || friend.NotExist() {
+		return nil, fmt.Errorf("the user not in the datastore, can not create talk room")
+	}
+	if user.ID == friend.ID {
+		return nil, fmt.Errorf("can not create talk room with user itself")
+	}
+	if err := validateNotBlockedBy(user, friend, "create talk room with"); err != nil {
+		return nil, err
+	}
+	if !user.HasFriend(friend) {
+		return nil, NewPermissionError("user(id=%d) is not a friend of user(id=%d), can not create talk room", friend.ID, user.ID)
+	}
+
+	now := time.Now()
+	r := &Room{
+		EventHolder:     NewEventHolder(),
+		ID:              0, // 0 means new entity
+		Name:            "",
+		IsTalkRoom:      true,
+		CreatedAt:       now,
+		OwnerID:         user.ID,
+		MemberIDSet:     NewUserIDSet(user.ID, friend.ID),
+		MemberReadTimes: NewTimeSet(),
+		MemberJoinedAt:  NewTimeSet(),
+		MemberRoles:     NewRoleSet(),
+	}
+	r.MemberReadTimes.Set(user.ID, now)
+	r.MemberReadTimes.Set(friend.ID, now)
+	r.MemberJoinedAt.Set(user.ID, now)
+	r.MemberJoinedAt.Set(friend.ID, now)
+
+	id, err := roomRepo.Store(ctx, *r)
+	if err != nil {
+		return nil, err
+	}
+	r.ID = id
+
+	ev := event.RoomCreated{
+		CreatedBy:  user.ID,
+		RoomID:     id,
+		Name:       r.Name,
+		IsTalkRoom: true,
+		MemberIDs:  r.MemberIDs(),
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+
+	return r, nil
+}
+
+// validateNotTalkRoom returns PermissionError when the room is
+// a talk room, whose members and settings are fixed.
+func (r *Room) validateNotTalkRoom(operation string) error {
+	if r.IsTalkRoom {
+		return NewPermissionError("talk room(id=%d) can not be %s", r.ID, operation)
+	}
+	return nil
+}
+
 // It deletes the room from repository.
 // After successing that, the room holds RoomDeleted event.
 func (r *Room) Delete(ctx context.Context, repo RoomRepository, user *User) error {
@@ -146,10 +273,18 @@ func (r *Room) Delete(ctx context.Context, repo RoomRepository, user *User) erro
 	if user.NotExist() {
 		return fmt.Errorf("the user not in the datastore, can not delete the room")
 	}
+	if err := r.validateNotTalkRoom("deleted by one side"); err != nil {
+		return err
+	}
 	if r.OwnerID != user.ID {
-		return fmt.Errorf("the user is not the owner of the room, can not delete the room")
+		return NewPermissionError("the user is not the owner of the room, can not delete the room")
 	}
+	return r.delete(ctx, repo, user)
+}
 
+// delete removes the room from repository without checking
+// the permission of the user.
+func (r *Room) delete(ctx context.Context, repo RoomRepository, user *User) error {
 	err := repo.Remove(ctx, *r)
 	if err != nil {
 		return err
@@ -181,28 +316,68 @@ func (r *Room) MemberIDs() []uint64 {
 	return r.MemberIDSet.List()
 }
 
-// It adds the member to the room.
+// RoleOf returns the role of the user in the room.
+// It returns empty role, which has no permission,
+// if the user is not a member of the room.
+func (r *Room) RoleOf(userID uint64) RoomRole {
+	if !r.MemberIDSet.Has(userID) {
+		return ""
+	}
+	if r.OwnerID == userID {
+		return RoomRoleOwner
+	}
+	return r.MemberRoles.Get(userID)
+}
+
+// validateMemberManager returns PermissionError when the commander
+// can not manage the room members.
+func (r *Room) validateMemberManager(commander *User) error {
+	if commander.NotExist() {
+		return fmt.Errorf("the user not in the datastore, can not manage the room members")
+	}
+	if !r.RoleOf(commander.ID).CanManageMembers() {
+		return NewPermissionError("user(id=%d) is not permitted to manage the members of the room(id=%d)", commander.ID, r.ID)
+	}
+	return nil
+}
+
+// It adds the member to the room by the commander, who must be
+// the owner or an admin of the room.
 // It returns the event adding to the room, and error
 // when the user already exist in the room.
-func (r *Room) AddMember(user User) (event.RoomAddedMember, error) {
+func (r *Room) AddMember(commander *User, user User) (event.RoomAddedMember, error) {
 	if r.NotExist() {
 		return event.RoomAddedMember{}, fmt.Errorf("newly room can not be added new member")
 	}
+	if r.IsArchived {
+		return event.RoomAddedMember{}, fmt.Errorf("archived room(id=%d) can not be added new member", r.ID)
+	}
+	if err := r.validateNotTalkRoom("added new member"); err != nil {
+		return event.RoomAddedMember{}, err
+	}
 	if user.NotExist() {
 		return event.RoomAddedMember{}, fmt.Errorf("the user not in the datastore, can not be a room member")
 	}
+	if err := r.validateMemberManager(commander); err != nil {
+		return event.RoomAddedMember{}, err
+	}
 	if r.HasMember(user) {
 		return event.RoomAddedMember{}, fmt.Errorf("user(id=%d) is already member of the room(id=%d)", user.ID, r.ID)
 	}
+	if err := validateNotBlockedBy(commander, user, "add room member"); err != nil {
+		return event.RoomAddedMember{}, err
+	}
 
 	r.MemberIDSet.Add(user.ID)
 	r.MemberReadTimes.Set(user.ID, r.CreatedAt)
+	r.MemberRoles.Delete(user.ID) // new member has default role.
 
 	ev := event.RoomAddedMember{
 		RoomID:      r.ID,
 		AddedUserID: user.ID,
 	}
 	ev.Occurs()
+	r.MemberJoinedAt.Set(user.ID, ev.Timestamp())
 	r.AddEvent(ev)
 	return ev, nil
 }
@@ -213,25 +388,52 @@ func (r *Room) HasMember(member User) bool {
 	return r.MemberIDSet.Has(member.ID)
 }
 
-// RoomRemovedMember removes the member from the room.
+// It returns the IDs of the room members who had already joined
+// the room at the time t. The member joined at unknown time is
+// regarded as joined before t.
+func (r *Room) MemberIDsJoinedBy(t time.Time) []uint64 {
+	memberIDs := r.MemberIDSet.List()
+	ids := make([]uint64, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if joinedAt, _ := r.MemberJoinedAt.Get(id); !joinedAt.After(t) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// RoomRemovedMember removes the member from the room by the commander,
+// who must be the owner or an admin of the room and must have
+// higher role than the removed member.
 // It returns the event the room member is removed or
 // returns error when the user already does not exist in the room.
-func (r *Room) RemoveMember(user User) (event.RoomRemovedMember, error) {
+func (r *Room) RemoveMember(commander *User, user User) (event.RoomRemovedMember, error) {
 	if r.NotExist() {
 		return event.RoomRemovedMember{}, fmt.Errorf("newly room can not remove a member")
 	}
 	if user.NotExist() {
 		return event.RoomRemovedMember{}, fmt.Errorf("the user not in the datastore, can not be removed from the room")
 	}
+	if err := r.validateNotTalkRoom("removed a member"); err != nil {
+		return event.RoomRemovedMember{}, err
+	}
+	if err := r.validateMemberManager(commander); err != nil {
+		return event.RoomRemovedMember{}, err
+	}
 	if !r.HasMember(user) {
 		return event.RoomRemovedMember{}, fmt.Errorf("user(id=%d) is not a member of the room(id=%d)", user.ID, r.ID)
 	}
 	if r.OwnerID == user.ID {
-		return event.RoomRemovedMember{}, fmt.Errorf("the room owner(id=%d) can not removed from the room(id=%d)", r.OwnerID, r.ID)
+		return event.RoomRemovedMember{}, NewPermissionError("the room owner(id=%d) can not removed from the room(id=%d)", r.OwnerID, r.ID)
+	}
+	if !r.RoleOf(commander.ID).IsHigherThan(r.RoleOf(user.ID)) {
+		return event.RoomRemovedMember{}, NewPermissionError("user(id=%d) is not permitted to remove the member(id=%d) of the room(id=%d)", commander.ID, user.ID, r.ID)
 	}
 
 	r.MemberIDSet.Remove(user.ID)
 	r.MemberReadTimes.Delete(user.ID)
+	r.MemberJoinedAt.Delete(user.ID)
+	r.MemberRoles.Delete(user.ID)
 
 	ev := event.RoomRemovedMember{
 		RoomID:        r.ID,
@@ -242,6 +444,239 @@ func (r *Room) RemoveMember(user User) (event.RoomRemovedMember, error) {
 	return ev, nil
 }
 
+// TransferOwnership transfers the ownership of the room from
+// the commander, who must be the owner of the room, to the other member.
+// The old owner becomes an admin of the room after transferring.
+//
+// It returns RoomOwnershipTransferred event and error if any.
+func (r *Room) TransferOwnership(commander *User, newOwner User) (event.RoomOwnershipTransferred, error) {
+	if r.NotExist() {
+		return event.RoomOwnershipTransferred{}, errors.New("newly room can not transfer the ownership")
+	}
+	if commander.NotExist() || newOwner.NotExist() {
+		return event.RoomOwnershipTransferred{}, errors.New("the user not in the datastore, can not transfer the ownership")
+	}
+	if err := r.validateNotTalkRoom("transferred the ownership"); err != nil {
+		return event.RoomOwnershipTransferred{}, err
+	}
+	if r.OwnerID != commander.ID {
+		return event.RoomOwnershipTransferred{}, NewPermissionError("user(id=%d) is not the owner of the room(id=%d), can not transfer the ownership", commander.ID, r.ID)
+	}
+	if !r.HasMember(newOwner) {
+		return event.RoomOwnershipTransferred{}, fmt.Errorf("user(id=%d) is not a member of the room(id=%d)", newOwner.ID, r.ID)
+	}
+	if newOwner.ID == commander.ID {
+		return event.RoomOwnershipTransferred{}, fmt.Errorf("user(id=%d) is already the owner of the room(id=%d)", newOwner.ID, r.ID)
+	}
+
+	return r.transferOwnership(newOwner.ID), nil
+}
+
+func (r *Room) transferOwnership(newOwnerID uint64) event.RoomOwnershipTransferred {
+	oldOwnerID := r.OwnerID
+	r.OwnerID = newOwnerID
+	r.MemberRoles.Delete(newOwnerID)
+	r.MemberRoles.Set(oldOwnerID, RoomRoleAdmin)
+
+	ev := event.RoomOwnershipTransferred{
+		RoomID:     r.ID,
+		OldOwnerID: oldOwnerID,
+		NewOwnerID: newOwnerID,
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+	return ev
+}
+
+// nextOwnerID returns the member ID who succeeds the ownership
+// when the owner leaves the room. The member having the highest role
+// is chosen, and the smallest ID is chosen for the same role.
+// It returns false if no other member exists.
+func (r *Room) nextOwnerID() (uint64, bool) {
+	var (
+		nextID   uint64
+		nextRole RoomRole
+		found    bool
+	)
+	for _, id := range r.MemberIDs() {
+		if id == r.OwnerID {
+			continue
+		}
+		role := r.MemberRoles.Get(id)
+		if !found || role.IsHigherThan(nextRole) || (role == nextRole && id < nextID) {
+			nextID, nextRole, found = id, role, true
+		}
+	}
+	return nextID, found
+}
+
+// Leave removes the user from the room by itself.
+// When the owner leaves the room, the ownership is transferred to
+// the other member who has the highest role, or the room is archived
+// if no other member remains.
+//
+// It returns RoomMemberLeft event and error if any.
+// The room also holds RoomOwnershipTransferred or RoomArchived event
+// when the owner leaves.
+func (r *Room) Leave(user *User) (event.RoomMemberLeft, error) {
+	if r.NotExist() {
+		return event.RoomMemberLeft{}, errors.New("newly room can not be left")
+	}
+	if user.NotExist() {
+		return event.RoomMemberLeft{}, errors.New("the user not in the datastore, can not leave the room")
+	}
+	if !r.HasMember(*user) {
+		return event.RoomMemberLeft{}, fmt.Errorf("user(id=%d) is not a member of the room(id=%d)", user.ID, r.ID)
+	}
+	if err := r.validateNotTalkRoom("left"); err != nil {
+		return event.RoomMemberLeft{}, err
+	}
+	return r.leave(user), nil
+}
+
+// leave removes the member from the room without checking
+// whether the room is a talk room.
+func (r *Room) leave(user *User) event.RoomMemberLeft {
+	archived := false
+	if r.OwnerID == user.ID {
+		if nextID, ok := r.nextOwnerID(); ok {
+			r.transferOwnership(nextID)
+		} else {
+			archived = true
+		}
+	}
+
+	r.MemberIDSet.Remove(user.ID)
+	r.MemberReadTimes.Delete(user.ID)
+	r.MemberJoinedAt.Delete(user.ID)
+	r.MemberRoles.Delete(user.ID)
+
+	ev := event.RoomMemberLeft{
+		RoomID: r.ID,
+		UserID: user.ID,
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+
+	if archived {
+		r.IsArchived = true
+		archivedEv := event.RoomArchived{
+			RoomID:     r.ID,
+			ArchivedBy: user.ID,
+		}
+		archivedEv.Occurs()
+		r.AddEvent(archivedEv)
+	}
+	return ev
+}
+
+// RemoveDeletedUser removes the member whose account is deleted
+// from the room, and stores the result into the repository.
+// The talk room is deleted since it can not remain with one member.
+// Otherwise, the user leaves the room as same as Leave().
+func (r *Room) RemoveDeletedUser(ctx context.Context, repo RoomRepository, user *User) error {
+	if r.NotExist() {
+		return errors.New("newly room can not be removed the deleted user")
+	}
+	if !r.HasMember(*user) {
+		return fmt.Errorf("user(id=%d) is not a member of the room(id=%d)", user.ID, r.ID)
+	}
+
+	if r.IsTalkRoom {
+		return r.delete(ctx, repo, user)
+	}
+
+	r.leave(user)
+	_, err := repo.Store(ctx, *r)
+	return err
+}
+
+// Rename changes the name of the room by the commander,
+// who must be the owner or an admin of the room.
+//
+// It returns RoomRenamed event and error if any.
+func (r *Room) Rename(commander *User, name string) (event.RoomRenamed, error) {
+	if r.NotExist() {
+		return event.RoomRenamed{}, errors.New("newly room can not be renamed")
+	}
+	if commander.NotExist() {
+		return event.RoomRenamed{}, errors.New("the user not in the datastore, can not rename the room")
+	}
+	if err := r.validateNotTalkRoom("renamed"); err != nil {
+		return event.RoomRenamed{}, err
+	}
+	if !r.RoleOf(commander.ID).CanRename() {
+		return event.RoomRenamed{}, NewPermissionError("user(id=%d) is not permitted to rename the room(id=%d)", commander.ID, r.ID)
+	}
+	if len(name) == 0 {
+		return event.RoomRenamed{}, errors.New("the room name must not be empty")
+	}
+
+	oldName := r.Name
+	r.Name = name
+
+	ev := event.RoomRenamed{
+		RoomID:    r.ID,
+		RenamedBy: commander.ID,
+		OldName:   oldName,
+		Name:      name,
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+	return ev, nil
+}
+
+// ChangeMemberRole changes the role of the room member by the commander,
+// who must be the owner or an admin of the room.
+// The commander must have higher role than both of the current and new role
+// of the member. The owner role can not be given by this method.
+//
+// It returns RoomMemberRoleChanged event and error if any.
+func (r *Room) ChangeMemberRole(commander *User, user User, role RoomRole) (event.RoomMemberRoleChanged, error) {
+	if r.NotExist() {
+		return event.RoomMemberRoleChanged{}, errors.New("newly room can not change the member role")
+	}
+	if user.NotExist() {
+		return event.RoomMemberRoleChanged{}, errors.New("the user not in the datastore, can not change the role")
+	}
+	if err := r.validateNotTalkRoom("changed the member role"); err != nil {
+		return event.RoomMemberRoleChanged{}, err
+	}
+	if err := r.validateMemberManager(commander); err != nil {
+		return event.RoomMemberRoleChanged{}, err
+	}
+	if !r.HasMember(user) {
+		return event.RoomMemberRoleChanged{}, fmt.Errorf("user(id=%d) is not a member of the room(id=%d)", user.ID, r.ID)
+	}
+	if _, err := ParseRoomRole(string(role)); err != nil {
+		return event.RoomMemberRoleChanged{}, err
+	}
+	if role == RoomRoleOwner {
+		return event.RoomMemberRoleChanged{}, errors.New("the owner role can not be given by changing the role")
+	}
+
+	commanderRole, oldRole := r.RoleOf(commander.ID), r.RoleOf(user.ID)
+	if !commanderRole.IsHigherThan(oldRole) || !commanderRole.IsHigherThan(role) {
+		return event.RoomMemberRoleChanged{}, NewPermissionError("user(id=%d) is not permitted to change the role of the member(id=%d) to %v", commander.ID, user.ID, role)
+	}
+	if oldRole == role {
+		return event.RoomMemberRoleChanged{}, fmt.Errorf("user(id=%d) already has the role %v", user.ID, role)
+	}
+
+	r.MemberRoles.Set(user.ID, role)
+
+	ev := event.RoomMemberRoleChanged{
+		RoomID:    r.ID,
+		UserID:    user.ID,
+		ChangedBy: commander.ID,
+		OldRole:   string(oldRole),
+		Role:      string(role),
+	}
+	ev.Occurs()
+	r.AddEvent(ev)
+	return ev, nil
+}
+
 // ReadMessagesBy marks that the room messages before time readAt
 // are read by the specified user.
 //
@@ -279,3 +714,59 @@ func (r *Room) ReadMessagesBy(u *User, readAt time.Time) (event.RoomMessagesRead
 	r.AddEvent(ev)
 	return ev, nil
 }
+
+// StartTypingBy notifies that the user starts typing in the room.
+// The typing state is ephemeral, so the returned event is not
+// added to the room and is not stored in the repository.
+//
+// It returns UserTypingStarted event and error if any.
+func (r *Room) StartTypingBy(u *User) (event.UserTypingStarted, error) {
+	if err := r.validateTypingUser(u); err != nil {
+		return event.UserTypingStarted{}, err
+	}
+
+	ev := event.UserTypingStarted{
+		RoomID:     r.ID,
+		SenderID:   u.ID,
+		SenderName: u.Name,
+	}
+	ev.Occurs()
+	ev.StartAt = ev.CreatedAt
+	return ev, nil
+}
+
+// EndTypingBy notifies that the user ends typing in the room.
+// The typing state is ephemeral, so the returned event is not
+// added to the room and is not stored in the repository.
+//
+// It returns UserTypingEnded event and error if any.
+func (r *Room) EndTypingBy(u *User) (event.UserTypingEnded, error) {
+	if err := r.validateTypingUser(u); err != nil {
+		return event.UserTypingEnded{}, err
+	}
+
+	ev := event.UserTypingEnded{
+		RoomID:     r.ID,
+		SenderID:   u.ID,
+		SenderName: u.Name,
+	}
+	ev.Occurs()
+	ev.EndAt = ev.CreatedAt
+	return ev, nil
+}
+
+func (r *Room) validateTypingUser(u *User) error {
+	if r.NotExist() {
+		return errors.New("newly room can not be typed by user")
+	}
+	if u.NotExist() {
+		return errors.New("the user not in the datastore, can not type in the room")
+	}
+	if !r.HasMember(*u) {
+		return fmt.Errorf("user (id=%d) is not a member of the room (id=%d)", u.ID, r.ID)
+	}
+	if !r.RoleOf(u.ID).CanPost() {
+		return NewPermissionError("user (id=%d) is read-only member of the room (id=%d)", u.ID, r.ID)
+	}
+	return nil
+}
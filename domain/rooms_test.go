@@ -3,6 +3,8 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -137,7 +139,7 @@ func TestRoomAddMember(t *testing.T) {
 	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet())
 	r.ID = 1 // it may not be allowed at application side.
 	u := User{ID: 1}
-	ev, err := r.AddMember(u)
+	ev, err := r.AddMember(owner, u)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -157,6 +159,9 @@ func TestRoomAddMember(t *testing.T) {
 	if _, ok := r.MemberReadTimes.Get(u.ID); !ok {
 		t.Errorf("AddMember missed read time of the added member")
 	}
+	if joinedAt, _ := r.MemberJoinedAt.Get(u.ID); !joinedAt.Equal(ev.Timestamp()) {
+		t.Errorf("AddMember has different join time, expect: %v, got: %v", ev.Timestamp(), joinedAt)
+	}
 
 	// room has two events: Created, AddedMember.
 	if got := len(r.Events()); got != 2 {
@@ -175,13 +180,13 @@ func TestRoomRemoveMember(t *testing.T) {
 	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet())
 	r.ID = 1 // it may not be allowed at application side.
 	u := User{ID: 1}
-	_, err := r.AddMember(u)
+	_, err := r.AddMember(owner, u)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// do test function
-	ev, err := r.RemoveMember(u)
+	ev, err := r.RemoveMember(owner, u)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -202,6 +207,9 @@ func TestRoomRemoveMember(t *testing.T) {
 	if _, ok := r.MemberReadTimes.Get(u.ID); ok {
 		t.Errorf("RemoveMember missed removing read time of the removed member")
 	}
+	if _, ok := r.MemberJoinedAt.Get(u.ID); ok {
+		t.Errorf("RemoveMember missed removing join time of the removed member")
+	}
 
 	// room has three events: Created, AddedMember, RemovedMember
 	if got := len(r.Events()); got != 3 {
@@ -216,6 +224,35 @@ func TestRoomRemoveMember(t *testing.T) {
 	}
 }
 
+func TestRoomMemberIDsJoinedBy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	r := Room{
+		MemberIDSet:    NewUserIDSet(1, 2, 3),
+		MemberJoinedAt: NewTimeSet(),
+	}
+	r.MemberJoinedAt.Set(1, now.Add(-time.Hour))
+	r.MemberJoinedAt.Set(2, now.Add(time.Hour))
+	// user 3 joined at unknown time.
+
+	for _, testcase := range []struct {
+		At     time.Time
+		Expect []uint64
+	}{
+		{now.Add(-2 * time.Hour), []uint64{3}},
+		{now.Add(-time.Hour), []uint64{1, 3}},
+		{now, []uint64{1, 3}},
+		{now.Add(time.Hour), []uint64{1, 2, 3}},
+	} {
+		got := r.MemberIDsJoinedBy(testcase.At)
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if !reflect.DeepEqual(testcase.Expect, got) {
+			t.Errorf("different members at %v, expect: %v, got: %v", testcase.At, testcase.Expect, got)
+		}
+	}
+}
+
 func TestRoomRemoveMemberFail(t *testing.T) {
 	t.Parallel()
 
@@ -225,7 +262,7 @@ func TestRoomRemoveMemberFail(t *testing.T) {
 		r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet())
 		r.ID = 1 // it may not be allowed at application side.
 		u := User{ID: 1}
-		_, err := r.RemoveMember(u)
+		_, err := r.RemoveMember(owner, u)
 		if err == nil {
 			t.Error("removed not found user, but no error")
 		}
@@ -241,7 +278,7 @@ func TestRoomRemoveMemberFail(t *testing.T) {
 		r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet())
 		r.ID = 1 // it may not be allowed at application side.
 
-		_, err := r.RemoveMember(*owner)
+		_, err := r.RemoveMember(owner, *owner)
 		if err == nil {
 			t.Error("removed owner self, but no error")
 		}
@@ -252,6 +289,401 @@ func TestRoomRemoveMemberFail(t *testing.T) {
 	}
 }
 
+func TestRoomMemberPermissions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1}
+	admin, member, readOnly := User{ID: 2}, User{ID: 3}, User{ID: 4}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(admin.ID, member.ID, readOnly.ID))
+	r.ID = 1 // it may not be allowed at application side.
+
+	if _, err := r.ChangeMemberRole(owner, admin, RoomRoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ChangeMemberRole(&admin, readOnly, RoomRoleReadOnly); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		User User
+		Role RoomRole
+	}{
+		{*owner, RoomRoleOwner},
+		{admin, RoomRoleAdmin},
+		{member, RoomRoleMember},
+		{readOnly, RoomRoleReadOnly},
+		{User{ID: 5}, ""},
+	} {
+		if got := r.RoleOf(c.User.ID); got != c.Role {
+			t.Errorf("different role for user(%d), expect: %v, got: %v", c.User.ID, c.Role, got)
+		}
+	}
+
+	// member can not manage the members.
+	if _, err := r.AddMember(&member, User{ID: 5}); !IsPermissionError(err) {
+		t.Errorf("member adds new member, expect permission error, got: %v", err)
+	}
+	if _, err := r.RemoveMember(&member, readOnly); !IsPermissionError(err) {
+		t.Errorf("member removes other member, expect permission error, got: %v", err)
+	}
+
+	// admin can not remove or change other admins.
+	if _, err := r.ChangeMemberRole(owner, member, RoomRoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RemoveMember(&admin, member); !IsPermissionError(err) {
+		t.Errorf("admin removes other admin, expect permission error, got: %v", err)
+	}
+	if _, err := r.ChangeMemberRole(&admin, member, RoomRoleMember); !IsPermissionError(err) {
+		t.Errorf("admin changes other admin's role, expect permission error, got: %v", err)
+	}
+	if _, err := r.ChangeMemberRole(&admin, readOnly, RoomRoleAdmin); !IsPermissionError(err) {
+		t.Errorf("admin grants admin role, expect permission error, got: %v", err)
+	}
+	if _, err := r.ChangeMemberRole(owner, admin, RoomRoleOwner); err == nil {
+		t.Error("owner role is given by changing the role, but no error")
+	}
+
+	// admin can manage lower members.
+	if _, err := r.RemoveMember(&admin, readOnly); err != nil {
+		t.Errorf("admin can not remove read-only member: %v", err)
+	}
+	if _, err := r.AddMember(&admin, readOnly); err != nil {
+		t.Errorf("admin can not add new member: %v", err)
+	}
+	if got := r.RoleOf(readOnly.ID); got != RoomRoleMember {
+		t.Errorf("re-added member has old role: %v", got)
+	}
+
+	// only the owner can delete the room.
+	if err := r.Delete(ctx, roomRepo, &admin); !IsPermissionError(err) {
+		t.Errorf("admin deletes the room, expect permission error, got: %v", err)
+	}
+}
+
+func TestRoomChangeMemberRole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1}
+	u := User{ID: 2}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(u.ID))
+	r.ID = 1 // it may not be allowed at application side.
+
+	ev, err := r.ChangeMemberRole(owner, u, RoomRoleReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.RoomID != r.ID || ev.UserID != u.ID || ev.ChangedBy != owner.ID {
+		t.Errorf("RoomMemberRoleChanged has different ids: %#v", ev)
+	}
+	if ev.OldRole != string(RoomRoleMember) || ev.Role != string(RoomRoleReadOnly) {
+		t.Errorf("RoomMemberRoleChanged has different roles: %#v", ev)
+	}
+	if got := ev.Timestamp(); got == (time.Time{}) {
+		t.Error("RoomMemberRoleChanged has no timestamp")
+	}
+	if got := r.Events()[len(r.Events())-1]; got != ev {
+		t.Errorf("different event is added, expect: %v, got: %v", ev, got)
+	}
+
+	// read-only member can not post.
+	if _, err := NewRoomMessage(ctx, msgRepo, userRepo, u, *r, "content"); !IsPermissionError(err) {
+		t.Errorf("read-only member posts message, expect permission error, got: %v", err)
+	}
+	if _, err := r.StartTypingBy(&u); !IsPermissionError(err) {
+		t.Errorf("read-only member starts typing, expect permission error, got: %v", err)
+	}
+
+	// same role is error.
+	if _, err := r.ChangeMemberRole(owner, u, RoomRoleReadOnly); err == nil {
+		t.Error("change to same role, but no error")
+	}
+	// invalid role is error.
+	if _, err := r.ChangeMemberRole(owner, u, RoomRole("invalid")); err == nil {
+		t.Error("change to invalid role, but no error")
+	}
+}
+
+func TestRoomRename(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1}
+	member := User{ID: 2}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(member.ID))
+	r.ID = 1 // it may not be allowed at application side.
+
+	if _, err := r.Rename(&member, "renamed"); !IsPermissionError(err) {
+		t.Errorf("member renames the room, expect permission error, got: %v", err)
+	}
+	if _, err := r.Rename(owner, ""); err == nil {
+		t.Error("rename to empty name, but no error")
+	}
+
+	ev, err := r.Rename(owner, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "renamed" {
+		t.Errorf("room name is not changed, got: %v", r.Name)
+	}
+	if ev.OldName != "test" || ev.Name != "renamed" || ev.RenamedBy != owner.ID || ev.RoomID != r.ID {
+		t.Errorf("RoomRenamed has different fields: %#v", ev)
+	}
+	if got := r.Events()[len(r.Events())-1]; got != ev {
+		t.Errorf("different event is added, expect: %v, got: %v", ev, got)
+	}
+}
+
+func TestRoomTransferOwnership(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1}
+	member := User{ID: 2}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(member.ID))
+	r.ID = 1 // it may not be allowed at application side.
+
+	if _, err := r.TransferOwnership(&member, member); !IsPermissionError(err) {
+		t.Errorf("not owner transfers the ownership, expect permission error, got: %v", err)
+	}
+	if _, err := r.TransferOwnership(owner, User{ID: 3}); err == nil {
+		t.Error("transfer the ownership to not a member, but no error")
+	}
+
+	ev, err := r.TransferOwnership(owner, member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.RoomID != r.ID || ev.OldOwnerID != owner.ID || ev.NewOwnerID != member.ID {
+		t.Errorf("RoomOwnershipTransferred has different fields: %#v", ev)
+	}
+	if got := ev.Timestamp(); got == (time.Time{}) {
+		t.Error("RoomOwnershipTransferred has no timestamp")
+	}
+	if r.OwnerID != member.ID {
+		t.Errorf("owner is not changed, expect: %v, got: %v", member.ID, r.OwnerID)
+	}
+	if got := r.RoleOf(owner.ID); got != RoomRoleAdmin {
+		t.Errorf("old owner should be admin, got: %v", got)
+	}
+	if got := r.RoleOf(member.ID); got != RoomRoleOwner {
+		t.Errorf("new owner should be owner, got: %v", got)
+	}
+}
+
+func TestRoomLeave(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1}
+	admin, member := User{ID: 3}, User{ID: 2}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(admin.ID, member.ID))
+	r.ID = 1 // it may not be allowed at application side.
+	if _, err := r.ChangeMemberRole(owner, admin, RoomRoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: member leaves.
+	{
+		nEvents := len(r.Events())
+		ev, err := r.Leave(&member)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.RoomID != r.ID || ev.UserID != member.ID {
+			t.Errorf("RoomMemberLeft has different fields: %#v", ev)
+		}
+		if r.HasMember(member) {
+			t.Error("left member still remains in the room")
+		}
+		if got := len(r.Events()); got != nEvents+1 {
+			t.Errorf("different number of events, expect: %v, got: %v", nEvents+1, got)
+		}
+		if _, err := r.Leave(&member); err == nil {
+			t.Error("not a member leaves the room, but no error")
+		}
+	}
+
+	// case2: owner leaves, then the admin becomes the owner.
+	{
+		if _, err := r.AddMember(owner, member); err != nil {
+			t.Fatal(err)
+		}
+		nEvents := len(r.Events())
+		if _, err := r.Leave(owner); err != nil {
+			t.Fatal(err)
+		}
+		if r.OwnerID != admin.ID {
+			t.Errorf("the admin should be new owner, expect: %v, got: %v", admin.ID, r.OwnerID)
+		}
+		if r.HasMember(*owner) {
+			t.Error("old owner still remains in the room")
+		}
+		evs := r.Events()[nEvents:]
+		if len(evs) != 2 {
+			t.Fatalf("different number of events, expect: %v, got: %v", 2, len(evs))
+		}
+		if _, ok := evs[0].(event.RoomOwnershipTransferred); !ok {
+			t.Errorf("invalid event, expect: %T, got: %T", event.RoomOwnershipTransferred{}, evs[0])
+		}
+		if _, ok := evs[1].(event.RoomMemberLeft); !ok {
+			t.Errorf("invalid event, expect: %T, got: %T", event.RoomMemberLeft{}, evs[1])
+		}
+	}
+
+	// case3: last member leaves, then the room is archived.
+	{
+		if _, err := r.Leave(&admin); err != nil {
+			t.Fatal(err)
+		}
+		if r.OwnerID != member.ID {
+			t.Errorf("the member should be new owner, expect: %v, got: %v", member.ID, r.OwnerID)
+		}
+		nEvents := len(r.Events())
+		if _, err := r.Leave(&member); err != nil {
+			t.Fatal(err)
+		}
+		if !r.IsArchived {
+			t.Error("the room is not archived after all members left")
+		}
+		evs := r.Events()[nEvents:]
+		if len(evs) != 2 {
+			t.Fatalf("different number of events, expect: %v, got: %v", 2, len(evs))
+		}
+		if ev, ok := evs[1].(event.RoomArchived); !ok || ev.ArchivedBy != member.ID {
+			t.Errorf("invalid RoomArchived event: %#v", evs[1])
+		}
+		if _, err := r.AddMember(&member, member); err == nil {
+			t.Error("archived room is added new member, but no error")
+		}
+	}
+}
+
+type talkRoomRepositoryStub struct {
+	RoomRepositoryStub
+	rooms []Room
+}
+
+func (r *talkRoomRepositoryStub) FindAllByUserID(ctx context.Context, userID uint64) ([]Room, error) {
+	return r.rooms, nil
+}
+
+func TestNewTalkRoom(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	user := &User{ID: 1, FriendIDs: NewUserIDSet(2)}
+	friend := User{ID: 2, FriendIDs: NewUserIDSet(1)}
+	other := User{ID: 3}
+
+	if _, err := NewTalkRoom(ctx, roomRepo, user, other); !IsPermissionError(err) {
+		t.Errorf("create talk room with not a friend, expect permission error, got: %v", err)
+	}
+	if _, err := NewTalkRoom(ctx, roomRepo, user, *user); err == nil {
+		t.Error("create talk room with user itself, but no error")
+	}
+
+	r, err := NewTalkRoom(ctx, roomRepo, user, friend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsTalkRoom {
+		t.Error("created room is not a talk room")
+	}
+	if !r.HasMember(*user) || !r.HasMember(friend) || len(r.MemberIDs()) != 2 {
+		t.Errorf("talk room has different members: %v", r.MemberIDs())
+	}
+	ev, ok := r.Events()[0].(event.RoomCreated)
+	if !ok || !ev.IsTalkRoom {
+		t.Errorf("invalid RoomCreated event: %#v", r.Events()[0])
+	}
+
+	// members and settings of the talk room are fixed.
+	if _, err := r.AddMember(user, other); !IsPermissionError(err) {
+		t.Errorf("add member to talk room, expect permission error, got: %v", err)
+	}
+	if _, err := r.RemoveMember(user, friend); !IsPermissionError(err) {
+		t.Errorf("remove member from talk room, expect permission error, got: %v", err)
+	}
+	if _, err := r.Leave(&friend); !IsPermissionError(err) {
+		t.Errorf("leave talk room, expect permission error, got: %v", err)
+	}
+	if _, err := r.Rename(user, "renamed"); !IsPermissionError(err) {
+		t.Errorf("rename talk room, expect permission error, got: %v", err)
+	}
+	if _, err := r.ChangeMemberRole(user, friend, RoomRoleReadOnly); !IsPermissionError(err) {
+		t.Errorf("change role in talk room, expect permission error, got: %v", err)
+	}
+	if _, err := r.TransferOwnership(user, friend); !IsPermissionError(err) {
+		t.Errorf("transfer ownership of talk room, expect permission error, got: %v", err)
+	}
+	if err := r.Delete(ctx, roomRepo, user); !IsPermissionError(err) {
+		t.Errorf("delete talk room by one side, expect permission error, got: %v", err)
+	}
+}
+
+func TestFindTalkRoom(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	user := &User{ID: 1}
+	friend := User{ID: 2}
+	repo := &talkRoomRepositoryStub{rooms: []Room{
+		{ID: 1, MemberIDSet: NewUserIDSet(1, 2)}, // group room having same members.
+		{ID: 2, IsTalkRoom: true, MemberIDSet: NewUserIDSet(1, 3)},
+		{ID: 3, IsTalkRoom: true, MemberIDSet: NewUserIDSet(1, 2)},
+	}}
+
+	r, found, err := FindTalkRoom(ctx, repo, user, friend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || r.ID != 3 {
+		t.Errorf("different talk room is found, expect id: %v, got: %v, found: %v", 3, r.ID, found)
+	}
+
+	if _, found, err := FindTalkRoom(ctx, repo, user, User{ID: 4}); err != nil || found {
+		t.Errorf("talk room with no talking user is found: %v, %v", found, err)
+	}
+}
+
+func TestRoomBlockedByMember(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	user := &User{ID: 1, FriendIDs: NewUserIDSet(2)}
+	blocker := User{ID: 2, FriendIDs: NewUserIDSet(1), BlockedUserIDs: NewUserIDSet(1)}
+	other := User{ID: 3}
+
+	if _, err := NewRoomWithMembers(ctx, roomRepo, "test", user, []User{other, blocker}); !IsPermissionError(err) {
+		t.Errorf("create room with the blocker, expect permission error, got: %v", err)
+	}
+	r, err := NewRoomWithMembers(ctx, roomRepo, "test", user, []User{*user, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.HasMember(*user) || !r.HasMember(other) || len(r.MemberIDs()) != 2 {
+		t.Errorf("room has different members: %v", r.MemberIDs())
+	}
+
+	if _, err := r.AddMember(user, blocker); !IsPermissionError(err) {
+		t.Errorf("add the blocker to room, expect permission error, got: %v", err)
+	}
+	if r.HasMember(blocker) {
+		t.Errorf("the blocker is added to the room")
+	}
+
+	if _, err := NewTalkRoom(ctx, roomRepo, user, blocker); !IsPermissionError(err) {
+		t.Errorf("create talk room with the blocker, expect permission error, got: %v", err)
+	}
+	if _, _, err := FindTalkRoom(ctx, roomRepo, user, blocker); !IsPermissionError(err) {
+		t.Errorf("find talk room with the blocker, expect permission error, got: %v", err)
+	}
+}
+
 func TestRoomReadMessagesByUser(t *testing.T) {
 	ctx := context.Background()
 	owner := &User{ID: 3}
@@ -307,6 +739,54 @@ func TestRoomReadMessagesByUser(t *testing.T) {
 	}
 }
 
+func TestRoomTypingBy(t *testing.T) {
+	ctx := context.Background()
+	owner := &User{ID: 3, Name: "owner"}
+	r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet())
+	r.ID = 1 // it may not be allowed at application side.
+
+	// case1: success.
+	{
+		started, err := r.StartTypingBy(owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if started.RoomID != r.ID || started.SenderID != owner.ID || started.SenderName != owner.Name {
+			t.Errorf("UserTypingStarted has different fields: %#v", started)
+		}
+		if started.StartAt.IsZero() || !started.StartAt.Equal(started.Timestamp()) {
+			t.Errorf("UserTypingStarted has invalid start time: %v", started.StartAt)
+		}
+
+		ended, err := r.EndTypingBy(owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ended.RoomID != r.ID || ended.SenderID != owner.ID || ended.SenderName != owner.Name {
+			t.Errorf("UserTypingEnded has different fields: %#v", ended)
+		}
+		if ended.EndAt.IsZero() {
+			t.Error("UserTypingEnded has no end time")
+		}
+
+		// typing events are ephemeral, room has only Created event.
+		if got := len(r.Events()); got != 1 {
+			t.Errorf("typing events should not be added to the room, got events: %v", got)
+		}
+	}
+
+	// case2: typing by not a room member
+	{
+		const NotExistUserID = uint64(999)
+		if _, err := r.StartTypingBy(&User{ID: NotExistUserID}); err == nil {
+			t.Error("start typing by not a room member, but no error")
+		}
+		if _, err := r.EndTypingBy(&User{ID: NotExistUserID}); err == nil {
+			t.Error("end typing by not a room member, but no error")
+		}
+	}
+}
+
 func TestGetSetReadTime(t *testing.T) {
 	set := NewTimeSet()
 	_, ok := set.Get(1)
@@ -332,3 +812,48 @@ func TestGetSetReadTime(t *testing.T) {
 		t.Error("after delete, get returned ok")
 	}
 }
+
+func TestRoomRemoveDeletedUser(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	owner := &User{ID: 1, FriendIDs: NewUserIDSet(2)}
+	member := User{ID: 2, FriendIDs: NewUserIDSet(1)}
+
+	// case1: the owner is deleted, then the ownership is transferred.
+	{
+		r, _ := NewRoom(ctx, roomRepo, "test", owner, NewUserIDSet(member.ID))
+		r.ID = 1 // it may not be allowed at application side.
+		if err := r.RemoveDeletedUser(ctx, roomRepo, owner); err != nil {
+			t.Fatal(err)
+		}
+		if r.HasMember(*owner) {
+			t.Error("deleted user still remains in the room")
+		}
+		if r.OwnerID != member.ID {
+			t.Errorf("different owner, expect: %v, got: %v", member.ID, r.OwnerID)
+		}
+		if err := r.RemoveDeletedUser(ctx, roomRepo, owner); err == nil {
+			t.Error("remove not a member but no error")
+		}
+	}
+
+	// case2: the talk room is deleted.
+	{
+		r, err := NewTalkRoom(ctx, roomRepo, owner, member)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.ID = 2 // it may not be allowed at application side.
+		if err := r.RemoveDeletedUser(ctx, roomRepo, &member); err != nil {
+			t.Fatal(err)
+		}
+		if !r.NotExist() {
+			t.Error("talk room is not deleted")
+		}
+		events := r.Events()
+		if _, ok := events[len(events)-1].(event.RoomDeleted); !ok {
+			t.Errorf("invalid event is added: %#v", events[len(events)-1])
+		}
+	}
+}
@@ -18,6 +18,18 @@ type UserRepository interface {
 
 	// Find one user by id.
 	Find(ctx context.Context, id uint64) (User, error)
+
+	// FindByName finds one user by the unique user name.
+	FindByName(ctx context.Context, name string) (User, error)
+
+	// ExistsByName returns whether the user having the name
+	// exists in the repository.
+	ExistsByName(ctx context.Context, name string) (bool, error)
+
+	// Remove the user from the repository.
+	// The friend relations from the other users to the removed user
+	// are not changed, they are updated by the domain event.
+	Remove(ctx context.Context, u User) error
 }
 
 // set for user id.
@@ -74,21 +86,52 @@ type User struct {
 	Name      string
 	FirstName string
 	LastName  string
-	Password  string
+	Password  HashedPassword
 
 	FriendIDs UserIDSet
+
+	// IDs of the users which the user sent the friend requests to,
+	// and they are not answered yet.
+	RequestedFriendIDs UserIDSet
+
+	// IDs of the users blocked by the user.
+	BlockedUserIDs UserIDSet
 }
 
-// TODO validation
+// validateUserName returns ValidationError when the name
+// can not be used as the user name.
+func validateUserName(ctx context.Context, userRepo UserRepository, name string) error {
+	if name == "" {
+		return NewValidationError("user name is empty")
+	}
+	exist, err := userRepo.ExistsByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return NewValidationError("user name(%v) already exists", name)
+	}
+	return nil
+}
 
-// create new Room entity into the repository. It retruns the new user
+// create new User entity into the repository. It retruns the new user
 // holding event for UserCreated and error if any.
+// The user name must be unique, and the password must be hashed
+// by PasswordHasher.
 func NewUser(
 	ctx context.Context,
 	userRepo UserRepository,
-	name, firstName, lastName, password string,
+	name, firstName, lastName string,
+	password HashedPassword,
 	friendIDs UserIDSet,
 ) (User, error) {
+	if err := validateUserName(ctx, userRepo, name); err != nil {
+		return User{}, err
+	}
+	if password.IsZero() {
+		return User{}, NewValidationError("password is empty")
+	}
+
 	u := User{
 		EventHolder: NewEventHolder(),
 		ID:          0, // 0 means new entity
@@ -106,6 +149,7 @@ func NewUser(
 	u.ID = id
 
 	ev := event.UserCreated{
+		UserID:    id,
 		Name:      name,
 		FirstName: firstName,
 		LastName:  lastName,
@@ -120,6 +164,27 @@ func NewUser(
 // return whether user is not in the datastore.
 func (u *User) NotExist() bool { return u == nil || u.ID == 0 }
 
+// VerifyPassword returns whether the password matches the user's one.
+func (u *User) VerifyPassword(hasher *PasswordHasher, password string) bool {
+	return hasher.Verify(u.Password, password)
+}
+
+// RehashPassword rehashes the password, which must be verified by
+// VerifyPassword, when the user's one is hashed by the old algorithm
+// or cost. It returns true when the password is rehashed, then
+// the user should be stored to persist it.
+func (u *User) RehashPassword(hasher *PasswordHasher, password string) (bool, error) {
+	if !hasher.NeedsRehash(u.Password) {
+		return false, nil
+	}
+	hashed, err := hasher.hash(password)
+	if err != nil {
+		return false, err
+	}
+	u.Password = hashed
+	return true, nil
+}
+
 // It adds the friend to the user.
 // It returns the event adding into the user, and error
 // when the friend already exist in the user.
@@ -149,3 +214,83 @@ func (u *User) AddFriend(friend User) (event.UserAddedFriend, error) {
 func (u *User) HasFriend(friend User) bool {
 	return u.FriendIDs.Has(friend.ID)
 }
+
+// It removes the friend from the user.
+// It returns the event adding into the user, and error
+// when the friend does not exist in the user.
+func (u *User) RemoveFriend(friend User) (event.UserRemovedFriend, error) {
+	if u.NotExist() {
+		return event.UserRemovedFriend{}, fmt.Errorf("newly user can not be removed friend")
+	}
+	if !u.HasFriend(friend) {
+		return event.UserRemovedFriend{}, fmt.Errorf("friend(id=%d) does not exist in the user(id=%d)", friend.ID, u.ID)
+	}
+
+	u.FriendIDs.Remove(friend.ID)
+
+	ev := event.UserRemovedFriend{
+		UserID:          u.ID,
+		RemovedFriendID: friend.ID,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// UpdateProfile changes the profile of the user.
+// The new name must be unique except for the current name.
+//
+// It returns UserProfileUpdated event and error if any.
+func (u *User) UpdateProfile(ctx context.Context, userRepo UserRepository, name, firstName, lastName string) (event.UserProfileUpdated, error) {
+	if u.NotExist() {
+		return event.UserProfileUpdated{}, fmt.Errorf("newly user can not update profile")
+	}
+	if name != u.Name {
+		if err := validateUserName(ctx, userRepo, name); err != nil {
+			return event.UserProfileUpdated{}, err
+		}
+	}
+	if name == u.Name && firstName == u.FirstName && lastName == u.LastName {
+		return event.UserProfileUpdated{}, NewValidationError("profile is not changed")
+	}
+
+	u.Name = name
+	u.FirstName = firstName
+	u.LastName = lastName
+
+	ev := event.UserProfileUpdated{
+		UserID:    u.ID,
+		Name:      name,
+		FirstName: firstName,
+		LastName:  lastName,
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return ev, nil
+}
+
+// It deletes the user from repository.
+// After successing that, the user holds UserDeleted event.
+// The rooms and friends of the user are not changed here, and
+// they should be updated by handling UserDeleted event.
+func (u *User) Delete(ctx context.Context, userRepo UserRepository) error {
+	if u.NotExist() {
+		return fmt.Errorf("the user not in the datastore, can not be deleted")
+	}
+
+	if err := userRepo.Remove(ctx, *u); err != nil {
+		return err
+	}
+
+	deletedID := u.ID
+	u.ID = 0 // means not in the repository.
+
+	ev := event.UserDeleted{
+		UserID:    deletedID,
+		Name:      u.Name,
+		FriendIDs: u.FriendIDs.List(),
+	}
+	ev.Occurs()
+	u.AddEvent(ev)
+	return nil
+}
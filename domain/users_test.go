@@ -23,11 +23,40 @@ func (u *UserRepositoryStub) Find(ctx context.Context, id uint64) (User, error)
 	panic("not implemented")
 }
 
+func (u *UserRepositoryStub) FindByName(ctx context.Context, name string) (User, error) {
+	if name != ExistUserName {
+		panic("not implemented")
+	}
+	return User{ID: ExistUserID, Name: ExistUserName}, nil
+}
+
+// ExistUserName and ExistUserID are the user which is regarded as
+// existing in the UserRepositoryStub.
+const (
+	ExistUserName = "exist-user"
+	ExistUserID   = 100
+)
+
+func (u *UserRepositoryStub) ExistsByName(ctx context.Context, name string) (bool, error) {
+	return name == ExistUserName, nil
+}
+
+func (u *UserRepositoryStub) Remove(ctx context.Context, user User) error {
+	return nil
+}
+
 var userRepo = &UserRepositoryStub{}
 
+// testPassword is the hashed password which is not used to verify.
+var testPassword = HashedPassword{
+	Algorithm: PasswordAlgorithmBcrypt,
+	Cost:      MinPasswordHashCost,
+	Hash:      "hash",
+}
+
 func TestUserCreated(t *testing.T) {
 	ctx := context.Background()
-	u, err := NewUser(ctx, userRepo, "user", "u-", "ser", "password", NewUserIDSet(1))
+	u, err := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet(1))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -67,7 +96,7 @@ func TestUserCreated(t *testing.T) {
 
 func TestUserAddFriendSuccess(t *testing.T) {
 	ctx := context.Background()
-	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", "password", NewUserIDSet())
+	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet())
 	u.ID = 1 // it may not be allowed at application side.
 	friend := User{ID: u.ID + 1}
 	ev, err := u.AddFriend(friend)
@@ -100,7 +129,7 @@ func TestUserAddFriendSuccess(t *testing.T) {
 func TestUserAddFriendFail(t *testing.T) {
 	// fail case: Add itself as friend.
 	ctx := context.Background()
-	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", "password", NewUserIDSet())
+	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet())
 	u.ID = 1 // it may not be allowed at application side.
 	_, err := u.AddFriend(u)
 	if err == nil {
@@ -112,3 +141,146 @@ func TestUserAddFriendFail(t *testing.T) {
 		t.Errorf("user has invalid event state")
 	}
 }
+
+func TestNewUserValidation(t *testing.T) {
+	ctx := context.Background()
+	for _, testcase := range []struct {
+		Name     string
+		Password HashedPassword
+	}{
+		{"", testPassword},
+		{ExistUserName, testPassword},
+		{"user", HashedPassword{}},
+	} {
+		_, err := NewUser(ctx, userRepo, testcase.Name, "u-", "ser", testcase.Password, NewUserIDSet())
+		if !IsValidationError(err) {
+			t.Errorf("invalid user(name=%q, password=%v) is created, got error: %v", testcase.Name, testcase.Password, err)
+		}
+	}
+}
+
+func TestUserVerifyAndRehashPassword(t *testing.T) {
+	hasher, err := NewPasswordHasher(DefaultPasswordPolicy, MinPasswordHashCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// legacy user having the plain password.
+	u := User{ID: 1, Password: HashedPassword{Hash: "password"}}
+	if u.VerifyPassword(hasher, "wrong") {
+		t.Errorf("wrong password is verified")
+	}
+	if !u.VerifyPassword(hasher, "password") {
+		t.Fatalf("correct password is not verified")
+	}
+
+	rehashed, err := u.RehashPassword(hasher, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rehashed {
+		t.Fatalf("plain password is not rehashed")
+	}
+	if u.Password.Algorithm != PasswordAlgorithmBcrypt || u.Password.Cost != MinPasswordHashCost {
+		t.Errorf("rehashed password has different algorithm or cost, got: %v", u.Password)
+	}
+	if !u.VerifyPassword(hasher, "password") {
+		t.Errorf("rehashed password is not verified")
+	}
+
+	// already hashed by current cost
+	rehashed, err = u.RehashPassword(hasher, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehashed {
+		t.Errorf("password hashed by current cost is rehashed")
+	}
+}
+
+func TestUserRemoveFriend(t *testing.T) {
+	ctx := context.Background()
+	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet(2))
+	u.ID = 1 // it may not be allowed at application side.
+	friend := User{ID: 2}
+
+	ev, err := u.RemoveFriend(friend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ev.UserID; got != u.ID {
+		t.Errorf("UserRemovedFriend has different user id, expect: %d, got: %d", u.ID, got)
+	}
+	if got := ev.RemovedFriendID; got != friend.ID {
+		t.Errorf("UserRemovedFriend has different friend id, expect: %d, got: %d", friend.ID, got)
+	}
+	if u.HasFriend(friend) {
+		t.Errorf("RemoveFriend could not remove friend from the user")
+	}
+
+	// fail case: not a friend.
+	if _, err := u.RemoveFriend(friend); err == nil {
+		t.Error("remove not a friend but no error")
+	}
+}
+
+func TestUserUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet())
+
+	ev, err := u.UpdateProfile(ctx, userRepo, "new-user", "new-first", "new-last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.UserID != u.ID || ev.Name != "new-user" || ev.FirstName != "new-first" || ev.LastName != "new-last" {
+		t.Errorf("UserProfileUpdated has different fields: %#v", ev)
+	}
+	if u.Name != "new-user" || u.FirstName != "new-first" || u.LastName != "new-last" {
+		t.Errorf("profile is not updated: %#v", u)
+	}
+
+	// keeping the name is OK.
+	if _, err := u.UpdateProfile(ctx, userRepo, "new-user", "first", "last"); err != nil {
+		t.Errorf("can not update profile with same name: %v", err)
+	}
+
+	// fail cases
+	for _, name := range []string{"", ExistUserName} {
+		if _, err := u.UpdateProfile(ctx, userRepo, name, "first", "last"); !IsValidationError(err) {
+			t.Errorf("update with invalid name(%q), got error: %v", name, err)
+		}
+	}
+	if _, err := u.UpdateProfile(ctx, userRepo, u.Name, u.FirstName, u.LastName); !IsValidationError(err) {
+		t.Errorf("update with same profile, got error: %v", err)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	ctx := context.Background()
+	u, _ := NewUser(ctx, userRepo, "user", "u-", "ser", testPassword, NewUserIDSet(2, 3))
+	userID := u.ID
+
+	if err := u.Delete(ctx, userRepo); err != nil {
+		t.Fatal(err)
+	}
+	if !u.NotExist() {
+		t.Error("deleted user still exists")
+	}
+
+	events := u.Events()
+	ev, ok := events[len(events)-1].(event.UserDeleted)
+	if !ok {
+		t.Fatalf("invalid event is added: %#v", events[len(events)-1])
+	}
+	if ev.UserID != userID {
+		t.Errorf("UserDeleted has different user id, expect: %d, got: %d", userID, ev.UserID)
+	}
+	if got := len(ev.FriendIDs); got != 2 {
+		t.Errorf("UserDeleted has different friends size, expect: %d, got: %d", 2, got)
+	}
+
+	// fail case: already deleted.
+	if err := u.Delete(ctx, userRepo); err == nil {
+		t.Error("delete deleted user but no error")
+	}
+}
@@ -0,0 +1,378 @@
+// package eventlog provides the persistent event store
+// backed by the append-only log files on the local file system.
+//
+// The events are appended to the segment files in the directory,
+// and each event is assigned a global sequence number, starting from 1,
+// which is unique and monotonically increasing.
+// When the active segment file exceeds the size limit, new segment
+// file is created and the events are appended into it.
+// The written events are committed to the storage by fsync before
+// returning from Store().
+package eventlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// DefaultSegmentSize is the default size limit for each segment file.
+const DefaultSegmentSize = 64 * 1024 * 1024 // 64MB
+
+// Options for the EventRepository.
+type Options struct {
+	// SegmentSize is the size limit in bytes for each segment file.
+	// The active segment is rotated when it exceeds this size.
+	// Zero value means DefaultSegmentSize.
+	SegmentSize int64
+}
+
+// EventRepository is the event store which persists the events into
+// the log files. It implements event.EventRepository and chat.EventQueryer.
+//
+// It is safe for the concurrent use.
+type EventRepository struct {
+	dir  string
+	opts Options
+
+	mu       sync.RWMutex
+	segments []*segment
+	entries  []entry // entries[i] has sequence number i+1.
+	closed   bool
+}
+
+// Open opens the event log in the directory dir.
+// The directory is created if not exist.
+// The existing segment files in the directory are loaded
+// to restore the events and its sequence numbers.
+func Open(dir string, opts Options) (*EventRepository, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	repo := &EventRepository{
+		dir:      dir,
+		opts:     opts,
+		segments: make([]*segment, 0, 4),
+		entries:  make([]entry, 0, 64),
+	}
+
+	paths, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, path := range paths {
+		// only the last segment can be repaired since the others
+		// are never written after rotation.
+		isLast := i == len(paths)-1
+		if err := repo.loadSegment(path, isLast); err != nil {
+			repo.Close()
+			return nil, fmt.Errorf("eventlog: can not load segment %v: %v", path, err)
+		}
+	}
+
+	if len(repo.segments) == 0 {
+		if err := repo.rotate(repo.lastSeq() + 1); err != nil {
+			repo.Close()
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+func (repo *EventRepository) loadSegment(path string, repair bool) error {
+	pending := make([]entry, 0, 64)
+	seg, err := openSegment(path, repair, func(r record, offset, length int64) error {
+		if expect := repo.lastSeq() + uint64(len(pending)) + 1; r.Seq != expect {
+			return fmt.Errorf("sequence is not continuous, expect: %d, got: %d", expect, r.Seq)
+		}
+		pending = append(pending, entry{
+			seq:       r.Seq,
+			streamID:  r.StreamID,
+			createdAt: r.CreatedAt,
+			offset:    offset,
+			length:    length,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		pending[i].segment = seg
+	}
+	repo.segments = append(repo.segments, seg)
+	repo.entries = append(repo.entries, pending...)
+	return nil
+}
+
+// lastSeq returns the sequence number of the last stored event.
+// It must be called under the lock.
+func (repo *EventRepository) lastSeq() uint64 {
+	return uint64(len(repo.entries))
+}
+
+// rotate creates new segment which starts with the sequence number
+// firstSeq, and makes it active. It must be called under the lock.
+func (repo *EventRepository) rotate(firstSeq uint64) error {
+	path := filepath.Join(repo.dir, segmentName(firstSeq))
+	seg, err := openSegment(path, false, func(record, int64, int64) error {
+		return fmt.Errorf("new segment %v is not empty", path)
+	})
+	if err != nil {
+		return err
+	}
+	if err := syncDir(repo.dir); err != nil {
+		seg.close()
+		return err
+	}
+	repo.segments = append(repo.segments, seg)
+	return nil
+}
+
+func (repo *EventRepository) activeSegment() *segment {
+	return repo.segments[len(repo.segments)-1]
+}
+
+// syncDir commits the directory entries, such as newly created file,
+// to the storage.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close closes all of the segment files.
+// The EventRepository can not be used after closing.
+func (repo *EventRepository) Close() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var firstErr error
+	for _, seg := range repo.segments {
+		if err := seg.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	repo.segments = nil
+	repo.entries = nil
+	repo.closed = true
+	return firstErr
+}
+
+// Store appends the events into the log, and returns
+// the sequence numbers assigned to the events.
+// The events are committed to the storage before returning.
+// It is all-or-nothing, that is, none of the events are stored
+// when it returns error.
+func (repo *EventRepository) Store(ctx context.Context, evs ...event.Event) ([]uint64, error) {
+	if len(evs) == 0 {
+		return []uint64{}, nil
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.closed {
+		return nil, chat.NewInfraError("eventlog: already closed")
+	}
+
+	// the all of events are validated and encoded before any write.
+	lines := make([][]byte, 0, len(evs))
+	for i, ev := range evs {
+		if ev.Type() == event.TypeNone || ev.Type() == event.TypeExternal {
+			return nil, chat.NewInfraError("eventlog: event type(%v) can not be stored", event.TypeString(ev))
+		}
+		line, err := encodeRecord(repo.lastSeq()+uint64(i)+1, ev)
+		if err != nil {
+			return nil, chat.NewInfraError("eventlog: can not encode event(%v): %v", ev.Type(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	// the events are written per segment, and the entries are
+	// added only after all of the writes are committed.
+	var (
+		buf     = make([]byte, 0, 512)
+		pending = make([]entry, 0, len(evs))
+		ids     = make([]uint64, 0, len(evs))
+		active  = repo.activeSegment()
+
+		nSegments = len(repo.segments)
+		firstSize = active.size
+	)
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		if err := active.write(buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+		return nil
+	}
+	// abort restores the segments written by this batch.
+	abort := func(err error) ([]uint64, error) {
+		if rerr := repo.restore(nSegments, firstSize); rerr != nil {
+			// the log can not be continued consistently.
+			repo.closed = true
+			return nil, chat.NewInfraError("eventlog: can not restore segments: %v, after error: %v", rerr, err)
+		}
+		return nil, err
+	}
+
+	for i, line := range lines {
+		seq := repo.lastSeq() + uint64(i) + 1
+		if size := active.size + int64(len(buf)); size > 0 && size+int64(len(line)) > repo.opts.SegmentSize {
+			if err := flush(); err != nil {
+				return abort(chat.NewInfraError("eventlog: can not write events: %v", err))
+			}
+			if err := repo.rotate(seq); err != nil {
+				return abort(chat.NewInfraError("eventlog: can not rotate segment: %v", err))
+			}
+			active = repo.activeSegment()
+		}
+
+		pending = append(pending, entry{
+			seq:       seq,
+			streamID:  evs[i].StreamID(),
+			createdAt: evs[i].Timestamp(),
+			segment:   active,
+			offset:    active.size + int64(len(buf)),
+			length:    int64(len(line)),
+		})
+		buf = append(buf, line...)
+		ids = append(ids, seq)
+	}
+
+	if err := flush(); err != nil {
+		return abort(chat.NewInfraError("eventlog: can not write events: %v", err))
+	}
+	repo.entries = append(repo.entries, pending...)
+	return ids, nil
+}
+
+// restore removes the segments after the first n segments, and
+// truncates the n-th segment to the size. It is used to discard
+// the partially written events. It must be called under the lock.
+func (repo *EventRepository) restore(n int, size int64) error {
+	for _, seg := range repo.segments[n:] {
+		if err := seg.remove(); err != nil {
+			return err
+		}
+	}
+	repo.segments = repo.segments[:n]
+	if err := syncDir(repo.dir); err != nil {
+		return err
+	}
+	return repo.activeSegment().truncate(size)
+}
+
+// findRecords returns the records, matched with filter function,
+// from the entries starting at index start.
+func (repo *EventRepository) findRecords(start uint64, limit int, filter func(e entry) bool) ([]event.Record, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	records := make([]event.Record, 0, limit)
+	for i := start; i < uint64(len(repo.entries)) && len(records) < limit; i++ {
+		e := repo.entries[i]
+		if !filter(e) {
+			continue
+		}
+		ev, err := e.read()
+		if err != nil {
+			return nil, chat.NewInfraError("eventlog: can not read event(seq=%d): %v", e.seq, err)
+		}
+		records = append(records, event.Record{Seq: e.seq, Event: ev})
+	}
+	return records, nil
+}
+
+func eventsOf(records []event.Record) []event.Event {
+	evs := make([]event.Event, 0, len(records))
+	for _, r := range records {
+		evs = append(evs, r.Event)
+	}
+	return evs
+}
+
+func (repo *EventRepository) FindAllByTimeCursor(ctx context.Context, after time.Time, limit int) ([]event.Event, error) {
+	if limit <= 0 {
+		return []event.Event{}, nil
+	}
+
+	records, err := repo.findRecords(0, limit, func(e entry) bool {
+		return e.createdAt.After(after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist after %v", after)
+	}
+	return eventsOf(records), nil
+}
+
+func (repo *EventRepository) FindAllByStreamID(ctx context.Context, streamID event.StreamID, after time.Time, limit int) ([]event.Event, error) {
+	if limit <= 0 {
+		return []event.Event{}, nil
+	}
+
+	records, err := repo.findRecords(0, limit, func(e entry) bool {
+		return e.streamID == streamID && e.createdAt.After(after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist for stream(%v) after %v", streamID, after)
+	}
+	return eventsOf(records), nil
+}
+
+func (repo *EventRepository) FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+	if limit <= 0 {
+		return []event.Record{}, nil
+	}
+
+	// entries[after] has the sequence number after+1.
+	records, err := repo.findRecords(after, limit, func(entry) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist after sequence %v", after)
+	}
+	return records, nil
+}
+
+func (repo *EventRepository) FindAllByStreamIDSequence(ctx context.Context, streamID event.StreamID, after uint64, limit int) ([]event.Record, error) {
+	if limit <= 0 {
+		return []event.Record{}, nil
+	}
+
+	records, err := repo.findRecords(after, limit, func(e entry) bool {
+		return e.streamID == streamID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist for stream(%v) after sequence %v", streamID, after)
+	}
+	return records, nil
+}
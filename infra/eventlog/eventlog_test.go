@@ -0,0 +1,374 @@
+package eventlog
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func createTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestEventLogStore(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	repo, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// case1: store single event
+	ev := event.UserCreated{Name: "user"}
+	ev.Occurs()
+	ids, err := repo.Store(ctx, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("different stored sequence, expect: %v, got: %v", []uint64{1}, ids)
+	}
+
+	// case2: store multiple events
+	ev2 := event.RoomCreated{RoomID: 1}
+	ev2.Occurs()
+	ev3 := event.MessageCreated{MessageID: 1}
+	ev3.Occurs()
+	ids, err = repo.Store(ctx, ev2, ev3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("different stored sequence, expect: %v, got: %v", []uint64{2, 3}, ids)
+	}
+
+	// case3: not storable event type
+	if _, err := repo.Store(ctx, event.ExternalEventEmbd{}); err == nil {
+		t.Errorf("store external event, but no error")
+	}
+}
+
+func TestEventLogReopen(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	repo, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := event.UserCreated{Name: "user"}
+	ev.Occurs()
+	if _, err := repo.Store(ctx, ev, ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: events survive reopening and sequence continues.
+	repo, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := repo.FindAllBySequence(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("different restored record size, expect: %v, got: %v", 2, len(records))
+	}
+	if got, ok := records[0].Event.(event.UserCreated); !ok || got.Name != ev.Name || !got.CreatedAt.Equal(ev.CreatedAt) {
+		t.Errorf("different restored event, expect: %#v, got: %#v", ev, records[0].Event)
+	}
+	ids, err := repo.Store(ctx, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids[0] != 3 {
+		t.Errorf("sequence is not continued after reopening, expect: %v, got: %v", 3, ids[0])
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// case2: broken tail, typically caused by crash, is repaired.
+	paths, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(paths[len(paths)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(`{"seq":4,"type":`)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	repo, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("opening log with broken tail, but error: %v", err)
+	}
+	defer repo.Close()
+	ids, err = repo.Store(ctx, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids[0] != 4 {
+		t.Errorf("different sequence after repair, expect: %v, got: %v", 4, ids[0])
+	}
+	records, err = repo.FindAllBySequence(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Errorf("different record size after repair, expect: %v, got: %v", 4, len(records))
+	}
+}
+
+func TestEventLogReopenCorruptRecord(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	repo, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := event.UserCreated{Name: "user"}
+	ev.Occurs()
+	if _, err := repo.Store(ctx, ev, ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the first record, which is followed by the valid record.
+	paths, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := paths[len(paths)-1]
+	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte(`#`), 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the corrupt record is not repaired, and the valid records are kept.
+	if repo, err := Open(dir, Options{}); err == nil {
+		repo.Close()
+		t.Fatal("opening log with corrupt record, but no error")
+	}
+	if after, err := os.Stat(path); err != nil || after.Size() != info.Size() {
+		t.Errorf("the segment with corrupt record is truncated, size before: %v, after: %v", info.Size(), after.Size())
+	}
+}
+
+func TestEventLogRotation(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	// small segment size to rotate for each event.
+	repo, err := Open(dir, Options{SegmentSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := event.UserCreated{Name: "user"}
+	ev.Occurs()
+	if _, err := repo.Store(ctx, ev, ev); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Store(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("different segment size, expect: %v, got: %v", 3, len(paths))
+	}
+	for i, path := range paths {
+		if expect, got := segmentName(uint64(i+1)), filepath.Base(path); expect != got {
+			t.Errorf("different segment name, expect: %v, got: %v", expect, got)
+		}
+	}
+
+	// events over the segments are restored.
+	repo, err = Open(dir, Options{SegmentSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	records, err := repo.FindAllBySequence(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seq != 2 || records[1].Seq != 3 {
+		t.Errorf("different records over the segments: %#v", records)
+	}
+}
+
+func TestEventLogStoreAllOrNothing(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	// small segment size to rotate for each event.
+	repo, err := Open(dir, Options{SegmentSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	ev := event.UserCreated{Name: "user"}
+	ev.Occurs()
+
+	// case1: the batch containing not storable event is not stored at all.
+	if _, err := repo.Store(ctx, ev, event.ExternalEventEmbd{}); err == nil {
+		t.Fatal("store external event, but no error")
+	}
+
+	// case2: the batch is discarded when the rotation fails in the middle of it.
+	blocker := filepath.Join(dir, segmentName(3))
+	if err := ioutil.WriteFile(blocker, []byte("not a segment\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Store(ctx, ev, ev, ev); err == nil {
+		t.Fatal("rotation is failed, but no error")
+	}
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, segmentName(2))); !os.IsNotExist(err) {
+		t.Errorf("the segment created by the failed batch remains: %v", err)
+	}
+	if _, err := repo.FindAllBySequence(ctx, 0, 10); !chat.IsNotFoundError(err) {
+		t.Errorf("the events of the failed batch are found, error: %v", err)
+	}
+
+	// the sequence continues without the failed batches.
+	ids, err := repo.Store(ctx, ev, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("different stored sequence, expect: %v, got: %v", []uint64{1, 2}, ids)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the log without the failed batches is restored.
+	repo, err = Open(dir, Options{SegmentSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	records, err := repo.FindAllBySequence(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Seq != 1 || records[1].Seq != 2 {
+		t.Errorf("different restored records: %#v", records)
+	}
+}
+
+func TestEventLogFindAll(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	repo, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// events having same timestamp.
+	uc := event.UserCreated{Name: "user"}
+	uc.Occurs()
+	rc := event.RoomCreated{RoomID: 1}
+	rc.CreatedAt = uc.CreatedAt
+	mc := event.MessageCreated{MessageID: 1}
+	mc.CreatedAt = uc.CreatedAt.Add(time.Second)
+	if _, err := repo.Store(ctx, uc, rc, mc); err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: by time cursor, the events of same timestamp can not be distinguished.
+	evs, err := repo.FindAllByTimeCursor(ctx, uc.CreatedAt, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Type() != event.TypeMessageCreated {
+		t.Errorf("different events after time cursor: %#v", evs)
+	}
+
+	// case2: by sequence, the events of same timestamp are distinguished.
+	records, err := repo.FindAllBySequence(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Seq != 2 || records[0].Event.Type() != event.TypeRoomCreated {
+		t.Errorf("different records after sequence: %#v", records)
+	}
+
+	// case3: by stream ID
+	evs, err = repo.FindAllByStreamID(ctx, event.RoomStream, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Type() != event.TypeRoomCreated {
+		t.Errorf("different events for room stream: %#v", evs)
+	}
+	records, err = repo.FindAllByStreamIDSequence(ctx, event.MessageStream, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Seq != 3 {
+		t.Errorf("different records for message stream: %#v", records)
+	}
+
+	// case4: not found
+	if _, err := repo.FindAllBySequence(ctx, 3, 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find events after latest one, expect NotFoundError but got: %v", err)
+	}
+	if _, err := repo.FindAllByTimeCursor(ctx, mc.CreatedAt, 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find events after latest time, expect NotFoundError but got: %v", err)
+	}
+}
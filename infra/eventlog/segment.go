@@ -0,0 +1,208 @@
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+const segmentExt = ".log"
+
+// segmentName returns the file name of the segment
+// which starts with the first sequence number.
+// The name is zero-padded so that the lexical order equals
+// to the order of the sequence.
+func segmentName(firstSeq uint64) string {
+	return fmt.Sprintf("%020d%s", firstSeq, segmentExt)
+}
+
+// listSegments returns paths of the segment files in the dir,
+// ordered by its first sequence number.
+func listSegments(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+		if _, err := strconv.ParseUint(name, 10, 64); err != nil {
+			continue // not a segment file.
+		}
+		segments = append(segments, path)
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// segment is a file holding a part of the event log.
+// The events are written as a line of JSON encoded record,
+// and never modified after that.
+type segment struct {
+	file *os.File
+	size int64
+}
+
+// record is the serialized form of the event in the segment.
+type record struct {
+	Seq       uint64          `json:"seq"`
+	Type      event.Type      `json:"type"`
+	StreamID  event.StreamID  `json:"stream_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func encodeRecord(seq uint64, ev event.Event) ([]byte, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	line, err := json.Marshal(record{
+		Seq:       seq,
+		Type:      ev.Type(),
+		StreamID:  ev.StreamID(),
+		CreatedAt: ev.Timestamp(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// entry is an index for the record in the segment.
+type entry struct {
+	seq       uint64
+	streamID  event.StreamID
+	createdAt time.Time
+
+	segment *segment
+	offset  int64
+	length  int64
+}
+
+// read reads and decodes the event pointed by the entry.
+func (e entry) read() (event.Event, error) {
+	buf := make([]byte, e.length)
+	if _, err := e.segment.file.ReadAt(buf, e.offset); err != nil {
+		return nil, err
+	}
+
+	var r record
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return nil, err
+	}
+	return event.Decode(r.Type, r.Data)
+}
+
+// openSegment opens the segment file and scans all of the records in it.
+// The scanned records are passed to the onRecord callback.
+//
+// If the last record is not terminated, typically caused by crash while
+// writing, the segment is truncated to the end of last complete record
+// when repair is true, or returns error when repair is false.
+// The complete record which can not be decoded is never repaired since
+// the records after it are valid, and it returns error.
+func openSegment(path string, repair bool, onRecord func(r record, offset, length int64) error) (*segment, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reader = bufio.NewReader(file)
+		offset int64
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+
+		var r record
+		torn := err == io.EOF // the line is not terminated.
+		if err != nil && err != io.EOF {
+			file.Close()
+			return nil, err
+		}
+		if !torn && json.Unmarshal(line, &r) != nil {
+			file.Close()
+			return nil, fmt.Errorf("corrupt record in segment %v at offset %d", path, offset)
+		}
+
+		if torn {
+			if !repair {
+				file.Close()
+				return nil, fmt.Errorf("broken record in segment %v at offset %d", path, offset)
+			}
+			if err := file.Truncate(offset); err != nil {
+				file.Close()
+				return nil, err
+			}
+			if err := file.Sync(); err != nil {
+				file.Close()
+				return nil, err
+			}
+			break
+		}
+
+		if err := onRecord(r, offset, int64(len(line))); err != nil {
+			file.Close()
+			return nil, err
+		}
+		offset += int64(len(line))
+	}
+
+	return &segment{file: file, size: offset}, nil
+}
+
+// write appends the data to the end of the segment,
+// and commits it to the storage by fsync.
+// If failed, the segment is restored to the size before writing.
+func (s *segment) write(data []byte) error {
+	if _, err := s.file.WriteAt(data, s.size); err != nil {
+		s.file.Truncate(s.size)
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		s.file.Truncate(s.size)
+		return err
+	}
+	s.size += int64(len(data))
+	return nil
+}
+
+// truncate discards the data after the size, and commits it
+// to the storage.
+func (s *segment) truncate(size int64) error {
+	if err := s.file.Truncate(size); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+// remove closes and removes the segment file.
+func (s *segment) remove() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(s.file.Name())
+}
+
+func (s *segment) close() error {
+	return s.file.Close()
+}
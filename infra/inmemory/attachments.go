@@ -0,0 +1,63 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+var (
+	attachmentMapMu *sync.RWMutex = new(sync.RWMutex)
+
+	// under mu
+	attachmentMap = make(map[uint64]domain.Attachment, 16)
+
+	attachmentCounter uint64 = 0
+)
+
+func errAttachmentNotFound(id uint64) *chat.NotFoundError {
+	return chat.NewNotFoundError("attachment (id=%v) is not found", id)
+}
+
+type AttachmentRepository struct {
+	domain.EmptyTxBeginner
+}
+
+func NewAttachmentRepository() *AttachmentRepository {
+	return &AttachmentRepository{}
+}
+
+func (repo *AttachmentRepository) Find(ctx context.Context, id uint64) (domain.Attachment, error) {
+	attachmentMapMu.RLock()
+	a, ok := attachmentMap[id]
+	attachmentMapMu.RUnlock()
+	if ok {
+		return a, nil
+	}
+	return domain.Attachment{}, errAttachmentNotFound(id)
+}
+
+func (repo *AttachmentRepository) Store(ctx context.Context, a domain.Attachment) (uint64, error) {
+	attachmentMapMu.Lock()
+	defer attachmentMapMu.Unlock()
+
+	if a.NotExist() {
+		attachmentCounter += 1
+		a.ID = attachmentCounter
+		a.CreatedAt = time.Now()
+		attachmentMap[a.ID] = a
+		return a.ID, nil
+	}
+
+	stored, ok := attachmentMap[a.ID]
+	if !ok {
+		return 0, chat.NewInfraError("attachment(id=%d) is not in the datastore", a.ID)
+	}
+	// created time is not changed by update.
+	a.CreatedAt = stored.CreatedAt
+	attachmentMap[a.ID] = a
+	return a.ID, nil
+}
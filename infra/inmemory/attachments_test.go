@@ -0,0 +1,70 @@
+package inmemory
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+func TestAttachmentRepository(t *testing.T) {
+	t.Parallel()
+
+	repo := NewAttachmentRepository()
+	ctx := context.Background()
+
+	id, err := repo.Store(ctx, domain.Attachment{UserID: 1, RoomID: 2, FileName: "photo.png", BlobKey: "blob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := repo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.FileName != "photo.png" || a.CreatedAt.IsZero() {
+		t.Errorf("different stored attachment, got %#v", a)
+	}
+
+	a.MessageID = 3
+	if _, err := repo.Store(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ = repo.Find(ctx, id); a.MessageID != 3 {
+		t.Errorf("the attachment is not updated, got %#v", a)
+	}
+
+	if _, err := repo.Find(ctx, id+100); !chat.IsNotFoundError(err) {
+		t.Errorf("find not existing attachment, expect NotFoundError but got %v", err)
+	}
+}
+
+func TestBlobStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewBlobStore()
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "key", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Get(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(r); string(data) != "hello" {
+		t.Errorf("different blob data, got %q", data)
+	}
+
+	if err := store.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "key"); !chat.IsNotFoundError(err) {
+		t.Errorf("get deleted blob, expect NotFoundError but got %v", err)
+	}
+	if err := store.Put(ctx, "../key", strings.NewReader("hello")); err == nil {
+		t.Error("put blob with invalid key, but no error")
+	}
+}
@@ -0,0 +1,58 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/shirasudon/go-chat/chat"
+)
+
+// BlobStore stores the blobs in the memory.
+// It implements chat.BlobStore.
+type BlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewBlobStore() *BlobStore {
+	return &BlobStore{
+		blobs: make(map[string][]byte),
+	}
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if !chat.IsValidBlobKey(key) {
+		return fmt.Errorf("inmemory: invalid blob key %q", key)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.blobs[key] = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	data, ok := s.blobs[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, chat.NewNotFoundError("blob (key=%v) is not found", key)
+	}
+	// the stored data is never modified, so it can be shared.
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.blobs, key)
+	s.mu.Unlock()
+	return nil
+}
@@ -79,7 +79,7 @@ func (EventRepository) FindAllByStreamID(ctx context.Context, streamID event.Str
 	defer eventStoreMu.RUnlock()
 
 	for _, ev := range eventStore {
-		if ev.StreamID() != streamID {
+		if ev.StreamID() != streamID || !ev.Timestamp().After(after) {
 			continue
 		}
 
@@ -91,3 +91,40 @@ func (EventRepository) FindAllByStreamID(ctx context.Context, streamID event.Str
 
 	return ret, nil
 }
+
+func (EventRepository) FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+	return findRecords(after, limit, func(event.Event) bool { return true })
+}
+
+func (EventRepository) FindAllByStreamIDSequence(ctx context.Context, streamID event.StreamID, after uint64, limit int) ([]event.Record, error) {
+	return findRecords(after, limit, func(ev event.Event) bool { return ev.StreamID() == streamID })
+}
+
+// findRecords returns the records matched with filter function
+// after the sequence number.
+// The sequence number for the event is the index of eventStore plus one.
+func findRecords(after uint64, limit int, filter func(event.Event) bool) ([]event.Record, error) {
+	if limit <= 0 {
+		return []event.Record{}, nil
+	}
+
+	eventStoreMu.RLock()
+	defer eventStoreMu.RUnlock()
+
+	ret := make([]event.Record, 0, limit)
+	for i := after; i < uint64(len(eventStore)); i++ {
+		ev := eventStore[i]
+		if !filter(ev) {
+			continue
+		}
+		ret = append(ret, event.Record{Seq: i + 1, Event: ev})
+		if len(ret) == limit {
+			break
+		}
+	}
+
+	if len(ret) == 0 {
+		return ret, chat.NewNotFoundError("event not exist after sequence %v", after)
+	}
+	return ret, nil
+}
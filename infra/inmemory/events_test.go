@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/domain/event"
 )
 
@@ -129,4 +130,52 @@ func TestEventFindAllByStreamID(t *testing.T) {
 			t.Errorf("unexpected event streamID, expect: %v, got: %v", streamID, got)
 		}
 	}
+
+	// check the events before the cursor are not returned.
+	evs, err := eventRepo.FindAllByStreamID(context.Background(), event.UserStream, time.Now().Add(1*time.Second), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 0 {
+		t.Errorf("the events before the cursor are returned: %v", evs)
+	}
+}
+
+func TestEventFindAllBySequence(t *testing.T) {
+	// create two events having same timestamp to
+	// distinguish them by sequence.
+	uc := event.UserCreated{}
+	uc.Occurs()
+	rc := event.RoomCreated{}
+	rc.CreatedAt = uc.CreatedAt
+	ids, err := eventRepo.Store(context.Background(), uc, rc)
+	if err != nil {
+		t.Fatalf("preparation is failed: %v", err)
+	}
+
+	// case1: find next event of the first one.
+	records, err := eventRepo.FindAllBySequence(context.Background(), ids[0], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("different returned record size, expect: %v, got: %v", 1, len(records))
+	}
+	if got := records[0]; got.Seq != ids[1] || got.Event.Type() != event.TypeRoomCreated {
+		t.Errorf("unexpected record is returned, expect seq: %v, got: %#v", ids[1], got)
+	}
+
+	// case2: find by stream ID
+	records, err = eventRepo.FindAllByStreamIDSequence(context.Background(), event.UserStream, ids[0]-1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Seq != ids[0] {
+		t.Errorf("unexpected records for user stream, expect seq: %v, got: %#v", ids[0], records)
+	}
+
+	// case3: not found after latest event
+	if _, err := eventRepo.FindAllBySequence(context.Background(), ids[1], 1); !chat.IsNotFoundError(err) {
+		t.Errorf("find events after latest one, expect NotFoundError but got: %v", err)
+	}
 }
@@ -48,7 +48,7 @@ type userAndRoomID struct {
 }
 
 func errMsgNotFound(msgID uint64) *chat.NotFoundError {
-	return chat.NewNotFoundError("message (id=%v) is not found")
+	return chat.NewNotFoundError("message (id=%v) is not found", msgID)
 }
 
 type MessageRepository struct {
@@ -119,11 +119,22 @@ func (repo *MessageRepository) Find(ctx context.Context, msgID uint64) (domain.M
 	m, ok := messageMap[msgID]
 	messageMapMu.RUnlock()
 	if ok {
-		return m, nil
+		return copyMessage(m), nil
 	}
 	return domain.Message{}, errMsgNotFound(msgID)
 }
 
+// copyMessage returns the copy of the message which does not share
+// the reactions, the mentions and the attachments with the original.
+func copyMessage(m domain.Message) domain.Message {
+	m.Reactions = m.Reactions.Copy()
+	m.MentionedUserIDs = domain.NewUserIDSet(m.MentionedUserIDs.List()...)
+	if m.AttachmentIDs != nil {
+		m.AttachmentIDs = append([]uint64(nil), m.AttachmentIDs...)
+	}
+	return m
+}
+
 func (repo *MessageRepository) FindRoomMessagesOrderByLatest(ctx context.Context, roomID uint64, before time.Time, limit int) ([]domain.Message, error) {
 	if limit <= 0 {
 		return []domain.Message{}, nil
@@ -133,35 +144,107 @@ func (repo *MessageRepository) FindRoomMessagesOrderByLatest(ctx context.Context
 
 	msgs := make([]domain.Message, 0, limit)
 	for _, m := range messageMap {
-		if m.RoomID == roomID && m.CreatedAt.Before(before) {
-			msgs = append(msgs, m)
+		// the replies are shown in the threads.
+		if m.RoomID == roomID && !m.IsReply() && m.CreatedAt.Before(before) {
+			msgs = append(msgs, copyMessage(m))
 		}
 	}
 	messageMapMu.RUnlock()
 
+	return sortMessagesByLatest(msgs, limit), nil
+}
+
+func (repo *MessageRepository) FindThreadMessagesOrderByLatest(ctx context.Context, parentID uint64, before time.Time, limit int) ([]domain.Message, error) {
+	if limit <= 0 {
+		return []domain.Message{}, nil
+	}
+
+	messageMapMu.RLock()
+
+	msgs := make([]domain.Message, 0, limit)
+	for _, m := range messageMap {
+		if m.ParentID == parentID && m.IsReply() && m.CreatedAt.Before(before) {
+			msgs = append(msgs, copyMessage(m))
+		}
+	}
+	messageMapMu.RUnlock()
+
+	return sortMessagesByLatest(msgs, limit), nil
+}
+
+// sortMessagesByLatest sorts the messages ordered by latest created at,
+// and returns first limit messages.
+func sortMessagesByLatest(msgs []domain.Message, limit int) []domain.Message {
 	sort.Slice(msgs, func(i, j int) bool { return msgs[i].CreatedAt.After(msgs[j].CreatedAt) })
 
 	if len(msgs) > limit {
 		msgs = msgs[:limit]
 	}
-	return msgs, nil
+	return msgs
 }
 
 func (repo *MessageRepository) Store(ctx context.Context, m domain.Message) (uint64, error) {
-	// TODO create or update
 	m.EventHolder = domain.NewEventHolder() // event should not be persisted.
+	if m.NotExist() {
+		return repo.Create(ctx, m)
+	} else {
+		return repo.Update(ctx, m)
+	}
+}
 
+func (repo *MessageRepository) Create(ctx context.Context, m domain.Message) (uint64, error) {
 	messageMapMu.Lock()
 
 	messageCounter += 1
 	m.ID = messageCounter
 	m.CreatedAt = time.Now()
-	messageMap[m.ID] = m
+	messageMap[m.ID] = copyMessage(m)
 
 	messageMapMu.Unlock()
 	return m.ID, nil
 }
 
+func (repo *MessageRepository) Update(ctx context.Context, m domain.Message) (uint64, error) {
+	messageMapMu.Lock()
+	defer messageMapMu.Unlock()
+
+	stored, ok := messageMap[m.ID]
+	if !ok {
+		return 0, chat.NewInfraError("message(id=%d) is not in the datastore", m.ID)
+	}
+
+	// created time is not changed by update.
+	// the thread summary is updated by RecountReplies only.
+	m.CreatedAt = stored.CreatedAt
+	m.ReplyCount = stored.ReplyCount
+	m.LastReplyAt = stored.LastReplyAt
+	messageMap[m.ID] = copyMessage(m)
+	return m.ID, nil
+}
+
+func (repo *MessageRepository) RecountReplies(ctx context.Context, parentID uint64) (domain.Message, error) {
+	messageMapMu.Lock()
+	defer messageMapMu.Unlock()
+
+	parent, ok := messageMap[parentID]
+	if !ok {
+		return domain.Message{}, chat.NewInfraError("message(id=%d) is not in the datastore", parentID)
+	}
+
+	parent.ReplyCount = 0
+	parent.LastReplyAt = time.Time{}
+	for _, m := range messageMap {
+		if m.ParentID == parentID && !m.Deleted {
+			parent.ReplyCount += 1
+			if m.CreatedAt.After(parent.LastReplyAt) {
+				parent.LastReplyAt = m.CreatedAt
+			}
+		}
+	}
+	messageMap[parentID] = parent
+	return copyMessage(parent), nil
+}
+
 func (repo *MessageRepository) RemoveAllByRoomID(ctx context.Context, roomID uint64) error {
 	messageMapMu.Lock()
 
@@ -192,12 +275,19 @@ func (repo *MessageRepository) FindUnreadRoomMessages(ctx context.Context, userI
 
 	unreadMsgs := make([]queried.Message, 0, limit)
 	for _, m := range messageMap {
-		if m.RoomID == roomID && m.CreatedAt.After(readTime) {
+		// deleted messages are not need to be read.
+		if m.RoomID == roomID && m.CreatedAt.After(readTime) && !m.Deleted {
 			qm := queried.Message{
-				MessageID: m.ID,
-				UserID:    m.UserID,
-				Content:   m.Content,
-				CreatedAt: m.CreatedAt,
+				MessageID:   m.ID,
+				UserID:      m.UserID,
+				Content:     m.Content,
+				CreatedAt:   m.CreatedAt,
+				EditedAt:    m.EditedAt,
+				ParentID:    m.ParentID,
+				ReplyCount:  m.ReplyCount,
+				LastReplyAt: m.LastReplyAt,
+				Reactions:   chat.NewQueriedReactions(m.Reactions, userID),
+				Attachments: m.AttachmentIDs,
 			}
 			unreadMsgs = append(unreadMsgs, qm)
 
@@ -213,3 +303,59 @@ func (repo *MessageRepository) FindUnreadRoomMessages(ctx context.Context, userI
 		MsgsSize: len(unreadMsgs),
 	}, nil
 }
+
+func (repo *MessageRepository) FindUserMentions(ctx context.Context, userID uint64, before time.Time, limit int) (*queried.UserMentions, error) {
+	if limit < 0 {
+		limit = 0
+	}
+
+	messageMapMu.RLock()
+	defer messageMapMu.RUnlock()
+
+	ret := queried.EmptyUserMentions
+	ret.UserID = userID
+
+	mentions := make([]queried.Mention, 0, limit)
+	for _, m := range messageMap {
+		if m.Deleted || !m.MentionedUserIDs.Has(userID) {
+			continue
+		}
+		// missing readTime indicates user not exist in the room
+		readTime, ok := userAndRoomIDToReadTime[userAndRoomID{userID, m.RoomID}]
+		if !ok {
+			continue
+		}
+
+		unread := m.CreatedAt.After(readTime)
+		if unread {
+			ret.UnreadCount += 1
+		}
+		if !m.CreatedAt.Before(before) {
+			continue
+		}
+		mentions = append(mentions, queried.Mention{
+			Message: queried.Message{
+				MessageID:   m.ID,
+				UserID:      m.UserID,
+				Content:     m.Content,
+				CreatedAt:   m.CreatedAt,
+				EditedAt:    m.EditedAt,
+				ParentID:    m.ParentID,
+				ReplyCount:  m.ReplyCount,
+				LastReplyAt: m.LastReplyAt,
+				Reactions:   chat.NewQueriedReactions(m.Reactions, userID),
+				Mentions:    m.MentionedIDs(),
+				Attachments: m.AttachmentIDs,
+			},
+			RoomID: m.RoomID,
+			Unread: unread,
+		})
+	}
+
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].CreatedAt.After(mentions[j].CreatedAt) })
+	if len(mentions) > limit {
+		mentions = mentions[:limit]
+	}
+	ret.Mentions = mentions
+	return &ret, nil
+}
@@ -21,6 +21,11 @@ func TestMain(m *testing.M) {
 	defer globalPubsub.Shutdown()
 	messageRepository = NewMessageRepository(globalPubsub)
 
+	// users related to the dummy rooms.
+	if err := userRepository.SeedUsers(DummyUser2, DummyUser3); err != nil {
+		panic(err)
+	}
+
 	ret := m.Run()
 	os.Exit(ret)
 }
@@ -79,6 +84,76 @@ func TestMessageRepoStore(t *testing.T) {
 	}
 }
 
+func TestMessageRepoStoreUpdate(t *testing.T) {
+	t.Parallel()
+
+	// case1: update existing message
+	id, err := messageRepository.Store(context.Background(), domain.Message{Content: "before"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := messageRepository.Find(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created.Content = ""
+	created.Deleted = true
+	updatedID, err := messageRepository.Store(context.Background(), created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updatedID != id {
+		t.Errorf("different message id after update, expect: %v, got: %v", id, updatedID)
+	}
+
+	updated, err := messageRepository.Find(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Deleted || updated.Content != "" {
+		t.Errorf("message is updated but stored message is not changed: %#v", updated)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("created time should not be changed by update, expect: %v, got: %v", created.CreatedAt, updated.CreatedAt)
+	}
+
+	// case2: update not found message
+	const NotFoundID = 99999
+	if _, err := messageRepository.Store(context.Background(), domain.Message{ID: NotFoundID}); err == nil {
+		t.Fatal("update not found message but no error")
+	}
+}
+
+func TestMessageRepoStoreReactions(t *testing.T) {
+	t.Parallel()
+
+	m := domain.Message{Content: "hello"}
+	m.Reactions.Add(":+1:", 1)
+	id, err := messageRepository.Store(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// modifying found message does not affect the stored one.
+	found, err := messageRepository.Find(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.Reactions.Add(":smile:", 2)
+
+	stored, err := messageRepository.Find(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Reactions.Has(":+1:", 1) {
+		t.Errorf("reaction is not stored")
+	}
+	if stored.Reactions.Has(":smile:", 2) {
+		t.Errorf("reaction added to found message affects the stored one")
+	}
+}
+
 func TestMessageRepoFind(t *testing.T) {
 	t.Parallel()
 
@@ -152,6 +227,102 @@ func TestMessageRepoFindRoomMessagesOrderByLatest(t *testing.T) {
 	}
 }
 
+func TestMessageRepoFindThreadMessagesOrderByLatest(t *testing.T) {
+	t.Parallel()
+
+	const ThreadRoomID = 901
+	parentID, err := messageRepository.Store(context.Background(), domain.Message{RoomID: ThreadRoomID, Content: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"1", "2"} {
+		m := domain.Message{RoomID: ThreadRoomID, ParentID: parentID, Content: content}
+		if _, err := messageRepository.Store(context.Background(), m); err != nil {
+			t.Fatalf("storing error with %v: %v", m, err)
+		}
+	}
+	afterCreated := time.Now().Add(time.Millisecond)
+
+	ms, err := messageRepository.FindThreadMessagesOrderByLatest(context.Background(), parentID, afterCreated, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("different thread messages size, expect: %v, got: %v", 2, len(ms))
+	}
+	if ms[0].CreatedAt.Before(ms[1].CreatedAt) {
+		t.Errorf("different order for the thread messages")
+	}
+
+	// the replies are not contained in the room messages.
+	ms, err = messageRepository.FindRoomMessagesOrderByLatest(context.Background(), ThreadRoomID, afterCreated, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].ID != parentID {
+		t.Errorf("room messages should contain only the root: %v", ms)
+	}
+}
+
+func TestMessageRepoRecountReplies(t *testing.T) {
+	t.Parallel()
+
+	const ThreadRoomID = 902
+	ctx := context.Background()
+	parentID, err := messageRepository.Store(ctx, domain.Message{RoomID: ThreadRoomID, Content: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the parent loaded before replying.
+	stale, err := messageRepository.Find(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replyIDs []uint64
+	for _, content := range []string{"1", "2"} {
+		id, err := messageRepository.Store(ctx, domain.Message{RoomID: ThreadRoomID, ParentID: parentID, Content: content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replyIDs = append(replyIDs, id)
+	}
+	lastReply, err := messageRepository.Find(ctx, replyIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent, err := messageRepository.RecountReplies(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 2 || !parent.LastReplyAt.Equal(lastReply.CreatedAt) {
+		t.Errorf("different thread summary: %v, %v", parent.ReplyCount, parent.LastReplyAt)
+	}
+
+	// storing the stale parent does not overwrite the thread summary.
+	stale.Content = "edited"
+	if _, err := messageRepository.Store(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	if updated, err := messageRepository.Find(ctx, parentID); err != nil || updated.ReplyCount != 2 {
+		t.Errorf("thread summary is overwritten by the stale parent: %v, %v", updated, err)
+	}
+
+	// the deleted reply is not counted.
+	lastReply.Deleted = true
+	if _, err := messageRepository.Store(ctx, lastReply); err != nil {
+		t.Fatal(err)
+	}
+	parent, err = messageRepository.RecountReplies(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 1 || !parent.LastReplyAt.Before(lastReply.CreatedAt) {
+		t.Errorf("different thread summary after deleting the reply: %v, %v", parent.ReplyCount, parent.LastReplyAt)
+	}
+}
+
 func TestMessageRepoRemoveAllByRoomID(t *testing.T) {
 	t.Parallel()
 
@@ -225,3 +396,50 @@ func TestMessageRepoFindUnreadRoomMessages(t *testing.T) {
 		t.Errorf("expect empty result, but got result: %#v", unreads.Msgs)
 	}
 }
+
+func TestMessageRepoFindUserMentions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const (
+		TargetRoomID = 998
+		OtherRoomID  = 997
+		TargetUserID = 10
+	)
+
+	mentioned := domain.NewUserIDSet(TargetUserID)
+	id, _ := messageRepository.Store(ctx, domain.Message{RoomID: TargetRoomID, UserID: 1, Content: "@target", MentionedUserIDs: mentioned})
+	_, _ = messageRepository.Store(ctx, domain.Message{RoomID: TargetRoomID, UserID: 1, Content: "no mention"})
+	_, _ = messageRepository.Store(ctx, domain.Message{RoomID: TargetRoomID, UserID: 1, Content: "", Deleted: true, MentionedUserIDs: mentioned})
+	// the room which the user does not belong to.
+	_, _ = messageRepository.Store(ctx, domain.Message{RoomID: OtherRoomID, UserID: 1, Content: "@target", MentionedUserIDs: mentioned})
+
+	messageRepository.updateByEvent(event.RoomCreated{RoomID: TargetRoomID, MemberIDs: []uint64{1, TargetUserID}})
+
+	mentions, err := messageRepository.FindUserMentions(ctx, TargetUserID, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mentions.UserID != TargetUserID || mentions.UnreadCount != 1 || len(mentions.Mentions) != 1 {
+		t.Fatalf("different user mentions: %#v", mentions)
+	}
+	if m := mentions.Mentions[0]; m.MessageID != id || m.RoomID != TargetRoomID || !m.Unread ||
+		len(m.Mentions) != 1 || m.Mentions[0] != TargetUserID {
+		t.Errorf("different mention: %#v", m)
+	}
+
+	// after read by user, the mention is read.
+	createdMsg, _ := messageRepository.Find(ctx, id)
+	messageRepository.updateByEvent(event.RoomMessagesReadByUser{
+		UserID: TargetUserID, RoomID: TargetRoomID, ReadAt: createdMsg.CreatedAt,
+	})
+
+	mentions, err = messageRepository.FindUserMentions(ctx, TargetUserID, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mentions.UnreadCount != 0 || len(mentions.Mentions) != 1 || mentions.Mentions[0].Unread {
+		t.Errorf("the mention is not read: %#v", mentions)
+	}
+}
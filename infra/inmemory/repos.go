@@ -14,6 +14,8 @@ func OpenRepositories(pubsub chat.Pubsub) *Repositories {
 		MessageRepository: NewMessageRepository(pubsub),
 		RoomRepository:    NewRoomRepository(),
 		EventRepository:   &EventRepository{},
+
+		AttachmentRepository: NewAttachmentRepository(),
 	}
 }
 
@@ -22,6 +24,8 @@ type Repositories struct {
 	*MessageRepository
 	*RoomRepository
 	*EventRepository
+
+	*AttachmentRepository
 }
 
 // run UpdatingService to make the query data is latest.
@@ -43,6 +47,10 @@ func (r Repositories) Rooms() domain.RoomRepository {
 	return r.RoomRepository
 }
 
+func (r Repositories) Attachments() domain.AttachmentRepository {
+	return r.AttachmentRepository
+}
+
 func (r Repositories) Events() event.EventRepository {
 	return r.EventRepository
 }
@@ -186,6 +186,7 @@ func (repo *RoomRepository) FindRoomInfo(ctx context.Context, userID, roomID uin
 		members = append(members, queried.RoomMemberProfile{
 			UserProfile:   createUserProfile(&u),
 			MessageReadAt: readAt,
+			Role:          string(r.RoleOf(id)),
 		})
 	}
 	userMapMu.RUnlock()
@@ -196,5 +197,6 @@ func (repo *RoomRepository) FindRoomInfo(ctx context.Context, userID, roomID uin
 		CreatorID:   r.OwnerID,
 		Members:     members,
 		MembersSize: len(members),
+		PinsSize:    r.Pins.Len(),
 	}, nil
 }
@@ -0,0 +1,445 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// parameters for ranking the messages by BM25.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// parameters for the snippet of the searched message.
+const (
+	// maximum length of the snippet in characters.
+	maxSnippetRunes = 120
+
+	// the number of characters shown before the first matched term.
+	snippetContextRunes = 30
+
+	snippetEllipsis = "..."
+)
+
+// the number of events read at once when the index is rebuilt.
+const rebuildBatchSize = 1000
+
+// MessageIndex is an inverted index for the full-text search of
+// the messages. It implements chat.MessageSearcher interface.
+//
+// The index is kept current by the message events, so
+// UpdatingService must be running to search the latest messages.
+type MessageIndex struct {
+	pubsub chat.Pubsub
+
+	mu sync.RWMutex
+
+	// key: message ID
+	docs map[uint64]indexedMessage
+
+	// key: term, value: term frequency for each message ID.
+	postings map[string]map[uint64]int
+
+	// the number of terms in all of the messages,
+	// which is used to calculate the average length.
+	totalTerms int
+}
+
+type indexedMessage struct {
+	MessageID uint64
+	RoomID    uint64
+	UserID    uint64
+	Content   string
+	CreatedAt time.Time
+
+	// the number of terms in the content.
+	Length int
+}
+
+func NewMessageIndex(pubsub chat.Pubsub) *MessageIndex {
+	return &MessageIndex{
+		pubsub:   pubsub,
+		docs:     make(map[uint64]indexedMessage, 64),
+		postings: make(map[string]map[uint64]int, 256),
+	}
+}
+
+// It runs infinite loop for updating the index by domain events.
+// if context is canceled, the infinite loop quits.
+// It must be called to search the latest messages.
+func (idx *MessageIndex) UpdatingService(ctx context.Context) {
+	evCh := idx.pubsub.Sub(
+		event.TypeMessageCreated,
+		event.TypeMessageEdited,
+		event.TypeMessageDeleted,
+		event.TypeRoomDeleted,
+	)
+	for {
+		select {
+		case ev, ok := <-evCh:
+			if !ok {
+				return
+			}
+			if ev, ok := ev.(event.Event); ok {
+				idx.updateByEvent(ev)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Rebuild indexes the messages from the events stored in the data-store.
+// It should be called before UpdatingService so that the messages
+// created before starting the application can also be searched.
+func (idx *MessageIndex) Rebuild(ctx context.Context, events chat.EventQueryer) error {
+	var after uint64
+	for {
+		records, err := events.FindAllBySequence(ctx, after, rebuildBatchSize)
+		if err != nil {
+			if chat.IsNotFoundError(err) {
+				return nil
+			}
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		for _, r := range records {
+			idx.updateByEvent(r.Event)
+		}
+		after = records[len(records)-1].Seq
+	}
+}
+
+func (idx *MessageIndex) updateByEvent(ev event.Event) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	switch ev := ev.(type) {
+	case event.MessageCreated:
+		idx.add(indexedMessage{
+			MessageID: ev.MessageID,
+			RoomID:    ev.RoomID,
+			UserID:    ev.CreatedBy,
+			Content:   ev.Content,
+			CreatedAt: ev.Timestamp(),
+		})
+
+	case event.MessageEdited:
+		// the message not indexed yet is ignored since its
+		// author and created time are unknown.
+		if doc, ok := idx.docs[ev.MessageID]; ok {
+			doc.Content = ev.Content
+			idx.add(doc)
+		}
+
+	case event.MessageDeleted:
+		idx.remove(ev.MessageID)
+
+	case event.RoomDeleted:
+		for id, doc := range idx.docs {
+			if doc.RoomID == ev.RoomID {
+				idx.remove(id)
+			}
+		}
+	}
+}
+
+// add adds the message into the index, or replaces the message
+// already indexed. It must be called under the lock.
+func (idx *MessageIndex) add(doc indexedMessage) {
+	idx.remove(doc.MessageID)
+
+	tokens := tokenize(doc.Content)
+	doc.Length = len(tokens)
+	for _, tk := range tokens {
+		freqs, ok := idx.postings[tk.Term]
+		if !ok {
+			freqs = make(map[uint64]int, 4)
+			idx.postings[tk.Term] = freqs
+		}
+		freqs[doc.MessageID] += 1
+	}
+	idx.docs[doc.MessageID] = doc
+	idx.totalTerms += doc.Length
+}
+
+// remove removes the message from the index.
+// It must be called under the lock.
+func (idx *MessageIndex) remove(msgID uint64) {
+	doc, ok := idx.docs[msgID]
+	if !ok {
+		return
+	}
+	for _, tk := range tokenize(doc.Content) {
+		freqs := idx.postings[tk.Term]
+		delete(freqs, msgID)
+		if len(freqs) == 0 {
+			delete(idx.postings, tk.Term)
+		}
+	}
+	delete(idx.docs, msgID)
+	idx.totalTerms -= doc.Length
+}
+
+func (idx *MessageIndex) SearchMessages(ctx context.Context, roomIDs []uint64, q action.QuerySearchMessages) ([]queried.MessageSearchResult, error) {
+	terms := uniqueTerms(tokenize(q.Query))
+	if len(terms) == 0 || len(roomIDs) == 0 || q.Limit <= 0 {
+		return []queried.MessageSearchResult{}, nil
+	}
+
+	rooms := make(map[uint64]bool, len(roomIDs))
+	for _, id := range roomIDs {
+		rooms[id] = true
+	}
+	after, before := q.After.Time(), q.Before.Time()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// all of the terms must be matched, so the candidates are
+	// the messages containing the rarest term.
+	sort.Slice(terms, func(i, j int) bool {
+		return len(idx.postings[terms[i]]) < len(idx.postings[terms[j]])
+	})
+
+	type scored struct {
+		doc   indexedMessage
+		score float64
+	}
+	hits := make([]scored, 0, q.Limit)
+	for msgID := range idx.postings[terms[0]] {
+		doc := idx.docs[msgID]
+		if !rooms[doc.RoomID] ||
+			(q.UserID != 0 && doc.UserID != q.UserID) ||
+			(!after.IsZero() && !doc.CreatedAt.After(after)) ||
+			(!before.IsZero() && !doc.CreatedAt.Before(before)) {
+			continue
+		}
+		if score, ok := idx.score(doc, terms); ok {
+			hits = append(hits, scored{doc, score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		if !hits[i].doc.CreatedAt.Equal(hits[j].doc.CreatedAt) {
+			return hits[i].doc.CreatedAt.After(hits[j].doc.CreatedAt)
+		}
+		return hits[i].doc.MessageID > hits[j].doc.MessageID
+	})
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+
+	termSet := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		termSet[term] = true
+	}
+	results := make([]queried.MessageSearchResult, 0, len(hits))
+	for _, h := range hits {
+		results = append(results, queried.MessageSearchResult{
+			MessageID: h.doc.MessageID,
+			Score:     h.score,
+			Snippet:   highlight(h.doc.Content, termSet),
+		})
+	}
+	return results, nil
+}
+
+// score returns the relevance of the message for the terms by BM25.
+// It returns false if the message does not contain all of the terms.
+// It must be called under the lock.
+func (idx *MessageIndex) score(doc indexedMessage, terms []string) (float64, bool) {
+	n := float64(len(idx.docs))
+	avgLength := float64(idx.totalTerms) / n
+
+	var score float64
+	for _, term := range terms {
+		freqs := idx.postings[term]
+		tf, ok := freqs[doc.MessageID]
+		if !ok {
+			return 0, false
+		}
+		df := float64(len(freqs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(doc.Length)/avgLength)
+		score += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+	}
+	return score, true
+}
+
+// token is a term in the text with its position.
+type token struct {
+	Term string
+
+	// byte offsets of the term in the text.
+	Start, End int
+}
+
+// tokenize splits the text into the lower-cased terms.
+// The words separated by spaces or punctuations are the terms.
+// The text written in CJK characters, which has no spaces
+// between the words, is split into the bi-grams.
+func tokenize(text string) []token {
+	tokens := make([]token, 0, 8)
+
+	wordStart := -1
+	cjkStarts := make([]int, 0, 8) // start offsets of the successive CJK characters.
+
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, newToken(text, wordStart, end))
+			wordStart = -1
+		}
+	}
+	flushCJK := func(end int) {
+		switch len(cjkStarts) {
+		case 0:
+			return
+		case 1:
+			tokens = append(tokens, newToken(text, cjkStarts[0], end))
+		default:
+			for i := 0; i+1 < len(cjkStarts); i++ {
+				bigramEnd := end
+				if i+2 < len(cjkStarts) {
+					bigramEnd = cjkStarts[i+2]
+				}
+				tokens = append(tokens, newToken(text, cjkStarts[i], bigramEnd))
+			}
+		}
+		cjkStarts = cjkStarts[:0]
+	}
+
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord(i)
+			cjkStarts = append(cjkStarts, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK(i)
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushCJK(i)
+		}
+	}
+	flushWord(len(text))
+	flushCJK(len(text))
+	return tokens
+}
+
+func newToken(text string, start, end int) token {
+	return token{Term: strings.ToLower(text[start:end]), Start: start, End: end}
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+func uniqueTerms(tokens []token) []string {
+	seen := make(map[string]bool, len(tokens))
+	terms := make([]string, 0, len(tokens))
+	for _, tk := range tokens {
+		if !seen[tk.Term] {
+			seen[tk.Term] = true
+			terms = append(terms, tk.Term)
+		}
+	}
+	return terms
+}
+
+// highlight returns the HTML-escaped snippet of the content around
+// the first matched term. The matched terms are enclosed by <em> tag.
+func highlight(content string, terms map[string]bool) string {
+	// matched ranges of the content, merging the overlapped bi-grams.
+	matched := make([]token, 0, 4)
+	for _, tk := range tokenize(content) {
+		if !terms[tk.Term] {
+			continue
+		}
+		if last := len(matched) - 1; last >= 0 && tk.Start <= matched[last].End {
+			if tk.End > matched[last].End {
+				matched[last].End = tk.End
+			}
+			continue
+		}
+		matched = append(matched, tk)
+	}
+
+	first := 0
+	if len(matched) > 0 {
+		first = matched[0].Start
+	}
+	start, end := snippetWindow(content, first)
+
+	var buf bytes.Buffer
+	if start > 0 {
+		buf.WriteString(snippetEllipsis)
+	}
+	pos := start
+	for _, m := range matched {
+		if m.End <= start {
+			continue
+		}
+		if m.Start >= end {
+			break
+		}
+		if m.Start > pos {
+			buf.WriteString(html.EscapeString(content[pos:m.Start]))
+			pos = m.Start
+		}
+		mEnd := m.End
+		if mEnd > end {
+			mEnd = end
+		}
+		buf.WriteString("<em>")
+		buf.WriteString(html.EscapeString(content[pos:mEnd]))
+		buf.WriteString("</em>")
+		pos = mEnd
+	}
+	buf.WriteString(html.EscapeString(content[pos:end]))
+	if end < len(content) {
+		buf.WriteString(snippetEllipsis)
+	}
+	return buf.String()
+}
+
+// snippetWindow returns byte offsets of the snippet which
+// starts a little before the first matched term.
+func snippetWindow(content string, first int) (start, end int) {
+	if utf8.RuneCountInString(content) <= maxSnippetRunes {
+		return 0, len(content)
+	}
+
+	start = first
+	for n := 0; start > 0 && n < snippetContextRunes; n++ {
+		_, size := utf8.DecodeLastRuneInString(content[:start])
+		start -= size
+	}
+	end = start
+	for n := 0; end < len(content) && n < maxSnippetRunes; n++ {
+		_, size := utf8.DecodeRuneInString(content[end:])
+		end += size
+	}
+	return start, end
+}
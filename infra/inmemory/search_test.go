@@ -0,0 +1,222 @@
+package inmemory
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/domain/event"
+	"github.com/shirasudon/go-chat/infra/pubsub"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	for _, testcase := range []struct {
+		Text  string
+		Terms []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"  go-chat v2 ", []string{"go", "chat", "v2"}},
+		{"こんにちは", []string{"こん", "んに", "にち", "ちは"}},
+		{"猫 and 犬です", []string{"猫", "and", "犬で", "です"}},
+		{"", []string{}},
+	} {
+		tokens := tokenize(testcase.Text)
+		terms := make([]string, 0, len(tokens))
+		for _, tk := range tokens {
+			terms = append(terms, tk.Term)
+			if got := strings.ToLower(testcase.Text[tk.Start:tk.End]); got != tk.Term {
+				t.Errorf("token %q has different position, got %q", tk.Term, got)
+			}
+		}
+		if !reflect.DeepEqual(terms, testcase.Terms) {
+			t.Errorf("tokenize(%q): expect %v, got %v", testcase.Text, testcase.Terms, terms)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a ", 50) + "target" + strings.Repeat(" b", 100)
+
+	for _, testcase := range []struct {
+		Content string
+		Terms   []string
+		Expect  string
+	}{
+		{"Hello world", []string{"hello"}, "<em>Hello</em> world"},
+		{"<b>hello</b> & hello", []string{"hello"}, "&lt;b&gt;<em>hello</em>&lt;/b&gt; &amp; <em>hello</em>"},
+		{"今日は晴れです", []string{"晴れ", "れで"}, "今日は<em>晴れで</em>す"},
+		{long, []string{"target"},
+			"..." + strings.Repeat("a ", 15) + "<em>target</em>" + strings.Repeat(" b", 42) + "..."},
+	} {
+		terms := make(map[string]bool)
+		for _, term := range testcase.Terms {
+			terms[term] = true
+		}
+		if got := highlight(testcase.Content, terms); got != testcase.Expect {
+			t.Errorf("highlight(%q):\nexpect %q\ngot    %q", testcase.Content, testcase.Expect, got)
+		}
+	}
+}
+
+func TestMessageIndexSearchMessages(t *testing.T) {
+	t.Parallel()
+
+	idx := NewMessageIndex(globalPubsub)
+	now := time.Now()
+	for _, ev := range []event.MessageCreated{
+		{MessageID: 1, RoomID: 1, CreatedBy: 1, Content: "go chat server"},
+		{MessageID: 2, RoomID: 1, CreatedBy: 2, Content: "chat chat about the weather"},
+		{MessageID: 3, RoomID: 2, CreatedBy: 1, Content: "chat in another room"},
+		{MessageID: 4, RoomID: 3, CreatedBy: 1, Content: "chat in the room not permitted"},
+		{MessageID: 5, RoomID: 1, CreatedBy: 1, Content: "nothing matched"},
+	} {
+		ev.CreatedAt = now.Add(time.Duration(ev.MessageID) * time.Second)
+		idx.updateByEvent(ev)
+	}
+
+	search := func(q action.QuerySearchMessages) []uint64 {
+		if q.Limit == 0 {
+			q.Limit = 10
+		}
+		res, err := idx.SearchMessages(context.Background(), []uint64{1, 2}, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]uint64, 0, len(res))
+		for _, r := range res {
+			ids = append(ids, r.MessageID)
+		}
+		return ids
+	}
+
+	for _, testcase := range []struct {
+		Query  action.QuerySearchMessages
+		Expect []uint64
+	}{
+		// ordered by the relevance, in which the more frequent and
+		// the shorter message is more relevant.
+		{action.QuerySearchMessages{Query: "Chat"}, []uint64{2, 1, 3}},
+		{action.QuerySearchMessages{Query: "chat go"}, []uint64{1}},
+		{action.QuerySearchMessages{Query: "chat", Limit: 1}, []uint64{2}},
+		{action.QuerySearchMessages{Query: "chat", UserID: 1}, []uint64{1, 3}},
+		{action.QuerySearchMessages{Query: "chat", After: action.Timestamp(now.Add(1 * time.Second))}, []uint64{2, 3}},
+		{action.QuerySearchMessages{Query: "chat", Before: action.Timestamp(now.Add(3 * time.Second))}, []uint64{2, 1}},
+		{action.QuerySearchMessages{Query: "unknown"}, []uint64{}},
+		{action.QuerySearchMessages{Query: "!!"}, []uint64{}},
+	} {
+		if got := search(testcase.Query); !reflect.DeepEqual(got, testcase.Expect) {
+			t.Errorf("search %#v: expect %v, got %v", testcase.Query, testcase.Expect, got)
+		}
+	}
+
+	// edited message is re-indexed.
+	idx.updateByEvent(event.MessageEdited{MessageID: 5, RoomID: 1, Content: "chat matched"})
+	if got := search(action.QuerySearchMessages{Query: "matched chat"}); !reflect.DeepEqual(got, []uint64{5}) {
+		t.Errorf("search the edited message, got %v", got)
+	}
+	if got := search(action.QuerySearchMessages{Query: "nothing"}); len(got) != 0 {
+		t.Errorf("search the content before edited, got %v", got)
+	}
+
+	// deleted message is removed.
+	idx.updateByEvent(event.MessageDeleted{MessageID: 2, RoomID: 1})
+	if got := search(action.QuerySearchMessages{Query: "weather"}); len(got) != 0 {
+		t.Errorf("search the deleted message, got %v", got)
+	}
+
+	// messages in the deleted room are removed.
+	idx.updateByEvent(event.RoomDeleted{RoomID: 1})
+	if got := search(action.QuerySearchMessages{Query: "chat"}); !reflect.DeepEqual(got, []uint64{3}) {
+		t.Errorf("search after the room deleted, got %v", got)
+	}
+	if len(idx.postings["server"]) != 0 || idx.totalTerms != len(tokenize("chat in another room chat in the room not permitted")) {
+		t.Errorf("the index is not cleaned up, postings: %v, total terms: %d", idx.postings, idx.totalTerms)
+	}
+}
+
+type stubEventQueryer struct {
+	chat.EventQueryer
+	records []event.Record
+}
+
+func (q stubEventQueryer) FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+	ret := make([]event.Record, 0, limit)
+	for _, r := range q.records {
+		if r.Seq > after && len(ret) < limit {
+			ret = append(ret, r)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, chat.NewNotFoundError("event not exist after sequence %v", after)
+	}
+	return ret, nil
+}
+
+func TestMessageIndexRebuild(t *testing.T) {
+	t.Parallel()
+
+	events := stubEventQueryer{}
+	for i := 1; i <= rebuildBatchSize+1; i++ {
+		events.records = append(events.records, event.Record{
+			Seq:   uint64(i),
+			Event: event.MessageCreated{MessageID: uint64(i), RoomID: 1, Content: "rebuilt"},
+		})
+	}
+
+	idx := NewMessageIndex(globalPubsub)
+	if err := idx.Rebuild(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	res, err := idx.SearchMessages(context.Background(), []uint64{1}, action.QuerySearchMessages{Query: "rebuilt", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || len(idx.docs) != rebuildBatchSize+1 {
+		t.Errorf("index is not rebuilt, got %v, indexed %d", res, len(idx.docs))
+	}
+}
+
+func TestMessageIndexUpdatingService(t *testing.T) {
+	t.Parallel()
+
+	// with timeout to quit correctly
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the own pubsub is used since the subscription remains after
+	// the service quits, which blocks publishing to the global one.
+	ps := pubsub.New()
+	defer ps.Shutdown()
+
+	idx := NewMessageIndex(ps)
+	go idx.UpdatingService(ctx)
+
+	// wait for subscribing the events.
+	time.Sleep(10 * time.Millisecond)
+
+	const RoomID = 99
+	ps.Pub(event.MessageCreated{MessageID: 99, RoomID: RoomID, Content: "updating service"})
+
+	for {
+		res, err := idx.SearchMessages(ctx, []uint64{RoomID}, action.QuerySearchMessages{Query: "updating", Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) == 1 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("timeout")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
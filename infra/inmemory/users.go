@@ -13,20 +13,23 @@ type UserRepository struct {
 	domain.EmptyTxBeginner
 }
 
+// dummyPassword is the plain password "password" for the dummy users.
+// It is rehashed at the first login.
+var dummyPassword = domain.HashedPassword{
+	Algorithm: domain.PasswordAlgorithmPlain,
+	Hash:      "password",
+}
+
 var (
-	DummyUser = domain.User{
-		ID:        0,
-		Name:      "user",
-		FirstName: "u-",
-		LastName:  "ser",
-		Password:  "password",
-	}
+	// DummyUser2 and DummyUser3 are the users related to
+	// the dummy rooms. They are not stored by default, and
+	// can be stored by SeedUsers() for testing or demonstration.
 	DummyUser2 = domain.User{
 		ID:        2,
 		Name:      "user2",
 		FirstName: "u-",
 		LastName:  "ser",
-		Password:  "password",
+		Password:  dummyPassword,
 		FriendIDs: domain.NewUserIDSet(3),
 	}
 	DummyUser3 = domain.User{
@@ -34,27 +37,18 @@ var (
 		Name:      "user3",
 		FirstName: "u-",
 		LastName:  "ser",
-		Password:  "password",
+		Password:  dummyPassword,
+		FriendIDs: domain.NewUserIDSet(2),
 	}
 
 	userMapMu *sync.RWMutex = new(sync.RWMutex)
 
-	userMap = map[uint64]domain.User{
-		0: DummyUser,
-		2: DummyUser2,
-		3: DummyUser3,
-	}
+	// the users are created by signing up.
+	userMap = map[uint64]domain.User{}
 
-	userNameUniqueMap = map[string]bool{
-		DummyUser.Name:  true,
-		DummyUser2.Name: true,
-		DummyUser3.Name: true,
-	}
+	userNameUniqueMap = map[string]bool{}
 
-	userToUsersMap = map[uint64]map[uint64]bool{
-		// user(id=2) relates with the user(id=3).
-		2: {3: true},
-	}
+	userToUsersMap = map[uint64]map[uint64]bool{}
 )
 
 func errUserNotFound(userID uint64) *chat.NotFoundError {
@@ -63,6 +57,52 @@ func errUserNotFound(userID uint64) *chat.NotFoundError {
 
 var userCounter uint64 = uint64(len(userMap))
 
+// SeedUsers stores the users with their own IDs into the datastore.
+// It is used to prepare the fixed users for testing or demonstration,
+// since the users are created by signing up in the application.
+func (repo *UserRepository) SeedUsers(users ...domain.User) error {
+	userMapMu.Lock()
+	defer userMapMu.Unlock()
+
+	for _, u := range users {
+		if u.NotExist() {
+			return chat.NewInfraError("seed user must have ID")
+		}
+		if _, ok := userMap[u.ID]; ok {
+			return chat.NewInfraError("user(id=%d) already exist", u.ID)
+		}
+		if exist := userNameUniqueMap[u.Name]; exist {
+			return chat.NewInfraError("user name(%v) already exist and not allowed", u.Name)
+		}
+		u.EventHolder = domain.NewEventHolder() // event should not be persisted.
+		userNameUniqueMap[u.Name] = true
+		userMap[u.ID] = copyUser(u)
+		userToUsersMap[u.ID] = friendIDMap(u)
+		if u.ID > userCounter {
+			userCounter = u.ID
+		}
+	}
+	return nil
+}
+
+// copyUser returns the user having its own ID sets, so that
+// modifying the returned user does not affect the stored one.
+func copyUser(u domain.User) domain.User {
+	u.FriendIDs = domain.NewUserIDSet(u.FriendIDs.List()...)
+	u.RequestedFriendIDs = domain.NewUserIDSet(u.RequestedFriendIDs.List()...)
+	u.BlockedUserIDs = domain.NewUserIDSet(u.BlockedUserIDs.List()...)
+	return u
+}
+
+func friendIDMap(u domain.User) map[uint64]bool {
+	friendIDs := u.FriendIDs.List()
+	userIDs := make(map[uint64]bool, len(friendIDs))
+	for _, friendID := range friendIDs {
+		userIDs[friendID] = true
+	}
+	return userIDs
+}
+
 func (repo UserRepository) Store(ctx context.Context, u domain.User) (uint64, error) {
 	u.EventHolder = domain.NewEventHolder() // event should not be persisted.
 	if u.NotExist() {
@@ -82,15 +122,9 @@ func (repo *UserRepository) Create(ctx context.Context, u domain.User) (uint64,
 	userNameUniqueMap[u.Name] = true
 
 	userCounter += 1
-	u.ID = roomCounter
-	userMap[u.ID] = u
-
-	friendIDs := u.FriendIDs.List()
-	userIDs := make(map[uint64]bool, len(friendIDs))
-	for _, friendID := range friendIDs {
-		userIDs[friendID] = true
-	}
-	userToUsersMap[u.ID] = userIDs
+	u.ID = userCounter
+	userMap[u.ID] = copyUser(u)
+	userToUsersMap[u.ID] = friendIDMap(u)
 
 	return u.ID, nil
 }
@@ -99,12 +133,20 @@ func (repo *UserRepository) Update(ctx context.Context, u domain.User) (uint64,
 	userMapMu.Lock()
 	defer userMapMu.Unlock()
 
-	if _, ok := userMap[u.ID]; !ok {
+	old, ok := userMap[u.ID]
+	if !ok {
 		return 0, chat.NewInfraError("user(id=%d) is not in the datastore", u.ID)
 	}
+	if old.Name != u.Name {
+		if exist := userNameUniqueMap[u.Name]; exist {
+			return 0, chat.NewInfraError("user name(%v) already exist and not allowed", u.Name)
+		}
+		delete(userNameUniqueMap, old.Name)
+		userNameUniqueMap[u.Name] = true
+	}
 
 	// update user
-	userMap[u.ID] = u
+	userMap[u.ID] = copyUser(u)
 
 	userIDs := userToUsersMap[u.ID]
 	if userIDs == nil {
@@ -130,31 +172,48 @@ func (repo *UserRepository) Update(ctx context.Context, u domain.User) (uint64,
 	return u.ID, nil
 }
 
+func (repo *UserRepository) Remove(ctx context.Context, u domain.User) error {
+	userMapMu.Lock()
+	defer userMapMu.Unlock()
+
+	stored, ok := userMap[u.ID]
+	if !ok {
+		return errUserNotFound(u.ID)
+	}
+	delete(userMap, u.ID)
+	delete(userNameUniqueMap, stored.Name)
+	delete(userToUsersMap, u.ID)
+	return nil
+}
+
 func (repo UserRepository) Find(ctx context.Context, id uint64) (domain.User, error) {
 	userMapMu.RLock()
 	defer userMapMu.RUnlock()
 
 	u, ok := userMap[id]
 	if ok {
-		return u, nil
+		return copyUser(u), nil
 	}
-	return DummyUser, errUserNotFound(id)
+	return domain.User{}, errUserNotFound(id)
 }
 
-func (repo UserRepository) FindByNameAndPassword(ctx context.Context, name, password string) (*queried.AuthUser, error) {
+func (repo UserRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	userMapMu.RLock()
+	defer userMapMu.RUnlock()
+
+	return userNameUniqueMap[name], nil
+}
+
+func (repo UserRepository) FindByName(ctx context.Context, name string) (domain.User, error) {
 	userMapMu.RLock()
 	defer userMapMu.RUnlock()
 
 	for _, u := range userMap {
-		if name == u.Name && password == u.Password {
-			return &queried.AuthUser{
-				ID:       u.ID,
-				Name:     u.Name,
-				Password: u.Password,
-			}, nil
+		if name == u.Name {
+			return copyUser(u), nil
 		}
 	}
-	return nil, chat.NewNotFoundError("user name (%v) and password are not matched", name)
+	return domain.User{}, chat.NewNotFoundError("user (name=%v) is not found", name)
 }
 
 func createUserProfile(u *domain.User) queried.UserProfile {
@@ -184,26 +243,56 @@ func (repo UserRepository) FindUserRelation(ctx context.Context, userID uint64)
 		}
 	}
 
+	outgoings := make([]queried.UserProfile, 0, 4)
+	for _, id := range user.RequestedFriendIDs.List() {
+		if receiver, ok := userMap[id]; ok {
+			outgoings = append(outgoings, createUserProfile(&receiver))
+		}
+	}
+
+	incomings := make([]queried.UserProfile, 0, 4)
+	for _, sender := range userMap {
+		if sender.HasRequestedFriend(user) {
+			incomings = append(incomings, createUserProfile(&sender))
+		}
+	}
+
 	userMapMu.RUnlock()
 
 	roomMapMu.RLock()
 
 	rooms := make([]queried.UserRoom, 0, 4)
+	talkRooms := make([]queried.UserTalkRoom, 0, 4)
 	for rID, userIDs := range roomToUsersMap {
-		if _, ok := userIDs[userID]; ok {
-			r := roomMap[rID]
-			rooms = append(rooms, queried.UserRoom{
-				RoomID:   rID,
-				RoomName: r.Name,
-			})
+		if _, ok := userIDs[userID]; !ok {
+			continue
+		}
+		r := roomMap[rID]
+		if r.IsTalkRoom {
+			for friendID := range userIDs {
+				if friendID != userID {
+					talkRooms = append(talkRooms, queried.UserTalkRoom{
+						RoomID:   rID,
+						FriendID: friendID,
+					})
+				}
+			}
+			continue
 		}
+		rooms = append(rooms, queried.UserRoom{
+			RoomID:   rID,
+			RoomName: r.Name,
+		})
 	}
 
 	roomMapMu.RUnlock()
 
 	return &queried.UserRelation{
-		UserProfile: createUserProfile(&user),
-		Friends:     friends,
-		Rooms:       rooms,
+		UserProfile:            createUserProfile(&user),
+		Friends:                friends,
+		Rooms:                  rooms,
+		TalkRooms:              talkRooms,
+		IncomingFriendRequests: incomings,
+		OutgoingFriendRequests: outgoings,
 	}, nil
 }
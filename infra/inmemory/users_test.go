@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/domain/event"
 )
@@ -97,10 +98,14 @@ func TestUsersFindUserRelation(t *testing.T) {
 	}
 }
 
-func TestUsersFindByNameAndPassword(t *testing.T) {
+func TestUsersFindByName(t *testing.T) {
 	newUser := domain.User{
-		Name:     "new user",
-		Password: "password",
+		Name: "new user",
+		Password: domain.HashedPassword{
+			Algorithm: domain.PasswordAlgorithmBcrypt,
+			Cost:      domain.MinPasswordHashCost,
+			Hash:      "hash",
+		},
 	}
 
 	id, err := userRepository.Store(context.Background(), newUser)
@@ -109,9 +114,9 @@ func TestUsersFindByNameAndPassword(t *testing.T) {
 	}
 
 	// case1: found
-	res, err := userRepository.FindByNameAndPassword(context.Background(), newUser.Name, newUser.Password)
+	res, err := userRepository.FindByName(context.Background(), newUser.Name)
 	if err != nil {
-		t.Fatalf("can not find user with name and password: %v", err)
+		t.Fatalf("can not find user with name: %v", err)
 	}
 
 	if res.ID != id {
@@ -125,8 +130,8 @@ func TestUsersFindByNameAndPassword(t *testing.T) {
 	}
 
 	// case2: not found
-	if _, err := userRepository.FindByNameAndPassword(context.Background(), "not found", newUser.Password); err == nil {
-		t.Errorf("not found user name and password specified but non erorr")
+	if _, err := userRepository.FindByName(context.Background(), "not found"); !chat.IsNotFoundError(err) {
+		t.Errorf("not found user name specified but no NotFoundError, got: %v", err)
 	}
 }
 
@@ -179,3 +184,108 @@ func TestUserStore(t *testing.T) {
 		t.Errorf("different stored user name, expect: %v, got: %v", SecondName, storedU.Name)
 	}
 }
+
+func TestUsersRemoveAndExistsByName(t *testing.T) {
+	repo := userRepository
+	ctx := context.Background()
+
+	newUser := domain.User{Name: "removed-user", FriendIDs: domain.NewUserIDSet(3)}
+	id, err := repo.Store(ctx, newUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser.ID = id
+
+	if exist, err := repo.ExistsByName(ctx, newUser.Name); err != nil || !exist {
+		t.Fatalf("stored user name does not exist, err: %v", err)
+	}
+
+	if err := repo.Remove(ctx, newUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Find(ctx, id); err == nil {
+		t.Error("removed user is found")
+	}
+	if exist, err := repo.ExistsByName(ctx, newUser.Name); err != nil || exist {
+		t.Errorf("removed user name still exists, err: %v", err)
+	}
+
+	// fail case: already removed.
+	if err := repo.Remove(ctx, newUser); err == nil {
+		t.Error("remove not found user but no error")
+	}
+}
+
+func TestUsersUpdateName(t *testing.T) {
+	repo := userRepository
+	ctx := context.Background()
+
+	u := domain.User{Name: "renamed-user"}
+	id, err := repo.Store(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.ID = id
+
+	// fail case: duplicated name.
+	u.Name = DummyUser2.Name
+	if _, err := repo.Store(ctx, u); err == nil {
+		t.Error("update to duplicated name but no error")
+	}
+
+	u.Name = "renamed-user-2"
+	if _, err := repo.Store(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if exist, _ := repo.ExistsByName(ctx, "renamed-user"); exist {
+		t.Error("old name still exists after renaming")
+	}
+	if exist, _ := repo.ExistsByName(ctx, u.Name); !exist {
+		t.Error("new name does not exist after renaming")
+	}
+}
+
+func TestUsersFindUserRelationFriendRequests(t *testing.T) {
+	ctx := context.Background()
+	receiverID, err := userRepository.Store(ctx, domain.User{Name: "request-receiver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderID, err := userRepository.Store(ctx, domain.User{
+		Name:               "request-sender",
+		RequestedFriendIDs: domain.NewUserIDSet(receiverID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	senderRelation, err := userRepository.FindUserRelation(ctx, senderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := senderRelation.OutgoingFriendRequests; len(got) != 1 || got[0].UserID != receiverID {
+		t.Errorf("different outgoing friend requests: %#v", got)
+	}
+
+	receiverRelation, err := userRepository.FindUserRelation(ctx, receiverID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiverRelation.IncomingFriendRequests; len(got) != 1 || got[0].UserID != senderID {
+		t.Errorf("different incoming friend requests: %#v", got)
+	}
+
+	// the found user does not share the ID set with the stored one.
+	sender, err := userRepository.Find(ctx, senderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.RequestedFriendIDs.Remove(receiverID)
+	stored, err := userRepository.Find(ctx, senderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.RequestedFriendIDs.Has(receiverID) {
+		t.Errorf("modifying the found user affects the stored one")
+	}
+}
@@ -0,0 +1,101 @@
+// package localfs provides the blob store backed by
+// the files on the local file system.
+//
+// Each of the blobs is stored as the file named by its key
+// in the directory. The data is written into the temporary
+// file first, then renamed to the key, so that the partially
+// written blob is never read.
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shirasudon/go-chat/chat"
+)
+
+// BlobStore stores the blobs as the files in the directory.
+// It implements chat.BlobStore.
+//
+// It is safe for the concurrent use.
+type BlobStore struct {
+	dir string
+}
+
+// Open opens the blob store in the directory dir.
+// The directory is created if not exist.
+func Open(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// path returns the file path for the key.
+// The key is validated to prevent accessing outside of the directory.
+func (s *BlobStore) path(key string) (string, error) {
+	if !chat.IsValidBlobKey(key) {
+		return "", fmt.Errorf("localfs: invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+func (s *BlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// the temporary file has the prefix "." which is not used by the keys.
+	tmp, err := ioutil.TempFile(s.dir, "."+key+"-")
+	if err != nil {
+		return chat.NewInfraError("localfs: can not create file for blob(key=%v): %v", key, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after renamed.
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return chat.NewInfraError("localfs: can not write blob(key=%v): %v", key, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return chat.NewInfraError("localfs: can not write blob(key=%v): %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return chat.NewInfraError("localfs: can not write blob(key=%v): %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return chat.NewInfraError("localfs: can not store blob(key=%v): %v", key, err)
+	}
+	return nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, chat.NewNotFoundError("blob (key=%v) is not found", key)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("localfs: can not open blob(key=%v): %v", key, err)
+	}
+	return f, nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return chat.NewInfraError("localfs: can not delete blob(key=%v): %v", key, err)
+	}
+	return nil
+}
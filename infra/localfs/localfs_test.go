@@ -0,0 +1,77 @@
+package localfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shirasudon/go-chat/chat"
+)
+
+func TestBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the directory is created if not exist.
+	store, err := Open(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// case1: put and get
+	if err := store.Put(ctx, "key-1", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "key-1", strings.NewReader("overwritten")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Get(ctx, "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "overwritten" {
+		t.Errorf("different blob data, got %q", data)
+	}
+
+	// no temporary files are left.
+	files, err := ioutil.ReadDir(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expect only one file, got %d files", len(files))
+	}
+
+	// case2: delete
+	if err := store.Delete(ctx, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "key-1"); !chat.IsNotFoundError(err) {
+		t.Errorf("get deleted blob, expect NotFoundError but got %v", err)
+	}
+	if err := store.Delete(ctx, "key-1"); err != nil {
+		t.Errorf("delete not existing blob, got error %v", err)
+	}
+
+	// case3: invalid keys
+	for _, key := range []string{"", "../key", "a/b", ".hidden"} {
+		if err := store.Put(ctx, key, strings.NewReader("data")); err == nil {
+			t.Errorf("put blob with invalid key %q, but no error", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil {
+			t.Errorf("get blob with invalid key %q, but no error", key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+// AttachmentRepository manages access to attachments in the database.
+// It implements domain.AttachmentRepository.
+type AttachmentRepository struct {
+	txBeginner
+}
+
+func errAttachmentNotFound(id uint64) *chat.NotFoundError {
+	return chat.NewNotFoundError("attachment (id=%v) is not found", id)
+}
+
+const attachmentColumns = `id, user_id, room_id, message_id, file_name, content_type, size, blob_key, thumbnail_key, created_at`
+
+func (repo *AttachmentRepository) Find(ctx context.Context, id uint64) (domain.Attachment, error) {
+	var a domain.Attachment
+	err := repo.conn(ctx).QueryRowContext(ctx,
+		`SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id,
+	).Scan(&a.ID, &a.UserID, &a.RoomID, &a.MessageID, &a.FileName, &a.ContentType, &a.Size,
+		&a.BlobKey, &a.ThumbnailKey, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return domain.Attachment{}, errAttachmentNotFound(id)
+	}
+	if err != nil {
+		return domain.Attachment{}, chat.NewInfraError("can not find attachment(id=%d): %v", id, err)
+	}
+	return a, nil
+}
+
+func (repo *AttachmentRepository) Store(ctx context.Context, a domain.Attachment) (uint64, error) {
+	if a.NotExist() {
+		return repo.Create(ctx, a)
+	} else {
+		return repo.Update(ctx, a)
+	}
+}
+
+func (repo *AttachmentRepository) Create(ctx context.Context, a domain.Attachment) (uint64, error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	res, err := repo.conn(ctx).ExecContext(ctx, `
+INSERT INTO attachments (user_id, room_id, message_id, file_name, content_type, size, blob_key, thumbnail_key, created_at)
+ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.UserID, a.RoomID, a.MessageID, a.FileName, a.ContentType, a.Size, a.BlobKey, a.ThumbnailKey, a.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not create attachment(file_name=%v): %v", a.FileName, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, chat.NewInfraError("can not create attachment(file_name=%v): %v", a.FileName, err)
+	}
+	return uint64(id), nil
+}
+
+// Update updates the message which the attachment is attached to.
+// The other fields are not changed after the attachment is created.
+func (repo *AttachmentRepository) Update(ctx context.Context, a domain.Attachment) (uint64, error) {
+	res, err := repo.conn(ctx).ExecContext(ctx,
+		`UPDATE attachments SET message_id = ? WHERE id = ?`, a.MessageID, a.ID,
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not update attachment(id=%d): %v", a.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, chat.NewInfraError("attachment(id=%d) is not in the datastore", a.ID)
+	}
+	return a.ID, nil
+}
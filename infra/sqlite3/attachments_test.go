@@ -0,0 +1,96 @@
+package sqlite3
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+func TestAttachmentsStore(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	attachmentRepo := repos.AttachmentRepository
+	ctx := context.Background()
+
+	// case1: create
+	a := domain.Attachment{
+		UserID: 1, RoomID: 2, FileName: "photo.png", ContentType: "image/png", Size: 10,
+		BlobKey: "blob", ThumbnailKey: "blob-thumbnail", CreatedAt: time.Now(),
+	}
+	id, err := attachmentRepo.Store(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := attachmentRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.ID = id
+	a.CreatedAt = stored.CreatedAt
+	if stored != a {
+		t.Errorf("different stored attachment, expect: %#v, got: %#v", a, stored)
+	}
+
+	// case2: attach to the message
+	stored.MessageID = 3
+	if _, err := attachmentRepo.Store(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := attachmentRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.MessageID != 3 {
+		t.Errorf("the attachment is not attached, got: %#v", updated)
+	}
+
+	// case3: not found
+	if _, err := attachmentRepo.Find(ctx, id+1); !chat.IsNotFoundError(err) {
+		t.Errorf("find not existing attachment, expect NotFoundError but got: %v", err)
+	}
+	if _, err := attachmentRepo.Store(ctx, domain.Attachment{ID: id + 1}); err == nil {
+		t.Errorf("update attachment not in the datastore, but no error")
+	}
+}
+
+func TestMessagesFindAttachmentIDs(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	ctx := context.Background()
+
+	msgID, err := repos.MessageRepository.Store(ctx, domain.Message{Content: "files", UserID: 1, RoomID: 2, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expect []uint64
+	for _, key := range []string{"a", "b"} {
+		id, err := repos.AttachmentRepository.Store(ctx, domain.Attachment{
+			UserID: 1, RoomID: 2, MessageID: msgID, FileName: key, ContentType: "text/plain", BlobKey: key,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect = append(expect, id)
+	}
+
+	m, err := repos.MessageRepository.Find(ctx, msgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.AttachmentIDs, expect) {
+		t.Errorf("different attachment IDs, expect: %v, got: %v", expect, m.AttachmentIDs)
+	}
+
+	msgs, err := repos.MessageRepository.FindRoomMessagesOrderByLatest(ctx, 2, time.Now().Add(time.Second), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || !reflect.DeepEqual(msgs[0].AttachmentIDs, expect) {
+		t.Errorf("different attachment IDs of the room messages, expect: %v, got: %v", expect, msgs)
+	}
+}
@@ -0,0 +1,154 @@
+package sqlite3
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// EventRepository manages access to events in the database.
+// It implements event.EventRepository and chat.EventQueryer.
+// The events are stored as JSON with its type, and are
+// never updated or deleted.
+type EventRepository struct {
+	txBeginner
+}
+
+func (repo *EventRepository) Store(ctx context.Context, evs ...event.Event) ([]uint64, error) {
+	conn := repo.conn(ctx)
+
+	ids := make([]uint64, 0, len(evs))
+	for _, ev := range evs {
+		if ev.Type() == event.TypeNone || ev.Type() == event.TypeExternal {
+			return nil, chat.NewInfraError("event type(%v) can not be stored", event.TypeString(ev))
+		}
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return nil, chat.NewInfraError("can not encode event(%v): %v", ev.Type(), err)
+		}
+
+		res, err := conn.ExecContext(ctx,
+			`INSERT INTO events (type, stream_id, created_at, data) VALUES (?, ?, ?, ?)`,
+			int64(ev.Type()), int64(ev.StreamID()), ev.Timestamp().UTC(), string(data),
+		)
+		if err != nil {
+			return nil, chat.NewInfraError("can not store event(%v): %v", ev.Type(), err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, chat.NewInfraError("can not store event(%v): %v", ev.Type(), err)
+		}
+		ids = append(ids, uint64(id))
+	}
+	return ids, nil
+}
+
+func (repo *EventRepository) FindAllByTimeCursor(ctx context.Context, after time.Time, limit int) ([]event.Event, error) {
+	if limit <= 0 {
+		return []event.Event{}, nil
+	}
+
+	records, err := repo.selectRecords(ctx, `
+SELECT id, type, data FROM events
+ WHERE created_at > ? ORDER BY id LIMIT ?`, after.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist after %v", after)
+	}
+	return eventsOf(records), nil
+}
+
+func (repo *EventRepository) FindAllByStreamID(ctx context.Context, streamID event.StreamID, after time.Time, limit int) ([]event.Event, error) {
+	if limit <= 0 {
+		return []event.Event{}, nil
+	}
+
+	records, err := repo.selectRecords(ctx, `
+SELECT id, type, data FROM events
+ WHERE stream_id = ? AND created_at > ? ORDER BY id LIMIT ?`, int64(streamID), after.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist for stream(%v) after %v", streamID, after)
+	}
+	return eventsOf(records), nil
+}
+
+func (repo *EventRepository) FindAllBySequence(ctx context.Context, after uint64, limit int) ([]event.Record, error) {
+	if limit <= 0 {
+		return []event.Record{}, nil
+	}
+
+	records, err := repo.selectRecords(ctx, `
+SELECT id, type, data FROM events
+ WHERE id > ? ORDER BY id LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist after sequence %v", after)
+	}
+	return records, nil
+}
+
+func (repo *EventRepository) FindAllByStreamIDSequence(ctx context.Context, streamID event.StreamID, after uint64, limit int) ([]event.Record, error) {
+	if limit <= 0 {
+		return []event.Record{}, nil
+	}
+
+	records, err := repo.selectRecords(ctx, `
+SELECT id, type, data FROM events
+ WHERE stream_id = ? AND id > ? ORDER BY id LIMIT ?`, int64(streamID), after, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, chat.NewNotFoundError("event not exist for stream(%v) after sequence %v", streamID, after)
+	}
+	return records, nil
+}
+
+func eventsOf(records []event.Record) []event.Event {
+	evs := make([]event.Event, 0, len(records))
+	for _, r := range records {
+		evs = append(evs, r.Event)
+	}
+	return evs
+}
+
+// selectRecords returns the events with its ID as the sequence number.
+// The query must select id, type and data columns.
+func (repo *EventRepository) selectRecords(ctx context.Context, query string, args ...interface{}) ([]event.Record, error) {
+	rows, err := repo.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find events: %v", err)
+	}
+	defer rows.Close()
+
+	records := make([]event.Record, 0, 8)
+	for rows.Next() {
+		var (
+			id     uint64
+			evType int64
+			data   string
+		)
+		if err := rows.Scan(&id, &evType, &data); err != nil {
+			return nil, chat.NewInfraError("can not find events: %v", err)
+		}
+		ev, err := event.Decode(event.Type(evType), []byte(data))
+		if err != nil {
+			return nil, chat.NewInfraError("can not decode event: %v", err)
+		}
+		records = append(records, event.Record{Seq: id, Event: ev})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, chat.NewInfraError("can not find events: %v", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,149 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestEventsStore(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	eventRepo := repos.EventRepository
+	ctx := context.Background()
+
+	// case1: store multiple events
+	ev1 := event.UserCreated{Name: "user"}
+	ev1.Occurs()
+	ev2 := event.RoomCreated{RoomID: 1}
+	ev2.Occurs()
+	ids, err := eventRepo.Store(ctx, ev1, ev2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("different stored event ids: %v", ids)
+	}
+
+	// case2: not storable event type
+	if _, err := eventRepo.Store(ctx, event.ExternalEventEmbd{}); err == nil {
+		t.Errorf("store external event, but no error")
+	}
+}
+
+func TestEventsFindAllByTimeCursor(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	eventRepo := repos.EventRepository
+	ctx := context.Background()
+
+	base := time.Now()
+	ev1 := event.UserCreated{Name: "user"}
+	ev1.CreatedAt = base
+	ev2 := event.MessageCreated{MessageID: 1, Content: "hello"}
+	ev2.CreatedAt = base.Add(time.Second)
+	if _, err := eventRepo.Store(ctx, ev1, ev2); err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: find all
+	evs, err := eventRepo.FindAllByTimeCursor(ctx, base.Add(-time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("different event size, expect: %v, got: %v", 2, len(evs))
+	}
+	if got, ok := evs[0].(event.UserCreated); !ok || got.Name != ev1.Name || !got.CreatedAt.Equal(ev1.CreatedAt) {
+		t.Errorf("different first event, expect: %#v, got: %#v", ev1, evs[0])
+	}
+	if got, ok := evs[1].(event.MessageCreated); !ok || got.Content != ev2.Content {
+		t.Errorf("different second event, expect: %#v, got: %#v", ev2, evs[1])
+	}
+
+	// case2: after the cursor with limit
+	evs, err = eventRepo.FindAllByTimeCursor(ctx, base, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Type() != event.TypeMessageCreated {
+		t.Errorf("different events after cursor: %#v", evs)
+	}
+
+	// case3: not found
+	if _, err := eventRepo.FindAllByTimeCursor(ctx, base.Add(time.Hour), 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find events after latest one, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestEventsFindAllByStreamID(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	eventRepo := repos.EventRepository
+	ctx := context.Background()
+
+	ev1 := event.UserCreated{Name: "user"}
+	ev1.Occurs()
+	ev2 := event.RoomCreated{RoomID: 1}
+	ev2.Occurs()
+	if _, err := eventRepo.Store(ctx, ev1, ev2); err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: found
+	evs, err := eventRepo.FindAllByStreamID(ctx, event.RoomStream, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Type() != event.TypeRoomCreated {
+		t.Errorf("different events for the room stream: %#v", evs)
+	}
+
+	// case2: not found
+	if _, err := eventRepo.FindAllByStreamID(ctx, event.MessageStream, time.Time{}, 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find events for empty stream, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestEventsFindAllBySequence(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	eventRepo := repos.EventRepository
+	ctx := context.Background()
+
+	// events having same timestamp are distinguished by sequence.
+	ev1 := event.UserCreated{Name: "user"}
+	ev1.Occurs()
+	ev2 := event.RoomCreated{RoomID: 1}
+	ev2.CreatedAt = ev1.CreatedAt
+	ids, err := eventRepo.Store(ctx, ev1, ev2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: found
+	records, err := eventRepo.FindAllBySequence(ctx, ids[0], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Seq != ids[1] || records[0].Event.Type() != event.TypeRoomCreated {
+		t.Errorf("different records after sequence %v: %#v", ids[0], records)
+	}
+
+	// case2: found by stream ID
+	records, err = eventRepo.FindAllByStreamIDSequence(ctx, event.UserStream, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Seq != ids[0] {
+		t.Errorf("different records for user stream: %#v", records)
+	}
+
+	// case3: not found
+	if _, err := eventRepo.FindAllBySequence(ctx, ids[1], 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find events after latest one, expect NotFoundError but got: %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+// MessageRepository manages access to messages in the database.
+// It implements domain.MessageRepository and chat.MessageQueryer.
+type MessageRepository struct {
+	txBeginner
+}
+
+func errMsgNotFound(msgID uint64) *chat.NotFoundError {
+	return chat.NewNotFoundError("message (id=%v) is not found", msgID)
+}
+
+const messageColumns = `id, room_id, user_id, content, created_at, edited_at, deleted, parent_id, reply_count, last_reply_at`
+
+func scanMessage(scan func(dest ...interface{}) error) (domain.Message, error) {
+	m := domain.Message{EventHolder: domain.NewEventHolder()}
+	err := scan(&m.ID, &m.RoomID, &m.UserID, &m.Content, &m.CreatedAt, &m.EditedAt, &m.Deleted,
+		&m.ParentID, &m.ReplyCount, &m.LastReplyAt)
+	return m, err
+}
+
+func (repo *MessageRepository) Find(ctx context.Context, msgID uint64) (domain.Message, error) {
+	conn := repo.conn(ctx)
+	row := conn.QueryRowContext(ctx, `SELECT `+messageColumns+` FROM messages WHERE id = ?`, msgID)
+	m, err := scanMessage(row.Scan)
+	if err == sql.ErrNoRows {
+		return domain.Message{}, errMsgNotFound(msgID)
+	}
+	if err != nil {
+		return domain.Message{}, chat.NewInfraError("can not find message(id=%d): %v", msgID, err)
+	}
+	if m.Reactions, err = findReactions(ctx, conn, m.ID); err != nil {
+		return domain.Message{}, chat.NewInfraError("can not find reactions of message(id=%d): %v", msgID, err)
+	}
+	if m.MentionedUserIDs, err = findMentions(ctx, conn, m.ID); err != nil {
+		return domain.Message{}, chat.NewInfraError("can not find mentions of message(id=%d): %v", msgID, err)
+	}
+	if m.AttachmentIDs, err = findAttachmentIDs(ctx, conn, m.ID); err != nil {
+		return domain.Message{}, chat.NewInfraError("can not find attachments of message(id=%d): %v", msgID, err)
+	}
+	return m, nil
+}
+
+// findReactions finds the reactions to the message.
+func findReactions(ctx context.Context, conn queryer, msgID uint64) (domain.ReactionSet, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT emoji, user_id FROM message_reactions WHERE message_id = ?`, msgID)
+	if err != nil {
+		return domain.ReactionSet{}, err
+	}
+	defer rows.Close()
+
+	reactions := domain.NewReactionSet()
+	for rows.Next() {
+		var (
+			emoji  string
+			userID uint64
+		)
+		if err := rows.Scan(&emoji, &userID); err != nil {
+			return domain.ReactionSet{}, err
+		}
+		reactions.Add(emoji, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.ReactionSet{}, err
+	}
+	return reactions, nil
+}
+
+// findMessagesReactions finds the reactions to each of the messages.
+// It must be called after the rows for the messages are closed.
+func findMessagesReactions(ctx context.Context, conn queryer, msgs []domain.Message) error {
+	for i := range msgs {
+		reactions, err := findReactions(ctx, conn, msgs[i].ID)
+		if err != nil {
+			return err
+		}
+		msgs[i].Reactions = reactions
+	}
+	return nil
+}
+
+// findMentions finds the IDs of the users mentioned in the message.
+func findMentions(ctx context.Context, conn queryer, msgID uint64) (domain.UserIDSet, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT user_id FROM message_mentions WHERE message_id = ?`, msgID)
+	if err != nil {
+		return domain.UserIDSet{}, err
+	}
+	defer rows.Close()
+
+	mentioned := domain.NewUserIDSet()
+	for rows.Next() {
+		var userID uint64
+		if err := rows.Scan(&userID); err != nil {
+			return domain.UserIDSet{}, err
+		}
+		mentioned.Add(userID)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.UserIDSet{}, err
+	}
+	return mentioned, nil
+}
+
+// findAttachmentIDs finds the IDs of the attachments on the message
+// ordered by the ID. It returns nil if the message has no attachments.
+func findAttachmentIDs(ctx context.Context, conn queryer, msgID uint64) ([]uint64, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT id FROM attachments WHERE message_id = ? ORDER BY id`, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// findMessagesRelations finds the reactions to, the mentions in and
+// the attachments on each of the messages.
+// It must be called after the rows for the messages are closed.
+func findMessagesRelations(ctx context.Context, conn queryer, msgs []domain.Message) error {
+	if err := findMessagesReactions(ctx, conn, msgs); err != nil {
+		return err
+	}
+	for i := range msgs {
+		mentioned, err := findMentions(ctx, conn, msgs[i].ID)
+		if err != nil {
+			return err
+		}
+		msgs[i].MentionedUserIDs = mentioned
+
+		attachmentIDs, err := findAttachmentIDs(ctx, conn, msgs[i].ID)
+		if err != nil {
+			return err
+		}
+		msgs[i].AttachmentIDs = attachmentIDs
+	}
+	return nil
+}
+
+// insertMentions inserts all of the mentions in the message.
+// The mentions are not changed after the message is created.
+func insertMentions(ctx context.Context, conn queryer, m domain.Message) error {
+	for _, userID := range m.MentionedUserIDs.List() {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO message_mentions (message_id, user_id) VALUES (?, ?)`, m.ID, userID)
+		if err != nil {
+			return chat.NewInfraError("can not store mention of message(id=%d): %v", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// insertReactions inserts all of the reactions to the message.
+func insertReactions(ctx context.Context, conn queryer, m domain.Message) error {
+	for _, emoji := range m.Reactions.Emojis() {
+		for _, userID := range m.Reactions.UserIDs(emoji) {
+			_, err := conn.ExecContext(ctx,
+				`INSERT INTO message_reactions (message_id, emoji, user_id) VALUES (?, ?, ?)`,
+				m.ID, emoji, userID,
+			)
+			if err != nil {
+				return chat.NewInfraError("can not store reaction(%v) of message(id=%d): %v", emoji, m.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (repo *MessageRepository) Store(ctx context.Context, m domain.Message) (uint64, error) {
+	if m.NotExist() {
+		return repo.Create(ctx, m)
+	} else {
+		return repo.Update(ctx, m)
+	}
+}
+
+func (repo *MessageRepository) Create(ctx context.Context, m domain.Message) (uint64, error) {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	conn := repo.conn(ctx)
+	res, err := conn.ExecContext(ctx,
+		`INSERT INTO messages (room_id, user_id, content, created_at, edited_at, deleted, parent_id, reply_count, last_reply_at)
+ VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.RoomID, m.UserID, m.Content, m.CreatedAt.UTC(), m.EditedAt.UTC(), m.Deleted,
+		m.ParentID, m.ReplyCount, m.LastReplyAt.UTC(),
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not create message: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, chat.NewInfraError("can not create message: %v", err)
+	}
+	m.ID = uint64(id)
+	if err := insertReactions(ctx, conn, m); err != nil {
+		return 0, err
+	}
+	if err := insertMentions(ctx, conn, m); err != nil {
+		return 0, err
+	}
+	return m.ID, nil
+}
+
+func (repo *MessageRepository) Update(ctx context.Context, m domain.Message) (uint64, error) {
+	conn := repo.conn(ctx)
+
+	// created time, room, author and parent are not changed by update.
+	// the thread summary is updated by RecountReplies only.
+	res, err := conn.ExecContext(ctx,
+		`UPDATE messages SET content = ?, edited_at = ?, deleted = ? WHERE id = ?`,
+		m.Content, m.EditedAt.UTC(), m.Deleted, m.ID,
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not update message(id=%d): %v", m.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, chat.NewInfraError("message(id=%d) is not in the datastore", m.ID)
+	}
+
+	if _, err := conn.ExecContext(ctx, `DELETE FROM message_reactions WHERE message_id = ?`, m.ID); err != nil {
+		return 0, chat.NewInfraError("can not update reactions of message(id=%d): %v", m.ID, err)
+	}
+	if err := insertReactions(ctx, conn, m); err != nil {
+		return 0, err
+	}
+	return m.ID, nil
+}
+
+func (repo *MessageRepository) RecountReplies(ctx context.Context, parentID uint64) (domain.Message, error) {
+	conn := repo.conn(ctx)
+
+	// counting and updating by a statement is atomic,
+	// so that the concurrent replies are not lost.
+	res, err := conn.ExecContext(ctx, `
+UPDATE messages SET
+  reply_count = (SELECT COUNT(*) FROM messages AS r WHERE r.parent_id = ? AND r.deleted = 0),
+  last_reply_at = COALESCE(
+    (SELECT MAX(r.created_at) FROM messages AS r WHERE r.parent_id = ? AND r.deleted = 0),
+    '0001-01-01 00:00:00+00:00')
+WHERE id = ?`, parentID, parentID, parentID)
+	if err != nil {
+		return domain.Message{}, chat.NewInfraError("can not recount replies of message(id=%d): %v", parentID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return domain.Message{}, chat.NewInfraError("message(id=%d) is not in the datastore", parentID)
+	}
+	return repo.Find(ctx, parentID)
+}
+
+func (repo *MessageRepository) RemoveAllByRoomID(ctx context.Context, roomID uint64) error {
+	conn := repo.conn(ctx)
+	_, err := conn.ExecContext(ctx,
+		`DELETE FROM message_reactions WHERE message_id IN (SELECT id FROM messages WHERE room_id = ?)`, roomID)
+	if err != nil {
+		return chat.NewInfraError("can not remove reactions of room(id=%d): %v", roomID, err)
+	}
+	_, err = conn.ExecContext(ctx,
+		`DELETE FROM message_mentions WHERE message_id IN (SELECT id FROM messages WHERE room_id = ?)`, roomID)
+	if err != nil {
+		return chat.NewInfraError("can not remove mentions of room(id=%d): %v", roomID, err)
+	}
+	_, err = conn.ExecContext(ctx, `DELETE FROM messages WHERE room_id = ?`, roomID)
+	if err != nil {
+		return chat.NewInfraError("can not remove messages of room(id=%d): %v", roomID, err)
+	}
+	return nil
+}
+
+func (repo *MessageRepository) FindRoomMessagesOrderByLatest(ctx context.Context, roomID uint64, before time.Time, limit int) ([]domain.Message, error) {
+	if limit <= 0 {
+		return []domain.Message{}, nil
+	}
+
+	// the replies are shown in the threads.
+	conn := repo.conn(ctx)
+	rows, err := conn.QueryContext(ctx, `
+SELECT `+messageColumns+` FROM messages
+ WHERE room_id = ? AND parent_id = 0 AND created_at < ?
+ ORDER BY created_at DESC, id DESC LIMIT ?`, roomID, before.UTC(), limit)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find messages of room(id=%d): %v", roomID, err)
+	}
+	msgs, err := scanMessages(rows, limit)
+	if err == nil {
+		err = findMessagesRelations(ctx, conn, msgs)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find messages of room(id=%d): %v", roomID, err)
+	}
+	return msgs, nil
+}
+
+func (repo *MessageRepository) FindThreadMessagesOrderByLatest(ctx context.Context, parentID uint64, before time.Time, limit int) ([]domain.Message, error) {
+	if limit <= 0 {
+		return []domain.Message{}, nil
+	}
+
+	conn := repo.conn(ctx)
+	rows, err := conn.QueryContext(ctx, `
+SELECT `+messageColumns+` FROM messages
+ WHERE parent_id = ? AND created_at < ?
+ ORDER BY created_at DESC, id DESC LIMIT ?`, parentID, before.UTC(), limit)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find messages of thread(id=%d): %v", parentID, err)
+	}
+	msgs, err := scanMessages(rows, limit)
+	if err == nil {
+		err = findMessagesRelations(ctx, conn, msgs)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find messages of thread(id=%d): %v", parentID, err)
+	}
+	return msgs, nil
+}
+
+// scanMessages scans all of the rows as the messages, then closes the rows.
+func scanMessages(rows *sql.Rows, capacity int) ([]domain.Message, error) {
+	defer rows.Close()
+
+	msgs := make([]domain.Message, 0, capacity)
+	for rows.Next() {
+		m, err := scanMessage(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (repo *MessageRepository) FindUnreadRoomMessages(ctx context.Context, userID, roomID uint64, limit int) (*queried.UnreadRoomMessages, error) {
+	conn := repo.conn(ctx)
+
+	var readAt time.Time
+	err := conn.QueryRowContext(ctx,
+		`SELECT read_at FROM room_members WHERE room_id = ? AND user_id = ?`, roomID, userID,
+	).Scan(&readAt)
+	if err == sql.ErrNoRows {
+		// missing read time indicates user not exist in the room
+		return nil, chat.NewNotFoundError("user (id=%v) has no unread messsages for the room (id=%v)", userID, roomID)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find read time of user(id=%d) for room(id=%d): %v", userID, roomID, err)
+	}
+
+	if limit <= 0 {
+		ret := queried.EmptyUnreadRoomMessages
+		ret.RoomID = roomID
+		return &ret, nil
+	}
+
+	// deleted messages are not need to be read.
+	rows, err := conn.QueryContext(ctx, `
+SELECT `+messageColumns+` FROM messages
+ WHERE room_id = ? AND created_at > ? AND deleted = 0
+ ORDER BY created_at DESC, id DESC LIMIT ?`, roomID, readAt.UTC(), limit)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find unread messages of room(id=%d): %v", roomID, err)
+	}
+	msgs, err := scanMessages(rows, limit)
+	if err == nil {
+		err = findMessagesRelations(ctx, conn, msgs)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find unread messages of room(id=%d): %v", roomID, err)
+	}
+
+	unreadMsgs := make([]queried.Message, 0, len(msgs))
+	for _, m := range msgs {
+		unreadMsgs = append(unreadMsgs, queried.Message{
+			MessageID:   m.ID,
+			UserID:      m.UserID,
+			Content:     m.Content,
+			CreatedAt:   m.CreatedAt,
+			EditedAt:    m.EditedAt,
+			ParentID:    m.ParentID,
+			ReplyCount:  m.ReplyCount,
+			LastReplyAt: m.LastReplyAt,
+			Reactions:   chat.NewQueriedReactions(m.Reactions, userID),
+			Attachments: m.AttachmentIDs,
+		})
+	}
+
+	return &queried.UnreadRoomMessages{
+		RoomID:   roomID,
+		Msgs:     unreadMsgs,
+		MsgsSize: len(unreadMsgs),
+	}, nil
+}
+
+func (repo *MessageRepository) FindUserMentions(ctx context.Context, userID uint64, before time.Time, limit int) (*queried.UserMentions, error) {
+	conn := repo.conn(ctx)
+
+	ret := queried.EmptyUserMentions
+	ret.UserID = userID
+
+	// the mentions in the rooms which user no longer belongs to are not contained.
+	err := conn.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM messages
+ INNER JOIN message_mentions AS mm ON mm.message_id = messages.id
+ INNER JOIN room_members AS rm ON rm.room_id = messages.room_id AND rm.user_id = mm.user_id
+ WHERE mm.user_id = ? AND messages.deleted = 0 AND messages.created_at > rm.read_at`, userID,
+	).Scan(&ret.UnreadCount)
+	if err != nil {
+		return nil, chat.NewInfraError("can not count unread mentions of user(id=%d): %v", userID, err)
+	}
+
+	if limit <= 0 {
+		return &ret, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+SELECT `+messageColumns+`,
+ (SELECT rm.read_at FROM room_members AS rm WHERE rm.room_id = messages.room_id AND rm.user_id = ?)
+ FROM messages
+ WHERE id IN (SELECT message_id FROM message_mentions WHERE user_id = ?)
+   AND room_id IN (SELECT room_id FROM room_members WHERE user_id = ?)
+   AND deleted = 0 AND created_at < ?
+ ORDER BY created_at DESC, id DESC LIMIT ?`, userID, userID, userID, before.UTC(), limit)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find mentions of user(id=%d): %v", userID, err)
+	}
+
+	var (
+		msgs    = make([]domain.Message, 0, limit)
+		readAts = make([]time.Time, 0, limit)
+	)
+	err = func() error {
+		defer rows.Close()
+		for rows.Next() {
+			var readAt time.Time
+			m, err := scanMessage(func(dest ...interface{}) error {
+				return rows.Scan(append(dest, &readAt)...)
+			})
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, m)
+			readAts = append(readAts, readAt)
+		}
+		return rows.Err()
+	}()
+	if err == nil {
+		err = findMessagesRelations(ctx, conn, msgs)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find mentions of user(id=%d): %v", userID, err)
+	}
+
+	ret.Mentions = make([]queried.Mention, 0, len(msgs))
+	for i, m := range msgs {
+		ret.Mentions = append(ret.Mentions, queried.Mention{
+			Message: queried.Message{
+				MessageID:   m.ID,
+				UserID:      m.UserID,
+				Content:     m.Content,
+				CreatedAt:   m.CreatedAt,
+				EditedAt:    m.EditedAt,
+				ParentID:    m.ParentID,
+				ReplyCount:  m.ReplyCount,
+				LastReplyAt: m.LastReplyAt,
+				Reactions:   chat.NewQueriedReactions(m.Reactions, userID),
+				Mentions:    m.MentionedIDs(),
+				Attachments: m.AttachmentIDs,
+			},
+			RoomID: m.RoomID,
+			Unread: m.CreatedAt.After(readAts[i]),
+		})
+	}
+	return &ret, nil
+}
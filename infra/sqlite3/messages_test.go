@@ -0,0 +1,387 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+func TestMessagesStore(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	msgRepo := repos.MessageRepository
+	ctx := context.Background()
+
+	// case1: create
+	m := domain.Message{Content: "hello", UserID: 1, RoomID: 2, CreatedAt: time.Now()}
+	id, err := msgRepo.Store(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := msgRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Content != m.Content || stored.UserID != m.UserID || stored.RoomID != m.RoomID {
+		t.Errorf("different stored message: %#v", stored)
+	}
+	if !stored.CreatedAt.Equal(m.CreatedAt) {
+		t.Errorf("different created at, expect: %v, got: %v", m.CreatedAt, stored.CreatedAt)
+	}
+
+	// case2: update
+	stored.Content = "edited"
+	stored.EditedAt = time.Now()
+	stored.Deleted = true
+	if _, err := msgRepo.Store(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := msgRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Content != "edited" || !updated.Deleted || !updated.EditedAt.Equal(stored.EditedAt) {
+		t.Errorf("different updated message: %#v", updated)
+	}
+	if !updated.CreatedAt.Equal(m.CreatedAt) {
+		t.Errorf("created at is changed by update, expect: %v, got: %v", m.CreatedAt, updated.CreatedAt)
+	}
+
+	// case3: not found
+	if _, err := msgRepo.Find(ctx, id+1); !chat.IsNotFoundError(err) {
+		t.Errorf("find not existing message, expect NotFoundError but got: %v", err)
+	}
+	if _, err := msgRepo.Store(ctx, domain.Message{ID: id + 1}); err == nil {
+		t.Errorf("update message not in the datastore, but no error")
+	}
+}
+
+func TestMessagesRemoveAllByRoomID(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	msgRepo := repos.MessageRepository
+	ctx := context.Background()
+
+	const RoomID = uint64(1)
+	removedID, err := msgRepo.Store(ctx, domain.Message{Content: "removed", RoomID: RoomID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remainedID, err := msgRepo.Store(ctx, domain.Message{Content: "remained", RoomID: RoomID + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := msgRepo.RemoveAllByRoomID(ctx, RoomID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := msgRepo.Find(ctx, removedID); !chat.IsNotFoundError(err) {
+		t.Errorf("removed message is found, or error is not NotFoundError: %v", err)
+	}
+	if _, err := msgRepo.Find(ctx, remainedID); err != nil {
+		t.Errorf("message in other room is removed: %v", err)
+	}
+}
+
+func TestMessagesStoreReactions(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	msgRepo := repos.MessageRepository
+	ctx := context.Background()
+
+	m := domain.Message{Content: "hello", UserID: 1, RoomID: 2}
+	m.Reactions.Add(":+1:", 1)
+	id, err := msgRepo.Store(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := msgRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Reactions.Has(":+1:", 1) {
+		t.Errorf("reaction is not stored on create: %v", stored.Reactions.Emojis())
+	}
+
+	stored.Reactions.Remove(":+1:", 1)
+	stored.Reactions.Add(":smile:", 2)
+	if _, err := msgRepo.Store(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := msgRepo.FindRoomMessagesOrderByLatest(ctx, m.RoomID, time.Now().Add(time.Second), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("different messages size, expect: 1, got: %v", len(msgs))
+	}
+	if got := msgs[0].Reactions; got.Has(":+1:", 1) || !got.Has(":smile:", 2) {
+		t.Errorf("reactions are not updated: %v", got.Emojis())
+	}
+
+	if err := msgRepo.RemoveAllByRoomID(ctx, m.RoomID); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := repos.DB.QueryRow(`SELECT COUNT(*) FROM message_reactions`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("reactions are remained after removing the messages, got: %v", count)
+	}
+}
+
+func TestMessagesFindRoomMessagesOrderByLatest(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	msgRepo := repos.MessageRepository
+	ctx := context.Background()
+
+	const RoomID = uint64(1)
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := msgRepo.Store(ctx, domain.Message{
+			Content:   "msg",
+			RoomID:    RoomID,
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// case1: latest first with limit
+	msgs, err := msgRepo.FindRoomMessagesOrderByLatest(ctx, RoomID, base.Add(time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("different message size, expect: %v, got: %v", 2, len(msgs))
+	}
+	if !msgs[0].CreatedAt.After(msgs[1].CreatedAt) {
+		t.Errorf("messages are not ordered by latest: %v, %v", msgs[0].CreatedAt, msgs[1].CreatedAt)
+	}
+
+	// case2: before the time cursor
+	msgs, err = msgRepo.FindRoomMessagesOrderByLatest(ctx, RoomID, base.Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Errorf("different message size before cursor, expect: %v, got: %v", 1, len(msgs))
+	}
+
+	// case3: zero limit
+	msgs, err = msgRepo.FindRoomMessagesOrderByLatest(ctx, RoomID, base.Add(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("zero limit returns messages: %v", len(msgs))
+	}
+}
+
+func TestMessagesFindThreadMessagesOrderByLatest(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	msgRepo := repos.MessageRepository
+	ctx := context.Background()
+
+	const RoomID = uint64(1)
+	base := time.Now()
+	parentID, err := msgRepo.Store(ctx, domain.Message{Content: "root", RoomID: RoomID, CreatedAt: base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the parent loaded before replying.
+	stale, err := msgRepo.Find(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replyIDs []uint64
+	for i := 1; i <= 3; i++ {
+		id, err := msgRepo.Store(ctx, domain.Message{
+			Content:   "reply",
+			RoomID:    RoomID,
+			ParentID:  parentID,
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		replyIDs = append(replyIDs, id)
+	}
+
+	// thread summary is recounted from the replies.
+	parent, err := msgRepo.RecountReplies(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 3 || !parent.LastReplyAt.Equal(base.Add(3*time.Second)) {
+		t.Errorf("different thread summary: %v, %v", parent.ReplyCount, parent.LastReplyAt)
+	}
+
+	// storing the stale parent does not overwrite the thread summary.
+	stale.Content = "edited"
+	if _, err := msgRepo.Store(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	if updated, err := msgRepo.Find(ctx, parentID); err != nil || updated.ReplyCount != 3 {
+		t.Errorf("thread summary is overwritten by the stale parent: %#v, %v", updated, err)
+	}
+
+	// the deleted reply is not counted.
+	lastReply, err := msgRepo.Find(ctx, replyIDs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastReply.Deleted = true
+	if _, err := msgRepo.Store(ctx, lastReply); err != nil {
+		t.Fatal(err)
+	}
+	parent, err = msgRepo.RecountReplies(ctx, parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 2 || !parent.LastReplyAt.Equal(base.Add(2*time.Second)) {
+		t.Errorf("different thread summary after deleting the reply: %v, %v", parent.ReplyCount, parent.LastReplyAt)
+	}
+
+	if _, err := msgRepo.RecountReplies(ctx, parentID+100); err == nil {
+		t.Error("recounting the replies of not existing message, but no error")
+	}
+
+	// case1: latest first with limit
+	msgs, err := msgRepo.FindThreadMessagesOrderByLatest(ctx, parentID, base.Add(time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("different message size, expect: %v, got: %v", 2, len(msgs))
+	}
+	if !msgs[0].CreatedAt.After(msgs[1].CreatedAt) || msgs[0].ParentID != parentID {
+		t.Errorf("different thread messages: %#v", msgs)
+	}
+
+	// case2: before the time cursor
+	msgs, err = msgRepo.FindThreadMessagesOrderByLatest(ctx, parentID, base.Add(2*time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Errorf("different message size before cursor, expect: %v, got: %v", 1, len(msgs))
+	}
+
+	// case3: replies are not contained in the room messages.
+	msgs, err = msgRepo.FindRoomMessagesOrderByLatest(ctx, RoomID, base.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != parentID {
+		t.Errorf("room messages should contain only the root: %#v", msgs)
+	}
+}
+
+func TestMessagesFindUnreadRoomMessages(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	ctx := context.Background()
+
+	const UserID = uint64(1)
+	readAt := time.Now()
+	r := domain.Room{Name: "room", MemberIDSet: domain.NewUserIDSet(UserID), MemberReadTimes: domain.NewTimeSet()}
+	r.MemberReadTimes.Set(UserID, readAt)
+	roomID, err := repos.Rooms().Store(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []domain.Message{
+		{Content: "read", RoomID: roomID, CreatedAt: readAt.Add(-time.Second)},
+		{Content: "unread", RoomID: roomID, CreatedAt: readAt.Add(time.Second)},
+		{Content: "", RoomID: roomID, CreatedAt: readAt.Add(2 * time.Second), Deleted: true},
+	} {
+		if _, err := repos.Messages().Store(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// case1: found
+	unread, err := repos.MessageRepository.FindUnreadRoomMessages(ctx, UserID, roomID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unread.RoomID != roomID || unread.MsgsSize != 1 || len(unread.Msgs) != 1 {
+		t.Fatalf("different unread messages: %#v", unread)
+	}
+	if unread.Msgs[0].Content != "unread" {
+		t.Errorf("different unread message content, expect: %v, got: %v", "unread", unread.Msgs[0].Content)
+	}
+
+	// case2: not a member
+	if _, err := repos.MessageRepository.FindUnreadRoomMessages(ctx, UserID+1, roomID, 10); !chat.IsNotFoundError(err) {
+		t.Errorf("find unread messages by not a member, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestMessagesFindUserMentions(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	ctx := context.Background()
+
+	const UserID = uint64(1)
+	readAt := time.Now()
+	r := domain.Room{Name: "room", MemberIDSet: domain.NewUserIDSet(UserID), MemberReadTimes: domain.NewTimeSet()}
+	r.MemberReadTimes.Set(UserID, readAt)
+	roomID, err := repos.Rooms().Store(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mentioned := domain.NewUserIDSet(UserID)
+	for _, m := range []domain.Message{
+		{Content: "read", RoomID: roomID, CreatedAt: readAt.Add(-time.Second), MentionedUserIDs: mentioned},
+		{Content: "unread", RoomID: roomID, CreatedAt: readAt.Add(time.Second), MentionedUserIDs: mentioned},
+		{Content: "not mentioned", RoomID: roomID, CreatedAt: readAt.Add(2 * time.Second)},
+		{Content: "", RoomID: roomID, CreatedAt: readAt.Add(3 * time.Second), Deleted: true, MentionedUserIDs: mentioned},
+		{Content: "not a member", RoomID: roomID + 1, CreatedAt: readAt.Add(4 * time.Second), MentionedUserIDs: mentioned},
+	} {
+		if _, err := repos.Messages().Store(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// case1: all of the mentions
+	mentions, err := repos.MessageRepository.FindUserMentions(ctx, UserID, readAt.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mentions.UserID != UserID || mentions.UnreadCount != 1 || len(mentions.Mentions) != 2 {
+		t.Fatalf("different user mentions: %#v", mentions)
+	}
+	for i, expect := range []struct {
+		Content string
+		Unread  bool
+	}{
+		{"unread", true},
+		{"read", false},
+	} {
+		if got := mentions.Mentions[i]; got.Content != expect.Content || got.Unread != expect.Unread ||
+			got.RoomID != roomID || len(got.Mentions) != 1 || got.Mentions[0] != UserID {
+			t.Errorf("different mention at %d: %#v", i, got)
+		}
+	}
+
+	// case2: paged by before
+	mentions, err = repos.MessageRepository.FindUserMentions(ctx, UserID, readAt, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mentions.UnreadCount != 1 || len(mentions.Mentions) != 1 || mentions.Mentions[0].Content != "read" {
+		t.Errorf("different user mentions before read time: %#v", mentions)
+	}
+}
@@ -0,0 +1,267 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a versioned change for the database schema.
+// Once a migration is released, it must not be modified.
+// Add new migration with incremented version instead.
+type migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// migrations must be ordered by ascending version.
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "create users, rooms, messages and events",
+		Statements: []string{
+			`CREATE TABLE users (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  name       VARCHAR(100) NOT NULL UNIQUE,
+  first_name VARCHAR(100) NOT NULL DEFAULT '',
+  last_name  VARCHAR(100) NOT NULL DEFAULT '',
+  password   VARCHAR(255) NOT NULL DEFAULT ''
+)`,
+			`CREATE TABLE user_friends (
+  user_id   INTEGER NOT NULL,
+  friend_id INTEGER NOT NULL,
+  PRIMARY KEY (user_id, friend_id)
+)`,
+			`CREATE TABLE rooms (
+  id           INTEGER PRIMARY KEY AUTOINCREMENT,
+  name         VARCHAR(100) NOT NULL,
+  is_talk_room BOOLEAN NOT NULL DEFAULT 0,
+  owner_id     INTEGER NOT NULL,
+  created_at   DATETIME NOT NULL
+)`,
+			`CREATE TABLE room_members (
+  room_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  read_at DATETIME NOT NULL,
+  PRIMARY KEY (room_id, user_id)
+)`,
+			`CREATE INDEX room_members_user_id ON room_members (user_id)`,
+			`CREATE TABLE messages (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  room_id    INTEGER NOT NULL,
+  user_id    INTEGER NOT NULL,
+  content    TEXT NOT NULL,
+  created_at DATETIME NOT NULL,
+  edited_at  DATETIME NOT NULL,
+  deleted    BOOLEAN NOT NULL DEFAULT 0
+)`,
+			`CREATE INDEX messages_room_id_created_at ON messages (room_id, created_at)`,
+			`CREATE TABLE events (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  type       INTEGER NOT NULL,
+  stream_id  INTEGER NOT NULL,
+  created_at DATETIME NOT NULL,
+  data       TEXT NOT NULL
+)`,
+			`CREATE INDEX events_created_at ON events (created_at)`,
+			`CREATE INDEX events_stream_id ON events (stream_id, id)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "add role to room_members",
+		Statements: []string{
+			// empty role means the default role of the member.
+			`ALTER TABLE room_members ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "add archived to rooms",
+		Statements: []string{
+			`ALTER TABLE rooms ADD COLUMN archived BOOLEAN NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "add password algorithm and cost to users",
+		Statements: []string{
+			// empty algorithm means the password is not hashed,
+			// and it is rehashed at the next login.
+			`ALTER TABLE users ADD COLUMN password_algorithm VARCHAR(20) NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN password_cost INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "create user_friend_requests and user_blocks",
+		Statements: []string{
+			`CREATE TABLE user_friend_requests (
+  user_id     INTEGER NOT NULL,
+  receiver_id INTEGER NOT NULL,
+  PRIMARY KEY (user_id, receiver_id)
+)`,
+			`CREATE INDEX user_friend_requests_receiver_id ON user_friend_requests (receiver_id)`,
+			`CREATE TABLE user_blocks (
+  user_id         INTEGER NOT NULL,
+  blocked_user_id INTEGER NOT NULL,
+  PRIMARY KEY (user_id, blocked_user_id)
+)`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "add thread to messages",
+		Statements: []string{
+			// zero parent_id means the message is not a reply.
+			`ALTER TABLE messages ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE messages ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE messages ADD COLUMN last_reply_at DATETIME NOT NULL DEFAULT '0001-01-01 00:00:00+00:00'`,
+			`CREATE INDEX messages_parent_id_created_at ON messages (parent_id, created_at)`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "create message_reactions",
+		Statements: []string{
+			`CREATE TABLE message_reactions (
+  message_id INTEGER NOT NULL,
+  emoji      VARCHAR(32) NOT NULL,
+  user_id    INTEGER NOT NULL,
+  PRIMARY KEY (message_id, emoji, user_id)
+)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "create room_pins",
+		Statements: []string{
+			`CREATE TABLE room_pins (
+  room_id    INTEGER NOT NULL,
+  message_id INTEGER NOT NULL,
+  pinned_by  INTEGER NOT NULL,
+  pinned_at  DATETIME NOT NULL,
+  PRIMARY KEY (room_id, message_id)
+)`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "create message_mentions",
+		Statements: []string{
+			`CREATE TABLE message_mentions (
+  message_id INTEGER NOT NULL,
+  user_id    INTEGER NOT NULL,
+  PRIMARY KEY (message_id, user_id)
+)`,
+			`CREATE INDEX message_mentions_user_id ON message_mentions (user_id)`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "create attachments",
+		Statements: []string{
+			`CREATE TABLE attachments (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id       INTEGER NOT NULL,
+  room_id       INTEGER NOT NULL,
+  message_id    INTEGER NOT NULL DEFAULT 0,
+  file_name     TEXT NOT NULL,
+  content_type  TEXT NOT NULL,
+  size          INTEGER NOT NULL,
+  blob_key      TEXT NOT NULL UNIQUE,
+  thumbnail_key TEXT NOT NULL DEFAULT '',
+  created_at    DATETIME NOT NULL
+)`,
+			`CREATE INDEX attachments_message_id ON attachments (message_id)`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "add joined_at to room_members",
+		Statements: []string{
+			// zero joined_at means the member joined before this migration.
+			`ALTER TABLE room_members ADD COLUMN joined_at DATETIME NOT NULL DEFAULT '0001-01-01 00:00:00+00:00'`,
+		},
+	},
+}
+
+// LatestSchemaVersion returns the schema version
+// after all of the migrations are applied.
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+  version     INTEGER PRIMARY KEY,
+  description TEXT NOT NULL,
+  applied_at  DATETIME NOT NULL
+)`
+
+// SchemaVersion returns current schema version of the db.
+// It returns 0 if no migration is applied.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, createSchemaMigrations); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// Migrate applies the migrations which are not applied yet to the db.
+// Each migration is applied in its own transaction, so the schema
+// version is not changed when the migration fails.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("migration: can not get schema version: %v", err)
+	}
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("migration: schema version(%d) is newer than this program supports(%d)", current, LatestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("migration: version(%d) %s: %v", m.Version, m.Description, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Description, time.Now().UTC(),
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,63 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open(DriverName, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+
+	// case1: new database has no version.
+	version, err := SchemaVersion(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Errorf("different schema version for new database, expect: %v, got: %v", 0, version)
+	}
+
+	// case2: migrate to latest version.
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	version, err = SchemaVersion(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := LatestSchemaVersion(); version != expect {
+		t.Errorf("different schema version after migration, expect: %v, got: %v", expect, version)
+	}
+
+	// case3: migrate again is no-operation.
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("migrating already migrated database, but got error: %v", err)
+	}
+
+	// case4: newer schema than this program supports.
+	if _, err := db.Exec(
+		`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, CURRENT_TIMESTAMP)`,
+		LatestSchemaVersion()+1, "future",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(ctx, db); err == nil {
+		t.Error("migrating newer schema, but no error")
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration versions should be sequential from 1, index: %v, got: %v", i, m.Version)
+		}
+	}
+}
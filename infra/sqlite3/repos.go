@@ -0,0 +1,132 @@
+// package sqlite3 provides the repositories and the queryers
+// backed by the SQL database through database/sql.
+//
+// The schema of the database is created and upgraded by
+// the versioned migrations when the repositories are opened.
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+
+	// register the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// DriverName is the name of database/sql driver used by this package.
+const DriverName = "sqlite3"
+
+// Open opens the database specified by dataSourceName, such as
+// file path, and returns the Repositories using it.
+// The schema of the database is migrated to the latest version
+// before returning.
+func Open(dataSourceName string) (*Repositories, error) {
+	db, err := sql.Open(DriverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite3 allows only one writer at a time, so the connection is
+	// limited to one to serialize the transactions without "database is locked" error.
+	db.SetMaxOpenConns(1)
+
+	if err := Migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return NewRepositories(db), nil
+}
+
+// NewRepositories creates the Repositories using already opened db.
+// The schema of the db should be migrated by Migrate() before use.
+func NewRepositories(db *sql.DB) *Repositories {
+	return &Repositories{
+		DB:                db,
+		UserRepository:    &UserRepository{txBeginner{db}},
+		MessageRepository: &MessageRepository{txBeginner{db}},
+		RoomRepository:    &RoomRepository{txBeginner{db}},
+		EventRepository:   &EventRepository{txBeginner{db}},
+
+		AttachmentRepository: &AttachmentRepository{txBeginner{db}},
+	}
+}
+
+// Repositories implements domain.Repositories and
+// its fields can be used as chat.Queryers.
+type Repositories struct {
+	DB *sql.DB
+
+	*UserRepository
+	*MessageRepository
+	*RoomRepository
+	*EventRepository
+
+	*AttachmentRepository
+}
+
+func (r Repositories) Users() domain.UserRepository {
+	return r.UserRepository
+}
+
+func (r Repositories) Messages() domain.MessageRepository {
+	return r.MessageRepository
+}
+
+func (r Repositories) Rooms() domain.RoomRepository {
+	return r.RoomRepository
+}
+
+func (r Repositories) Attachments() domain.AttachmentRepository {
+	return r.AttachmentRepository
+}
+
+func (r Repositories) Events() event.EventRepository {
+	return r.EventRepository
+}
+
+func (r *Repositories) Close() error {
+	return r.DB.Close()
+}
+
+// queryer is a common interface for the *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txBeginner implements domain.TxBeginner, and is shared by
+// each repository as embedded struct.
+type txBeginner struct {
+	db *sql.DB
+}
+
+// BeginTx begins new transaction. If the context already has the
+// transaction, it returns no-operating transaction so that
+// the repository operations join the outer transaction.
+func (b txBeginner) BeginTx(ctx context.Context, opts *sql.TxOptions) (domain.Tx, error) {
+	if _, ok := getSQLTx(ctx); ok {
+		return domain.EmptyTxBeginner{}, nil
+	}
+	return b.db.BeginTx(ctx, opts)
+}
+
+// conn returns the transaction in the context if exists, otherwise db itself.
+func (b txBeginner) conn(ctx context.Context) queryer {
+	if tx, ok := getSQLTx(ctx); ok {
+		return tx
+	}
+	return b.db
+}
+
+func getSQLTx(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := domain.GetTx(ctx)
+	if !ok {
+		return nil, false
+	}
+	sqlTx, ok := tx.(*sql.Tx)
+	return sqlTx, ok
+}
@@ -0,0 +1,90 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+// openTestRepositories opens the Repositories with new in-memory database.
+func openTestRepositories(t *testing.T) *Repositories {
+	repos, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repos
+}
+
+func TestRepositoriesImplementInterfaces(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+
+	var _ domain.Repositories = repos
+	var _ = &chat.Queryers{
+		UserQueryer:    repos.UserRepository,
+		RoomQueryer:    repos.RoomRepository,
+		MessageQueryer: repos.MessageRepository,
+		EventQueryer:   repos.EventRepository,
+	}
+}
+
+func TestRepositoriesTransaction(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+
+	// case1: rollback discards stored data.
+	{
+		ctx := context.Background()
+		tx, err := repos.Users().BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txCtx := domain.SetTx(ctx, tx)
+
+		id, err := repos.Users().Store(txCtx, domain.User{Name: "rollbacked"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// it can be found in the same transaction.
+		if _, err := repos.Users().Find(txCtx, id); err != nil {
+			t.Fatalf("stored user is not found in the transaction: %v", err)
+		}
+
+		// nested transaction joins outer transaction.
+		nestedTx, err := repos.Rooms().BeginTx(txCtx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nestedTx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Users().Find(ctx, id); !chat.IsNotFoundError(err) {
+			t.Errorf("rollbacked user should not be found, but got error: %v", err)
+		}
+	}
+
+	// case2: commit persists stored data.
+	{
+		ctx := context.Background()
+		tx, err := repos.Users().BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := repos.Users().Store(domain.SetTx(ctx, tx), domain.User{Name: "committed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Users().Find(ctx, id); err != nil {
+			t.Errorf("committed user should be found, but got error: %v", err)
+		}
+	}
+}
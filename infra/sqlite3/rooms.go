@@ -0,0 +1,306 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+// RoomRepository manages access to rooms in the database.
+// It implements domain.RoomRepository and chat.RoomQueryer.
+type RoomRepository struct {
+	txBeginner
+}
+
+func errRoomNotFound(roomID uint64) *chat.NotFoundError {
+	return chat.NewNotFoundError("room (id=%v) is not found", roomID)
+}
+
+func (repo *RoomRepository) Store(ctx context.Context, r domain.Room) (uint64, error) {
+	if r.NotExist() {
+		return repo.Create(ctx, r)
+	} else {
+		return repo.Update(ctx, r)
+	}
+}
+
+func (repo *RoomRepository) Create(ctx context.Context, r domain.Room) (uint64, error) {
+	conn := repo.conn(ctx)
+
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	res, err := conn.ExecContext(ctx,
+		`INSERT INTO rooms (name, is_talk_room, archived, owner_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+		r.Name, r.IsTalkRoom, r.IsArchived, r.OwnerID, r.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not create room(name=%v): %v", r.Name, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, chat.NewInfraError("can not create room(name=%v): %v", r.Name, err)
+	}
+	r.ID = uint64(id)
+
+	if err := insertMembers(ctx, conn, r); err != nil {
+		return 0, err
+	}
+	if err := insertPins(ctx, conn, r); err != nil {
+		return 0, err
+	}
+	return r.ID, nil
+}
+
+func (repo *RoomRepository) Update(ctx context.Context, r domain.Room) (uint64, error) {
+	conn := repo.conn(ctx)
+
+	res, err := conn.ExecContext(ctx,
+		`UPDATE rooms SET name = ?, is_talk_room = ?, archived = ?, owner_id = ? WHERE id = ?`,
+		r.Name, r.IsTalkRoom, r.IsArchived, r.OwnerID, r.ID,
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not update room(id=%d): %v", r.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, chat.NewInfraError("room(id=%d) is not in the datastore", r.ID)
+	}
+
+	for _, table := range []string{"room_members", "room_pins"} {
+		if _, err := conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE room_id = ?`, r.ID); err != nil {
+			return 0, chat.NewInfraError("can not update %v of room(id=%d): %v", table, r.ID, err)
+		}
+	}
+	if err := insertMembers(ctx, conn, r); err != nil {
+		return 0, err
+	}
+	if err := insertPins(ctx, conn, r); err != nil {
+		return 0, err
+	}
+	return r.ID, nil
+}
+
+func insertMembers(ctx context.Context, conn queryer, r domain.Room) error {
+	for _, memberID := range r.MemberIDs() {
+		readAt, _ := r.MemberReadTimes.Get(memberID)
+		joinedAt, _ := r.MemberJoinedAt.Get(memberID)
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO room_members (room_id, user_id, read_at, role, joined_at) VALUES (?, ?, ?, ?, ?)`,
+			r.ID, memberID, readAt.UTC(), string(r.RoleOf(memberID)), joinedAt.UTC(),
+		)
+		if err != nil {
+			return chat.NewInfraError("can not store member(id=%d) of room(id=%d): %v", memberID, r.ID, err)
+		}
+	}
+	return nil
+}
+
+func insertPins(ctx context.Context, conn queryer, r domain.Room) error {
+	for _, pin := range r.Pins.List() {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO room_pins (room_id, message_id, pinned_by, pinned_at) VALUES (?, ?, ?, ?)`,
+			r.ID, pin.MessageID, pin.PinnedBy, pin.PinnedAt.UTC(),
+		)
+		if err != nil {
+			return chat.NewInfraError("can not store pin(message_id=%d) of room(id=%d): %v", pin.MessageID, r.ID, err)
+		}
+	}
+	return nil
+}
+
+func (repo *RoomRepository) Remove(ctx context.Context, r domain.Room) error {
+	conn := repo.conn(ctx)
+
+	for _, table := range []string{"room_members", "room_pins"} {
+		if _, err := conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE room_id = ?`, r.ID); err != nil {
+			return chat.NewInfraError("can not remove %v of room(id=%d): %v", table, r.ID, err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `DELETE FROM rooms WHERE id = ?`, r.ID); err != nil {
+		return chat.NewInfraError("can not remove room(id=%d): %v", r.ID, err)
+	}
+	return nil
+}
+
+const roomColumns = `rooms.id, rooms.name, rooms.is_talk_room, rooms.archived, rooms.owner_id, rooms.created_at`
+
+func (repo *RoomRepository) Find(ctx context.Context, roomID uint64) (domain.Room, error) {
+	conn := repo.conn(ctx)
+
+	rooms, err := selectRooms(ctx, conn, `SELECT `+roomColumns+` FROM rooms WHERE id = ?`, roomID)
+	if err != nil {
+		return domain.Room{}, chat.NewInfraError("can not find room(id=%d): %v", roomID, err)
+	}
+	if len(rooms) == 0 {
+		return domain.Room{}, errRoomNotFound(roomID)
+	}
+	return rooms[0], nil
+}
+
+func (repo *RoomRepository) FindAllByUserID(ctx context.Context, userID uint64) ([]domain.Room, error) {
+	rooms, err := selectRooms(ctx, repo.conn(ctx), `
+SELECT `+roomColumns+`
+  FROM rooms INNER JOIN room_members ON rooms.id = room_members.room_id
+ WHERE room_members.user_id = ? ORDER BY rooms.id`, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find rooms of user(id=%d): %v", userID, err)
+	}
+	return rooms, nil
+}
+
+// selectRooms returns the rooms, with its members, queried by the query.
+func selectRooms(ctx context.Context, conn queryer, query string, args ...interface{}) ([]domain.Room, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]domain.Room, 0, 4)
+	for rows.Next() {
+		r := domain.Room{EventHolder: domain.NewEventHolder()}
+		if err := rows.Scan(&r.ID, &r.Name, &r.IsTalkRoom, &r.IsArchived, &r.OwnerID, &r.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// members are queried after closing rows because
+	// the connection can not be shared with opened rows.
+	for i := range rooms {
+		if err := selectMembers(ctx, conn, &rooms[i]); err != nil {
+			return nil, err
+		}
+		if err := selectPins(ctx, conn, &rooms[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rooms, nil
+}
+
+func selectMembers(ctx context.Context, conn queryer, r *domain.Room) error {
+	rows, err := conn.QueryContext(ctx,
+		`SELECT user_id, read_at, role, joined_at FROM room_members WHERE room_id = ? ORDER BY user_id`, r.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	r.MemberIDSet = domain.NewUserIDSet()
+	r.MemberReadTimes = domain.NewTimeSet()
+	r.MemberJoinedAt = domain.NewTimeSet()
+	r.MemberRoles = domain.NewRoleSet()
+	for rows.Next() {
+		var (
+			userID   uint64
+			readAt   time.Time
+			role     string
+			joinedAt time.Time
+		)
+		if err := rows.Scan(&userID, &readAt, &role, &joinedAt); err != nil {
+			return err
+		}
+		r.MemberIDSet.Add(userID)
+		r.MemberReadTimes.Set(userID, readAt)
+		r.MemberJoinedAt.Set(userID, joinedAt)
+		if role := domain.RoomRole(role); role != "" && role != domain.RoomRoleOwner {
+			r.MemberRoles.Set(userID, role)
+		}
+	}
+	return rows.Err()
+}
+
+func selectPins(ctx context.Context, conn queryer, r *domain.Room) error {
+	rows, err := conn.QueryContext(ctx,
+		`SELECT message_id, pinned_by, pinned_at FROM room_pins WHERE room_id = ?`, r.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	r.Pins = domain.NewPinSet()
+	for rows.Next() {
+		var pin domain.Pin
+		if err := rows.Scan(&pin.MessageID, &pin.PinnedBy, &pin.PinnedAt); err != nil {
+			return err
+		}
+		r.Pins.Add(pin)
+	}
+	return rows.Err()
+}
+
+func (repo *RoomRepository) FindRoomInfo(ctx context.Context, userID, roomID uint64) (*queried.RoomInfo, error) {
+	conn := repo.conn(ctx)
+
+	var (
+		name    string
+		ownerID uint64
+	)
+	err := conn.QueryRowContext(ctx, `SELECT name, owner_id FROM rooms WHERE id = ?`, roomID).Scan(&name, &ownerID)
+	if err == sql.ErrNoRows {
+		return nil, errRoomNotFound(roomID)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find room(id=%d): %v", roomID, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+SELECT users.id, users.name, users.first_name, users.last_name, room_members.read_at, room_members.role
+  FROM users INNER JOIN room_members ON users.id = room_members.user_id
+ WHERE room_members.room_id = ? ORDER BY users.id`, roomID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find members of room(id=%d): %v", roomID, err)
+	}
+	defer rows.Close()
+
+	var isMember bool
+	members := make([]queried.RoomMemberProfile, 0, 2)
+	for rows.Next() {
+		var m queried.RoomMemberProfile
+		if err := rows.Scan(&m.UserID, &m.UserName, &m.FirstName, &m.LastName, &m.MessageReadAt, &m.Role); err != nil {
+			return nil, chat.NewInfraError("can not find members of room(id=%d): %v", roomID, err)
+		}
+		switch {
+		case m.UserID == ownerID:
+			m.Role = string(domain.RoomRoleOwner)
+		case m.Role == "":
+			m.Role = string(domain.RoomRoleMember)
+		}
+		if m.UserID == userID {
+			isMember = true
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, chat.NewInfraError("can not find members of room(id=%d): %v", roomID, err)
+	}
+
+	if !isMember {
+		return nil, chat.NewNotFoundError("user (id=%v) is not a member of the room (id=%v)", userID, roomID)
+	}
+
+	var pinsSize int
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM room_pins WHERE room_id = ?`, roomID).Scan(&pinsSize)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find pins of room(id=%d): %v", roomID, err)
+	}
+
+	return &queried.RoomInfo{
+		RoomName:    name,
+		RoomID:      roomID,
+		CreatorID:   ownerID,
+		Members:     members,
+		MembersSize: len(members),
+		PinsSize:    pinsSize,
+	}, nil
+}
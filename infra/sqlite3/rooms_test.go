@@ -0,0 +1,230 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+func TestRoomsStore(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	roomRepo := repos.RoomRepository
+	ctx := context.Background()
+
+	readAt := time.Now().Add(-time.Hour)
+
+	// case1: create
+	r := domain.Room{
+		Name:            "room",
+		OwnerID:         1,
+		MemberIDSet:     domain.NewUserIDSet(1, 2),
+		MemberReadTimes: domain.NewTimeSet(1, 2),
+	}
+	r.MemberReadTimes.Set(2, readAt)
+	r.MemberJoinedAt.Set(2, readAt)
+	r.Pins.Add(domain.Pin{MessageID: 1, PinnedBy: 1, PinnedAt: readAt})
+	id, err := roomRepo.Store(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 {
+		t.Fatal("created id is invalid (0)")
+	}
+
+	stored, err := roomRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != r.Name || stored.OwnerID != r.OwnerID || stored.CreatedAt.IsZero() {
+		t.Errorf("different stored room: %#v", stored)
+	}
+	if got := len(stored.MemberIDs()); got != 2 {
+		t.Errorf("different member size, expect: %v, got: %v", 2, got)
+	}
+	if got, ok := stored.MemberReadTimes.Get(2); !ok || !got.Equal(readAt) {
+		t.Errorf("different member read time, expect: %v, got: %v", readAt, got)
+	}
+	if got, ok := stored.MemberJoinedAt.Get(2); !ok || !got.Equal(readAt) {
+		t.Errorf("different member join time, expect: %v, got: %v", readAt, got)
+	}
+	if got, ok := stored.MemberJoinedAt.Get(1); !ok || !got.IsZero() {
+		t.Errorf("the member joined at unknown time should have zero join time, got: %v", got)
+	}
+	if pins := stored.Pins.List(); len(pins) != 1 || pins[0].MessageID != 1 || pins[0].PinnedBy != 1 || !pins[0].PinnedAt.Equal(readAt) {
+		t.Errorf("different stored pins: %#v", pins)
+	}
+
+	// case2: update
+	stored.Name = "updated"
+	stored.IsArchived = true
+	stored.MemberIDSet.Remove(2)
+	stored.MemberReadTimes.Delete(2)
+	stored.Pins.Remove(1)
+	stored.Pins.Add(domain.Pin{MessageID: 2, PinnedBy: 1, PinnedAt: readAt})
+	if _, err := roomRepo.Store(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := roomRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "updated" {
+		t.Errorf("different updated name, expect: %v, got: %v", "updated", updated.Name)
+	}
+	if !updated.IsArchived {
+		t.Errorf("archived flag is not updated")
+	}
+	if updated.MemberIDSet.Has(2) {
+		t.Errorf("removed member still exists in the room")
+	}
+	if updated.Pins.Has(1) || !updated.Pins.Has(2) {
+		t.Errorf("pins are not updated: %#v", updated.Pins.List())
+	}
+
+	// case3: update room not in the datastore
+	if _, err := roomRepo.Store(ctx, domain.Room{ID: 99}); err == nil {
+		t.Errorf("update room not in the datastore, but no error")
+	}
+}
+
+func TestRoomsRemove(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	roomRepo := repos.RoomRepository
+	ctx := context.Background()
+
+	id, err := roomRepo.Store(ctx, domain.Room{Name: "room", MemberIDSet: domain.NewUserIDSet(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := roomRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := roomRepo.Remove(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := roomRepo.Find(ctx, id); !chat.IsNotFoundError(err) {
+		t.Errorf("removed room is found, or error is not NotFoundError: %v", err)
+	}
+	rooms, err := roomRepo.FindAllByUserID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 0 {
+		t.Errorf("removed room is still related with the member: %#v", rooms)
+	}
+}
+
+func TestRoomsFindAllByUserID(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	roomRepo := repos.RoomRepository
+	ctx := context.Background()
+
+	for _, r := range []domain.Room{
+		{Name: "room1", MemberIDSet: domain.NewUserIDSet(1, 2)},
+		{Name: "room2", MemberIDSet: domain.NewUserIDSet(2)},
+		{Name: "room3", MemberIDSet: domain.NewUserIDSet(1)},
+	} {
+		if _, err := roomRepo.Store(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rooms, err := roomRepo.FindAllByUserID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 2 {
+		t.Fatalf("different room size, expect: %v, got: %v", 2, len(rooms))
+	}
+	if rooms[0].Name != "room1" || rooms[1].Name != "room2" {
+		t.Errorf("different rooms order by id: %v, %v", rooms[0].Name, rooms[1].Name)
+	}
+	if got := len(rooms[0].MemberIDs()); got != 2 {
+		t.Errorf("different member size, expect: %v, got: %v", 2, got)
+	}
+}
+
+func TestRoomsFindRoomInfo(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	ctx := context.Background()
+
+	userID, err := repos.Users().Store(ctx, domain.User{Name: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := repos.Users().Store(ctx, domain.User{Name: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID, err := repos.Users().Store(ctx, domain.User{Name: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAt := time.Now()
+	r := domain.Room{
+		Name:            "room",
+		OwnerID:         userID,
+		MemberIDSet:     domain.NewUserIDSet(userID, adminID),
+		MemberReadTimes: domain.NewTimeSet(),
+		MemberRoles:     domain.NewRoleSet(),
+	}
+	r.MemberReadTimes.Set(userID, readAt)
+	r.MemberRoles.Set(adminID, domain.RoomRoleAdmin)
+	r.Pins.Add(domain.Pin{MessageID: 1, PinnedBy: adminID, PinnedAt: readAt})
+	roomID, err := repos.Rooms().Store(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// roles are restored by Find.
+	found, err := repos.Rooms().Find(ctx, roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := found.RoleOf(userID); got != domain.RoomRoleOwner {
+		t.Errorf("different role of the owner, expect: %v, got: %v", domain.RoomRoleOwner, got)
+	}
+	if got := found.RoleOf(adminID); got != domain.RoomRoleAdmin {
+		t.Errorf("different role of the admin, expect: %v, got: %v", domain.RoomRoleAdmin, got)
+	}
+
+	// case1: found
+	info, err := repos.RoomRepository.FindRoomInfo(ctx, userID, roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RoomID != roomID || info.RoomName != "room" || info.CreatorID != userID {
+		t.Errorf("different room info: %#v", info)
+	}
+	if info.PinsSize != 1 {
+		t.Errorf("different pins size, expect: %v, got: %v", 1, info.PinsSize)
+	}
+	if info.MembersSize != 2 || len(info.Members) != 2 {
+		t.Fatalf("different members size, expect: %v, got: %v", 2, info.MembersSize)
+	}
+	if m := info.Members[0]; m.UserID != userID || !m.MessageReadAt.Equal(readAt) || m.Role != "owner" {
+		t.Errorf("different member profile: %#v", m)
+	}
+	if m := info.Members[1]; m.UserID != adminID || m.Role != "admin" {
+		t.Errorf("different member profile: %#v", m)
+	}
+
+	// case2: not a member
+	if _, err := repos.RoomRepository.FindRoomInfo(ctx, otherID, roomID); !chat.IsNotFoundError(err) {
+		t.Errorf("find room info by not a member, expect NotFoundError but got: %v", err)
+	}
+
+	// case3: room not found
+	if _, err := repos.RoomRepository.FindRoomInfo(ctx, userID, roomID+1); !chat.IsNotFoundError(err) {
+		t.Errorf("find not existing room info, expect NotFoundError but got: %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+// UserRepository manages access to users in the database.
+// It implements domain.UserRepository and chat.UserQueryer.
+type UserRepository struct {
+	txBeginner
+}
+
+func errUserNotFound(userID uint64) *chat.NotFoundError {
+	return chat.NewNotFoundError("user (id=%v) is not found", userID)
+}
+
+func (repo *UserRepository) Store(ctx context.Context, u domain.User) (uint64, error) {
+	if u.NotExist() {
+		return repo.Create(ctx, u)
+	} else {
+		return repo.Update(ctx, u)
+	}
+}
+
+func (repo *UserRepository) Create(ctx context.Context, u domain.User) (uint64, error) {
+	conn := repo.conn(ctx)
+
+	res, err := conn.ExecContext(ctx,
+		`INSERT INTO users (name, first_name, last_name, password, password_algorithm, password_cost) VALUES (?, ?, ?, ?, ?, ?)`,
+		u.Name, u.FirstName, u.LastName,
+		u.Password.Hash, u.Password.Algorithm, u.Password.Cost,
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not create user(name=%v): %v", u.Name, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, chat.NewInfraError("can not create user(name=%v): %v", u.Name, err)
+	}
+	u.ID = uint64(id)
+
+	if err := insertFriends(ctx, conn, u); err != nil {
+		return 0, err
+	}
+	if err := insertUserIDs(ctx, conn, u.ID, "user_friend_requests", "receiver_id", u.RequestedFriendIDs); err != nil {
+		return 0, err
+	}
+	if err := insertUserIDs(ctx, conn, u.ID, "user_blocks", "blocked_user_id", u.BlockedUserIDs); err != nil {
+		return 0, err
+	}
+	return u.ID, nil
+}
+
+func (repo *UserRepository) Update(ctx context.Context, u domain.User) (uint64, error) {
+	conn := repo.conn(ctx)
+
+	res, err := conn.ExecContext(ctx,
+		`UPDATE users SET name = ?, first_name = ?, last_name = ?, password = ?, password_algorithm = ?, password_cost = ? WHERE id = ?`,
+		u.Name, u.FirstName, u.LastName,
+		u.Password.Hash, u.Password.Algorithm, u.Password.Cost, u.ID,
+	)
+	if err != nil {
+		return 0, chat.NewInfraError("can not update user(id=%d): %v", u.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, chat.NewInfraError("user(id=%d) is not in the datastore", u.ID)
+	}
+
+	for _, table := range []string{"user_friends", "user_friend_requests", "user_blocks"} {
+		if _, err := conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, u.ID); err != nil {
+			return 0, chat.NewInfraError("can not update %v of user(id=%d): %v", table, u.ID, err)
+		}
+	}
+	if err := insertFriends(ctx, conn, u); err != nil {
+		return 0, err
+	}
+	if err := insertUserIDs(ctx, conn, u.ID, "user_friend_requests", "receiver_id", u.RequestedFriendIDs); err != nil {
+		return 0, err
+	}
+	if err := insertUserIDs(ctx, conn, u.ID, "user_blocks", "blocked_user_id", u.BlockedUserIDs); err != nil {
+		return 0, err
+	}
+	return u.ID, nil
+}
+
+func insertFriends(ctx context.Context, conn queryer, u domain.User) error {
+	for _, friendID := range u.FriendIDs.List() {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO user_friends (user_id, friend_id) VALUES (?, ?)`,
+			u.ID, friendID,
+		)
+		if err != nil {
+			return chat.NewInfraError("can not store friend(id=%d) of user(id=%d): %v", friendID, u.ID, err)
+		}
+	}
+	return nil
+}
+
+// insertUserIDs inserts the IDs related with the user into the table,
+// which has user_id and the column for the related ID.
+func insertUserIDs(ctx context.Context, conn queryer, userID uint64, table, column string, ids domain.UserIDSet) error {
+	for _, id := range ids.List() {
+		_, err := conn.ExecContext(ctx,
+			`INSERT INTO `+table+` (user_id, `+column+`) VALUES (?, ?)`,
+			userID, id,
+		)
+		if err != nil {
+			return chat.NewInfraError("can not store %v(id=%d) of user(id=%d): %v", column, id, userID, err)
+		}
+	}
+	return nil
+}
+
+func (repo *UserRepository) Find(ctx context.Context, id uint64) (domain.User, error) {
+	u, err := findUser(ctx, repo.conn(ctx), `WHERE id = ?`, id)
+	if err == sql.ErrNoRows {
+		return domain.User{}, errUserNotFound(id)
+	}
+	if err != nil {
+		return domain.User{}, chat.NewInfraError("can not find user(id=%d): %v", id, err)
+	}
+	return u, nil
+}
+
+func (repo *UserRepository) FindByName(ctx context.Context, name string) (domain.User, error) {
+	u, err := findUser(ctx, repo.conn(ctx), `WHERE name = ?`, name)
+	if err == sql.ErrNoRows {
+		return domain.User{}, chat.NewNotFoundError("user (name=%v) is not found", name)
+	}
+	if err != nil {
+		return domain.User{}, chat.NewInfraError("can not find user(name=%v): %v", name, err)
+	}
+	return u, nil
+}
+
+// findUser finds one user and its friends by the where clause.
+// It returns sql.ErrNoRows when the user is not found.
+func findUser(ctx context.Context, conn queryer, where string, args ...interface{}) (domain.User, error) {
+	u := domain.User{EventHolder: domain.NewEventHolder()}
+	err := conn.QueryRowContext(ctx,
+		`SELECT id, name, first_name, last_name, password, password_algorithm, password_cost FROM users `+where, args...,
+	).Scan(
+		&u.ID, &u.Name, &u.FirstName, &u.LastName,
+		&u.Password.Hash, &u.Password.Algorithm, &u.Password.Cost,
+	)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	friendIDs, err := selectIDs(ctx, conn, `SELECT friend_id FROM user_friends WHERE user_id = ? ORDER BY friend_id`, u.ID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("can not find friends: %v", err)
+	}
+	u.FriendIDs = domain.NewUserIDSet(friendIDs...)
+
+	requestedIDs, err := selectIDs(ctx, conn, `SELECT receiver_id FROM user_friend_requests WHERE user_id = ?`, u.ID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("can not find friend requests: %v", err)
+	}
+	u.RequestedFriendIDs = domain.NewUserIDSet(requestedIDs...)
+
+	blockedIDs, err := selectIDs(ctx, conn, `SELECT blocked_user_id FROM user_blocks WHERE user_id = ?`, u.ID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("can not find blocked users: %v", err)
+	}
+	u.BlockedUserIDs = domain.NewUserIDSet(blockedIDs...)
+	return u, nil
+}
+
+func (repo *UserRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
+	var count int
+	err := repo.conn(ctx).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE name = ?`, name,
+	).Scan(&count)
+	if err != nil {
+		return false, chat.NewInfraError("can not find user(name=%v): %v", name, err)
+	}
+	return count > 0, nil
+}
+
+func (repo *UserRepository) Remove(ctx context.Context, u domain.User) error {
+	conn := repo.conn(ctx)
+
+	res, err := conn.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, u.ID)
+	if err != nil {
+		return chat.NewInfraError("can not remove user(id=%d): %v", u.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errUserNotFound(u.ID)
+	}
+
+	for _, table := range []string{"user_friends", "user_friend_requests", "user_blocks"} {
+		if _, err := conn.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, u.ID); err != nil {
+			return chat.NewInfraError("can not remove %v of user(id=%d): %v", table, u.ID, err)
+		}
+	}
+	return nil
+}
+
+func (repo *UserRepository) FindUserRelation(ctx context.Context, userID uint64) (*queried.UserRelation, error) {
+	conn := repo.conn(ctx)
+
+	relation := &queried.UserRelation{}
+	err := conn.QueryRowContext(ctx,
+		`SELECT id, name, first_name, last_name FROM users WHERE id = ?`, userID,
+	).Scan(
+		&relation.UserID, &relation.UserName,
+		&relation.FirstName, &relation.LastName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errUserNotFound(userID)
+	}
+	if err != nil {
+		return nil, chat.NewInfraError("can not find user(id=%d): %v", userID, err)
+	}
+
+	relation.Friends, err = selectUserProfiles(ctx, conn, `
+SELECT users.id, users.name, users.first_name, users.last_name
+  FROM users INNER JOIN user_friends ON users.id = user_friends.friend_id
+ WHERE user_friends.user_id = ? ORDER BY users.id`, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find friends of user(id=%d): %v", userID, err)
+	}
+
+	relation.IncomingFriendRequests, err = selectUserProfiles(ctx, conn, `
+SELECT users.id, users.name, users.first_name, users.last_name
+  FROM users INNER JOIN user_friend_requests ON users.id = user_friend_requests.user_id
+ WHERE user_friend_requests.receiver_id = ? ORDER BY users.id`, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find incoming friend requests of user(id=%d): %v", userID, err)
+	}
+
+	relation.OutgoingFriendRequests, err = selectUserProfiles(ctx, conn, `
+SELECT users.id, users.name, users.first_name, users.last_name
+  FROM users INNER JOIN user_friend_requests ON users.id = user_friend_requests.receiver_id
+ WHERE user_friend_requests.user_id = ? ORDER BY users.id`, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find outgoing friend requests of user(id=%d): %v", userID, err)
+	}
+
+	relation.Rooms, err = selectUserRooms(ctx, conn, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find rooms of user(id=%d): %v", userID, err)
+	}
+
+	relation.TalkRooms, err = selectUserTalkRooms(ctx, conn, userID)
+	if err != nil {
+		return nil, chat.NewInfraError("can not find talk rooms of user(id=%d): %v", userID, err)
+	}
+	return relation, nil
+}
+
+func selectUserProfiles(ctx context.Context, conn queryer, query string, args ...interface{}) ([]queried.UserProfile, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]queried.UserProfile, 0, 4)
+	for rows.Next() {
+		var p queried.UserProfile
+		if err := rows.Scan(&p.UserID, &p.UserName, &p.FirstName, &p.LastName); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func selectUserRooms(ctx context.Context, conn queryer, userID uint64) ([]queried.UserRoom, error) {
+	rows, err := conn.QueryContext(ctx, `
+SELECT rooms.id, rooms.name
+  FROM rooms INNER JOIN room_members ON rooms.id = room_members.room_id
+ WHERE room_members.user_id = ? AND rooms.is_talk_room = 0 ORDER BY rooms.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := make([]queried.UserRoom, 0, 4)
+	for rows.Next() {
+		var r queried.UserRoom
+		if err := rows.Scan(&r.RoomID, &r.RoomName); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	return rooms, rows.Err()
+}
+
+func selectUserTalkRooms(ctx context.Context, conn queryer, userID uint64) ([]queried.UserTalkRoom, error) {
+	rows, err := conn.QueryContext(ctx, `
+SELECT rooms.id, friends.user_id
+  FROM rooms
+       INNER JOIN room_members AS me ON rooms.id = me.room_id
+       INNER JOIN room_members AS friends ON rooms.id = friends.room_id
+ WHERE me.user_id = ? AND friends.user_id <> me.user_id AND rooms.is_talk_room = 1
+ ORDER BY rooms.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	talkRooms := make([]queried.UserTalkRoom, 0, 4)
+	for rows.Next() {
+		var r queried.UserTalkRoom
+		if err := rows.Scan(&r.RoomID, &r.FriendID); err != nil {
+			return nil, err
+		}
+		talkRooms = append(talkRooms, r)
+	}
+	return talkRooms, rows.Err()
+}
+
+// selectIDs returns the list of single ID column queried by the query.
+func selectIDs(ctx context.Context, conn queryer, query string, args ...interface{}) ([]uint64, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint64, 0, 4)
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
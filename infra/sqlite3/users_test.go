@@ -0,0 +1,279 @@
+package sqlite3
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+func TestUsersStore(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	userRepo := repos.UserRepository
+	ctx := context.Background()
+
+	// case1: create
+	newUser := domain.User{
+		Name:      "stored-user",
+		FirstName: "first",
+		Password: domain.HashedPassword{
+			Algorithm: domain.PasswordAlgorithmBcrypt,
+			Cost:      domain.MinPasswordHashCost,
+			Hash:      "hash",
+		},
+	}
+	id, err := userRepo.Store(ctx, newUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == 0 {
+		t.Errorf("created id is invalid (0)")
+	}
+
+	// case2: duplicated name error
+	if _, err := userRepo.Store(ctx, newUser); err == nil {
+		t.Errorf("store duplicated name user, but no error")
+	}
+
+	// case3: update
+	friendID, err := userRepo.Store(ctx, domain.User{Name: "friend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUser.ID = id
+	newUser.LastName = "last"
+	newUser.FriendIDs = domain.NewUserIDSet(friendID)
+	if _, err := userRepo.Store(ctx, newUser); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := userRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastName != "last" {
+		t.Errorf("different updated last name, expect: %v, got: %v", "last", stored.LastName)
+	}
+	if !stored.FriendIDs.Has(friendID) {
+		t.Errorf("updated friend is not found")
+	}
+
+	// case4: update user not in the datastore
+	if _, err := userRepo.Store(ctx, domain.User{ID: 99, Name: "not-found"}); err == nil {
+		t.Errorf("update user not in the datastore, but no error")
+	}
+}
+
+func TestUsersFind(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	userRepo := repos.UserRepository
+	ctx := context.Background()
+
+	// case1: found
+	id, err := userRepo.Store(ctx, domain.User{Name: "find-user", FirstName: "f", LastName: "l"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := userRepo.Find(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != id || u.Name != "find-user" || u.FirstName != "f" || u.LastName != "l" {
+		t.Errorf("different found user: %#v", u)
+	}
+
+	// case2: not found
+	if _, err := userRepo.Find(ctx, id+1); !chat.IsNotFoundError(err) {
+		t.Errorf("find not existing user, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestUsersRemoveAndExistsByName(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	userRepo := repos.UserRepository
+	ctx := context.Background()
+
+	friendID, err := userRepo.Store(ctx, domain.User{Name: "friend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := domain.User{Name: "removed-user", FriendIDs: domain.NewUserIDSet(friendID)}
+	u.ID, err = userRepo.Store(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exist, err := userRepo.ExistsByName(ctx, u.Name); err != nil || !exist {
+		t.Fatalf("stored user name does not exist, err: %v", err)
+	}
+
+	if err := userRepo.Remove(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userRepo.Find(ctx, u.ID); !chat.IsNotFoundError(err) {
+		t.Errorf("find removed user, expect NotFoundError but got: %v", err)
+	}
+	if exist, err := userRepo.ExistsByName(ctx, u.Name); err != nil || exist {
+		t.Errorf("removed user name still exists, err: %v", err)
+	}
+
+	// fail case: already removed.
+	if err := userRepo.Remove(ctx, u); !chat.IsNotFoundError(err) {
+		t.Errorf("remove not found user, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestUsersFindByName(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	userRepo := repos.UserRepository
+	ctx := context.Background()
+
+	password := domain.HashedPassword{
+		Algorithm: domain.PasswordAlgorithmBcrypt,
+		Cost:      domain.MinPasswordHashCost,
+		Hash:      "hash",
+	}
+	id, err := userRepo.Store(ctx, domain.User{Name: "auth-user", Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: found
+	u, err := userRepo.FindByName(ctx, "auth-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != id || u.Name != "auth-user" {
+		t.Errorf("different found user: %#v", u)
+	}
+	if u.Password != password {
+		t.Errorf("different password, expect: %v, got: %v", password, u.Password)
+	}
+
+	// case2: not found
+	if _, err := userRepo.FindByName(ctx, "not-found"); !chat.IsNotFoundError(err) {
+		t.Errorf("find not found user, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestUsersFindUserRelation(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	ctx := context.Background()
+
+	friendID, err := repos.Users().Store(ctx, domain.User{Name: "friend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := repos.Users().Store(ctx, domain.User{Name: "user", FriendIDs: domain.NewUserIDSet(friendID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := repos.Rooms().Store(ctx, domain.Room{
+		Name:            "room",
+		OwnerID:         userID,
+		MemberIDSet:     domain.NewUserIDSet(userID),
+		MemberReadTimes: domain.NewTimeSet(userID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	talkRoomID, err := repos.Rooms().Store(ctx, domain.Room{
+		IsTalkRoom:      true,
+		OwnerID:         userID,
+		MemberIDSet:     domain.NewUserIDSet(userID, friendID),
+		MemberReadTimes: domain.NewTimeSet(userID, friendID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// case1: found
+	relation, err := repos.UserRepository.FindUserRelation(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relation.UserID != userID || relation.UserName != "user" {
+		t.Errorf("different user profile: %#v", relation.UserProfile)
+	}
+	if len(relation.Friends) != 1 || relation.Friends[0].UserID != friendID {
+		t.Errorf("different friends, expect id: %v, got: %#v", friendID, relation.Friends)
+	}
+	if len(relation.Rooms) != 1 || relation.Rooms[0].RoomID != roomID {
+		t.Errorf("different rooms, expect id: %v, got: %#v", roomID, relation.Rooms)
+	}
+	if len(relation.TalkRooms) != 1 || relation.TalkRooms[0].RoomID != talkRoomID || relation.TalkRooms[0].FriendID != friendID {
+		t.Errorf("different talk rooms, expect id: %v, got: %#v", talkRoomID, relation.TalkRooms)
+	}
+
+	// case2: not found
+	if _, err := repos.UserRepository.FindUserRelation(ctx, 99); !chat.IsNotFoundError(err) {
+		t.Errorf("find relation for not existing user, expect NotFoundError but got: %v", err)
+	}
+}
+
+func TestUsersFriendRequestsAndBlocks(t *testing.T) {
+	repos := openTestRepositories(t)
+	defer repos.Close()
+	userRepo := repos.UserRepository
+	ctx := context.Background()
+
+	receiverID, err := userRepo.Store(ctx, domain.User{Name: "receiver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockedID, err := userRepo.Store(ctx, domain.User{Name: "blocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderID, err := userRepo.Store(ctx, domain.User{
+		Name:               "sender",
+		RequestedFriendIDs: domain.NewUserIDSet(receiverID),
+		BlockedUserIDs:     domain.NewUserIDSet(blockedID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := userRepo.Find(ctx, senderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sender.RequestedFriendIDs.Has(receiverID) || !sender.BlockedUserIDs.Has(blockedID) {
+		t.Errorf("different stored user: %#v", sender)
+	}
+
+	// pending requests in both directions.
+	senderRelation, err := userRepo.FindUserRelation(ctx, senderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := senderRelation.OutgoingFriendRequests; len(got) != 1 || got[0].UserID != receiverID {
+		t.Errorf("different outgoing friend requests: %#v", got)
+	}
+	receiverRelation, err := userRepo.FindUserRelation(ctx, receiverID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiverRelation.IncomingFriendRequests; len(got) != 1 || got[0].UserID != senderID {
+		t.Errorf("different incoming friend requests: %#v", got)
+	}
+
+	// update removes the answered request.
+	sender.RequestedFriendIDs.Remove(receiverID)
+	if _, err := userRepo.Store(ctx, sender); err != nil {
+		t.Fatal(err)
+	}
+	receiverRelation, err = userRepo.FindUserRelation(ctx, receiverID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := receiverRelation.IncomingFriendRequests; len(got) != 0 {
+		t.Errorf("answered request still remains: %#v", got)
+	}
+}
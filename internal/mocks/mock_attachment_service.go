@@ -0,0 +1,76 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/shirasudon/go-chat/chat (interfaces: AttachmentService)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	queried "github.com/shirasudon/go-chat/chat/queried"
+	io "io"
+	reflect "reflect"
+)
+
+// MockAttachmentService is a mock of AttachmentService interface
+type MockAttachmentService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttachmentServiceMockRecorder
+}
+
+// MockAttachmentServiceMockRecorder is the mock recorder for MockAttachmentService
+type MockAttachmentServiceMockRecorder struct {
+	mock *MockAttachmentService
+}
+
+// NewMockAttachmentService creates a new mock instance
+func NewMockAttachmentService(ctrl *gomock.Controller) *MockAttachmentService {
+	mock := &MockAttachmentService{ctrl: ctrl}
+	mock.recorder = &MockAttachmentServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAttachmentService) EXPECT() *MockAttachmentServiceMockRecorder {
+	return m.recorder
+}
+
+// Find mocks base method
+func (m *MockAttachmentService) Find(arg0 context.Context, arg1, arg2 uint64) (*queried.Attachment, error) {
+	ret := m.ctrl.Call(m, "Find", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *MockAttachmentServiceMockRecorder) Find(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockAttachmentService)(nil).Find), arg0, arg1, arg2)
+}
+
+// Open mocks base method
+func (m *MockAttachmentService) Open(arg0 context.Context, arg1, arg2 uint64, arg3 bool) (*queried.Attachment, io.ReadCloser, error) {
+	ret := m.ctrl.Call(m, "Open", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*queried.Attachment)
+	ret1, _ := ret[1].(io.ReadCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Open indicates an expected call of Open
+func (mr *MockAttachmentServiceMockRecorder) Open(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockAttachmentService)(nil).Open), arg0, arg1, arg2, arg3)
+}
+
+// Upload mocks base method
+func (m *MockAttachmentService) Upload(arg0 context.Context, arg1, arg2 uint64, arg3, arg4 string, arg5 io.Reader) (*queried.Attachment, error) {
+	ret := m.ctrl.Call(m, "Upload", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(*queried.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload
+func (mr *MockAttachmentServiceMockRecorder) Upload(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockAttachmentService)(nil).Upload), arg0, arg1, arg2, arg3, arg4, arg5)
+}
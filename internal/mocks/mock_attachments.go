@@ -0,0 +1,75 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/shirasudon/go-chat/domain (interfaces: AttachmentRepository)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	sql "database/sql"
+	gomock "github.com/golang/mock/gomock"
+	domain "github.com/shirasudon/go-chat/domain"
+	reflect "reflect"
+)
+
+// MockAttachmentRepository is a mock of AttachmentRepository interface
+type MockAttachmentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttachmentRepositoryMockRecorder
+}
+
+// MockAttachmentRepositoryMockRecorder is the mock recorder for MockAttachmentRepository
+type MockAttachmentRepositoryMockRecorder struct {
+	mock *MockAttachmentRepository
+}
+
+// NewMockAttachmentRepository creates a new mock instance
+func NewMockAttachmentRepository(ctrl *gomock.Controller) *MockAttachmentRepository {
+	mock := &MockAttachmentRepository{ctrl: ctrl}
+	mock.recorder = &MockAttachmentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAttachmentRepository) EXPECT() *MockAttachmentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// BeginTx mocks base method
+func (m *MockAttachmentRepository) BeginTx(arg0 context.Context, arg1 *sql.TxOptions) (domain.Tx, error) {
+	ret := m.ctrl.Call(m, "BeginTx", arg0, arg1)
+	ret0, _ := ret[0].(domain.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTx indicates an expected call of BeginTx
+func (mr *MockAttachmentRepositoryMockRecorder) BeginTx(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockAttachmentRepository)(nil).BeginTx), arg0, arg1)
+}
+
+// Find mocks base method
+func (m *MockAttachmentRepository) Find(arg0 context.Context, arg1 uint64) (domain.Attachment, error) {
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].(domain.Attachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *MockAttachmentRepositoryMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockAttachmentRepository)(nil).Find), arg0, arg1)
+}
+
+// Store mocks base method
+func (m *MockAttachmentRepository) Store(arg0 context.Context, arg1 domain.Attachment) (uint64, error) {
+	ret := m.ctrl.Call(m, "Store", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Store indicates an expected call of Store
+func (mr *MockAttachmentRepositoryMockRecorder) Store(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockAttachmentRepository)(nil).Store), arg0, arg1)
+}
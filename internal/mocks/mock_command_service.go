@@ -35,6 +35,32 @@ func (m *MockCommandService) EXPECT() *MockCommandServiceMockRecorder {
 	return m.recorder
 }
 
+// AcceptFriendRequest mocks base method
+func (m *MockCommandService) AcceptFriendRequest(arg0 context.Context, arg1 action.AcceptFriendRequest) (uint64, error) {
+	ret := m.ctrl.Call(m, "AcceptFriendRequest", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptFriendRequest indicates an expected call of AcceptFriendRequest
+func (mr *MockCommandServiceMockRecorder) AcceptFriendRequest(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptFriendRequest", reflect.TypeOf((*MockCommandService)(nil).AcceptFriendRequest), arg0, arg1)
+}
+
+// AddReaction mocks base method
+func (m *MockCommandService) AddReaction(arg0 context.Context, arg1 action.AddReaction) (uint64, error) {
+	ret := m.ctrl.Call(m, "AddReaction", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddReaction indicates an expected call of AddReaction
+func (mr *MockCommandServiceMockRecorder) AddReaction(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReaction", reflect.TypeOf((*MockCommandService)(nil).AddReaction), arg0, arg1)
+}
+
 // AddRoomMember mocks base method
 func (m *MockCommandService) AddRoomMember(arg0 context.Context, arg1 action.AddRoomMember) (*result.AddRoomMember, error) {
 	ret := m.ctrl.Call(m, "AddRoomMember", arg0, arg1)
@@ -48,6 +74,45 @@ func (mr *MockCommandServiceMockRecorder) AddRoomMember(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRoomMember", reflect.TypeOf((*MockCommandService)(nil).AddRoomMember), arg0, arg1)
 }
 
+// BlockUser mocks base method
+func (m *MockCommandService) BlockUser(arg0 context.Context, arg1 action.BlockUser) (uint64, error) {
+	ret := m.ctrl.Call(m, "BlockUser", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockUser indicates an expected call of BlockUser
+func (mr *MockCommandServiceMockRecorder) BlockUser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockCommandService)(nil).BlockUser), arg0, arg1)
+}
+
+// CancelFriendRequest mocks base method
+func (m *MockCommandService) CancelFriendRequest(arg0 context.Context, arg1 action.CancelFriendRequest) (uint64, error) {
+	ret := m.ctrl.Call(m, "CancelFriendRequest", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelFriendRequest indicates an expected call of CancelFriendRequest
+func (mr *MockCommandServiceMockRecorder) CancelFriendRequest(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelFriendRequest", reflect.TypeOf((*MockCommandService)(nil).CancelFriendRequest), arg0, arg1)
+}
+
+// ChangeRoomMemberRole mocks base method
+func (m *MockCommandService) ChangeRoomMemberRole(arg0 context.Context, arg1 action.ChangeRoomMemberRole) (uint64, error) {
+	ret := m.ctrl.Call(m, "ChangeRoomMemberRole", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeRoomMemberRole indicates an expected call of ChangeRoomMemberRole
+func (mr *MockCommandServiceMockRecorder) ChangeRoomMemberRole(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeRoomMemberRole", reflect.TypeOf((*MockCommandService)(nil).ChangeRoomMemberRole), arg0, arg1)
+}
+
 // CreateRoom mocks base method
 func (m *MockCommandService) CreateRoom(arg0 context.Context, arg1 action.CreateRoom) (uint64, error) {
 	ret := m.ctrl.Call(m, "CreateRoom", arg0, arg1)
@@ -61,6 +126,32 @@ func (mr *MockCommandServiceMockRecorder) CreateRoom(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRoom", reflect.TypeOf((*MockCommandService)(nil).CreateRoom), arg0, arg1)
 }
 
+// CreateUser mocks base method
+func (m *MockCommandService) CreateUser(arg0 context.Context, arg1 action.CreateUser) (uint64, error) {
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser
+func (mr *MockCommandServiceMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockCommandService)(nil).CreateUser), arg0, arg1)
+}
+
+// DeclineFriendRequest mocks base method
+func (m *MockCommandService) DeclineFriendRequest(arg0 context.Context, arg1 action.DeclineFriendRequest) (uint64, error) {
+	ret := m.ctrl.Call(m, "DeclineFriendRequest", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeclineFriendRequest indicates an expected call of DeclineFriendRequest
+func (mr *MockCommandServiceMockRecorder) DeclineFriendRequest(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeclineFriendRequest", reflect.TypeOf((*MockCommandService)(nil).DeclineFriendRequest), arg0, arg1)
+}
+
 // DeleteRoom mocks base method
 func (m *MockCommandService) DeleteRoom(arg0 context.Context, arg1 action.DeleteRoom) (uint64, error) {
 	ret := m.ctrl.Call(m, "DeleteRoom", arg0, arg1)
@@ -74,6 +165,97 @@ func (mr *MockCommandServiceMockRecorder) DeleteRoom(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoom", reflect.TypeOf((*MockCommandService)(nil).DeleteRoom), arg0, arg1)
 }
 
+// DeleteRoomMessage mocks base method
+func (m *MockCommandService) DeleteRoomMessage(arg0 context.Context, arg1 action.DeleteChatMessage) (uint64, error) {
+	ret := m.ctrl.Call(m, "DeleteRoomMessage", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRoomMessage indicates an expected call of DeleteRoomMessage
+func (mr *MockCommandServiceMockRecorder) DeleteRoomMessage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoomMessage", reflect.TypeOf((*MockCommandService)(nil).DeleteRoomMessage), arg0, arg1)
+}
+
+// DeleteUser mocks base method
+func (m *MockCommandService) DeleteUser(arg0 context.Context, arg1 action.DeleteUser) (uint64, error) {
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteUser indicates an expected call of DeleteUser
+func (mr *MockCommandServiceMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockCommandService)(nil).DeleteUser), arg0, arg1)
+}
+
+// EditRoomMessage mocks base method
+func (m *MockCommandService) EditRoomMessage(arg0 context.Context, arg1 action.EditChatMessage) (uint64, error) {
+	ret := m.ctrl.Call(m, "EditRoomMessage", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EditRoomMessage indicates an expected call of EditRoomMessage
+func (mr *MockCommandServiceMockRecorder) EditRoomMessage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditRoomMessage", reflect.TypeOf((*MockCommandService)(nil).EditRoomMessage), arg0, arg1)
+}
+
+// EndTyping mocks base method
+func (m *MockCommandService) EndTyping(arg0 context.Context, arg1 action.TypeEnd) (uint64, error) {
+	ret := m.ctrl.Call(m, "EndTyping", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EndTyping indicates an expected call of EndTyping
+func (mr *MockCommandServiceMockRecorder) EndTyping(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndTyping", reflect.TypeOf((*MockCommandService)(nil).EndTyping), arg0, arg1)
+}
+
+// FindOrCreateTalkRoom mocks base method
+func (m *MockCommandService) FindOrCreateTalkRoom(arg0 context.Context, arg1 action.FindOrCreateTalkRoom) (*result.FindOrCreateTalkRoom, error) {
+	ret := m.ctrl.Call(m, "FindOrCreateTalkRoom", arg0, arg1)
+	ret0, _ := ret[0].(*result.FindOrCreateTalkRoom)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreateTalkRoom indicates an expected call of FindOrCreateTalkRoom
+func (mr *MockCommandServiceMockRecorder) FindOrCreateTalkRoom(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateTalkRoom", reflect.TypeOf((*MockCommandService)(nil).FindOrCreateTalkRoom), arg0, arg1)
+}
+
+// LeaveRoom mocks base method
+func (m *MockCommandService) LeaveRoom(arg0 context.Context, arg1 action.LeaveRoom) (uint64, error) {
+	ret := m.ctrl.Call(m, "LeaveRoom", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LeaveRoom indicates an expected call of LeaveRoom
+func (mr *MockCommandServiceMockRecorder) LeaveRoom(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveRoom", reflect.TypeOf((*MockCommandService)(nil).LeaveRoom), arg0, arg1)
+}
+
+// PinMessage mocks base method
+func (m *MockCommandService) PinMessage(arg0 context.Context, arg1 action.PinMessage) (uint64, error) {
+	ret := m.ctrl.Call(m, "PinMessage", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PinMessage indicates an expected call of PinMessage
+func (mr *MockCommandServiceMockRecorder) PinMessage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinMessage", reflect.TypeOf((*MockCommandService)(nil).PinMessage), arg0, arg1)
+}
+
 // PostRoomMessage mocks base method
 func (m *MockCommandService) PostRoomMessage(arg0 context.Context, arg1 action.ChatMessage) (uint64, error) {
 	ret := m.ctrl.Call(m, "PostRoomMessage", arg0, arg1)
@@ -100,6 +282,32 @@ func (mr *MockCommandServiceMockRecorder) ReadRoomMessages(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadRoomMessages", reflect.TypeOf((*MockCommandService)(nil).ReadRoomMessages), arg0, arg1)
 }
 
+// RemoveFriend mocks base method
+func (m *MockCommandService) RemoveFriend(arg0 context.Context, arg1 action.RemoveFriend) (uint64, error) {
+	ret := m.ctrl.Call(m, "RemoveFriend", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveFriend indicates an expected call of RemoveFriend
+func (mr *MockCommandServiceMockRecorder) RemoveFriend(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveFriend", reflect.TypeOf((*MockCommandService)(nil).RemoveFriend), arg0, arg1)
+}
+
+// RemoveReaction mocks base method
+func (m *MockCommandService) RemoveReaction(arg0 context.Context, arg1 action.RemoveReaction) (uint64, error) {
+	ret := m.ctrl.Call(m, "RemoveReaction", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveReaction indicates an expected call of RemoveReaction
+func (mr *MockCommandServiceMockRecorder) RemoveReaction(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveReaction", reflect.TypeOf((*MockCommandService)(nil).RemoveReaction), arg0, arg1)
+}
+
 // RemoveRoomMember mocks base method
 func (m *MockCommandService) RemoveRoomMember(arg0 context.Context, arg1 action.RemoveRoomMember) (*result.RemoveRoomMember, error) {
 	ret := m.ctrl.Call(m, "RemoveRoomMember", arg0, arg1)
@@ -112,3 +320,94 @@ func (m *MockCommandService) RemoveRoomMember(arg0 context.Context, arg1 action.
 func (mr *MockCommandServiceMockRecorder) RemoveRoomMember(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoomMember", reflect.TypeOf((*MockCommandService)(nil).RemoveRoomMember), arg0, arg1)
 }
+
+// RenameRoom mocks base method
+func (m *MockCommandService) RenameRoom(arg0 context.Context, arg1 action.RenameRoom) (uint64, error) {
+	ret := m.ctrl.Call(m, "RenameRoom", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameRoom indicates an expected call of RenameRoom
+func (mr *MockCommandServiceMockRecorder) RenameRoom(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameRoom", reflect.TypeOf((*MockCommandService)(nil).RenameRoom), arg0, arg1)
+}
+
+// SendFriendRequest mocks base method
+func (m *MockCommandService) SendFriendRequest(arg0 context.Context, arg1 action.SendFriendRequest) (uint64, error) {
+	ret := m.ctrl.Call(m, "SendFriendRequest", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendFriendRequest indicates an expected call of SendFriendRequest
+func (mr *MockCommandServiceMockRecorder) SendFriendRequest(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendFriendRequest", reflect.TypeOf((*MockCommandService)(nil).SendFriendRequest), arg0, arg1)
+}
+
+// StartTyping mocks base method
+func (m *MockCommandService) StartTyping(arg0 context.Context, arg1 action.TypeStart) (uint64, error) {
+	ret := m.ctrl.Call(m, "StartTyping", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartTyping indicates an expected call of StartTyping
+func (mr *MockCommandServiceMockRecorder) StartTyping(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartTyping", reflect.TypeOf((*MockCommandService)(nil).StartTyping), arg0, arg1)
+}
+
+// TransferRoomOwnership mocks base method
+func (m *MockCommandService) TransferRoomOwnership(arg0 context.Context, arg1 action.TransferRoomOwnership) (uint64, error) {
+	ret := m.ctrl.Call(m, "TransferRoomOwnership", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferRoomOwnership indicates an expected call of TransferRoomOwnership
+func (mr *MockCommandServiceMockRecorder) TransferRoomOwnership(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferRoomOwnership", reflect.TypeOf((*MockCommandService)(nil).TransferRoomOwnership), arg0, arg1)
+}
+
+// UnblockUser mocks base method
+func (m *MockCommandService) UnblockUser(arg0 context.Context, arg1 action.UnblockUser) (uint64, error) {
+	ret := m.ctrl.Call(m, "UnblockUser", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnblockUser indicates an expected call of UnblockUser
+func (mr *MockCommandServiceMockRecorder) UnblockUser(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockCommandService)(nil).UnblockUser), arg0, arg1)
+}
+
+// UnpinMessage mocks base method
+func (m *MockCommandService) UnpinMessage(arg0 context.Context, arg1 action.UnpinMessage) (uint64, error) {
+	ret := m.ctrl.Call(m, "UnpinMessage", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnpinMessage indicates an expected call of UnpinMessage
+func (mr *MockCommandServiceMockRecorder) UnpinMessage(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinMessage", reflect.TypeOf((*MockCommandService)(nil).UnpinMessage), arg0, arg1)
+}
+
+// UpdateUserProfile mocks base method
+func (m *MockCommandService) UpdateUserProfile(arg0 context.Context, arg1 action.UpdateUserProfile) (uint64, error) {
+	ret := m.ctrl.Call(m, "UpdateUserProfile", arg0, arg1)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserProfile indicates an expected call of UpdateUserProfile
+func (mr *MockCommandServiceMockRecorder) UpdateUserProfile(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserProfile", reflect.TypeOf((*MockCommandService)(nil).UpdateUserProfile), arg0, arg1)
+}
@@ -61,6 +61,19 @@ func (mr *MockMessageRepositoryMockRecorder) Find(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockMessageRepository)(nil).Find), arg0, arg1)
 }
 
+// RecountReplies mocks base method
+func (m *MockMessageRepository) RecountReplies(arg0 context.Context, arg1 uint64) (domain.Message, error) {
+	ret := m.ctrl.Call(m, "RecountReplies", arg0, arg1)
+	ret0, _ := ret[0].(domain.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecountReplies indicates an expected call of RecountReplies
+func (mr *MockMessageRepositoryMockRecorder) RecountReplies(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecountReplies", reflect.TypeOf((*MockMessageRepository)(nil).RecountReplies), arg0, arg1)
+}
+
 // RemoveAllByRoomID mocks base method
 func (m *MockMessageRepository) RemoveAllByRoomID(arg0 context.Context, arg1 uint64) error {
 	ret := m.ctrl.Call(m, "RemoveAllByRoomID", arg0, arg1)
@@ -35,6 +35,19 @@ func (m *MockQueryService) EXPECT() *MockQueryServiceMockRecorder {
 	return m.recorder
 }
 
+// FindBlockedUsers mocks base method
+func (m *MockQueryService) FindBlockedUsers(arg0 context.Context, arg1 uint64) (*queried.BlockedUsers, error) {
+	ret := m.ctrl.Call(m, "FindBlockedUsers", arg0, arg1)
+	ret0, _ := ret[0].(*queried.BlockedUsers)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindBlockedUsers indicates an expected call of FindBlockedUsers
+func (mr *MockQueryServiceMockRecorder) FindBlockedUsers(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBlockedUsers", reflect.TypeOf((*MockQueryService)(nil).FindBlockedUsers), arg0, arg1)
+}
+
 // FindRoomInfo mocks base method
 func (m *MockQueryService) FindRoomInfo(arg0 context.Context, arg1, arg2 uint64) (*queried.RoomInfo, error) {
 	ret := m.ctrl.Call(m, "FindRoomInfo", arg0, arg1, arg2)
@@ -61,6 +74,32 @@ func (mr *MockQueryServiceMockRecorder) FindRoomMessages(arg0, arg1, arg2 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoomMessages", reflect.TypeOf((*MockQueryService)(nil).FindRoomMessages), arg0, arg1, arg2)
 }
 
+// FindRoomPins mocks base method
+func (m *MockQueryService) FindRoomPins(arg0 context.Context, arg1, arg2 uint64) (*queried.RoomPins, error) {
+	ret := m.ctrl.Call(m, "FindRoomPins", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.RoomPins)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRoomPins indicates an expected call of FindRoomPins
+func (mr *MockQueryServiceMockRecorder) FindRoomPins(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoomPins", reflect.TypeOf((*MockQueryService)(nil).FindRoomPins), arg0, arg1, arg2)
+}
+
+// FindThreadMessages mocks base method
+func (m *MockQueryService) FindThreadMessages(arg0 context.Context, arg1 uint64, arg2 action.QueryThreadMessages) (*queried.ThreadMessages, error) {
+	ret := m.ctrl.Call(m, "FindThreadMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.ThreadMessages)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindThreadMessages indicates an expected call of FindThreadMessages
+func (mr *MockQueryServiceMockRecorder) FindThreadMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindThreadMessages", reflect.TypeOf((*MockQueryService)(nil).FindThreadMessages), arg0, arg1, arg2)
+}
+
 // FindUnreadRoomMessages mocks base method
 func (m *MockQueryService) FindUnreadRoomMessages(arg0 context.Context, arg1 uint64, arg2 action.QueryUnreadRoomMessages) (*queried.UnreadRoomMessages, error) {
 	ret := m.ctrl.Call(m, "FindUnreadRoomMessages", arg0, arg1, arg2)
@@ -74,17 +113,30 @@ func (mr *MockQueryServiceMockRecorder) FindUnreadRoomMessages(arg0, arg1, arg2
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnreadRoomMessages", reflect.TypeOf((*MockQueryService)(nil).FindUnreadRoomMessages), arg0, arg1, arg2)
 }
 
-// FindUserByNameAndPassword mocks base method
-func (m *MockQueryService) FindUserByNameAndPassword(arg0 context.Context, arg1, arg2 string) (*queried.AuthUser, error) {
-	ret := m.ctrl.Call(m, "FindUserByNameAndPassword", arg0, arg1, arg2)
-	ret0, _ := ret[0].(*queried.AuthUser)
+// FindUserEvents mocks base method
+func (m *MockQueryService) FindUserEvents(arg0 context.Context, arg1 uint64, arg2 action.QueryUserEvents) (*queried.UserEvents, error) {
+	ret := m.ctrl.Call(m, "FindUserEvents", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.UserEvents)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// FindUserByNameAndPassword indicates an expected call of FindUserByNameAndPassword
-func (mr *MockQueryServiceMockRecorder) FindUserByNameAndPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByNameAndPassword", reflect.TypeOf((*MockQueryService)(nil).FindUserByNameAndPassword), arg0, arg1, arg2)
+// FindUserEvents indicates an expected call of FindUserEvents
+func (mr *MockQueryServiceMockRecorder) FindUserEvents(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserEvents", reflect.TypeOf((*MockQueryService)(nil).FindUserEvents), arg0, arg1, arg2)
+}
+
+// FindUserMentions mocks base method
+func (m *MockQueryService) FindUserMentions(arg0 context.Context, arg1 uint64, arg2 action.QueryUserMentions) (*queried.UserMentions, error) {
+	ret := m.ctrl.Call(m, "FindUserMentions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.UserMentions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserMentions indicates an expected call of FindUserMentions
+func (mr *MockQueryServiceMockRecorder) FindUserMentions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserMentions", reflect.TypeOf((*MockQueryService)(nil).FindUserMentions), arg0, arg1, arg2)
 }
 
 // FindUserRelation mocks base method
@@ -99,3 +151,16 @@ func (m *MockQueryService) FindUserRelation(arg0 context.Context, arg1 uint64) (
 func (mr *MockQueryServiceMockRecorder) FindUserRelation(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserRelation", reflect.TypeOf((*MockQueryService)(nil).FindUserRelation), arg0, arg1)
 }
+
+// SearchMessages mocks base method
+func (m *MockQueryService) SearchMessages(arg0 context.Context, arg1 uint64, arg2 action.QuerySearchMessages) (*queried.SearchedMessages, error) {
+	ret := m.ctrl.Call(m, "SearchMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queried.SearchedMessages)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchMessages indicates an expected call of SearchMessages
+func (mr *MockQueryServiceMockRecorder) SearchMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessages", reflect.TypeOf((*MockQueryService)(nil).SearchMessages), arg0, arg1, arg2)
+}
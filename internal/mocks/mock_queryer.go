@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/shirasudon/go-chat/chat (interfaces: UserQueryer,RoomQueryer,MessageQueryer,EventQueryer)
+// Source: github.com/shirasudon/go-chat/chat (interfaces: UserQueryer,RoomQueryer,MessageQueryer,MessageSearcher,EventQueryer)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
+	action "github.com/shirasudon/go-chat/chat/action"
 	queried "github.com/shirasudon/go-chat/chat/queried"
 	domain "github.com/shirasudon/go-chat/domain"
 	event "github.com/shirasudon/go-chat/domain/event"
@@ -50,19 +51,6 @@ func (mr *MockUserQueryerMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserQueryer)(nil).Find), arg0, arg1)
 }
 
-// FindByNameAndPassword mocks base method
-func (m *MockUserQueryer) FindByNameAndPassword(arg0 context.Context, arg1, arg2 string) (*queried.AuthUser, error) {
-	ret := m.ctrl.Call(m, "FindByNameAndPassword", arg0, arg1, arg2)
-	ret0, _ := ret[0].(*queried.AuthUser)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// FindByNameAndPassword indicates an expected call of FindByNameAndPassword
-func (mr *MockUserQueryerMockRecorder) FindByNameAndPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByNameAndPassword", reflect.TypeOf((*MockUserQueryer)(nil).FindByNameAndPassword), arg0, arg1, arg2)
-}
-
 // FindUserRelation mocks base method
 func (m *MockUserQueryer) FindUserRelation(arg0 context.Context, arg1 uint64) (*queried.UserRelation, error) {
 	ret := m.ctrl.Call(m, "FindUserRelation", arg0, arg1)
@@ -161,6 +149,19 @@ func (m *MockMessageQueryer) EXPECT() *MockMessageQueryerMockRecorder {
 	return m.recorder
 }
 
+// Find mocks base method
+func (m *MockMessageQueryer) Find(arg0 context.Context, arg1 uint64) (domain.Message, error) {
+	ret := m.ctrl.Call(m, "Find", arg0, arg1)
+	ret0, _ := ret[0].(domain.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find
+func (mr *MockMessageQueryerMockRecorder) Find(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockMessageQueryer)(nil).Find), arg0, arg1)
+}
+
 // FindRoomMessagesOrderByLatest mocks base method
 func (m *MockMessageQueryer) FindRoomMessagesOrderByLatest(arg0 context.Context, arg1 uint64, arg2 time.Time, arg3 int) ([]domain.Message, error) {
 	ret := m.ctrl.Call(m, "FindRoomMessagesOrderByLatest", arg0, arg1, arg2, arg3)
@@ -174,6 +175,19 @@ func (mr *MockMessageQueryerMockRecorder) FindRoomMessagesOrderByLatest(arg0, ar
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoomMessagesOrderByLatest", reflect.TypeOf((*MockMessageQueryer)(nil).FindRoomMessagesOrderByLatest), arg0, arg1, arg2, arg3)
 }
 
+// FindThreadMessagesOrderByLatest mocks base method
+func (m *MockMessageQueryer) FindThreadMessagesOrderByLatest(arg0 context.Context, arg1 uint64, arg2 time.Time, arg3 int) ([]domain.Message, error) {
+	ret := m.ctrl.Call(m, "FindThreadMessagesOrderByLatest", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]domain.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindThreadMessagesOrderByLatest indicates an expected call of FindThreadMessagesOrderByLatest
+func (mr *MockMessageQueryerMockRecorder) FindThreadMessagesOrderByLatest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindThreadMessagesOrderByLatest", reflect.TypeOf((*MockMessageQueryer)(nil).FindThreadMessagesOrderByLatest), arg0, arg1, arg2, arg3)
+}
+
 // FindUnreadRoomMessages mocks base method
 func (m *MockMessageQueryer) FindUnreadRoomMessages(arg0 context.Context, arg1, arg2 uint64, arg3 int) (*queried.UnreadRoomMessages, error) {
 	ret := m.ctrl.Call(m, "FindUnreadRoomMessages", arg0, arg1, arg2, arg3)
@@ -187,6 +201,55 @@ func (mr *MockMessageQueryerMockRecorder) FindUnreadRoomMessages(arg0, arg1, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnreadRoomMessages", reflect.TypeOf((*MockMessageQueryer)(nil).FindUnreadRoomMessages), arg0, arg1, arg2, arg3)
 }
 
+// FindUserMentions mocks base method
+func (m *MockMessageQueryer) FindUserMentions(arg0 context.Context, arg1 uint64, arg2 time.Time, arg3 int) (*queried.UserMentions, error) {
+	ret := m.ctrl.Call(m, "FindUserMentions", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*queried.UserMentions)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserMentions indicates an expected call of FindUserMentions
+func (mr *MockMessageQueryerMockRecorder) FindUserMentions(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserMentions", reflect.TypeOf((*MockMessageQueryer)(nil).FindUserMentions), arg0, arg1, arg2, arg3)
+}
+
+// MockMessageSearcher is a mock of MessageSearcher interface
+type MockMessageSearcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockMessageSearcherMockRecorder
+}
+
+// MockMessageSearcherMockRecorder is the mock recorder for MockMessageSearcher
+type MockMessageSearcherMockRecorder struct {
+	mock *MockMessageSearcher
+}
+
+// NewMockMessageSearcher creates a new mock instance
+func NewMockMessageSearcher(ctrl *gomock.Controller) *MockMessageSearcher {
+	mock := &MockMessageSearcher{ctrl: ctrl}
+	mock.recorder = &MockMessageSearcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMessageSearcher) EXPECT() *MockMessageSearcherMockRecorder {
+	return m.recorder
+}
+
+// SearchMessages mocks base method
+func (m *MockMessageSearcher) SearchMessages(arg0 context.Context, arg1 []uint64, arg2 action.QuerySearchMessages) ([]queried.MessageSearchResult, error) {
+	ret := m.ctrl.Call(m, "SearchMessages", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]queried.MessageSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchMessages indicates an expected call of SearchMessages
+func (mr *MockMessageSearcherMockRecorder) SearchMessages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchMessages", reflect.TypeOf((*MockMessageSearcher)(nil).SearchMessages), arg0, arg1, arg2)
+}
+
 // MockEventQueryer is a mock of EventQueryer interface
 type MockEventQueryer struct {
 	ctrl     *gomock.Controller
@@ -210,6 +273,19 @@ func (m *MockEventQueryer) EXPECT() *MockEventQueryerMockRecorder {
 	return m.recorder
 }
 
+// FindAllBySequence mocks base method
+func (m *MockEventQueryer) FindAllBySequence(arg0 context.Context, arg1 uint64, arg2 int) ([]event.Record, error) {
+	ret := m.ctrl.Call(m, "FindAllBySequence", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]event.Record)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllBySequence indicates an expected call of FindAllBySequence
+func (mr *MockEventQueryerMockRecorder) FindAllBySequence(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllBySequence", reflect.TypeOf((*MockEventQueryer)(nil).FindAllBySequence), arg0, arg1, arg2)
+}
+
 // FindAllByStreamID mocks base method
 func (m *MockEventQueryer) FindAllByStreamID(arg0 context.Context, arg1 event.StreamID, arg2 time.Time, arg3 int) ([]event.Event, error) {
 	ret := m.ctrl.Call(m, "FindAllByStreamID", arg0, arg1, arg2, arg3)
@@ -223,6 +299,19 @@ func (mr *MockEventQueryerMockRecorder) FindAllByStreamID(arg0, arg1, arg2, arg3
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllByStreamID", reflect.TypeOf((*MockEventQueryer)(nil).FindAllByStreamID), arg0, arg1, arg2, arg3)
 }
 
+// FindAllByStreamIDSequence mocks base method
+func (m *MockEventQueryer) FindAllByStreamIDSequence(arg0 context.Context, arg1 event.StreamID, arg2 uint64, arg3 int) ([]event.Record, error) {
+	ret := m.ctrl.Call(m, "FindAllByStreamIDSequence", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]event.Record)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAllByStreamIDSequence indicates an expected call of FindAllByStreamIDSequence
+func (mr *MockEventQueryerMockRecorder) FindAllByStreamIDSequence(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAllByStreamIDSequence", reflect.TypeOf((*MockEventQueryer)(nil).FindAllByStreamIDSequence), arg0, arg1, arg2, arg3)
+}
+
 // FindAllByTimeCursor mocks base method
 func (m *MockEventQueryer) FindAllByTimeCursor(arg0 context.Context, arg1 time.Time, arg2 int) ([]event.Event, error) {
 	ret := m.ctrl.Call(m, "FindAllByTimeCursor", arg0, arg1, arg2)
@@ -34,6 +34,18 @@ func (m *MockRepositories) EXPECT() *MockRepositoriesMockRecorder {
 	return m.recorder
 }
 
+// Attachments mocks base method
+func (m *MockRepositories) Attachments() domain.AttachmentRepository {
+	ret := m.ctrl.Call(m, "Attachments")
+	ret0, _ := ret[0].(domain.AttachmentRepository)
+	return ret0
+}
+
+// Attachments indicates an expected call of Attachments
+func (mr *MockRepositoriesMockRecorder) Attachments() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attachments", reflect.TypeOf((*MockRepositories)(nil).Attachments))
+}
+
 // Events mocks base method
 func (m *MockRepositories) Events() event.EventRepository {
 	ret := m.ctrl.Call(m, "Events")
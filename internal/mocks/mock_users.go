@@ -48,6 +48,19 @@ func (mr *MockUserRepositoryMockRecorder) BeginTx(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockUserRepository)(nil).BeginTx), arg0, arg1)
 }
 
+// ExistsByName mocks base method
+func (m *MockUserRepository) ExistsByName(arg0 context.Context, arg1 string) (bool, error) {
+	ret := m.ctrl.Call(m, "ExistsByName", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExistsByName indicates an expected call of ExistsByName
+func (mr *MockUserRepositoryMockRecorder) ExistsByName(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistsByName", reflect.TypeOf((*MockUserRepository)(nil).ExistsByName), arg0, arg1)
+}
+
 // Find mocks base method
 func (m *MockUserRepository) Find(arg0 context.Context, arg1 uint64) (domain.User, error) {
 	ret := m.ctrl.Call(m, "Find", arg0, arg1)
@@ -61,6 +74,31 @@ func (mr *MockUserRepositoryMockRecorder) Find(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockUserRepository)(nil).Find), arg0, arg1)
 }
 
+// FindByName mocks base method
+func (m *MockUserRepository) FindByName(arg0 context.Context, arg1 string) (domain.User, error) {
+	ret := m.ctrl.Call(m, "FindByName", arg0, arg1)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByName indicates an expected call of FindByName
+func (mr *MockUserRepositoryMockRecorder) FindByName(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByName", reflect.TypeOf((*MockUserRepository)(nil).FindByName), arg0, arg1)
+}
+
+// Remove mocks base method
+func (m *MockUserRepository) Remove(arg0 context.Context, arg1 domain.User) error {
+	ret := m.ctrl.Call(m, "Remove", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove
+func (mr *MockUserRepositoryMockRecorder) Remove(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockUserRepository)(nil).Remove), arg0, arg1)
+}
+
 // Store mocks base method
 func (m *MockUserRepository) Store(arg0 context.Context, arg1 domain.User) (uint64, error) {
 	ret := m.ctrl.Call(m, "Store", arg0, arg1)
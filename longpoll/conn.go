@@ -0,0 +1,294 @@
+package longpoll
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// MaxBufferedEvents is the maximum number of the events buffered
+// in the Conn until they are received by the client.
+// The Conn is closed when the buffer overflows by Send, then the client
+// should reconnect with the last received sequence number to
+// receive the missed events.
+// SendWait, which is used to replay the stored events, waits for
+// the buffer to be polled instead.
+const MaxBufferedEvents = 1000
+
+// Batch is the result of the polling which is responded to the client.
+type Batch struct {
+	SessionID string        `json:"session_id"`
+	Events    []event.Event `json:"events"`
+
+	// Cursor is the position of the last event in the batch.
+	// The next polling with it acknowledges the events until it,
+	// and the events after it are returned.
+	Cursor uint64 `json:"cursor"`
+}
+
+// Conn is end-point for buffering the events until the client polls them.
+// One Conn corresponds to one poll session of the browser-side client.
+type Conn struct {
+	sessionID string
+	userID    uint64
+
+	mu       *sync.Mutex
+	events   []event.Event // under mu
+	first    uint64        // position of events[0], under mu
+	polling  chan struct{} // closed to cancel the current poll, under mu
+	lastPoll time.Time     // under mu
+	closed   bool          // under mu
+	done     chan struct{} // done is managed by closed.
+	stalled  chan struct{} // closed when SendWait waits first, under mu
+
+	notify chan struct{}
+	space  chan struct{} // notified when the events are acknowledged.
+
+	onClosed func(*Conn)
+}
+
+// NewConn creates the Conn with new random session ID.
+func NewConn(userID uint64) (*Conn, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	return &Conn{
+		sessionID: hex.EncodeToString(b[:]),
+		userID:    userID,
+		mu:        new(sync.Mutex),
+		events:    make([]event.Event, 0, 8),
+		first:     1,
+		lastPoll:  time.Now(),
+		closed:    false,
+		done:      make(chan struct{}, 1),
+		stalled:   make(chan struct{}),
+		notify:    make(chan struct{}, 1),
+		space:     make(chan struct{}, 1),
+	}, nil
+}
+
+// SessionID returns the ID to identify the poll session.
+func (c *Conn) SessionID() string {
+	return c.sessionID
+}
+
+// UserID returns user ID binding to the connection.
+func (c *Conn) UserID() uint64 {
+	return c.userID
+}
+
+// set callback function to handle the event for the connection is closed .
+// the callback function may be called asynchronously.
+func (c *Conn) OnClosed(f func(*Conn)) {
+	c.onClosed = f
+}
+
+// Send buffers the event until the client polls it.
+// It never blocks, and the event is ignored when Conn is closed.
+func (c *Conn) Send(ev event.Event) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	if len(c.events) >= MaxBufferedEvents {
+		c.mu.Unlock()
+		// Send may be called by the hub, which is also called back
+		// by closing, so it is closed on other goroutine.
+		go c.Close()
+		return
+	}
+	c.events = append(c.events, ev)
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// SendWait buffers the event as same as Send, but it waits for
+// the client to poll the buffered events when the buffer is full,
+// instead of closing the Conn.
+// It returns ErrAlreadyClosed when the Conn is closed, or
+// the context error when the context is done.
+func (c *Conn) SendWait(ctx context.Context, ev event.Event) error {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return ErrAlreadyClosed
+		}
+		if len(c.events) < MaxBufferedEvents {
+			c.events = append(c.events, ev)
+			c.mu.Unlock()
+
+			select {
+			case c.notify <- struct{}{}:
+			default:
+			}
+			return nil
+		}
+		select {
+		case <-c.stalled:
+		default:
+			close(c.stalled)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.space:
+		case <-c.done:
+			return ErrAlreadyClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Stalled returns the channel which is closed when the SendWait
+// waits for the buffer to be polled.
+func (c *Conn) Stalled() <-chan struct{} {
+	return c.stalled
+}
+
+// Done returns the channel which is closed when the Conn is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+var ErrAlreadyClosed = errors.New("already closed")
+
+// Close stops Poll() immediately.
+// it returns ErrAlreadyClosed when the Conn is
+// already closed otherwise nil.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrAlreadyClosed
+	}
+
+	c.closed = true
+	close(c.done)
+	c.events = nil
+	c.mu.Unlock() // to avoid dead lock, Unlock before OnClosed.
+
+	if c.onClosed != nil {
+		c.onClosed(c)
+	}
+	return nil
+}
+
+// Poll acknowledges the events until the cursor, then returns
+// at most limit events after the cursor.
+// It blocks until any event is buffered, the timeout is passed or
+// context is done, so the returned batch may have no events.
+// The previous Poll blocking yet returns immediately.
+// It returns ErrAlreadyClosed when the Conn is closed.
+func (c *Conn) Poll(ctx context.Context, cursor uint64, limit int, timeout time.Duration) (*Batch, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrAlreadyClosed
+	}
+	c.ack(cursor)
+	if c.polling != nil {
+		close(c.polling)
+	}
+	polling := make(chan struct{})
+	c.polling = polling
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		if c.polling == polling {
+			c.polling = nil
+		}
+		c.lastPoll = time.Now()
+		c.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// the notification may be left by the event already polled,
+	// so the buffer is checked after notified.
+	for !c.hasEvents() {
+		select {
+		case <-c.notify:
+			continue
+		case <-polling:
+		case <-timer.C:
+		case <-ctx.Done():
+		case <-c.done:
+			return nil, ErrAlreadyClosed
+		}
+		break
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrAlreadyClosed
+	}
+	n := len(c.events)
+	if n > limit {
+		n = limit
+	}
+	batch := &Batch{
+		SessionID: c.sessionID,
+		Events:    append(make([]event.Event, 0, n), c.events[:n]...),
+		Cursor:    c.first + uint64(n) - 1,
+	}
+	return batch, nil
+}
+
+// ack removes the events until the cursor.
+// It must be called under the mu.
+func (c *Conn) ack(cursor uint64) {
+	if cursor < c.first {
+		return
+	}
+	n := cursor - c.first + 1
+	if n > uint64(len(c.events)) {
+		n = uint64(len(c.events))
+	}
+	// the acknowledged events are released.
+	rest := make([]event.Event, 0, len(c.events)-int(n)+8)
+	c.events = append(rest, c.events[n:]...)
+	c.first += n
+
+	if n > 0 {
+		select {
+		case c.space <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// isExpired returns whether the Conn is not polled for the idle
+// duration at now.
+func (c *Conn) isExpired(now time.Time, idle time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.polling == nil && now.Sub(c.lastPoll) > idle
+}
+
+func (c *Conn) hasEvents() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events) > 0
+}
+
+func (c *Conn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
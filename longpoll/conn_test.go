@@ -0,0 +1,231 @@
+package longpoll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+const Timeout = 10 * time.Millisecond
+
+func contentsOf(batch *Batch) []string {
+	contents := make([]string, 0, len(batch.Events))
+	for _, ev := range batch.Events {
+		contents = append(contents, ev.(event.MessageCreated).Content)
+	}
+	return contents
+}
+
+func TestNewConn(t *testing.T) {
+	const UserID = uint64(1)
+	c1, err := NewConn(UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewConn(UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.UserID() != UserID {
+		t.Errorf("different user id, expect: %v, got: %v", UserID, c1.UserID())
+	}
+	if c1.SessionID() == "" || c1.SessionID() == c2.SessionID() {
+		t.Errorf("session id should be unique, got: %v and %v", c1.SessionID(), c2.SessionID())
+	}
+}
+
+func TestConnPoll(t *testing.T) {
+	conn, err := NewConn(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	conn.Send(event.MessageCreated{Content: "1"})
+	conn.Send(event.MessageCreated{Content: "2"})
+	conn.Send(event.MessageCreated{Content: "3"})
+
+	// the buffered events are returned immediately.
+	batch, err := conn.Poll(ctx, 0, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contentsOf(batch); len(got) != 2 || got[0] != "1" || got[1] != "2" || batch.Cursor != 2 {
+		t.Errorf("different batch, got: %v with cursor %v", got, batch.Cursor)
+	}
+	if batch.SessionID != conn.SessionID() {
+		t.Errorf("different session id, expect: %v, got: %v", conn.SessionID(), batch.SessionID)
+	}
+
+	// the events are returned again until acknowledged.
+	batch, err = conn.Poll(ctx, 0, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contentsOf(batch); len(got) != 3 || batch.Cursor != 3 {
+		t.Errorf("different batch, got: %v with cursor %v", got, batch.Cursor)
+	}
+
+	// acknowledge the events until the cursor.
+	batch, err = conn.Poll(ctx, 2, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contentsOf(batch); len(got) != 1 || got[0] != "3" || batch.Cursor != 3 {
+		t.Errorf("different batch, got: %v with cursor %v", got, batch.Cursor)
+	}
+
+	// no events until timeout.
+	start := time.Now()
+	batch, err = conn.Poll(ctx, batch.Cursor, 10, Timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Events) != 0 || batch.Cursor != 3 {
+		t.Errorf("different batch, got: %v with cursor %v", contentsOf(batch), batch.Cursor)
+	}
+	if time.Since(start) < Timeout {
+		t.Errorf("Poll returns before timeout")
+	}
+
+	// the waiting poll returns after the event is sent.
+	go func() {
+		time.Sleep(Timeout)
+		conn.Send(event.MessageCreated{Content: "4"})
+	}()
+	batch, err = conn.Poll(ctx, batch.Cursor, 10, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contentsOf(batch); len(got) != 1 || got[0] != "4" || batch.Cursor != 4 {
+		t.Errorf("different batch, got: %v with cursor %v", got, batch.Cursor)
+	}
+}
+
+func TestConnPollCanceled(t *testing.T) {
+	conn, err := NewConn(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the previous poll is returned by the next poll.
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Poll(context.Background(), 0, 10, time.Second)
+		done <- err
+	}()
+	time.Sleep(Timeout)
+	go conn.Poll(context.Background(), 0, 10, time.Second)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("the previous poll returns error: %v", err)
+		}
+	case <-time.After(10 * Timeout):
+		t.Fatal("the previous poll is not returned")
+	}
+
+	// the waiting poll returns error by closing.
+	closed := false
+	conn.OnClosed(func(*Conn) { closed = true })
+	go func() {
+		_, err := conn.Poll(context.Background(), 0, 10, time.Second)
+		done <- err
+	}()
+	time.Sleep(Timeout)
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != ErrAlreadyClosed {
+		t.Errorf("closing twice should return ErrAlreadyClosed, got: %v", err)
+	}
+	if !closed {
+		t.Error("OnClosed is not called")
+	}
+	select {
+	case err := <-done:
+		if err != ErrAlreadyClosed {
+			t.Errorf("the poll should return ErrAlreadyClosed, got: %v", err)
+		}
+	case <-time.After(10 * Timeout):
+		t.Fatal("the poll is not returned after closed")
+	}
+
+	// the closed conn ignores the event.
+	conn.Send(event.MessageCreated{})
+}
+
+func TestConnBufferOverflow(t *testing.T) {
+	conn, err := NewConn(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan struct{})
+	conn.OnClosed(func(*Conn) { close(closed) })
+
+	for i := 0; i <= MaxBufferedEvents; i++ {
+		conn.Send(event.MessageCreated{})
+	}
+	select {
+	case <-closed:
+	case <-time.After(Timeout):
+		t.Fatal("the conn is not closed after the buffer overflows")
+	}
+}
+
+func TestConnSendWait(t *testing.T) {
+	conn, err := NewConn(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < MaxBufferedEvents; i++ {
+		if err := conn.SendWait(ctx, event.MessageCreated{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the full buffer waits for polling instead of closing.
+	sent := make(chan error, 1)
+	go func() { sent <- conn.SendWait(ctx, event.MessageCreated{Content: "last"}) }()
+	select {
+	case <-conn.Stalled():
+	case <-time.After(Timeout):
+		t.Fatal("SendWait does not wait for the full buffer")
+	}
+
+	batch, err := conn.Poll(ctx, 0, 1, Timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Poll(ctx, batch.Cursor, 1, Timeout); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(Timeout):
+		t.Fatal("SendWait does not return after the events are acknowledged")
+	}
+	if conn.isClosed() {
+		t.Error("the conn is closed by SendWait")
+	}
+
+	// the waiting is canceled by closing.
+	go func() { sent <- conn.SendWait(ctx, event.MessageCreated{}) }()
+	conn.Close()
+	select {
+	case err := <-sent:
+		if err != ErrAlreadyClosed {
+			t.Errorf("expect ErrAlreadyClosed, got: %v", err)
+		}
+	case <-time.After(Timeout):
+		t.Fatal("SendWait does not return after the conn is closed")
+	}
+}
@@ -0,0 +1,5 @@
+// package longpoll defines implementation for the
+// long-polling connetion, which is the fallback for the client
+// using neither the websocket nor the server-sent events.
+
+package longpoll
@@ -0,0 +1,133 @@
+package longpoll
+
+import (
+	"sync"
+	"time"
+)
+
+// Sessions manages the poll sessions, Conns, by the session ID.
+// The Conn which is not polled for the idle timeout is expired,
+// that is, closed and removed from the Sessions.
+type Sessions struct {
+	idleTimeout time.Duration
+
+	mu    *sync.Mutex
+	conns map[string]*Conn // under mu
+
+	shutdown chan struct{}
+	once     *sync.Once
+}
+
+// NewSessions creates the Sessions which expires the Conns
+// not polled for the idleTimeout. The non-positive idleTimeout
+// means the Conns are never expired.
+// Shutdown should be called to stop expiring after used.
+func NewSessions(idleTimeout time.Duration) *Sessions {
+	s := &Sessions{
+		idleTimeout: idleTimeout,
+		mu:          new(sync.Mutex),
+		conns:       make(map[string]*Conn),
+		shutdown:    make(chan struct{}),
+		once:        new(sync.Once),
+	}
+	if idleTimeout > 0 {
+		go s.expireLoop()
+	}
+	return s
+}
+
+// Add adds the Conn to the Sessions.
+func (s *Sessions) Add(c *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[c.SessionID()] = c
+}
+
+// Find returns the Conn specified by the session ID.
+// It returns false if the Conn is not found or already closed.
+func (s *Sessions) Find(sessionID string) (*Conn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.conns[sessionID]
+	if !ok {
+		return nil, false
+	}
+	if c.isClosed() {
+		delete(s.conns, sessionID)
+		return nil, false
+	}
+	return c, true
+}
+
+// Remove closes the Conn specified by the session ID and
+// removes it from the Sessions.
+// It returns false if the Conn is not found.
+func (s *Sessions) Remove(sessionID string) bool {
+	s.mu.Lock()
+	c, ok := s.conns[sessionID]
+	delete(s.conns, sessionID)
+	s.mu.Unlock()
+
+	if ok {
+		c.Close()
+	}
+	return ok
+}
+
+// Len returns the number of the Conns in the Sessions.
+func (s *Sessions) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Shutdown stops expiring and closes all of the Conns.
+// It is safe to call multiple times.
+func (s *Sessions) Shutdown() {
+	s.once.Do(func() {
+		close(s.shutdown)
+
+		s.mu.Lock()
+		conns := s.conns
+		s.conns = make(map[string]*Conn)
+		s.mu.Unlock()
+
+		for _, c := range conns {
+			c.Close()
+		}
+	})
+}
+
+func (s *Sessions) expireLoop() {
+	ticker := time.NewTicker(s.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.shutdown:
+			return
+		case now := <-ticker.C:
+			s.expire(now)
+		}
+	}
+}
+
+// expire closes and removes the Conns which are closed or
+// not polled for the idle timeout at now.
+func (s *Sessions) expire(now time.Time) {
+	expired := make([]*Conn, 0, 4)
+
+	s.mu.Lock()
+	for id, c := range s.conns {
+		if c.isClosed() || c.isExpired(now, s.idleTimeout) {
+			delete(s.conns, id)
+			expired = append(expired, c)
+		}
+	}
+	s.mu.Unlock()
+
+	// to avoid dead lock, close them after Unlock.
+	for _, c := range expired {
+		c.Close()
+	}
+}
@@ -0,0 +1,86 @@
+package longpoll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+func TestSessions(t *testing.T) {
+	const IdleTimeout = time.Minute
+	sessions := NewSessions(IdleTimeout)
+	defer sessions.Shutdown()
+
+	conn, err := NewConn(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions.Add(conn)
+	if got, ok := sessions.Find(conn.SessionID()); !ok || got != conn {
+		t.Fatalf("added conn is not found")
+	}
+	if _, ok := sessions.Find("not-found"); ok {
+		t.Errorf("not added conn is found")
+	}
+
+	// the polling conn is not expired.
+	go conn.Poll(context.Background(), 0, 10, time.Second)
+	time.Sleep(Timeout)
+	sessions.expire(time.Now().Add(2 * IdleTimeout))
+	if _, ok := sessions.Find(conn.SessionID()); !ok {
+		t.Fatalf("the polling conn is expired")
+	}
+
+	// the idle conn is expired.
+	conn.Send(event.MessageCreated{}) // to return the poll.
+	time.Sleep(Timeout)
+	sessions.expire(time.Now().Add(IdleTimeout / 2))
+	if _, ok := sessions.Find(conn.SessionID()); !ok {
+		t.Fatalf("the conn is expired before the idle timeout")
+	}
+	sessions.expire(time.Now().Add(2 * IdleTimeout))
+	if _, ok := sessions.Find(conn.SessionID()); ok {
+		t.Errorf("the idle conn is not expired")
+	}
+	if !conn.isClosed() {
+		t.Errorf("the expired conn is not closed")
+	}
+}
+
+func TestSessionsRemoveAndShutdown(t *testing.T) {
+	sessions := NewSessions(time.Minute)
+
+	conns := make([]*Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := NewConn(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions.Add(conn)
+		conns = append(conns, conn)
+	}
+
+	if !sessions.Remove(conns[0].SessionID()) {
+		t.Fatal("added conn can not be removed")
+	}
+	if !conns[0].isClosed() {
+		t.Errorf("the removed conn is not closed")
+	}
+	if sessions.Remove(conns[0].SessionID()) {
+		t.Errorf("removed conn is removed again")
+	}
+
+	// the closed conn is not found.
+	conns[1].Close()
+	if _, ok := sessions.Find(conns[1].SessionID()); ok {
+		t.Errorf("closed conn is found")
+	}
+
+	sessions.Shutdown()
+	sessions.Shutdown() // no panic
+	if !conns[2].isClosed() || sessions.Len() != 0 {
+		t.Errorf("the conns are not closed by shutdown")
+	}
+}
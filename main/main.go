@@ -8,45 +8,125 @@ import (
 	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/domain"
 	"github.com/shirasudon/go-chat/infra/config"
+	"github.com/shirasudon/go-chat/infra/eventlog"
 	"github.com/shirasudon/go-chat/infra/inmemory"
+	"github.com/shirasudon/go-chat/infra/localfs"
 	"github.com/shirasudon/go-chat/infra/pubsub"
+	"github.com/shirasudon/go-chat/infra/sqlite3"
 	"github.com/shirasudon/go-chat/server"
 )
 
 type DoneFunc func()
 
-func createInfra() (domain.Repositories, *chat.Queryers, chat.Pubsub, DoneFunc) {
+func createInfra(databaseFile, eventLogDir, attachmentDir string) (domain.Repositories, *chat.Queryers, chat.Pubsub, chat.BlobStore, DoneFunc) {
 	ps := pubsub.New()
 	doneFuncs := make([]func(), 0, 4)
 	doneFuncs = append(doneFuncs, ps.Shutdown)
 
-	repos := inmemory.OpenRepositories(ps)
-	doneFuncs = append(doneFuncs, func() { _ = repos.Close() })
-
 	ctx, cancel := context.WithCancel(context.Background())
 	doneFuncs = append(doneFuncs, cancel)
-	go repos.UpdatingService(ctx)
 
-	qs := &chat.Queryers{
-		UserQueryer:    repos.UserRepository,
-		RoomQueryer:    repos.RoomRepository,
-		MessageQueryer: repos.MessageRepository,
-		EventQueryer:   repos.EventRepository,
+	var (
+		repos domain.Repositories
+		qs    *chat.Queryers
+	)
+	if len(databaseFile) > 0 {
+		log.Printf("[Database] Opening file: %s\n", databaseFile)
+		sqlRepos, err := sqlite3.Open(databaseFile)
+		if err != nil {
+			log.Fatalf("[Database] Open Error: %v", err)
+		}
+		doneFuncs = append(doneFuncs, func() { _ = sqlRepos.Close() })
+
+		repos = sqlRepos
+		qs = &chat.Queryers{
+			UserQueryer:    sqlRepos.UserRepository,
+			RoomQueryer:    sqlRepos.RoomRepository,
+			MessageQueryer: sqlRepos.MessageRepository,
+			EventQueryer:   sqlRepos.EventRepository,
+		}
+	} else {
+		log.Println("[Database] Use in-memory")
+		memRepos := inmemory.OpenRepositories(ps)
+		doneFuncs = append(doneFuncs, func() { _ = memRepos.Close() })
+
+		go memRepos.UpdatingService(ctx)
+
+		repos = memRepos
+		qs = &chat.Queryers{
+			UserQueryer:    memRepos.UserRepository,
+			RoomQueryer:    memRepos.RoomRepository,
+			MessageQueryer: memRepos.MessageRepository,
+			EventQueryer:   memRepos.EventRepository,
+		}
+	}
+
+	if len(eventLogDir) > 0 {
+		log.Printf("[EventLog] Opening directory: %s\n", eventLogDir)
+		events, err := eventlog.Open(eventLogDir, eventlog.Options{})
+		if err != nil {
+			log.Fatalf("[EventLog] Open Error: %v", err)
+		}
+		doneFuncs = append(doneFuncs, func() { _ = events.Close() })
+
+		repos = domain.SimpleRepositories{
+			UserRepository:    repos.Users(),
+			MessageRepository: repos.Messages(),
+			RoomRepository:    repos.Rooms(),
+			EventRepository:   events,
+
+			AttachmentRepository: repos.Attachments(),
+		}
+		qs.EventQueryer = events
+	}
+
+	// the index is built from the stored events, then kept current
+	// by the events published after that.
+	index := inmemory.NewMessageIndex(ps)
+	if err := index.Rebuild(ctx, qs.EventQueryer); err != nil {
+		log.Fatalf("[Search] Rebuild Index Error: %v", err)
+	}
+	go index.UpdatingService(ctx)
+	qs.MessageSearcher = index
+
+	var blobs chat.BlobStore
+	if len(attachmentDir) > 0 {
+		log.Printf("[Attachment] Opening directory: %s\n", attachmentDir)
+		fsBlobs, err := localfs.Open(attachmentDir)
+		if err != nil {
+			log.Fatalf("[Attachment] Open Error: %v", err)
+		}
+		blobs = fsBlobs
+	} else {
+		log.Println("[Attachment] Use in-memory")
+		blobs = inmemory.NewBlobStore()
 	}
 
 	done := func() {
 		// reverse order to simulate defer statement.
-		for i := len(doneFuncs); i >= 0; i-- {
+		for i := len(doneFuncs) - 1; i >= 0; i-- {
 			doneFuncs[i]()
 		}
 	}
 
-	return repos, qs, ps, done
+	return repos, qs, ps, blobs, done
 }
 
 const (
 	DefaultConfigFile = "config.toml"
 	KeyConfigFileENV  = "GOCHAT_CONFIG_FILE"
+
+	// the sqlite3 database file is used instead of in-memory
+	// data-store if it is set.
+	KeyDatabaseFileENV = "GOCHAT_DATABASE_FILE"
+
+	// the events are stored into the append-only log files
+	// in the directory if it is set.
+	KeyEventLogDirENV = "GOCHAT_EVENT_LOG_DIR"
+
+	// the attachment files are stored in the directory
+	// instead of in-memory if it is set.
+	KeyAttachmentDirENV = "GOCHAT_ATTACHMENT_DIR"
 )
 
 func main() {
@@ -68,8 +148,12 @@ func main() {
 		log.Println("[Config] Use default")
 	}
 
-	repos, qs, ps, infraDoneFunc := createInfra()
-	s, done := server.CreateServerFromInfra(repos, qs, ps, &defaultConf)
+	repos, qs, ps, blobs, infraDoneFunc := createInfra(
+		os.Getenv(KeyDatabaseFileENV),
+		os.Getenv(KeyEventLogDirENV),
+		os.Getenv(KeyAttachmentDirENV),
+	)
+	s, done := server.CreateServerFromInfra(repos, qs, ps, blobs, &defaultConf)
 	defer func() {
 		done()
 		infraDoneFunc()
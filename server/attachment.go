@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain"
+)
+
+const (
+	// key for the URL parameter of the attachment ID.
+	ParamKeyAttachmentID = "attachment_id"
+
+	// key for the multipart form file to upload.
+	FormKeyAttachmentFile = "file"
+
+	// multipartOverhead is the allowance for the multipart headers
+	// and boundaries in the upload request body.
+	multipartOverhead = 64 * 1024
+)
+
+// AttachmentHandler handles the uploading and downloading
+// the attachment files.
+type AttachmentHandler struct {
+	attachments chat.AttachmentService
+	conf        Config
+}
+
+// NewAttachmentHandler creates AttachmentHandler which limits
+// the attachment files by the config.
+func NewAttachmentHandler(attachments chat.AttachmentService, conf *Config) *AttachmentHandler {
+	if conf == nil {
+		conf = &DefaultConfig
+	}
+	return &AttachmentHandler{
+		attachments: attachments,
+		conf:        *conf,
+	}
+}
+
+func validateParamAttachmentID(e echo.Context) (uint64, error) {
+	param := e.Param(ParamKeyAttachmentID)
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested attachment id(%v) is not allowed", param))
+	}
+
+	return id, nil
+}
+
+// attachmentHTTPError converts the error from the AttachmentService
+// into the HTTPError.
+func attachmentHTTPError(err error) error {
+	if domain.IsValidationError(err) {
+		return NewHTTPError(http.StatusBadRequest, err)
+	}
+	if domain.IsPermissionError(err) {
+		return NewHTTPError(http.StatusForbidden, err)
+	}
+	if chat.IsNotFoundError(err) {
+		return NewHTTPError(http.StatusNotFound, err)
+	}
+	return NewHTTPError(http.StatusInternalServerError, err)
+}
+
+// detectContentType detects the MIME type of the content
+// without any parameters, such as charset.
+// It returns the type, the reader for the whole content and error if any.
+func detectContentType(r io.Reader) (string, io.Reader, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", nil, err
+	}
+	return mediaType, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+func (h *AttachmentHandler) Upload(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	req := e.Request()
+	maxBodySize := h.conf.AttachmentMaxSize + multipartOverhead
+	if req.ContentLength > maxBodySize {
+		return NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Errorf("the attachment file must be at most %d bytes", h.conf.AttachmentMaxSize))
+	}
+	req.Body = http.MaxBytesReader(e.Response(), req.Body, maxBodySize)
+
+	fh, err := e.FormFile(FormKeyAttachmentFile)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("the attachment file is required as %q form field: %v", FormKeyAttachmentFile, err))
+	}
+	if fh.Size > h.conf.AttachmentMaxSize {
+		return NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Errorf("the attachment file must be at most %d bytes", h.conf.AttachmentMaxSize))
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer file.Close()
+
+	// the type declared by the client is not trusted.
+	contentType, content, err := detectContentType(file)
+	if err != nil {
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if !h.conf.AllowsAttachmentType(contentType) {
+		return NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Errorf("the attachment file of type %v is not allowed", contentType))
+	}
+
+	attachment, err := h.attachments.Upload(req.Context(), userID, roomID, fh.Filename, contentType, content)
+	if err != nil {
+		return attachmentHTTPError(err)
+	}
+	return e.JSON(http.StatusCreated, attachment)
+}
+
+func (h *AttachmentHandler) GetAttachment(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	attachmentID, err := validateParamAttachmentID(e)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := h.attachments.Find(e.Request().Context(), userID, attachmentID)
+	if err != nil {
+		return attachmentHTTPError(err)
+	}
+	return e.JSON(http.StatusOK, attachment)
+}
+
+func (h *AttachmentHandler) DownloadContent(e echo.Context) error {
+	return h.download(e, false)
+}
+
+func (h *AttachmentHandler) DownloadThumbnail(e echo.Context) error {
+	return h.download(e, true)
+}
+
+func (h *AttachmentHandler) download(e echo.Context, thumbnail bool) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	attachmentID, err := validateParamAttachmentID(e)
+	if err != nil {
+		return err
+	}
+
+	attachment, content, err := h.attachments.Open(e.Request().Context(), userID, attachmentID, thumbnail)
+	if err != nil {
+		return attachmentHTTPError(err)
+	}
+	defer content.Close()
+
+	contentType := attachment.ContentType
+	if thumbnail {
+		contentType = chat.ThumbnailContentType
+	}
+	// the images are shown in the browser, and the others are downloaded.
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	// the file name which can not be formatted is omitted.
+	if d := mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}); d != "" {
+		disposition = d
+	}
+
+	header := e.Response().Header()
+	header.Set(echo.HeaderContentDisposition, disposition)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if !thumbnail {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	}
+	return e.Stream(http.StatusOK, contentType, content)
+}
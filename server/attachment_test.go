@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/chat/queried"
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/internal/mocks"
+)
+
+// pngHeader is the signature of the PNG file, which is
+// enough to be detected as image/png.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newUploadRequest(t *testing.T, fileName string, content []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile(FormKeyAttachmentFile, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(echo.POST, "/", &body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func TestAttachmentRequireLoggedInUserID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := NewAttachmentHandler(mocks.NewMockAttachmentService(ctrl), nil)
+	for _, handler := range []echo.HandlerFunc{
+		h.Upload,
+		h.GetAttachment,
+		h.DownloadContent,
+		h.DownloadThumbnail,
+	} {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := theEcho.NewContext(req, rec)
+
+		if err := handler(c); err != ErrAPIRequireLoginFirst {
+			t.Errorf("requesting with not loggedin state but login error is not returned: %v", err)
+		}
+	}
+}
+
+func TestAttachmentUpload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const (
+		UserID   = uint64(1)
+		RoomID   = uint64(2)
+		FileName = "photo.png"
+	)
+	content := append(pngHeader, "image data"...)
+	uploaded := &queried.Attachment{AttachmentID: 1, UserID: UserID, RoomID: RoomID, FileName: FileName, ContentType: "image/png"}
+
+	service := mocks.NewMockAttachmentService(ctrl)
+	service.EXPECT().
+		Upload(gomock.Any(), UserID, RoomID, FileName, "image/png", gomock.Any()).
+		DoAndReturn(func(_, _, _, _, _ interface{}, data io.Reader) (*queried.Attachment, error) {
+			if got, _ := ioutil.ReadAll(data); !bytes.Equal(got, content) {
+				t.Errorf("different uploaded content, expect %q, got %q", content, got)
+			}
+			return uploaded, nil
+		}).
+		Times(1)
+
+	h := NewAttachmentHandler(service, nil)
+
+	rec := httptest.NewRecorder()
+	c := theEcho.NewContext(newUploadRequest(t, FileName, content), rec)
+	c.Set(KeyLoggedInUserID, UserID)
+	c.SetParamNames(ParamKeyRoomID)
+	c.SetParamValues("2")
+
+	if err := h.Upload(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("different status code, expect %v, got %v", http.StatusCreated, rec.Code)
+	}
+	var got queried.Attachment
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != *uploaded {
+		t.Errorf("different uploaded attachment, expect %#v, got %#v", *uploaded, got)
+	}
+}
+
+func TestAttachmentUploadFail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockAttachmentService(ctrl)
+	service.EXPECT().
+		Upload(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, domain.NewPermissionError("read-only member")).
+		Times(1)
+
+	conf := DefaultConfig
+	conf.AttachmentMaxSize = 100
+	conf.AttachmentAllowedTypes = "image/*"
+	h := NewAttachmentHandler(service, &conf)
+
+	for _, testcase := range []struct {
+		Request    *http.Request
+		StatusCode int
+	}{
+		// no file
+		{httptest.NewRequest(echo.POST, "/", nil), http.StatusBadRequest},
+		// too large
+		{newUploadRequest(t, "large.png", append(pngHeader, strings.Repeat("a", 100)...)), http.StatusRequestEntityTooLarge},
+		// not allowed type
+		{newUploadRequest(t, "note.txt", []byte("hello")), http.StatusUnsupportedMediaType},
+		// not permitted by the service
+		{newUploadRequest(t, "photo.png", pngHeader), http.StatusForbidden},
+	} {
+		rec := httptest.NewRecorder()
+		c := theEcho.NewContext(testcase.Request, rec)
+		c.Set(KeyLoggedInUserID, uint64(1))
+		c.SetParamNames(ParamKeyRoomID)
+		c.SetParamValues("2")
+
+		err := h.Upload(c)
+		testAssertHTTPError(t, err, testcase.StatusCode, true)
+	}
+}
+
+func TestAttachmentDownload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const (
+		UserID       = uint64(1)
+		AttachmentID = uint64(3)
+	)
+	attachment := &queried.Attachment{AttachmentID: AttachmentID, FileName: "note.txt", ContentType: "text/plain", Size: 5}
+
+	service := mocks.NewMockAttachmentService(ctrl)
+	service.EXPECT().
+		Open(gomock.Any(), UserID, AttachmentID, gomock.Any()).
+		DoAndReturn(func(_, _, _ interface{}, thumbnail bool) (*queried.Attachment, io.ReadCloser, error) {
+			if thumbnail {
+				return attachment, ioutil.NopCloser(bytes.NewReader(pngHeader)), nil
+			}
+			return attachment, ioutil.NopCloser(strings.NewReader("hello")), nil
+		}).
+		Times(2)
+	service.EXPECT().
+		Open(gomock.Any(), UserID, AttachmentID+1, gomock.Any()).
+		Return(nil, nil, chat.NewNotFoundError("not found")).
+		Times(1)
+
+	h := NewAttachmentHandler(service, nil)
+	newContext := func(attachmentID string) (echo.Context, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		c := theEcho.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames(ParamKeyAttachmentID)
+		c.SetParamValues(attachmentID)
+		return c, rec
+	}
+
+	// case1: content
+	{
+		c, rec := newContext("3")
+		if err := h.DownloadContent(c); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Body.String(); got != "hello" {
+			t.Errorf("different content, got %q", got)
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); got != "text/plain" {
+			t.Errorf("different content type, got %v", got)
+		}
+		if got := rec.Header().Get(echo.HeaderContentDisposition); got != `attachment; filename=note.txt` {
+			t.Errorf("different content disposition, got %v", got)
+		}
+	}
+
+	// case2: thumbnail
+	{
+		c, rec := newContext("3")
+		if err := h.DownloadThumbnail(c); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); got != chat.ThumbnailContentType {
+			t.Errorf("different content type of the thumbnail, got %v", got)
+		}
+		if got := rec.Header().Get(echo.HeaderContentDisposition); !strings.HasPrefix(got, "inline") {
+			t.Errorf("the thumbnail is not shown inline, got %v", got)
+		}
+	}
+
+	// case3: not found
+	{
+		c, _ := newContext("4")
+		testAssertHTTPError(t, h.DownloadContent(c), http.StatusNotFound, true)
+	}
+
+	// case4: invalid attachment id
+	{
+		c, _ := newContext("invalid")
+		testAssertHTTPError(t, h.DownloadContent(c), http.StatusBadRequest, true)
+	}
+}
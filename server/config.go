@@ -3,6 +3,10 @@ package server
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/ws"
 )
 
 // Configuration for server behavior.
@@ -34,6 +38,49 @@ type Config struct {
 
 	// show all of URI routes at server starts.
 	ShowRoutes bool
+
+	// cost of the bcrypt hashing for the user password.
+	// The password hashed with other cost is rehashed at the next login.
+	PasswordHashCost int
+
+	// minimum length of the user password.
+	PasswordMinLength int
+
+	// indicates whether the user password must contain at least one letter.
+	PasswordRequireLetter bool
+
+	// indicates whether the user password must contain at least one digit.
+	PasswordRequireDigit bool
+
+	// maximum size of the attachment file in bytes.
+	AttachmentMaxSize int64
+
+	// comma separated MIME types of the attachment files which can be uploaded,
+	// e.g. "image/png,application/pdf". The type with wildcard subtype,
+	// such as "image/*", matches any of its subtypes.
+	// The type of the file is detected from its content.
+	AttachmentAllowedTypes string
+
+	// maximum width and height of the thumbnail for the image attachment in pixels.
+	AttachmentThumbnailSize int
+
+	// seconds to hold the long-polling request until any event arrives.
+	LongPollTimeout int
+
+	// seconds to expire the long-polling session which is not polled.
+	// The expired session is disconnected from the chat.
+	LongPollIdleTimeout int
+
+	// seconds between the ping frames sent to the websocket client.
+	WebsocketPingInterval int
+
+	// seconds to wait for any data, including the pong frame, from the websocket client.
+	// The connection is closed if nothing is received during it, so that
+	// the silent client is disconnected from the chat.
+	WebsocketReadTimeout int
+
+	// seconds to wait for writing a message to the websocket client.
+	WebsocketWriteTimeout int
 }
 
 // DefaultConfig is default configuration for the server.
@@ -44,6 +91,21 @@ var DefaultConfig = Config{
 	StaticFileDir:         "", // current directory
 	EnableServeStaticFile: true,
 	ShowRoutes:            true,
+	PasswordHashCost:      domain.DefaultPasswordHashCost,
+	PasswordMinLength:     domain.DefaultPasswordPolicy.MinLength,
+	PasswordRequireLetter: domain.DefaultPasswordPolicy.RequireLetter,
+	PasswordRequireDigit:  domain.DefaultPasswordPolicy.RequireDigit,
+
+	AttachmentMaxSize:       10 * 1024 * 1024, // 10MB
+	AttachmentAllowedTypes:  "image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain",
+	AttachmentThumbnailSize: 256,
+
+	LongPollTimeout:     30,
+	LongPollIdleTimeout: 60,
+
+	WebsocketPingInterval: 30,
+	WebsocketReadTimeout:  60,
+	WebsocketWriteTimeout: 10,
 }
 
 // Validate checks whether the all of field values are correct format.
@@ -57,5 +119,85 @@ func (c *Config) Validate() error {
 	if len(c.StaticHandlerPrefix) > 0 && !strings.HasPrefix(c.StaticHandlerPrefix, "/") {
 		return fmt.Errorf("config: StaticHandlerPrefix should start with \"/\" but %v", c.StaticHandlerPrefix)
 	}
+	if c.PasswordMinLength < 0 {
+		return fmt.Errorf("config: PasswordMinLength should not be negative but %v", c.PasswordMinLength)
+	}
+	if _, err := c.PasswordHasher(); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	if c.AttachmentMaxSize <= 0 {
+		return fmt.Errorf("config: AttachmentMaxSize should be positive but %v", c.AttachmentMaxSize)
+	}
+	for _, t := range c.attachmentAllowedTypes() {
+		if ss := strings.Split(t, "/"); len(ss) != 2 || ss[0] == "" || ss[0] == "*" || ss[1] == "" {
+			return fmt.Errorf("config: AttachmentAllowedTypes should be comma separated type/subtype but %v", c.AttachmentAllowedTypes)
+		}
+	}
+	if c.AttachmentThumbnailSize <= 0 {
+		return fmt.Errorf("config: AttachmentThumbnailSize should be positive but %v", c.AttachmentThumbnailSize)
+	}
+	if c.LongPollTimeout <= 0 {
+		return fmt.Errorf("config: LongPollTimeout should be positive but %v", c.LongPollTimeout)
+	}
+	if c.LongPollIdleTimeout <= c.LongPollTimeout {
+		return fmt.Errorf("config: LongPollIdleTimeout should be greater than LongPollTimeout but %v", c.LongPollIdleTimeout)
+	}
+	if c.WebsocketPingInterval <= 0 {
+		return fmt.Errorf("config: WebsocketPingInterval should be positive but %v", c.WebsocketPingInterval)
+	}
+	if c.WebsocketReadTimeout <= c.WebsocketPingInterval {
+		return fmt.Errorf("config: WebsocketReadTimeout should be greater than WebsocketPingInterval but %v", c.WebsocketReadTimeout)
+	}
+	if c.WebsocketWriteTimeout <= 0 {
+		return fmt.Errorf("config: WebsocketWriteTimeout should be positive but %v", c.WebsocketWriteTimeout)
+	}
 	return nil
 }
+
+// WebsocketConfig returns the configuration for the heartbeat
+// of the websocket connection.
+func (c *Config) WebsocketConfig() ws.Config {
+	return ws.Config{
+		PingInterval: time.Duration(c.WebsocketPingInterval) * time.Second,
+		ReadTimeout:  time.Duration(c.WebsocketReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(c.WebsocketWriteTimeout) * time.Second,
+	}
+}
+
+// attachmentAllowedTypes returns the list of AttachmentAllowedTypes
+// in lower case.
+func (c *Config) attachmentAllowedTypes() []string {
+	types := make([]string, 0, 8)
+	for _, t := range strings.Split(c.AttachmentAllowedTypes, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// AllowsAttachmentType returns whether the attachment file of
+// the MIME type, without any parameters, can be uploaded.
+func (c *Config) AllowsAttachmentType(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, t := range c.attachmentAllowedTypes() {
+		if t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PasswordHasher returns domain.PasswordHasher configured by
+// the Password* fields.
+func (c *Config) PasswordHasher() (*domain.PasswordHasher, error) {
+	policy := domain.PasswordPolicy{
+		MinLength:     c.PasswordMinLength,
+		RequireLetter: c.PasswordRequireLetter,
+		RequireDigit:  c.PasswordRequireDigit,
+	}
+	return domain.NewPasswordHasher(policy, c.PasswordHashCost)
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/labstack/echo"
+
+	"github.com/shirasudon/go-chat/domain"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -20,13 +22,52 @@ func TestConfigValidate(t *testing.T) {
 		{HTTP: "a::"},
 		{ChatAPIPrefix: "api/chat"},
 		{StaticHandlerPrefix: "sta/tic"},
+		{HTTP: "localhost:8080", PasswordHashCost: domain.MaxPasswordHashCost + 1},
+		{HTTP: "localhost:8080", PasswordHashCost: domain.DefaultPasswordHashCost, PasswordMinLength: -1},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("It should be error but not, %#v", c)
+		}
+	}
+
+	for _, modify := range []func(c *Config){
+		func(c *Config) { c.AttachmentMaxSize = 0 },
+		func(c *Config) { c.AttachmentAllowedTypes = "image" },
+		func(c *Config) { c.AttachmentAllowedTypes = "image/png,*/*" },
+		func(c *Config) { c.AttachmentThumbnailSize = 0 },
+		func(c *Config) { c.LongPollTimeout = 0 },
+		func(c *Config) { c.LongPollIdleTimeout = c.LongPollTimeout },
+		func(c *Config) { c.WebsocketPingInterval = 0 },
+		func(c *Config) { c.WebsocketReadTimeout = c.WebsocketPingInterval },
+		func(c *Config) { c.WebsocketWriteTimeout = 0 },
 	} {
+		c := DefaultConfig
+		modify(&c)
 		if err := c.Validate(); err == nil {
 			t.Errorf("It should be error but not, %#v", c)
 		}
 	}
 }
 
+func TestConfigAllowsAttachmentType(t *testing.T) {
+	c := DefaultConfig
+	c.AttachmentAllowedTypes = " image/*, Application/PDF "
+	for _, testcase := range []struct {
+		Type    string
+		Allowed bool
+	}{
+		{"image/png", true},
+		{"image/jpeg", true},
+		{"application/pdf", true},
+		{"text/plain", false},
+		{"imagex/png", false},
+	} {
+		if got := c.AllowsAttachmentType(testcase.Type); got != testcase.Allowed {
+			t.Errorf("AllowsAttachmentType(%q): expect %v, got %v", testcase.Type, testcase.Allowed, got)
+		}
+	}
+}
+
 func findRoute(routes []*echo.Route, query echo.Route) bool {
 	for _, r := range routes {
 		if *r == query {
@@ -46,7 +87,7 @@ func TestServerConfEnableServeStaticFile(t *testing.T) {
 	{ // enable serve static file
 		conf := DefaultConfig
 		conf.EnableServeStaticFile = true
-		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server1.Shutdown(context.Background())
 		if !findRoute(server1.echo.Routes(), staticFileRoute) {
 			t.Errorf("staticContents route (%#v) is not found", staticFileRoute)
@@ -56,7 +97,7 @@ func TestServerConfEnableServeStaticFile(t *testing.T) {
 	{ // disable serve static file
 		conf := DefaultConfig
 		conf.EnableServeStaticFile = false
-		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server2.Shutdown(context.Background())
 		if findRoute(server2.echo.Routes(), staticFileRoute) {
 			t.Errorf("staticContents route (%#v) should be not found", staticFileRoute)
@@ -74,7 +115,7 @@ func TestServerConfStaticHandlerPrefix(t *testing.T) {
 	{ // set static prefix
 		conf := DefaultConfig
 		conf.StaticHandlerPrefix = "/static"
-		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server1.Shutdown(context.Background())
 		if !findRoute(server1.echo.Routes(), Query) {
 			t.Errorf("staticContents route (%#v) is not found", Query)
@@ -83,7 +124,7 @@ func TestServerConfStaticHandlerPrefix(t *testing.T) {
 
 	{ // no static prefix
 		conf := DefaultConfig
-		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server2.Shutdown(context.Background())
 		if findRoute(server2.echo.Routes(), Query) {
 			t.Errorf("staticContents route (%#v) should be not found", Query)
@@ -101,7 +142,7 @@ func TestServerConfChatAPIPrefix(t *testing.T) {
 	{ // set chat prefix
 		conf := DefaultConfig
 		conf.ChatAPIPrefix = "/api"
-		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server1 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server1.Shutdown(context.Background())
 		if !findRoute(server1.echo.Routes(), Query) {
 			t.Errorf("chat API route (%#v) is not found", Query)
@@ -110,7 +151,7 @@ func TestServerConfChatAPIPrefix(t *testing.T) {
 
 	{ // no chat prefix
 		conf := DefaultConfig
-		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, &conf)
+		server2 := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
 		defer server2.Shutdown(context.Background())
 		if findRoute(server2.echo.Routes(), Query) {
 			t.Errorf("chat API route (%#v) should be not found", Query)
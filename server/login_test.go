@@ -29,9 +29,8 @@ const (
 )
 
 var AuthUser = queried.AuthUser{
-	ID:       2,
-	Name:     CorrectName,
-	Password: CorrectPassword,
+	ID:   2,
+	Name: CorrectName,
 }
 
 func TestLogin(t *testing.T) {
@@ -41,9 +40,8 @@ func TestLogin(t *testing.T) {
 	defer ctrl.Finish()
 
 	var AuthUser = queried.AuthUser{
-		ID:       2,
-		Name:     "user",
-		Password: "password",
+		ID:   2,
+		Name: "user",
 	}
 
 	// correct user login
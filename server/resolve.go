@@ -15,18 +15,42 @@ type DoneFunc func()
 
 // CreateServerFromInfra creates server with infrastructure dependencies.
 // It returns created server and finalize function.
+// a nil blobs is OK and the attachments are not supported.
 // a nil config is OK and use DefaultConfig insteadly.
-func CreateServerFromInfra(repos domain.Repositories, qs *chat.Queryers, ps chat.Pubsub, conf *Config) (*Server, DoneFunc) {
-	chatCmd := chat.NewCommandServiceImpl(repos, ps)
+func CreateServerFromInfra(repos domain.Repositories, qs *chat.Queryers, ps chat.Pubsub, blobs chat.BlobStore, conf *Config) (*Server, DoneFunc) {
+	hasher := domain.DefaultPasswordHasher
+	thumbnailSize := DefaultConfig.AttachmentThumbnailSize
+	if conf != nil {
+		// invalid config is reported at server starts,
+		// so the default is used insteadly here.
+		if h, err := conf.PasswordHasher(); err == nil {
+			hasher = h
+		}
+		if conf.AttachmentThumbnailSize > 0 {
+			thumbnailSize = conf.AttachmentThumbnailSize
+		}
+	}
+
+	chatCmd := chat.NewCommandServiceImplWithHasher(repos, ps, hasher)
+	go chatCmd.RunUpdateService(context.Background())
 	chatQuery := chat.NewQueryServiceImpl(qs)
 	chatHub := chat.NewHubImpl(chatCmd)
+	if qs != nil && qs.EventQueryer != nil {
+		chatHub = chat.NewHubImplWithEvents(chatCmd, qs.EventQueryer)
+	}
 	go chatHub.Listen(context.Background())
 
-	login := chat.NewLoginServiceImpl(qs.UserQueryer, ps)
+	login := chat.NewLoginServiceImpl(repos.Users(), hasher, ps)
+
+	var attachments chat.AttachmentService
+	if blobs != nil {
+		attachments = chat.NewAttachmentServiceImpl(repos, blobs, thumbnailSize)
+	}
 
-	server := NewServer(chatCmd, chatQuery, chatHub, login, conf)
+	server := NewServer(chatCmd, chatQuery, chatHub, login, attachments, conf)
 	doneFunc := func() {
 		chatHub.Shutdown()
+		chatCmd.CancelUpdateService()
 	}
 	return server, doneFunc
 }
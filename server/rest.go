@@ -3,12 +3,14 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/labstack/echo"
 
 	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/chat/action"
+	"github.com/shirasudon/go-chat/domain"
 )
 
 var (
@@ -30,8 +32,9 @@ func NewRESTHandler(chatCmd chat.CommandService, chatQuery chat.QueryService) *R
 
 const (
 	// keys for the URL parameters. e.g. /root/:param_name
-	ParamKeyUserID = "user_id"
-	ParamKeyRoomID = "room_id"
+	ParamKeyUserID    = "user_id"
+	ParamKeyRoomID    = "room_id"
+	ParamKeyMessageID = "message_id"
 )
 
 func validateParamUserID(e echo.Context) (uint64, error) {
@@ -54,6 +57,16 @@ func validateParamRoomID(e echo.Context) (uint64, error) {
 	return roomID, nil
 }
 
+func validateParamMessageID(e echo.Context) (uint64, error) {
+	param := e.Param(ParamKeyMessageID)
+	msgID, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested message id(%v) is not allowed", param))
+	}
+
+	return msgID, nil
+}
+
 func (rest *RESTHandler) CreateRoom(e echo.Context) error {
 	userID, ok := LoggedInUserID(e)
 	if !ok {
@@ -97,6 +110,9 @@ func (rest *RESTHandler) DeleteRoom(e echo.Context) error {
 
 	deletedID, err := rest.chatCmd.DeleteRoom(e.Request().Context(), deleteRoom)
 	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
 		return NewHTTPError(http.StatusInternalServerError, err)
 	}
 
@@ -110,6 +126,47 @@ func (rest *RESTHandler) DeleteRoom(e echo.Context) error {
 	return e.JSON(http.StatusOK, response)
 }
 
+func (rest *RESTHandler) FindOrCreateTalkRoom(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	talkRoom := action.FindOrCreateTalkRoom{}
+	if err := e.Bind(&talkRoom); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	talkRoom.SenderID = userID
+
+	res, err := rest.chatCmd.FindOrCreateTalkRoom(e.Request().Context(), talkRoom)
+	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		RoomID   uint64 `json:"room_id"`
+		FriendID uint64 `json:"friend_id"`
+		Created  bool   `json:"created"`
+		OK       bool   `json:"ok"`
+	}{
+		RoomID:   res.RoomID,
+		FriendID: talkRoom.FriendID,
+		Created:  res.Created,
+		OK:       true,
+	}
+	status := http.StatusOK
+	if res.Created {
+		status = http.StatusCreated
+	}
+	return e.JSON(status, response)
+}
+
 func (rest *RESTHandler) AddRoomMember(e echo.Context) error {
 	userID, ok := LoggedInUserID(e)
 	if !ok {
@@ -129,6 +186,9 @@ func (rest *RESTHandler) AddRoomMember(e echo.Context) error {
 
 	res, err := rest.chatCmd.AddRoomMember(e.Request().Context(), addRoomMember)
 	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
 		if chat.IsNotFoundError(err) {
 			return NewHTTPError(http.StatusNotFound, err)
 		}
@@ -166,6 +226,9 @@ func (rest *RESTHandler) RemoveRoomMember(e echo.Context) error {
 
 	res, err := rest.chatCmd.RemoveRoomMember(e.Request().Context(), removeRoomMember)
 	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
 		if chat.IsNotFoundError(err) {
 			return NewHTTPError(http.StatusNotFound, err)
 		}
@@ -184,6 +247,169 @@ func (rest *RESTHandler) RemoveRoomMember(e echo.Context) error {
 	return e.JSON(http.StatusOK, response)
 }
 
+func (rest *RESTHandler) RenameRoom(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	renameRoom := action.RenameRoom{}
+	if err := e.Bind(&renameRoom); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	renameRoom.SenderID = userID
+	renameRoom.RoomID = roomID
+
+	renamedID, err := rest.chatCmd.RenameRoom(e.Request().Context(), renameRoom)
+	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		RoomID   uint64 `json:"room_id"`
+		RoomName string `json:"room_name"`
+		OK       bool   `json:"ok"`
+	}{
+		RoomID:   renamedID,
+		RoomName: renameRoom.RoomName,
+		OK:       true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) ChangeRoomMemberRole(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	memberID, err := validateParamUserID(e)
+	if err != nil {
+		return err
+	}
+
+	changeRole := action.ChangeRoomMemberRole{}
+	if err := e.Bind(&changeRole); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	changeRole.SenderID = userID
+	changeRole.RoomID = roomID
+	changeRole.UserID = memberID
+
+	if _, err := domain.ParseRoomRole(changeRole.Role); err != nil {
+		return NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	changedID, err := rest.chatCmd.ChangeRoomMemberRole(e.Request().Context(), changeRole)
+	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		RoomID uint64 `json:"room_id"`
+		UserID uint64 `json:"user_id"`
+		Role   string `json:"role"`
+		OK     bool   `json:"ok"`
+	}{
+		RoomID: changedID,
+		UserID: changeRole.UserID,
+		Role:   changeRole.Role,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) LeaveRoom(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	leaveRoom := action.LeaveRoom{}
+	leaveRoom.SenderID = userID
+	leaveRoom.RoomID = roomID
+
+	leftID, err := rest.chatCmd.LeaveRoom(e.Request().Context(), leaveRoom)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		RoomID uint64 `json:"left_room_id"`
+		OK     bool   `json:"ok"`
+	}{
+		RoomID: leftID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) TransferRoomOwnership(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	transfer := action.TransferRoomOwnership{}
+	if err := e.Bind(&transfer); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	transfer.SenderID = userID
+	transfer.RoomID = roomID
+
+	transferredID, err := rest.chatCmd.TransferRoomOwnership(e.Request().Context(), transfer)
+	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		RoomID     uint64 `json:"room_id"`
+		NewOwnerID uint64 `json:"new_owner_id"`
+		OK         bool   `json:"ok"`
+	}{
+		RoomID:     transferredID,
+		NewOwnerID: transfer.NewOwnerID,
+		OK:         true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
 func (rest *RESTHandler) GetRoomInfo(e echo.Context) error {
 	userID, ok := LoggedInUserID(e)
 	if !ok {
@@ -225,61 +451,677 @@ func (rest *RESTHandler) GetUserInfo(e echo.Context) error {
 	return e.JSON(http.StatusOK, relation)
 }
 
-func (rest *RESTHandler) PostRoomMessage(e echo.Context) error {
-	userID, ok := LoggedInUserID(e)
+// CreateUser signs up the new user.
+// It does not require the login, and the created user is not logged in.
+func (rest *RESTHandler) CreateUser(e echo.Context) error {
+	createUser := action.CreateUser{}
+	if err := e.Bind(&createUser); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+
+	createdID, err := rest.chatCmd.CreateUser(e.Request().Context(), createUser)
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		UserID uint64 `json:"user_id"`
+		OK     bool   `json:"ok"`
+	}{
+		UserID: createdID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusCreated, response)
+}
+
+// validateParamLoggedInUserID returns the user ID in the URL parameter,
+// which must be same as the logged in user.
+func validateParamLoggedInUserID(e echo.Context, loggedInUserID uint64) (uint64, error) {
+	userID, err := validateParamUserID(e)
+	if err != nil {
+		return 0, err
+	}
+	if userID != loggedInUserID {
+		return 0, NewHTTPError(http.StatusForbidden, fmt.Errorf("can not change other user(id=%d)", userID))
+	}
+	return userID, nil
+}
+
+func (rest *RESTHandler) UpdateUserProfile(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
 	if !ok {
 		return ErrAPIRequireLoginFirst
 	}
-	roomID, err := validateParamRoomID(e)
+
+	userID, err := validateParamLoggedInUserID(e, loggedInUserID)
 	if err != nil {
 		return err
 	}
 
-	postMsg := action.ChatMessage{}
-	if err := e.Bind(&postMsg); err != nil {
+	updateProfile := action.UpdateUserProfile{}
+	if err := e.Bind(&updateProfile); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	updateProfile.SenderID = userID
+
+	updatedID, err := rest.chatCmd.UpdateUserProfile(e.Request().Context(), updateProfile)
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		UserID uint64 `json:"user_id"`
+		OK     bool   `json:"ok"`
+	}{
+		UserID: updatedID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) DeleteUser(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamLoggedInUserID(e, loggedInUserID)
+	if err != nil {
 		return err
 	}
-	postMsg.SenderID = userID
-	postMsg.RoomID = roomID
-	msgID, err := rest.chatCmd.PostRoomMessage(e.Request().Context(), postMsg)
+
+	deleteUser := action.DeleteUser{SenderID: userID}
+	deletedID, err := rest.chatCmd.DeleteUser(e.Request().Context(), deleteUser)
 	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
 		return NewHTTPError(http.StatusInternalServerError, err)
 	}
 
 	response := struct {
-		MsgID  uint64 `json:"message_id"`
-		RoomID uint64 `json:"room_id"`
+		UserID uint64 `json:"deleted_user_id"`
 		OK     bool   `json:"ok"`
 	}{
-		MsgID:  msgID,
-		RoomID: postMsg.RoomID,
+		UserID: deletedID,
 		OK:     true,
 	}
-	return e.JSON(http.StatusCreated, response)
+	return e.JSON(http.StatusOK, response)
 }
 
-func (rest *RESTHandler) GetRoomMessages(e echo.Context) error {
-	userID, ok := LoggedInUserID(e)
+// respondUserRelation responds the result of the actions which
+// relate the logged in user with other user, such as SendFriendRequest.
+func respondUserRelation(e echo.Context, userID uint64, err error) error {
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		UserID uint64 `json:"user_id"`
+		OK     bool   `json:"ok"`
+	}{
+		UserID: userID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) SendFriendRequest(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
 	if !ok {
 		return ErrAPIRequireLoginFirst
 	}
-	roomID, err := validateParamRoomID(e)
+
+	sendRequest := action.SendFriendRequest{}
+	if err := e.Bind(&sendRequest); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	sendRequest.SenderID = loggedInUserID
+
+	requestedID, err := rest.chatCmd.SendFriendRequest(e.Request().Context(), sendRequest)
+	return respondUserRelation(e, requestedID, err)
+}
+
+func (rest *RESTHandler) AcceptFriendRequest(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamUserID(e)
 	if err != nil {
 		return err
 	}
 
-	qRoomMsg := action.QueryRoomMessages{}
-	if err := e.Bind(&qRoomMsg); err != nil {
+	accept := action.AcceptFriendRequest{SenderID: loggedInUserID, UserID: userID}
+	requestingID, err := rest.chatCmd.AcceptFriendRequest(e.Request().Context(), accept)
+	return respondUserRelation(e, requestingID, err)
+}
+
+func (rest *RESTHandler) DeclineFriendRequest(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamUserID(e)
+	if err != nil {
 		return err
 	}
-	qRoomMsg.RoomID = roomID
 
-	roomMsg, err := rest.chatQuery.FindRoomMessages(e.Request().Context(), userID, qRoomMsg)
+	decline := action.DeclineFriendRequest{SenderID: loggedInUserID, UserID: userID}
+	requestingID, err := rest.chatCmd.DeclineFriendRequest(e.Request().Context(), decline)
+	return respondUserRelation(e, requestingID, err)
+}
+
+func (rest *RESTHandler) CancelFriendRequest(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamUserID(e)
 	if err != nil {
-		return NewHTTPError(http.StatusInternalServerError, err)
+		return err
 	}
 
-	return e.JSON(http.StatusOK, roomMsg)
+	cancel := action.CancelFriendRequest{SenderID: loggedInUserID, UserID: userID}
+	requestedID, err := rest.chatCmd.CancelFriendRequest(e.Request().Context(), cancel)
+	return respondUserRelation(e, requestedID, err)
+}
+
+func (rest *RESTHandler) RemoveFriend(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamUserID(e)
+	if err != nil {
+		return err
+	}
+
+	removeFriend := action.RemoveFriend{SenderID: loggedInUserID, UserID: userID}
+	removedID, err := rest.chatCmd.RemoveFriend(e.Request().Context(), removeFriend)
+	return respondUserRelation(e, removedID, err)
+}
+
+func (rest *RESTHandler) BlockUser(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	blockUser := action.BlockUser{}
+	if err := e.Bind(&blockUser); err != nil {
+		return err // default Bind returns *echo.NewHTTPError
+	}
+	blockUser.SenderID = loggedInUserID
+
+	blockedID, err := rest.chatCmd.BlockUser(e.Request().Context(), blockUser)
+	return respondUserRelation(e, blockedID, err)
+}
+
+func (rest *RESTHandler) UnblockUser(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	userID, err := validateParamUserID(e)
+	if err != nil {
+		return err
+	}
+
+	unblockUser := action.UnblockUser{SenderID: loggedInUserID, UserID: userID}
+	unblockedID, err := rest.chatCmd.UnblockUser(e.Request().Context(), unblockUser)
+	return respondUserRelation(e, unblockedID, err)
+}
+
+func (rest *RESTHandler) GetBlockedUsers(e echo.Context) error {
+	loggedInUserID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	blocked, err := rest.chatQuery.FindBlockedUsers(e.Request().Context(), loggedInUserID)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return e.JSON(http.StatusOK, blocked)
+}
+
+func (rest *RESTHandler) PostRoomMessage(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	postMsg := action.ChatMessage{}
+	if err := e.Bind(&postMsg); err != nil {
+		return err
+	}
+	postMsg.SenderID = userID
+	postMsg.RoomID = roomID
+	msgID, err := rest.chatCmd.PostRoomMessage(e.Request().Context(), postMsg)
+	if err != nil {
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if chat.IsNotFoundError(err) {
+			// the parent message to reply is not found.
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		MsgID  uint64 `json:"message_id"`
+		RoomID uint64 `json:"room_id"`
+		OK     bool   `json:"ok"`
+	}{
+		MsgID:  msgID,
+		RoomID: postMsg.RoomID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusCreated, response)
+}
+
+func (rest *RESTHandler) EditRoomMessage(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+
+	editMsg := action.EditChatMessage{}
+	if err := e.Bind(&editMsg); err != nil {
+		return err
+	}
+	editMsg.SenderID = userID
+	editMsg.RoomID = roomID
+	editMsg.MessageID = msgID
+
+	editedID, err := rest.chatCmd.EditRoomMessage(e.Request().Context(), editMsg)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		MsgID  uint64 `json:"message_id"`
+		RoomID uint64 `json:"room_id"`
+		OK     bool   `json:"ok"`
+	}{
+		MsgID:  editedID,
+		RoomID: editMsg.RoomID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) DeleteRoomMessage(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+
+	deleteMsg := action.DeleteChatMessage{}
+	deleteMsg.SenderID = userID
+	deleteMsg.RoomID = roomID
+	deleteMsg.MessageID = msgID
+
+	deletedID, err := rest.chatCmd.DeleteRoomMessage(e.Request().Context(), deleteMsg)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		MsgID  uint64 `json:"message_id"`
+		RoomID uint64 `json:"room_id"`
+		OK     bool   `json:"ok"`
+	}{
+		MsgID:  deletedID,
+		RoomID: deleteMsg.RoomID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+// respondRoomMessageAction responds the result of the actions
+// to the message in the room, such as AddReaction and PinMessage.
+func respondRoomMessageAction(e echo.Context, msgID, roomID uint64, err error) error {
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if domain.IsPermissionError(err) {
+			return NewHTTPError(http.StatusForbidden, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	response := struct {
+		MsgID  uint64 `json:"message_id"`
+		RoomID uint64 `json:"room_id"`
+		OK     bool   `json:"ok"`
+	}{
+		MsgID:  msgID,
+		RoomID: roomID,
+		OK:     true,
+	}
+	return e.JSON(http.StatusOK, response)
+}
+
+func (rest *RESTHandler) AddReaction(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+
+	addReaction := action.AddReaction{}
+	if err := e.Bind(&addReaction); err != nil {
+		return err
+	}
+	addReaction.SenderID = userID
+	addReaction.RoomID = roomID
+	addReaction.MessageID = msgID
+
+	reactedID, err := rest.chatCmd.AddReaction(e.Request().Context(), addReaction)
+	return respondRoomMessageAction(e, reactedID, roomID, err)
+}
+
+func (rest *RESTHandler) RemoveReaction(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+	// the emoji may be escaped in the path.
+	emoji, err := url.PathUnescape(e.Param("emoji"))
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested emoji(%v) is not allowed", e.Param("emoji")))
+	}
+
+	removeReaction := action.RemoveReaction{}
+	removeReaction.SenderID = userID
+	removeReaction.RoomID = roomID
+	removeReaction.MessageID = msgID
+	removeReaction.Emoji = emoji
+
+	reactedID, err := rest.chatCmd.RemoveReaction(e.Request().Context(), removeReaction)
+	return respondRoomMessageAction(e, reactedID, roomID, err)
+}
+
+func (rest *RESTHandler) PinMessage(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	pinMessage := action.PinMessage{}
+	if err := e.Bind(&pinMessage); err != nil {
+		return err
+	}
+	pinMessage.SenderID = userID
+	pinMessage.RoomID = roomID
+
+	pinnedRoomID, err := rest.chatCmd.PinMessage(e.Request().Context(), pinMessage)
+	return respondRoomMessageAction(e, pinMessage.MessageID, pinnedRoomID, err)
+}
+
+func (rest *RESTHandler) UnpinMessage(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+
+	unpinMessage := action.UnpinMessage{}
+	unpinMessage.SenderID = userID
+	unpinMessage.RoomID = roomID
+	unpinMessage.MessageID = msgID
+
+	unpinnedRoomID, err := rest.chatCmd.UnpinMessage(e.Request().Context(), unpinMessage)
+	return respondRoomMessageAction(e, msgID, unpinnedRoomID, err)
+}
+
+func (rest *RESTHandler) GetRoomPins(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	pins, err := rest.chatQuery.FindRoomPins(e.Request().Context(), userID, roomID)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, pins)
+}
+
+func (rest *RESTHandler) GetRoomMessages(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+
+	qRoomMsg := action.QueryRoomMessages{}
+	if err := e.Bind(&qRoomMsg); err != nil {
+		return err
+	}
+	qRoomMsg.RoomID = roomID
+
+	roomMsg, err := rest.chatQuery.FindRoomMessages(e.Request().Context(), userID, qRoomMsg)
+	if err != nil {
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, roomMsg)
+}
+
+func (rest *RESTHandler) GetThreadMessages(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+	roomID, err := validateParamRoomID(e)
+	if err != nil {
+		return err
+	}
+	msgID, err := validateParamMessageID(e)
+	if err != nil {
+		return err
+	}
+
+	qThreadMsg := action.QueryThreadMessages{}
+	if err := e.Bind(&qThreadMsg); err != nil {
+		return err
+	}
+	qThreadMsg.RoomID = roomID
+	qThreadMsg.MessageID = msgID
+
+	threadMsg, err := rest.chatQuery.FindThreadMessages(e.Request().Context(), userID, qThreadMsg)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, threadMsg)
+}
+
+func (rest *RESTHandler) GetUserMentions(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	qMentions := action.QueryUserMentions{}
+	if err := e.Bind(&qMentions); err != nil {
+		return err
+	}
+
+	mentions, err := rest.chatQuery.FindUserMentions(e.Request().Context(), userID, qMentions)
+	if err != nil {
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, mentions)
+}
+
+func (rest *RESTHandler) GetUserEvents(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	qEvents := action.QueryUserEvents{}
+	if err := e.Bind(&qEvents); err != nil {
+		return err
+	}
+
+	events, err := rest.chatQuery.FindUserEvents(e.Request().Context(), userID, qEvents)
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, events)
+}
+
+func (rest *RESTHandler) SearchMessages(e echo.Context) error {
+	userID, ok := LoggedInUserID(e)
+	if !ok {
+		return ErrAPIRequireLoginFirst
+	}
+
+	qSearch := action.QuerySearchMessages{}
+	if err := e.Bind(&qSearch); err != nil {
+		return err
+	}
+
+	searched, err := rest.chatQuery.SearchMessages(e.Request().Context(), userID, qSearch)
+	if err != nil {
+		if domain.IsValidationError(err) {
+			return NewHTTPError(http.StatusBadRequest, err)
+		}
+		if chat.IsNotFoundError(err) {
+			return NewHTTPError(http.StatusNotFound, err)
+		}
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return e.JSON(http.StatusOK, searched)
 }
 
 func (rest *RESTHandler) GetUnreadRoomMessages(e echo.Context) error {
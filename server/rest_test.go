@@ -18,6 +18,8 @@ import (
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/chat/queried"
 	"github.com/shirasudon/go-chat/chat/result"
+	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
 	"github.com/shirasudon/go-chat/infra/pubsub"
 	"github.com/shirasudon/go-chat/internal/mocks"
 )
@@ -54,13 +56,39 @@ func TestRESTRequireLoggeinUserID(t *testing.T) {
 	}{
 		{"CreateRoom", RESTHandler.CreateRoom},
 		{"DeleteRoom", RESTHandler.DeleteRoom},
+		{"FindOrCreateTalkRoom", RESTHandler.FindOrCreateTalkRoom},
 		{"AddRoomMember", RESTHandler.AddRoomMember},
 		{"RemoveRoomMember", RESTHandler.RemoveRoomMember},
+		{"RenameRoom", RESTHandler.RenameRoom},
+		{"ChangeRoomMemberRole", RESTHandler.ChangeRoomMemberRole},
+		{"LeaveRoom", RESTHandler.LeaveRoom},
+		{"TransferRoomOwnership", RESTHandler.TransferRoomOwnership},
 		{"GetRoomInfo", RESTHandler.GetRoomInfo},
 		{"GetUserInfo", RESTHandler.GetUserInfo},
+		{"UpdateUserProfile", RESTHandler.UpdateUserProfile},
+		{"DeleteUser", RESTHandler.DeleteUser},
+		{"SendFriendRequest", RESTHandler.SendFriendRequest},
+		{"AcceptFriendRequest", RESTHandler.AcceptFriendRequest},
+		{"DeclineFriendRequest", RESTHandler.DeclineFriendRequest},
+		{"CancelFriendRequest", RESTHandler.CancelFriendRequest},
+		{"RemoveFriend", RESTHandler.RemoveFriend},
+		{"BlockUser", RESTHandler.BlockUser},
+		{"UnblockUser", RESTHandler.UnblockUser},
+		{"GetBlockedUsers", RESTHandler.GetBlockedUsers},
 		{"PostRoomMessage", RESTHandler.PostRoomMessage},
+		{"EditRoomMessage", RESTHandler.EditRoomMessage},
+		{"DeleteRoomMessage", RESTHandler.DeleteRoomMessage},
 		{"GetRoomMessages", RESTHandler.GetRoomMessages},
+		{"GetThreadMessages", RESTHandler.GetThreadMessages},
+		{"AddReaction", RESTHandler.AddReaction},
+		{"RemoveReaction", RESTHandler.RemoveReaction},
+		{"PinMessage", RESTHandler.PinMessage},
+		{"UnpinMessage", RESTHandler.UnpinMessage},
+		{"GetRoomPins", RESTHandler.GetRoomPins},
 		{"GetUnreadRoomMessages", RESTHandler.GetUnreadRoomMessages},
+		{"SearchMessages", RESTHandler.SearchMessages},
+		{"GetUserMentions", RESTHandler.GetUserMentions},
+		{"GetUserEvents", RESTHandler.GetUserEvents},
 		{"ReadRoomMessages", RESTHandler.ReadRoomMessages},
 	}
 
@@ -294,6 +322,88 @@ func TestRESTDeleteRoomFail(t *testing.T) {
 	}
 }
 
+func TestRESTFindOrCreateTalkRoom(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID   = uint64(1)
+		FriendID = uint64(2)
+		RoomID   = uint64(3)
+	)
+	TalkRoom := action.FindOrCreateTalkRoom{
+		SenderID: UserID,
+		FriendID: FriendID,
+	}
+
+	for _, testcase := range []struct {
+		Created bool
+		Status  int
+	}{
+		{true, http.StatusCreated},
+		{false, http.StatusOK},
+	} {
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().FindOrCreateTalkRoom(gomock.Any(), TalkRoom).
+			Return(&result.FindOrCreateTalkRoom{RoomID: RoomID, Created: testcase.Created}, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.POST, "/talk_rooms", TalkRoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+
+		if err := RESTHandler.FindOrCreateTalkRoom(c); err != nil {
+			t.Fatalf("FindOrCreateTalkRoom returns error: %v", err)
+		}
+		if rec.Code != testcase.Status {
+			t.Errorf("different status code, expect: %v, got: %v", testcase.Status, rec.Code)
+		}
+
+		response := struct {
+			RoomID   uint64 `json:"room_id"`
+			FriendID uint64 `json:"friend_id"`
+			Created  bool   `json:"created"`
+			OK       bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.RoomID != RoomID || response.FriendID != FriendID || response.Created != testcase.Created || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+
+	// not a friend
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().FindOrCreateTalkRoom(gomock.Any(), TalkRoom).
+			Return(nil, domain.NewPermissionError("not a friend")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.POST, "/talk_rooms", TalkRoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+
+		err = RESTHandler.FindOrCreateTalkRoom(c)
+		if err == nil {
+			t.Fatal("requesting talk room with not a friend, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusForbidden, true)
+	}
+}
+
 func TestRESTAddRoomMember(t *testing.T) {
 	t.Parallel()
 
@@ -552,6 +662,308 @@ func TestRESTRemoveRoomMember(t *testing.T) {
 
 // TODO rewrite test function with gomock, which other tests depends on command service
 
+func TestRESTRenameRoom(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID = uint64(1)
+		RoomID = uint64(2)
+	)
+	RenameRoom := action.RenameRoom{
+		SenderID: UserID,
+		RoomID:   RoomID,
+		RoomName: "renamed",
+	}
+
+	// case 1: success
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().RenameRoom(gomock.Any(), RenameRoom).
+			Return(RoomID, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, "/rooms/:room_id", RenameRoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(fmt.Sprint(RoomID))
+
+		if err := RESTHandler.RenameRoom(c); err != nil {
+			t.Fatalf("RenameRoom returns error: %v", err)
+		}
+
+		response := struct {
+			RoomID   uint64 `json:"room_id"`
+			RoomName string `json:"room_name"`
+			OK       bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.RoomID != RoomID || response.RoomName != RenameRoom.RoomName || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+
+	// case 2: not permitted
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().RenameRoom(gomock.Any(), RenameRoom).
+			Return(uint64(0), domain.NewPermissionError("not permitted")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, "/rooms/:room_id", RenameRoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(fmt.Sprint(RoomID))
+
+		err = RESTHandler.RenameRoom(c)
+		if err == nil {
+			t.Fatal("requesting not permitted operation, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusForbidden, true)
+	}
+}
+
+func TestRESTChangeRoomMemberRole(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID   = uint64(1)
+		RoomID   = uint64(2)
+		MemberID = uint64(3)
+	)
+	ChangeRole := action.ChangeRoomMemberRole{
+		SenderID: UserID,
+		RoomID:   RoomID,
+		UserID:   MemberID,
+		Role:     "admin",
+	}
+
+	// case 1: success
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().ChangeRoomMemberRole(gomock.Any(), ChangeRole).
+			Return(RoomID, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PUT, "/rooms/:room_id/members/:user_id/role", map[string]string{"role": "admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "user_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MemberID))
+
+		if err := RESTHandler.ChangeRoomMemberRole(c); err != nil {
+			t.Fatalf("ChangeRoomMemberRole returns error: %v", err)
+		}
+
+		response := struct {
+			RoomID uint64 `json:"room_id"`
+			UserID uint64 `json:"user_id"`
+			Role   string `json:"role"`
+			OK     bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.RoomID != RoomID || response.UserID != MemberID || response.Role != ChangeRole.Role || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+
+	// case 2: not permitted
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().ChangeRoomMemberRole(gomock.Any(), ChangeRole).
+			Return(uint64(0), domain.NewPermissionError("not permitted")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PUT, "/rooms/:room_id/members/:user_id/role", map[string]string{"role": "admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "user_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MemberID))
+
+		err = RESTHandler.ChangeRoomMemberRole(c)
+		if err == nil {
+			t.Fatal("requesting not permitted operation, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusForbidden, true)
+	}
+
+	// case 3: invalid role
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PUT, "/rooms/:room_id/members/:user_id/role", map[string]string{"role": "king"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "user_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MemberID))
+
+		err = RESTHandler.ChangeRoomMemberRole(c)
+		if err == nil {
+			t.Fatal("requesting invalid role, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusBadRequest, true)
+	}
+}
+
+func TestRESTLeaveRoom(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID = uint64(1)
+		RoomID = uint64(2)
+	)
+	LeaveRoom := action.LeaveRoom{
+		SenderID: UserID,
+		RoomID:   RoomID,
+	}
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().LeaveRoom(gomock.Any(), LeaveRoom).
+		Return(RoomID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	req := httptest.NewRequest(echo.POST, "/rooms/:room_id/leave", nil)
+	rec := httptest.NewRecorder()
+
+	c := theEcho.NewContext(req, rec)
+	c.Set(KeyLoggedInUserID, UserID)
+	c.SetParamNames("room_id")
+	c.SetParamValues(fmt.Sprint(RoomID))
+
+	if err := RESTHandler.LeaveRoom(c); err != nil {
+		t.Fatalf("LeaveRoom returns error: %v", err)
+	}
+
+	response := struct {
+		RoomID uint64 `json:"left_room_id"`
+		OK     bool   `json:"ok"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.RoomID != RoomID || !response.OK {
+		t.Errorf("different response: %#v", response)
+	}
+}
+
+func TestRESTTransferRoomOwnership(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID     = uint64(1)
+		RoomID     = uint64(2)
+		NewOwnerID = uint64(3)
+	)
+	Transfer := action.TransferRoomOwnership{
+		SenderID:   UserID,
+		RoomID:     RoomID,
+		NewOwnerID: NewOwnerID,
+	}
+
+	// case 1: success
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().TransferRoomOwnership(gomock.Any(), Transfer).
+			Return(RoomID, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PUT, "/rooms/:room_id/owner", Transfer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(fmt.Sprint(RoomID))
+
+		if err := RESTHandler.TransferRoomOwnership(c); err != nil {
+			t.Fatalf("TransferRoomOwnership returns error: %v", err)
+		}
+
+		response := struct {
+			RoomID     uint64 `json:"room_id"`
+			NewOwnerID uint64 `json:"new_owner_id"`
+			OK         bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.RoomID != RoomID || response.NewOwnerID != NewOwnerID || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+
+	// case 2: not permitted
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().TransferRoomOwnership(gomock.Any(), Transfer).
+			Return(uint64(0), domain.NewPermissionError("not permitted")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PUT, "/rooms/:room_id/owner", Transfer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(fmt.Sprint(RoomID))
+
+		err = RESTHandler.TransferRoomOwnership(c)
+		if err == nil {
+			t.Fatal("requesting not permitted operation, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusForbidden, true)
+	}
+}
+
 func TestRESTReadRoomMessages(t *testing.T) {
 	t.Parallel()
 
@@ -907,33 +1319,401 @@ func TestRESTPostRoomMessage(t *testing.T) {
 	t.Logf("%#v", response)
 }
 
-func TestRESTGetRoomMessages(t *testing.T) {
-	const URL = "/rooms/:room_id/messages"
+func TestRESTEditRoomMessage(t *testing.T) {
+	const URL = "/rooms/:room_id/messages/:message_id"
 
 	t.Parallel()
 
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	// case: found resource with json
-	{
-		const (
-			LoginUserID = uint64(1)
-			RoomID      = uint64(2)
-		)
-
-		res := queried.EmptyRoomMessages
-		res.RoomID = RoomID
-		res.Msgs = []queried.Message{queried.Message{}}
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
 
-		query := action.QueryRoomMessages{
-			RoomID: RoomID,
-			Before: NormTimestampNow(),
-			Limit:  1,
+	// case 1: success
+	{
+		EditChatMessage := action.EditChatMessage{
+			SenderID:  UserID,
+			RoomID:    RoomID,
+			MessageID: MessageID,
+			Content:   "edited",
 		}
 
-		qs := mocks.NewMockQueryService(mockCtrl)
-		qs.EXPECT().
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().EditRoomMessage(gomock.Any(), EditChatMessage).
+			Return(MessageID, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, URL, action.EditChatMessage{Content: EditChatMessage.Content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err = RESTHandler.EditRoomMessage(c)
+		if err != nil {
+			t.Fatalf("EditRoomMessage returns error: %v", err)
+		}
+		if expect, got := http.StatusOK, rec.Code; expect != got {
+			t.Errorf("different http status code, expect: %v, got: %v", expect, got)
+		}
+
+		response := make(map[string]interface{})
+		err = json.Unmarshal(rec.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgID := uint64(response["message_id"].(float64)); msgID != MessageID {
+			t.Errorf("different edited message id, expect: %v, got: %v", MessageID, msgID)
+		}
+		if ok, assertionOK := response["ok"].(bool); !assertionOK || !ok {
+			t.Errorf("message edited but not ok status")
+		}
+	}
+
+	// case 2: referring not found message
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().EditRoomMessage(gomock.Any(), gomock.Any()).
+			Return(uint64(0), chat.NewNotFoundError("not found")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, URL, action.EditChatMessage{Content: "edited"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err = RESTHandler.EditRoomMessage(c)
+		if err == nil {
+			t.Fatal("requesting not found message, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusNotFound, true)
+	}
+
+	// case 2-1: edited by not an author, or edit the deleted message
+	for _, testcase := range []struct {
+		Err    error
+		Status int
+	}{
+		{domain.NewPermissionError("not an author"), http.StatusForbidden},
+		{domain.NewValidationError("already deleted"), http.StatusBadRequest},
+	} {
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().EditRoomMessage(gomock.Any(), gomock.Any()).
+			Return(uint64(0), testcase.Err).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, URL, action.EditChatMessage{Content: "edited"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err = RESTHandler.EditRoomMessage(c)
+		testAssertHTTPError(t, err, testcase.Status, true)
+	}
+
+	// case 3: bad request
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.PATCH, URL, action.EditChatMessage{Content: "edited"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), "invalid_message_id")
+
+		err = RESTHandler.EditRoomMessage(c)
+		if err == nil {
+			t.Fatal("requesting bad arguments, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusBadRequest, true)
+	}
+}
+
+func TestRESTAddReaction(t *testing.T) {
+	const URL = "/rooms/:room_id/messages/:message_id/reactions"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+
+	for _, testcase := range []struct {
+		Err    error
+		Status int
+	}{
+		{nil, http.StatusOK},
+		{domain.NewValidationError("already reacted"), http.StatusBadRequest},
+		{domain.NewPermissionError("not a member"), http.StatusForbidden},
+		{chat.NewNotFoundError("not found"), http.StatusNotFound},
+	} {
+		AddReaction := action.AddReaction{
+			SenderID:  UserID,
+			RoomID:    RoomID,
+			MessageID: MessageID,
+			Emoji:     ":+1:",
+		}
+
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().AddReaction(gomock.Any(), AddReaction).
+			Return(MessageID, testcase.Err).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.POST, URL, action.AddReaction{Emoji: AddReaction.Emoji})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err = RESTHandler.AddReaction(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("AddReaction returns error: %v", err)
+		}
+		if expect, got := http.StatusOK, rec.Code; expect != got {
+			t.Errorf("different http status code, expect: %v, got: %v", expect, got)
+		}
+
+		response := make(map[string]interface{})
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if msgID := uint64(response["message_id"].(float64)); msgID != MessageID {
+			t.Errorf("different reacted message id, expect: %v, got: %v", MessageID, msgID)
+		}
+		if ok, assertionOK := response["ok"].(bool); !assertionOK || !ok {
+			t.Errorf("reaction added but not ok status")
+		}
+	}
+}
+
+func TestRESTRemoveReaction(t *testing.T) {
+	const URL = "/rooms/:room_id/messages/:message_id/reactions/:emoji"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().
+		RemoveReaction(gomock.Any(), action.RemoveReaction{
+			SenderID:  UserID,
+			RoomID:    RoomID,
+			MessageID: MessageID,
+			Emoji:     "\U0001f44d",
+		}).
+		Return(MessageID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		MessageID string
+		Emoji     string
+		Status    int
+	}{
+		{fmt.Sprint(MessageID), "%F0%9F%91%8D", http.StatusOK},
+		{fmt.Sprint(MessageID), "%zz", http.StatusBadRequest},
+		{"invalid", "%F0%9F%91%8D", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.DELETE, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id", "emoji")
+		c.SetParamValues(fmt.Sprint(RoomID), testcase.MessageID, testcase.Emoji)
+
+		err := RESTHandler.RemoveReaction(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("RemoveReaction returns error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("different status code, expect: %v, got: %v", http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func TestRESTDeleteRoomMessage(t *testing.T) {
+	const URL = "/rooms/:room_id/messages/:message_id"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+
+	// case 1: success
+	{
+		DeleteChatMessage := action.DeleteChatMessage{
+			SenderID:  UserID,
+			RoomID:    RoomID,
+			MessageID: MessageID,
+		}
+
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().DeleteRoomMessage(gomock.Any(), DeleteChatMessage).
+			Return(MessageID, nil).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req := httptest.NewRequest(echo.DELETE, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err := RESTHandler.DeleteRoomMessage(c)
+		if err != nil {
+			t.Fatalf("DeleteRoomMessage returns error: %v", err)
+		}
+		if expect, got := http.StatusOK, rec.Code; expect != got {
+			t.Errorf("different http status code, expect: %v, got: %v", expect, got)
+		}
+
+		response := make(map[string]interface{})
+		err = json.Unmarshal(rec.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgID := uint64(response["message_id"].(float64)); msgID != MessageID {
+			t.Errorf("different deleted message id, expect: %v, got: %v", MessageID, msgID)
+		}
+		if ok, assertionOK := response["ok"].(bool); !assertionOK || !ok {
+			t.Errorf("message deleted but not ok status")
+		}
+	}
+
+	// case 2: referring not found message
+	{
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().DeleteRoomMessage(gomock.Any(), gomock.Any()).
+			Return(uint64(0), chat.NewNotFoundError("not found")).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req := httptest.NewRequest(echo.DELETE, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err := RESTHandler.DeleteRoomMessage(c)
+		if err == nil {
+			t.Fatal("requesting not found message, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusNotFound, true)
+	}
+
+	// case 3: deleted by not an author, or deleted twice
+	for _, testcase := range []struct {
+		Err    error
+		Status int
+	}{
+		{domain.NewPermissionError("not an author"), http.StatusForbidden},
+		{domain.NewValidationError("already deleted"), http.StatusBadRequest},
+	} {
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().DeleteRoomMessage(gomock.Any(), gomock.Any()).
+			Return(uint64(0), testcase.Err).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req := httptest.NewRequest(echo.DELETE, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), fmt.Sprint(MessageID))
+
+		err := RESTHandler.DeleteRoomMessage(c)
+		testAssertHTTPError(t, err, testcase.Status, true)
+	}
+}
+
+func TestRESTGetRoomMessages(t *testing.T) {
+	const URL = "/rooms/:room_id/messages"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// case: found resource with json
+	{
+		const (
+			LoginUserID = uint64(1)
+			RoomID      = uint64(2)
+		)
+
+		res := queried.EmptyRoomMessages
+		res.RoomID = RoomID
+		res.Msgs = []queried.Message{queried.Message{}}
+
+		query := action.QueryRoomMessages{
+			RoomID: RoomID,
+			Before: NormTimestampNow(),
+			Limit:  1,
+		}
+
+		qs := mocks.NewMockQueryService(mockCtrl)
+		qs.EXPECT().
 			FindRoomMessages(gomock.Any(), LoginUserID, query).
 			Return(&res, nil).
 			Times(1)
@@ -1248,3 +2028,826 @@ func TestRESTGetUnreadRoomMessages(t *testing.T) {
 		}
 	}
 }
+
+func TestRESTCreateUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const UserID = uint64(1)
+	CreateUser := action.CreateUser{
+		Name:     "user",
+		Password: "password",
+	}
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().CreateUser(gomock.Any(), CreateUser).
+		Return(UserID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	// case1: success without login.
+	{
+		req, err := newJSONRequest(echo.POST, "/signup", CreateUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		c := theEcho.NewContext(req, rec)
+
+		if err := RESTHandler.CreateUser(c); err != nil {
+			t.Fatalf("CreateUser returns error: %v", err)
+		}
+		if rec.Code != http.StatusCreated {
+			t.Errorf("different status code, expect: %v, got: %v", http.StatusCreated, rec.Code)
+		}
+
+		response := struct {
+			UserID uint64 `json:"user_id"`
+			OK     bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.UserID != UserID || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+
+	// case2: invalid user
+	{
+		invalid := action.CreateUser{Name: "user"}
+		cmdService.EXPECT().CreateUser(gomock.Any(), invalid).
+			Return(uint64(0), domain.NewValidationError("password is empty")).Times(1)
+
+		req, err := newJSONRequest(echo.POST, "/signup", invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		c := theEcho.NewContext(req, rec)
+
+		err = RESTHandler.CreateUser(c)
+		if err == nil {
+			t.Fatal("create invalid user, but no error")
+		}
+		testAssertHTTPError(t, err, http.StatusBadRequest, true)
+	}
+}
+
+func TestRESTUpdateUserProfile(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID      = uint64(1)
+		OtherUserID = uint64(2)
+	)
+	UpdateProfile := action.UpdateUserProfile{
+		SenderID:  UserID,
+		Name:      "new-name",
+		FirstName: "first",
+		LastName:  "last",
+	}
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().UpdateUserProfile(gomock.Any(), UpdateProfile).
+		Return(UserID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		ParamUserID uint64
+		Status      int
+	}{
+		{UserID, http.StatusOK},
+		{OtherUserID, http.StatusForbidden},
+	} {
+		req, err := newJSONRequest(echo.PATCH, "/users/:user_id", UpdateProfile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("user_id")
+		c.SetParamValues(fmt.Sprint(testcase.ParamUserID))
+
+		err = RESTHandler.UpdateUserProfile(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("UpdateUserProfile returns error: %v", err)
+		}
+
+		response := struct {
+			UserID uint64 `json:"user_id"`
+			OK     bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.UserID != UserID || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+}
+
+func TestRESTDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID      = uint64(1)
+		OtherUserID = uint64(2)
+	)
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().DeleteUser(gomock.Any(), action.DeleteUser{SenderID: UserID}).
+		Return(UserID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		ParamUserID uint64
+		Status      int
+	}{
+		{UserID, http.StatusOK},
+		{OtherUserID, http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(echo.DELETE, "/users/:user_id", nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("user_id")
+		c.SetParamValues(fmt.Sprint(testcase.ParamUserID))
+
+		err := RESTHandler.DeleteUser(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("DeleteUser returns error: %v", err)
+		}
+
+		response := struct {
+			UserID uint64 `json:"deleted_user_id"`
+			OK     bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.UserID != UserID || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+}
+
+func TestRESTSendFriendRequest(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID     = uint64(1)
+		ReceiverID = uint64(2)
+	)
+	SendRequest := action.SendFriendRequest{SenderID: UserID, UserID: ReceiverID}
+
+	for _, testcase := range []struct {
+		Err    error
+		Status int
+	}{
+		{nil, http.StatusOK},
+		{domain.NewValidationError("already sent"), http.StatusBadRequest},
+		{domain.NewPermissionError("blocked"), http.StatusForbidden},
+		{chat.NewNotFoundError("not found"), http.StatusNotFound},
+	} {
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().SendFriendRequest(gomock.Any(), SendRequest).
+			Return(ReceiverID, testcase.Err).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.POST, "/friend_requests", SendRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+
+		err = RESTHandler.SendFriendRequest(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("SendFriendRequest returns error: %v", err)
+		}
+
+		response := struct {
+			UserID uint64 `json:"user_id"`
+			OK     bool   `json:"ok"`
+		}{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.UserID != ReceiverID || !response.OK {
+			t.Errorf("different response: %#v", response)
+		}
+	}
+}
+
+func TestRESTAcceptFriendRequest(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID   = uint64(1)
+		SenderID = uint64(2)
+	)
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().
+		AcceptFriendRequest(gomock.Any(), action.AcceptFriendRequest{SenderID: UserID, UserID: SenderID}).
+		Return(SenderID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		Param  string
+		Status int
+	}{
+		{fmt.Sprint(SenderID), http.StatusOK},
+		{"invalid", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.POST, "/friend_requests/:user_id/accept", nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("user_id")
+		c.SetParamValues(testcase.Param)
+
+		err := RESTHandler.AcceptFriendRequest(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("AcceptFriendRequest returns error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("different status code, expect: %v, got: %v", http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func TestRESTUnblockUser(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		BlockedID = uint64(2)
+	)
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().
+		UnblockUser(gomock.Any(), action.UnblockUser{SenderID: UserID, UserID: BlockedID}).
+		Return(BlockedID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		Param  string
+		Status int
+	}{
+		{fmt.Sprint(BlockedID), http.StatusOK},
+		{"invalid", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.DELETE, "/blocks/:user_id", nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("user_id")
+		c.SetParamValues(testcase.Param)
+
+		err := RESTHandler.UnblockUser(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("UnblockUser returns error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("different status code, expect: %v, got: %v", http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func TestRESTGetBlockedUsers(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const UserID = uint64(1)
+
+	blocked := &queried.BlockedUsers{
+		UserID: UserID,
+		Users:  []queried.UserProfile{{UserID: 2, UserName: "blocked"}},
+	}
+	queryService := mocks.NewMockQueryService(mockCtrl)
+	queryService.EXPECT().FindBlockedUsers(gomock.Any(), UserID).
+		Return(blocked, nil).Times(1)
+	RESTHandler := &RESTHandler{chatQuery: queryService}
+
+	req := httptest.NewRequest(echo.GET, "/blocks", nil)
+	rec := httptest.NewRecorder()
+
+	c := theEcho.NewContext(req, rec)
+	c.Set(KeyLoggedInUserID, UserID)
+
+	if err := RESTHandler.GetBlockedUsers(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("different status code, expect: %v, got: %v", http.StatusOK, rec.Code)
+	}
+
+	got := &queried.BlockedUsers{}
+	if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != UserID || len(got.Users) != 1 || got.Users[0] != blocked.Users[0] {
+		t.Errorf("different blocked users, expect: %#v, got: %#v", blocked, got)
+	}
+}
+
+func TestRESTGetThreadMessages(t *testing.T) {
+	const URL = "/rooms/:room_id/messages/:message_id/replies"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		LoginUserID = uint64(1)
+		RoomID      = uint64(2)
+		ParentID    = uint64(3)
+	)
+
+	res := queried.EmptyThreadMessages
+	res.RoomID = RoomID
+	res.Parent = queried.Message{MessageID: ParentID, ReplyCount: 1}
+	res.Msgs = []queried.Message{{MessageID: 4, ParentID: ParentID}}
+
+	query := action.QueryThreadMessages{
+		RoomID:    RoomID,
+		MessageID: ParentID,
+		Before:    NormTimestampNow(),
+		Limit:     1,
+	}
+
+	qs := mocks.NewMockQueryService(mockCtrl)
+	qs.EXPECT().
+		FindThreadMessages(gomock.Any(), LoginUserID, query).
+		Return(&res, nil).
+		Times(1)
+	qs.EXPECT().
+		FindThreadMessages(gomock.Any(), LoginUserID, gomock.Any()).
+		Return(nil, chat.NewNotFoundError("not found")).
+		Times(1)
+	RESTHandler := &RESTHandler{chatQuery: qs}
+
+	for _, testcase := range []struct {
+		Param  string
+		Status int
+	}{
+		{fmt.Sprint(ParentID), http.StatusOK},
+		{fmt.Sprint(ParentID + 1), http.StatusNotFound},
+		{"invalid", http.StatusBadRequest},
+	} {
+		body := query
+		body.RoomID, body.MessageID = 0, 0 // remove IDs from query JSON.
+		req, err := newJSONRequest(echo.GET, URL, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, LoginUserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), testcase.Param)
+
+		err = RESTHandler.GetThreadMessages(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := queried.ThreadMessages{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Parent.MessageID != ParentID || len(got.Msgs) != 1 || got.Msgs[0].ParentID != ParentID {
+			t.Errorf("different thread messages: %#v", got)
+		}
+	}
+}
+
+func TestRESTPinMessage(t *testing.T) {
+	const URL = "/rooms/:room_id/pins"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+
+	for _, testcase := range []struct {
+		Err    error
+		Status int
+	}{
+		{nil, http.StatusOK},
+		{domain.NewValidationError("already pinned"), http.StatusBadRequest},
+		{domain.NewPermissionError("not an admin"), http.StatusForbidden},
+		{chat.NewNotFoundError("not found"), http.StatusNotFound},
+	} {
+		cmdService := mocks.NewMockCommandService(mockCtrl)
+		cmdService.EXPECT().
+			PinMessage(gomock.Any(), action.PinMessage{SenderID: UserID, RoomID: RoomID, MessageID: MessageID}).
+			Return(RoomID, testcase.Err).Times(1)
+		RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+		req, err := newJSONRequest(echo.POST, URL, action.PinMessage{MessageID: MessageID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(fmt.Sprint(RoomID))
+
+		err = RESTHandler.PinMessage(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("PinMessage returns error: %v", err)
+		}
+
+		response := make(map[string]interface{})
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if msgID := uint64(response["message_id"].(float64)); msgID != MessageID {
+			t.Errorf("different pinned message id, expect: %v, got: %v", MessageID, msgID)
+		}
+		if roomID := uint64(response["room_id"].(float64)); roomID != RoomID {
+			t.Errorf("different pinned room id, expect: %v, got: %v", RoomID, roomID)
+		}
+	}
+}
+
+func TestRESTUnpinMessage(t *testing.T) {
+	const URL = "/rooms/:room_id/pins/:message_id"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		UserID    = uint64(1)
+		RoomID    = uint64(2)
+		MessageID = uint64(3)
+	)
+
+	cmdService := mocks.NewMockCommandService(mockCtrl)
+	cmdService.EXPECT().
+		UnpinMessage(gomock.Any(), action.UnpinMessage{SenderID: UserID, RoomID: RoomID, MessageID: MessageID}).
+		Return(RoomID, nil).Times(1)
+	RESTHandler := &RESTHandler{chatCmd: cmdService}
+
+	for _, testcase := range []struct {
+		Param  string
+		Status int
+	}{
+		{fmt.Sprint(MessageID), http.StatusOK},
+		{"invalid", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.DELETE, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, UserID)
+		c.SetParamNames("room_id", "message_id")
+		c.SetParamValues(fmt.Sprint(RoomID), testcase.Param)
+
+		err := RESTHandler.UnpinMessage(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("UnpinMessage returns error: %v", err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Errorf("different status code, expect: %v, got: %v", http.StatusOK, rec.Code)
+		}
+	}
+}
+
+func TestRESTGetRoomPins(t *testing.T) {
+	const URL = "/rooms/:room_id/pins"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		LoginUserID = uint64(1)
+		RoomID      = uint64(2)
+	)
+
+	res := queried.EmptyRoomPins
+	res.RoomID = RoomID
+	res.Pins = []queried.PinnedMessage{{Message: queried.Message{MessageID: 3}, PinnedBy: LoginUserID}}
+	res.PinsSize = 1
+
+	qs := mocks.NewMockQueryService(mockCtrl)
+	qs.EXPECT().
+		FindRoomPins(gomock.Any(), LoginUserID, RoomID).
+		Return(&res, nil).
+		Times(1)
+	qs.EXPECT().
+		FindRoomPins(gomock.Any(), LoginUserID, RoomID+1).
+		Return(nil, chat.NewNotFoundError("not found")).
+		Times(1)
+	RESTHandler := &RESTHandler{chatQuery: qs}
+
+	for _, testcase := range []struct {
+		Param  string
+		Status int
+	}{
+		{fmt.Sprint(RoomID), http.StatusOK},
+		{fmt.Sprint(RoomID + 1), http.StatusNotFound},
+		{"invalid", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.GET, URL, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, LoginUserID)
+		c.SetParamNames("room_id")
+		c.SetParamValues(testcase.Param)
+
+		err := RESTHandler.GetRoomPins(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("GetRoomPins returns error: %v", err)
+		}
+
+		var got queried.RoomPins
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.RoomID != RoomID || got.PinsSize != 1 || len(got.Pins) != 1 ||
+			got.Pins[0].MessageID != 3 || got.Pins[0].PinnedBy != LoginUserID {
+			t.Errorf("different room pins: %#v", got)
+		}
+	}
+}
+
+func TestRESTSearchMessages(t *testing.T) {
+	const URL = "/messages/search"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		LoginUserID = uint64(1)
+		RoomID      = uint64(2)
+		Query       = "hello"
+	)
+
+	res := queried.EmptySearchedMessages
+	res.Query = Query
+	res.Msgs = []queried.SearchedMessage{
+		{Message: queried.Message{MessageID: 3}, RoomID: RoomID, Score: 1.5, Snippet: "<em>hello</em> world"},
+	}
+	res.MsgsSize = 1
+
+	qs := mocks.NewMockQueryService(mockCtrl)
+	qs.EXPECT().
+		SearchMessages(gomock.Any(), LoginUserID, action.QuerySearchMessages{Query: Query, RoomID: RoomID}).
+		Return(&res, nil).
+		Times(1)
+	qs.EXPECT().
+		SearchMessages(gomock.Any(), LoginUserID, action.QuerySearchMessages{Query: Query, RoomID: RoomID + 1}).
+		Return(nil, chat.NewNotFoundError("not found")).
+		Times(1)
+	qs.EXPECT().
+		SearchMessages(gomock.Any(), LoginUserID, action.QuerySearchMessages{}).
+		Return(nil, domain.NewValidationError("empty query")).
+		Times(1)
+	RESTHandler := &RESTHandler{chatQuery: qs}
+
+	for _, testcase := range []struct {
+		Query  string
+		Status int
+	}{
+		{fmt.Sprintf("q=%s&room_id=%d", Query, RoomID), http.StatusOK},
+		{fmt.Sprintf("q=%s&room_id=%d", Query, RoomID+1), http.StatusNotFound},
+		{"", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.GET, URL+"?"+testcase.Query, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, LoginUserID)
+
+		err := RESTHandler.SearchMessages(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("SearchMessages returns error: %v", err)
+		}
+
+		var got queried.SearchedMessages
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Query != Query || got.MsgsSize != 1 || len(got.Msgs) != 1 ||
+			got.Msgs[0].MessageID != 3 || got.Msgs[0].RoomID != RoomID ||
+			got.Msgs[0].Snippet != res.Msgs[0].Snippet {
+			t.Errorf("different searched messages: %#v", got)
+		}
+	}
+}
+
+func TestRESTGetUserMentions(t *testing.T) {
+	const URL = "/mentions"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		LoginUserID = uint64(1)
+		Limit       = 10
+	)
+
+	res := queried.EmptyUserMentions
+	res.UserID = LoginUserID
+	res.Mentions = []queried.Mention{{Message: queried.Message{MessageID: 3}, RoomID: 2, Unread: true}}
+	res.UnreadCount = 1
+
+	qs := mocks.NewMockQueryService(mockCtrl)
+	qs.EXPECT().
+		FindUserMentions(gomock.Any(), LoginUserID, action.QueryUserMentions{Limit: Limit}).
+		Return(&res, nil).
+		Times(1)
+	qs.EXPECT().
+		FindUserMentions(gomock.Any(), LoginUserID, action.QueryUserMentions{}).
+		Return(nil, chat.NewNotFoundError("not found")).
+		Times(1)
+	RESTHandler := &RESTHandler{chatQuery: qs}
+
+	for _, testcase := range []struct {
+		Query  string
+		Status int
+	}{
+		{fmt.Sprintf("limit=%d", Limit), http.StatusOK},
+		{"", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(echo.GET, URL+"?"+testcase.Query, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, LoginUserID)
+
+		err := RESTHandler.GetUserMentions(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("GetUserMentions returns error: %v", err)
+		}
+
+		var got queried.UserMentions
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != LoginUserID || got.UnreadCount != 1 || len(got.Mentions) != 1 ||
+			got.Mentions[0].MessageID != 3 || got.Mentions[0].RoomID != 2 || !got.Mentions[0].Unread {
+			t.Errorf("different user mentions: %#v", got)
+		}
+	}
+}
+
+func TestRESTGetUserEvents(t *testing.T) {
+	const URL = "/events"
+
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		LoginUserID = uint64(1)
+		After       = uint64(5)
+		Limit       = 10
+	)
+
+	res := &queried.UserEvents{
+		UserID: LoginUserID,
+		Events: []event.Event{chat.NewEventJSON(event.RoomCreated{RoomID: 2})},
+	}
+
+	qs := mocks.NewMockQueryService(mockCtrl)
+	qs.EXPECT().
+		FindUserEvents(gomock.Any(), LoginUserID, action.QueryUserEvents{After: After, Stream: "room", Limit: Limit}).
+		Return(res, nil).
+		Times(1)
+	qs.EXPECT().
+		FindUserEvents(gomock.Any(), LoginUserID, action.QueryUserEvents{Stream: "unknown"}).
+		Return(nil, domain.NewValidationError("unknown stream")).
+		Times(1)
+	RESTHandler := &RESTHandler{chatQuery: qs}
+
+	for _, testcase := range []struct {
+		Query  string
+		Status int
+	}{
+		{fmt.Sprintf("after=%d&stream=room&limit=%d", After, Limit), http.StatusOK},
+		{"stream=unknown", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(echo.GET, URL+"?"+testcase.Query, nil)
+		rec := httptest.NewRecorder()
+
+		c := theEcho.NewContext(req, rec)
+		c.Set(KeyLoggedInUserID, LoginUserID)
+
+		err := RESTHandler.GetUserEvents(c)
+		if testcase.Status != http.StatusOK {
+			testAssertHTTPError(t, err, testcase.Status, true)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("GetUserEvents returns error: %v", err)
+		}
+
+		var got struct {
+			UserID uint64 `json:"user_id"`
+			Events []struct {
+				Event string `json:"event"`
+			} `json:"events"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != LoginUserID || len(got.Events) != 1 ||
+			got.Events[0].Event != chat.EventNameRoomCreated {
+			t.Errorf("different user events: %s", rec.Body.String())
+		}
+	}
+}
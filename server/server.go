@@ -8,25 +8,58 @@ import (
 	"net/http"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 
 	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/chat/action"
-	"github.com/shirasudon/go-chat/domain/event"
+	"github.com/shirasudon/go-chat/longpoll"
+	"github.com/shirasudon/go-chat/sse"
 	"github.com/shirasudon/go-chat/ws"
 )
 
+// key for the URL query of the websocket connection, which is the
+// sequence number of the last received event. The events after it
+// are replayed before the live events.
+const QueryKeyEventCursor = "after"
+
+const (
+	// key for the URL query of the long-polling, which is the
+	// session ID returned by the first polling.
+	QueryKeyPollSession = "session_id"
+
+	// key for the URL query of the long-polling, which is the cursor
+	// returned by the last polling. The events until it are acknowledged.
+	QueryKeyPollCursor = "cursor"
+
+	// key for the URL query of the long-polling, which is the
+	// maximum number of the events returned.
+	QueryKeyPollLimit = "limit"
+
+	// key for the URL parameter of the long-polling session ID.
+	ParamKeyPollSession = "session_id"
+
+	// MaxPollEvents is the maximum number of the events returned
+	// by the long-polling at once.
+	MaxPollEvents = 100
+)
+
 // it represents server which can accepts chat room and its clients.
 type Server struct {
 	echo *echo.Echo
 
 	wsServer     *ws.Server
+	pollSessions *longpoll.Sessions
 	loginHandler *LoginHandler
 	restHandler  *RESTHandler
 
+	// it is nil when the attachments are not supported.
+	attachmentHandler *AttachmentHandler
+
 	chatHub chat.Hub
 
 	conf Config
@@ -34,7 +67,8 @@ type Server struct {
 
 // it returns new constructed server with config.
 // nil config is ok and use DefaultConfig insteadly.
-func NewServer(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub chat.Hub, login chat.LoginService, conf *Config) *Server {
+// nil attachments is also ok and the attachment APIs are not served.
+func NewServer(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub chat.Hub, login chat.LoginService, attachments chat.AttachmentService, conf *Config) *Server {
 	if conf == nil {
 		conf = &DefaultConfig
 	}
@@ -50,6 +84,11 @@ func NewServer(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub
 		conf:         *conf,
 	}
 	s.wsServer = ws.NewServerFunc(s.handleWsConn)
+	s.wsServer.Config = s.conf.WebsocketConfig()
+	s.pollSessions = longpoll.NewSessions(time.Duration(s.conf.LongPollIdleTimeout) * time.Second)
+	if attachments != nil {
+		s.attachmentHandler = NewAttachmentHandler(attachments, conf)
+	}
 
 	// initilize router
 	e.Use(middleware.Logger())
@@ -64,6 +103,10 @@ func NewServer(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub
 	e.POST("/logout", s.loginHandler.Logout).
 		Name = "doLogout"
 
+	// sign up does not require login.
+	e.POST("/signup", s.restHandler.CreateUser).
+		Name = "doSignup"
+
 	chatPath := path.Join(s.conf.ChatAPIPrefix, "/chat")
 	chatGroup := e.Group(chatPath, s.loginHandler.Filter())
 
@@ -72,29 +115,105 @@ func NewServer(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub
 		Name = "chat.createRoom"
 	chatGroup.DELETE("/rooms/:room_id", s.restHandler.DeleteRoom).
 		Name = "chat.deleteRoom"
+	chatGroup.POST("/talk_rooms", s.restHandler.FindOrCreateTalkRoom).
+		Name = "chat.findOrCreateTalkRoom"
 	chatGroup.GET("/rooms/:room_id", s.restHandler.GetRoomInfo).
 		Name = "chat.getRoomInfo"
 	chatGroup.POST("/rooms/:room_id/members", s.restHandler.AddRoomMember).
 		Name = "chat.addRoomMember"
 	chatGroup.DELETE("/rooms/:room_id/members", s.restHandler.RemoveRoomMember).
 		Name = "chat.removeRoomMember"
+	chatGroup.PATCH("/rooms/:room_id", s.restHandler.RenameRoom).
+		Name = "chat.renameRoom"
+	chatGroup.PUT("/rooms/:room_id/members/:user_id/role", s.restHandler.ChangeRoomMemberRole).
+		Name = "chat.changeRoomMemberRole"
+	chatGroup.POST("/rooms/:room_id/leave", s.restHandler.LeaveRoom).
+		Name = "chat.leaveRoom"
+	chatGroup.PUT("/rooms/:room_id/owner", s.restHandler.TransferRoomOwnership).
+		Name = "chat.transferRoomOwnership"
 
 	chatGroup.GET("/users/:user_id", s.restHandler.GetUserInfo).
 		Name = "chat.getUserInfo"
+	chatGroup.PATCH("/users/:user_id", s.restHandler.UpdateUserProfile).
+		Name = "chat.updateUserProfile"
+	chatGroup.DELETE("/users/:user_id", s.restHandler.DeleteUser).
+		Name = "chat.deleteUser"
+
+	chatGroup.POST("/friend_requests", s.restHandler.SendFriendRequest).
+		Name = "chat.sendFriendRequest"
+	chatGroup.POST("/friend_requests/:user_id/accept", s.restHandler.AcceptFriendRequest).
+		Name = "chat.acceptFriendRequest"
+	chatGroup.POST("/friend_requests/:user_id/decline", s.restHandler.DeclineFriendRequest).
+		Name = "chat.declineFriendRequest"
+	chatGroup.DELETE("/friend_requests/:user_id", s.restHandler.CancelFriendRequest).
+		Name = "chat.cancelFriendRequest"
+	chatGroup.DELETE("/friends/:user_id", s.restHandler.RemoveFriend).
+		Name = "chat.removeFriend"
+	chatGroup.POST("/blocks", s.restHandler.BlockUser).
+		Name = "chat.blockUser"
+	chatGroup.GET("/blocks", s.restHandler.GetBlockedUsers).
+		Name = "chat.getBlockedUsers"
+	chatGroup.DELETE("/blocks/:user_id", s.restHandler.UnblockUser).
+		Name = "chat.unblockUser"
 
 	chatGroup.POST("/rooms/:room_id/messages", s.restHandler.PostRoomMessage).
 		Name = "chat.postRoomMessage"
 	chatGroup.GET("/rooms/:room_id/messages", s.restHandler.GetRoomMessages).
 		Name = "chat.getRoomMessages"
+	chatGroup.PATCH("/rooms/:room_id/messages/:message_id", s.restHandler.EditRoomMessage).
+		Name = "chat.editRoomMessage"
+	chatGroup.DELETE("/rooms/:room_id/messages/:message_id", s.restHandler.DeleteRoomMessage).
+		Name = "chat.deleteRoomMessage"
+	chatGroup.GET("/rooms/:room_id/messages/:message_id/replies", s.restHandler.GetThreadMessages).
+		Name = "chat.getThreadMessages"
+	chatGroup.POST("/rooms/:room_id/messages/:message_id/reactions", s.restHandler.AddReaction).
+		Name = "chat.addReaction"
+	chatGroup.DELETE("/rooms/:room_id/messages/:message_id/reactions/:emoji", s.restHandler.RemoveReaction).
+		Name = "chat.removeReaction"
+
+	chatGroup.GET("/rooms/:room_id/pins", s.restHandler.GetRoomPins).
+		Name = "chat.getRoomPins"
+	chatGroup.POST("/rooms/:room_id/pins", s.restHandler.PinMessage).
+		Name = "chat.pinMessage"
+	chatGroup.DELETE("/rooms/:room_id/pins/:message_id", s.restHandler.UnpinMessage).
+		Name = "chat.unpinMessage"
 	chatGroup.POST("/rooms/:room_id/messages/read", s.restHandler.ReadRoomMessages).
 		Name = "chat.readRoomMessages"
 	chatGroup.GET("/rooms/:room_id/messages/unread", s.restHandler.GetUnreadRoomMessages).
 		Name = "chat.getUnreadRoomMessages"
+	chatGroup.GET("/messages/search", s.restHandler.SearchMessages).
+		Name = "chat.searchMessages"
+	chatGroup.GET("/mentions", s.restHandler.GetUserMentions).
+		Name = "chat.getUserMentions"
+	chatGroup.GET("/events", s.restHandler.GetUserEvents).
+		Name = "chat.getUserEvents"
+
+	// set attachmentHandler
+	if s.attachmentHandler != nil {
+		chatGroup.POST("/rooms/:room_id/attachments", s.attachmentHandler.Upload).
+			Name = "chat.uploadAttachment"
+		chatGroup.GET("/attachments/:attachment_id", s.attachmentHandler.GetAttachment).
+			Name = "chat.getAttachment"
+		chatGroup.GET("/attachments/:attachment_id/content", s.attachmentHandler.DownloadContent).
+			Name = "chat.downloadAttachment"
+		chatGroup.GET("/attachments/:attachment_id/thumbnail", s.attachmentHandler.DownloadThumbnail).
+			Name = "chat.downloadAttachmentThumbnail"
+	}
 
 	// set websocket handler
 	chatGroup.GET("/ws", s.serveChatWebsocket).
 		Name = "chat.connentWebsocket"
 
+	// set server-sent events handler
+	chatGroup.GET("/sse", s.serveChatSSE).
+		Name = "chat.connectSSE"
+
+	// set long-polling handler
+	chatGroup.GET("/poll", s.serveChatLongPoll).
+		Name = "chat.longPoll"
+	chatGroup.DELETE("/poll/:session_id", s.closeChatLongPoll).
+		Name = "chat.closeLongPoll"
+
 	// serve static content
 	if s.conf.EnableServeStaticFile {
 		route := path.Join(s.conf.StaticHandlerPrefix, "/")
@@ -113,34 +232,254 @@ func (s *Server) handleWsConn(conn *ws.Conn) {
 		s.chatHub.Send(conn, m)
 	})
 	conn.OnError(func(conn *ws.Conn, err error) {
-		conn.Send(event.ErrorRaised{Message: err.Error()})
+		// the error event is already sent to the client by the conn.
+		log.Printf("websocket error: %v\n", err)
 	})
 	conn.OnClosed(func(conn *ws.Conn) {
 		s.chatHub.Disconnect(conn)
 	})
 
-	err := s.chatHub.Connect(ctx, conn)
-	if err != nil {
-		conn.Send(event.ErrorRaised{Message: err.Error()})
-		log.Printf("websocket connect error: %v\n", err)
-		return
-	}
+	// the cursor is already validated before the connection upgraded.
+	after, replay, _ := eventCursor(conn.Request())
+
+	// the connection must be listening while connecting, since
+	// the replayed events are sent to it before connected.
+	go func() {
+		if err := s.connectHub(ctx, conn, after, replay); err != nil {
+			conn.Send(chat.NewEventJSON(chat.NewErrorRaised(err, nil)))
+			log.Printf("websocket connect error: %v\n", err)
+			conn.Close()
+		}
+	}()
 
 	// blocking to avoid connection closed
 	conn.Listen(ctx)
 }
 
+// connectHub connects the conn to the hub. The stored events after
+// the cursor are replayed to the conn before the live events if replay is true.
+func (s *Server) connectHub(ctx context.Context, conn chat.Conn, after uint64, replay bool) error {
+	if replay {
+		return s.chatHub.ConnectAfter(ctx, conn, after)
+	}
+	return s.chatHub.Connect(ctx, conn)
+}
+
 func (s *Server) serveChatWebsocket(c echo.Context) error {
 	// LoggedInUserID is valid at middleware layer, loginHandler.Filter.
 	userID, ok := LoggedInUserID(c)
 	if !ok {
 		return errors.New("needs logged in, but access without logged in state")
 	}
+	if _, _, err := eventCursor(c.Request()); err != nil {
+		return err
+	}
 
 	s.wsServer.ServeHTTPWithUserID(c.Response(), c.Request(), userID)
 	return nil
 }
 
+func (s *Server) serveChatSSE(c echo.Context) error {
+	// LoggedInUserID is valid at middleware layer, loginHandler.Filter.
+	userID, ok := LoggedInUserID(c)
+	if !ok {
+		return errors.New("needs logged in, but access without logged in state")
+	}
+	after, replay, err := lastEventCursor(c.Request())
+	if err != nil {
+		return err
+	}
+
+	conn, err := sse.NewConn(c.Response(), c.Request(), userID)
+	if err != nil {
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+	log.Println("Server.acceptSSEConn: ")
+	defer conn.Close()
+
+	var ctx = c.Request().Context()
+
+	conn.OnError(func(conn *sse.Conn, err error) {
+		log.Printf("server-sent events error: %v\n", err)
+	})
+	conn.OnClosed(func(conn *sse.Conn) {
+		s.chatHub.Disconnect(conn)
+	})
+
+	// same as the websocket, the connection must be listening while connecting.
+	// the response header is already written at the connect error,
+	// so the error is sent as the error event.
+	go func() {
+		if err := s.connectHub(ctx, conn, after, replay); err != nil {
+			conn.Send(chat.NewEventJSON(chat.NewErrorRaised(err, nil)))
+			log.Printf("server-sent events connect error: %v\n", err)
+			conn.Close()
+		}
+	}()
+
+	// blocking until the client disconnects.
+	conn.Listen(ctx)
+	return nil
+}
+
+func (s *Server) serveChatLongPoll(c echo.Context) error {
+	// LoggedInUserID is valid at middleware layer, loginHandler.Filter.
+	userID, ok := LoggedInUserID(c)
+	if !ok {
+		return errors.New("needs logged in, but access without logged in state")
+	}
+
+	req := c.Request()
+	query := req.URL.Query()
+	cursor, err := parseUintQuery(query.Get(QueryKeyPollCursor), 0)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested poll cursor is not allowed: %v", err))
+	}
+	limit, err := parseUintQuery(query.Get(QueryKeyPollLimit), MaxPollEvents)
+	if err != nil {
+		return NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested poll limit is not allowed: %v", err))
+	}
+	if limit == 0 || limit > MaxPollEvents {
+		limit = MaxPollEvents
+	}
+
+	var conn *longpoll.Conn
+	if sessionID := query.Get(QueryKeyPollSession); sessionID != "" {
+		conn, err = s.findPollSession(userID, sessionID)
+	} else {
+		conn, err = s.connectPollSession(userID, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(s.conf.LongPollTimeout) * time.Second
+	batch, err := conn.Poll(req.Context(), cursor, int(limit), timeout)
+	if err == longpoll.ErrAlreadyClosed {
+		return NewHTTPError(http.StatusNotFound, fmt.Errorf("poll session(%v) is expired", conn.SessionID()))
+	}
+	if err != nil {
+		return NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return c.JSON(http.StatusOK, batch)
+}
+
+// connectPollSession creates new poll session for the user and
+// connects it to the hub. The stored events are replayed if
+// the event cursor is specified by the request.
+func (s *Server) connectPollSession(userID uint64, req *http.Request) (*longpoll.Conn, error) {
+	after, replay, err := eventCursor(req)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := longpoll.NewConn(userID)
+	if err != nil {
+		return nil, NewHTTPError(http.StatusInternalServerError, err)
+	}
+	conn.OnClosed(func(conn *longpoll.Conn) {
+		s.chatHub.Disconnect(conn)
+	})
+
+	// the conn buffers the replayed events, so it need not be polling while connecting.
+	// If the replayed events are more than the conn can buffer, the rest of them are
+	// replayed while the client polls. The connecting outlives the request in that case.
+	connected := make(chan error, 1)
+	go func() {
+		connected <- s.connectHub(context.Background(), conn, after, replay)
+	}()
+	select {
+	case err := <-connected:
+		if err != nil {
+			conn.Close()
+			if chat.IsNotFoundError(err) {
+				return nil, NewHTTPError(http.StatusNotFound, err)
+			}
+			return nil, NewHTTPError(http.StatusInternalServerError, err)
+		}
+	case <-conn.Stalled():
+		go func() {
+			if err := <-connected; err != nil {
+				log.Printf("Server.connectPollSession: %v", err)
+				conn.Close()
+				return
+			}
+			// the conn closed while connecting is not disconnected by OnClosed.
+			select {
+			case <-conn.Done():
+				s.chatHub.Disconnect(conn)
+			default:
+			}
+		}()
+	}
+	log.Println("Server.acceptPollConn: ")
+
+	s.pollSessions.Add(conn)
+	return conn, nil
+}
+
+// findPollSession returns the poll session of the user.
+func (s *Server) findPollSession(userID uint64, sessionID string) (*longpoll.Conn, error) {
+	conn, ok := s.pollSessions.Find(sessionID)
+	if !ok || conn.UserID() != userID {
+		return nil, NewHTTPError(http.StatusNotFound, fmt.Errorf("poll session(%v) is not found", sessionID))
+	}
+	return conn, nil
+}
+
+func (s *Server) closeChatLongPoll(c echo.Context) error {
+	// LoggedInUserID is valid at middleware layer, loginHandler.Filter.
+	userID, ok := LoggedInUserID(c)
+	if !ok {
+		return errors.New("needs logged in, but access without logged in state")
+	}
+
+	sessionID := c.Param(ParamKeyPollSession)
+	if _, err := s.findPollSession(userID, sessionID); err != nil {
+		return err
+	}
+	s.pollSessions.Remove(sessionID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// parseUintQuery parses the query value as uint64.
+// It returns the defaultValue for the empty value.
+func parseUintQuery(value string, defaultValue uint64) (uint64, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// lastEventCursor is similar with the eventCursor except that
+// the Last-Event-ID header, which is set by the reconnecting EventSource,
+// takes precedence over the URL query.
+func lastEventCursor(req *http.Request) (uint64, bool, error) {
+	param := req.Header.Get(sse.HeaderLastEventID)
+	if param == "" {
+		return eventCursor(req)
+	}
+	after, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, false, NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested last event id(%v) is not allowed", param))
+	}
+	return after, true, nil
+}
+
+// eventCursor returns the sequence number of the event cursor
+// specified by the request query, and whether it is specified.
+func eventCursor(req *http.Request) (uint64, bool, error) {
+	param := req.URL.Query().Get(QueryKeyEventCursor)
+	if param == "" {
+		return 0, false, nil
+	}
+	after, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, false, NewHTTPError(http.StatusBadRequest, fmt.Errorf("requested event cursor(%v) is not allowed", param))
+	}
+	return after, true, nil
+}
+
 // Handler returns http.Handler interface in the server.
 func (s *Server) Handler() http.Handler {
 	return s.echo
@@ -181,6 +520,7 @@ func (s *Server) ListenAndServe() error {
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.pollSessions.Shutdown()
 	return s.echo.Shutdown(ctx)
 }
 
@@ -189,8 +529,8 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // A nil config is OK and use DefaultConfig insteadly.
 // It blocks until the process occurs any error and
 // return the error.
-func ListenAndServe(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub chat.Hub, login chat.LoginService, conf *Config) error {
-	s := NewServer(chatCmd, chatQuery, chatHub, login, conf)
+func ListenAndServe(chatCmd chat.CommandService, chatQuery chat.QueryService, chatHub chat.Hub, login chat.LoginService, attachments chat.AttachmentService, conf *Config) error {
+	s := NewServer(chatCmd, chatQuery, chatHub, login, attachments, conf)
 	defer s.Shutdown(context.Background())
 	return s.ListenAndServe()
 }
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,8 +20,11 @@ import (
 	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/domain"
+	"github.com/shirasudon/go-chat/domain/event"
 	"github.com/shirasudon/go-chat/infra/inmemory"
 	"github.com/shirasudon/go-chat/infra/pubsub"
+	"github.com/shirasudon/go-chat/longpoll"
+	"github.com/shirasudon/go-chat/sse"
 	"github.com/shirasudon/go-chat/ws/wstest"
 )
 
@@ -35,9 +41,9 @@ var (
 
 	chatCmd   = chat.NewCommandServiceImpl(repository, globalPubsub)
 	chatQuery = chat.NewQueryServiceImpl(queryers)
-	chatHub   = chat.NewHubImpl(chatCmd)
+	chatHub   = chat.NewHubImplWithEvents(chatCmd, repository.EventRepository)
 
-	loginService = chat.NewLoginServiceImpl(queryers.UserQueryer, globalPubsub)
+	loginService = chat.NewLoginServiceImpl(repository.Users(), domain.DefaultPasswordHasher, globalPubsub)
 
 	theEcho = echo.New()
 )
@@ -45,6 +51,11 @@ var (
 func TestMain(m *testing.M) {
 	defer globalPubsub.Shutdown()
 
+	// users related to the dummy rooms.
+	if err := repository.UserRepository.SeedUsers(inmemory.DummyUser2, inmemory.DummyUser3); err != nil {
+		panic(err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go repository.UpdatingService(ctx)
@@ -69,7 +80,7 @@ func TestServerListenAndServeFailWithConfig(t *testing.T) {
 		t.Fatal("Config should be invalid here")
 	}
 
-	server := NewServer(chatCmd, chatQuery, chatHub, loginService, &Config)
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &Config)
 	defer server.Shutdown(context.Background())
 
 	errCh := make(chan error, 1)
@@ -89,7 +100,7 @@ func TestServerListenAndServeFailWithConfig(t *testing.T) {
 }
 
 func TestServerServeChatWebsocket(t *testing.T) {
-	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil)
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
 	defer server.Shutdown(context.Background())
 
 	// run server process
@@ -190,16 +201,18 @@ func TestServerServeChatWebsocket(t *testing.T) {
 
 func TestServerConnIsClosedAfterLogout(t *testing.T) {
 	// setup user to be used here
-	var (
-		err      error = nil
-		testUser       = domain.User{Name: "test_user", Password: "password"}
-	)
+	const testPassword = "password"
+	hashed, err := domain.DefaultPasswordHasher.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testUser := domain.User{Name: "test_user", Password: hashed}
 	testUser.ID, err = repository.UserRepository.Store(context.Background(), testUser)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil)
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
 	defer server.Shutdown(context.Background())
 
 	// run server process
@@ -244,7 +257,7 @@ func TestServerConnIsClosedAfterLogout(t *testing.T) {
 	defer conn.Close()
 
 	// login by using login_test.doLogin.
-	loginC, err := doLogin(server.loginHandler, testUser.Name, testUser.Password, false)
+	loginC, err := doLogin(server.loginHandler, testUser.Name, testPassword, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -266,8 +279,381 @@ func TestServerConnIsClosedAfterLogout(t *testing.T) {
 	// PASS
 }
 
+func TestServerServeChatWebsocketReplay(t *testing.T) {
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
+	defer server.Shutdown(context.Background())
+
+	e := echo.New()
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			c := e.NewContext(req, w)
+			c.Set(KeyLoggedInUserID, uint64(LoginUserID)) // To use check for login state
+			if err := server.serveChatWebsocket(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	// post messages while the user is not connected.
+	postMessage := func(content string) uint64 {
+		cm := action.ChatMessage{Content: content}
+		cm.SenderID = LoginUserID
+		cm.RoomID = 3
+		if _, err := chatCmd.PostRoomMessage(context.Background(), cm); err != nil {
+			t.Fatal(err)
+		}
+		records, err := repository.EventRepository.FindAllBySequence(context.Background(), 0, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records[len(records)-1].Seq
+	}
+	seenSeq := postMessage("seen message")
+	missedSeq := postMessage("missed message")
+	lastSeq := postMessage("last missed message")
+
+	// invalid cursor is rejected before connected.
+	res, err := http.Get(ts.URL + "/chat/ws?" + QueryKeyEventCursor + "=invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("different status code for the invalid cursor, expect: %v, got: %v", http.StatusBadRequest, res.StatusCode)
+	}
+
+	// reconnect with the cursor of the last seen event.
+	requestPath := ts.URL + "/chat/ws?" + QueryKeyEventCursor + "=" + strconv.FormatUint(seenSeq, 10)
+	origin := ts.URL[0:strings.LastIndex(ts.URL, ":")]
+	conn, err := wstest.NewClientConn(requestPath, origin)
+	if err != nil {
+		t.Fatalf("can not create websocket connetion, error: %v", err)
+	}
+	defer conn.Close()
+
+	// the missed message is replayed before the live events.
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var replayed struct {
+		Event string                 `json:"event"`
+		Data  map[string]interface{} `json:"data"`
+		Seq   uint64                 `json:"seq"`
+	}
+	if err := websocket.JSON.Receive(conn, &replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Event != chat.EventNameMessageCreated || replayed.Seq != missedSeq {
+		t.Errorf("different replayed event, expect: %v with seq %v, got: %v with seq %v",
+			chat.EventNameMessageCreated, missedSeq, replayed.Event, replayed.Seq)
+	}
+	if got := replayed.Data["content"]; got != "missed message" {
+		t.Errorf("different replayed message content, got: %v", got)
+	}
+
+	// all of the missed messages are replayed.
+	if err := websocket.JSON.Receive(conn, &replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Seq != lastSeq {
+		t.Errorf("different replayed event, expect seq %v, got seq %v", lastSeq, replayed.Seq)
+	}
+}
+
+func TestServerServeChatSSEReplay(t *testing.T) {
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
+	defer server.Shutdown(context.Background())
+
+	e := echo.New()
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			c := e.NewContext(req, w)
+			c.Set(KeyLoggedInUserID, uint64(LoginUserID)) // To use check for login state
+			if err := server.serveChatSSE(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	// post messages while the user is not connected.
+	var seqs []uint64
+	for _, content := range []string{"seen message", "missed message", "last missed message"} {
+		cm := action.ChatMessage{Content: content}
+		cm.SenderID = LoginUserID
+		cm.RoomID = 3
+		if _, err := chatCmd.PostRoomMessage(context.Background(), cm); err != nil {
+			t.Fatal(err)
+		}
+		records, err := repository.EventRepository.FindAllBySequence(context.Background(), 0, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, records[len(records)-1].Seq)
+	}
+
+	request := func(lastEventID string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/chat/sse", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(sse.HeaderLastEventID, lastEventID)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	// invalid cursor is rejected before connected.
+	res := request("invalid")
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("different status code for the invalid cursor, expect: %v, got: %v", http.StatusBadRequest, res.StatusCode)
+	}
+
+	// reconnect with the id of the last seen event.
+	res = request(strconv.FormatUint(seqs[0], 10))
+	defer res.Body.Close()
+	if got := res.Header.Get("Content-Type"); got != sse.ContentType {
+		t.Errorf("different content type, expect: %v, got: %v", sse.ContentType, got)
+	}
+
+	// the missed messages are replayed with the ids.
+	reader := bufio.NewReader(res.Body)
+	for _, expect := range seqs[1:] {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectLine := "id: " + strconv.FormatUint(expect, 10) + "\n"; line != expectLine {
+			t.Errorf("different event id, expect: %q, got: %q", expectLine, line)
+		}
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var replayed struct {
+			Event string `json:"event"`
+			Seq   uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &replayed); err != nil {
+			t.Fatalf("invalid data line %q: %v", line, err)
+		}
+		if replayed.Event != chat.EventNameMessageCreated || replayed.Seq != expect {
+			t.Errorf("different replayed event, expect: %v with seq %v, got: %v with seq %v",
+				chat.EventNameMessageCreated, expect, replayed.Event, replayed.Seq)
+		}
+		// skip the blank line
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServerServeChatLongPoll(t *testing.T) {
+	conf := DefaultConfig
+	conf.LongPollTimeout = 1
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, &conf)
+	defer server.Shutdown(context.Background())
+
+	e := echo.New()
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			c := e.NewContext(req, w)
+			c.Set(KeyLoggedInUserID, uint64(LoginUserID)) // To use check for login state
+			var err error
+			if req.Method == http.MethodDelete {
+				c.SetParamNames(ParamKeyPollSession)
+				c.SetParamValues(strings.TrimPrefix(req.URL.Path, "/chat/poll/"))
+				err = server.closeChatLongPoll(c)
+			} else {
+				err = server.serveChatLongPoll(c)
+			}
+			if err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	postMessage := func(content string) uint64 {
+		cm := action.ChatMessage{Content: content}
+		cm.SenderID = LoginUserID
+		cm.RoomID = 3
+		if _, err := chatCmd.PostRoomMessage(context.Background(), cm); err != nil {
+			t.Fatal(err)
+		}
+		records, err := repository.EventRepository.FindAllBySequence(context.Background(), 0, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records[len(records)-1].Seq
+	}
+	type Batch struct {
+		SessionID string `json:"session_id"`
+		Events    []struct {
+			Event string                 `json:"event"`
+			Data  map[string]interface{} `json:"data"`
+			Seq   uint64                 `json:"seq"`
+		} `json:"events"`
+		Cursor uint64 `json:"cursor"`
+	}
+	poll := func(query string, expectStatus int) Batch {
+		res, err := http.Get(ts.URL + "/chat/poll?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != expectStatus {
+			t.Fatalf("different status code for %q, expect: %v, got: %v", query, expectStatus, res.StatusCode)
+		}
+		var batch Batch
+		if expectStatus == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(&batch); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return batch
+	}
+
+	seenSeq := postMessage("seen message")
+	missedSeq := postMessage("missed message")
+
+	// invalid queries are rejected.
+	poll(QueryKeyEventCursor+"=invalid", http.StatusBadRequest)
+	poll(QueryKeyPollCursor+"=invalid", http.StatusBadRequest)
+	poll(QueryKeyPollSession+"=not-found", http.StatusNotFound)
+
+	// the first poll creates new session with the replayed events.
+	batch := poll(QueryKeyEventCursor+"="+strconv.FormatUint(seenSeq, 10), http.StatusOK)
+	if batch.SessionID == "" {
+		t.Fatal("no session id is returned")
+	}
+	var replayed bool
+	for _, ev := range batch.Events {
+		if ev.Event == chat.EventNameMessageCreated && ev.Seq == missedSeq {
+			replayed = true
+		}
+	}
+	if !replayed {
+		t.Errorf("the missed message is not replayed, got: %#v", batch.Events)
+	}
+	session := QueryKeyPollSession + "=" + batch.SessionID
+
+	// the next poll waits for the live event.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		postMessage("live message")
+	}()
+	var live bool
+	for i := 0; i < 5 && !live; i++ {
+		batch = poll(session+"&"+QueryKeyPollCursor+"="+strconv.FormatUint(batch.Cursor, 10), http.StatusOK)
+		for _, ev := range batch.Events {
+			if ev.Event == chat.EventNameMessageCreated && ev.Data["content"] == "live message" {
+				live = true
+			}
+		}
+	}
+	if !live {
+		t.Fatal("the live message is not polled")
+	}
+
+	// the closed session can not be polled.
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/chat/poll/"+batch.SessionID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("different status code for closing, expect: %v, got: %v", http.StatusNoContent, res.StatusCode)
+	}
+	poll(session, http.StatusNotFound)
+}
+
+func TestServerServeChatLongPollReplayOverBuffer(t *testing.T) {
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
+	defer server.Shutdown(context.Background())
+
+	e := echo.New()
+	ts := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			c := e.NewContext(req, w)
+			c.Set(KeyLoggedInUserID, uint64(LoginUserID)) // To use check for login state
+			if err := server.serveChatLongPoll(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+		}),
+	)
+	defer ts.Close()
+
+	// the missed events are more than the conn can buffer.
+	const NumMissed = longpoll.MaxBufferedEvents + 10
+	missed := make([]event.Event, 0, NumMissed+1)
+	for i := 0; i <= NumMissed; i++ {
+		ev := event.MessageCreated{RoomID: 3}
+		ev.Occurs()
+		missed = append(missed, ev)
+	}
+	seqs, err := repository.EventRepository.Store(context.Background(), missed...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seenSeq := seqs[0]
+
+	type Batch struct {
+		SessionID string `json:"session_id"`
+		Events    []struct {
+			Seq uint64 `json:"seq"`
+		} `json:"events"`
+		Cursor uint64 `json:"cursor"`
+	}
+	poll := func(query string) Batch {
+		res, err := http.Get(ts.URL + "/chat/poll?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("different status code for %q, expect: %v, got: %v", query, http.StatusOK, res.StatusCode)
+		}
+		var batch Batch
+		if err := json.NewDecoder(res.Body).Decode(&batch); err != nil {
+			t.Fatal(err)
+		}
+		return batch
+	}
+
+	// all of the missed events are replayed by polling without closing the session.
+	batch := poll(QueryKeyEventCursor + "=" + strconv.FormatUint(seenSeq, 10))
+	replayed := make([]uint64, 0, NumMissed)
+	for len(batch.Events) > 0 {
+		for _, ev := range batch.Events {
+			if ev.Seq > 0 {
+				replayed = append(replayed, ev.Seq)
+			}
+		}
+		if len(replayed) >= NumMissed {
+			break
+		}
+		batch = poll(QueryKeyPollSession + "=" + batch.SessionID + "&" +
+			QueryKeyPollCursor + "=" + strconv.FormatUint(batch.Cursor, 10))
+	}
+	if len(replayed) != NumMissed {
+		t.Fatalf("different number of the replayed events, expect: %v, got: %v", NumMissed, len(replayed))
+	}
+	for i, seq := range replayed {
+		if expect := seqs[i+1]; seq != expect {
+			t.Fatalf("different replayed event at %d, expect seq: %v, got: %v", i, expect, seq)
+		}
+	}
+}
+
 func TestServerHandler(t *testing.T) {
-	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil)
+	server := NewServer(chatCmd, chatQuery, chatHub, loginService, nil, nil)
 	defer server.Shutdown(context.Background())
 
 	// check type
@@ -0,0 +1,181 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+// ContentType is the MIME type of the Server-Sent Events stream.
+const ContentType = "text/event-stream"
+
+// HeaderLastEventID is the request header which the client sets
+// to the id of the last received event when it reconnects.
+const HeaderLastEventID = "Last-Event-ID"
+
+// ErrStreamingUnsupported is returned when the http.ResponseWriter
+// can not flush the events to the client.
+var ErrStreamingUnsupported = errors.New("streaming unsupported")
+
+// Conn is end-point for writing messages to the Server-Sent Events stream.
+// One Conn corresponds to one browser-side client.
+// Unlike the websocket, it can not receive any message from the client.
+type Conn struct {
+	userID uint64
+
+	w       http.ResponseWriter
+	flusher http.Flusher
+	req     *http.Request
+
+	mu     *sync.Mutex
+	closed bool          // under mu
+	done   chan struct{} // done is managed by closed.
+
+	messages chan event.Event
+
+	onClosed func(*Conn)
+	onError  func(*Conn, error)
+}
+
+// NewConn creates the Conn which writes the events into w.
+// It returns ErrStreamingUnsupported if w does not implement http.Flusher.
+func NewConn(w http.ResponseWriter, req *http.Request, userID uint64) (*Conn, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+	return &Conn{
+		userID:   userID,
+		w:        w,
+		flusher:  flusher,
+		req:      req,
+		mu:       new(sync.Mutex),
+		closed:   false,
+		messages: make(chan event.Event), // to be written before closed.
+		done:     make(chan struct{}, 1),
+	}, nil
+}
+
+// UserID returns user ID binding to the connection.
+func (c *Conn) UserID() uint64 {
+	return c.userID
+}
+
+// Request returns its internal http request.
+func (c *Conn) Request() *http.Request {
+	return c.req
+}
+
+// set callback function to handle the event for the connection is closed .
+// the callback function may be called asynchronously.
+func (c *Conn) OnClosed(f func(*Conn)) {
+	c.onClosed = f
+}
+
+// set callback function to handle the event for the connection gets error.
+// the callback function may be called asynchronously.
+func (c *Conn) OnError(f func(*Conn, error)) {
+	c.onError = f
+}
+
+// Send the event to browser-side client.
+// event is ignored when Conn is closed.
+func (c *Conn) Send(ev event.Event) {
+	select {
+	case c.messages <- ev:
+	case <-c.done:
+	}
+}
+
+var ErrAlreadyClosed = errors.New("already closed")
+
+// Close stops Listen() immediately.
+// it returns ErrAlreadyClosed when the Conn is
+// already closed otherwise nil.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrAlreadyClosed
+	}
+
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock() // to avoid dead lock, Unlock before OnClosed.
+
+	if c.onClosed != nil {
+		c.onClosed(c)
+	}
+	return nil
+}
+
+// Listen starts writing the stream.
+// it blocks until the client disconnects, Conn is closed or
+// context is done.
+//
+// when Listen() ends, Conn is closed.
+func (c *Conn) Listen(ctx context.Context) {
+	defer c.Close()
+
+	header := c.w.Header()
+	header.Set("Content-Type", ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// disable the response buffering by the reverse proxy, such as nginx.
+	header.Set("X-Accel-Buffering", "no")
+	c.w.WriteHeader(http.StatusOK)
+	c.flusher.Flush()
+
+	// the request context is done when the client disconnects.
+	reqDone := c.req.Context().Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reqDone:
+			return
+		case <-c.done:
+			return
+		case ev := <-c.messages:
+			message, err := encode(ev)
+			if err != nil {
+				if c.onError != nil {
+					c.onError(c, err)
+				}
+				continue
+			}
+			if _, err := c.w.Write(message); err != nil {
+				// the client can not receive any more.
+				if c.onError != nil {
+					c.onError(c, err)
+				}
+				return
+			}
+			c.flusher.Flush()
+		}
+	}
+}
+
+// encode encodes the event as a message of the stream.
+// The sequence number of the event is used as the message id,
+// which is sent back by the client as Last-Event-ID header.
+func encode(ev event.Event) ([]byte, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if seq := event.SequenceOf(ev); seq > 0 {
+		fmt.Fprintf(&buf, "id: %d\n", seq)
+	}
+	// the encoded JSON never contains the new line.
+	fmt.Fprintf(&buf, "data: %s\n\n", data)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/chat"
+	"github.com/shirasudon/go-chat/domain/event"
+)
+
+const Timeout = 10 * time.Millisecond
+
+// writerOnly hides the http.Flusher of the underlying ResponseWriter.
+type writerOnly struct {
+	http.ResponseWriter
+}
+
+func TestNewConn(t *testing.T) {
+	const UserID = uint64(1)
+	req := httptest.NewRequest("GET", "/sse", nil)
+
+	conn, err := NewConn(httptest.NewRecorder(), req, UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.UserID() != UserID {
+		t.Errorf("different user id, expect: %v, got: %v", UserID, conn.UserID())
+	}
+	if conn.Request() != req {
+		t.Errorf("different request")
+	}
+
+	if _, err := NewConn(writerOnly{httptest.NewRecorder()}, req, UserID); err != ErrStreamingUnsupported {
+		t.Errorf("no flusher writer is accepted, got: %v", err)
+	}
+}
+
+func TestConnListen(t *testing.T) {
+	rec := httptest.NewRecorder()
+	conn, err := NewConn(rec, httptest.NewRequest("GET", "/sse", nil), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := false
+	conn.OnClosed(func(*Conn) { closed = true })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.Listen(context.Background())
+	}()
+
+	stored := event.MessageCreated{Content: "stored"}
+	conn.Send(chat.NewEventJSON(event.WithSequence(stored, 3)))
+	conn.Send(chat.NewEventJSON(event.MessageCreated{Content: "live"}))
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != ErrAlreadyClosed {
+		t.Errorf("closing twice should return ErrAlreadyClosed, got: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatal("Listen is not done after closed")
+	}
+	if !closed {
+		t.Error("OnClosed is not called")
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("different content type, expect: %v, got: %v", ContentType, got)
+	}
+	messages := strings.Split(rec.Body.String(), "\n\n")
+	if len(messages) != 3 || messages[2] != "" {
+		t.Fatalf("different number of messages, got: %q", rec.Body.String())
+	}
+	if !strings.HasPrefix(messages[0], "id: 3\ndata: {") || !strings.Contains(messages[0], `"content":"stored"`) {
+		t.Errorf("the stored event should have the id, got: %q", messages[0])
+	}
+	if !strings.HasPrefix(messages[1], "data: {") {
+		t.Errorf("the not stored event should have no id, got: %q", messages[1])
+	}
+
+	// the closed conn ignores the event.
+	conn.Send(chat.NewEventJSON(stored))
+}
+
+func TestConnListenDisconnected(t *testing.T) {
+	reqCtx, disconnect := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/sse", nil).WithContext(reqCtx)
+	conn, err := NewConn(httptest.NewRecorder(), req, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.Listen(context.Background())
+	}()
+	disconnect()
+
+	select {
+	case <-done:
+	case <-time.After(Timeout):
+		t.Fatal("Listen is not done after the client disconnected")
+	}
+	if err := conn.Close(); err != ErrAlreadyClosed {
+		t.Errorf("the conn is not closed after Listen is done, got: %v", err)
+	}
+}
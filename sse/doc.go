@@ -0,0 +1,4 @@
+// package sse defines implementation for the
+// Server-Sent Events connetion.
+
+package sse
@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/domain/event"
 
@@ -18,6 +20,15 @@ import (
 type ActionJSON struct {
 	ActionName action.Action     `json:"action"`
 	Data       action.AnyMessage `json:"data"`
+
+	// CorrelationID is an optional ID supplied by the client.
+	// It is echoed back with the error event caused by the action.
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// RequestID is an optional ID supplied by the client.
+	// The action with RequestID is acknowledged by the ack event,
+	// and the retried action with same RequestID is not executed twice.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Conn is end-point for reading/writing messages from/to websocket.
@@ -26,6 +37,7 @@ type Conn struct {
 	userID uint64
 
 	conn *websocket.Conn
+	conf Config
 
 	mu     *sync.Mutex
 	closed bool          // under mu
@@ -38,10 +50,12 @@ type Conn struct {
 	onError         func(*Conn, error)
 }
 
+// NewConn creates the Conn with DefaultConfig.
 func NewConn(conn *websocket.Conn, userID uint64) *Conn {
 	return &Conn{
 		userID:   userID,
 		conn:     conn,
+		conf:     DefaultConfig,
 		mu:       new(sync.Mutex),
 		closed:   false,
 		messages: make(chan interface{}, 1),
@@ -130,6 +144,13 @@ func (c *Conn) Listen(ctx context.Context) {
 }
 
 func (c *Conn) sendPump(ctx context.Context, receiveDoneCh chan struct{}) {
+	var pingCh <-chan time.Time
+	if c.conf.PingInterval > 0 {
+		ticker := time.NewTicker(c.conf.PingInterval)
+		defer ticker.Stop()
+		pingCh = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -138,7 +159,20 @@ func (c *Conn) sendPump(ctx context.Context, receiveDoneCh chan struct{}) {
 			return
 		case <-receiveDoneCh:
 			return
+		case <-pingCh:
+			if err := c.ping(); err != nil {
+				if c.onError != nil {
+					c.onError(c, err)
+				}
+				return
+			}
 		case m := <-c.messages:
+			if err := c.setWriteDeadline(); err != nil {
+				if c.onError != nil {
+					c.onError(c, err)
+				}
+				return
+			}
 			if err := websocket.JSON.Send(c.conn, m); err != nil {
 				// io.EOF means connection is closed
 				if err == io.EOF {
@@ -147,6 +181,10 @@ func (c *Conn) sendPump(ctx context.Context, receiveDoneCh chan struct{}) {
 				if c.onError != nil {
 					c.onError(c, err)
 				}
+				// the client can not receive any more.
+				if isConnectionError(err) {
+					return
+				}
 			}
 		}
 	}
@@ -162,11 +200,11 @@ func (c *Conn) receivePump(ctx context.Context) {
 		default:
 			message, err := c.receiveActionJSON()
 			if err != nil {
-				if err == io.EOF {
+				if isConnectionError(err) {
 					return
 				}
 				// return error message to client
-				c.Send(event.ErrorRaised{Message: err.Error()})
+				c.sendError(err, message)
 				continue
 			}
 			// Receive success, handling received message
@@ -175,13 +213,15 @@ func (c *Conn) receivePump(ctx context.Context) {
 	}
 }
 
-// return fatal error, such as io.EOF with connection closed,
-// otherwise handle itself.
+// return fatal error, such as io.EOF with connection closed
+// or timeout, otherwise handle itself.
+// The received message is returned with the error if it is
+// decoded but has invalid structure.
 func (c *Conn) receiveActionJSON() (*ActionJSON, error) {
 	var message ActionJSON
 	if err := websocket.JSON.Receive(c.conn, &message); err != nil {
 		// io.EOF means connection is closed
-		if err == io.EOF {
+		if isConnectionError(err) {
 			return nil, err
 		}
 
@@ -199,7 +239,7 @@ func (c *Conn) receiveActionJSON() (*ActionJSON, error) {
 		if c.onError != nil {
 			c.onError(c, err)
 		}
-		return nil, err
+		return &message, err
 	}
 	return &message, nil
 }
@@ -211,16 +251,60 @@ func (c *Conn) handleActionJSON(m *ActionJSON) {
 	}
 	data.SetString(action.KeyAction, string(m.ActionName))
 	data.SetNumber(action.KeySenderID, float64(c.userID))
+	if m.CorrelationID != "" {
+		data.SetString(action.KeyCorrelationID, m.CorrelationID)
+	}
+	if m.RequestID != "" {
+		data.SetString(action.KeyRequestID, m.RequestID)
+	}
 
 	actionMsg, err := action.ConvertAnyMessage(data)
 	if err != nil {
 		if c.onError != nil {
 			c.onError(c, err)
 		}
-		c.Send(event.ErrorRaised{Message: err.Error()})
+		c.sendError(err, m)
 		return
 	}
 	if c.onActionMessage != nil {
 		c.onActionMessage(c, actionMsg)
 	}
 }
+
+// sendError sends the error event for the invalid action message
+// to the client. The message m may be nil when the message can not
+// be decoded.
+// If the message has the request ID, the error is sent as the Ack.
+func (c *Conn) sendError(err error, m *ActionJSON) {
+	ev := event.ErrorRaised{
+		Message: err.Error(),
+		Code:    chat.ErrorCodeInvalidAction,
+	}
+	var requestID string
+	if m != nil {
+		ev.Action = string(m.ActionName)
+		ev.CorrelationID = m.CorrelationID
+		requestID = m.RequestID
+		if m.Data != nil {
+			if ev.CorrelationID == "" {
+				ev.CorrelationID = m.Data.GetCorrelationID()
+			}
+			if requestID == "" {
+				requestID = m.Data.GetRequestID()
+			}
+		}
+	}
+	ev.Occurs()
+
+	if requestID == "" {
+		c.Send(chat.NewEventJSON(ev))
+		return
+	}
+	c.Send(chat.NewAckJSON(chat.Ack{
+		RequestID: requestID,
+		Action:    ev.Action,
+		OK:        false,
+		Error:     &ev,
+		AckedAt:   ev.CreatedAt,
+	}))
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/shirasudon/go-chat/chat"
 	"github.com/shirasudon/go-chat/chat/action"
 	"github.com/shirasudon/go-chat/domain/event"
 	"github.com/shirasudon/go-chat/ws/wstest"
@@ -126,21 +127,14 @@ func TestConnGotsInvalidMessages(t *testing.T) {
 		t.Fatalf("client send error: %v", err)
 	}
 
-	var (
-		anyMsg map[string]interface{}
-	)
-	if err := websocket.JSON.Receive(conn, &anyMsg); err != nil {
-		t.Fatalf("client receive error: %v", err)
-	}
-
-	errMsg, ok := anyMsg["message"].(string)
-	if !ok {
-		t.Fatalf("got invalid error message: %#v", anyMsg)
-	}
-	if len(errMsg) == 0 {
+	errRaised := receiveErrorRaised(t, conn)
+	if len(errRaised.Message) == 0 {
 		t.Errorf("got error message but message is empty")
 	}
-	t.Logf("LOG: send invalid message, then return: %v", errMsg)
+	if errRaised.Code != chat.ErrorCodeInvalidAction {
+		t.Errorf("different error code, expect: %v, got: %v", chat.ErrorCodeInvalidAction, errRaised.Code)
+	}
+	t.Logf("LOG: send invalid message, then return: %v", errRaised.Message)
 
 	// Send no action Message
 	cm := action.ChatMessage{}
@@ -148,18 +142,68 @@ func TestConnGotsInvalidMessages(t *testing.T) {
 	if err := websocket.JSON.Send(conn, cm); err != nil {
 		t.Fatalf("client send error: %v", err)
 	}
-	if err := websocket.JSON.Receive(conn, &anyMsg); err != nil {
+
+	errRaised = receiveErrorRaised(t, conn)
+	if len(errRaised.Message) == 0 {
+		t.Errorf("got error message but message is empty")
+	}
+	t.Logf("LOG: send invalid message, then return: %v", errRaised.Message)
+
+	// Send unknown action Message with correlation ID
+	const CorrelationID = "correlation-1"
+	unknown := ActionJSON{
+		ActionName:    action.Action("UNKNOWN"),
+		Data:          action.AnyMessage{},
+		CorrelationID: CorrelationID,
+	}
+	if err := websocket.JSON.Send(conn, unknown); err != nil {
+		t.Fatalf("client send error: %v", err)
+	}
+
+	errRaised = receiveErrorRaised(t, conn)
+	if errRaised.Action != string(unknown.ActionName) {
+		t.Errorf("different error action, expect: %v, got: %v", unknown.ActionName, errRaised.Action)
+	}
+	if errRaised.CorrelationID != CorrelationID {
+		t.Errorf("different correlation ID, expect: %v, got: %v", CorrelationID, errRaised.CorrelationID)
+	}
+
+	// Send unknown action Message with request ID, then
+	// the error is returned as the ack.
+	const RequestID = "request-1"
+	unknown.RequestID = RequestID
+	if err := websocket.JSON.Send(conn, unknown); err != nil {
+		t.Fatalf("client send error: %v", err)
+	}
+
+	var ackJSON struct {
+		EventName string   `json:"event"`
+		Data      chat.Ack `json:"data"`
+	}
+	if err := websocket.JSON.Receive(conn, &ackJSON); err != nil {
 		t.Fatalf("client receive error: %v", err)
 	}
+	if ackJSON.EventName != chat.EventNameAck {
+		t.Fatalf("different event name, expect: %v, got: %v", chat.EventNameAck, ackJSON.EventName)
+	}
+	if ack := ackJSON.Data; ack.OK || ack.RequestID != RequestID || ack.Error == nil {
+		t.Errorf("invalid ack for the failed request, got: %#v", ack)
+	}
+}
 
-	errMsg, ok = anyMsg["message"].(string)
-	if !ok {
-		t.Fatalf("got invalid error message: %#v", anyMsg)
+// receiveErrorRaised receives the error event from the conn.
+func receiveErrorRaised(t *testing.T, conn *websocket.Conn) event.ErrorRaised {
+	var evJSON struct {
+		EventName string            `json:"event"`
+		Data      event.ErrorRaised `json:"data"`
 	}
-	if len(errMsg) == 0 {
-		t.Errorf("got error message but message is empty")
+	if err := websocket.JSON.Receive(conn, &evJSON); err != nil {
+		t.Fatalf("client receive error: %v", err)
+	}
+	if evJSON.EventName != chat.EventNameErrorRaised {
+		t.Fatalf("different event name, expect: %v, got: %v", chat.EventNameErrorRaised, evJSON.EventName)
 	}
-	t.Logf("LOG: send invalid message, then return: %v", errMsg)
+	return evJSON.Data
 }
 
 func TestConnClose(t *testing.T) {
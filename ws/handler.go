@@ -20,6 +20,9 @@ type Server struct {
 
 	// Handler for the Conn type in this package.
 	Handler Handler
+
+	// Config for the heartbeat of the served Conn.
+	Config Config
 }
 
 // NewServer creates the server which serves websocket Connection and
@@ -28,7 +31,7 @@ func NewServer(handler Handler) *Server {
 	if handler == nil {
 		panic("nil handler")
 	}
-	s := &Server{Handler: handler}
+	s := &Server{Handler: handler, Config: DefaultConfig}
 	s.server = &websocket.Server{Handler: s.wsHandler}
 	return s
 }
@@ -51,6 +54,7 @@ func (s *Server) wsHandler(wsConn *websocket.Conn) {
 	}
 
 	c := NewConn(wsConn, userID)
+	c.conf = s.Config
 	s.Handler(c)
 }
 
@@ -58,6 +62,9 @@ func (s *Server) wsHandler(wsConn *websocket.Conn) {
 // it requires userID to specify the which user connects.
 func (s *Server) ServeHTTPWithUserID(w http.ResponseWriter, req *http.Request, userID uint64) {
 	newCtx := setConnectUserID(req.Context(), userID)
+	if s.Config.ReadTimeout > 0 {
+		w = deadlineWriter{ResponseWriter: w, readTimeout: s.Config.ReadTimeout}
+	}
 	s.server.ServeHTTP(w, req.WithContext(newCtx))
 }
 
@@ -0,0 +1,112 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Config is the configuration for the heartbeat of the websocket connection.
+// The zero value of each field disables it.
+type Config struct {
+	// interval to send the ping frame to the client.
+	PingInterval time.Duration
+
+	// duration to wait for any data, including the pong frame, from the client.
+	// The connection is closed if nothing is received during it.
+	// It is applied to the connection served by the Server.
+	ReadTimeout time.Duration
+
+	// duration to wait for writing a message to the client.
+	WriteTimeout time.Duration
+}
+
+// DefaultConfig is default configuration for the heartbeat.
+var DefaultConfig = Config{
+	PingInterval: 30 * time.Second,
+	ReadTimeout:  60 * time.Second,
+	WriteTimeout: 10 * time.Second,
+}
+
+// deadlineWriter is a http.ResponseWriter whose hijacked connection
+// extends the read deadline at every read.
+//
+// The pong frames are consumed by the websocket package and never
+// returned to the Conn, so the deadline is extended by the underlying
+// connection to keep the client sending only the pong frames alive.
+type deadlineWriter struct {
+	http.ResponseWriter
+	readTimeout time.Duration
+}
+
+// implements http.Hijacker interface.
+func (w deadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijack unsupported")
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the data already buffered is read before the connection.
+	buffered, err := buf.Reader.Peek(buf.Reader.Buffered())
+	if err != nil {
+		return nil, nil, err
+	}
+	dc := &deadlineConn{Conn: conn, timeout: w.readTimeout}
+	r := io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), dc)
+	return dc, bufio.NewReadWriter(bufio.NewReader(r), buf.Writer), nil
+}
+
+// deadlineConn is a net.Conn which extends the read deadline at every read.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
+
+// setWriteDeadline sets the write deadline of the websocket
+// by the WriteTimeout.
+func (c *Conn) setWriteDeadline() error {
+	if c.conf.WriteTimeout <= 0 {
+		return nil
+	}
+	return c.conn.SetWriteDeadline(time.Now().Add(c.conf.WriteTimeout))
+}
+
+// ping sends the ping frame to the client.
+// The client responds the pong frame for it.
+func (c *Conn) ping() error {
+	if err := c.setWriteDeadline(); err != nil {
+		return err
+	}
+	// only the sendPump writes by the PayloadType.
+	c.conn.PayloadType = websocket.PingFrame
+	_, err := c.conn.Write(nil)
+	c.conn.PayloadType = websocket.TextFrame
+	return err
+}
+
+// isConnectionError returns whether the error is caused by
+// the broken or closed connection, such as timeout.
+func isConnectionError(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shirasudon/go-chat/ws/wstest"
+	"golang.org/x/net/websocket"
+)
+
+// newHeartbeatServer returns the test server which serves the Conn
+// with the config. The closed channel is closed when the Conn is closed.
+func newHeartbeatServer(conf Config) (*httptest.Server, chan struct{}) {
+	closed := make(chan struct{})
+	s := NewServerFunc(func(c *Conn) {
+		c.OnClosed(func(*Conn) { close(closed) })
+		c.Listen(context.Background())
+	})
+	s.Config = conf
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.ServeHTTPWithUserID(w, req, 1)
+	}))
+	return server, closed
+}
+
+func dialHeartbeatServer(t *testing.T, server *httptest.Server) *websocket.Conn {
+	origin := server.URL[0:strings.LastIndex(server.URL, ":")]
+	conn, err := wstest.NewClientConn(server.URL+"/ws", origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+var heartbeatConfig = Config{
+	PingInterval: 10 * time.Millisecond,
+	ReadTimeout:  50 * time.Millisecond,
+	WriteTimeout: time.Second,
+}
+
+func TestConnHeartbeatAlive(t *testing.T) {
+	server, closed := newHeartbeatServer(heartbeatConfig)
+	defer server.Close()
+
+	conn := dialHeartbeatServer(t, server)
+	defer conn.Close()
+
+	// the client responds the pong frames while reading.
+	go func() {
+		var msg string
+		for {
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("the client responding the pong frames is closed")
+	case <-time.After(4 * heartbeatConfig.ReadTimeout):
+	}
+}
+
+func TestConnHeartbeatSilent(t *testing.T) {
+	server, closed := newHeartbeatServer(heartbeatConfig)
+	defer server.Close()
+
+	// the client never reads, so that the pong frames are not responded.
+	conn := dialHeartbeatServer(t, server)
+	defer conn.Close()
+
+	select {
+	case <-closed:
+	case <-time.After(10 * heartbeatConfig.ReadTimeout):
+		t.Fatal("the silent client is not closed")
+	}
+}